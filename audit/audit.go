@@ -0,0 +1,89 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/casvisor/casvisor-go-sdk/casvisorsdk"
+)
+
+var sinks []*BufferedSink
+
+// the longest block timeout of the sinks, Emit blocks for that long at most whatever the number of sinks
+var maxBlockTimeout time.Duration
+
+// InitSinks creates the audit sinks from the "auditSinks" config, which is a JSON array of SinkConfig.
+func InitSinks(configText string) error {
+	if configText == "" {
+		return nil
+	}
+
+	configs := []*SinkConfig{}
+	err := json.Unmarshal([]byte(configText), &configs)
+	if err != nil {
+		return fmt.Errorf("invalid auditSinks config: %s", err.Error())
+	}
+
+	res := []*BufferedSink{}
+	for i, config := range configs {
+		if config.Name == "" {
+			config.Name = fmt.Sprintf("%s-%d", config.Type, i)
+		}
+
+		sink, err := NewSink(config)
+		if err != nil {
+			return err
+		}
+
+		res = append(res, NewBufferedSink(config, sink))
+	}
+
+	sinks = res
+	maxBlockTimeout = 0
+	for _, sink := range sinks {
+		if sink.getBlockTimeout() > maxBlockTimeout {
+			maxBlockTimeout = sink.getBlockTimeout()
+		}
+	}
+	return nil
+}
+
+// Emit hands a copy of the record to every audit sink without waiting for the delivery.
+// The sinks with the block overflow share a single deadline, not one timeout each.
+func Emit(record *casvisorsdk.Record) {
+	deadline := time.Now().Add(maxBlockTimeout)
+	for _, sink := range sinks {
+		record2 := *record
+		sink.pushUntil(&record2, deadline)
+	}
+}
+
+func GetSinkStatuses() []*SinkStatus {
+	res := []*SinkStatus{}
+	for _, sink := range sinks {
+		res = append(res, sink.GetStatus())
+	}
+	return res
+}
+
+func CloseSinks() {
+	for _, sink := range sinks {
+		_ = sink.Close()
+	}
+	sinks = nil
+}
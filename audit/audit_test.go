@@ -0,0 +1,208 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/casvisor/casvisor-go-sdk/casvisorsdk"
+)
+
+type blockingSink struct {
+	mutex   sync.Mutex
+	release chan struct{}
+	records []*casvisorsdk.Record
+}
+
+func (s *blockingSink) Send(records []*casvisorsdk.Record) error {
+	<-s.release
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.records = append(s.records, records...)
+	return nil
+}
+
+func (s *blockingSink) Close() error {
+	return nil
+}
+
+func TestBufferedSinkOverflow(t *testing.T) {
+	sink := &blockingSink{release: make(chan struct{})}
+	config := &SinkConfig{Name: "test", BufferSize: 2, BatchSize: 1, Overflow: OverflowDrop}
+	b := NewBufferedSink(config, sink)
+
+	// the first record is taken by the worker, which then blocks in Send
+	dropped := 0
+	for i := 0; i < 10; i++ {
+		if !b.Push(&casvisorsdk.Record{Name: "record"}) {
+			dropped++
+		}
+	}
+
+	if dropped == 0 {
+		t.Errorf("expected records to be dropped when the buffer is full")
+	}
+	if b.GetStatus().Dropped != uint64(dropped) {
+		t.Errorf("expected %d dropped records, got %d", dropped, b.GetStatus().Dropped)
+	}
+
+	close(sink.release)
+	err := b.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sink.records)+dropped != 10 {
+		t.Errorf("expected %d delivered records, got %d", 10-dropped, len(sink.records))
+	}
+}
+
+type failingSink struct {
+	mutex    sync.Mutex
+	failures int
+	// the failures deliver the first record of the batch when isPartial
+	isPartial    bool
+	maxBatchSize int
+	records      []*casvisorsdk.Record
+}
+
+func (s *failingSink) Send(records []*casvisorsdk.Record) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if len(records) > s.maxBatchSize {
+		s.maxBatchSize = len(records)
+	}
+	if s.failures > 0 {
+		s.failures--
+		if s.isPartial {
+			s.records = append(s.records, records[0])
+			return &PartialSendError{Sent: 1, Err: errors.New("unavailable")}
+		}
+		return errors.New("unavailable")
+	}
+	s.records = append(s.records, records...)
+	return nil
+}
+
+func (s *failingSink) Close() error {
+	return nil
+}
+
+func TestBufferedSinkRetry(t *testing.T) {
+	for _, isPartial := range []bool{false, true} {
+		testBufferedSinkRetry(t, isPartial)
+	}
+}
+
+func testBufferedSinkRetry(t *testing.T, isPartial bool) {
+	sink := &failingSink{failures: 3, isPartial: isPartial}
+	config := &SinkConfig{Name: "test", BufferSize: 10, BatchSize: 2, FlushIntervalMs: 10, Overflow: OverflowBlock, BlockTimeoutMs: 1000}
+	b := NewBufferedSink(config, sink)
+
+	// the records are still queued while the failed batch waits for its retry
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if !b.Push(&casvisorsdk.Record{Name: "record"}) {
+			t.Fatal("no record should be dropped")
+		}
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Fatalf("the retries should not block the callers: %s", time.Since(start))
+	}
+
+	for i := 0; i < 100 && b.GetStatus().Sent != 5; i++ {
+		time.Sleep(20 * time.Millisecond)
+	}
+	err := b.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the records accumulated during the retries are sent by batches, the partially sent ones only once
+	status := b.GetStatus()
+	if status.Sent != 5 || status.Failed != 0 || len(sink.records) != 5 || sink.maxBatchSize > 2 || !strings.HasSuffix(status.LastError, "unavailable") {
+		t.Fatalf("unexpected status: %+v, %d records, max batch size: %d", status, len(sink.records), sink.maxBatchSize)
+	}
+}
+
+func TestGetSyslogSentCount(t *testing.T) {
+	ends := []int{10, 25, 40}
+	for written, expected := range map[int]int{0: 0, 9: 0, 10: 1, 24: 1, 25: 2, 40: 3} {
+		if res := getSyslogSentCount(ends, written); res != expected {
+			t.Fatalf("%d bytes written: got %d frames, expected %d", written, res, expected)
+		}
+	}
+}
+
+func TestEmitBlockTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	previousSinks, previousMaxBlockTimeout := sinks, maxBlockTimeout
+	defer func() {
+		sinks, maxBlockTimeout = previousSinks, previousMaxBlockTimeout
+	}()
+
+	sinks = nil
+	for i := 0; i < 3; i++ {
+		config := &SinkConfig{Name: "test", BufferSize: 1, BatchSize: 1, Overflow: OverflowBlock, BlockTimeoutMs: 200}
+		b := NewBufferedSink(config, &blockingSink{release: release})
+
+		// the worker blocks in Send with the first record, the second one fills the queue
+		b.Push(&casvisorsdk.Record{Name: "record"})
+		for len(b.queue) != 0 {
+			time.Sleep(time.Millisecond)
+		}
+		b.Push(&casvisorsdk.Record{Name: "record"})
+		sinks = append(sinks, b)
+	}
+	maxBlockTimeout = 200 * time.Millisecond
+
+	// the sinks share the timeout instead of blocking for 200ms each
+	start := time.Now()
+	Emit(&casvisorsdk.Record{Name: "record"})
+	if elapsed := time.Since(start); elapsed > 400*time.Millisecond {
+		t.Fatalf("Emit has blocked for %s", elapsed)
+	}
+	for _, b := range sinks {
+		if b.GetStatus().Dropped != 1 {
+			t.Fatalf("the record should be dropped by every sink: %+v", b.GetStatus())
+		}
+	}
+}
+
+func TestGetSyslogMessage(t *testing.T) {
+	record := &casvisorsdk.Record{
+		CreatedTime:  "2024-01-02T03:04:05Z",
+		Organization: "built-in",
+		User:         "ad\"min]",
+		Action:       "update user",
+		StatusCode:   200,
+	}
+
+	message, err := getSyslogMessage(record, "host")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prefix := "<110>1 2024-01-02T03:04:05Z host casdoor - update_user [casdoor@32473 organization=\"built-in\" user=\"ad\\\"min\\]\""
+	if !strings.HasPrefix(message, prefix) {
+		t.Errorf("unexpected syslog message: %s", message)
+	}
+}
@@ -0,0 +1,247 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/beego/beego/logs"
+	"github.com/casvisor/casvisor-go-sdk/casvisorsdk"
+)
+
+type SinkStatus struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Queued    int    `json:"queued"`
+	Capacity  int    `json:"capacity"`
+	Sent      uint64 `json:"sent"`
+	Dropped   uint64 `json:"dropped"`
+	Failed    uint64 `json:"failed"`
+	LastError string `json:"lastError"`
+}
+
+// the backoff of the retries of a failed batch, doubled on each failure
+const (
+	minRetryBackoff = 100 * time.Millisecond
+	maxRetryBackoff = 30 * time.Second
+)
+
+// BufferedSink queues records in front of a sink and delivers them in batches
+// from its own goroutine, so that a slow sink never blocks the others.
+// When the queue is full, records are either dropped or the caller is blocked
+// for up to BlockTimeoutMs, depending on the Overflow setting.
+// A failed batch is retried with a backoff by the same goroutine, which keeps
+// queueing the records meanwhile until the batch reaches BufferSize.
+type BufferedSink struct {
+	config *SinkConfig
+	sink   Sink
+	queue  chan *casvisorsdk.Record
+	stop   chan struct{}
+	done   chan struct{}
+
+	sent    uint64
+	dropped uint64
+	failed  uint64
+
+	lastErrorMutex sync.Mutex
+	lastError      string
+}
+
+func NewBufferedSink(config *SinkConfig, sink Sink) *BufferedSink {
+	if config.BufferSize <= 0 {
+		config.BufferSize = 10000
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+	if config.FlushIntervalMs <= 0 {
+		config.FlushIntervalMs = 1000
+	}
+	if config.Overflow == "" {
+		config.Overflow = OverflowDrop
+	}
+	if config.BlockTimeoutMs <= 0 {
+		config.BlockTimeoutMs = 5000
+	}
+
+	b := &BufferedSink{
+		config: config,
+		sink:   sink,
+		queue:  make(chan *casvisorsdk.Record, config.BufferSize),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+func (b *BufferedSink) getBlockTimeout() time.Duration {
+	if b.config.Overflow != OverflowBlock {
+		return 0
+	}
+	return time.Duration(b.config.BlockTimeoutMs) * time.Millisecond
+}
+
+// Push enqueues a record, it returns false if the record has been dropped.
+func (b *BufferedSink) Push(record *casvisorsdk.Record) bool {
+	return b.pushUntil(record, time.Now().Add(b.getBlockTimeout()))
+}
+
+// pushUntil is Push with the caller blocked until the deadline at most, Emit shares
+// the deadline between the sinks so that a record never blocks for longer than one timeout.
+func (b *BufferedSink) pushUntil(record *casvisorsdk.Record, deadline time.Time) bool {
+	select {
+	case b.queue <- record:
+		return true
+	default:
+	}
+
+	if timeout := time.Until(deadline); timeout > 0 && b.config.Overflow == OverflowBlock {
+		if timeout > b.getBlockTimeout() {
+			timeout = b.getBlockTimeout()
+		}
+
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+
+		select {
+		case b.queue <- record:
+			return true
+		case <-timer.C:
+		}
+	}
+
+	atomic.AddUint64(&b.dropped, 1)
+	return false
+}
+
+func (b *BufferedSink) run() {
+	defer close(b.done)
+
+	ticker := time.NewTicker(time.Duration(b.config.FlushIntervalMs) * time.Millisecond)
+	defer ticker.Stop()
+
+	batch := []*casvisorsdk.Record{}
+	backoff := time.Duration(0)
+	// retry is only set while a failed batch waits for its next attempt
+	var retry <-chan time.Time
+	for {
+		// a failed batch stops taking records once full, so that the queue applies backpressure
+		queue := b.queue
+		if retry != nil && len(batch) >= b.config.BufferSize {
+			queue = nil
+		}
+
+		isFlushing := false
+		select {
+		case record := <-queue:
+			batch = append(batch, record)
+			isFlushing = retry == nil && len(batch) >= b.config.BatchSize
+		case <-ticker.C:
+			isFlushing = retry == nil
+		case <-retry:
+			isFlushing = true
+		case <-b.stop:
+			for len(b.queue) > 0 {
+				batch = append(batch, <-b.queue)
+			}
+			batch, _ = b.flush(batch, backoff)
+			atomic.AddUint64(&b.failed, uint64(len(batch)))
+			return
+		}
+
+		if isFlushing {
+			batch, backoff = b.flush(batch, backoff)
+			retry = nil
+			if backoff > 0 {
+				retry = time.After(backoff)
+			}
+		}
+	}
+}
+
+// flush sends the batch in sends of BatchSize records until one fails, it returns the records to keep
+// for the next attempt and the backoff before it
+func (b *BufferedSink) flush(batch []*casvisorsdk.Record, backoff time.Duration) ([]*casvisorsdk.Record, time.Duration) {
+	var err error
+	for len(batch) > 0 {
+		size := len(batch)
+		if size > b.config.BatchSize {
+			size = b.config.BatchSize
+		}
+
+		err = b.sink.Send(batch[:size])
+		if err != nil {
+			// the records delivered before the failure are not sent again
+			var partialErr *PartialSendError
+			if errors.As(err, &partialErr) && partialErr.Sent > 0 && partialErr.Sent <= size {
+				atomic.AddUint64(&b.sent, uint64(partialErr.Sent))
+				batch = batch[partialErr.Sent:]
+			}
+			break
+		}
+
+		atomic.AddUint64(&b.sent, uint64(size))
+		batch = batch[size:]
+	}
+	if err == nil {
+		return batch, 0
+	}
+
+	b.setLastError(err)
+	logs.Error(fmt.Sprintf("audit sink %s error: %s", b.config.Name, err.Error()))
+
+	backoff *= 2
+	if backoff < minRetryBackoff {
+		backoff = minRetryBackoff
+	}
+	if backoff > maxRetryBackoff {
+		backoff = maxRetryBackoff
+	}
+	return batch, backoff
+}
+
+func (b *BufferedSink) setLastError(err error) {
+	b.lastErrorMutex.Lock()
+	defer b.lastErrorMutex.Unlock()
+	b.lastError = err.Error()
+}
+
+func (b *BufferedSink) Close() error {
+	close(b.stop)
+	<-b.done
+	return b.sink.Close()
+}
+
+func (b *BufferedSink) GetStatus() *SinkStatus {
+	b.lastErrorMutex.Lock()
+	lastError := b.lastError
+	b.lastErrorMutex.Unlock()
+
+	return &SinkStatus{
+		Name:      b.config.Name,
+		Type:      b.config.Type,
+		Queued:    len(b.queue),
+		Capacity:  b.config.BufferSize,
+		Sent:      atomic.LoadUint64(&b.sent),
+		Dropped:   atomic.LoadUint64(&b.dropped),
+		Failed:    atomic.LoadUint64(&b.failed),
+		LastError: lastError,
+	}
+}
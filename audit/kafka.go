@@ -0,0 +1,90 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/casvisor/casvisor-go-sdk/casvisorsdk"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl/plain"
+)
+
+// KafkaSink produces records as JSON messages to a Kafka-protocol broker,
+// keyed by organization so that the records of one organization stay ordered.
+// The endpoint is a comma-separated broker list, prefixed with "tls://" to enable TLS.
+type KafkaSink struct {
+	writer *kafka.Writer
+}
+
+func NewKafkaSink(config *SinkConfig) (*KafkaSink, error) {
+	if config.Topic == "" {
+		return nil, fmt.Errorf("the topic of the Kafka audit sink: %s should not be empty", config.Name)
+	}
+
+	endpoint := config.Endpoint
+	transport := &kafka.Transport{DialTimeout: 10 * time.Second}
+	if strings.HasPrefix(endpoint, "tls://") {
+		endpoint = strings.TrimPrefix(endpoint, "tls://")
+		transport.TLS = getTlsConfig(config)
+	}
+	if config.Username != "" {
+		transport.SASL = plain.Mechanism{Username: config.Username, Password: config.Password}
+	}
+
+	brokers := strings.Split(endpoint, ",")
+	if endpoint == "" || len(brokers) == 0 {
+		return nil, fmt.Errorf("the brokers of the Kafka audit sink: %s should not be empty", config.Name)
+	}
+
+	writer := &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        config.Topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		WriteTimeout: 10 * time.Second,
+		Transport:    transport,
+	}
+
+	return &KafkaSink{writer: writer}, nil
+}
+
+func (s *KafkaSink) Send(records []*casvisorsdk.Record) error {
+	messages := []kafka.Message{}
+	for _, record := range records {
+		value, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+
+		messages = append(messages, kafka.Message{
+			Key:   []byte(record.Organization),
+			Value: value,
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	return s.writer.WriteMessages(ctx, messages...)
+}
+
+func (s *KafkaSink) Close() error {
+	return s.writer.Close()
+}
@@ -0,0 +1,190 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/casvisor/casvisor-go-sdk/casvisorsdk"
+)
+
+type otlpAnyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano         string         `json:"timeUnixNano"`
+	ObservedTimeUnixNano string         `json:"observedTimeUnixNano"`
+	SeverityNumber       int            `json:"severityNumber"`
+	SeverityText         string         `json:"severityText"`
+	Body                 otlpAnyValue   `json:"body"`
+	Attributes           []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeLogs struct {
+	Scope      map[string]string `json:"scope"`
+	LogRecords []*otlpLogRecord  `json:"logRecords"`
+}
+
+type otlpResourceLogs struct {
+	Resource  map[string][]otlpKeyValue `json:"resource"`
+	ScopeLogs []*otlpScopeLogs          `json:"scopeLogs"`
+}
+
+type otlpLogsRequest struct {
+	ResourceLogs []*otlpResourceLogs `json:"resourceLogs"`
+}
+
+// OtlpSink exports records as OpenTelemetry logs with the OTLP/HTTP JSON encoding.
+type OtlpSink struct {
+	config *SinkConfig
+	url    string
+	client *http.Client
+}
+
+func NewOtlpSink(config *SinkConfig) (*OtlpSink, error) {
+	u, err := url.Parse(config.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported OTLP endpoint scheme: %s, should be http or https", u.Scheme)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/logs"
+	}
+
+	client := &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: getTlsConfig(config)},
+	}
+
+	return &OtlpSink{
+		config: config,
+		url:    u.String(),
+		client: client,
+	}, nil
+}
+
+func getOtlpLogRecord(record *casvisorsdk.Record) (*otlpLogRecord, error) {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	createdTime, err := time.Parse(time.RFC3339, record.CreatedTime)
+	if err != nil {
+		createdTime = now
+	}
+
+	severityNumber, severityText := 9, "INFO"
+	if IsRecordFailed(record) {
+		severityNumber, severityText = 13, "WARN"
+	}
+
+	attributes := []otlpKeyValue{}
+	for _, kv := range [][]string{
+		{"casdoor.record.name", record.Name},
+		{"casdoor.organization", record.Organization},
+		{"enduser.id", record.User},
+		{"client.address", record.ClientIp},
+		{"http.request.method", record.Method},
+		{"url.path", record.RequestUri},
+		{"casdoor.action", record.Action},
+	} {
+		attributes = append(attributes, otlpKeyValue{Key: kv[0], Value: otlpAnyValue{StringValue: kv[1]}})
+	}
+
+	return &otlpLogRecord{
+		TimeUnixNano:         fmt.Sprintf("%d", createdTime.UnixNano()),
+		ObservedTimeUnixNano: fmt.Sprintf("%d", now.UnixNano()),
+		SeverityNumber:       severityNumber,
+		SeverityText:         severityText,
+		Body:                 otlpAnyValue{StringValue: string(body)},
+		Attributes:           attributes,
+	}, nil
+}
+
+func (s *OtlpSink) Send(records []*casvisorsdk.Record) error {
+	logRecords := []*otlpLogRecord{}
+	for _, record := range records {
+		logRecord, err := getOtlpLogRecord(record)
+		if err != nil {
+			return err
+		}
+		logRecords = append(logRecords, logRecord)
+	}
+
+	request := &otlpLogsRequest{
+		ResourceLogs: []*otlpResourceLogs{
+			{
+				Resource: map[string][]otlpKeyValue{
+					"attributes": {{Key: "service.name", Value: otlpAnyValue{StringValue: "casdoor"}}},
+				},
+				ScopeLogs: []*otlpScopeLogs{
+					{
+						Scope:      map[string]string{"name": "casdoor.audit"},
+						LogRecords: logRecords,
+					},
+				},
+			},
+		},
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range s.config.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 300))
+		return fmt.Errorf("OTLP endpoint returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	return nil
+}
+
+func (s *OtlpSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,92 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+
+	"github.com/casvisor/casvisor-go-sdk/casvisorsdk"
+)
+
+// Sink delivers a batch of records to an external audit system.
+// A Send failing after delivering the first records of the batch returns a *PartialSendError.
+type Sink interface {
+	Send(records []*casvisorsdk.Record) error
+	Close() error
+}
+
+// PartialSendError is the error of a Send that has delivered the first Sent records, they are not sent again
+type PartialSendError struct {
+	Sent int
+	Err  error
+}
+
+func (e *PartialSendError) Error() string {
+	return fmt.Sprintf("%d records sent: %s", e.Sent, e.Err.Error())
+}
+
+func (e *PartialSendError) Unwrap() error {
+	return e.Err
+}
+
+type SinkConfig struct {
+	Name               string            `json:"name"`
+	Type               string            `json:"type"`
+	Endpoint           string            `json:"endpoint"`
+	Topic              string            `json:"topic"`
+	Username           string            `json:"username"`
+	Password           string            `json:"password"`
+	Headers            map[string]string `json:"headers"`
+	InsecureSkipVerify bool              `json:"insecureSkipVerify"`
+
+	BufferSize      int    `json:"bufferSize"`
+	BatchSize       int    `json:"batchSize"`
+	FlushIntervalMs int    `json:"flushIntervalMs"`
+	Overflow        string `json:"overflow"`
+	BlockTimeoutMs  int    `json:"blockTimeoutMs"`
+}
+
+const (
+	SinkTypeSyslog = "syslog"
+	SinkTypeOtlp   = "otlp"
+	SinkTypeKafka  = "kafka"
+
+	OverflowDrop  = "drop"
+	OverflowBlock = "block"
+)
+
+func NewSink(config *SinkConfig) (Sink, error) {
+	switch config.Type {
+	case SinkTypeSyslog:
+		return NewSyslogSink(config)
+	case SinkTypeOtlp:
+		return NewOtlpSink(config)
+	case SinkTypeKafka:
+		return NewKafkaSink(config)
+	}
+
+	return nil, fmt.Errorf("unsupported audit sink type: %s", config.Type)
+}
+
+func getTlsConfig(config *SinkConfig) *tls.Config {
+	return &tls.Config{InsecureSkipVerify: config.InsecureSkipVerify}
+}
+
+// IsRecordFailed tells whether the record is of a failed request, from its status code or its response
+func IsRecordFailed(record *casvisorsdk.Record) bool {
+	return record.StatusCode >= 400 || strings.Contains(record.Response, "status:\"error\"")
+}
@@ -0,0 +1,198 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/casvisor/casvisor-go-sdk/casvisorsdk"
+)
+
+const (
+	// facility 13 is "log audit" in RFC 5424
+	syslogFacility        = 13
+	syslogSeverityInfo    = 6
+	syslogSeverityWarning = 4
+	syslogEnterpriseId    = "casdoor@32473"
+)
+
+var syslogParamReplacer = strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "]", "\\]")
+
+// SyslogSink sends RFC 5424 messages with octet-counting framing (RFC 6587)
+// over TCP, or over TLS (RFC 5425) when the endpoint scheme is "tls".
+type SyslogSink struct {
+	config   *SinkConfig
+	network  string
+	address  string
+	hostname string
+	conn     net.Conn
+}
+
+func NewSyslogSink(config *SinkConfig) (*SyslogSink, error) {
+	u, err := url.Parse(config.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "tcp" && u.Scheme != "tls" {
+		return nil, fmt.Errorf("unsupported syslog endpoint scheme: %s, should be tcp or tls", u.Scheme)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+
+	return &SyslogSink{
+		config:   config,
+		network:  u.Scheme,
+		address:  u.Host,
+		hostname: hostname,
+	}, nil
+}
+
+func (s *SyslogSink) connect() error {
+	if s.conn != nil {
+		return nil
+	}
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+
+	var err error
+	if s.network == "tls" {
+		s.conn, err = tls.DialWithDialer(dialer, "tcp", s.address, getTlsConfig(s.config))
+	} else {
+		s.conn, err = dialer.Dial("tcp", s.address)
+	}
+	return err
+}
+
+func getSyslogToken(s string, maxLength int) string {
+	res := strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return '_'
+		}
+		return r
+	}, s)
+
+	if res == "" {
+		return "-"
+	}
+	if len(res) > maxLength {
+		res = res[:maxLength]
+	}
+	return res
+}
+
+func getSyslogMessage(record *casvisorsdk.Record, hostname string) (string, error) {
+	severity := syslogSeverityInfo
+	if IsRecordFailed(record) {
+		severity = syslogSeverityWarning
+	}
+
+	timestamp := record.CreatedTime
+	if timestamp == "" {
+		timestamp = time.Now().Format(time.RFC3339)
+	}
+
+	params := [][]string{
+		{"organization", record.Organization},
+		{"user", record.User},
+		{"clientIp", record.ClientIp},
+		{"method", record.Method},
+		{"requestUri", record.RequestUri},
+		{"statusCode", fmt.Sprintf("%d", record.StatusCode)},
+	}
+	structuredData := "[" + syslogEnterpriseId
+	for _, param := range params {
+		structuredData += fmt.Sprintf(" %s=\"%s\"", param[0], syslogParamReplacer.Replace(param[1]))
+	}
+	structuredData += "]"
+
+	msg, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("<%d>1 %s %s casdoor - %s %s %s",
+		syslogFacility*8+severity,
+		timestamp,
+		getSyslogToken(hostname, 255),
+		getSyslogToken(record.Action, 32),
+		structuredData,
+		msg,
+	), nil
+}
+
+func (s *SyslogSink) Send(records []*casvisorsdk.Record) error {
+	err := s.connect()
+	if err != nil {
+		return err
+	}
+
+	var builder strings.Builder
+	// ends are the offsets of the end of the frame of each record
+	ends := []int{}
+	for _, record := range records {
+		message, err := getSyslogMessage(record, s.hostname)
+		if err != nil {
+			return err
+		}
+
+		builder.WriteString(fmt.Sprintf("%d %s", len(message), message))
+		ends = append(ends, builder.Len())
+	}
+
+	err = s.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	if err != nil {
+		return err
+	}
+
+	n, err := s.conn.Write([]byte(builder.String()))
+	if err != nil {
+		// reconnect on the next attempt
+		s.conn.Close()
+		s.conn = nil
+
+		// the records whose frames have been written are not sent again, a truncated frame is
+		sent := getSyslogSentCount(ends, n)
+		if sent > 0 {
+			return &PartialSendError{Sent: sent, Err: err}
+		}
+	}
+	return err
+}
+
+// getSyslogSentCount returns the count of the frames ending at the given offsets that fit in the written bytes
+func getSyslogSentCount(ends []int, written int) int {
+	sent := 0
+	for sent < len(ends) && ends[sent] <= written {
+		sent++
+	}
+	return sent
+}
+
+func (s *SyslogSink) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
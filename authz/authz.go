@@ -63,6 +63,8 @@ p, *, *, GET, /api/get-user, *, *
 p, *, *, GET, /api/get-user-application, *, *
 p, *, *, GET, /api/get-resources, *, *
 p, *, *, GET, /api/get-records, *, *
+p, *, *, GET, /api/get-change-events, *, *
+p, *, *, GET, /api/stream-change-events, *, *
 p, *, *, GET, /api/get-product, *, *
 p, *, *, POST, /api/buy-product, *, *
 p, *, *, GET, /api/get-payment, *, *
@@ -77,6 +79,15 @@ p, *, *, POST, /api/verify-captcha, *, *
 p, *, *, POST, /api/verify-code, *, *
 p, *, *, POST, /api/reset-email-or-phone, *, *
 p, *, *, POST, /api/upload-resource, *, *
+p, *, *, POST, /api/request-elevation, *, *
+p, *, *, GET, /api/get-access-request, *, *
+p, *, *, GET, /api/get-my-access-requests, *, *
+p, *, *, GET, /api/get-pending-access-requests, *, *
+p, *, *, POST, /api/submit-access-request, *, *
+p, *, *, POST, /api/review-access-request, *, *
+p, *, *, POST, /api/cancel-access-request, *, *
+p, *, *, GET, /api/get-my-access-review-items, *, *
+p, *, *, POST, /api/decide-access-review-item, *, *
 p, *, *, GET, /.well-known/openid-configuration, *, *
 p, *, *, GET, /.well-known/webfinger, *, *
 p, *, *, *, /.well-known/jwks, *, *
@@ -84,9 +95,13 @@ p, *, *, GET, /api/get-saml-login, *, *
 p, *, *, POST, /api/acs, *, *
 p, *, *, GET, /api/saml/metadata, *, *
 p, *, *, *, /api/saml/redirect, *, *
+p, *, *, *, /api/saml/slo, *, *
+p, *, *, POST, /api/saml/artifact, *, *
+p, *, *, GET, /api/get-saml-idps, *, *
 p, *, *, *, /cas, *, *
 p, *, *, *, /scim, *, *
 p, *, *, *, /api/webauthn, *, *
+p, *, *, *, /api/mfa/push, *, *
 p, *, *, GET, /api/get-release, *, *
 p, *, *, GET, /api/get-default-application, *, *
 p, *, *, GET, /api/get-prometheus-info, *, *
@@ -0,0 +1,246 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/beego/beego/utils/pagination"
+	"github.com/casdoor/casdoor/object"
+	"github.com/casdoor/casdoor/util"
+)
+
+// GetAccessRequests
+// @Title GetAccessRequests
+// @Tag Access Request API
+// @Description get access requests
+// @Param   owner     query    string  built-in/admin	true        "The owner of access requests"
+// @Success 200 {array} object.AccessRequest The Response object
+// @router /get-access-requests [get]
+// @Security test_apiKey
+func (c *ApiController) GetAccessRequests() {
+	owner := c.Input().Get("owner")
+	limit := c.Input().Get("pageSize")
+	page := c.Input().Get("p")
+	field := c.Input().Get("field")
+	value := c.Input().Get("value")
+	sortField := c.Input().Get("sortField")
+	sortOrder := c.Input().Get("sortOrder")
+
+	if limit == "" || page == "" {
+		requests, err := object.GetAccessRequests(owner)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+
+		c.ResponseOk(requests)
+	} else {
+		limit := util.ParseInt(limit)
+		count, err := object.GetAccessRequestCount(owner, field, value)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+
+		paginator := pagination.SetPaginator(c.Ctx, limit, count)
+
+		requests, err := object.GetPaginationAccessRequests(owner, paginator.Offset(), limit, field, value, sortField, sortOrder)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+
+		c.ResponseOk(requests, paginator.Nums())
+	}
+}
+
+// getAccessRequest returns the request of the id parameter, if the signed-in user is its requester,
+// one of its approvers or an admin of its organization
+func (c *ApiController) getAccessRequest() (*object.AccessRequest, *object.User, bool) {
+	user, ok := c.RequireSignedInUser()
+	if !ok {
+		return nil, nil, false
+	}
+
+	id := c.Input().Get("id")
+	request, err := object.GetAccessRequest(id)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return nil, nil, false
+	}
+	if request == nil {
+		c.ResponseError(fmt.Sprintf(c.T("general:The access request: %s doesn't exist"), id))
+		return nil, nil, false
+	}
+
+	userId := user.GetId()
+	isOrgAdmin := user.Owner == "built-in" || (user.IsAdmin && user.Owner == request.Owner)
+	if !isOrgAdmin && request.User != userId && !util.InSlice(request.Approvers, userId) {
+		c.ResponseError(c.T("auth:Unauthorized operation"))
+		return nil, nil, false
+	}
+
+	return request, user, true
+}
+
+// GetAccessRequest
+// @Title GetAccessRequest
+// @Tag Access Request API
+// @Description get access request with its history
+// @Param   id     query    string  built-in/admin	true        "The id ( owner/name ) of the access request"
+// @Success 200 {object} object.AccessRequest The Response object
+// @router /get-access-request [get]
+func (c *ApiController) GetAccessRequest() {
+	request, _, ok := c.getAccessRequest()
+	if !ok {
+		return
+	}
+
+	c.ResponseOk(request)
+}
+
+// GetMyAccessRequests
+// @Title GetMyAccessRequests
+// @Tag Access Request API
+// @Description get the access requests submitted by the signed-in user
+// @Success 200 {array} object.AccessRequest The Response object
+// @router /get-my-access-requests [get]
+func (c *ApiController) GetMyAccessRequests() {
+	user, ok := c.RequireSignedInUser()
+	if !ok {
+		return
+	}
+
+	requests, err := object.GetAccessRequestsByUser(user.GetId())
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.ResponseOk(requests)
+}
+
+// GetPendingAccessRequests
+// @Title GetPendingAccessRequests
+// @Tag Access Request API
+// @Description get the access requests waiting for the approval of the signed-in user
+// @Success 200 {array} object.AccessRequest The Response object
+// @router /get-pending-access-requests [get]
+func (c *ApiController) GetPendingAccessRequests() {
+	user, ok := c.RequireSignedInUser()
+	if !ok {
+		return
+	}
+
+	requests, err := object.GetAccessRequestsByApprover(user.GetId())
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.ResponseOk(requests)
+}
+
+// SubmitAccessRequest
+// @Title SubmitAccessRequest
+// @Tag Access Request API
+// @Description request a role, a group or a permission for the signed-in user
+// @Param   body    body   object.AccessRequest  true        "The target type, target, reason and duration of the request"
+// @Success 200 {object} object.AccessRequest The Response object
+// @router /submit-access-request [post]
+func (c *ApiController) SubmitAccessRequest() {
+	user, ok := c.RequireSignedInUser()
+	if !ok {
+		return
+	}
+
+	var request object.AccessRequest
+	err := json.Unmarshal(c.Ctx.Input.RequestBody, &request)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	res, err := object.SubmitAccessRequest(user, &request)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.ResponseOk(res)
+}
+
+// ReviewAccessRequest
+// @Title ReviewAccessRequest
+// @Tag Access Request API
+// @Description approve the current step of an access request, or deny the request
+// @Param   id     query    string  built-in/admin	true        "The id ( owner/name ) of the access request"
+// @Param   action     query    string  true        "approve or deny"
+// @Param   comment     query    string  false        "The comment of the review"
+// @Success 200 {object} controllers.Response The Response object
+// @router /review-access-request [post]
+func (c *ApiController) ReviewAccessRequest() {
+	request, user, ok := c.getAccessRequest()
+	if !ok {
+		return
+	}
+
+	action := c.Input().Get("action")
+	if action != "approve" && action != "deny" {
+		c.ResponseError(fmt.Sprintf(c.T("general:Unknown action: %s"), action))
+		return
+	}
+
+	c.Data["json"] = wrapActionResponse(object.ReviewAccessRequest(request, user, action == "approve", c.Input().Get("comment")))
+	c.ServeJSON()
+}
+
+// CancelAccessRequest
+// @Title CancelAccessRequest
+// @Tag Access Request API
+// @Description cancel a pending access request of the signed-in user
+// @Param   id     query    string  built-in/admin	true        "The id ( owner/name ) of the access request"
+// @Success 200 {object} controllers.Response The Response object
+// @router /cancel-access-request [post]
+func (c *ApiController) CancelAccessRequest() {
+	request, user, ok := c.getAccessRequest()
+	if !ok {
+		return
+	}
+
+	c.Data["json"] = wrapActionResponse(object.CancelAccessRequest(request, user))
+	c.ServeJSON()
+}
+
+// DeleteAccessRequest
+// @Title DeleteAccessRequest
+// @Tag Access Request API
+// @Description delete access request
+// @Param   body    body   object.AccessRequest  true        "The details of the access request"
+// @Success 200 {object} controllers.Response The Response object
+// @router /delete-access-request [post]
+func (c *ApiController) DeleteAccessRequest() {
+	var request object.AccessRequest
+	err := json.Unmarshal(c.Ctx.Input.RequestBody, &request)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.Data["json"] = wrapActionResponse(object.DeleteAccessRequest(&request))
+	c.ServeJSON()
+}
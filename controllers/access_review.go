@@ -0,0 +1,305 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/beego/beego/utils/pagination"
+	"github.com/casdoor/casdoor/object"
+	"github.com/casdoor/casdoor/util"
+)
+
+// GetAccessReviews
+// @Title GetAccessReviews
+// @Tag Access Review API
+// @Description get access reviews
+// @Param   owner     query    string  built-in/admin	true        "The owner of access reviews"
+// @Success 200 {array} object.AccessReview The Response object
+// @router /get-access-reviews [get]
+// @Security test_apiKey
+func (c *ApiController) GetAccessReviews() {
+	owner := c.Input().Get("owner")
+	limit := c.Input().Get("pageSize")
+	page := c.Input().Get("p")
+	field := c.Input().Get("field")
+	value := c.Input().Get("value")
+	sortField := c.Input().Get("sortField")
+	sortOrder := c.Input().Get("sortOrder")
+
+	if limit == "" || page == "" {
+		reviews, err := object.GetAccessReviews(owner)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+
+		c.ResponseOk(reviews)
+	} else {
+		limit := util.ParseInt(limit)
+		count, err := object.GetAccessReviewCount(owner, field, value)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+
+		paginator := pagination.SetPaginator(c.Ctx, limit, count)
+
+		reviews, err := object.GetPaginationAccessReviews(owner, paginator.Offset(), limit, field, value, sortField, sortOrder)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+
+		c.ResponseOk(reviews, paginator.Nums())
+	}
+}
+
+// GetAccessReview
+// @Title GetAccessReview
+// @Tag Access Review API
+// @Description get access review
+// @Param   id     query    string  built-in/admin	true        "The id ( owner/name ) of the access review"
+// @Success 200 {object} object.AccessReview The Response object
+// @router /get-access-review [get]
+func (c *ApiController) GetAccessReview() {
+	id := c.Input().Get("id")
+
+	review, err := object.GetAccessReview(id)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.ResponseOk(review)
+}
+
+// UpdateAccessReview
+// @Title UpdateAccessReview
+// @Tag Access Review API
+// @Description update access review
+// @Param   id     query    string  built-in/admin true        "The id ( owner/name ) of the access review"
+// @Param   body    body   object.AccessReview  true        "The details of the access review"
+// @Success 200 {object} controllers.Response The Response object
+// @router /update-access-review [post]
+func (c *ApiController) UpdateAccessReview() {
+	id := c.Input().Get("id")
+
+	var review object.AccessReview
+	err := json.Unmarshal(c.Ctx.Input.RequestBody, &review)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.Data["json"] = wrapActionResponse(object.UpdateAccessReview(id, &review))
+	c.ServeJSON()
+}
+
+// AddAccessReview
+// @Title AddAccessReview
+// @Tag Access Review API
+// @Description add access review
+// @Param   body    body   object.AccessReview  true        "The details of the access review"
+// @Success 200 {object} controllers.Response The Response object
+// @router /add-access-review [post]
+func (c *ApiController) AddAccessReview() {
+	var review object.AccessReview
+	err := json.Unmarshal(c.Ctx.Input.RequestBody, &review)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.Data["json"] = wrapActionResponse(object.AddAccessReview(&review))
+	c.ServeJSON()
+}
+
+// DeleteAccessReview
+// @Title DeleteAccessReview
+// @Tag Access Review API
+// @Description delete access review
+// @Param   body    body   object.AccessReview  true        "The details of the access review"
+// @Success 200 {object} controllers.Response The Response object
+// @router /delete-access-review [post]
+func (c *ApiController) DeleteAccessReview() {
+	var review object.AccessReview
+	err := json.Unmarshal(c.Ctx.Input.RequestBody, &review)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.Data["json"] = wrapActionResponse(object.DeleteAccessReview(&review))
+	c.ServeJSON()
+}
+
+func (c *ApiController) getAccessReview() (*object.AccessReview, bool) {
+	id := c.Input().Get("id")
+	review, err := object.GetAccessReview(id)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return nil, false
+	}
+	if review == nil {
+		c.ResponseError(fmt.Sprintf(c.T("general:The access review: %s doesn't exist"), id))
+		return nil, false
+	}
+
+	return review, true
+}
+
+// StartAccessReview
+// @Title StartAccessReview
+// @Tag Access Review API
+// @Description snapshot the access under review and notify the reviewers
+// @Param   id     query    string  built-in/admin true        "The id ( owner/name ) of the access review"
+// @Success 200 {object} controllers.Response The Response object
+// @router /start-access-review [post]
+func (c *ApiController) StartAccessReview() {
+	review, ok := c.getAccessReview()
+	if !ok {
+		return
+	}
+
+	c.Data["json"] = wrapActionResponse(object.StartAccessReview(review))
+	c.ServeJSON()
+}
+
+// CompleteAccessReview
+// @Title CompleteAccessReview
+// @Tag Access Review API
+// @Description take the undecided action for the pending items and close the access review
+// @Param   id     query    string  built-in/admin true        "The id ( owner/name ) of the access review"
+// @Success 200 {object} controllers.Response The Response object
+// @router /complete-access-review [post]
+func (c *ApiController) CompleteAccessReview() {
+	review, ok := c.getAccessReview()
+	if !ok {
+		return
+	}
+
+	c.Data["json"] = wrapActionResponse(object.CompleteAccessReview(review))
+	c.ServeJSON()
+}
+
+// ExportAccessReview
+// @Title ExportAccessReview
+// @Tag Access Review API
+// @Description export the evidence report of the access review
+// @Param   id     query    string  built-in/admin true        "The id ( owner/name ) of the access review"
+// @Param   format     query    string  false        "The export format: xlsx or csv"
+// @Success 200 {string} string The evidence report
+// @router /export-access-review [get]
+func (c *ApiController) ExportAccessReview() {
+	review, ok := c.getAccessReview()
+	if !ok {
+		return
+	}
+
+	format := c.Input().Get("format")
+	if format == "" {
+		format = "xlsx"
+	}
+
+	data, err := object.ExportAccessReview(review, format)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	contentType := "text/csv"
+	if format == "xlsx" {
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+
+	c.Ctx.Output.Header("Content-Disposition", fmt.Sprintf("attachment; filename=access-review-%s.%s", review.Name, format))
+	c.Ctx.Output.ContentType(contentType)
+	c.Ctx.Output.Body(data)
+}
+
+// GetAccessReviewItems
+// @Title GetAccessReviewItems
+// @Tag Access Review API
+// @Description get the items of the access review
+// @Param   id     query    string  built-in/admin true        "The id ( owner/name ) of the access review"
+// @Success 200 {array} object.AccessReviewItem The Response object
+// @router /get-access-review-items [get]
+func (c *ApiController) GetAccessReviewItems() {
+	review, ok := c.getAccessReview()
+	if !ok {
+		return
+	}
+
+	items, err := object.GetAccessReviewItems(review)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.ResponseOk(items)
+}
+
+// GetMyAccessReviewItems
+// @Title GetMyAccessReviewItems
+// @Tag Access Review API
+// @Description get the pending access review items of the signed-in reviewer
+// @Success 200 {array} object.AccessReviewItem The Response object
+// @router /get-my-access-review-items [get]
+func (c *ApiController) GetMyAccessReviewItems() {
+	user, ok := c.RequireSignedInUser()
+	if !ok {
+		return
+	}
+
+	items, err := object.GetAccessReviewItemsByReviewer(user.GetId())
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.ResponseOk(items)
+}
+
+// DecideAccessReviewItem
+// @Title DecideAccessReviewItem
+// @Tag Access Review API
+// @Description keep or revoke the access of an access review item
+// @Param   id     query    string  built-in/admin true        "The id ( owner/name ) of the access review item"
+// @Param   decision     query    string  true        "Keep or Revoke"
+// @Param   comment     query    string  false        "The comment of the decision"
+// @Success 200 {object} controllers.Response The Response object
+// @router /decide-access-review-item [post]
+func (c *ApiController) DecideAccessReviewItem() {
+	user, ok := c.RequireSignedInUser()
+	if !ok {
+		return
+	}
+
+	id := c.Input().Get("id")
+	item, err := object.GetAccessReviewItem(id)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+	if item == nil {
+		c.ResponseError(fmt.Sprintf(c.T("general:The access review item: %s doesn't exist"), id))
+		return
+	}
+
+	c.Data["json"] = wrapActionResponse(object.DecideAccessReviewItem(item, user, c.Input().Get("decision"), c.Input().Get("comment")))
+	c.ServeJSON()
+}
@@ -0,0 +1,147 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"encoding/json"
+
+	"github.com/beego/beego/utils/pagination"
+	"github.com/casdoor/casdoor/object"
+	"github.com/casdoor/casdoor/util"
+)
+
+// GetAccessWorkflows
+// @Title GetAccessWorkflows
+// @Tag Access Workflow API
+// @Description get access workflows
+// @Param   owner     query    string  built-in/admin	true        "The owner of access workflows"
+// @Success 200 {array} object.AccessWorkflow The Response object
+// @router /get-access-workflows [get]
+// @Security test_apiKey
+func (c *ApiController) GetAccessWorkflows() {
+	owner := c.Input().Get("owner")
+	limit := c.Input().Get("pageSize")
+	page := c.Input().Get("p")
+	field := c.Input().Get("field")
+	value := c.Input().Get("value")
+	sortField := c.Input().Get("sortField")
+	sortOrder := c.Input().Get("sortOrder")
+
+	if limit == "" || page == "" {
+		workflows, err := object.GetAccessWorkflows(owner)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+
+		c.ResponseOk(workflows)
+	} else {
+		limit := util.ParseInt(limit)
+		count, err := object.GetAccessWorkflowCount(owner, field, value)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+
+		paginator := pagination.SetPaginator(c.Ctx, limit, count)
+
+		workflows, err := object.GetPaginationAccessWorkflows(owner, paginator.Offset(), limit, field, value, sortField, sortOrder)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+
+		c.ResponseOk(workflows, paginator.Nums())
+	}
+}
+
+// GetAccessWorkflow
+// @Title GetAccessWorkflow
+// @Tag Access Workflow API
+// @Description get access workflow
+// @Param   id     query    string  built-in/admin	true        "The id ( owner/name ) of the access workflow"
+// @Success 200 {object} object.AccessWorkflow The Response object
+// @router /get-access-workflow [get]
+func (c *ApiController) GetAccessWorkflow() {
+	id := c.Input().Get("id")
+
+	workflow, err := object.GetAccessWorkflow(id)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.ResponseOk(workflow)
+}
+
+// UpdateAccessWorkflow
+// @Title UpdateAccessWorkflow
+// @Tag Access Workflow API
+// @Description update access workflow
+// @Param   id     query    string  built-in/admin true        "The id ( owner/name ) of the access workflow"
+// @Param   body    body   object.AccessWorkflow  true        "The details of the access workflow"
+// @Success 200 {object} controllers.Response The Response object
+// @router /update-access-workflow [post]
+func (c *ApiController) UpdateAccessWorkflow() {
+	id := c.Input().Get("id")
+
+	var workflow object.AccessWorkflow
+	err := json.Unmarshal(c.Ctx.Input.RequestBody, &workflow)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.Data["json"] = wrapActionResponse(object.UpdateAccessWorkflow(id, &workflow))
+	c.ServeJSON()
+}
+
+// AddAccessWorkflow
+// @Title AddAccessWorkflow
+// @Tag Access Workflow API
+// @Description add access workflow
+// @Param   body    body   object.AccessWorkflow  true        "The details of the access workflow"
+// @Success 200 {object} controllers.Response The Response object
+// @router /add-access-workflow [post]
+func (c *ApiController) AddAccessWorkflow() {
+	var workflow object.AccessWorkflow
+	err := json.Unmarshal(c.Ctx.Input.RequestBody, &workflow)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.Data["json"] = wrapActionResponse(object.AddAccessWorkflow(&workflow))
+	c.ServeJSON()
+}
+
+// DeleteAccessWorkflow
+// @Title DeleteAccessWorkflow
+// @Tag Access Workflow API
+// @Description delete access workflow
+// @Param   body    body   object.AccessWorkflow  true        "The details of the access workflow"
+// @Success 200 {object} controllers.Response The Response object
+// @router /delete-access-workflow [post]
+func (c *ApiController) DeleteAccessWorkflow() {
+	var workflow object.AccessWorkflow
+	err := json.Unmarshal(c.Ctx.Input.RequestBody, &workflow)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.Data["json"] = wrapActionResponse(object.DeleteAccessWorkflow(&workflow))
+	c.ServeJSON()
+}
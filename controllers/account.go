@@ -340,6 +340,7 @@ func (c *ApiController) Logout() {
 			return
 		}
 
+		c.sendCasLogoutRequests()
 		c.ClearUserSession()
 		c.ClearTokenSession()
 		owner, username := util.GetOwnerAndNameFromId(user)
@@ -387,6 +388,7 @@ func (c *ApiController) Logout() {
 			user = util.GetId(token.Organization, token.User)
 		}
 
+		c.sendCasLogoutRequests()
 		c.ClearUserSession()
 		c.ClearTokenSession()
 		// TODO https://github.com/casdoor/casdoor/pull/1494#discussion_r1095675265
@@ -517,6 +519,28 @@ func (c *ApiController) GetUserinfo() {
 		return
 	}
 
+	encryptedUserinfo := ""
+	if aud != "" {
+		application, err := object.GetApplicationByClientId(aud)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+
+		encryptedUserinfo, err = object.EncryptUserinfo(application, userInfo)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+	}
+
+	if encryptedUserinfo != "" {
+		// https://openid.net/specs/openid-connect-core-1_0.html#UserInfoResponse
+		c.Ctx.Output.Header("Content-Type", "application/jwt")
+		c.Ctx.WriteString(encryptedUserinfo)
+		return
+	}
+
 	c.Data["json"] = userInfo
 	c.ServeJSON()
 }
@@ -62,6 +62,7 @@ func (c *ApiController) HandleLoggedIn(application *object.Application, user *ob
 	}
 
 	userId := user.GetId()
+	c.setMfaUsedSession(false)
 
 	clientIp := util.GetClientIpFromRequest(c.Ctx.Request)
 	err := object.CheckEntryIp(clientIp, user, application, application.OrganizationObj, c.GetAcceptLanguage())
@@ -165,35 +166,50 @@ func (c *ApiController) HandleLoggedIn(application *object.Application, user *ob
 		} else {
 			scope := c.Input().Get("scope")
 			nonce := c.Input().Get("nonce")
-			token, _ := object.GetTokenByUser(application, user, scope, nonce, c.Ctx.Request.Host)
+			token, err := object.GetTokenByUser(application, user, scope, nonce, c.Ctx.Request.Host)
+			if err != nil {
+				c.ResponseError(err.Error(), nil)
+				return
+			}
+
 			resp = tokenToResponse(token)
+			if form.Type == ResponseTypeIdToken {
+				// the ID token is encrypted for the applications which have chosen an encryption algorithm
+				resp.Data = token.IdToken
+			}
 
 			resp.Data2 = user.NeedUpdatePassword
 		}
 	} else if form.Type == ResponseTypeDevice {
-		authCache, ok := object.DeviceAuthMap.LoadAndDelete(form.UserCode)
-		if !ok {
-			c.ResponseError(c.T("auth:UserCode Expired"))
+		authCache, err := object.TakeDeviceAuthCache(form.UserCode)
+		if err != nil {
+			c.ResponseError(err.Error())
 			return
 		}
-
-		authCacheCast := authCache.(object.DeviceAuthCache)
-		if authCacheCast.RequestAt.Add(time.Second * 120).Before(time.Now()) {
+		if authCache == nil {
 			c.ResponseError(c.T("auth:UserCode Expired"))
 			return
 		}
 
-		deviceAuthCacheDeviceCode, ok := object.DeviceAuthMap.Load(authCacheCast.UserName)
-		if !ok {
+		// the user name of the user code cache is the device code
+		deviceAuthCache, err := object.GetDeviceAuthCache(authCache.UserName)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+		if deviceAuthCache == nil {
 			c.ResponseError(c.T("auth:DeviceCode Invalid"))
 			return
 		}
 
-		deviceAuthCacheDeviceCodeCast := deviceAuthCacheDeviceCode.(object.DeviceAuthCache)
-		deviceAuthCacheDeviceCodeCast.UserName = user.Name
-		deviceAuthCacheDeviceCodeCast.UserSignIn = true
+		deviceAuthCache.UserName = user.Name
+		deviceAuthCache.UserSignIn = true
 
-		object.DeviceAuthMap.Store(authCacheCast.UserName, deviceAuthCacheDeviceCodeCast)
+		err = object.StoreDeviceAuthCache(authCache.UserName, deviceAuthCache)
+		if err != nil {
+			c.ResponseError(c.T("auth:UserCode Expired"))
+			return
+		}
 
 		resp = &Response{Status: "ok", Msg: "", Data: userId, Data2: user.NeedUpdatePassword}
 	} else if form.Type == ResponseTypeSaml { // saml flow
@@ -213,7 +229,10 @@ func (c *ApiController) HandleLoggedIn(application *object.Application, user *ob
 		service := c.Input().Get("service")
 		resp = wrapErrorResponse(nil)
 		if service != "" {
-			st, err := object.GenerateCasToken(userId, service)
+			st, err := object.GenerateCasToken(application, userId, service)
+			if err == nil {
+				err = c.addCasSessionTicket(application, service, user.Name, st)
+			}
 			if err != nil {
 				resp = wrapErrorResponse(err)
 			} else {
@@ -297,14 +316,17 @@ func (c *ApiController) GetApplicationLogin() {
 			return
 		}
 	} else if loginType == "device" {
-		deviceAuthCache, ok := object.DeviceAuthMap.Load(userCode)
-		if !ok {
+		deviceAuthCache, err := object.GetDeviceAuthCache(userCode)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+		if deviceAuthCache == nil {
 			c.ResponseError(c.T("auth:UserCode Invalid"))
 			return
 		}
 
-		deviceAuthCacheCast := deviceAuthCache.(object.DeviceAuthCache)
-		application, err = object.GetApplication(deviceAuthCacheCast.ApplicationId)
+		application, err = object.GetApplication(deviceAuthCache.ApplicationId)
 		if err != nil {
 			c.ResponseError(err.Error())
 			return
@@ -1047,11 +1069,21 @@ func (c *ApiController) Login() {
 			return
 		}
 
+		if authForm.MfaType == object.RecoveryCodeType && authForm.RecoveryCode == "" {
+			authForm.RecoveryCode = authForm.Passcode
+			authForm.Passcode = ""
+		}
+
 		if authForm.Passcode != "" {
 			if authForm.MfaType == c.GetSession("verificationCodeType") {
 				c.ResponseError("Invalid multi-factor authentication type")
 				return
 			}
+			// a push is only accepted from the device the user has set up
+			if authForm.MfaType == object.PushType && !user.MfaPushEnabled {
+				c.ResponseError("Invalid multi-factor authentication type")
+				return
+			}
 			user.CountryCode = user.GetCountryCode(user.CountryCode)
 			mfaUtil := object.GetMfaUtil(authForm.MfaType, user.GetMfaProps(authForm.MfaType, false))
 			if mfaUtil == nil {
@@ -1059,6 +1091,17 @@ func (c *ApiController) Login() {
 				return
 			}
 
+			if webAuthnMfa, ok := mfaUtil.(*object.WebAuthnMfa); ok {
+				err = c.prepareWebAuthnMfa(webAuthnMfa, user)
+				if err != nil {
+					c.ResponseError(err.Error())
+					return
+				}
+			}
+			if pushMfa, ok := mfaUtil.(*object.PushMfa); ok {
+				pushMfa.UserId = user.GetId()
+			}
+
 			passed, err := c.checkOrgMasterVerificationCode(user, authForm.Passcode)
 			if err != nil {
 				c.ResponseError(err.Error())
@@ -1103,6 +1146,9 @@ func (c *ApiController) Login() {
 
 		resp = c.HandleLoggedIn(application, user, &authForm)
 		c.setMfaUserSession("")
+		if resp != nil && resp.Status == "ok" {
+			c.setMfaUsedSession(true)
+		}
 
 		c.Ctx.Input.SetParam("recordUserId", user.GetId())
 	} else {
@@ -1165,7 +1211,8 @@ func getDisplayNameForProvider(providerType string, userInfo *idp.UserInfo, defa
 func (c *ApiController) GetSamlLogin() {
 	providerId := c.Input().Get("id")
 	relayState := c.Input().Get("relayState")
-	authURL, method, err := object.GenerateSamlRequest(providerId, relayState, c.Ctx.Request.Host, c.GetAcceptLanguage())
+	entityId := c.Input().Get("entityId")
+	authURL, method, err := object.GenerateSamlRequest(providerId, entityId, relayState, c.Ctx.Request.Host, c.GetAcceptLanguage())
 	if err != nil {
 		c.ResponseError(err.Error())
 		return
@@ -1389,11 +1436,16 @@ func (c *ApiController) DeviceAuth() {
 			c.ServeJSON()
 			return
 		}
-		_, ok := object.DeviceAuthMap.Load(userCode)
-		if !ok {
+		deviceAuthCache, err := object.GetDeviceAuthCache(userCode)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+		if deviceAuthCache == nil {
 			break
 		}
 
+		userCode = util.GetRandomName()
 		generateTime++
 	}
 
@@ -1413,8 +1465,17 @@ func (c *ApiController) DeviceAuth() {
 		RequestAt:     time.Now(),
 	}
 
-	object.DeviceAuthMap.Store(deviceCode, deviceAuthCache)
-	object.DeviceAuthMap.Store(userCode, userAuthCache)
+	err = object.StoreDeviceAuthCache(deviceCode, &deviceAuthCache)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	err = object.StoreDeviceAuthCache(userCode, &userAuthCache)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
 
 	c.Data["json"] = object.GetDeviceAuthResponse(deviceCode, userCode, c.Ctx.Request.Host)
 	c.ServeJSON()
@@ -148,6 +148,7 @@ func (c *ApiController) GetSessionApplication() *object.Application {
 func (c *ApiController) ClearUserSession() {
 	c.SetSessionUsername("")
 	c.SetSessionData(nil)
+	c.setMfaUsedSession(false)
 }
 
 func (c *ApiController) ClearTokenSession() {
@@ -223,6 +224,16 @@ func (c *ApiController) getMfaUserSession() string {
 	return userId.(string)
 }
 
+// setMfaUsedSession records whether the user of the session passed the multi-factor authentication
+func (c *ApiController) setMfaUsedSession(used bool) {
+	c.SetSession(object.MfaSessionUsed, used)
+}
+
+func (c *ApiController) isMfaUsedSession() bool {
+	used, ok := c.GetSession(object.MfaSessionUsed).(bool)
+	return ok && used
+}
+
 func (c *ApiController) setExpireForSession() {
 	timestamp := time.Now().Unix()
 	timestamp += 3600 * 24
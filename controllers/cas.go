@@ -20,8 +20,12 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
+	"github.com/beego/beego"
+	"github.com/beego/beego/logs"
 	"github.com/casdoor/casdoor/object"
+	"github.com/casdoor/casdoor/util"
 )
 
 const (
@@ -40,6 +44,21 @@ func queryUnescape(service string) string {
 	return s
 }
 
+// getCasApplication returns the application of the "/cas/:organization/:application" path, nil if not found
+func (c *RootController) getCasApplication() (*object.Application, error) {
+	organization := c.Ctx.Input.Param(":organization")
+	applicationName := c.Ctx.Input.Param(":application")
+
+	application, err := object.GetApplication(util.GetId("admin", applicationName))
+	if err != nil || application == nil {
+		return nil, err
+	}
+	if application.Organization != organization {
+		return nil, nil
+	}
+	return application, nil
+}
+
 func (c *RootController) CasValidate() {
 	ticket := c.Input().Get("ticket")
 	service := c.Input().Get("service")
@@ -48,10 +67,20 @@ func (c *RootController) CasValidate() {
 		c.Ctx.Output.Body([]byte("no\n"))
 		return
 	}
-	if ok, response, issuedService, _ := object.GetCasTokenByTicket(ticket); ok {
+	application, err := c.getCasApplication()
+	if err != nil || application == nil {
+		c.Ctx.Output.Body([]byte("no\n"))
+		return
+	}
+	token, err := object.GetCasTokenByTicket(application, ticket)
+	if err != nil {
+		c.Ctx.Output.Body([]byte("no\n"))
+		return
+	}
+	if token != nil {
 		// check whether service is the one for which we previously issued token
-		if issuedService == service {
-			c.Ctx.Output.Body([]byte(fmt.Sprintf("yes\n%s\n", response.User)))
+		if token.Service == service {
+			c.Ctx.Output.Body([]byte(fmt.Sprintf("yes\n%s\n", token.AuthenticationSuccess.User)))
 			return
 		}
 	}
@@ -64,6 +93,7 @@ func (c *RootController) CasServiceValidate() {
 	format := c.Input().Get("format")
 	if !strings.HasPrefix(ticket, "ST") {
 		c.sendCasAuthenticationResponseErr(InvalidTicket, fmt.Sprintf("Ticket %s not recognized", ticket), format)
+		return
 	}
 	c.CasP3ProxyValidate()
 }
@@ -79,6 +109,7 @@ func (c *RootController) CasP3ServiceValidate() {
 	format := c.Input().Get("format")
 	if !strings.HasPrefix(ticket, "ST") {
 		c.sendCasAuthenticationResponseErr(InvalidTicket, fmt.Sprintf("Ticket %s not recognized", ticket), format)
+		return
 	}
 	c.CasP3ProxyValidate()
 }
@@ -98,12 +129,27 @@ func (c *RootController) CasP3ProxyValidate() {
 		c.sendCasAuthenticationResponseErr(InvalidRequest, "service and ticket must exist", format)
 		return
 	}
-	ok, response, issuedService, userId := object.GetCasTokenByTicket(ticket)
+	application, err := c.getCasApplication()
+	if err != nil {
+		c.sendCasAuthenticationResponseErr(InternalError, err.Error(), format)
+		return
+	}
+	if application == nil {
+		c.sendCasAuthenticationResponseErr(InvalidRequest, "application not found", format)
+		return
+	}
+
+	token, err := object.GetCasTokenByTicket(application, ticket)
+	if err != nil {
+		c.sendCasAuthenticationResponseErr(InternalError, err.Error(), format)
+		return
+	}
 	// find the token
-	if ok {
+	if token != nil {
+		issuedService := token.Service
 		// check whether service is the one for which we previously issued token
 		if strings.HasPrefix(service, issuedService) || strings.HasPrefix(queryUnescape(service), issuedService) {
-			serviceResponse.Success = response
+			serviceResponse.Success = token.AuthenticationSuccess
 		} else {
 			// service not match
 			c.sendCasAuthenticationResponseErr(InvalidService, fmt.Sprintf("service %s and %s does not match", service, issuedService), format)
@@ -117,9 +163,11 @@ func (c *RootController) CasP3ProxyValidate() {
 
 	if pgtUrl != "" && serviceResponse.Failure == nil {
 		// that means we are in proxy web flow
-		pgt := object.StoreCasTokenForPgt(serviceResponse.Success, service, userId)
-		pgtiou := serviceResponse.Success.ProxyGrantingTicket
-		// todo: check whether it is https
+		if !application.IsCasProxyCallbackAllowed(token.Service, pgtUrl) {
+			c.sendCasAuthenticationResponseErr(UnauthorizedServiceProxy, fmt.Sprintf("service %s is not allowed to proxy with the callback %s", token.Service, pgtUrl), format)
+			return
+		}
+
 		pgtUrlObj, err := url.Parse(pgtUrl)
 		if err != nil {
 			c.sendCasAuthenticationResponseErr(InvalidProxyCallback, err.Error(), format)
@@ -131,6 +179,13 @@ func (c *RootController) CasP3ProxyValidate() {
 			return
 		}
 
+		pgt, err := object.StoreCasTokenForPgt(application, serviceResponse.Success, service, token.UserId)
+		if err != nil {
+			c.sendCasAuthenticationResponseErr(InternalError, err.Error(), format)
+			return
+		}
+		pgtiou := serviceResponse.Success.ProxyGrantingTicket
+
 		// make a request to pgturl passing pgt and pgtiou
 		param := pgtUrlObj.Query()
 		param.Add("pgtId", pgt)
@@ -144,11 +199,16 @@ func (c *RootController) CasP3ProxyValidate() {
 		}
 
 		resp, err := http.DefaultClient.Do(request)
-		if err != nil || !(resp.StatusCode >= 200 && resp.StatusCode < 400) {
+		if err != nil {
 			// failed to send request
 			c.sendCasAuthenticationResponseErr(InvalidProxyCallback, err.Error(), format)
 			return
 		}
+		resp.Body.Close()
+		if !(resp.StatusCode >= 200 && resp.StatusCode < 400) {
+			c.sendCasAuthenticationResponseErr(InvalidProxyCallback, fmt.Sprintf("callback responded with the status: %s", resp.Status), format)
+			return
+		}
 	}
 	// everything is ok, send the response
 	if format == "json" {
@@ -169,18 +229,36 @@ func (c *RootController) CasProxy() {
 		return
 	}
 
-	ok, authenticationSuccess, issuedService, userId := object.GetCasTokenByPgt(pgt)
-	if !ok {
+	application, err := c.getCasApplication()
+	if err != nil {
+		c.sendCasProxyResponseErr(InternalError, err.Error(), format)
+		return
+	}
+	if application == nil {
+		c.sendCasProxyResponseErr(InvalidRequest, "application not found", format)
+		return
+	}
+
+	token, err := object.GetCasTokenByPgt(application, pgt)
+	if err != nil {
+		c.sendCasProxyResponseErr(InternalError, err.Error(), format)
+		return
+	}
+	if token == nil || !application.IsCasServiceAllowed(targetService) {
 		c.sendCasProxyResponseErr(UnauthorizedService, "service not authorized", format)
 		return
 	}
 
-	newAuthenticationSuccess := authenticationSuccess.DeepCopy()
+	newAuthenticationSuccess := token.AuthenticationSuccess.DeepCopy()
 	if newAuthenticationSuccess.Proxies == nil {
 		newAuthenticationSuccess.Proxies = &object.CasProxies{}
 	}
-	newAuthenticationSuccess.Proxies.Proxies = append(newAuthenticationSuccess.Proxies.Proxies, issuedService)
-	proxyTicket := object.StoreCasTokenForProxyTicket(&newAuthenticationSuccess, targetService, userId)
+	newAuthenticationSuccess.Proxies.Proxies = append(newAuthenticationSuccess.Proxies.Proxies, token.Service)
+	proxyTicket, err := object.StoreCasTokenForProxyTicket(application, &newAuthenticationSuccess, targetService, token.UserId)
+	if err != nil {
+		c.sendCasProxyResponseErr(InternalError, err.Error(), format)
+		return
+	}
 
 	serviceResponse := object.CasServiceResponse{
 		Xmlns: "http://www.yale.edu/tp/cas",
@@ -216,7 +294,17 @@ func (c *RootController) SamlValidate() {
 		return
 	}
 
-	response, service, err := object.GetValidationBySaml(envelopRequest.Body.Content, c.Ctx.Request.Host)
+	application, err := c.getCasApplication()
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+	if application == nil {
+		c.ResponseError(fmt.Sprintf(c.T("saml:Application %s not found"), c.Ctx.Input.Param(":application")))
+		return
+	}
+
+	response, service, err := object.GetValidationBySaml(application, envelopRequest.Body.Content, c.Ctx.Request.Host)
 	if err != nil {
 		c.ResponseError(err.Error())
 		return
@@ -246,6 +334,23 @@ func (c *RootController) SamlValidate() {
 	c.Ctx.Output.Body(data)
 }
 
+// addCasSessionTicket remembers the service ticket, to log its service out when the session ends
+func (c *ApiController) addCasSessionTicket(application *object.Application, service string, user string, st string) error {
+	ttl := time.Duration(beego.BConfig.WebConfig.Session.SessionGCMaxLifetime) * time.Second
+	return object.AddCasSessionTicket(c.Ctx.Input.CruSession.SessionID(), application, service, user, st, ttl)
+}
+
+// sendCasLogoutRequests sends the CAS single logout requests of the session in the background
+func (c *ApiController) sendCasLogoutRequests() {
+	sessionId := c.Ctx.Input.CruSession.SessionID()
+	util.SafeGoroutine(func() {
+		err := object.SendCasLogoutRequests(sessionId)
+		if err != nil {
+			logs.Error(fmt.Sprintf("sendCasLogoutRequests() error: %s", err.Error()))
+		}
+	})
+}
+
 func (c *RootController) sendCasProxyResponseErr(code, msg, format string) {
 	serviceResponse := object.CasServiceResponse{
 		Xmlns: "http://www.yale.edu/tp/cas",
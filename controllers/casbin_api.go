@@ -26,7 +26,7 @@ import (
 // @Title Enforce
 // @Tag Enforcer API
 // @Description Call Casbin Enforce API
-// @Param   body    body   []string  true   "Casbin request"
+// @Param   body    body   []string  true   "Casbin request, or an object with the request and its context"
 // @Param   permissionId    query   string  false   "permission id"
 // @Param   modelId    query   string  false   "model id"
 // @Param   resourceId    query   string  false   "resource id"
@@ -45,8 +45,15 @@ func (c *ApiController) Enforce() {
 		return
 	}
 
-	var request []string
-	err := json.Unmarshal(c.Ctx.Input.RequestBody, &request)
+	var body enforceBody
+	err := json.Unmarshal(c.Ctx.Input.RequestBody, &body)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+	request := body.Request
+
+	env, err := c.getEnforceEnvironment(body.Context)
 	if err != nil {
 		c.ResponseError(err.Error())
 		return
@@ -62,10 +69,13 @@ func (c *ApiController) Enforce() {
 		res := []bool{}
 		keyRes := []string{}
 
-		// type transformation
-		interfaceRequest := util.StringToInterfaceArray(request)
+		requests, err := object.HydrateEnforceRequests(enforcer.Enforcer, [][]string{request}, env)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
 
-		enforceResult, err := enforcer.Enforce(interfaceRequest...)
+		enforceResult, err := enforcer.Enforce(requests[0]...)
 		if err != nil {
 			c.ResponseError(err.Error())
 			return
@@ -92,7 +102,7 @@ func (c *ApiController) Enforce() {
 		res := []bool{}
 		keyRes := []string{}
 
-		enforceResult, err := object.Enforce(permission, request)
+		enforceResult, err := object.EnforceWithEnvironment(permission, request, env)
 		if err != nil {
 			c.ResponseError(err.Error())
 			return
@@ -140,7 +150,7 @@ func (c *ApiController) Enforce() {
 			return
 		}
 
-		enforceResult, err := object.Enforce(firstPermission, request, permissionIds...)
+		enforceResult, err := object.EnforceWithEnvironment(firstPermission, request, env, permissionIds...)
 		if err != nil {
 			c.ResponseError(err.Error())
 			return
@@ -157,7 +167,7 @@ func (c *ApiController) Enforce() {
 // @Title BatchEnforce
 // @Tag Enforcer API
 // @Description Call Casbin BatchEnforce API
-// @Param   body    body   []string  true   "array of casbin requests"
+// @Param   body    body   []string  true   "array of casbin requests, or an object with the requests and their context"
 // @Param   permissionId    query   string  false   "permission id"
 // @Param   modelId    query   string  false   "model id"
 // @Param   owner    query   string  false   "owner"
@@ -169,8 +179,15 @@ func (c *ApiController) BatchEnforce() {
 	enforcerId := c.Input().Get("enforcerId")
 	owner := c.Input().Get("owner")
 
-	var requests [][]string
-	err := json.Unmarshal(c.Ctx.Input.RequestBody, &requests)
+	var body batchEnforceBody
+	err := json.Unmarshal(c.Ctx.Input.RequestBody, &body)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+	requests := body.Requests
+
+	env, err := c.getEnforceEnvironment(body.Context)
 	if err != nil {
 		c.ResponseError(err.Error())
 		return
@@ -186,8 +203,11 @@ func (c *ApiController) BatchEnforce() {
 		res := [][]bool{}
 		keyRes := []string{}
 
-		// type transformation
-		interfaceRequests := util.StringToInterfaceArray2d(requests)
+		interfaceRequests, err := object.HydrateEnforceRequests(enforcer.Enforcer, requests, env)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
 
 		enforceResult, err := enforcer.BatchEnforce(interfaceRequests)
 		if err != nil {
@@ -216,7 +236,7 @@ func (c *ApiController) BatchEnforce() {
 		res := [][]bool{}
 		keyRes := []string{}
 
-		enforceResult, err := object.BatchEnforce(permission, requests)
+		enforceResult, err := object.BatchEnforceWithEnvironment(permission, requests, env)
 		if err != nil {
 			c.ResponseError(err.Error())
 			return
@@ -258,7 +278,7 @@ func (c *ApiController) BatchEnforce() {
 			return
 		}
 
-		enforceResult, err := object.BatchEnforce(firstPermission, requests, permissionIds...)
+		enforceResult, err := object.BatchEnforceWithEnvironment(firstPermission, requests, env, permissionIds...)
 		if err != nil {
 			c.ResponseError(err.Error())
 			return
@@ -327,3 +347,83 @@ func (c *ApiController) GetAllRoles() {
 
 	c.ResponseOk(roles)
 }
+
+// EnforceExplain
+// @Title EnforceExplain
+// @Tag Enforcer API
+// @Description explain the decisions of the permissions for the requests, or dry-run a proposed permission or role change against them
+// @Param   body    body   object.EnforceExplainRequest  true   "The requests, with the proposed permission or roles for a dry run"
+// @Param   permissionId    query   string  false   "permission id"
+// @Param   modelId    query   string  false   "model id"
+// @Param   resourceId    query   string  false   "resource id"
+// @Param   owner    query   string  false   "owner"
+// @Success 200 {array} object.EnforceExplanation The Response object
+// @router /enforce-explain [post]
+func (c *ApiController) EnforceExplain() {
+	permissionId := c.Input().Get("permissionId")
+	modelId := c.Input().Get("modelId")
+	resourceId := c.Input().Get("resourceId")
+	owner := c.Input().Get("owner")
+
+	var request object.EnforceExplainRequest
+	err := json.Unmarshal(c.Ctx.Input.RequestBody, &request)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	if request.IsDryRun() {
+		res, err := object.DryRunEnforce(&request)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+
+		c.ResponseOk(res)
+		return
+	}
+
+	permissions := []*object.Permission{}
+	if permissionId != "" {
+		permission, err := object.GetPermission(permissionId)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+		if permission == nil {
+			c.ResponseError(fmt.Sprintf(c.T("permission:The permission: \"%s\" doesn't exist"), permissionId))
+			return
+		}
+		permissions = append(permissions, permission)
+	} else if modelId != "" {
+		owner, modelName := util.GetOwnerAndNameFromId(modelId)
+		permissions, err = object.GetPermissionsByModel(owner, modelName)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+	} else if resourceId != "" {
+		permissions, err = object.GetPermissionsByResource(resourceId)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+	} else if owner != "" {
+		permissions, err = object.GetPermissions(owner)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+	} else {
+		c.ResponseError(c.T("general:Missing parameter"))
+		return
+	}
+
+	res, err := object.ExplainEnforce(permissions, request.Requests)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.ResponseOk(res)
+}
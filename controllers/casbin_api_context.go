@@ -0,0 +1,74 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/casdoor/casdoor/object"
+	"github.com/casdoor/casdoor/util"
+)
+
+// enforceBody is the body of /api/enforce, either the request only or the request with its context
+type enforceBody struct {
+	Request []string        `json:"request"`
+	Context json.RawMessage `json:"context"`
+}
+
+func (body *enforceBody) UnmarshalJSON(data []byte) error {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return json.Unmarshal(data, &body.Request)
+	}
+
+	type plainEnforceBody enforceBody
+	return json.Unmarshal(data, (*plainEnforceBody)(body))
+}
+
+// batchEnforceBody is the body of /api/batch-enforce, either the requests only or the requests with their context
+type batchEnforceBody struct {
+	Requests [][]string      `json:"requests"`
+	Context  json.RawMessage `json:"context"`
+}
+
+func (body *batchEnforceBody) UnmarshalJSON(data []byte) error {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return json.Unmarshal(data, &body.Requests)
+	}
+
+	type plainBatchEnforceBody batchEnforceBody
+	return json.Unmarshal(data, (*plainBatchEnforceBody)(body))
+}
+
+// getEnforceEnvironment returns the context of the HTTP request, with the values given in the body instead,
+// like the IP address of the end user when an application enforces on their behalf
+func (c *ApiController) getEnforceEnvironment(context json.RawMessage) (*object.EnforceEnvironment, error) {
+	env := object.NewEnforceEnvironment(util.GetClientIpFromRequest(c.Ctx.Request), c.Ctx.Request.UserAgent(), c.isMfaUsedSession(), time.Now())
+	if len(context) == 0 {
+		return env, nil
+	}
+
+	err := json.Unmarshal(context, env)
+	if err != nil {
+		return nil, err
+	}
+
+	now, err := time.Parse(time.RFC3339, env.Time)
+	if err != nil {
+		return nil, err
+	}
+	return object.NewEnforceEnvironment(env.Ip, env.UserAgent, env.MfaUsed, now), nil
+}
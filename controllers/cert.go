@@ -16,6 +16,7 @@ package controllers
 
 import (
 	"encoding/json"
+	"fmt"
 
 	"github.com/beego/beego/utils/pagination"
 	"github.com/casdoor/casdoor/object"
@@ -183,3 +184,31 @@ func (c *ApiController) DeleteCert() {
 	c.Data["json"] = wrapActionResponse(object.DeleteCert(&cert))
 	c.ServeJSON()
 }
+
+// RotateCert
+// @Title RotateCert
+// @Tag Cert API
+// @Description switch the cert to a new key, the previous key stays in the JWKS until the tokens signed with it expire
+// @Param   id     query    string  true        "The id ( owner/name ) of the cert"
+// @Success 200 {object} controllers.Response The Response object
+// @router /rotate-cert [post]
+func (c *ApiController) RotateCert() {
+	id := c.Input().Get("id")
+	cert, err := object.GetCert(id)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+	if cert == nil {
+		c.ResponseError(fmt.Sprintf("the cert: %s does not exist", id))
+		return
+	}
+
+	err = object.RotateCert(cert)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.ResponseOk(object.GetMaskedCert(cert))
+}
@@ -0,0 +1,175 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/casdoor/casdoor/object"
+	"github.com/casdoor/casdoor/util"
+)
+
+const (
+	defaultChangeEventLimit = 100
+	maxChangeEventLimit     = 1000
+	maxChangeEventTimeout   = 60
+	// the interval of the comments keeping an idle event stream open through the proxies
+	changeEventKeepAliveInterval = 15 * time.Second
+)
+
+// getChangeEventFilter returns the filter of the events readable by the admin, an organization admin only reads
+// the events of its organization
+func (c *ApiController) getChangeEventFilter() (*object.ChangeEventFilter, int, bool) {
+	organization, ok := c.RequireAdmin()
+	if !ok {
+		return nil, 0, false
+	}
+
+	owner := c.Input().Get("owner")
+	if owner != "" {
+		if organization != "" && owner != organization {
+			c.ResponseError(c.T("auth:Unauthorized operation"))
+			return nil, 0, false
+		}
+		organization = owner
+	}
+
+	filter := &object.ChangeEventFilter{Organization: organization}
+	types := c.Input().Get("types")
+	if types != "" {
+		filter.ObjectTypes = strings.Split(types, ",")
+	}
+
+	limit := defaultChangeEventLimit
+	if c.Input().Get("limit") != "" {
+		limit = util.ParseInt(c.Input().Get("limit"))
+		if limit <= 0 || limit > maxChangeEventLimit {
+			limit = maxChangeEventLimit
+		}
+	}
+
+	return filter, limit, true
+}
+
+// GetChangeEvents
+// @Title GetChangeEvents
+// @Tag Change Event API
+// @Description get the create, update and delete events of the users, groups, roles, permissions, applications and organizations after a cursor, waiting for them up to the timeout
+// @Param   owner     query    string  false        "The organization of the events, all of them for a global admin when empty"
+// @Param   types     query    string  false        "The comma-separated object types, like: user,group"
+// @Param   cursor    query    string  false        "The cursor returned by the previous call, the start of the stream when empty or its end with: latest. The error is: cursor expired, when the next events have been removed by the retention"
+// @Param   limit     query    int     false        "The maximum number of events, 100 by default"
+// @Param   timeout   query    int     false        "The seconds to wait for new events when there is none, 0 by default and 60 at most"
+// @Success 200 {array} object.ChangeEvent The Response object, data2 is the cursor to read the next events
+// @router /get-change-events [get]
+func (c *ApiController) GetChangeEvents() {
+	filter, limit, ok := c.getChangeEventFilter()
+	if !ok {
+		return
+	}
+
+	cursor := c.Input().Get("cursor")
+	timeout := util.ParseInt(c.Input().Get("timeout"))
+	if timeout > maxChangeEventTimeout {
+		timeout = maxChangeEventTimeout
+	}
+
+	var events []*object.ChangeEvent
+	var nextCursor string
+	var err error
+	if timeout > 0 {
+		events, nextCursor, err = object.WaitChangeEvents(c.Ctx.Request.Context(), cursor, filter, limit, time.Duration(timeout)*time.Second)
+	} else {
+		events, nextCursor, err = object.GetChangeEvents(cursor, filter, limit)
+	}
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.ResponseOk(events, nextCursor)
+}
+
+// StreamChangeEvents
+// @Title StreamChangeEvents
+// @Tag Change Event API
+// @Description stream the create, update and delete events of the users, groups, roles, permissions, applications and organizations as server-sent events, a reconnection resumes from the Last-Event-ID header
+// @Param   owner     query    string  false        "The organization of the events, all of them for a global admin when empty"
+// @Param   types     query    string  false        "The comma-separated object types, like: user,group"
+// @Param   cursor    query    string  false        "The cursor to start after, the start of the stream when empty or its end with: latest. The error is: cursor expired, when the next events have been removed by the retention"
+// @Success 200 {string} string "The event stream, the id of each event is its cursor"
+// @router /stream-change-events [get]
+func (c *ApiController) StreamChangeEvents() {
+	filter, limit, ok := c.getChangeEventFilter()
+	if !ok {
+		return
+	}
+
+	cursor := c.Input().Get("cursor")
+	if lastEventId := c.Ctx.Request.Header.Get("Last-Event-ID"); lastEventId != "" {
+		cursor = lastEventId
+	}
+
+	// the cursor is checked before the stream starts so that an invalid one is reported as a usual error
+	events, nextCursor, err := object.GetChangeEvents(cursor, filter, limit)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	writer := c.Ctx.ResponseWriter
+	writer.Header().Set("Content-Type", "text/event-stream")
+	writer.Header().Set("Cache-Control", "no-cache")
+	writer.Header().Set("Connection", "keep-alive")
+	writer.Header().Set("X-Accel-Buffering", "no")
+	writer.WriteHeader(200)
+	writer.Flush()
+
+	ctx := c.Ctx.Request.Context()
+	for {
+		for _, event := range events {
+			data, err := json.Marshal(event)
+			if err != nil {
+				return
+			}
+
+			_, err = fmt.Fprintf(writer, "id: %s\nevent: %s.%s\ndata: %s\n\n", event.Cursor, event.ObjectType, event.Action, data)
+			if err != nil {
+				return
+			}
+		}
+		if len(events) == 0 {
+			_, err = fmt.Fprint(writer, ": keep-alive\n\n")
+			if err != nil {
+				return
+			}
+		}
+		writer.Flush()
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		events, nextCursor, err = object.WaitChangeEvents(ctx, nextCursor, filter, limit, changeEventKeepAliveInterval)
+		if err != nil {
+			_, _ = fmt.Fprintf(writer, "event: error\ndata: %s\n\n", strings.ReplaceAll(err.Error(), "\n", " "))
+			writer.Flush()
+			return
+		}
+	}
+}
@@ -16,10 +16,10 @@ package controllers
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/casdoor/casdoor/object"
 	"github.com/casdoor/casdoor/util"
-	"github.com/google/uuid"
 )
 
 // MfaSetupInitiate
@@ -64,8 +64,13 @@ func (c *ApiController) MfaSetupInitiate() {
 		return
 	}
 
-	recoveryCode := uuid.NewString()
-	mfaProps.RecoveryCodes = []string{recoveryCode}
+	if mfaType != object.RecoveryCodeType {
+		mfaProps.RecoveryCodes, err = object.GenerateRecoveryCodes()
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+	}
 
 	resp := mfaProps
 	c.ResponseOk(resp)
@@ -117,6 +122,8 @@ func (c *ApiController) MfaSetupVerify() {
 			return
 		}
 		config.Secret = dest
+	} else if mfaType == object.RecoveryCodeType {
+		config.RecoveryCodes = strings.Split(c.Ctx.Request.Form.Get("recoveryCodes"), ",")
 	}
 
 	mfaUtil := object.GetMfaUtil(mfaType, config)
@@ -125,6 +132,23 @@ func (c *ApiController) MfaSetupVerify() {
 		return
 	}
 
+	if pushMfa, ok := mfaUtil.(*object.PushMfa); ok {
+		pushMfa.UserId = c.GetSessionUsername()
+	}
+
+	if webAuthnMfa, ok := mfaUtil.(*object.WebAuthnMfa); ok {
+		user, ok := c.RequireSignedInUser()
+		if !ok {
+			return
+		}
+
+		err := c.prepareWebAuthnMfa(webAuthnMfa, user)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+	}
+
 	err := mfaUtil.SetupVerify(passcode)
 	if err != nil {
 		c.ResponseError(err.Error())
@@ -180,6 +204,8 @@ func (c *ApiController) MfaSetupEnable() {
 			}
 			user.Email = dest
 		}
+	} else if mfaType == object.PushType {
+		config.Secret = dest
 	} else if mfaType == object.SmsType {
 		if user.Phone == "" {
 			if dest == "" {
@@ -199,7 +225,7 @@ func (c *ApiController) MfaSetupEnable() {
 		c.ResponseError("recovery codes is missing")
 		return
 	}
-	config.RecoveryCodes = []string{recoveryCodes}
+	config.RecoveryCodes = strings.Split(recoveryCodes, ",")
 
 	mfaUtil := object.GetMfaUtil(mfaType, config)
 	if mfaUtil == nil {
@@ -280,3 +306,81 @@ func (c *ApiController) SetPreferredMfa() {
 	}
 	c.ResponseOk(object.GetAllMfaProps(user, true))
 }
+
+// GetRecoveryCodeCount
+// @Title GetRecoveryCodeCount
+// @Tag MFA API
+// @Description get the number of the user's remaining recovery codes
+// @Param   id     query    string  true        "The id ( owner/name ) of the user"
+// @Success 200 {object} controllers.Response The Response object
+// @router /get-recovery-code-count [get]
+func (c *ApiController) GetRecoveryCodeCount() {
+	id := c.Input().Get("id")
+
+	user, err := object.GetUser(id)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+	if user == nil {
+		c.ResponseError("User doesn't exist")
+		return
+	}
+
+	if !c.IsAdminOrSelf(user) {
+		c.ResponseError(c.T("auth:Unauthorized operation"))
+		return
+	}
+
+	c.ResponseOk(len(user.RecoveryCodes))
+}
+
+// RegenerateRecoveryCodes
+// @Title RegenerateRecoveryCodes
+// @Tag MFA API
+// @Description replace the user's recovery codes, the current user needs to confirm the password
+// @param owner	form	string	true	"owner of user"
+// @param name	form	string	true	"name of user"
+// @param password	form	string	true	"password of the current user"
+// @Success 200 {object} controllers.Response The Response object
+// @router /regenerate-recovery-codes [post]
+func (c *ApiController) RegenerateRecoveryCodes() {
+	owner := c.Ctx.Request.Form.Get("owner")
+	name := c.Ctx.Request.Form.Get("name")
+	password := c.Ctx.Request.Form.Get("password")
+
+	currentUser, ok := c.RequireSignedInUser()
+	if !ok {
+		return
+	}
+
+	user, err := object.GetUser(util.GetId(owner, name))
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+	if user == nil {
+		c.ResponseError("User doesn't exist")
+		return
+	}
+
+	if !c.IsAdminOrSelf(user) {
+		c.ResponseError(c.T("auth:Unauthorized operation"))
+		return
+	}
+
+	// re-authenticate the current user before showing new codes
+	err = object.CheckPassword(currentUser, password, c.GetAcceptLanguage())
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	recoveryCodes, err := object.RegenerateRecoveryCodes(user)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.ResponseOk(recoveryCodes)
+}
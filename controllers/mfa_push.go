@@ -0,0 +1,202 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/casdoor/casdoor/object"
+	"github.com/casdoor/casdoor/util"
+)
+
+// getMfaPushUser returns the user in the MFA step of the sign-in, or the signed-in user setting up the push MFA,
+// and whether it is the sign-in
+func (c *ApiController) getMfaPushUser() (*object.User, bool, bool) {
+	isSignin := true
+	userId := c.getMfaUserSession()
+	if userId == "" {
+		isSignin = false
+		userId = c.GetSessionUsername()
+	}
+	if userId == "" {
+		c.ResponseError(c.T("general:Please login first"))
+		return nil, false, false
+	}
+
+	user, err := object.GetUser(userId)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return nil, false, false
+	}
+	if user == nil {
+		c.ResponseError(fmt.Sprintf(c.T("general:The user: %s doesn't exist"), userId))
+		return nil, false, false
+	}
+	return user, isSignin, true
+}
+
+// SendMfaPush
+// @Title SendMfaPush
+// @Tag MFA API
+// @Description send a push challenge with number matching to the user's device
+// @Param   dest     formData    string  false        "The notification provider ( owner/name ) of the user's organization to test during the setup"
+// @Success 200 {object} controllers.Response The Response object
+// @router /mfa/push/send [post]
+func (c *ApiController) SendMfaPush() {
+	user, isSignin, ok := c.getMfaPushUser()
+	if !ok {
+		return
+	}
+
+	dest := c.Ctx.Request.Form.Get("dest")
+	if isSignin {
+		// the sign-in only pushes to the device the user has set up
+		if !user.MfaPushEnabled {
+			c.ResponseError("Invalid multi-factor authentication type")
+			return
+		}
+	} else if !user.MfaPushEnabled && dest != "" {
+		// the provider is only saved when the push MFA is enabled
+		user.MfaPushProvider = dest
+	}
+
+	challenge, err := object.SendMfaPushChallenge(user, util.GetClientIpFromRequest(c.Ctx.Request), c.Ctx.Request.UserAgent(), c.Ctx.Request.Host)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.ResponseOk(challenge.Id, challenge.Number)
+}
+
+// GetMfaPushStatus
+// @Title GetMfaPushStatus
+// @Tag MFA API
+// @Description get the status of the push challenge sent by the sign-in screen
+// @Param   id     query    string  true        "The id of the push challenge"
+// @Success 200 {object} controllers.Response The Response object
+// @router /mfa/push/get-status [get]
+func (c *ApiController) GetMfaPushStatus() {
+	user, _, ok := c.getMfaPushUser()
+	if !ok {
+		return
+	}
+
+	challenge, err := object.GetMfaPushChallenge(c.Input().Get("id"))
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+	if challenge == nil || challenge.Owner != user.Owner || challenge.Name != user.Name {
+		c.ResponseError("the push request does not exist")
+		return
+	}
+
+	c.ResponseOk(challenge.Status)
+}
+
+// GetMfaPushChallenge
+// @Title GetMfaPushChallenge
+// @Tag MFA API
+// @Description get the push challenge of the link sent to the user's device
+// @Param   token     query    string  true        "The token of the push challenge"
+// @Success 200 {object} object.MfaPushChallenge The Response object
+// @router /mfa/push/get-challenge [get]
+func (c *ApiController) GetMfaPushChallenge() {
+	challenge, err := object.GetMfaPushChallengeByToken(c.Input().Get("token"))
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+	if challenge == nil {
+		c.ResponseError("the push request does not exist")
+		return
+	}
+
+	c.ResponseOk(challenge.GetMasked())
+}
+
+// GetPendingMfaPushChallenges
+// @Title GetPendingMfaPushChallenges
+// @Tag MFA API
+// @Description get the pending push challenges of the signed-in user, used by the companion app
+// @Success 200 {array} object.MfaPushChallenge The Response object
+// @router /mfa/push/get-pending-challenges [get]
+func (c *ApiController) GetPendingMfaPushChallenges() {
+	user, ok := c.RequireSignedInUser()
+	if !ok {
+		return
+	}
+
+	pendingChallenges, err := object.GetPendingMfaPushChallenges(user.Owner, user.Name)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	challenges := []*object.MfaPushChallenge{}
+	for _, challenge := range pendingChallenges {
+		challenges = append(challenges, challenge.GetMasked())
+	}
+
+	c.ResponseOk(challenges)
+}
+
+// RespondMfaPush
+// @Title RespondMfaPush
+// @Tag MFA API
+// @Description approve, deny or report a push challenge as fraudulent, reporting a fraud locks the account
+// @Param   token     formData    string  false        "The token of the push challenge from the notification link"
+// @Param   id     formData    string  false        "The id of the push challenge, for the signed-in companion app"
+// @Param   action     formData    string  true        "approve, deny or fraud"
+// @Param   number     formData    string  false        "The number shown on the sign-in screen, required to approve"
+// @Success 200 {object} controllers.Response The Response object
+// @router /mfa/push/respond [post]
+func (c *ApiController) RespondMfaPush() {
+	token := c.Ctx.Request.Form.Get("token")
+	id := c.Ctx.Request.Form.Get("id")
+
+	var challenge *object.MfaPushChallenge
+	var err error
+	if token != "" {
+		challenge, err = object.GetMfaPushChallengeByToken(token)
+	} else if id != "" {
+		user, ok := c.RequireSignedInUser()
+		if !ok {
+			return
+		}
+
+		challenge, err = object.GetMfaPushChallenge(id)
+		if challenge != nil && (challenge.Owner != user.Owner || challenge.Name != user.Name) {
+			challenge = nil
+		}
+	}
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+	if challenge == nil {
+		c.ResponseError("the push request does not exist")
+		return
+	}
+
+	err = object.RespondMfaPushChallenge(challenge, c.Ctx.Request.Form.Get("action"), c.Ctx.Request.Form.Get("number"))
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.ResponseOk(challenge.Status)
+}
@@ -16,6 +16,7 @@ package controllers
 
 import (
 	"encoding/json"
+	"fmt"
 
 	"github.com/beego/beego/utils/pagination"
 	"github.com/casdoor/casdoor/object"
@@ -182,3 +183,86 @@ func (c *ApiController) DeletePermission() {
 	c.Data["json"] = wrapActionResponse(object.DeletePermission(&permission))
 	c.ServeJSON()
 }
+
+// RequestElevation
+// @Title RequestElevation
+// @Tag Permission API
+// @Description request the access of a permission for a while, as a pending permission to be approved
+// @Param   body    body   object.ElevationRequest  true        "The permission, duration in minutes and reason"
+// @Success 200 {object} object.Permission The Response object
+// @router /request-elevation [post]
+func (c *ApiController) RequestElevation() {
+	user, ok := c.RequireSignedInUser()
+	if !ok {
+		return
+	}
+
+	var request object.ElevationRequest
+	err := json.Unmarshal(c.Ctx.Input.RequestBody, &request)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	permission, err := object.RequestElevation(user, &request)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.ResponseOk(permission)
+}
+
+func (c *ApiController) getElevation() (*object.Permission, *object.User, bool) {
+	user, ok := c.RequireSignedInUser()
+	if !ok {
+		return nil, nil, false
+	}
+
+	id := c.Input().Get("id")
+	permission, err := object.GetPermission(id)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return nil, nil, false
+	}
+	if permission == nil {
+		c.ResponseError(fmt.Sprintf(c.T("permission:The permission: \"%s\" doesn't exist"), id))
+		return nil, nil, false
+	}
+
+	return permission, user, true
+}
+
+// ApproveElevation
+// @Title ApproveElevation
+// @Tag Permission API
+// @Description approve a pending elevation, granting its access from now on for the requested duration
+// @Param   id     query    string  true        "The id ( owner/name ) of the elevation permission"
+// @Success 200 {object} controllers.Response The Response object
+// @router /approve-elevation [post]
+func (c *ApiController) ApproveElevation() {
+	permission, user, ok := c.getElevation()
+	if !ok {
+		return
+	}
+
+	c.Data["json"] = wrapActionResponse(object.ApproveElevation(permission, user))
+	c.ServeJSON()
+}
+
+// RejectElevation
+// @Title RejectElevation
+// @Tag Permission API
+// @Description reject a pending elevation
+// @Param   id     query    string  true        "The id ( owner/name ) of the elevation permission"
+// @Success 200 {object} controllers.Response The Response object
+// @router /reject-elevation [post]
+func (c *ApiController) RejectElevation() {
+	permission, user, ok := c.getElevation()
+	if !ok {
+		return
+	}
+
+	c.Data["json"] = wrapActionResponse(object.RejectElevation(permission, user))
+	c.ServeJSON()
+}
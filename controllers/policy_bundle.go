@@ -0,0 +1,122 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/casdoor/casdoor/object"
+)
+
+type policyBundleForm struct {
+	Owner   string `json:"owner"`
+	Content string `json:"content"`
+}
+
+// ExportPolicies
+// @Title ExportPolicies
+// @Tag Policy API
+// @Description export the models, adapters, enforcers, groups, roles and permissions of an organization as YAML
+// @Param   owner     query    string  true        "The owner of the policies"
+// @Success 200 {string} string The YAML bundle
+// @router /export-policies [get]
+func (c *ApiController) ExportPolicies() {
+	owner := c.Input().Get("owner")
+	if owner == "" {
+		c.ResponseError(c.T("general:Missing parameter"))
+		return
+	}
+
+	bundle, err := object.ExportPolicyBundle(owner)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	data, err := bundle.ToYaml()
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.Ctx.Output.Header("Content-Disposition", fmt.Sprintf("attachment; filename=policies-%s.yaml", owner))
+	c.Ctx.Output.ContentType("application/yaml")
+	c.Ctx.Output.Body(data)
+}
+
+func (c *ApiController) getPolicyBundleForm() (*policyBundleForm, *object.PolicyBundle, bool) {
+	var form policyBundleForm
+	err := json.Unmarshal(c.Ctx.Input.RequestBody, &form)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return nil, nil, false
+	}
+	if form.Owner == "" {
+		c.ResponseError(c.T("general:Missing parameter"))
+		return nil, nil, false
+	}
+
+	bundle, err := object.ParsePolicyBundle([]byte(form.Content))
+	if err != nil {
+		c.ResponseError(err.Error())
+		return nil, nil, false
+	}
+	return &form, bundle, true
+}
+
+// PlanPolicies
+// @Title PlanPolicies
+// @Tag Policy API
+// @Description show the adds, changes and deletes that importing a YAML bundle would apply
+// @Param   body    body   controllers.policyBundleForm  true   "The owner and the YAML bundle"
+// @Success 200 {object} object.PolicyPlan The Response object
+// @router /plan-policies [post]
+func (c *ApiController) PlanPolicies() {
+	form, bundle, ok := c.getPolicyBundleForm()
+	if !ok {
+		return
+	}
+
+	plan, err := object.PlanPolicyBundle(form.Owner, bundle)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.ResponseOk(plan)
+}
+
+// ImportPolicies
+// @Title ImportPolicies
+// @Tag Policy API
+// @Description reconcile the organization with a YAML bundle, the kinds listed in the bundle are replaced
+// @Param   body    body   controllers.policyBundleForm  true   "The owner and the YAML bundle"
+// @Success 200 {object} object.PolicyPlan The Response object
+// @router /import-policies [post]
+func (c *ApiController) ImportPolicies() {
+	form, bundle, ok := c.getPolicyBundleForm()
+	if !ok {
+		return
+	}
+
+	plan, err := object.ApplyPolicyBundle(form.Owner, bundle)
+	if err != nil {
+		c.ResponseError(err.Error(), plan)
+		return
+	}
+
+	c.ResponseOk(plan)
+}
@@ -16,10 +16,12 @@ package controllers
 
 import (
 	"encoding/json"
+	"fmt"
 
 	"github.com/casvisor/casvisor-go-sdk/casvisorsdk"
 
 	"github.com/beego/beego/utils/pagination"
+	"github.com/casdoor/casdoor/audit"
 	"github.com/casdoor/casdoor/object"
 	"github.com/casdoor/casdoor/util"
 )
@@ -126,3 +128,102 @@ func (c *ApiController) AddRecord() {
 	c.Data["json"] = wrapActionResponse(object.AddRecord(&record))
 	c.ServeJSON()
 }
+
+// VerifyRecords
+// @Title VerifyRecords
+// @Tag Record API
+// @Description verify the hash chain and the signed checkpoints of the records
+// @Param   organization     query    string  false        "The organization of the records"
+// @Success 200 {object} object.RecordChainReport The Response object
+// @router /verify-records [get]
+func (c *ApiController) VerifyRecords() {
+	organization, ok := c.RequireAdmin()
+	if !ok {
+		return
+	}
+
+	organizationName := c.Input().Get("organization")
+	if c.IsGlobalAdmin() && organizationName != "" {
+		organization = organizationName
+	}
+
+	report, err := object.VerifyRecordChain(organization)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.ResponseOk(report)
+}
+
+// ExportRecords
+// @Title ExportRecords
+// @Tag Record API
+// @Description export the records with their chain hashes
+// @Param   organization     query    string  false        "The organization of the records"
+// @Param   format     query    string  false        "The export format: jsonl or cef"
+// @Success 200 {string} string The exported records
+// @router /export-records [get]
+func (c *ApiController) ExportRecords() {
+	organization, ok := c.RequireAdmin()
+	if !ok {
+		return
+	}
+
+	organizationName := c.Input().Get("organization")
+	if c.IsGlobalAdmin() && organizationName != "" {
+		organization = organizationName
+	}
+
+	format := c.Input().Get("format")
+	if format == "" {
+		format = "jsonl"
+	}
+
+	data, err := object.ExportRecords(organization, format)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.Ctx.Output.Header("Content-Disposition", fmt.Sprintf("attachment; filename=records-%s.%s", organization, format))
+	c.Ctx.Output.ContentType("text/plain")
+	c.Ctx.Output.Body(data)
+}
+
+// AddRecordCheckpoint
+// @Title AddRecordCheckpoint
+// @Tag Record API
+// @Description sign the current head of the record chain
+// @Param   organization     query    string  false        "The organization of the records"
+// @Success 200 {object} controllers.Response The Response object
+// @router /add-record-checkpoint [post]
+func (c *ApiController) AddRecordCheckpoint() {
+	organization, ok := c.RequireAdmin()
+	if !ok {
+		return
+	}
+
+	organizationName := c.Input().Get("organization")
+	if c.IsGlobalAdmin() && organizationName != "" {
+		organization = organizationName
+	}
+
+	c.Data["json"] = wrapActionResponse(object.AddRecordCheckpoint(organization))
+	c.ServeJSON()
+}
+
+// GetAuditSinks
+// @Title GetAuditSinks
+// @Tag Record API
+// @Description get the delivery status of the audit sinks
+// @Success 200 {array} audit.SinkStatus The Response object
+// @router /get-audit-sinks [get]
+func (c *ApiController) GetAuditSinks() {
+	if !c.IsGlobalAdmin() {
+		c.ResponseError(c.T("auth:Unauthorized operation"))
+		return
+	}
+
+	c.ResponseOk(audit.GetSinkStatuses())
+}
@@ -0,0 +1,107 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"github.com/casdoor/casdoor/sync_v2"
+)
+
+// GetReplicationStatus
+// @Title GetReplicationStatus
+// @Tag System API
+// @Description get the status of the database replication, like the lag, the positions and the GTID sets
+// @Success 200 {object} sync_v2.Status The Response object
+// @router /get-replication-status [get]
+func (c *ApiController) GetReplicationStatus() {
+	if !c.IsGlobalAdmin() {
+		c.ResponseError(c.T("auth:Unauthorized operation"))
+		return
+	}
+
+	status, err := sync_v2.GetReplicationStatus()
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.ResponseOk(status)
+}
+
+// StartReplication
+// @Title StartReplication
+// @Tag System API
+// @Description create the replication user in the primary database and start replicating it to the replica
+// @Success 200 {object} sync_v2.Status The Response object
+// @router /start-replication [post]
+func (c *ApiController) StartReplication() {
+	if !c.IsGlobalAdmin() {
+		c.ResponseError(c.T("auth:Unauthorized operation"))
+		return
+	}
+
+	status, err := sync_v2.StartReplication()
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.ResponseOk(status)
+}
+
+// StopReplication
+// @Title StopReplication
+// @Tag System API
+// @Description pause the replication, the replica stays read-only
+// @Success 200 {object} sync_v2.Status The Response object
+// @router /stop-replication [post]
+func (c *ApiController) StopReplication() {
+	if !c.IsGlobalAdmin() {
+		c.ResponseError(c.T("auth:Unauthorized operation"))
+		return
+	}
+
+	status, err := sync_v2.StopReplication()
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.ResponseOk(status)
+}
+
+// PromoteReplica
+// @Title PromoteReplica
+// @Tag System API
+// @Description make the replica database the primary, the primary is made read-only first or confirmed stopped when unreachable
+// @Param   force     query    bool  false        "Promote even when the replica is late or does not catch up in time"
+// @Param   primaryStopped     query    bool  false        "Confirm that the unreachable primary has been stopped or isolated"
+// @Success 200 {object} sync_v2.Status The Response object
+// @router /promote-replica [post]
+func (c *ApiController) PromoteReplica() {
+	if !c.IsGlobalAdmin() {
+		c.ResponseError(c.T("auth:Unauthorized operation"))
+		return
+	}
+
+	force := c.Input().Get("force") == "true"
+	isPrimaryStopped := c.Input().Get("primaryStopped") == "true"
+	status, err := sync_v2.PromoteReplica(force, isPrimaryStopped)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.ResponseOk(status)
+}
@@ -15,10 +15,12 @@
 package controllers
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 
 	"github.com/casdoor/casdoor/object"
+	"github.com/casdoor/casdoor/util"
 )
 
 func (c *ApiController) GetSamlMeta() {
@@ -64,3 +66,205 @@ func (c *ApiController) HandleSamlRedirect() {
 
 	c.Redirect(targetURL, http.StatusSeeOther)
 }
+
+func (c *ApiController) getSamlApplication() *object.Application {
+	owner := c.Ctx.Input.Param(":owner")
+	applicationName := c.Ctx.Input.Param(":application")
+
+	application, err := object.GetApplication(util.GetId(owner, applicationName))
+	if err != nil {
+		c.ResponseError(err.Error())
+		return nil
+	}
+
+	if application == nil {
+		c.ResponseError(fmt.Sprintf(c.T("saml:Application %s not found"), util.GetId(owner, applicationName)))
+		return nil
+	}
+
+	return application
+}
+
+// clearSamlSession logs the current browser out of Casdoor and of the application
+func (c *ApiController) clearSamlSession(application *object.Application, userId string) error {
+	c.sendCasLogoutRequests()
+	c.ClearUserSession()
+	c.ClearTokenSession()
+
+	owner, username := util.GetOwnerAndNameFromId(userId)
+	sessionId := c.Ctx.Input.CruSession.SessionID()
+	_, err := object.DeleteSessionId(util.GetSessionId(owner, username, object.CasdoorApplication), sessionId)
+	if err != nil {
+		return err
+	}
+
+	_, err = object.DeleteSessionId(util.GetSessionId(owner, username, application.Name), sessionId)
+	return err
+}
+
+// HandleSamlLogout
+// @Title HandleSamlLogout
+// @Tag SAML API
+// @Description the SAML single logout endpoint. With a SAMLRequest, the SP logs the user out and gets a LogoutResponse back.
+// With a SAMLResponse, the SP has finished an IdP-initiated logout. Otherwise, the signed-in user is logged out
+// and a LogoutRequest is sent to the SP, the "redirect" parameter is where to go afterwards.
+// @Param   owner    path    string  true        "owner of the application"
+// @Param   application    path    string  true        "name of the application"
+// @router /saml/slo/:owner/:application [get,post]
+func (c *ApiController) HandleSamlLogout() {
+	host := c.Ctx.Request.Host
+	application := c.getSamlApplication()
+	if application == nil {
+		return
+	}
+
+	rawQuery := c.Ctx.Request.URL.RawQuery
+	relayState := c.Input().Get("RelayState")
+	samlRequest := c.Input().Get("SAMLRequest")
+	samlResponse := c.Input().Get("SAMLResponse")
+
+	if samlRequest != "" {
+		request, err := object.ParseSamlLogoutRequest(application, samlRequest, rawQuery)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+
+		// the browser carrying the request is the one logged out, its user has to be the subject of the request
+		user := c.getCurrentUser()
+		if user != nil && object.GetSamlNameId(application, user) == request.NameID {
+			err = c.clearSamlSession(application, user.GetId())
+			if err != nil {
+				c.ResponseError(err.Error())
+				return
+			}
+			util.LogInfo(c.Ctx, "API: [%s] logged out by SAML single logout of the application: %s", user.GetId(), application.GetId())
+		}
+
+		redirectUrl, err := object.GetSamlLogoutResponseUrl(application, request, relayState, host)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+
+		c.Redirect(redirectUrl, http.StatusFound)
+		return
+	}
+
+	if samlResponse != "" {
+		_, err := object.ParseSamlLogoutResponse(application, samlResponse, rawQuery)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+
+		c.Redirect(object.GetSamlLogoutRedirectUrl(application, relayState, host), http.StatusFound)
+		return
+	}
+
+	user := c.getCurrentUser()
+	if user == nil {
+		c.ResponseError(c.T("general:Please login first"))
+		return
+	}
+
+	redirectUrl, err := object.GetSamlLogoutRequestUrl(application, user, c.Input().Get("redirect"), host)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	err = c.clearSamlSession(application, user.GetId())
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	util.LogInfo(c.Ctx, "API: [%s] logged out by SAML single logout to the application: %s", user.GetId(), application.GetId())
+	c.Redirect(redirectUrl, http.StatusFound)
+}
+
+// ResolveSamlArtifact
+// @Title ResolveSamlArtifact
+// @Tag SAML API
+// @Description the SOAP artifact resolution endpoint of the HTTP-Artifact binding
+// @Param   owner    path    string  true        "owner of the application"
+// @Param   application    path    string  true        "name of the application"
+// @router /saml/artifact/:owner/:application [post]
+func (c *ApiController) ResolveSamlArtifact() {
+	application := c.getSamlApplication()
+	if application == nil {
+		return
+	}
+
+	response, err := object.ResolveSamlArtifact(application, c.Ctx.Input.RequestBody, c.Ctx.Request.Host)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.Ctx.Output.Header("Content-Type", "text/xml; charset=utf-8")
+	c.Ctx.Output.Body(response)
+}
+
+// ParseSamlSpMetadata
+// @Title ParseSamlSpMetadata
+// @Tag SAML API
+// @Description parse the metadata of a SAML SP to fill the SAML settings of an application
+// @Param   body    body   string  true        "the XML metadata of the SP"
+// @Success 200 {object} object.SamlSpMetadata The Response object
+// @router /parse-saml-sp-metadata [post]
+func (c *ApiController) ParseSamlSpMetadata() {
+	metadata, err := object.ParseSamlSpMetadata(string(c.Ctx.Input.RequestBody))
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.ResponseOk(metadata)
+}
+
+// GetSamlIdps
+// @Title GetSamlIdps
+// @Tag SAML API
+// @Description list the IdPs of a SAML provider for the IdP discovery, e.g. the IdPs of an eduGAIN-style federation
+// @Param   id    query    string  true        "The id ( owner/name ) of the SAML provider"
+// @Param   query    query    string  false        "the text to search in the entity IDs and display names"
+// @Success 200 {array} object.SamlIdp The Response object
+// @router /get-saml-idps [get]
+func (c *ApiController) GetSamlIdps() {
+	id := c.Input().Get("id")
+	query := c.Input().Get("query")
+
+	idps, err := object.GetSamlIdps(id, query)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.ResponseOk(idps)
+}
+
+// FetchSamlIdpMetadata
+// @Title FetchSamlIdpMetadata
+// @Tag SAML API
+// @Description fetch the IdP metadata URL of a SAML provider, the provider is returned with the settings of the IdP when there is only one
+// @Param   body    body   object.Provider  true        "The details of the provider"
+// @Success 200 {object} controllers.Response The Response object
+// @router /fetch-saml-idp-metadata [post]
+func (c *ApiController) FetchSamlIdpMetadata() {
+	var provider object.Provider
+	err := json.Unmarshal(c.Ctx.Input.RequestBody, &provider)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	idps, err := object.FetchSamlIdpMetadata(&provider)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.ResponseOk(provider, len(idps))
+}
@@ -16,6 +16,7 @@ package controllers
 
 import (
 	"encoding/json"
+	"fmt"
 
 	"github.com/beego/beego/utils/pagination"
 	"github.com/casdoor/casdoor/object"
@@ -160,13 +161,109 @@ func (c *ApiController) RunSyncer() {
 		return
 	}
 
-	err = object.RunSyncer(syncer)
+	run, err := object.RunSyncer(syncer)
 	if err != nil {
 		c.ResponseError(err.Error())
 		return
 	}
 
-	c.ResponseOk()
+	c.ResponseOk(run)
+}
+
+// DryRunSyncer
+// @Title DryRunSyncer
+// @Tag Syncer API
+// @Description report the users the syncer would add, update, disable or delete, without changing them
+// @Param   id     query    string  true        "The id ( owner/name ) of the syncer"
+// @Success 200 {object} object.SyncerRun The Response object
+// @router /dry-run-syncer [post]
+func (c *ApiController) DryRunSyncer() {
+	id := c.Input().Get("id")
+	syncer, err := object.GetSyncer(id)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+	if syncer == nil {
+		c.ResponseError(fmt.Sprintf(c.T("general:The syncer: %s doesn't exist"), id))
+		return
+	}
+
+	run, err := object.DryRunSyncer(syncer)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.ResponseOk(run)
+}
+
+// GetSyncerRuns
+// @Title GetSyncerRuns
+// @Tag Syncer API
+// @Description get the runs of a syncer, the latest first
+// @Param   id     query    string  true        "The id ( owner/name ) of the syncer"
+// @Success 200 {array} object.SyncerRun The Response object
+// @router /get-syncer-runs [get]
+func (c *ApiController) GetSyncerRuns() {
+	id := c.Input().Get("id")
+	limit := c.Input().Get("pageSize")
+	page := c.Input().Get("p")
+	field := c.Input().Get("field")
+	value := c.Input().Get("value")
+	sortField := c.Input().Get("sortField")
+	sortOrder := c.Input().Get("sortOrder")
+
+	owner, name, err := util.GetOwnerAndNameFromIdWithError(id)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	if limit == "" || page == "" {
+		runs, err := object.GetSyncerRuns(owner, name)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+
+		c.ResponseOk(runs)
+	} else {
+		limit := util.ParseInt(limit)
+		count, err := object.GetSyncerRunCount(owner, name, field, value)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+
+		paginator := pagination.SetPaginator(c.Ctx, limit, count)
+		runs, err := object.GetPaginationSyncerRuns(owner, name, paginator.Offset(), limit, field, value, sortField, sortOrder)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+
+		c.ResponseOk(runs, paginator.Nums())
+	}
+}
+
+// GetSyncerRun
+// @Title GetSyncerRun
+// @Tag Syncer API
+// @Description get a run of a syncer, with the changed users and the errors
+// @Param   id     query    string  true        "The id ( owner/name ) of the syncer run"
+// @Success 200 {object} object.SyncerRun The Response object
+// @router /get-syncer-run [get]
+func (c *ApiController) GetSyncerRun() {
+	id := c.Input().Get("id")
+
+	run, err := object.GetSyncerRun(id)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.ResponseOk(run)
 }
 
 func (c *ApiController) TestSyncerDb() {
@@ -15,6 +15,10 @@
 package controllers
 
 import (
+	"fmt"
+
+	"github.com/casdoor/casdoor/object"
+	"github.com/casdoor/casdoor/sync_v2"
 	"github.com/casdoor/casdoor/util"
 )
 
@@ -70,9 +74,36 @@ func (c *ApiController) GetVersionInfo() {
 // Health
 // @Title Health
 // @Tag System API
-// @Description check if the system is live
+// @Description check if the system is live, the HTTP status is 503 when the database or the replication is unhealthy
+// @Param   role     query    string  false        "The expected replication role of the database: primary or replica, a load balancer sends the writes to the instance healthy for primary"
 // @Success 200 {object} controllers.Response The Response object
 // @router /health [get]
 func (c *ApiController) Health() {
-	c.ResponseOk()
+	err := object.PingDatabase()
+	if err != nil {
+		c.Ctx.Output.SetStatus(503)
+		c.ResponseError(c.T("general:The database is unreachable"))
+		return
+	}
+
+	health := sync_v2.GetReplicationHealth()
+	if health == nil {
+		c.ResponseOk()
+		return
+	}
+
+	if health.State == sync_v2.StateUnhealthy {
+		c.Ctx.Output.SetStatus(503)
+		c.ResponseError(c.T("general:The replication is unhealthy"), health)
+		return
+	}
+
+	role := c.Input().Get("role")
+	if role != "" && role != health.Role {
+		c.Ctx.Output.SetStatus(503)
+		c.ResponseError(fmt.Sprintf(c.T("general:The replication role of this instance is %s"), health.Role), health)
+		return
+	}
+
+	c.ResponseOk(health)
 }
@@ -16,7 +16,6 @@ package controllers
 
 import (
 	"encoding/json"
-	"time"
 
 	"github.com/beego/beego/utils/pagination"
 	"github.com/casdoor/casdoor/object"
@@ -222,8 +221,17 @@ func (c *ApiController) GetOAuthToken() {
 	}
 
 	if deviceCode != "" {
-		deviceAuthCache, ok := object.DeviceAuthMap.Load(deviceCode)
-		if !ok {
+		deviceAuthCache, err := object.GetDeviceAuthCache(deviceCode)
+		if err != nil {
+			c.Data["json"] = &object.TokenError{
+				Error:            object.EndpointError,
+				ErrorDescription: err.Error(),
+			}
+			c.SetTokenErrorHttpStatus()
+			c.ServeJSON()
+			return
+		}
+		if deviceAuthCache == nil {
 			c.Data["json"] = &object.TokenError{
 				Error:            "expired_token",
 				ErrorDescription: "token is expired",
@@ -234,8 +242,7 @@ func (c *ApiController) GetOAuthToken() {
 			return
 		}
 
-		deviceAuthCacheCast := deviceAuthCache.(object.DeviceAuthCache)
-		if !deviceAuthCacheCast.UserSignIn {
+		if !deviceAuthCache.UserSignIn {
 			c.Data["json"] = &object.TokenError{
 				Error:            "authorization_pending",
 				ErrorDescription: "authorization pending",
@@ -246,7 +253,18 @@ func (c *ApiController) GetOAuthToken() {
 			return
 		}
 
-		if deviceAuthCacheCast.RequestAt.Add(time.Second * 120).Before(time.Now()) {
+		// a device code only gets a token once, even when the device polls several replicas at the same time
+		deviceAuthCache, err = object.TakeDeviceAuthCache(deviceCode)
+		if err != nil {
+			c.Data["json"] = &object.TokenError{
+				Error:            object.EndpointError,
+				ErrorDescription: err.Error(),
+			}
+			c.SetTokenErrorHttpStatus()
+			c.ServeJSON()
+			return
+		}
+		if deviceAuthCache == nil {
 			c.Data["json"] = &object.TokenError{
 				Error:            "expired_token",
 				ErrorDescription: "token is expired",
@@ -256,9 +274,8 @@ func (c *ApiController) GetOAuthToken() {
 			c.SetTokenErrorHttpStatus()
 			return
 		}
-		object.DeviceAuthMap.Delete(deviceCode)
 
-		username = deviceAuthCacheCast.UserName
+		username = deviceAuthCache.UserName
 	}
 
 	host := c.Ctx.Request.Host
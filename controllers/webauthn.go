@@ -17,7 +17,9 @@ package controllers
 import (
 	"bytes"
 	"encoding/base64"
+	"fmt"
 	"io"
+	"strings"
 
 	"github.com/casdoor/casdoor/form"
 	"github.com/casdoor/casdoor/object"
@@ -45,8 +47,31 @@ func (c *ApiController) WebAuthnSignupBegin() {
 		return
 	}
 
+	organization, err := object.GetOrganizationByUser(user)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	application, err := object.GetApplicationByUser(user)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
 	registerOptions := func(credCreationOpts *protocol.PublicKeyCredentialCreationOptions) {
 		credCreationOpts.CredentialExcludeList = user.CredentialExcludeList()
+		// discoverable credentials (passkeys) allow signing in without entering the username,
+		// they are required when the application signs in with passkeys as they can not be found otherwise
+		credCreationOpts.AuthenticatorSelection.ResidentKey = protocol.ResidentKeyRequirementPreferred
+		if application != nil && application.EnableWebAuthn {
+			credCreationOpts.AuthenticatorSelection.ResidentKey = protocol.ResidentKeyRequirementRequired
+			credCreationOpts.AuthenticatorSelection.RequireResidentKey = protocol.ResidentKeyRequired()
+		}
+		if organization != nil && len(organization.WebauthnAllowedAaguids) != 0 {
+			// the AAGUID can only be trusted when the authenticator provides its attestation
+			credCreationOpts.Attestation = protocol.PreferDirectAttestation
+		}
 	}
 	options, sessionData, err := webauthnObj.BeginRegistration(
 		user,
@@ -65,6 +90,7 @@ func (c *ApiController) WebAuthnSignupBegin() {
 // @Title WebAuthnSignupFinish
 // @Tag User API
 // @Description WebAuthn Registration Flow 2nd stage
+// @Param   displayName    query    string  false       "display name of the credential"
 // @Param   body    body   protocol.CredentialCreationResponse  true        "authenticator attestation Response"
 // @Success 200 {object} controllers.Response "The Response object"
 // @router /webauthn/signup/finish [post]
@@ -93,8 +119,21 @@ func (c *ApiController) WebAuthnSignupFinish() {
 		c.ResponseError(err.Error())
 		return
 	}
+
+	organization, err := object.GetOrganizationByUser(user)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	err = object.CheckWebauthnAaguid(organization, credential)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
 	isGlobalAdmin := c.IsGlobalAdmin()
-	_, err = user.AddCredentials(*credential, isGlobalAdmin)
+	_, err = user.AddCredentials(*credential, c.Input().Get("displayName"), isGlobalAdmin)
 	if err != nil {
 		c.ResponseError(err.Error())
 		return
@@ -118,7 +157,8 @@ func (c *ApiController) WebAuthnSigninBegin() {
 		return
 	}
 
-	options, sessionData, err := webauthnObj.BeginDiscoverableLogin()
+	// a passkey is the only factor of the sign-in, so the authenticator has to verify the user
+	options, sessionData, err := webauthnObj.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
 	if err != nil {
 		c.ResponseError(err.Error())
 		return
@@ -161,7 +201,13 @@ func (c *ApiController) WebAuthnSigninFinish() {
 		return user, nil
 	}
 
-	_, err = webauthnObj.FinishDiscoverableLogin(handler, sessionData, c.Ctx.Request)
+	credential, err := webauthnObj.FinishDiscoverableLogin(handler, sessionData, c.Ctx.Request)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	err = user.UpdateCredentialsUsage(credential)
 	if err != nil {
 		c.ResponseError(err.Error())
 		return
@@ -187,3 +233,145 @@ func (c *ApiController) WebAuthnSigninFinish() {
 	c.Data["json"] = resp
 	c.ServeJSON()
 }
+
+// WebAuthnMfaBegin
+// @Title WebAuthnMfaBegin
+// @Tag Login API
+// @Description begin the WebAuthn verification of the user in the MFA step or of the signed-in user in the MFA setup
+// @Success 200 {object} protocol.CredentialAssertion The CredentialAssertion object
+// @router /webauthn/mfa/begin [get]
+func (c *ApiController) WebAuthnMfaBegin() {
+	userId := c.getMfaUserSession()
+	if userId == "" {
+		userId = c.GetSessionUsername()
+	}
+	if userId == "" {
+		c.ResponseError(c.T("general:Please login first"))
+		return
+	}
+
+	user, err := object.GetUser(userId)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+	if user == nil {
+		c.ResponseError(fmt.Sprintf(c.T("general:The user: %s doesn't exist"), userId))
+		return
+	}
+
+	webauthnObj, err := object.GetWebAuthnObject(c.Ctx.Request.Host)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	options, sessionData, err := object.BeginWebAuthnMfa(webauthnObj, user)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+	c.SetSession("mfaAuthentication", *sessionData)
+	c.Data["json"] = options
+	c.ServeJSON()
+}
+
+// prepareWebAuthnMfa sets what the WebAuthn MFA needs to validate the assertion started by WebAuthnMfaBegin
+func (c *ApiController) prepareWebAuthnMfa(mfaUtil *object.WebAuthnMfa, user *object.User) error {
+	webauthnObj, err := object.GetWebAuthnObject(c.Ctx.Request.Host)
+	if err != nil {
+		return err
+	}
+
+	sessionData, ok := c.GetSession("mfaAuthentication").(webauthn.SessionData)
+	if !ok {
+		return fmt.Errorf(c.T("webauthn:Please call WebAuthnSigninBegin first"))
+	}
+	c.DelSession("mfaAuthentication")
+
+	mfaUtil.WebAuthn = webauthnObj
+	mfaUtil.User = user
+	mfaUtil.SessionData = &sessionData
+	return nil
+}
+
+func (c *ApiController) getWebAuthnCredentialUser(id string) (*object.User, bool) {
+	if id == "" {
+		return c.RequireSignedInUser()
+	}
+
+	user, err := object.GetUser(id)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return nil, false
+	}
+	if user == nil {
+		c.ResponseError(fmt.Sprintf(c.T("general:The user: %s doesn't exist"), id))
+		return nil, false
+	}
+
+	if !c.IsAdminOrSelf(user) {
+		c.ResponseError(c.T("auth:Unauthorized operation"))
+		return nil, false
+	}
+	return user, true
+}
+
+// GetWebAuthnCredentials
+// @Title GetWebAuthnCredentials
+// @Tag User API
+// @Description get the WebAuthn credentials of a user, the signed-in user by default
+// @Param   id     query    string  false        "The id ( owner/name ) of the user"
+// @Success 200 {array} object.WebauthnCredential The Response object
+// @router /webauthn/get-credentials [get]
+func (c *ApiController) GetWebAuthnCredentials() {
+	user, ok := c.getWebAuthnCredentialUser(c.Input().Get("id"))
+	if !ok {
+		return
+	}
+
+	c.ResponseOk(user.WebauthnCredentials)
+}
+
+// RenameWebAuthnCredential
+// @Title RenameWebAuthnCredential
+// @Tag User API
+// @Description rename a WebAuthn credential
+// @Param   id     formData    string  false        "The id ( owner/name ) of the user"
+// @Param   credentialID     formData    string  true        "The base64 encoded credential ID"
+// @Param   displayName     formData    string  true        "The new display name"
+// @Success 200 {object} controllers.Response The Response object
+// @router /webauthn/rename-credential [post]
+func (c *ApiController) RenameWebAuthnCredential() {
+	user, ok := c.getWebAuthnCredentialUser(c.Ctx.Request.Form.Get("id"))
+	if !ok {
+		return
+	}
+
+	displayName := strings.TrimSpace(c.Ctx.Request.Form.Get("displayName"))
+	if displayName == "" {
+		c.ResponseError(c.T("general:Missing parameter") + ": displayName")
+		return
+	}
+
+	c.Data["json"] = wrapActionResponse(user.RenameCredentials(c.Ctx.Request.Form.Get("credentialID"), displayName))
+	c.ServeJSON()
+}
+
+// DeleteWebAuthnCredential
+// @Title DeleteWebAuthnCredential
+// @Tag User API
+// @Description revoke a WebAuthn credential
+// @Param   id     formData    string  false        "The id ( owner/name ) of the user"
+// @Param   credentialID     formData    string  true        "The base64 encoded credential ID"
+// @Success 200 {object} controllers.Response The Response object
+// @router /webauthn/delete-credential [post]
+func (c *ApiController) DeleteWebAuthnCredential() {
+	user, ok := c.getWebAuthnCredentialUser(c.Ctx.Request.Form.Get("id"))
+	if !ok {
+		return
+	}
+
+	c.Data["json"] = wrapActionResponse(user.DeleteCredentials(c.Ctx.Request.Form.Get("credentialID")))
+	c.ServeJSON()
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/beego/beego"
 	"github.com/beego/beego/logs"
 	_ "github.com/beego/beego/session/redis"
+	"github.com/casdoor/casdoor/audit"
 	"github.com/casdoor/casdoor/authz"
 	"github.com/casdoor/casdoor/conf"
 	"github.com/casdoor/casdoor/controllers"
@@ -29,6 +30,8 @@ import (
 	"github.com/casdoor/casdoor/proxy"
 	"github.com/casdoor/casdoor/radius"
 	"github.com/casdoor/casdoor/routers"
+	casdoorsync "github.com/casdoor/casdoor/sync"
+	"github.com/casdoor/casdoor/sync_v2"
 	"github.com/casdoor/casdoor/util"
 )
 
@@ -36,6 +39,9 @@ func main() {
 	object.InitFlag()
 	object.InitAdapter()
 	object.CreateTables()
+	if object.RunColumnEncryptionCommand() {
+		return
+	}
 
 	object.InitDb()
 	object.InitDefaultStorageProvider()
@@ -46,7 +52,33 @@ func main() {
 	object.InitFromFile()
 	object.InitCasvisorConfig()
 
+	err := audit.InitSinks(conf.GetConfigString("auditSinks"))
+	if err != nil {
+		panic(err)
+	}
+
+	err = sync_v2.InitReplication(conf.GetConfigString("replicationConfig"))
+	if err != nil {
+		panic(err)
+	}
+
+	err = casdoorsync.InitSyncJob(conf.GetConfigString("syncConfig"))
+	if err != nil {
+		panic(err)
+	}
+
 	util.SafeGoroutine(func() { object.RunSyncUsersJob() })
+	util.SafeGoroutine(func() { object.RunRecordRetentionJob() })
+	util.SafeGoroutine(func() { object.RunChangeEventRetentionJob() })
+	util.SafeGoroutine(func() { object.RunCertRotationJob() })
+	util.SafeGoroutine(func() { object.RunSamlMetadataRefreshJob() })
+	util.SafeGoroutine(func() { object.RunTicketPurgeJob() })
+	util.SafeGoroutine(func() { object.RunPermissionEnforcerWatcher() })
+	util.SafeGoroutine(func() { object.RunAccessGrantJob() })
+	util.SafeGoroutine(func() { object.RunAccessRequestJob() })
+	util.SafeGoroutine(func() { object.RunAccessReviewJob() })
+	util.SafeGoroutine(func() { sync_v2.RunReplicationMonitor() })
+	util.SafeGoroutine(func() { casdoorsync.RunSyncJob() })
 	util.SafeGoroutine(func() { controllers.InitCLIDownloader() })
 
 	// beego.DelStaticPath("/static")
@@ -80,7 +112,7 @@ func main() {
 
 	var logAdapter string
 	logConfigMap := make(map[string]interface{})
-	err := json.Unmarshal([]byte(conf.GetConfigString("logConfig")), &logConfigMap)
+	err = json.Unmarshal([]byte(conf.GetConfigString("logConfig")), &logConfigMap)
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,355 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/beego/beego/logs"
+	"github.com/casdoor/casdoor/util"
+	"github.com/xorm-io/core"
+)
+
+const accessGrantJobInterval = time.Minute
+
+// the name of the lease of the job, it outlives a missed run of its holder and expires soon after the holder stops
+const accessGrantJobLease = "access-grant"
+
+// AccessGrant limits the membership of a user, group or role in a role, or of a user or role in a permission,
+// to a time window. An empty start or end time leaves the window open on that side.
+type AccessGrant struct {
+	Member    string `json:"member"`
+	StartTime string `json:"startTime"`
+	EndTime   string `json:"endTime"`
+}
+
+func parseAccessGrantTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func (grant *AccessGrant) getWindow() (time.Time, time.Time) {
+	// the grants are checked when saved, a time that can't be parsed leaves the window open
+	startTime, _ := parseAccessGrantTime(grant.StartTime)
+	endTime, _ := parseAccessGrantTime(grant.EndTime)
+	return startTime, endTime
+}
+
+func (grant *AccessGrant) isActive(t time.Time) bool {
+	startTime, endTime := grant.getWindow()
+	if !startTime.IsZero() && t.Before(startTime) {
+		return false
+	}
+	return endTime.IsZero() || t.Before(endTime)
+}
+
+func (grant *AccessGrant) isExpired(t time.Time) bool {
+	_, endTime := grant.getWindow()
+	return !endTime.IsZero() && !t.Before(endTime)
+}
+
+func getAccessGrant(grants []*AccessGrant, member string) *AccessGrant {
+	for _, grant := range grants {
+		if grant.Member == member {
+			return grant
+		}
+	}
+	return nil
+}
+
+// isPermanentMember tells whether the member is in the list without a window, a time-bound grant would end its membership
+func isPermanentMember(members []string, grants []*AccessGrant, member string) bool {
+	return util.InSlice(members, member) && getAccessGrant(grants, member) == nil
+}
+
+func deleteAccessGrant(grants []*AccessGrant, member string) []*AccessGrant {
+	res := []*AccessGrant{}
+	for _, grant := range grants {
+		if grant.Member != member {
+			res = append(res, grant)
+		}
+	}
+	return res
+}
+
+func isMemberActive(grants []*AccessGrant, member string, t time.Time) bool {
+	grant := getAccessGrant(grants, member)
+	return grant == nil || grant.isActive(t)
+}
+
+// getActiveMembers drops the members whose grants haven't started or have expired at t, the others have no window
+func getActiveMembers(members []string, grants []*AccessGrant, t time.Time) []string {
+	if len(grants) == 0 {
+		return members
+	}
+
+	res := []string{}
+	for _, member := range members {
+		if isMemberActive(grants, member, t) {
+			res = append(res, member)
+		}
+	}
+	return res
+}
+
+// checkAccessGrants verifies the grants are windows of the given members
+func checkAccessGrants(grants []*AccessGrant, memberLists ...[]string) error {
+	visited := map[string]bool{}
+	for _, grant := range grants {
+		if visited[grant.Member] {
+			return fmt.Errorf("the member: %s has more than one grant", grant.Member)
+		}
+		visited[grant.Member] = true
+
+		isMember := false
+		for _, members := range memberLists {
+			if util.InSlice(members, grant.Member) {
+				isMember = true
+			}
+		}
+		if !isMember {
+			return fmt.Errorf("the grant member: %s is not in the users, groups or roles", grant.Member)
+		}
+
+		startTime, err := parseAccessGrantTime(grant.StartTime)
+		if err != nil {
+			return fmt.Errorf("invalid start time of the grant of %s: %s", grant.Member, err.Error())
+		}
+		endTime, err := parseAccessGrantTime(grant.EndTime)
+		if err != nil {
+			return fmt.Errorf("invalid end time of the grant of %s: %s", grant.Member, err.Error())
+		}
+		if !startTime.IsZero() && !endTime.IsZero() && !startTime.Before(endTime) {
+			return fmt.Errorf("the grant of %s should end after it starts", grant.Member)
+		}
+	}
+	return nil
+}
+
+func splitExpiredAccessGrants(grants []*AccessGrant, t time.Time) ([]*AccessGrant, []*AccessGrant) {
+	active := []*AccessGrant{}
+	expired := []*AccessGrant{}
+	for _, grant := range grants {
+		if grant.isExpired(t) {
+			expired = append(expired, grant)
+		} else {
+			active = append(active, grant)
+		}
+	}
+	return active, expired
+}
+
+// getNextAccessGrantTime returns when the policies of the grants saved at t change next: the first start after t,
+// or the first end, which is t or before for an expired grant still to remove. It is empty when nothing changes.
+// The time is in UTC, so that the times of all the grants compare as strings.
+func getNextAccessGrantTime(grants []*AccessGrant, t time.Time) string {
+	next := time.Time{}
+	for _, grant := range grants {
+		startTime, endTime := grant.getWindow()
+		if !startTime.IsZero() && startTime.After(t) && (next.IsZero() || startTime.Before(next)) {
+			next = startTime
+		}
+		if !endTime.IsZero() && (next.IsZero() || endTime.Before(next)) {
+			next = endTime
+		}
+	}
+
+	if next.IsZero() {
+		return ""
+	}
+	return formatAccessGrantTime(next)
+}
+
+func formatAccessGrantTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func (role *Role) BeforeInsert() {
+	role.NextGrantTime = getNextAccessGrantTime(role.Grants, time.Now())
+}
+
+func (role *Role) BeforeUpdate() {
+	role.NextGrantTime = getNextAccessGrantTime(role.Grants, time.Now())
+}
+
+func (permission *Permission) BeforeInsert() {
+	permission.NextGrantTime = getNextAccessGrantTime(permission.Grants, time.Now())
+}
+
+func (permission *Permission) BeforeUpdate() {
+	permission.NextGrantTime = getNextAccessGrantTime(permission.Grants, time.Now())
+}
+
+func addAccessGrantRecord(owner string, action string, object string, grant *AccessGrant) {
+	_, memberName := util.GetOwnerAndNameFromIdNoCheck(grant.Member)
+	addUserActionRecord(owner, memberName, action, util.StructToJson(map[string]string{
+		"object":    object,
+		"member":    grant.Member,
+		"startTime": grant.StartTime,
+		"endTime":   grant.EndTime,
+	}))
+}
+
+// refreshRoleGrants removes the expired members of a role whose next grant time has come, and saves the policies
+// of the grants starting or ending. UpdateRole removes all the policies of the role and adds the ones active now.
+func refreshRoleGrants(role *Role, t time.Time) error {
+	grants, expired := splitExpiredAccessGrants(role.Grants, t)
+	role.Grants = grants
+	for _, grant := range expired {
+		role.Users = util.DeleteVal(role.Users, grant.Member)
+		role.Groups = util.DeleteVal(role.Groups, grant.Member)
+		role.Roles = util.DeleteVal(role.Roles, grant.Member)
+	}
+
+	_, err := UpdateRole(role.GetId(), role)
+	if err != nil {
+		return err
+	}
+
+	for _, grant := range expired {
+		addAccessGrantRecord(role.Owner, "expire-role-grant", role.GetId(), grant)
+	}
+	return nil
+}
+
+func refreshPermissionGrants(permission *Permission, t time.Time) error {
+	grants, expired := splitExpiredAccessGrants(permission.Grants, t)
+	permission.Grants = grants
+	for _, grant := range expired {
+		permission.Users = util.DeleteVal(permission.Users, grant.Member)
+		permission.Roles = util.DeleteVal(permission.Roles, grant.Member)
+	}
+
+	_, err := UpdatePermission(permission.GetId(), permission)
+	if err != nil {
+		return err
+	}
+
+	for _, grant := range expired {
+		addAccessGrantRecord(permission.Owner, "expire-permission-grant", permission.GetId(), grant)
+	}
+	return nil
+}
+
+// initAccessGrantTimes sets the next grant times of the roles and permissions saved without them, before the
+// column existed. It loads all the roles and permissions without one, so it only runs once per instance.
+func initAccessGrantTimes(t time.Time) error {
+	roles := []*Role{}
+	err := ormer.Engine.Where("next_grant_time is null or next_grant_time = ''").Find(&roles)
+	if err != nil {
+		return err
+	}
+
+	for _, role := range roles {
+		nextGrantTime := getNextAccessGrantTime(role.Grants, t)
+		if nextGrantTime != "" {
+			// the hook computes the same time from the grants
+			_, err = ormer.Engine.ID(core.PK{role.Owner, role.Name}).Cols("next_grant_time").Update(role)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	permissions := []*Permission{}
+	err = ormer.Engine.Where("next_grant_time is null or next_grant_time = ''").Find(&permissions)
+	if err != nil {
+		return err
+	}
+
+	for _, permission := range permissions {
+		nextGrantTime := getNextAccessGrantTime(permission.Grants, t)
+		if nextGrantTime != "" {
+			_, err = ormer.Engine.ID(core.PK{permission.Owner, permission.Name}).Cols("next_grant_time").Update(permission)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// refreshAccessGrants refreshes the roles and permissions whose next grant time is t or before
+func refreshAccessGrants(t time.Time) error {
+	var lastErr error
+
+	roles := []*Role{}
+	err := ormer.Engine.Where("next_grant_time != '' and next_grant_time <= ?", formatAccessGrantTime(t)).Find(&roles)
+	if err != nil {
+		return err
+	}
+
+	for _, role := range roles {
+		err = refreshRoleGrants(role, t)
+		if err != nil {
+			logs.Error(fmt.Sprintf("refreshAccessGrants() error for role %s: %s", role.GetId(), err.Error()))
+			lastErr = err
+		}
+	}
+
+	permissions := []*Permission{}
+	err = ormer.Engine.Where("next_grant_time != '' and next_grant_time <= ?", formatAccessGrantTime(t)).Find(&permissions)
+	if err != nil {
+		return err
+	}
+
+	for _, permission := range permissions {
+		err = refreshPermissionGrants(permission, t)
+		if err != nil {
+			logs.Error(fmt.Sprintf("refreshAccessGrants() error for permission %s: %s", permission.GetId(), err.Error()))
+			lastErr = err
+		}
+	}
+
+	return lastErr
+}
+
+// RunAccessGrantJob saves the policies of the time-bound grants when they start, and removes them when they expire.
+// Only the instance holding the lease of the job runs it, the grants started or expired while no instance did are
+// caught up with by the next run.
+func RunAccessGrantJob() {
+	ticker := time.NewTicker(accessGrantJobInterval)
+	defer ticker.Stop()
+
+	isInitialized := false
+	for ; true; <-ticker.C {
+		isHolder, err := acquireJobLease(accessGrantJobLease, 2*accessGrantJobInterval)
+		if err != nil {
+			logs.Error(fmt.Sprintf("RunAccessGrantJob() error: %s", err.Error()))
+			continue
+		}
+		if !isHolder {
+			continue
+		}
+
+		now := time.Now()
+		if !isInitialized {
+			err = initAccessGrantTimes(now)
+			if err != nil {
+				logs.Error(fmt.Sprintf("RunAccessGrantJob() error: %s", err.Error()))
+				continue
+			}
+			isInitialized = true
+		}
+
+		err = refreshAccessGrants(now)
+		if err != nil {
+			logs.Error(fmt.Sprintf("RunAccessGrantJob() error: %s", err.Error()))
+		}
+	}
+}
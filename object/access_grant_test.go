@@ -0,0 +1,128 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/casdoor/casdoor/util"
+)
+
+func TestAccessGrants(t *testing.T) {
+	now := time.Now()
+	past := util.Time2String(now.Add(-time.Hour))
+	future := util.Time2String(now.Add(time.Hour))
+
+	permission := &Permission{
+		Owner:     "built-in",
+		Name:      "permission-grant",
+		Users:     []string{"built-in/alice", "built-in/bob", "built-in/carol", "built-in/dave"},
+		Resources: []string{"data1"},
+		Actions:   []string{"Read"},
+		Effect:    "Allow",
+		Grants: []*AccessGrant{
+			{Member: "built-in/bob", StartTime: past, EndTime: future},
+			{Member: "built-in/carol", EndTime: past},
+			{Member: "built-in/dave", StartTime: future},
+		},
+	}
+
+	err := checkAccessGrants(permission.Grants, permission.Users, permission.Roles)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the members without a grant and the ones in their window have policies
+	users := []string{}
+	for _, policy := range getPolicies(permission) {
+		users = append(users, policy[0])
+	}
+	if !reflect.DeepEqual(users, []string{"built-in/alice", "built-in/bob"}) {
+		t.Fatalf("unexpected users of the active policies: %v", users)
+	}
+
+	// the policies of all the members are removed, whatever their windows
+	if len(getAllPolicies(permission)) != 4 {
+		t.Fatalf("unexpected count of all the policies: %d", len(getAllPolicies(permission)))
+	}
+
+	if !isPermanentMember(permission.Users, permission.Grants, "built-in/alice") || isPermanentMember(permission.Users, permission.Grants, "built-in/bob") || isPermanentMember(permission.Users, permission.Grants, "built-in/eve") {
+		t.Fatal("only the members without a grant should be permanent")
+	}
+
+	active, expired := splitExpiredAccessGrants(permission.Grants, now)
+	if len(active) != 2 || len(expired) != 1 || expired[0].Member != "built-in/carol" {
+		t.Fatalf("unexpected expired grants: %v", expired)
+	}
+
+	// the job refreshes the policies when a window starts or ends, and retries the expired grants left
+	pastTime, _ := parseAccessGrantTime(past)
+	futureTime, _ := parseAccessGrantTime(future)
+	if res := getNextAccessGrantTime(permission.Grants, now); res != formatAccessGrantTime(pastTime) {
+		t.Fatalf("an expired grant should be due: %s", res)
+	}
+	if res := getNextAccessGrantTime(active, now); res != formatAccessGrantTime(futureTime) || res <= formatAccessGrantTime(now) {
+		t.Fatalf("the grants should be due when they start or end: %s", res)
+	}
+	if res := getNextAccessGrantTime([]*AccessGrant{{Member: "built-in/bob", StartTime: past}}, now); res != "" {
+		t.Fatalf("a started grant without an end should not be due: %s", res)
+	}
+
+	invalidGrants := [][]*AccessGrant{
+		{{Member: "built-in/eve"}},
+		{{Member: "built-in/alice", StartTime: future, EndTime: past}},
+		{{Member: "built-in/alice", EndTime: "tomorrow"}},
+		{{Member: "built-in/alice"}, {Member: "built-in/alice"}},
+	}
+	for _, grants := range invalidGrants {
+		if checkAccessGrants(grants, permission.Users) == nil {
+			t.Fatalf("the grants should be invalid: %s", util.StructToJson(grants))
+		}
+	}
+}
+
+func TestAcquireJobLease(t *testing.T) {
+	setupTestOrmer(t, &JobLease{})
+
+	isHolder, err := acquireJobLease("job", time.Minute)
+	if err != nil || !isHolder {
+		t.Fatalf("the lease should be taken: %v", err)
+	}
+	isHolder, err = acquireJobLease("job", time.Minute)
+	if err != nil || !isHolder {
+		t.Fatalf("the lease should be renewed: %v", err)
+	}
+
+	// another instance waits for the lease to expire
+	_, err = ormer.Engine.ID("job").Cols("holder").Update(&JobLease{Holder: "another instance"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	isHolder, err = acquireJobLease("job", time.Minute)
+	if err != nil || isHolder {
+		t.Fatalf("the lease of another instance should not be taken: %v", err)
+	}
+
+	_, err = ormer.Engine.ID("job").Cols("expire_time").Update(&JobLease{ExpireTime: time.Now().Add(-time.Second).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	isHolder, err = acquireJobLease("job", time.Minute)
+	if err != nil || !isHolder {
+		t.Fatalf("the expired lease should be taken over: %v", err)
+	}
+}
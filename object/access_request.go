@@ -0,0 +1,766 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/beego/beego/logs"
+	"github.com/casdoor/casdoor/util"
+	"github.com/xorm-io/core"
+)
+
+const (
+	AccessRequestStatePending  = "Pending"
+	AccessRequestStateApproved = "Approved"
+	AccessRequestStateDenied   = "Denied"
+	AccessRequestStateCanceled = "Canceled"
+)
+
+const (
+	AccessRequestActionSubmit  = "Submit"
+	AccessRequestActionApprove = "Approve"
+	AccessRequestActionDeny    = "Deny"
+	AccessRequestActionCancel  = "Cancel"
+	AccessRequestActionTimeout = "Timeout"
+	AccessRequestActionGrant   = "Grant"
+	AccessRequestActionFail    = "Fail"
+)
+
+const accessRequestJobInterval = time.Minute
+
+// the name of the lease of the job, see accessGrantJobLease
+const accessRequestJobLease = "access-request"
+
+// AccessRequestEvent is an entry of the history of an access request
+type AccessRequestEvent struct {
+	Time    string `json:"time"`
+	User    string `json:"user"`
+	Action  string `json:"action"`
+	Step    int    `json:"step"`
+	Comment string `json:"comment"`
+}
+
+// AccessRequest is the request of a user for a role, a group or a permission, going through the steps of the
+// workflow of its target. The workflow is copied when the request is submitted, later changes don't affect it.
+type AccessRequest struct {
+	Owner       string `xorm:"varchar(100) notnull pk" json:"owner"`
+	Name        string `xorm:"varchar(100) notnull pk" json:"name"`
+	CreatedTime string `xorm:"varchar(100)" json:"createdTime"`
+	UpdatedTime string `xorm:"varchar(100)" json:"updatedTime"`
+
+	User       string `xorm:"varchar(100) index" json:"user"`
+	TargetType string `xorm:"varchar(100)" json:"targetType"`
+	Target     string `xorm:"varchar(100)" json:"target"`
+	Reason     string `xorm:"varchar(500)" json:"reason"`
+	// Duration is the minutes the role or permission is granted for, 0 for no end
+	Duration int `json:"duration"`
+	// Permission is the pending permission copied for the user from the requested one
+	Permission string `xorm:"varchar(100)" json:"permission"`
+
+	Workflow             string                `xorm:"varchar(100)" json:"workflow"`
+	Steps                []*AccessWorkflowStep `xorm:"mediumtext" json:"steps"`
+	Timeout              int                   `json:"timeout"`
+	NotificationProvider string                `xorm:"varchar(100)" json:"notificationProvider"`
+
+	CurrentStep int                   `json:"currentStep"`
+	Approvers   []string              `xorm:"mediumtext" json:"approvers"`
+	Deadline    string                `xorm:"varchar(100)" json:"deadline"`
+	State       string                `xorm:"varchar(100) index" json:"state"`
+	History     []*AccessRequestEvent `xorm:"mediumtext" json:"history"`
+}
+
+func GetAccessRequestCount(owner, field, value string) (int64, error) {
+	session := GetSession(owner, -1, -1, field, value, "", "")
+	return session.Count(&AccessRequest{})
+}
+
+func GetAccessRequests(owner string) ([]*AccessRequest, error) {
+	requests := []*AccessRequest{}
+	err := ormer.Engine.Desc("created_time").Find(&requests, &AccessRequest{Owner: owner})
+	if err != nil {
+		return requests, err
+	}
+
+	return requests, nil
+}
+
+func GetPaginationAccessRequests(owner string, offset, limit int, field, value, sortField, sortOrder string) ([]*AccessRequest, error) {
+	requests := []*AccessRequest{}
+	session := GetSession(owner, offset, limit, field, value, sortField, sortOrder)
+	err := session.Find(&requests)
+	if err != nil {
+		return requests, err
+	}
+
+	return requests, nil
+}
+
+// GetAccessRequestsByUser returns the requests submitted by the user
+func GetAccessRequestsByUser(userId string) ([]*AccessRequest, error) {
+	requests := []*AccessRequest{}
+	err := ormer.Engine.Desc("created_time").Find(&requests, &AccessRequest{User: userId})
+	if err != nil {
+		return requests, err
+	}
+
+	return requests, nil
+}
+
+// GetAccessRequestsByApprover returns the pending requests waiting for the approval of the user
+func GetAccessRequestsByApprover(userId string) ([]*AccessRequest, error) {
+	requests := []*AccessRequest{}
+	err := ormer.Engine.Desc("created_time").Where("approvers like ?", "%\""+userId+"\"%").Find(&requests, &AccessRequest{State: AccessRequestStatePending})
+	if err != nil {
+		return requests, err
+	}
+
+	res := []*AccessRequest{}
+	for _, request := range requests {
+		if util.InSlice(request.Approvers, userId) {
+			res = append(res, request)
+		}
+	}
+	return res, nil
+}
+
+func getAccessRequest(owner string, name string) (*AccessRequest, error) {
+	if owner == "" || name == "" {
+		return nil, nil
+	}
+
+	request := AccessRequest{Owner: owner, Name: name}
+	existed, err := ormer.Engine.Get(&request)
+	if err != nil {
+		return &request, err
+	}
+
+	if existed {
+		return &request, nil
+	} else {
+		return nil, nil
+	}
+}
+
+func GetAccessRequest(id string) (*AccessRequest, error) {
+	owner, name := util.GetOwnerAndNameFromIdNoCheck(id)
+	return getAccessRequest(owner, name)
+}
+
+func DeleteAccessRequest(request *AccessRequest) (bool, error) {
+	affected, err := ormer.Engine.ID(core.PK{request.Owner, request.Name}).Delete(&AccessRequest{})
+	if err != nil {
+		return false, err
+	}
+
+	return affected != 0, nil
+}
+
+func (request *AccessRequest) GetId() string {
+	return fmt.Sprintf("%s/%s", request.Owner, request.Name)
+}
+
+func (request *AccessRequest) addEvent(user string, action string, comment string) {
+	request.History = append(request.History, &AccessRequestEvent{
+		Time:    util.GetCurrentTime(),
+		User:    user,
+		Action:  action,
+		Step:    request.CurrentStep,
+		Comment: comment,
+	})
+}
+
+// checkAccessRequestApprovers verifies no user approved more than one step of the request, so that the
+// steps are signed off by distinct approvers. A step opened again after a failed grant may be approved again.
+func checkAccessRequestApprovers(request *AccessRequest) error {
+	approvedSteps := map[string]int{}
+	for _, event := range request.History {
+		if event.Action != AccessRequestActionApprove {
+			continue
+		}
+
+		step, ok := approvedSteps[event.User]
+		if ok && step != event.Step {
+			return fmt.Errorf("the user: %s has already approved the step %d of the access request: %s", event.User, step+1, request.GetId())
+		}
+		approvedSteps[event.User] = event.Step
+	}
+	return nil
+}
+
+// saveAccessRequest saves the request if it is still at the state and step it was read at,
+// so that two approvers reviewing the same step at once can't both move it forward
+func saveAccessRequest(request *AccessRequest, state string, step int) error {
+	err := checkAccessRequestApprovers(request)
+	if err != nil {
+		return err
+	}
+
+	request.UpdatedTime = util.GetCurrentTime()
+	affected, err := ormer.Engine.ID(core.PK{request.Owner, request.Name}).
+		Where("state = ? and current_step = ?", state, step).AllCols().Update(request)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("the access request: %s has been changed meanwhile", request.GetId())
+	}
+	return nil
+}
+
+func getUserIdInOrganization(owner string, user string) string {
+	if strings.Contains(user, "/") {
+		return user
+	}
+	return util.GetId(owner, user)
+}
+
+// checkAccessTarget verifies the target exists in the organization, and returns the permission for a permission target
+func checkAccessTarget(owner string, targetType string, target string) (*Permission, error) {
+	targetOwner, targetName := util.GetOwnerAndNameFromIdNoCheck(target)
+	if targetOwner != owner || targetName == "" {
+		return nil, fmt.Errorf("the %s: %s doesn't exist", strings.ToLower(targetType), target)
+	}
+
+	var existed bool
+	var permission *Permission
+	var err error
+	switch targetType {
+	case AccessTargetRole:
+		var role *Role
+		role, err = getRole(targetOwner, targetName)
+		existed = role != nil
+	case AccessTargetGroup:
+		var group *Group
+		group, err = getGroup(targetOwner, targetName)
+		existed = group != nil
+	case AccessTargetPermission:
+		permission, err = getPermission(targetOwner, targetName)
+		existed = permission != nil
+	default:
+		return nil, fmt.Errorf("invalid target type of the access request: %s", targetType)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	if !existed {
+		return nil, fmt.Errorf("the %s: %s doesn't exist", strings.ToLower(targetType), target)
+	}
+	return permission, nil
+}
+
+func getOrganizationAdmins(owner string) ([]string, error) {
+	users := []*User{}
+	err := ormer.Engine.Find(&users, &User{Owner: owner, IsAdmin: true})
+	if err != nil {
+		return nil, err
+	}
+
+	res := []string{}
+	for _, user := range users {
+		if !user.IsForbidden && !user.IsDeleted {
+			res = append(res, user.GetId())
+		}
+	}
+	return res, nil
+}
+
+func getGroupManagers(groupIds []string) ([]string, error) {
+	res := []string{}
+	for _, groupId := range groupIds {
+		owner, name := util.GetOwnerAndNameFromIdNoCheck(groupId)
+		group, err := getGroup(owner, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if group != nil && group.Manager != "" {
+			res = append(res, getUserIdInOrganization(group.Owner, group.Manager))
+		}
+	}
+	return res, nil
+}
+
+// getAccessRequestApprovers returns the approvers of the current step, the requester never approves its own request
+func getAccessRequestApprovers(request *AccessRequest) ([]string, error) {
+	step := request.Steps[request.CurrentStep]
+
+	var approvers []string
+	var err error
+	switch step.ApproverType {
+	case ApproverTypeUsers:
+		for _, approver := range step.Approvers {
+			approvers = append(approvers, getUserIdInOrganization(request.Owner, approver))
+		}
+	case ApproverTypeOwner:
+		approvers, err = getOrganizationAdmins(request.Owner)
+	case ApproverTypeManager:
+		groupIds := []string{request.Target}
+		if request.TargetType != AccessTargetGroup {
+			var user *User
+			user, err = GetUser(request.User)
+			if err != nil {
+				return nil, err
+			}
+			if user == nil {
+				return nil, fmt.Errorf("the user: %s doesn't exist", request.User)
+			}
+			groupIds = user.Groups
+		}
+		approvers, err = getGroupManagers(groupIds)
+	default:
+		return nil, fmt.Errorf("invalid approver type of the step %d of the access request: %s", request.CurrentStep+1, request.GetId())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res := []string{}
+	for _, approver := range approvers {
+		if approver != request.User && !util.InSlice(res, approver) {
+			res = append(res, approver)
+		}
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("no approver is found for the step %d of the access request: %s", request.CurrentStep+1, request.GetId())
+	}
+	return res, nil
+}
+
+// startAccessRequestStep routes the request to the approvers of its current step
+func startAccessRequestStep(request *AccessRequest) error {
+	approvers, err := getAccessRequestApprovers(request)
+	if err != nil {
+		return err
+	}
+
+	request.Approvers = approvers
+	request.Deadline = ""
+	if request.Timeout > 0 {
+		request.Deadline = util.Time2String(time.Now().Add(time.Duration(request.Timeout) * time.Hour))
+	}
+	return nil
+}
+
+func SubmitAccessRequest(user *User, request *AccessRequest) (*AccessRequest, error) {
+	target, err := checkAccessTarget(user.Owner, request.TargetType, request.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Duration < 0 {
+		return nil, fmt.Errorf("the duration of the access request should not be negative")
+	}
+	if request.Duration > 0 && request.TargetType == AccessTargetGroup {
+		return nil, fmt.Errorf("the group memberships can't be time-bound")
+	}
+
+	requests, err := GetAccessRequestsByUser(user.GetId())
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range requests {
+		if r.State == AccessRequestStatePending && r.TargetType == request.TargetType && r.Target == request.Target {
+			return nil, fmt.Errorf("the access request: %s for %s is already pending", r.GetId(), r.Target)
+		}
+	}
+
+	workflow, err := getAccessWorkflowByTarget(user.Owner, request.TargetType, request.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &AccessRequest{
+		Owner:                user.Owner,
+		Name:                 util.GenerateId(),
+		CreatedTime:          util.GetCurrentTime(),
+		UpdatedTime:          util.GetCurrentTime(),
+		User:                 user.GetId(),
+		TargetType:           request.TargetType,
+		Target:               request.Target,
+		Reason:               request.Reason,
+		Duration:             request.Duration,
+		Workflow:             workflow.Name,
+		Steps:                workflow.Steps,
+		Timeout:              workflow.Timeout,
+		NotificationProvider: workflow.NotificationProvider,
+		State:                AccessRequestStatePending,
+		History:              []*AccessRequestEvent{},
+	}
+	res.addEvent(res.User, AccessRequestActionSubmit, request.Reason)
+
+	err = startAccessRequestStep(res)
+	if err != nil {
+		return nil, err
+	}
+
+	if target != nil {
+		permission, err := newRequestedPermission(target, user, request.Reason, "req")
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = AddPermission(permission)
+		if err != nil {
+			return nil, err
+		}
+		res.Permission = permission.GetId()
+	}
+
+	_, err = ormer.Engine.Insert(res)
+	if err != nil {
+		return nil, err
+	}
+
+	addUserActionRecord(user.Owner, user.Name, "submit-access-request", util.StructToJson(res))
+	notifyAccessRequestApprovers(res)
+	return res, nil
+}
+
+// grantAccessRequest gives the requester the role, group or permission of an approved request
+func grantAccessRequest(request *AccessRequest, approver string) error {
+	owner, name := util.GetOwnerAndNameFromIdNoCheck(request.Target)
+
+	var grants []*AccessGrant
+	if request.Duration > 0 {
+		now := time.Now()
+		grants = []*AccessGrant{{
+			Member:    request.User,
+			StartTime: util.Time2String(now),
+			EndTime:   util.Time2String(now.Add(time.Duration(request.Duration) * time.Minute)),
+		}}
+	}
+
+	switch request.TargetType {
+	case AccessTargetRole:
+		role, err := getRole(owner, name)
+		if err != nil {
+			return err
+		}
+		if role == nil {
+			return fmt.Errorf("the role: %s doesn't exist", request.Target)
+		}
+
+		// the user already has the role for good, the window of the request would remove it once expired
+		if isPermanentMember(role.Users, role.Grants, request.User) {
+			return nil
+		}
+
+		if !util.InSlice(role.Users, request.User) {
+			role.Users = append(role.Users, request.User)
+		}
+		// a previous window of the user is replaced, a request with no duration removes it
+		for _, grant := range role.Grants {
+			if grant.Member != request.User {
+				grants = append(grants, grant)
+			}
+		}
+		role.Grants = grants
+
+		_, err = UpdateRole(role.GetId(), role)
+		return err
+	case AccessTargetGroup:
+		user, err := GetUser(request.User)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return fmt.Errorf("the user: %s doesn't exist", request.User)
+		}
+
+		if !util.InSlice(user.Groups, request.Target) {
+			user.Groups = append(user.Groups, request.Target)
+		}
+		_, err = UpdateUser(user.GetId(), user, []string{"groups"}, false)
+		return err
+	case AccessTargetPermission:
+		permission, err := GetPermission(request.Permission)
+		if err != nil {
+			return err
+		}
+		if permission == nil {
+			return fmt.Errorf("the permission: %s doesn't exist", request.Permission)
+		}
+
+		_, approverName := util.GetOwnerAndNameFromIdNoCheck(approver)
+		permission.Users = []string{request.User}
+		permission.Grants = grants
+		permission.State = PermissionStateApproved
+		permission.Approver = approverName
+		permission.ApproveTime = util.GetCurrentTime()
+
+		_, err = UpdatePermission(permission.GetId(), permission)
+		return err
+	default:
+		return fmt.Errorf("invalid target type of the access request: %s", request.TargetType)
+	}
+}
+
+// closeAccessRequest denies or cancels the request, rejecting its pending permission
+func closeAccessRequest(request *AccessRequest, state string, user string, action string, comment string) error {
+	step := request.CurrentStep
+	request.State = state
+	request.Approvers = []string{}
+	request.Deadline = ""
+	request.addEvent(user, action, comment)
+
+	err := saveAccessRequest(request, AccessRequestStatePending, step)
+	if err != nil {
+		return err
+	}
+
+	if request.Permission != "" {
+		permission, err := GetPermission(request.Permission)
+		if err != nil {
+			return err
+		}
+
+		if permission != nil && permission.State == PermissionStatePending {
+			_, approverName := util.GetOwnerAndNameFromIdNoCheck(user)
+			permission.State = PermissionStateRejected
+			permission.Approver = approverName
+			permission.ApproveTime = util.GetCurrentTime()
+			_, err = UpdatePermission(permission.GetId(), permission)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	addUserActionRecord(request.Owner, request.User, strings.ToLower(action)+"-access-request", util.StructToJson(request))
+	notifyAccessRequestUser(request)
+	return nil
+}
+
+func checkAccessRequestPending(request *AccessRequest) error {
+	if request.State != AccessRequestStatePending {
+		return fmt.Errorf("the access request: %s is not pending", request.GetId())
+	}
+	return nil
+}
+
+// ReviewAccessRequest approves the current step of the request or denies the request. The requester is granted
+// the access once the last step is approved.
+func ReviewAccessRequest(request *AccessRequest, reviewer *User, approved bool, comment string) (bool, error) {
+	err := checkAccessRequestPending(request)
+	if err != nil {
+		return false, err
+	}
+
+	reviewerId := reviewer.GetId()
+	if !util.InSlice(request.Approvers, reviewerId) {
+		return false, fmt.Errorf("the user: %s is not an approver of the current step of the access request: %s", reviewerId, request.GetId())
+	}
+
+	if !approved {
+		err = closeAccessRequest(request, AccessRequestStateDenied, reviewerId, AccessRequestActionDeny, comment)
+		return err == nil, err
+	}
+
+	step := request.CurrentStep
+	request.addEvent(reviewerId, AccessRequestActionApprove, comment)
+	err = checkAccessRequestApprovers(request)
+	if err != nil {
+		return false, err
+	}
+
+	addUserActionRecord(reviewer.Owner, reviewer.Name, "approve-access-request", util.StructToJson(map[string]interface{}{
+		"object": request.GetId(),
+		"step":   step + 1,
+	}))
+
+	if step+1 < len(request.Steps) {
+		request.CurrentStep++
+		err = startAccessRequestStep(request)
+		if err != nil {
+			return false, err
+		}
+
+		err = saveAccessRequest(request, AccessRequestStatePending, step)
+		if err != nil {
+			return false, err
+		}
+
+		notifyAccessRequestApprovers(request)
+		return true, nil
+	}
+
+	// the request is approved first, so that another approver can't grant it twice
+	request.State = AccessRequestStateApproved
+	request.Approvers = []string{}
+	request.Deadline = ""
+	err = saveAccessRequest(request, AccessRequestStatePending, step)
+	if err != nil {
+		return false, err
+	}
+
+	err = grantAccessRequest(request, reviewerId)
+	if err != nil {
+		// the step is open again, to be approved once the failure is fixed
+		request.State = AccessRequestStatePending
+		request.addEvent(reviewerId, AccessRequestActionFail, err.Error())
+		startErr := startAccessRequestStep(request)
+		if startErr == nil {
+			startErr = saveAccessRequest(request, AccessRequestStateApproved, step)
+		}
+		if startErr != nil {
+			logs.Error(fmt.Sprintf("ReviewAccessRequest() error: %s", startErr.Error()))
+		}
+		return false, err
+	}
+
+	request.addEvent("", AccessRequestActionGrant, "")
+	err = saveAccessRequest(request, AccessRequestStateApproved, step)
+	if err != nil {
+		return false, err
+	}
+
+	addUserActionRecord(request.Owner, request.User, "grant-access-request", util.StructToJson(request))
+	notifyAccessRequestUser(request)
+	return true, nil
+}
+
+func CancelAccessRequest(request *AccessRequest, user *User) (bool, error) {
+	err := checkAccessRequestPending(request)
+	if err != nil {
+		return false, err
+	}
+
+	if request.User != user.GetId() {
+		return false, fmt.Errorf("the access request: %s can only be canceled by its requester", request.GetId())
+	}
+
+	err = closeAccessRequest(request, AccessRequestStateCanceled, user.GetId(), AccessRequestActionCancel, "")
+	return err == nil, err
+}
+
+func getAccessRequestMessage(request *AccessRequest) (string, string) {
+	title := fmt.Sprintf("Access request for the %s: %s", strings.ToLower(request.TargetType), request.Target)
+	if request.State != AccessRequestStatePending {
+		return title, fmt.Sprintf("The access request of %s for the %s: %s has been %s.",
+			request.User, strings.ToLower(request.TargetType), request.Target, strings.ToLower(request.State))
+	}
+
+	step := request.Steps[request.CurrentStep]
+	content := fmt.Sprintf("%s requests the %s: %s. Reason: %s. The step %d of %d: %s is waiting for your approval.",
+		request.User, strings.ToLower(request.TargetType), request.Target, request.Reason, request.CurrentStep+1, len(request.Steps), step.Name)
+	if request.Deadline != "" {
+		content += fmt.Sprintf(" The request is denied if not approved before %s.", request.Deadline)
+	}
+	return title, content
+}
+
+// sendAccessMessage emails the users with the email provider of the organization, and posts the message
+// to the notification provider if any. A failed notification doesn't fail the request or review it is about.
+func sendAccessMessage(owner string, notificationProvider string, title string, content string, userIds []string) {
+	util.SafeGoroutine(func() {
+		application, err := GetDefaultApplication(util.GetId("admin", owner))
+		if err != nil {
+			logs.Warning(fmt.Sprintf("sendAccessMessage() error: %s", err.Error()))
+		}
+
+		if application != nil {
+			provider, err := application.GetEmailProvider("All")
+			if err != nil {
+				logs.Warning(fmt.Sprintf("sendAccessMessage() error: %s", err.Error()))
+			}
+
+			for _, userId := range userIds {
+				user, err := GetUser(userId)
+				if err != nil || provider == nil || user == nil || user.Email == "" {
+					continue
+				}
+
+				err = SendEmail(provider, title, content, user.Email, application.DisplayName)
+				if err != nil {
+					logs.Warning(fmt.Sprintf("sendAccessMessage() error: %s", err.Error()))
+				}
+			}
+		}
+
+		if notificationProvider != "" {
+			provider, err := GetProvider(util.GetId("admin", notificationProvider))
+			if err != nil || provider == nil || provider.Category != "Notification" {
+				logs.Warning(fmt.Sprintf("sendAccessMessage() error: the notification provider: %s is not found", notificationProvider))
+				return
+			}
+
+			err = SendNotification(provider, fmt.Sprintf("%s\n%s", title, content))
+			if err != nil {
+				logs.Warning(fmt.Sprintf("sendAccessMessage() error: %s", err.Error()))
+			}
+		}
+	})
+}
+
+func sendAccessRequestMessage(request *AccessRequest, userIds []string) {
+	title, content := getAccessRequestMessage(request)
+	sendAccessMessage(request.Owner, request.NotificationProvider, title, content, userIds)
+}
+
+func notifyAccessRequestApprovers(request *AccessRequest) {
+	sendAccessRequestMessage(request, request.Approvers)
+}
+
+func notifyAccessRequestUser(request *AccessRequest) {
+	sendAccessRequestMessage(request, []string{request.User})
+}
+
+func denyTimedOutAccessRequests(now time.Time) error {
+	requests := []*AccessRequest{}
+	err := ormer.Engine.Where("deadline != ?", "").Find(&requests, &AccessRequest{State: AccessRequestStatePending})
+	if err != nil {
+		return err
+	}
+
+	for _, request := range requests {
+		deadline, err := time.Parse(time.RFC3339, request.Deadline)
+		if err != nil || now.Before(deadline) {
+			continue
+		}
+
+		err = closeAccessRequest(request, AccessRequestStateDenied, "", AccessRequestActionTimeout, "")
+		if err != nil {
+			logs.Error(fmt.Sprintf("denyTimedOutAccessRequests() error for access request %s: %s", request.GetId(), err.Error()))
+		}
+	}
+	return nil
+}
+
+// RunAccessRequestJob denies the access requests whose current step has timed out. Only the instance holding
+// the lease of the job runs it, so that a timed out request is denied and notified once.
+func RunAccessRequestJob() {
+	ticker := time.NewTicker(accessRequestJobInterval)
+	defer ticker.Stop()
+
+	for ; true; <-ticker.C {
+		isHolder, err := acquireJobLease(accessRequestJobLease, 2*accessRequestJobInterval)
+		if err != nil {
+			logs.Error(fmt.Sprintf("RunAccessRequestJob() error: %s", err.Error()))
+			continue
+		}
+		if !isHolder {
+			continue
+		}
+
+		err = denyTimedOutAccessRequests(time.Now())
+		if err != nil {
+			logs.Error(fmt.Sprintf("RunAccessRequestJob() error: %s", err.Error()))
+		}
+	}
+}
@@ -0,0 +1,791 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/beego/beego/logs"
+	"github.com/casdoor/casdoor/conf"
+	"github.com/casdoor/casdoor/util"
+	"github.com/casdoor/casdoor/xlsx"
+	"github.com/xorm-io/core"
+)
+
+const (
+	AccessReviewStateScheduled = "Scheduled"
+	AccessReviewStateActive    = "Active"
+	AccessReviewStateCompleted = "Completed"
+)
+
+const (
+	AccessReviewDecisionKeep   = "Keep"
+	AccessReviewDecisionRevoke = "Revoke"
+)
+
+const (
+	AccessReviewItemStatePending = "Pending"
+	AccessReviewItemStateKept    = "Kept"
+	AccessReviewItemStateRevoked = "Revoked"
+	AccessReviewItemStateFailed  = "Failed"
+)
+
+const accessReviewJobInterval = time.Minute
+
+// AccessReview is a campaign certifying who has which role, group and permission of an organization.
+// Its items are snapshotted when it starts, each one is kept or revoked by its reviewer before the deadline.
+type AccessReview struct {
+	Owner       string `xorm:"varchar(100) notnull pk" json:"owner"`
+	Name        string `xorm:"varchar(100) notnull pk" json:"name"`
+	CreatedTime string `xorm:"varchar(100)" json:"createdTime"`
+	DisplayName string `xorm:"varchar(100)" json:"displayName"`
+
+	TargetTypes []string `xorm:"mediumtext" json:"targetTypes"`
+	// DefaultReviewer reviews the access of the users without a group manager, the organization admins when empty
+	DefaultReviewer string `xorm:"varchar(100)" json:"defaultReviewer"`
+	// StartTime is when the job starts the campaign, empty to start it by hand
+	StartTime string `xorm:"varchar(100)" json:"startTime"`
+	Deadline  string `xorm:"varchar(100)" json:"deadline"`
+	// UndecidedAction is the decision taken for the items still pending at the deadline
+	UndecidedAction string `xorm:"varchar(100)" json:"undecidedAction"`
+	// RecurrenceMonths schedules the next campaign that many months later once completed, 0 for no recurrence
+	RecurrenceMonths     int    `json:"recurrenceMonths"`
+	NotificationProvider string `xorm:"varchar(100)" json:"notificationProvider"`
+
+	State         string `xorm:"varchar(100)" json:"state"`
+	CompletedTime string `xorm:"varchar(100)" json:"completedTime"`
+}
+
+// AccessReviewItem is the access of a user to a role, group or permission at the start of a campaign
+type AccessReviewItem struct {
+	Owner       string `xorm:"varchar(100) notnull pk" json:"owner"`
+	Name        string `xorm:"varchar(100) notnull pk" json:"name"`
+	CreatedTime string `xorm:"varchar(100)" json:"createdTime"`
+
+	Review     string `xorm:"varchar(100) index" json:"review"`
+	User       string `xorm:"varchar(100)" json:"user"`
+	TargetType string `xorm:"varchar(100)" json:"targetType"`
+	Target     string `xorm:"varchar(100)" json:"target"`
+	Reviewer   string `xorm:"varchar(100) index" json:"reviewer"`
+
+	Decision string `xorm:"varchar(100)" json:"decision"`
+	Comment  string `xorm:"varchar(500)" json:"comment"`
+	// DecidedBy is empty for the decisions taken at the deadline
+	DecidedBy    string `xorm:"varchar(100)" json:"decidedBy"`
+	DecisionTime string `xorm:"varchar(100)" json:"decisionTime"`
+	State        string `xorm:"varchar(100)" json:"state"`
+	Message      string `xorm:"varchar(500)" json:"message"`
+}
+
+func GetAccessReviewCount(owner, field, value string) (int64, error) {
+	session := GetSession(owner, -1, -1, field, value, "", "")
+	return session.Count(&AccessReview{})
+}
+
+func GetAccessReviews(owner string) ([]*AccessReview, error) {
+	reviews := []*AccessReview{}
+	err := ormer.Engine.Desc("created_time").Find(&reviews, &AccessReview{Owner: owner})
+	if err != nil {
+		return reviews, err
+	}
+
+	return reviews, nil
+}
+
+func GetPaginationAccessReviews(owner string, offset, limit int, field, value, sortField, sortOrder string) ([]*AccessReview, error) {
+	reviews := []*AccessReview{}
+	session := GetSession(owner, offset, limit, field, value, sortField, sortOrder)
+	err := session.Find(&reviews)
+	if err != nil {
+		return reviews, err
+	}
+
+	return reviews, nil
+}
+
+func getAccessReview(owner string, name string) (*AccessReview, error) {
+	if owner == "" || name == "" {
+		return nil, nil
+	}
+
+	review := AccessReview{Owner: owner, Name: name}
+	existed, err := ormer.Engine.Get(&review)
+	if err != nil {
+		return &review, err
+	}
+
+	if existed {
+		return &review, nil
+	} else {
+		return nil, nil
+	}
+}
+
+func GetAccessReview(id string) (*AccessReview, error) {
+	owner, name := util.GetOwnerAndNameFromIdNoCheck(id)
+	return getAccessReview(owner, name)
+}
+
+func checkAccessReview(review *AccessReview) error {
+	if len(review.TargetTypes) == 0 {
+		return fmt.Errorf("the access review: %s should review at least one target type", review.GetId())
+	}
+	for _, targetType := range review.TargetTypes {
+		if !util.InSlice([]string{AccessTargetRole, AccessTargetGroup, AccessTargetPermission}, targetType) {
+			return fmt.Errorf("invalid target type of the access review: %s", targetType)
+		}
+	}
+
+	if review.UndecidedAction != AccessReviewDecisionKeep && review.UndecidedAction != AccessReviewDecisionRevoke {
+		return fmt.Errorf("invalid undecided action of the access review: %s", review.UndecidedAction)
+	}
+	if review.RecurrenceMonths < 0 {
+		return fmt.Errorf("the recurrence of the access review: %s should not be negative", review.GetId())
+	}
+
+	startTime, err := parseAccessGrantTime(review.StartTime)
+	if err != nil {
+		return fmt.Errorf("invalid start time of the access review: %s", err.Error())
+	}
+	deadline, err := time.Parse(time.RFC3339, review.Deadline)
+	if err != nil {
+		return fmt.Errorf("invalid deadline of the access review: %s", err.Error())
+	}
+	if !startTime.IsZero() && !startTime.Before(deadline) {
+		return fmt.Errorf("the deadline of the access review: %s should be after its start time", review.GetId())
+	}
+	return nil
+}
+
+// UpdateAccessReview updates the settings of the campaign, its state only changes by starting and completing it
+func UpdateAccessReview(id string, review *AccessReview) (bool, error) {
+	owner, name := util.GetOwnerAndNameFromIdNoCheck(id)
+	oldReview, err := getAccessReview(owner, name)
+	if err != nil {
+		return false, err
+	}
+	if oldReview == nil {
+		return false, nil
+	}
+
+	if oldReview.State != AccessReviewStateScheduled && (review.Owner != owner || review.Name != name) {
+		return false, fmt.Errorf("the access review: %s can't be renamed once started", id)
+	}
+
+	err = checkAccessReview(review)
+	if err != nil {
+		return false, err
+	}
+
+	review.State = oldReview.State
+	review.CompletedTime = oldReview.CompletedTime
+	affected, err := ormer.Engine.ID(core.PK{owner, name}).AllCols().Update(review)
+	if err != nil {
+		return false, err
+	}
+
+	return affected != 0, nil
+}
+
+func AddAccessReview(review *AccessReview) (bool, error) {
+	err := checkAccessReview(review)
+	if err != nil {
+		return false, err
+	}
+
+	review.State = AccessReviewStateScheduled
+	review.CompletedTime = ""
+	affected, err := ormer.Engine.Insert(review)
+	if err != nil {
+		return false, err
+	}
+
+	return affected != 0, nil
+}
+
+func DeleteAccessReview(review *AccessReview) (bool, error) {
+	affected, err := ormer.Engine.ID(core.PK{review.Owner, review.Name}).Delete(&AccessReview{})
+	if err != nil {
+		return false, err
+	}
+
+	_, err = ormer.Engine.Delete(&AccessReviewItem{Owner: review.Owner, Review: review.Name})
+	if err != nil {
+		return false, err
+	}
+
+	return affected != 0, nil
+}
+
+func (review *AccessReview) GetId() string {
+	return fmt.Sprintf("%s/%s", review.Owner, review.Name)
+}
+
+func GetAccessReviewItems(review *AccessReview) ([]*AccessReviewItem, error) {
+	items := []*AccessReviewItem{}
+	err := ormer.Engine.Asc("user").Asc("target_type").Asc("target").Find(&items, &AccessReviewItem{Owner: review.Owner, Review: review.Name})
+	if err != nil {
+		return items, err
+	}
+
+	return items, nil
+}
+
+// GetAccessReviewItemsByReviewer returns the pending items the user has to review
+func GetAccessReviewItemsByReviewer(userId string) ([]*AccessReviewItem, error) {
+	items := []*AccessReviewItem{}
+	err := ormer.Engine.Asc("review").Asc("user").Find(&items, &AccessReviewItem{Reviewer: userId, State: AccessReviewItemStatePending})
+	if err != nil {
+		return items, err
+	}
+
+	return items, nil
+}
+
+func GetAccessReviewItem(id string) (*AccessReviewItem, error) {
+	owner, name := util.GetOwnerAndNameFromIdNoCheck(id)
+	if owner == "" || name == "" {
+		return nil, nil
+	}
+
+	item := AccessReviewItem{Owner: owner, Name: name}
+	existed, err := ormer.Engine.Get(&item)
+	if err != nil {
+		return &item, err
+	}
+
+	if existed {
+		return &item, nil
+	} else {
+		return nil, nil
+	}
+}
+
+func (item *AccessReviewItem) GetId() string {
+	return fmt.Sprintf("%s/%s", item.Owner, item.Name)
+}
+
+// accessReviewSnapshot resolves the reviewers of the access of an organization's users
+type accessReviewSnapshot struct {
+	review *AccessReview
+	users  map[string]*User
+	groups map[string]*Group
+	items  []*AccessReviewItem
+}
+
+// getReviewer returns the manager of the reviewed group, or else the first manager of the user's groups,
+// or else the default reviewer. Nobody reviews their own access, the organization admins review it instead.
+func (snapshot *accessReviewSnapshot) getReviewer(userId string, targetType string, target string) string {
+	groupIds := []string{target}
+	if targetType != AccessTargetGroup {
+		groupIds = nil
+		if user, ok := snapshot.users[userId]; ok {
+			groupIds = user.Groups
+		}
+	}
+
+	for _, groupId := range groupIds {
+		group, ok := snapshot.groups[groupId]
+		if !ok || group.Manager == "" {
+			continue
+		}
+
+		manager := getUserIdInOrganization(group.Owner, group.Manager)
+		if manager != userId {
+			return manager
+		}
+	}
+
+	if snapshot.review.DefaultReviewer != "" {
+		reviewer := getUserIdInOrganization(snapshot.review.Owner, snapshot.review.DefaultReviewer)
+		if reviewer != userId {
+			return reviewer
+		}
+	}
+	return ""
+}
+
+func (snapshot *accessReviewSnapshot) addItem(userId string, targetType string, target string) {
+	// wildcard users can't be certified one by one
+	if strings.Contains(userId, "*") {
+		return
+	}
+
+	snapshot.items = append(snapshot.items, &AccessReviewItem{
+		Owner:       snapshot.review.Owner,
+		Name:        util.GenerateId(),
+		CreatedTime: util.GetCurrentTime(),
+		Review:      snapshot.review.Name,
+		User:        userId,
+		TargetType:  targetType,
+		Target:      target,
+		Reviewer:    snapshot.getReviewer(userId, targetType, target),
+		State:       AccessReviewItemStatePending,
+	})
+}
+
+func (snapshot *accessReviewSnapshot) addItems(users []*User, roles []*Role, permissions []*Permission) {
+	for _, targetType := range snapshot.review.TargetTypes {
+		switch targetType {
+		case AccessTargetRole:
+			for _, role := range roles {
+				for _, userId := range role.Users {
+					snapshot.addItem(userId, AccessTargetRole, role.GetId())
+				}
+			}
+		case AccessTargetGroup:
+			for _, user := range users {
+				for _, groupId := range user.Groups {
+					snapshot.addItem(user.GetId(), AccessTargetGroup, groupId)
+				}
+			}
+		case AccessTargetPermission:
+			for _, permission := range permissions {
+				for _, userId := range permission.Users {
+					snapshot.addItem(userId, AccessTargetPermission, permission.GetId())
+				}
+			}
+		}
+	}
+}
+
+func newAccessReviewSnapshot(review *AccessReview, users []*User, groups []*Group) *accessReviewSnapshot {
+	snapshot := &accessReviewSnapshot{
+		review: review,
+		users:  map[string]*User{},
+		groups: map[string]*Group{},
+		items:  []*AccessReviewItem{},
+	}
+	for _, user := range users {
+		snapshot.users[user.GetId()] = user
+	}
+	for _, group := range groups {
+		snapshot.groups[group.GetId()] = group
+	}
+	return snapshot
+}
+
+func getAccessReviewItems(review *AccessReview) ([]*AccessReviewItem, error) {
+	users, err := GetUsers(review.Owner)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := GetGroups(review.Owner)
+	if err != nil {
+		return nil, err
+	}
+	roles, err := GetRoles(review.Owner)
+	if err != nil {
+		return nil, err
+	}
+	permissions, err := GetPermissions(review.Owner)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := newAccessReviewSnapshot(review, users, groups)
+	snapshot.addItems(users, roles, permissions)
+	return snapshot.items, nil
+}
+
+func getAccessReviewReviewers(review *AccessReview, items []*AccessReviewItem) ([]string, error) {
+	reviewers := []string{}
+	hasAdminItems := false
+	for _, item := range items {
+		if item.Reviewer == "" {
+			hasAdminItems = true
+		} else if !util.InSlice(reviewers, item.Reviewer) {
+			reviewers = append(reviewers, item.Reviewer)
+		}
+	}
+
+	if hasAdminItems {
+		admins, err := getOrganizationAdmins(review.Owner)
+		if err != nil {
+			return nil, err
+		}
+		for _, admin := range admins {
+			if !util.InSlice(reviewers, admin) {
+				reviewers = append(reviewers, admin)
+			}
+		}
+	}
+	return reviewers, nil
+}
+
+func notifyAccessReviewers(review *AccessReview, items []*AccessReviewItem) error {
+	reviewers, err := getAccessReviewReviewers(review, items)
+	if err != nil {
+		return err
+	}
+
+	title := fmt.Sprintf("Access review: %s", review.DisplayName)
+	undecidedState := map[string]string{AccessReviewDecisionKeep: "kept", AccessReviewDecisionRevoke: "revoked"}[review.UndecidedAction]
+	content := fmt.Sprintf("The access review: %s has started, please keep or revoke the access of your reports before %s. "+
+		"The access still undecided then is %s automatically.", review.DisplayName, review.Deadline, undecidedState)
+	sendAccessMessage(review.Owner, review.NotificationProvider, title, content, reviewers)
+	return nil
+}
+
+// StartAccessReview snapshots the access under review and notifies the reviewers
+func StartAccessReview(review *AccessReview) (bool, error) {
+	if review.State != AccessReviewStateScheduled {
+		return false, fmt.Errorf("the access review: %s has already started", review.GetId())
+	}
+
+	items, err := getAccessReviewItems(review)
+	if err != nil {
+		return false, err
+	}
+
+	review.State = AccessReviewStateActive
+	if review.StartTime == "" {
+		review.StartTime = util.GetCurrentTime()
+	}
+
+	affected, err := ormer.Engine.ID(core.PK{review.Owner, review.Name}).
+		Where("state = ?", AccessReviewStateScheduled).Cols("state", "start_time").Update(review)
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, fmt.Errorf("the access review: %s has already started", review.GetId())
+	}
+
+	batchSize := conf.GetConfigBatchSize()
+	for i := 0; i < len(items); i += batchSize {
+		end := i + batchSize
+		if end > len(items) {
+			end = len(items)
+		}
+
+		_, err = ormer.Engine.Insert(items[i:end])
+		if err != nil {
+			return false, err
+		}
+	}
+
+	addUserActionRecord(review.Owner, "", "start-access-review", util.StructToJson(map[string]interface{}{
+		"object": review.GetId(),
+		"items":  len(items),
+	}))
+
+	err = notifyAccessReviewers(review, items)
+	if err != nil {
+		logs.Warning(fmt.Sprintf("StartAccessReview() error: %s", err.Error()))
+	}
+	return true, nil
+}
+
+// revokeAccessReviewItem removes the reviewed access of the user, together with its time-bound grant
+func revokeAccessReviewItem(item *AccessReviewItem) error {
+	owner, name := util.GetOwnerAndNameFromIdNoCheck(item.Target)
+
+	switch item.TargetType {
+	case AccessTargetRole:
+		role, err := getRole(owner, name)
+		if err != nil {
+			return err
+		}
+		if role == nil || !util.InSlice(role.Users, item.User) {
+			return nil
+		}
+
+		role.Users = util.DeleteVal(role.Users, item.User)
+		role.Grants = deleteAccessGrant(role.Grants, item.User)
+		_, err = UpdateRole(role.GetId(), role)
+		return err
+	case AccessTargetGroup:
+		user, err := GetUser(item.User)
+		if err != nil {
+			return err
+		}
+		if user == nil || !util.InSlice(user.Groups, item.Target) {
+			return nil
+		}
+
+		user.Groups = util.DeleteVal(user.Groups, item.Target)
+		_, err = UpdateUser(user.GetId(), user, []string{"groups"}, false)
+		return err
+	case AccessTargetPermission:
+		permission, err := getPermission(owner, name)
+		if err != nil {
+			return err
+		}
+		if permission == nil || !util.InSlice(permission.Users, item.User) {
+			return nil
+		}
+
+		permission.Users = util.DeleteVal(permission.Users, item.User)
+		permission.Grants = deleteAccessGrant(permission.Grants, item.User)
+		_, err = UpdatePermission(permission.GetId(), permission)
+		return err
+	default:
+		return fmt.Errorf("invalid target type of the access review item: %s", item.TargetType)
+	}
+}
+
+// decideAccessReviewItem saves the decision of a pending item, and revokes the access if so
+func decideAccessReviewItem(item *AccessReviewItem, decidedBy string, decision string, comment string) error {
+	item.Decision = decision
+	item.Comment = comment
+	item.DecidedBy = decidedBy
+	item.DecisionTime = util.GetCurrentTime()
+	item.State = AccessReviewItemStateKept
+	item.Message = ""
+	if decision == AccessReviewDecisionRevoke {
+		item.State = AccessReviewItemStateRevoked
+	}
+
+	// the item is claimed before the access is revoked, so that concurrent decisions revoke it only once
+	affected, err := ormer.Engine.ID(core.PK{item.Owner, item.Name}).
+		Where("state = ?", AccessReviewItemStatePending).AllCols().Update(item)
+	if err != nil {
+		return err
+	}
+	if affected != 1 {
+		return fmt.Errorf("the access review item: %s has already been decided", item.GetId())
+	}
+
+	if decision == AccessReviewDecisionRevoke {
+		err = revokeAccessReviewItem(item)
+		if err != nil {
+			item.State = AccessReviewItemStateFailed
+			item.Message = err.Error()
+			_, err = ormer.Engine.ID(core.PK{item.Owner, item.Name}).Cols("state", "message").Update(item)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	_, userName := util.GetOwnerAndNameFromIdNoCheck(item.User)
+	addUserActionRecord(item.Owner, userName, strings.ToLower(decision)+"-access-review-item", util.StructToJson(item))
+	return nil
+}
+
+// DecideAccessReviewItem keeps or revokes the access of an item, as its reviewer or an admin of the organization
+func DecideAccessReviewItem(item *AccessReviewItem, user *User, decision string, comment string) (bool, error) {
+	if decision != AccessReviewDecisionKeep && decision != AccessReviewDecisionRevoke {
+		return false, fmt.Errorf("invalid decision of the access review item: %s", decision)
+	}
+	if item.State != AccessReviewItemStatePending {
+		return false, fmt.Errorf("the access review item: %s has already been decided", item.GetId())
+	}
+
+	userId := user.GetId()
+	if item.User == userId {
+		return false, fmt.Errorf("the user: %s can't review their own access", userId)
+	}
+	isOrgAdmin := user.Owner == "built-in" || (user.IsAdmin && user.Owner == item.Owner)
+	if item.Reviewer != userId && !isOrgAdmin {
+		return false, fmt.Errorf("the user: %s is not the reviewer of the access review item: %s", userId, item.GetId())
+	}
+
+	review, err := getAccessReview(item.Owner, item.Review)
+	if err != nil {
+		return false, err
+	}
+	if review == nil || review.State != AccessReviewStateActive {
+		return false, fmt.Errorf("the access review: %s is not active", util.GetId(item.Owner, item.Review))
+	}
+
+	err = decideAccessReviewItem(item, userId, decision, comment)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func addMonths(value string, months int) string {
+	if value == "" {
+		return ""
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return ""
+	}
+	return util.Time2String(t.AddDate(0, months, 0))
+}
+
+// scheduleNextAccessReview copies a recurring campaign, shifted by its recurrence
+func scheduleNextAccessReview(review *AccessReview) error {
+	startTime := review.StartTime
+	if startTime == "" {
+		startTime = review.CreatedTime
+	}
+
+	next := *review
+	next.Name = fmt.Sprintf("%s_%s", review.Name, util.GetRandomName())
+	next.CreatedTime = util.GetCurrentTime()
+	next.StartTime = addMonths(startTime, review.RecurrenceMonths)
+	next.Deadline = addMonths(review.Deadline, review.RecurrenceMonths)
+	if len(next.Name) > 100 {
+		next.Name = next.Name[len(next.Name)-100:]
+	}
+
+	_, err := AddAccessReview(&next)
+	return err
+}
+
+// CompleteAccessReview takes the undecided action for the pending items and closes the campaign
+func CompleteAccessReview(review *AccessReview) (bool, error) {
+	if review.State != AccessReviewStateActive {
+		return false, fmt.Errorf("the access review: %s is not active", review.GetId())
+	}
+
+	items, err := GetAccessReviewItems(review)
+	if err != nil {
+		return false, err
+	}
+
+	for _, item := range items {
+		if item.State != AccessReviewItemStatePending {
+			continue
+		}
+
+		err = decideAccessReviewItem(item, "", review.UndecidedAction, "")
+		if err != nil {
+			return false, err
+		}
+	}
+
+	review.State = AccessReviewStateCompleted
+	review.CompletedTime = util.GetCurrentTime()
+	affected, err := ormer.Engine.ID(core.PK{review.Owner, review.Name}).
+		Where("state = ?", AccessReviewStateActive).Cols("state", "completed_time").Update(review)
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, fmt.Errorf("the access review: %s has already been completed", review.GetId())
+	}
+
+	addUserActionRecord(review.Owner, "", "complete-access-review", util.StructToJson(map[string]interface{}{
+		"object": review.GetId(),
+		"items":  len(items),
+	}))
+
+	if review.RecurrenceMonths > 0 {
+		err = scheduleNextAccessReview(review)
+		if err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func getAccessReviewEvidenceRows(review *AccessReview, items []*AccessReviewItem) [][]string {
+	rows := [][]string{
+		{"Access review", review.GetId()},
+		{"Display name", review.DisplayName},
+		{"Start time", review.StartTime},
+		{"Deadline", review.Deadline},
+		{"Completed time", review.CompletedTime},
+		{"State", review.State},
+		{"Undecided action", review.UndecidedAction},
+		{},
+		{"User", "Target type", "Target", "Reviewer", "Decision", "Decided by", "Decision time", "Comment", "State", "Message"},
+	}
+
+	for _, item := range items {
+		rows = append(rows, []string{
+			item.User, item.TargetType, item.Target, item.Reviewer, item.Decision,
+			item.DecidedBy, item.DecisionTime, item.Comment, item.State, item.Message,
+		})
+	}
+	return rows
+}
+
+// ExportAccessReview returns the evidence report of the campaign as an "xlsx" or "csv" file
+func ExportAccessReview(review *AccessReview, format string) ([]byte, error) {
+	if format != "xlsx" && format != "csv" {
+		return nil, fmt.Errorf("unsupported access review export format: %s", format)
+	}
+
+	items, err := GetAccessReviewItems(review)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := getAccessReviewEvidenceRows(review, items)
+	if format == "xlsx" {
+		return xlsx.WriteXlsxFile("Access review", rows)
+	}
+
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	err = writer.WriteAll(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func refreshAccessReviews(now time.Time) error {
+	reviews := []*AccessReview{}
+	err := ormer.Engine.In("state", AccessReviewStateScheduled, AccessReviewStateActive).Find(&reviews)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for _, review := range reviews {
+		if review.State == AccessReviewStateScheduled {
+			startTime, err := time.Parse(time.RFC3339, review.StartTime)
+			if err != nil || now.Before(startTime) {
+				continue
+			}
+
+			_, err = StartAccessReview(review)
+			if err != nil {
+				logs.Error(fmt.Sprintf("refreshAccessReviews() error for access review %s: %s", review.GetId(), err.Error()))
+				lastErr = err
+			}
+			continue
+		}
+
+		deadline, err := time.Parse(time.RFC3339, review.Deadline)
+		if err != nil || now.Before(deadline) {
+			continue
+		}
+
+		_, err = CompleteAccessReview(review)
+		if err != nil {
+			logs.Error(fmt.Sprintf("refreshAccessReviews() error for access review %s: %s", review.GetId(), err.Error()))
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// RunAccessReviewJob starts the scheduled access reviews and completes the ones past their deadline
+func RunAccessReviewJob() {
+	ticker := time.NewTicker(accessReviewJobInterval)
+	defer ticker.Stop()
+
+	for ; true; <-ticker.C {
+		err := refreshAccessReviews(time.Now())
+		if err != nil {
+			logs.Error(fmt.Sprintf("RunAccessReviewJob() error: %s", err.Error()))
+		}
+	}
+}
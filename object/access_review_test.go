@@ -0,0 +1,137 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestAccessReviewSnapshot(t *testing.T) {
+	review := &AccessReview{
+		Owner:           "built-in",
+		Name:            "review-snapshot",
+		TargetTypes:     []string{AccessTargetRole, AccessTargetGroup, AccessTargetPermission},
+		DefaultReviewer: "carol",
+		UndecidedAction: AccessReviewDecisionKeep,
+		Deadline:        "2024-04-01T00:00:00Z",
+	}
+
+	users := []*User{
+		{Owner: "built-in", Name: "alice", Groups: []string{"built-in/dev"}},
+		{Owner: "built-in", Name: "bob", Groups: []string{"built-in/ops"}},
+		{Owner: "built-in", Name: "carol"},
+	}
+	groups := []*Group{
+		{Owner: "built-in", Name: "dev", Manager: "bob"},
+		{Owner: "built-in", Name: "ops", Manager: "bob"},
+	}
+	roles := []*Role{
+		{Owner: "built-in", Name: "role-admin", Users: []string{"built-in/alice", "built-in/bob"}},
+	}
+	permissions := []*Permission{
+		{Owner: "built-in", Name: "permission-all", Users: []string{"built-in/*", "built-in/carol"}},
+	}
+
+	err := checkAccessReview(review)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot := newAccessReviewSnapshot(review, users, groups)
+	snapshot.addItems(users, roles, permissions)
+
+	// the group manager reviews the reports, the default reviewer the others, and the organization admins
+	// the access of the default reviewer, as nobody reviews their own access
+	res := []string{}
+	for _, item := range snapshot.items {
+		res = append(res, fmt.Sprintf("%s %s %s %s", item.User, item.TargetType, item.Target, item.Reviewer))
+	}
+	expected := []string{
+		"built-in/alice Role built-in/role-admin built-in/bob",
+		"built-in/bob Role built-in/role-admin built-in/carol",
+		"built-in/alice Group built-in/dev built-in/bob",
+		"built-in/bob Group built-in/ops built-in/carol",
+		"built-in/carol Permission built-in/permission-all ",
+	}
+	if !reflect.DeepEqual(res, expected) {
+		t.Fatalf("unexpected items: %v", res)
+	}
+
+	// the evidence lists the items after the summary of the campaign
+	rows := getAccessReviewEvidenceRows(review, snapshot.items)
+	if len(rows) != 9+len(snapshot.items) || rows[9][0] != "built-in/alice" {
+		t.Fatalf("unexpected evidence rows: %v", rows)
+	}
+
+	review.UndecidedAction = "Ignore"
+	if checkAccessReview(review) == nil {
+		t.Fatal("an unknown undecided action should be invalid")
+	}
+	review.UndecidedAction = AccessReviewDecisionRevoke
+
+	review.StartTime = "2024-05-01T00:00:00Z"
+	if checkAccessReview(review) == nil {
+		t.Fatal("a deadline before the start time should be invalid")
+	}
+}
+
+func TestAddMonths(t *testing.T) {
+	if addMonths("2024-01-15T00:00:00Z", 3) != "2024-04-15T00:00:00Z" {
+		t.Fatalf("unexpected next quarter: %s", addMonths("2024-01-15T00:00:00Z", 3))
+	}
+	if addMonths("", 3) != "" {
+		t.Fatal("an empty time should stay empty")
+	}
+}
+
+func TestDecideAccessReviewItem(t *testing.T) {
+	setupTestOrmer(t, new(AccessReviewItem), new(Role))
+
+	item := &AccessReviewItem{Owner: "built-in", Name: "item-decide", User: "built-in/alice", TargetType: AccessTargetRole, Target: "built-in/role-missing", State: AccessReviewItemStatePending}
+	_, err := ormer.Engine.Insert(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a concurrent decision loaded the item while it was pending, only the first one is saved and revokes the access
+	stale := *item
+	err = decideAccessReviewItem(item, "built-in/bob", AccessReviewDecisionRevoke, "")
+	if err != nil || item.State != AccessReviewItemStateRevoked {
+		t.Fatalf("unexpected decision: %s, %v", item.State, err)
+	}
+	err = decideAccessReviewItem(&stale, "built-in/carol", AccessReviewDecisionKeep, "")
+	if err == nil {
+		t.Fatal("the item should have already been decided")
+	}
+
+	// an access failing to be revoked leaves the item failed
+	failed := &AccessReviewItem{Owner: "built-in", Name: "item-failed", User: "built-in/alice", TargetType: "Unknown", Target: "built-in/unknown", State: AccessReviewItemStatePending}
+	_, err = ormer.Engine.Insert(failed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = decideAccessReviewItem(failed, "built-in/bob", AccessReviewDecisionRevoke, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	saved := &AccessReviewItem{Owner: "built-in", Name: "item-failed"}
+	_, err = ormer.Engine.Get(saved)
+	if err != nil || saved.State != AccessReviewItemStateFailed || saved.Message == "" {
+		t.Fatalf("unexpected saved item: %v, %v", saved, err)
+	}
+}
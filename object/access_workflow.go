@@ -0,0 +1,214 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"fmt"
+
+	"github.com/casdoor/casdoor/util"
+	"github.com/xorm-io/core"
+)
+
+const (
+	AccessTargetRole       = "Role"
+	AccessTargetGroup      = "Group"
+	AccessTargetPermission = "Permission"
+)
+
+const (
+	// ApproverTypeManager is the manager of the requested group, or the managers of the requester's groups
+	ApproverTypeManager = "Manager"
+	// ApproverTypeOwner is the admins of the organization owning the requested role, group or permission
+	ApproverTypeOwner = "Owner"
+	ApproverTypeUsers = "Users"
+)
+
+// AccessWorkflowStep is a level of approval, any of its approvers approves the step
+type AccessWorkflowStep struct {
+	Name         string   `json:"name"`
+	ApproverType string   `json:"approverType"`
+	Approvers    []string `json:"approvers"`
+}
+
+// AccessWorkflow routes the access requests of its targets through its steps, one after another
+type AccessWorkflow struct {
+	Owner       string `xorm:"varchar(100) notnull pk" json:"owner"`
+	Name        string `xorm:"varchar(100) notnull pk" json:"name"`
+	CreatedTime string `xorm:"varchar(100)" json:"createdTime"`
+	DisplayName string `xorm:"varchar(100)" json:"displayName"`
+
+	TargetType string `xorm:"varchar(100)" json:"targetType"`
+	// Targets are the ids of the roles, groups or permissions, the workflow applies to all of them when empty
+	Targets []string              `xorm:"mediumtext" json:"targets"`
+	Steps   []*AccessWorkflowStep `xorm:"mediumtext" json:"steps"`
+	// Timeout is the hours a step waits for an approval before the request is denied, 0 to wait forever
+	Timeout              int    `json:"timeout"`
+	NotificationProvider string `xorm:"varchar(100)" json:"notificationProvider"`
+	IsEnabled            bool   `json:"isEnabled"`
+}
+
+// defaultAccessWorkflow applies to the targets without an enabled workflow, their owners approve the requests
+var defaultAccessWorkflow = &AccessWorkflow{
+	Steps: []*AccessWorkflowStep{{Name: "Owner", ApproverType: ApproverTypeOwner}},
+}
+
+func GetAccessWorkflowCount(owner, field, value string) (int64, error) {
+	session := GetSession(owner, -1, -1, field, value, "", "")
+	return session.Count(&AccessWorkflow{})
+}
+
+func GetAccessWorkflows(owner string) ([]*AccessWorkflow, error) {
+	workflows := []*AccessWorkflow{}
+	err := ormer.Engine.Desc("created_time").Find(&workflows, &AccessWorkflow{Owner: owner})
+	if err != nil {
+		return workflows, err
+	}
+
+	return workflows, nil
+}
+
+func GetPaginationAccessWorkflows(owner string, offset, limit int, field, value, sortField, sortOrder string) ([]*AccessWorkflow, error) {
+	workflows := []*AccessWorkflow{}
+	session := GetSession(owner, offset, limit, field, value, sortField, sortOrder)
+	err := session.Find(&workflows)
+	if err != nil {
+		return workflows, err
+	}
+
+	return workflows, nil
+}
+
+func getAccessWorkflow(owner string, name string) (*AccessWorkflow, error) {
+	if owner == "" || name == "" {
+		return nil, nil
+	}
+
+	workflow := AccessWorkflow{Owner: owner, Name: name}
+	existed, err := ormer.Engine.Get(&workflow)
+	if err != nil {
+		return &workflow, err
+	}
+
+	if existed {
+		return &workflow, nil
+	} else {
+		return nil, nil
+	}
+}
+
+func GetAccessWorkflow(id string) (*AccessWorkflow, error) {
+	owner, name := util.GetOwnerAndNameFromIdNoCheck(id)
+	return getAccessWorkflow(owner, name)
+}
+
+func checkAccessWorkflow(workflow *AccessWorkflow) error {
+	if !util.InSlice([]string{AccessTargetRole, AccessTargetGroup, AccessTargetPermission}, workflow.TargetType) {
+		return fmt.Errorf("invalid target type of the access workflow: %s", workflow.TargetType)
+	}
+	if len(workflow.Steps) == 0 {
+		return fmt.Errorf("the access workflow: %s should have at least one step", workflow.GetId())
+	}
+	if workflow.Timeout < 0 {
+		return fmt.Errorf("the timeout of the access workflow: %s should not be negative", workflow.GetId())
+	}
+
+	for i, step := range workflow.Steps {
+		switch step.ApproverType {
+		case ApproverTypeManager, ApproverTypeOwner:
+		case ApproverTypeUsers:
+			if len(step.Approvers) == 0 {
+				return fmt.Errorf("the step %d of the access workflow: %s should have approvers", i+1, workflow.GetId())
+			}
+		default:
+			return fmt.Errorf("invalid approver type of the step %d of the access workflow: %s", i+1, workflow.GetId())
+		}
+	}
+	return nil
+}
+
+func UpdateAccessWorkflow(id string, workflow *AccessWorkflow) (bool, error) {
+	owner, name := util.GetOwnerAndNameFromIdNoCheck(id)
+	if w, err := getAccessWorkflow(owner, name); err != nil {
+		return false, err
+	} else if w == nil {
+		return false, nil
+	}
+
+	err := checkAccessWorkflow(workflow)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := ormer.Engine.ID(core.PK{owner, name}).AllCols().Update(workflow)
+	if err != nil {
+		return false, err
+	}
+
+	return affected != 0, nil
+}
+
+func AddAccessWorkflow(workflow *AccessWorkflow) (bool, error) {
+	err := checkAccessWorkflow(workflow)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := ormer.Engine.Insert(workflow)
+	if err != nil {
+		return false, err
+	}
+
+	return affected != 0, nil
+}
+
+func DeleteAccessWorkflow(workflow *AccessWorkflow) (bool, error) {
+	affected, err := ormer.Engine.ID(core.PK{workflow.Owner, workflow.Name}).Delete(&AccessWorkflow{})
+	if err != nil {
+		return false, err
+	}
+
+	return affected != 0, nil
+}
+
+func (workflow *AccessWorkflow) GetId() string {
+	return fmt.Sprintf("%s/%s", workflow.Owner, workflow.Name)
+}
+
+// getAccessWorkflowByTarget returns the enabled workflow naming the target, or else the one for all the targets of its type
+func getAccessWorkflowByTarget(owner string, targetType string, target string) (*AccessWorkflow, error) {
+	workflows, err := GetAccessWorkflows(owner)
+	if err != nil {
+		return nil, err
+	}
+
+	var res *AccessWorkflow
+	for _, workflow := range workflows {
+		if !workflow.IsEnabled || workflow.TargetType != targetType {
+			continue
+		}
+
+		if util.InSlice(workflow.Targets, target) {
+			return workflow, nil
+		}
+		if len(workflow.Targets) == 0 && res == nil {
+			res = workflow
+		}
+	}
+
+	if res == nil {
+		return defaultAccessWorkflow, nil
+	}
+	return res, nil
+}
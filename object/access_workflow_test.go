@@ -0,0 +1,138 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckAccessWorkflow(t *testing.T) {
+	workflow := &AccessWorkflow{
+		Owner:      "built-in",
+		Name:       "workflow-check",
+		TargetType: AccessTargetRole,
+		Steps: []*AccessWorkflowStep{
+			{Name: "Manager", ApproverType: ApproverTypeManager},
+			{Name: "Security", ApproverType: ApproverTypeUsers, Approvers: []string{"alice"}},
+		},
+		Timeout: 24,
+	}
+
+	err := checkAccessWorkflow(workflow)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a step of named users needs approvers
+	workflow.Steps[1].Approvers = nil
+	if checkAccessWorkflow(workflow) == nil {
+		t.Fatal("a step of users without approvers should be invalid")
+	}
+	workflow.Steps[1].Approvers = []string{"alice"}
+
+	workflow.Steps[0].ApproverType = "Anyone"
+	if checkAccessWorkflow(workflow) == nil {
+		t.Fatal("an unknown approver type should be invalid")
+	}
+	workflow.Steps[0].ApproverType = ApproverTypeManager
+
+	workflow.Timeout = -1
+	if checkAccessWorkflow(workflow) == nil {
+		t.Fatal("a negative timeout should be invalid")
+	}
+	workflow.Timeout = 0
+
+	workflow.TargetType = "Application"
+	if checkAccessWorkflow(workflow) == nil {
+		t.Fatal("an unknown target type should be invalid")
+	}
+	workflow.TargetType = AccessTargetRole
+
+	workflow.Steps = nil
+	if checkAccessWorkflow(workflow) == nil {
+		t.Fatal("a workflow without steps should be invalid")
+	}
+}
+
+func TestAccessRequestHistory(t *testing.T) {
+	request := &AccessRequest{
+		Owner:      "built-in",
+		Name:       "request-history",
+		User:       "built-in/alice",
+		TargetType: AccessTargetRole,
+		Target:     "built-in/role-admin",
+		Steps: []*AccessWorkflowStep{
+			{Name: "Manager", ApproverType: ApproverTypeManager},
+			{Name: "Owner", ApproverType: ApproverTypeOwner},
+		},
+		Deadline: "2024-01-01T00:00:00Z",
+		State:    AccessRequestStatePending,
+	}
+
+	request.addEvent(request.User, AccessRequestActionSubmit, "on call")
+	request.CurrentStep++
+	request.addEvent("built-in/bob", AccessRequestActionApprove, "")
+
+	steps := []int{}
+	actions := []string{}
+	for _, event := range request.History {
+		steps = append(steps, event.Step)
+		actions = append(actions, event.Action)
+	}
+	if !reflect.DeepEqual(steps, []int{0, 1}) || !reflect.DeepEqual(actions, []string{AccessRequestActionSubmit, AccessRequestActionApprove}) {
+		t.Fatalf("unexpected history: %v %v", steps, actions)
+	}
+
+	title, content := getAccessRequestMessage(request)
+	if title != "Access request for the role: built-in/role-admin" {
+		t.Fatalf("unexpected title: %s", title)
+	}
+	expected := "built-in/alice requests the role: built-in/role-admin. Reason: . The step 2 of 2: Owner is waiting for your approval. " +
+		"The request is denied if not approved before 2024-01-01T00:00:00Z."
+	if content != expected {
+		t.Fatalf("unexpected content: %s", content)
+	}
+}
+
+func TestCheckAccessRequestApprovers(t *testing.T) {
+	request := &AccessRequest{
+		Owner: "built-in",
+		Name:  "request-approvers",
+		Steps: []*AccessWorkflowStep{
+			{Name: "Manager", ApproverType: ApproverTypeManager},
+			{Name: "Owner", ApproverType: ApproverTypeOwner},
+		},
+		State: AccessRequestStatePending,
+	}
+
+	request.addEvent("built-in/bob", AccessRequestActionApprove, "")
+	request.addEvent("built-in/bob", AccessRequestActionFail, "")
+	request.addEvent("built-in/bob", AccessRequestActionApprove, "")
+	if err := checkAccessRequestApprovers(request); err != nil {
+		t.Fatalf("approving a step opened again should be allowed: %v", err)
+	}
+
+	request.CurrentStep++
+	request.addEvent("built-in/carol", AccessRequestActionApprove, "")
+	if err := checkAccessRequestApprovers(request); err != nil {
+		t.Fatalf("distinct approvers should be allowed: %v", err)
+	}
+
+	request.addEvent("built-in/bob", AccessRequestActionApprove, "")
+	if checkAccessRequestApprovers(request) == nil {
+		t.Fatal("an approver of an earlier step should be rejected")
+	}
+}
@@ -114,6 +114,7 @@ func UpdateAdapter(id string, adapter *Adapter) (bool, error) {
 		return false, err
 	}
 
+	refreshPermissionEnforcers(permissionEnforcerMessageAdapter, util.GetId(owner, name))
 	return affected != 0, nil
 }
 
@@ -132,6 +133,8 @@ func DeleteAdapter(adapter *Adapter) (bool, error) {
 		return false, err
 	}
 
+	refreshPermissionEnforcers(permissionEnforcerMessageAdapter, adapter.GetId())
+
 	return affected != 0, nil
 }
 
@@ -21,7 +21,9 @@ import (
 
 	"github.com/casdoor/casdoor/i18n"
 	"github.com/casdoor/casdoor/util"
+	"github.com/xorm-io/builder"
 	"github.com/xorm-io/core"
+	"github.com/xorm-io/xorm"
 )
 
 type SigninMethod struct {
@@ -65,64 +67,75 @@ type Application struct {
 	Name        string `xorm:"varchar(100) notnull pk" json:"name"`
 	CreatedTime string `xorm:"varchar(100)" json:"createdTime"`
 
-	DisplayName           string          `xorm:"varchar(100)" json:"displayName"`
-	Logo                  string          `xorm:"varchar(200)" json:"logo"`
-	HomepageUrl           string          `xorm:"varchar(100)" json:"homepageUrl"`
-	Description           string          `xorm:"varchar(100)" json:"description"`
-	Organization          string          `xorm:"varchar(100)" json:"organization"`
-	Cert                  string          `xorm:"varchar(100)" json:"cert"`
-	DefaultGroup          string          `xorm:"varchar(100)" json:"defaultGroup"`
-	HeaderHtml            string          `xorm:"mediumtext" json:"headerHtml"`
-	EnablePassword        bool            `json:"enablePassword"`
-	EnableSignUp          bool            `json:"enableSignUp"`
-	EnableSigninSession   bool            `json:"enableSigninSession"`
-	EnableAutoSignin      bool            `json:"enableAutoSignin"`
-	EnableCodeSignin      bool            `json:"enableCodeSignin"`
-	EnableSamlCompress    bool            `json:"enableSamlCompress"`
-	EnableSamlC14n10      bool            `json:"enableSamlC14n10"`
-	EnableSamlPostBinding bool            `json:"enableSamlPostBinding"`
-	UseEmailAsSamlNameId  bool            `json:"useEmailAsSamlNameId"`
-	EnableWebAuthn        bool            `json:"enableWebAuthn"`
-	EnableLinkWithEmail   bool            `json:"enableLinkWithEmail"`
-	OrgChoiceMode         string          `json:"orgChoiceMode"`
-	SamlReplyUrl          string          `xorm:"varchar(500)" json:"samlReplyUrl"`
-	Providers             []*ProviderItem `xorm:"mediumtext" json:"providers"`
-	SigninMethods         []*SigninMethod `xorm:"varchar(2000)" json:"signinMethods"`
-	SignupItems           []*SignupItem   `xorm:"varchar(3000)" json:"signupItems"`
-	SigninItems           []*SigninItem   `xorm:"mediumtext" json:"signinItems"`
-	GrantTypes            []string        `xorm:"varchar(1000)" json:"grantTypes"`
-	OrganizationObj       *Organization   `xorm:"-" json:"organizationObj"`
-	CertPublicKey         string          `xorm:"-" json:"certPublicKey"`
-	Tags                  []string        `xorm:"mediumtext" json:"tags"`
-	SamlAttributes        []*SamlItem     `xorm:"varchar(1000)" json:"samlAttributes"`
-	IsShared              bool            `json:"isShared"`
-	IpRestriction         string          `json:"ipRestriction"`
-
-	ClientId                string     `xorm:"varchar(100)" json:"clientId"`
-	ClientSecret            string     `xorm:"varchar(100)" json:"clientSecret"`
-	RedirectUris            []string   `xorm:"varchar(1000)" json:"redirectUris"`
-	ForcedRedirectOrigin    string     `xorm:"varchar(100)" json:"forcedRedirectOrigin"`
-	TokenFormat             string     `xorm:"varchar(100)" json:"tokenFormat"`
-	TokenSigningMethod      string     `xorm:"varchar(100)" json:"tokenSigningMethod"`
-	TokenFields             []string   `xorm:"varchar(1000)" json:"tokenFields"`
-	ExpireInHours           int        `json:"expireInHours"`
-	RefreshExpireInHours    int        `json:"refreshExpireInHours"`
-	SignupUrl               string     `xorm:"varchar(200)" json:"signupUrl"`
-	SigninUrl               string     `xorm:"varchar(200)" json:"signinUrl"`
-	ForgetUrl               string     `xorm:"varchar(200)" json:"forgetUrl"`
-	AffiliationUrl          string     `xorm:"varchar(100)" json:"affiliationUrl"`
-	IpWhitelist             string     `xorm:"varchar(200)" json:"ipWhitelist"`
-	TermsOfUse              string     `xorm:"varchar(100)" json:"termsOfUse"`
-	SignupHtml              string     `xorm:"mediumtext" json:"signupHtml"`
-	SigninHtml              string     `xorm:"mediumtext" json:"signinHtml"`
-	ThemeData               *ThemeData `xorm:"json" json:"themeData"`
-	FooterHtml              string     `xorm:"mediumtext" json:"footerHtml"`
-	FormCss                 string     `xorm:"text" json:"formCss"`
-	FormCssMobile           string     `xorm:"text" json:"formCssMobile"`
-	FormOffset              int        `json:"formOffset"`
-	FormSideHtml            string     `xorm:"mediumtext" json:"formSideHtml"`
-	FormBackgroundUrl       string     `xorm:"varchar(200)" json:"formBackgroundUrl"`
-	FormBackgroundUrlMobile string     `xorm:"varchar(200)" json:"formBackgroundUrlMobile"`
+	DisplayName               string          `xorm:"varchar(100)" json:"displayName"`
+	Logo                      string          `xorm:"varchar(200)" json:"logo"`
+	HomepageUrl               string          `xorm:"varchar(100)" json:"homepageUrl"`
+	Description               string          `xorm:"varchar(100)" json:"description"`
+	Organization              string          `xorm:"varchar(100)" json:"organization"`
+	Cert                      string          `xorm:"varchar(100)" json:"cert"`
+	DefaultGroup              string          `xorm:"varchar(100)" json:"defaultGroup"`
+	HeaderHtml                string          `xorm:"mediumtext" json:"headerHtml"`
+	EnablePassword            bool            `json:"enablePassword"`
+	EnableSignUp              bool            `json:"enableSignUp"`
+	EnableSigninSession       bool            `json:"enableSigninSession"`
+	EnableAutoSignin          bool            `json:"enableAutoSignin"`
+	EnableCodeSignin          bool            `json:"enableCodeSignin"`
+	EnableSamlCompress        bool            `json:"enableSamlCompress"`
+	EnableSamlC14n10          bool            `json:"enableSamlC14n10"`
+	EnableSamlPostBinding     bool            `json:"enableSamlPostBinding"`
+	EnableSamlArtifactBinding bool            `json:"enableSamlArtifactBinding"`
+	UseEmailAsSamlNameId      bool            `json:"useEmailAsSamlNameId"`
+	EnableWebAuthn            bool            `json:"enableWebAuthn"`
+	EnableLinkWithEmail       bool            `json:"enableLinkWithEmail"`
+	OrgChoiceMode             string          `json:"orgChoiceMode"`
+	SamlReplyUrl              string          `xorm:"varchar(500)" json:"samlReplyUrl"`
+	SamlSloUrl                string          `xorm:"varchar(500)" json:"samlSloUrl"`
+	SamlSpCertificate         string          `xorm:"mediumtext" json:"samlSpCertificate"`
+	SamlAssertionEncryption   string          `xorm:"varchar(100)" json:"samlAssertionEncryption"`
+	Providers                 []*ProviderItem `xorm:"mediumtext" json:"providers"`
+	SigninMethods             []*SigninMethod `xorm:"varchar(2000)" json:"signinMethods"`
+	SignupItems               []*SignupItem   `xorm:"varchar(3000)" json:"signupItems"`
+	SigninItems               []*SigninItem   `xorm:"mediumtext" json:"signinItems"`
+	GrantTypes                []string        `xorm:"varchar(1000)" json:"grantTypes"`
+	OrganizationObj           *Organization   `xorm:"-" json:"organizationObj"`
+	CertPublicKey             string          `xorm:"-" json:"certPublicKey"`
+	Tags                      []string        `xorm:"mediumtext" json:"tags"`
+	SamlAttributes            []*SamlItem     `xorm:"varchar(1000)" json:"samlAttributes"`
+	IsShared                  bool            `json:"isShared"`
+	IpRestriction             string          `json:"ipRestriction"`
+
+	ClientId                string        `xorm:"varchar(100)" json:"clientId"`
+	ClientSecret            string        `xorm:"varchar(100)" json:"clientSecret"`
+	RedirectUris            []string      `xorm:"varchar(1000)" json:"redirectUris"`
+	ForcedRedirectOrigin    string        `xorm:"varchar(100)" json:"forcedRedirectOrigin"`
+	TokenFormat             string        `xorm:"varchar(100)" json:"tokenFormat"`
+	TokenSigningMethod      string        `xorm:"varchar(100)" json:"tokenSigningMethod"`
+	TokenFields             []string      `xorm:"varchar(1000)" json:"tokenFields"`
+	IdTokenEncryptionAlg    string        `xorm:"varchar(100)" json:"idTokenEncryptionAlg"`
+	UserinfoEncryptionAlg   string        `xorm:"varchar(100)" json:"userinfoEncryptionAlg"`
+	ClientJwks              string        `xorm:"mediumtext" json:"clientJwks"`
+	ExpireInHours           int           `json:"expireInHours"`
+	RefreshExpireInHours    int           `json:"refreshExpireInHours"`
+	CasServices             []*CasService `xorm:"mediumtext" json:"casServices"`
+	CasTicketTtl            int           `json:"casTicketTtl"`
+	CasProxyTicketTtl       int           `json:"casProxyTicketTtl"`
+	CasPgtTtl               int           `json:"casPgtTtl"`
+	SignupUrl               string        `xorm:"varchar(200)" json:"signupUrl"`
+	SigninUrl               string        `xorm:"varchar(200)" json:"signinUrl"`
+	ForgetUrl               string        `xorm:"varchar(200)" json:"forgetUrl"`
+	AffiliationUrl          string        `xorm:"varchar(100)" json:"affiliationUrl"`
+	IpWhitelist             string        `xorm:"varchar(200)" json:"ipWhitelist"`
+	TermsOfUse              string        `xorm:"varchar(100)" json:"termsOfUse"`
+	SignupHtml              string        `xorm:"mediumtext" json:"signupHtml"`
+	SigninHtml              string        `xorm:"mediumtext" json:"signinHtml"`
+	ThemeData               *ThemeData    `xorm:"json" json:"themeData"`
+	FooterHtml              string        `xorm:"mediumtext" json:"footerHtml"`
+	FormCss                 string        `xorm:"text" json:"formCss"`
+	FormCssMobile           string        `xorm:"text" json:"formCssMobile"`
+	FormOffset              int           `json:"formOffset"`
+	FormSideHtml            string        `xorm:"mediumtext" json:"formSideHtml"`
+	FormBackgroundUrl       string        `xorm:"varchar(200)" json:"formBackgroundUrl"`
+	FormBackgroundUrlMobile string        `xorm:"varchar(200)" json:"formBackgroundUrlMobile"`
 
 	FailedSigninLimit      int `json:"failedSigninLimit"`
 	FailedSigninFrozenTime int `json:"failedSigninFrozenTime"`
@@ -536,9 +549,13 @@ func GetMaskedApplication(application *Application, userId string) *Application
 	application.EnableSamlCompress = false
 	application.EnableSamlC14n10 = false
 	application.EnableSamlPostBinding = false
+	application.EnableSamlArtifactBinding = false
 	application.EnableWebAuthn = false
 	application.EnableLinkWithEmail = false
 	application.SamlReplyUrl = "***"
+	application.SamlSloUrl = "***"
+	application.SamlSpCertificate = ""
+	application.SamlAssertionEncryption = "***"
 
 	providerItems := []*ProviderItem{}
 	for _, providerItem := range application.Providers {
@@ -656,15 +673,27 @@ func UpdateApplication(id string, application *Application) (bool, error) {
 		return false, fmt.Errorf("only applications belonging to built-in organization can be shared")
 	}
 
+	err = checkCasServices(application)
+	if err != nil {
+		return false, err
+	}
+
 	for _, providerItem := range application.Providers {
 		providerItem.Provider = nil
 	}
 
-	session := ormer.Engine.ID(core.PK{owner, name}).AllCols()
-	if application.ClientSecret == "***" {
-		session.Omit("client_secret")
+	oldObject, err := getChangeEventObject(ormer.Engine, oldApplication)
+	if err != nil {
+		return false, err
 	}
-	affected, err := session.Update(application)
+
+	affected, err := updateWithChangeEvents(oldObject, application, func(session *xorm.Session) (int64, error) {
+		session = session.ID(core.PK{owner, name}).AllCols()
+		if application.ClientSecret == "***" {
+			session.Omit("client_secret")
+		}
+		return session.Update(application)
+	})
 	if err != nil {
 		return false, err
 	}
@@ -695,11 +724,16 @@ func AddApplication(application *Application) (bool, error) {
 		return false, nil
 	}
 
+	err = checkCasServices(application)
+	if err != nil {
+		return false, err
+	}
+
 	for _, providerItem := range application.Providers {
 		providerItem.Provider = nil
 	}
 
-	affected, err := ormer.Engine.Insert(application)
+	affected, err := insertWithChangeEvents(application)
 	if err != nil {
 		return false, nil
 	}
@@ -708,7 +742,12 @@ func AddApplication(application *Application) (bool, error) {
 }
 
 func deleteApplication(application *Application) (bool, error) {
-	affected, err := ormer.Engine.ID(core.PK{application.Owner, application.Name}).Delete(&Application{})
+	oldApplication, err := getChangeEventObject(ormer.Engine, application)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := deleteWithChangeEvents(oldApplication, application)
 	if err != nil {
 		return false, err
 	}
@@ -880,19 +919,26 @@ func applicationChangeTrigger(oldName string, newName string) error {
 		return err
 	}
 
-	organization := new(Organization)
-	organization.DefaultApplication = newName
-	_, err = session.Where("default_application=?", oldName).Update(organization)
+	events, err := getCascadeChangeEvents(session, &[]*Organization{}, &[]*Organization{}, builder.Eq{"default_application": oldName}, builder.Eq{"default_application": newName}, getChangeEventId, func() error {
+		organization := new(Organization)
+		organization.DefaultApplication = newName
+		_, err := session.Where("default_application=?", oldName).Update(organization)
+		return err
+	})
 	if err != nil {
 		return err
 	}
 
-	user := new(User)
-	user.SignupApplication = newName
-	_, err = session.Where("signup_application=?", oldName).Update(user)
+	userEvents, err := getCascadeChangeEvents(session, &[]*User{}, &[]*User{}, builder.Eq{"signup_application": oldName}, builder.Eq{"signup_application": newName}, getChangeEventId, func() error {
+		user := new(User)
+		user.SignupApplication = newName
+		_, err := session.Where("signup_application=?", oldName).Update(user)
+		return err
+	})
 	if err != nil {
 		return err
 	}
+	events = append(events, userEvents...)
 
 	resource := new(Resource)
 	resource.Application = newName
@@ -907,6 +953,13 @@ func applicationChangeTrigger(oldName string, newName string) error {
 		return err
 	}
 	for i := 0; i < len(permissions); i++ {
+		if !util.InSlice(permissions[i].Resources, oldName) {
+			continue
+		}
+
+		oldPermission := *permissions[i]
+		oldPermission.Resources = append([]string{}, permissions[i].Resources...)
+
 		permissionResoureces := permissions[i].Resources
 		for j := 0; j < len(permissionResoureces); j++ {
 			if permissionResoureces[j] == oldName {
@@ -918,7 +971,25 @@ func applicationChangeTrigger(oldName string, newName string) error {
 		if err != nil {
 			return err
 		}
+
+		var permissionEvents []*ChangeEvent
+		permissionEvents, err = getUpdateChangeEvents(session, &oldPermission, permissions[i])
+		if err != nil {
+			return err
+		}
+		events = append(events, permissionEvents...)
+	}
+
+	err = addChangeEvents(session, events)
+	if err != nil {
+		return err
+	}
+
+	err = session.Commit()
+	if err != nil {
+		return err
 	}
 
-	return session.Commit()
+	notifyChangeEvents()
+	return nil
 }
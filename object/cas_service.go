@@ -0,0 +1,266 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/beego/beego/logs"
+	"github.com/casdoor/casdoor/proxy"
+	"github.com/casdoor/casdoor/util"
+)
+
+const casLogoutTimeout = time.Second * 10
+
+// CasService is a service allowed to sign in with the CAS protocol of an application
+type CasService struct {
+	Name string `json:"name"`
+	// ServicePattern is a regex the whole service URL has to match
+	ServicePattern string `json:"servicePattern"`
+	// AllowedAttributes are the user attributes released to the service, all of them when empty
+	AllowedAttributes []string `json:"allowedAttributes"`
+	// AllowProxy lets the service get a proxy granting ticket sent to a pgtUrl matching ProxyCallbackPattern
+	AllowProxy           bool   `json:"allowProxy"`
+	ProxyCallbackPattern string `json:"proxyCallbackPattern"`
+	// EnableSingleLogout posts a logout request to LogoutUrl, or to the service URL, when the session ends
+	EnableSingleLogout bool   `json:"enableSingleLogout"`
+	LogoutUrl          string `json:"logoutUrl"`
+}
+
+func matchCasPattern(pattern string, value string) bool {
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return false
+	}
+	return re.MatchString(value)
+}
+
+func checkCasServices(application *Application) error {
+	for _, casService := range application.CasServices {
+		if casService.ServicePattern == "" {
+			return fmt.Errorf("the service pattern of the CAS service: %s should not be empty", casService.Name)
+		}
+		for _, pattern := range []string{casService.ServicePattern, casService.ProxyCallbackPattern} {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("invalid pattern of the CAS service: %s, %s", casService.Name, err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+// GetCasService returns the first registered service matching the service URL
+func (application *Application) GetCasService(service string) *CasService {
+	for _, casService := range application.CasServices {
+		if matchCasPattern(casService.ServicePattern, service) {
+			return casService
+		}
+	}
+	return nil
+}
+
+// IsCasServiceAllowed tells whether a service can get tickets, the redirect URIs are checked when no service is registered
+func (application *Application) IsCasServiceAllowed(service string) bool {
+	if len(application.CasServices) > 0 {
+		return application.GetCasService(service) != nil
+	}
+	return len(application.RedirectUris) == 0 || application.IsRedirectUriValid(service)
+}
+
+// IsCasProxyCallbackAllowed applies the proxy policy of the service a proxy granting ticket is asked for
+func (application *Application) IsCasProxyCallbackAllowed(service string, pgtUrl string) bool {
+	if len(application.CasServices) == 0 {
+		return true
+	}
+
+	casService := application.GetCasService(service)
+	if casService == nil || !casService.AllowProxy {
+		return false
+	}
+	return casService.ProxyCallbackPattern == "" || matchCasPattern(casService.ProxyCallbackPattern, pgtUrl)
+}
+
+func getCasTtl(seconds int, defaultTtl time.Duration) time.Duration {
+	if seconds <= 0 {
+		return defaultTtl
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func (application *Application) getCasTicketTtl() time.Duration {
+	return getCasTtl(application.CasTicketTtl, casTicketTtl)
+}
+
+func (application *Application) getCasProxyTicketTtl() time.Duration {
+	return getCasTtl(application.CasProxyTicketTtl, casTicketTtl)
+}
+
+func (application *Application) getCasPgtTtl() time.Duration {
+	return getCasTtl(application.CasPgtTtl, casPgtTtl)
+}
+
+// filterCasAttributes drops the user attributes the service isn't allowed to get
+func filterCasAttributes(token *CasAuthenticationSuccess, casService *CasService) {
+	if casService == nil || len(casService.AllowedAttributes) == 0 || token.Attributes == nil || token.Attributes.UserAttributes == nil {
+		return
+	}
+
+	attributes := []*CasNamedAttribute{}
+	for _, attribute := range token.Attributes.UserAttributes.Attributes {
+		if util.InSlice(casService.AllowedAttributes, attribute.Name) {
+			attributes = append(attributes, attribute)
+		}
+	}
+	token.Attributes.UserAttributes.Attributes = attributes
+}
+
+// casSessionTicket is a service ticket issued in a browser session, for the single logout of its service
+type casSessionTicket struct {
+	Application string
+	Service     string
+	User        string
+	Ticket      string
+}
+
+func getCasSessionKey(sessionId string) string {
+	return "cas_session:" + sessionId
+}
+
+// AddCasSessionTicket remembers the service ticket to log the service out when the session ends
+func AddCasSessionTicket(sessionId string, application *Application, service string, user string, ticket string, ttl time.Duration) error {
+	casService := application.GetCasService(service)
+	if sessionId == "" || casService == nil || !casService.EnableSingleLogout {
+		return nil
+	}
+
+	// the services signed in by the same session at the same time are all kept
+	return updateTicket(getCasSessionKey(sessionId), ttl, func(value []byte) ([]byte, error) {
+		var tickets []*casSessionTicket
+		_, err := unmarshalTicket(value, &tickets)
+		if err != nil {
+			return nil, err
+		}
+
+		tickets = append(tickets, &casSessionTicket{
+			Application: application.GetId(),
+			Service:     service,
+			User:        user,
+			Ticket:      ticket,
+		})
+		return json.Marshal(tickets)
+	})
+}
+
+type casLogoutRequest struct {
+	XMLName      xml.Name `xml:"samlp:LogoutRequest"`
+	Samlp        string   `xml:"xmlns:samlp,attr"`
+	Saml         string   `xml:"xmlns:saml,attr"`
+	Id           string   `xml:"ID,attr"`
+	Version      string   `xml:"Version,attr"`
+	IssueInstant string   `xml:"IssueInstant,attr"`
+	NameId       string   `xml:"saml:NameID"`
+	SessionIndex string   `xml:"samlp:SessionIndex"`
+}
+
+func getCasLogoutRequest(user string, ticket string) (string, error) {
+	request := casLogoutRequest{
+		Samlp:        "urn:oasis:names:tc:SAML:2.0:protocol",
+		Saml:         "urn:oasis:names:tc:SAML:2.0:assertion",
+		Id:           "LR-" + util.GenerateId(),
+		Version:      "2.0",
+		IssueInstant: time.Now().UTC().Format(time.RFC3339),
+		NameId:       user,
+		SessionIndex: ticket,
+	}
+
+	data, err := xml.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func sendCasLogoutRequest(logoutUrl string, logoutRequest string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), casLogoutTimeout)
+	defer cancel()
+
+	body := url.Values{"logoutRequest": {logoutRequest}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, logoutUrl, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := proxy.DefaultHttpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return fmt.Errorf("the CAS logout request to %s has failed with the status: %s", logoutUrl, resp.Status)
+	}
+	return nil
+}
+
+// SendCasLogoutRequests posts the CAS single logout requests to the services signed in by the session.
+// The services are checked against the registry again, so that a service removed since can't get requests.
+func SendCasLogoutRequests(sessionId string) error {
+	var tickets []*casSessionTicket
+	ok, err := takeTicket(getCasSessionKey(sessionId), &tickets)
+	if err != nil || !ok {
+		return err
+	}
+
+	for _, ticket := range tickets {
+		application, err := GetApplication(ticket.Application)
+		if err != nil {
+			return err
+		}
+		if application == nil {
+			continue
+		}
+
+		casService := application.GetCasService(ticket.Service)
+		if casService == nil || !casService.EnableSingleLogout {
+			continue
+		}
+
+		logoutUrl := casService.LogoutUrl
+		if logoutUrl == "" {
+			logoutUrl = ticket.Service
+		}
+
+		logoutRequest, err := getCasLogoutRequest(ticket.User, ticket.Ticket)
+		if err != nil {
+			return err
+		}
+
+		// a service not responding doesn't stop the others from being logged out
+		err = sendCasLogoutRequest(logoutUrl, logoutRequest)
+		if err != nil {
+			logs.Warning(fmt.Sprintf("SendCasLogoutRequests() error: %s", err.Error()))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/casdoor/casdoor/proxy"
+)
+
+func TestCasServiceRegistry(t *testing.T) {
+	application := &Application{
+		Owner: "admin",
+		Name:  "app-cas",
+		CasServices: []*CasService{
+			{Name: "portal", ServicePattern: `https://portal\.example\.com/.*`, AllowedAttributes: []string{"email"}},
+			{Name: "backend", ServicePattern: `https://backend\.example\.com/.*`, AllowProxy: true, ProxyCallbackPattern: `https://backend\.example\.com/pgt`},
+		},
+		CasTicketTtl: 30,
+	}
+
+	err := checkCasServices(application)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = checkCasServices(&Application{CasServices: []*CasService{{Name: "broken", ServicePattern: "https://("}}})
+	if err == nil {
+		t.Fatal("an invalid service pattern should not be saved")
+	}
+
+	// the pattern matches the whole service URL
+	if !application.IsCasServiceAllowed("https://portal.example.com/login") {
+		t.Fatal("a registered service should be allowed")
+	}
+	if application.IsCasServiceAllowed("https://evil.example.com/?https://portal.example.com/") {
+		t.Fatal("a service only containing a registered one should not be allowed")
+	}
+
+	if application.IsCasProxyCallbackAllowed("https://portal.example.com/login", "https://portal.example.com/pgt") {
+		t.Fatal("a service without proxy should not get a PGT")
+	}
+	if application.IsCasProxyCallbackAllowed("https://backend.example.com/login", "https://evil.example.com/pgt") {
+		t.Fatal("a PGT should not be sent to an unknown callback")
+	}
+	if !application.IsCasProxyCallbackAllowed("https://backend.example.com/login", "https://backend.example.com/pgt") {
+		t.Fatal("a proxying service should get a PGT")
+	}
+
+	if application.getCasTicketTtl() != 30*time.Second || application.getCasPgtTtl() != casPgtTtl {
+		t.Fatalf("unexpected TTLs: %s, %s", application.getCasTicketTtl(), application.getCasPgtTtl())
+	}
+
+	token := &CasAuthenticationSuccess{
+		User: "alice",
+		Attributes: &CasAttributes{UserAttributes: &CasUserAttributes{Attributes: []*CasNamedAttribute{
+			{Name: "email", Value: "alice@example.com"},
+			{Name: "phone", Value: "123456"},
+		}}},
+	}
+	filterCasAttributes(token, application.GetCasService("https://portal.example.com/login"))
+	attributes := token.Attributes.UserAttributes.Attributes
+	if len(attributes) != 1 || attributes[0].Name != "email" {
+		t.Fatalf("unexpected attributes: %+v", attributes)
+	}
+}
+
+func TestCasSingleLogout(t *testing.T) {
+	ticketStore, ticketStoreErr = &memoryTicketStore{}, nil
+	ticketStoreOnce.Do(func() {})
+
+	application := &Application{
+		Owner: "admin",
+		Name:  "app-cas",
+		CasServices: []*CasService{
+			{Name: "portal", ServicePattern: `https://portal\.example\.com/.*`, EnableSingleLogout: true},
+			{Name: "wiki", ServicePattern: `https://wiki\.example\.com/.*`},
+		},
+	}
+
+	for _, service := range []string{"https://portal.example.com/", "https://wiki.example.com/"} {
+		err := AddCasSessionTicket("session", application, service, "alice", "ST-"+service, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var tickets []*casSessionTicket
+	ok, err := loadTicket(getCasSessionKey("session"), &tickets)
+	if err != nil || !ok || len(tickets) != 1 || tickets[0].Ticket != "ST-https://portal.example.com/" {
+		t.Fatalf("only the services with single logout should be remembered: %+v, %v", tickets, err)
+	}
+
+	proxy.InitHttpClient()
+	var request struct {
+		XMLName      xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:protocol LogoutRequest"`
+		NameId       string   `xml:"urn:oasis:names:tc:SAML:2.0:assertion NameID"`
+		SessionIndex string   `xml:"urn:oasis:names:tc:SAML:2.0:protocol SessionIndex"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := xml.Unmarshal([]byte(r.PostFormValue("logoutRequest")), &request)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	logoutRequest, err := getCasLogoutRequest("alice", tickets[0].Ticket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sendCasLogoutRequest(server.URL, logoutRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if request.NameId != "alice" || request.SessionIndex != tickets[0].Ticket {
+		t.Fatalf("unexpected logout request: %+v", request)
+	}
+}
@@ -35,6 +35,12 @@ type Cert struct {
 
 	Certificate string `xorm:"mediumtext" json:"certificate"`
 	PrivateKey  string `xorm:"mediumtext" json:"privateKey"`
+	KeyStore    string `xorm:"varchar(100)" json:"keyStore"`
+
+	KeyId                   string `xorm:"varchar(100)" json:"keyId"`
+	RotationIntervalDays    int    `json:"rotationIntervalDays"`
+	RotationPrepublishHours int    `json:"rotationPrepublishHours"`
+	LastRotatedTime         string `xorm:"varchar(100)" json:"lastRotatedTime"`
 }
 
 func GetMaskedCert(cert *Cert) *Cert {
@@ -178,6 +184,11 @@ func UpdateCert(id string, cert *Cert) (bool, error) {
 		return false, err
 	}
 
+	err = cert.storePrivateKey()
+	if err != nil {
+		return false, err
+	}
+
 	affected, err := ormer.Engine.ID(core.PK{owner, name}).AllCols().Update(cert)
 	if err != nil {
 		return false, err
@@ -192,6 +203,11 @@ func AddCert(cert *Cert) (bool, error) {
 		return false, err
 	}
 
+	err = cert.storePrivateKey()
+	if err != nil {
+		return false, err
+	}
+
 	affected, err := ormer.Engine.Insert(cert)
 	if err != nil {
 		return false, err
@@ -206,6 +222,11 @@ func DeleteCert(cert *Cert) (bool, error) {
 		return false, err
 	}
 
+	_, err = ormer.Engine.Where("owner = ? and cert = ?", cert.Owner, cert.Name).Delete(&CertKey{})
+	if err != nil {
+		return false, err
+	}
+
 	return affected != 0, nil
 }
 
@@ -227,6 +248,22 @@ func (p *Cert) populateContent() error {
 		p.CryptoAlgorithm = "RS256"
 	}
 
+	if p.CryptoAlgorithm == "EdDSA" {
+		if getCertKeyStoreName(p) == CertKeyStorePkcs11 {
+			return fmt.Errorf("populateContent() error, EdDSA keys are not supported by the %s key store", CertKeyStorePkcs11)
+		}
+
+		// Ed25519 has a fixed key size and hash, so there is no SHA size to parse
+		certificate, privateKey, err := generateEdKeys(p.ExpireInYears, p.Name, p.Owner)
+		if err != nil {
+			return err
+		}
+
+		p.Certificate = certificate
+		p.PrivateKey = privateKey
+		return nil
+	}
+
 	sigAlgorithm := p.CryptoAlgorithm[:2]
 	shaSize, err := util.ParseIntWithError(p.CryptoAlgorithm[2:])
 	if err != nil {
@@ -234,7 +271,9 @@ func (p *Cert) populateContent() error {
 	}
 
 	var certificate, privateKey string
-	if sigAlgorithm == "RS" {
+	if getCertKeyStoreName(p) == CertKeyStorePkcs11 {
+		certificate, privateKey, err = generatePkcs11Keys(sigAlgorithm, p.BitSize, shaSize, p.ExpireInYears, p.Name, p.Owner)
+	} else if sigAlgorithm == "RS" {
 		certificate, privateKey, err = generateRsaKeys(p.BitSize, shaSize, p.ExpireInYears, p.Name, p.Owner)
 	} else if sigAlgorithm == "ES" {
 		certificate, privateKey, err = generateEsKeys(shaSize, p.ExpireInYears, p.Name, p.Owner)
@@ -0,0 +1,268 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"github.com/casdoor/casdoor/conf"
+	"github.com/casdoor/casdoor/util"
+)
+
+const (
+	CertKeyStoreDatabase  = "Database"
+	CertKeyStoreEncrypted = "Encrypted"
+	CertKeyStorePkcs11    = "PKCS#11"
+)
+
+const (
+	encryptedKeyPrefix = "encrypted:v1:"
+	pkcs11KeyPrefix    = "pkcs11:"
+)
+
+// CertKeyStore keeps the private keys of certs. The value in the private_key column
+// tells which key store it belongs to, so that certs can be moved between key stores
+// one at a time and keep working in the meantime.
+type CertKeyStore interface {
+	// StoreKey turns a PEM private key into the value kept in the private_key column
+	StoreKey(privateKey string) (string, error)
+	// GetSigner returns the signer of a value kept in the private_key column
+	GetSigner(storedKey string) (crypto.Signer, error)
+}
+
+// databaseKeyStore keeps the PEM private key in the database as it is
+type databaseKeyStore struct{}
+
+func (ks databaseKeyStore) StoreKey(privateKey string) (string, error) {
+	return privateKey, nil
+}
+
+func (ks databaseKeyStore) GetSigner(storedKey string) (crypto.Signer, error) {
+	return parsePrivateKeyPem(storedKey)
+}
+
+// encryptedKeyStore keeps the PEM private key encrypted with a random data key,
+// and the data key encrypted with the master key from the "certMasterKey" config or environment variable
+type encryptedKeyStore struct {
+	masterKey []byte
+}
+
+func newEncryptedKeyStore() (*encryptedKeyStore, error) {
+	masterKeyBase64 := conf.GetConfigString("certMasterKey")
+	if masterKeyBase64 == "" {
+		return nil, fmt.Errorf("certMasterKey should be set to use the %s key store", CertKeyStoreEncrypted)
+	}
+
+	masterKey, err := base64.StdEncoding.DecodeString(masterKeyBase64)
+	if err != nil || len(masterKey) != 32 {
+		return nil, fmt.Errorf("certMasterKey should be a base64 encoded 256-bit key")
+	}
+
+	return &encryptedKeyStore{masterKey: masterKey}, nil
+}
+
+func (ks *encryptedKeyStore) StoreKey(privateKey string) (string, error) {
+	dataKey := make([]byte, 32)
+	_, err := rand.Read(dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	encryptedKey, err := util.AesGcmEncrypt(dataKey, []byte(privateKey))
+	if err != nil {
+		return "", err
+	}
+
+	wrappedDataKey, err := util.AesGcmEncrypt(ks.masterKey, dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	return encryptedKeyPrefix + base64.StdEncoding.EncodeToString(wrappedDataKey) + ":" + base64.StdEncoding.EncodeToString(encryptedKey), nil
+}
+
+func (ks *encryptedKeyStore) decryptKey(storedKey string) (string, error) {
+	tokens := strings.Split(strings.TrimPrefix(storedKey, encryptedKeyPrefix), ":")
+	if len(tokens) != 2 {
+		return "", fmt.Errorf("invalid encrypted private key")
+	}
+
+	wrappedDataKey, err := base64.StdEncoding.DecodeString(tokens[0])
+	if err != nil {
+		return "", err
+	}
+	encryptedKey, err := base64.StdEncoding.DecodeString(tokens[1])
+	if err != nil {
+		return "", err
+	}
+
+	dataKey, err := util.AesGcmDecrypt(ks.masterKey, wrappedDataKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt the private key with certMasterKey: %s", err.Error())
+	}
+
+	privateKey, err := util.AesGcmDecrypt(dataKey, encryptedKey)
+	if err != nil {
+		return "", err
+	}
+	return string(privateKey), nil
+}
+
+func (ks *encryptedKeyStore) GetSigner(storedKey string) (crypto.Signer, error) {
+	privateKey, err := ks.decryptKey(storedKey)
+	if err != nil {
+		return nil, err
+	}
+	return parsePrivateKeyPem(privateKey)
+}
+
+func parsePrivateKeyPem(privateKey string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(privateKey))
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode the private key PEM")
+	}
+
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		// "PRIVATE KEY" and "RSA PSS PRIVATE KEY" are PKCS#8
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type: %T", key)
+	}
+	return signer, nil
+}
+
+func getCertKeyStoreName(cert *Cert) string {
+	if cert.KeyStore != "" {
+		return cert.KeyStore
+	}
+
+	keyStore := conf.GetConfigString("certKeyStore")
+	if keyStore != "" {
+		return keyStore
+	}
+	return CertKeyStoreDatabase
+}
+
+func getStoredKeyStoreName(storedKey string) string {
+	if strings.HasPrefix(storedKey, encryptedKeyPrefix) {
+		return CertKeyStoreEncrypted
+	} else if strings.HasPrefix(storedKey, pkcs11KeyPrefix) {
+		return CertKeyStorePkcs11
+	}
+	return CertKeyStoreDatabase
+}
+
+func getCertKeyStore(name string) (CertKeyStore, error) {
+	switch name {
+	case CertKeyStoreDatabase:
+		return databaseKeyStore{}, nil
+	case CertKeyStoreEncrypted:
+		return newEncryptedKeyStore()
+	case CertKeyStorePkcs11:
+		return getPkcs11KeyStore()
+	default:
+		return nil, fmt.Errorf("unsupported cert key store: %s", name)
+	}
+}
+
+// getCertSigner returns the signer of the cert's private key, wherever it is kept
+func getCertSigner(cert *Cert) (crypto.Signer, error) {
+	if cert.PrivateKey == "" {
+		return nil, fmt.Errorf("the cert: %s has no private key", cert.GetId())
+	}
+
+	keyStore, err := getCertKeyStore(getStoredKeyStoreName(cert.PrivateKey))
+	if err != nil {
+		return nil, err
+	}
+	return keyStore.GetSigner(cert.PrivateKey)
+}
+
+// getCertPrivateKeyPem is for the few users of a cert that need the key itself rather than a signer
+func getCertPrivateKeyPem(cert *Cert) (string, error) {
+	switch getStoredKeyStoreName(cert.PrivateKey) {
+	case CertKeyStoreEncrypted:
+		keyStore, err := newEncryptedKeyStore()
+		if err != nil {
+			return "", err
+		}
+		return keyStore.decryptKey(cert.PrivateKey)
+	case CertKeyStorePkcs11:
+		return "", fmt.Errorf("the private key of the cert: %s is kept in a PKCS#11 token and cannot be exported", cert.GetId())
+	default:
+		return cert.PrivateKey, nil
+	}
+}
+
+// GetCertTlsCertificate returns the cert as a TLS certificate whose private key stays in the cert's key store
+func GetCertTlsCertificate(cert *Cert) (tls.Certificate, error) {
+	block, _ := pem.Decode([]byte(cert.Certificate))
+	if block == nil {
+		return tls.Certificate{}, fmt.Errorf("failed to decode the certificate of the cert: %s", cert.GetId())
+	}
+
+	signer, err := getCertSigner(cert)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.Certificate{Certificate: [][]byte{block.Bytes}, PrivateKey: signer}, nil
+}
+
+// storePrivateKey moves the cert's private key to the cert's key store
+func (p *Cert) storePrivateKey() error {
+	// the "private key" of payment certs can be a public key of the payment platform
+	if p.Type != "x509" || p.PrivateKey == "" {
+		return nil
+	}
+
+	keyStoreName := getCertKeyStoreName(p)
+	if getStoredKeyStoreName(p.PrivateKey) == keyStoreName {
+		return nil
+	}
+
+	privateKey, err := getCertPrivateKeyPem(p)
+	if err != nil {
+		return err
+	}
+
+	keyStore, err := getCertKeyStore(keyStoreName)
+	if err != nil {
+		return err
+	}
+
+	p.PrivateKey, err = keyStore.StoreKey(privateKey)
+	return err
+}
@@ -0,0 +1,131 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build cgo
+
+package object
+
+import (
+	"crypto"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ThalesIgnite/crypto11"
+	"github.com/casdoor/casdoor/conf"
+)
+
+// pkcs11KeyStore keeps the private keys in a PKCS#11 token such as an HSM or SoftHSM,
+// the database only keeps the id of the key in the token
+type pkcs11KeyStore struct {
+	context *crypto11.Context
+}
+
+var (
+	pkcs11Store *pkcs11KeyStore
+	pkcs11Mutex sync.Mutex
+)
+
+func getPkcs11KeyStore() (CertKeyStore, error) {
+	return getPkcs11KeyStoreInternal()
+}
+
+func getPkcs11KeyStoreInternal() (*pkcs11KeyStore, error) {
+	pkcs11Mutex.Lock()
+	defer pkcs11Mutex.Unlock()
+
+	if pkcs11Store != nil {
+		return pkcs11Store, nil
+	}
+
+	module := conf.GetConfigString("pkcs11Module")
+	if module == "" {
+		return nil, fmt.Errorf("pkcs11Module should be set to use the %s key store", CertKeyStorePkcs11)
+	}
+
+	context, err := crypto11.Configure(&crypto11.Config{
+		Path:       module,
+		TokenLabel: conf.GetConfigString("pkcs11TokenLabel"),
+		Pin:        conf.GetConfigString("pkcs11Pin"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pkcs11Store = &pkcs11KeyStore{context: context}
+	return pkcs11Store, nil
+}
+
+func (ks *pkcs11KeyStore) StoreKey(privateKey string) (string, error) {
+	return "", fmt.Errorf("existing private keys cannot be moved into the %s key store, generate a new key in the token instead", CertKeyStorePkcs11)
+}
+
+func (ks *pkcs11KeyStore) GetSigner(storedKey string) (crypto.Signer, error) {
+	id, err := hex.DecodeString(strings.TrimPrefix(storedKey, pkcs11KeyPrefix+"id="))
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := ks.context.FindKeyPair(id, nil)
+	if err != nil {
+		return nil, err
+	}
+	if signer == nil {
+		return nil, fmt.Errorf("the key: %s does not exist in the PKCS#11 token", storedKey)
+	}
+	return signer, nil
+}
+
+// generatePkcs11Key creates a key pair inside the token, the private key never leaves it
+func generatePkcs11Key(sigAlgorithm string, bitSize int, shaSize int, label string) (crypto.Signer, string, error) {
+	ks, err := getPkcs11KeyStoreInternal()
+	if err != nil {
+		return nil, "", err
+	}
+
+	id := make([]byte, 16)
+	_, err = rand.Read(id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var signer crypto.Signer
+	switch sigAlgorithm {
+	case "RS", "PS":
+		signer, err = ks.context.GenerateRSAKeyPairWithLabel(id, []byte(label), bitSize)
+	case "ES":
+		var curve elliptic.Curve
+		switch shaSize {
+		case 256:
+			curve = elliptic.P256()
+		case 384:
+			curve = elliptic.P384()
+		case 512:
+			curve = elliptic.P521()
+		default:
+			return nil, "", fmt.Errorf("generatePkcs11Key() error, unsupported SHA size: %d", shaSize)
+		}
+		signer, err = ks.context.GenerateECDSAKeyPairWithLabel(id, []byte(label), curve)
+	default:
+		return nil, "", fmt.Errorf("generatePkcs11Key() error, unsupported signature algorithm: %s", sigAlgorithm)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	return signer, pkcs11KeyPrefix + "id=" + hex.EncodeToString(id), nil
+}
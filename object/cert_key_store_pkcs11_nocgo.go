@@ -0,0 +1,32 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !cgo
+
+package object
+
+import (
+	"crypto"
+	"fmt"
+)
+
+// the PKCS#11 key store loads the token's module with cgo, which the CGO_ENABLED=0 release builds don't have
+
+func getPkcs11KeyStore() (CertKeyStore, error) {
+	return nil, fmt.Errorf("the %s key store is not available in a build without cgo", CertKeyStorePkcs11)
+}
+
+func generatePkcs11Key(sigAlgorithm string, bitSize int, shaSize int, label string) (crypto.Signer, string, error) {
+	return nil, "", fmt.Errorf("the %s key store is not available in a build without cgo", CertKeyStorePkcs11)
+}
@@ -0,0 +1,122 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func signAndParseJwtToken(t *testing.T, cert *Cert, method jwt.SigningMethod) {
+	signer, err := getCertSigner(cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := jwt.NewWithClaims(method, Claims{TokenType: "access-token"})
+	tokenString, err := signJwtToken(token, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := ParseJwtToken(tokenString, cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.TokenType != "access-token" {
+		t.Fatalf("unexpected claims: %v", claims)
+	}
+}
+
+func TestEncryptedKeyStore(t *testing.T) {
+	t.Setenv("certMasterKey", "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")
+
+	for _, cryptoAlgorithm := range []string{"RS256", "ES256"} {
+		cert := &Cert{Owner: "admin", Name: "cert-test", Type: "x509", CryptoAlgorithm: cryptoAlgorithm, BitSize: 2048, ExpireInYears: 1, KeyStore: CertKeyStoreEncrypted}
+		err := cert.populateContent()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		privateKey := cert.PrivateKey
+		err = cert.storePrivateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(cert.PrivateKey, encryptedKeyPrefix) || strings.Contains(cert.PrivateKey, "PRIVATE KEY") {
+			t.Fatalf("the private key should be encrypted, got %s", cert.PrivateKey)
+		}
+
+		signAndParseJwtToken(t, cert, getCertSigningMethod(cert))
+
+		// moving the cert back to the database decrypts the key
+		cert.KeyStore = CertKeyStoreDatabase
+		err = cert.storePrivateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cert.PrivateKey != privateKey {
+			t.Fatalf("unexpected private key: %s", cert.PrivateKey)
+		}
+	}
+
+	t.Setenv("certMasterKey", "ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA=")
+	cert := &Cert{Owner: "admin", Name: "cert-test", Type: "x509", CryptoAlgorithm: "RS256", BitSize: 2048, ExpireInYears: 1, KeyStore: CertKeyStoreEncrypted}
+	err := cert.populateContent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cert.storePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("certMasterKey", "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")
+	_, err = getCertSigner(cert)
+	if err == nil {
+		t.Fatal("the key should not be usable with another master key")
+	}
+}
+
+// TestPkcs11KeyStore runs against SoftHSM, e.g.:
+//
+//	softhsm2-util --init-token --free --label casdoor --pin 1234 --so-pin 1234
+//	pkcs11Module=/usr/lib/softhsm/libsofthsm2.so pkcs11TokenLabel=casdoor pkcs11Pin=1234 go test ./object -run TestPkcs11KeyStore
+func TestPkcs11KeyStore(t *testing.T) {
+	if os.Getenv("pkcs11Module") == "" {
+		t.Skip("pkcs11Module is not set")
+	}
+
+	for _, cryptoAlgorithm := range []string{"RS256", "ES256"} {
+		cert := &Cert{Owner: "admin", Name: "cert-test", Type: "x509", CryptoAlgorithm: cryptoAlgorithm, BitSize: 2048, ExpireInYears: 1, KeyStore: CertKeyStorePkcs11}
+		err := cert.populateContent()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(cert.PrivateKey, pkcs11KeyPrefix) {
+			t.Fatalf("the private key should be kept in the token, got %s", cert.PrivateKey)
+		}
+
+		signAndParseJwtToken(t, cert, getCertSigningMethod(cert))
+
+		_, err = getCertPrivateKeyPem(cert)
+		if err == nil {
+			t.Fatal("the private key should not be exportable")
+		}
+	}
+}
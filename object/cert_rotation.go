@@ -0,0 +1,401 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/beego/beego/logs"
+	"github.com/casdoor/casdoor/util"
+	"github.com/xorm-io/core"
+	"gopkg.in/square/go-jose.v2"
+)
+
+const (
+	CertKeyStateNext    = "Next"
+	CertKeyStateRetired = "Retired"
+	CertKeyStateExpired = "Expired"
+)
+
+// CertKey is a key of a cert other than the active one: the next key, published in the JWKS
+// before the cutover, or a retired key, published until the tokens signed with it expire.
+// An expired key is no longer published, it is kept to verify the record checkpoints signed with it.
+// The active key stays in the cert itself, so that everything signing with the cert is unchanged.
+type CertKey struct {
+	Owner       string `xorm:"varchar(100) notnull pk" json:"owner"`
+	Name        string `xorm:"varchar(100) notnull pk" json:"name"`
+	CreatedTime string `xorm:"varchar(100)" json:"createdTime"`
+
+	Cert        string `xorm:"varchar(100) index" json:"cert"`
+	State       string `xorm:"varchar(100)" json:"state"`
+	Certificate string `xorm:"mediumtext" json:"certificate"`
+	PrivateKey  string `xorm:"mediumtext" json:"privateKey"`
+
+	// ActivateTime is the cutover time of a next key, ExpireTime is when a retired key is unpublished
+	ActivateTime string `xorm:"varchar(100)" json:"activateTime"`
+	ExpireTime   string `xorm:"varchar(100)" json:"expireTime"`
+}
+
+const certRotationJobLease = "cert-rotation"
+
+var certRotationMutex sync.Mutex
+
+// GetKeyId returns the "kid" of the active key, certs created before rotation existed keep their name as the key id
+func (p *Cert) GetKeyId() string {
+	if p.KeyId != "" {
+		return p.KeyId
+	}
+	return p.Name
+}
+
+// getCertKeyId returns the RFC 7638 thumbprint of the public key, so that the key id never changes for a given key
+func getCertKeyId(certificate string) (string, error) {
+	block, _ := pem.Decode([]byte(certificate))
+	if block == nil {
+		return "", fmt.Errorf("failed to decode the certificate PEM")
+	}
+
+	x509Cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return "", err
+	}
+
+	jwk := jose.JSONWebKey{Key: x509Cert.PublicKey}
+	thumbprint, err := jwk.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(thumbprint), nil
+}
+
+func GetCertKeys(cert *Cert) ([]*CertKey, error) {
+	certKeys := []*CertKey{}
+	err := ormer.Engine.Where("owner = ? and cert = ?", cert.Owner, cert.Name).Asc("created_time").Find(&certKeys)
+	if err != nil {
+		return certKeys, err
+	}
+
+	return certKeys, nil
+}
+
+func getCertKey(cert *Cert, keyId string) (*CertKey, error) {
+	certKey := CertKey{Owner: cert.Owner, Name: keyId}
+	existed, err := ormer.Engine.Get(&certKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if !existed || certKey.Cert != cert.Name {
+		return nil, nil
+	}
+	return &certKey, nil
+}
+
+// getCertificateByKeyId returns the certificate to verify a token signed with the given key of the cert.
+// The active key is checked without reading the database: a cert that has never been rotated has no other key,
+// and once rotated, its legacy key id, which is its name, is the name of its first retired key.
+func getCertificateByKeyId(cert *Cert, keyId string) (string, error) {
+	if keyId == "" || keyId == cert.GetKeyId() {
+		return cert.Certificate, nil
+	}
+
+	certKey, err := getCertKey(cert, keyId)
+	if err != nil {
+		return "", err
+	}
+	if certKey != nil {
+		if certKey.State == CertKeyStateExpired {
+			return "", fmt.Errorf("the key: %s of the cert: %s has expired", keyId, cert.GetId())
+		}
+		return certKey.Certificate, nil
+	}
+	return "", fmt.Errorf("the key: %s of the cert: %s does not exist", keyId, cert.GetId())
+}
+
+// getSigningCertificates returns the certificates to verify a signature made with the given key of the cert,
+// including the expired keys. Without a key id, which is the case of the checkpoints signed before
+// the key ids were kept, the signing key is unknown and every key that has been active is returned.
+func getSigningCertificates(cert *Cert, keyId string) ([]string, error) {
+	if keyId == "" {
+		certificates := []string{cert.Certificate}
+		certKeys, err := GetCertKeys(cert)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, certKey := range certKeys {
+			if certKey.State != CertKeyStateNext {
+				certificates = append(certificates, certKey.Certificate)
+			}
+		}
+		return certificates, nil
+	}
+
+	if keyId == cert.GetKeyId() {
+		return []string{cert.Certificate}, nil
+	}
+
+	certKey, err := getCertKey(cert, keyId)
+	if err != nil {
+		return nil, err
+	}
+	if certKey != nil {
+		return []string{certKey.Certificate}, nil
+	}
+	return nil, fmt.Errorf("the key: %s of the cert: %s does not exist", keyId, cert.GetId())
+}
+
+func (p *Cert) getRotatedTime() time.Time {
+	rotatedTime := p.LastRotatedTime
+	if rotatedTime == "" {
+		rotatedTime = p.CreatedTime
+	}
+
+	t, err := time.Parse(time.RFC3339, rotatedTime)
+	if err != nil {
+		return time.Now()
+	}
+	return t
+}
+
+func (p *Cert) getPrepublishDuration() time.Duration {
+	hours := p.RotationPrepublishHours
+	if hours <= 0 {
+		hours = 24
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// getCertMaxTokenLifetime returns how long a token signed with the cert can stay valid,
+// which is how long a retired key has to remain in the JWKS
+func getCertMaxTokenLifetime(cert *Cert) (time.Duration, error) {
+	applications := []*Application{}
+	session := ormer.Engine.Where("cert = ?", cert.Name)
+	if cert.Owner == "admin" && cert.Name == "cert-built-in" {
+		// applications without a cert use the built-in one
+		session = session.Or("cert = ?", "")
+	}
+	err := session.Find(&applications)
+	if err != nil {
+		return 0, err
+	}
+
+	hours := 0
+	for _, application := range applications {
+		if application.ExpireInHours > hours {
+			hours = application.ExpireInHours
+		}
+		if application.RefreshExpireInHours > hours {
+			hours = application.RefreshExpireInHours
+		}
+	}
+	if hours <= 0 {
+		hours = 168
+	}
+	return time.Duration(hours) * time.Hour, nil
+}
+
+func addNextCertKey(cert *Cert, activateTime time.Time) (*CertKey, error) {
+	nextCert := *cert
+	nextCert.Certificate = ""
+	nextCert.PrivateKey = ""
+	err := nextCert.populateContent()
+	if err != nil {
+		return nil, err
+	}
+
+	err = nextCert.storePrivateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	keyId, err := getCertKeyId(nextCert.Certificate)
+	if err != nil {
+		return nil, err
+	}
+
+	certKey := &CertKey{
+		Owner:        cert.Owner,
+		Name:         keyId,
+		CreatedTime:  util.GetCurrentTime(),
+		Cert:         cert.Name,
+		State:        CertKeyStateNext,
+		Certificate:  nextCert.Certificate,
+		PrivateKey:   nextCert.PrivateKey,
+		ActivateTime: activateTime.Format(time.RFC3339),
+	}
+	_, err = ormer.Engine.Insert(certKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return certKey, nil
+}
+
+// cutoverCert makes the next key the active key of the cert and retires the previous one
+func cutoverCert(cert *Cert, nextKey *CertKey) error {
+	lifetime, err := getCertMaxTokenLifetime(cert)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	retiredKey := &CertKey{
+		Owner:       cert.Owner,
+		Name:        cert.GetKeyId(),
+		CreatedTime: util.GetCurrentTime(),
+		Cert:        cert.Name,
+		State:       CertKeyStateRetired,
+		Certificate: cert.Certificate,
+		// a retired key only verifies, its private key is not kept
+		ExpireTime: now.Add(lifetime).Format(time.RFC3339),
+	}
+
+	session := ormer.Engine.NewSession()
+	defer session.Close()
+
+	err = session.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = session.Insert(retiredKey)
+	if err != nil {
+		session.Rollback()
+		return err
+	}
+
+	_, err = session.ID(core.PK{nextKey.Owner, nextKey.Name}).Delete(&CertKey{})
+	if err != nil {
+		session.Rollback()
+		return err
+	}
+
+	cert.KeyId = nextKey.Name
+	cert.Certificate = nextKey.Certificate
+	cert.PrivateKey = nextKey.PrivateKey
+	cert.LastRotatedTime = now.Format(time.RFC3339)
+	_, err = session.ID(core.PK{cert.Owner, cert.Name}).Cols("key_id", "certificate", "private_key", "last_rotated_time").Update(cert)
+	if err != nil {
+		session.Rollback()
+		return err
+	}
+
+	return session.Commit()
+}
+
+// rotateCert publishes the next key ahead of the cutover, switches signing to it at the cutover
+// and unpublishes the retired keys once the tokens signed with them have expired
+func rotateCert(cert *Cert, force bool) error {
+	certRotationMutex.Lock()
+	defer certRotationMutex.Unlock()
+
+	certKeys, err := GetCertKeys(cert)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var nextKey *CertKey
+	for _, certKey := range certKeys {
+		if certKey.State == CertKeyStateNext {
+			nextKey = certKey
+		} else if certKey.State == CertKeyStateRetired {
+			expireTime, err := time.Parse(time.RFC3339, certKey.ExpireTime)
+			if err == nil && now.After(expireTime) {
+				certKey.State = CertKeyStateExpired
+				certKey.PrivateKey = ""
+				_, err = ormer.Engine.ID(core.PK{certKey.Owner, certKey.Name}).Cols("state", "private_key").Update(certKey)
+				if err != nil {
+					return err
+				}
+			} else if certKey.PrivateKey != "" {
+				// the keys retired before the private keys were dropped
+				certKey.PrivateKey = ""
+				_, err = ormer.Engine.ID(core.PK{certKey.Owner, certKey.Name}).Cols("private_key").Update(certKey)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if cert.RotationIntervalDays <= 0 && !force {
+		return nil
+	}
+
+	cutoverTime := cert.getRotatedTime().AddDate(0, 0, cert.RotationIntervalDays)
+	if force {
+		cutoverTime = now
+	}
+
+	if nextKey == nil && (force || now.After(cutoverTime.Add(-cert.getPrepublishDuration()))) {
+		nextKey, err = addNextCertKey(cert, cutoverTime)
+		if err != nil {
+			return err
+		}
+	}
+
+	if nextKey != nil && (force || !now.Before(cutoverTime)) {
+		return cutoverCert(cert, nextKey)
+	}
+	return nil
+}
+
+// RotateCert immediately switches the cert to a new key, the previous key remains published until its tokens expire
+func RotateCert(cert *Cert) error {
+	return rotateCert(cert, true)
+}
+
+// RunCertRotationJob rotates the certs whose rotation is due. Only the instance holding the lease of the job
+// runs it, so that the replicas don't each publish a next key and race each other to the cutover.
+func RunCertRotationJob() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for ; true; <-ticker.C {
+		isHolder, err := acquireJobLease(certRotationJobLease, 2*time.Hour)
+		if err != nil {
+			logs.Error(fmt.Sprintf("RunCertRotationJob() error: %s", err.Error()))
+			continue
+		}
+		if !isHolder {
+			continue
+		}
+
+		certs, err := GetGlobalCerts()
+		if err != nil {
+			logs.Error(fmt.Sprintf("RunCertRotationJob() error: %s", err.Error()))
+			continue
+		}
+
+		for _, cert := range certs {
+			if cert.Type != "x509" {
+				continue
+			}
+
+			err = rotateCert(cert, false)
+			if err != nil {
+				logs.Error(fmt.Sprintf("RunCertRotationJob() error for cert %s: %s", cert.GetId(), err.Error()))
+			}
+		}
+	}
+}
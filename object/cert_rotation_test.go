@@ -0,0 +1,124 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import "testing"
+
+func TestGetCertKeyId(t *testing.T) {
+	cert := &Cert{Owner: "admin", Name: "cert-test", CryptoAlgorithm: "RS256", BitSize: 2048, ExpireInYears: 1}
+	err := cert.populateContent()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyId, err := getCertKeyId(cert.Certificate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sameKeyId, err := getCertKeyId(cert.Certificate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keyId == "" || keyId != sameKeyId {
+		t.Fatalf("the key id should be stable, got %s and %s", keyId, sameKeyId)
+	}
+
+	// certs created before rotation keep their name as the key id
+	if cert.GetKeyId() != cert.Name {
+		t.Fatalf("unexpected legacy key id: %s", cert.GetKeyId())
+	}
+
+	setupTestOrmer(t, new(CertKey))
+	certificate, err := getCertificateByKeyId(cert, cert.Name)
+	if err != nil || certificate != cert.Certificate {
+		t.Fatalf("the legacy kid should select the active key before the first rotation: %v", err)
+	}
+
+	// after the first rotation, the legacy kid is the retired key
+	retiredKey := &CertKey{Owner: cert.Owner, Name: cert.GetKeyId(), Cert: cert.Name, State: CertKeyStateRetired, Certificate: "retired certificate"}
+	_, err = ormer.Engine.Insert(retiredKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert.KeyId = keyId
+
+	for kid, expected := range map[string]string{"": cert.Certificate, keyId: cert.Certificate, cert.Name: retiredKey.Certificate} {
+		certificate, err := getCertificateByKeyId(cert, kid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if certificate != expected {
+			t.Fatalf("the kid: %q selects the wrong key", kid)
+		}
+	}
+
+	_, err = getCertificateByKeyId(cert, "unknown")
+	if err == nil {
+		t.Fatal("an unknown kid should not select a key")
+	}
+}
+
+func TestVerifyByCertAfterRotation(t *testing.T) {
+	cert := &Cert{Owner: "admin", Name: "cert-test", CryptoAlgorithm: "RS256", BitSize: 2048, ExpireInYears: 1}
+	err := cert.populateContent()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	setupTestOrmer(t, new(CertKey))
+	keyId := cert.GetKeyId()
+	signature, err := signByCert(cert, "data")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the signing key is rotated out and its tokens have expired
+	expiredKey := &CertKey{Owner: cert.Owner, Name: keyId, Cert: cert.Name, State: CertKeyStateExpired, Certificate: cert.Certificate}
+	_, err = ormer.Engine.Insert(expiredKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nextCert := *cert
+	nextCert.Certificate = ""
+	nextCert.PrivateKey = ""
+	err = nextCert.populateContent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert.Certificate = nextCert.Certificate
+	cert.KeyId, err = getCertKeyId(cert.Certificate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, kid := range []string{keyId, ""} {
+		err = verifyByCert(cert, kid, "data", signature)
+		if err != nil {
+			t.Fatalf("the signature of the key: %q should verify after the rotation: %v", kid, err)
+		}
+	}
+
+	err = verifyByCert(cert, cert.KeyId, "data", signature)
+	if err == nil {
+		t.Fatal("the signature should not verify with the new key")
+	}
+
+	_, err = getCertificateByKeyId(cert, keyId)
+	if err == nil {
+		t.Fatal("an expired key should not verify tokens")
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func getCertSigningMethod(cert *Cert) jwt.SigningMethod {
+	method := jwt.GetSigningMethod(cert.CryptoAlgorithm)
+	if method == nil {
+		return jwt.SigningMethodRS256
+	}
+	return method
+}
+
+// parseEdPublicKeyFromPem also accepts a certificate, like jwt.ParseRSAPublicKeyFromPEM() and jwt.ParseECPublicKeyFromPEM() do
+func parseEdPublicKeyFromPem(certificate string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(certificate))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return jwt.ParseEdPublicKeyFromPEM([]byte(certificate))
+	}
+
+	x509Cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, ok := x509Cert.PublicKey.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("the certificate does not contain an Ed25519 public key")
+	}
+	return publicKey, nil
+}
+
+func getCertPublicKey(cert *Cert) (interface{}, error) {
+	switch getCertSigningMethod(cert).(type) {
+	case *jwt.SigningMethodECDSA:
+		return jwt.ParseECPublicKeyFromPEM([]byte(cert.Certificate))
+	case *jwt.SigningMethodEd25519:
+		return parseEdPublicKeyFromPem(cert.Certificate)
+	default:
+		return jwt.ParseRSAPublicKeyFromPEM([]byte(cert.Certificate))
+	}
+}
+
+// signBySigner produces a JWS signature of data with the signing method, the signer can be a key in memory or in a PKCS#11 token
+func signBySigner(method jwt.SigningMethod, signer crypto.Signer, data []byte) ([]byte, error) {
+	switch m := method.(type) {
+	case *jwt.SigningMethodRSA:
+		if _, ok := signer.Public().(*rsa.PublicKey); !ok {
+			return nil, fmt.Errorf("the signing method: %s needs an RSA key", method.Alg())
+		}
+		return signer.Sign(rand.Reader, getDigest(m.Hash, data), m.Hash)
+	case *jwt.SigningMethodRSAPSS:
+		if _, ok := signer.Public().(*rsa.PublicKey); !ok {
+			return nil, fmt.Errorf("the signing method: %s needs an RSA key", method.Alg())
+		}
+		return signer.Sign(rand.Reader, getDigest(m.Hash, data), &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: m.Hash})
+	case *jwt.SigningMethodECDSA:
+		if _, ok := signer.Public().(*ecdsa.PublicKey); !ok {
+			return nil, fmt.Errorf("the signing method: %s needs an ECDSA key", method.Alg())
+		}
+
+		// crypto.Signer returns an ASN.1 signature, JWS wants r and s as fixed size big-endian integers
+		signature, err := signer.Sign(rand.Reader, getDigest(m.Hash, data), m.Hash)
+		if err != nil {
+			return nil, err
+		}
+
+		var esSignature struct {
+			R, S *big.Int
+		}
+		_, err = asn1.Unmarshal(signature, &esSignature)
+		if err != nil {
+			return nil, err
+		}
+
+		res := make([]byte, 2*m.KeySize)
+		esSignature.R.FillBytes(res[:m.KeySize])
+		esSignature.S.FillBytes(res[m.KeySize:])
+		return res, nil
+	case *jwt.SigningMethodEd25519:
+		if _, ok := signer.Public().(ed25519.PublicKey); !ok {
+			return nil, fmt.Errorf("the signing method: %s needs an Ed25519 key", method.Alg())
+		}
+		return signer.Sign(rand.Reader, data, crypto.Hash(0))
+	default:
+		return nil, fmt.Errorf("unsupported signing method: %s", method.Alg())
+	}
+}
+
+func getDigest(hash crypto.Hash, data []byte) []byte {
+	hasher := hash.New()
+	hasher.Write(data)
+	return hasher.Sum(nil)
+}
+
+// signJwtToken is token.SignedString() for a signer instead of a private key
+func signJwtToken(token *jwt.Token, signer crypto.Signer) (string, error) {
+	signingString, err := token.SigningString()
+	if err != nil {
+		return "", err
+	}
+
+	signature, err := signBySigner(token.Method, signer, []byte(signingString))
+	if err != nil {
+		return "", err
+	}
+
+	return signingString + "." + token.EncodeSegment(signature), nil
+}
+
+// signByCert signs data with the cert's private key and returns a base64url signature
+func signByCert(cert *Cert, data string) (string, error) {
+	signer, err := getCertSigner(cert)
+	if err != nil {
+		return "", err
+	}
+
+	signature, err := signBySigner(getCertSigningMethod(cert), signer, []byte(data))
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// verifyByCert checks a signature made by signByCert with the given key of the cert, which may have been rotated since
+func verifyByCert(cert *Cert, keyId string, data string, signature string) error {
+	certificates, err := getSigningCertificates(cert, keyId)
+	if err != nil {
+		return err
+	}
+
+	signatureBytes, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return err
+	}
+
+	// the cert keeps its algorithm across rotations, only the key changes
+	for _, certificate := range certificates {
+		keyCert := *cert
+		keyCert.Certificate = certificate
+		var key interface{}
+		key, err = getCertPublicKey(&keyCert)
+		if err != nil {
+			continue
+		}
+
+		err = getCertSigningMethod(&keyCert).Verify(data, signatureBytes, key)
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
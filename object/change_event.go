@@ -0,0 +1,621 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/beego/beego/logs"
+	"github.com/casdoor/casdoor/conf"
+	"github.com/casdoor/casdoor/util"
+	"github.com/xorm-io/builder"
+	"github.com/xorm-io/core"
+	"github.com/xorm-io/xorm"
+)
+
+const (
+	ChangeEventActionCreate = "create"
+	ChangeEventActionUpdate = "update"
+	ChangeEventActionDelete = "delete"
+)
+
+const (
+	ChangeEventObjectUser         = "user"
+	ChangeEventObjectGroup        = "group"
+	ChangeEventObjectRole         = "role"
+	ChangeEventObjectPermission   = "permission"
+	ChangeEventObjectApplication  = "application"
+	ChangeEventObjectOrganization = "organization"
+)
+
+const (
+	// the ids scanned after a cursor for the gaps in one read
+	changeEventScanSize = 1000
+	// a gap in the ids younger than this may still be filled by an insert not committed yet, the events after it
+	// are not read until then so that the stream stays ordered
+	changeEventSettleSeconds = 5
+	// the interval at which the waiting readers look for the events added by the other Casdoor instances
+	changeEventPollInterval = time.Second
+)
+
+// ChangeEvent is a create, update or delete of a user, group, role, permission, application or organization.
+// The ids are the order of the stream, the readers resume from the cursor of the last event they got.
+type ChangeEvent struct {
+	Id           int64  `xorm:"pk autoincr" json:"id"`
+	CreatedTime  string `xorm:"varchar(100) index" json:"createdTime"`
+	Organization string `xorm:"varchar(100) index" json:"organization"`
+	ObjectType   string `xorm:"varchar(100) index" json:"objectType"`
+	ObjectId     string `xorm:"varchar(255)" json:"objectId"`
+	Action       string `xorm:"varchar(100)" json:"action"`
+
+	// Before and After are the masked images of the object, Before is nil for a create and After for a delete
+	Before map[string]interface{} `xorm:"mediumtext" json:"before"`
+	After  map[string]interface{} `xorm:"mediumtext" json:"after"`
+
+	Cursor string `xorm:"-" json:"cursor"`
+}
+
+// ErrChangeEventCursorExpired is returned for a cursor whose next events have been removed by the retention,
+// the reader has to resynchronize and read again from the "latest" cursor
+var ErrChangeEventCursorExpired = errors.New("cursor expired")
+
+type ChangeEventFilter struct {
+	// Organization is empty for the events of all the organizations
+	Organization string
+	ObjectTypes  []string
+}
+
+// changeEventSignal is closed and replaced when events are added, to wake up the waiting readers
+var (
+	changeEventSignal      = make(chan struct{})
+	changeEventSignalMutex sync.Mutex
+)
+
+func getChangeEventSignal() <-chan struct{} {
+	changeEventSignalMutex.Lock()
+	defer changeEventSignalMutex.Unlock()
+	return changeEventSignal
+}
+
+func notifyChangeEvents() {
+	changeEventSignalMutex.Lock()
+	defer changeEventSignalMutex.Unlock()
+	close(changeEventSignal)
+	changeEventSignal = make(chan struct{})
+}
+
+// getChangeEventTarget returns the type, the organization and the primary key of an object of the stream
+func getChangeEventTarget(object interface{}) (string, string, string, string) {
+	switch o := object.(type) {
+	case *User:
+		return ChangeEventObjectUser, o.Owner, o.Owner, o.Name
+	case *Group:
+		return ChangeEventObjectGroup, o.Owner, o.Owner, o.Name
+	case *Role:
+		return ChangeEventObjectRole, o.Owner, o.Owner, o.Name
+	case *Permission:
+		return ChangeEventObjectPermission, o.Owner, o.Owner, o.Name
+	case *Application:
+		return ChangeEventObjectApplication, o.Organization, o.Owner, o.Name
+	case *Organization:
+		return ChangeEventObjectOrganization, o.Name, o.Owner, o.Name
+	}
+	return "", "", "", ""
+}
+
+func isNilObject(object interface{}) bool {
+	if object == nil {
+		return true
+	}
+	value := reflect.ValueOf(object)
+	return value.Kind() == reflect.Ptr && value.IsNil()
+}
+
+// getChangeEventObject reads an object of the stream from the database or in a session, nil when it does not exist
+func getChangeEventObject(session xorm.Interface, object interface{}) (interface{}, error) {
+	_, _, owner, name := getChangeEventTarget(object)
+	res := reflect.New(reflect.TypeOf(object).Elem()).Interface()
+	existed, err := session.ID(core.PK{owner, name}).Get(res)
+	if err != nil {
+		return nil, err
+	}
+
+	if !existed {
+		return nil, nil
+	}
+	return res, nil
+}
+
+// getChangeEventImage returns the image of an object with its secrets masked as by the get APIs,
+// the object itself is not modified
+func getChangeEventImage(object interface{}) (map[string]interface{}, error) {
+	if isNilObject(object) {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+	copied := reflect.New(reflect.TypeOf(object).Elem()).Interface()
+	err = json.Unmarshal(data, copied)
+	if err != nil {
+		return nil, err
+	}
+
+	switch o := copied.(type) {
+	case *User:
+		_, err = GetMaskedUser(o, false)
+	case *Organization:
+		_, err = GetMaskedOrganization(o)
+	case *Application:
+		if o.ClientSecret != "" {
+			o.ClientSecret = "***"
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data, err = json.Marshal(copied)
+	if err != nil {
+		return nil, err
+	}
+	res := map[string]interface{}{}
+	err = json.Unmarshal(data, &res)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func getChangeEventId(object interface{}) string {
+	_, _, owner, name := getChangeEventTarget(object)
+	return util.GetId(owner, name)
+}
+
+func getChangeEventName(object interface{}) string {
+	_, _, _, name := getChangeEventTarget(object)
+	return name
+}
+
+// isChangeEventObjectChanged compares the objects unmasked, so that a change of a masked field like the password
+// is still an update
+func isChangeEventObjectChanged(before interface{}, after interface{}) (bool, error) {
+	beforeData, err := json.Marshal(before)
+	if err != nil {
+		return false, err
+	}
+	afterData, err := json.Marshal(after)
+	if err != nil {
+		return false, err
+	}
+	return !bytes.Equal(beforeData, afterData), nil
+}
+
+// newChangeEvent returns the event of an object
+func newChangeEvent(action string, before interface{}, after interface{}) (*ChangeEvent, error) {
+	object := after
+	if isNilObject(after) {
+		object = before
+	}
+
+	objectType, organization, owner, name := getChangeEventTarget(object)
+	if objectType == "" {
+		return nil, fmt.Errorf("the object type: %T has no change events", object)
+	}
+
+	beforeImage, err := getChangeEventImage(before)
+	if err != nil {
+		return nil, err
+	}
+	afterImage, err := getChangeEventImage(after)
+	if err != nil {
+		return nil, err
+	}
+
+	event := &ChangeEvent{
+		CreatedTime:  util.GetCurrentTime(),
+		Organization: organization,
+		ObjectType:   objectType,
+		ObjectId:     util.GetId(owner, name),
+		Action:       action,
+		Before:       beforeImage,
+		After:        afterImage,
+	}
+	return event, nil
+}
+
+// newChangeEvents returns the events of an object, none for an update not changing anything.
+// An update renaming the object is also the delete of its old id, for the readers keeping the objects by id.
+func newChangeEvents(action string, before interface{}, after interface{}) ([]*ChangeEvent, error) {
+	events := []*ChangeEvent{}
+	if isNilObject(before) && isNilObject(after) {
+		return events, nil
+	}
+
+	if action == ChangeEventActionUpdate {
+		if isNilObject(after) {
+			action = ChangeEventActionDelete
+		} else if isNilObject(before) {
+			action = ChangeEventActionCreate
+		} else {
+			isChanged, err := isChangeEventObjectChanged(before, after)
+			if err != nil || !isChanged {
+				return events, err
+			}
+
+			if getChangeEventId(before) != getChangeEventId(after) {
+				event, err := newChangeEvent(ChangeEventActionDelete, before, nil)
+				if err != nil {
+					return nil, err
+				}
+				events = append(events, event)
+			}
+		}
+	}
+
+	event, err := newChangeEvent(action, before, after)
+	if err != nil {
+		return nil, err
+	}
+	return append(events, event), nil
+}
+
+// newCreateChangeEvents returns the create events of a slice of objects
+func newCreateChangeEvents(objects interface{}) ([]*ChangeEvent, error) {
+	events := []*ChangeEvent{}
+	value := reflect.ValueOf(objects)
+	for i := 0; i < value.Len(); i++ {
+		event, err := newChangeEvent(ChangeEventActionCreate, nil, value.Index(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// getUpdateChangeEvents returns the events of the update of an object, its after image is read in the session
+// as the updates may not write all the fields
+func getUpdateChangeEvents(session *xorm.Session, before interface{}, object interface{}) ([]*ChangeEvent, error) {
+	after, err := getChangeEventObject(session, object)
+	if err != nil {
+		return nil, err
+	}
+	return newChangeEvents(ChangeEventActionUpdate, before, after)
+}
+
+// newCascadeChangeEvents returns the events of a write changing many objects at once, before and after are
+// the slices of the objects read before and after it. The objects are paired by the key of getKey, which is
+// their name when the write moves them to another organization, the objects only read after it are not changed by it.
+func newCascadeChangeEvents(before interface{}, after interface{}, getKey func(object interface{}) string) ([]*ChangeEvent, error) {
+	afterMap := map[string]interface{}{}
+	afterValue := reflect.ValueOf(after)
+	for i := 0; i < afterValue.Len(); i++ {
+		object := afterValue.Index(i).Interface()
+		afterMap[getKey(object)] = object
+	}
+
+	events := []*ChangeEvent{}
+	beforeValue := reflect.ValueOf(before)
+	for i := 0; i < beforeValue.Len(); i++ {
+		object := beforeValue.Index(i).Interface()
+		objectEvents, err := newChangeEvents(ChangeEventActionUpdate, object, afterMap[getKey(object)])
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, objectEvents...)
+	}
+	return events, nil
+}
+
+// getCascadeChangeEvents runs a write changing many objects at once in the session and returns its events,
+// the objects are found with the condition before the write and with the other one after it.
+// before and after are pointers to empty slices of the objects.
+func getCascadeChangeEvents(session *xorm.Session, before interface{}, after interface{}, beforeCond builder.Cond, afterCond builder.Cond, getKey func(object interface{}) string, write func() error) ([]*ChangeEvent, error) {
+	err := session.Where(beforeCond).Find(before)
+	if err != nil {
+		return nil, err
+	}
+
+	err = write()
+	if err != nil {
+		return nil, err
+	}
+
+	err = session.Where(afterCond).Find(after)
+	if err != nil {
+		return nil, err
+	}
+
+	return newCascadeChangeEvents(reflect.ValueOf(before).Elem().Interface(), reflect.ValueOf(after).Elem().Interface(), getKey)
+}
+
+// addChangeEvents inserts the events in the session writing their objects, the readers are notified
+// by notifyChangeEvents() once it is committed
+func addChangeEvents(session *xorm.Session, events []*ChangeEvent) error {
+	// the events are inserted one by one so that their ids follow their order
+	for _, event := range events {
+		if event == nil {
+			continue
+		}
+
+		_, err := session.Insert(event)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeWithChangeEvents runs a write of objects of the stream and the inserts of its events in one transaction,
+// so that the events are added if and only if the write is. getEvents is called when the write has affected rows.
+func writeWithChangeEvents(write func(session *xorm.Session) (int64, error), getEvents func(session *xorm.Session) ([]*ChangeEvent, error)) (int64, error) {
+	session := ormer.Engine.NewSession()
+	defer session.Close()
+
+	err := session.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	affected, err := write(session)
+	if err == nil && affected != 0 {
+		var events []*ChangeEvent
+		events, err = getEvents(session)
+		if err == nil {
+			err = addChangeEvents(session, events)
+		}
+	}
+	if err != nil {
+		session.Rollback()
+		return 0, err
+	}
+
+	err = session.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	if affected != 0 {
+		notifyChangeEvents()
+	}
+	return affected, nil
+}
+
+// updateWithChangeEvents runs the update of an object and adds its events, before is the object read before the update
+func updateWithChangeEvents(before interface{}, object interface{}, update func(session *xorm.Session) (int64, error)) (int64, error) {
+	return writeWithChangeEvents(update, func(session *xorm.Session) ([]*ChangeEvent, error) {
+		return getUpdateChangeEvents(session, before, object)
+	})
+}
+
+// insertWithChangeEvents inserts an object or a slice of objects and adds their create events
+func insertWithChangeEvents(objects interface{}) (int64, error) {
+	return writeWithChangeEvents(func(session *xorm.Session) (int64, error) {
+		return session.Insert(objects)
+	}, func(session *xorm.Session) ([]*ChangeEvent, error) {
+		if reflect.ValueOf(objects).Kind() == reflect.Slice {
+			return newCreateChangeEvents(objects)
+		}
+		return newChangeEvents(ChangeEventActionCreate, nil, objects)
+	})
+}
+
+// deleteWithChangeEvents deletes an object and adds its delete event, before is the object read before the delete
+func deleteWithChangeEvents(before interface{}, object interface{}) (int64, error) {
+	return writeWithChangeEvents(func(session *xorm.Session) (int64, error) {
+		_, _, owner, name := getChangeEventTarget(object)
+		return session.ID(core.PK{owner, name}).Delete(reflect.New(reflect.TypeOf(object).Elem()).Interface())
+	}, func(session *xorm.Session) ([]*ChangeEvent, error) {
+		return newChangeEvents(ChangeEventActionDelete, before, nil)
+	})
+}
+
+func GetChangeEventCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("v1:%d", id)))
+}
+
+// parseChangeEventCursor returns the id of a cursor, an empty cursor is the start of the stream
+// and "latest" its current end
+func parseChangeEventCursor(cursor string) (int64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+
+	if cursor == "latest" {
+		event := ChangeEvent{}
+		_, err := ormer.Engine.Desc("id").Cols("id").Get(&event)
+		if err != nil {
+			return 0, err
+		}
+		return event.Id, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err == nil && strings.HasPrefix(string(data), "v1:") {
+		id, err := strconv.ParseInt(strings.TrimPrefix(string(data), "v1:"), 10, 64)
+		if err == nil && id >= 0 {
+			return id, nil
+		}
+	}
+	return 0, fmt.Errorf("the cursor: %s is invalid", cursor)
+}
+
+// getChangeEventReadableId returns the last id that can be read after an id: the ids before a recent gap
+// are readable, the ids after it wait for the gap to be filled or to be old enough
+func getChangeEventReadableId(afterId int64) (int64, error) {
+	events := []*ChangeEvent{}
+	err := ormer.Engine.Cols("id", "created_time").Where("id > ?", afterId).Asc("id").Limit(changeEventScanSize).Find(&events)
+	if err != nil {
+		return 0, err
+	}
+
+	settledTime := time.Now().Add(-changeEventSettleSeconds * time.Second)
+	res := afterId
+	for _, event := range events {
+		if event.Id != res+1 {
+			createdTime, err := time.Parse(time.RFC3339, event.CreatedTime)
+			if err != nil || createdTime.After(settledTime) {
+				break
+			}
+		}
+		res = event.Id
+	}
+	return res, nil
+}
+
+// checkChangeEventCursor fails when events after the id have been removed by the retention: the cursors are ids of
+// events, so the next event of a cursor is gone when the oldest event is not next to it. The retention keeps the
+// latest event so that there is always an oldest one.
+func checkChangeEventCursor(afterId int64) error {
+	if afterId == 0 {
+		return nil
+	}
+
+	event := ChangeEvent{}
+	existed, err := ormer.Engine.Asc("id").Cols("id").Get(&event)
+	if err != nil {
+		return err
+	}
+
+	if existed && afterId < event.Id-1 {
+		return ErrChangeEventCursorExpired
+	}
+	return nil
+}
+
+// GetChangeEvents returns the events after a cursor in their order and the cursor to read the next ones
+func GetChangeEvents(cursor string, filter *ChangeEventFilter, limit int) ([]*ChangeEvent, string, error) {
+	afterId, err := parseChangeEventCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = checkChangeEventCursor(afterId)
+	if err != nil {
+		return nil, "", err
+	}
+
+	readableId, err := getChangeEventReadableId(afterId)
+	if err != nil {
+		return nil, "", err
+	}
+
+	events := []*ChangeEvent{}
+	if readableId > afterId {
+		session := ormer.Engine.Where("id > ? and id <= ?", afterId, readableId)
+		if filter.Organization != "" {
+			session = session.And("organization = ?", filter.Organization)
+		}
+		if len(filter.ObjectTypes) != 0 {
+			session = session.In("object_type", filter.ObjectTypes)
+		}
+		err = session.Asc("id").Limit(limit).Find(&events)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	// the events filtered out are skipped by the next cursor, unless the limit is reached before them
+	nextId := readableId
+	if len(events) == limit {
+		nextId = events[len(events)-1].Id
+	}
+	for _, event := range events {
+		event.Cursor = GetChangeEventCursor(event.Id)
+	}
+
+	return events, GetChangeEventCursor(nextId), nil
+}
+
+// WaitChangeEvents returns the events after a cursor, waiting up to the timeout for new ones when there is none.
+// The events added by this instance wake it up at once, those of the other instances are polled.
+func WaitChangeEvents(ctx context.Context, cursor string, filter *ChangeEventFilter, limit int, timeout time.Duration) ([]*ChangeEvent, string, error) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		signal := getChangeEventSignal()
+		events, nextCursor, err := GetChangeEvents(cursor, filter, limit)
+		if err != nil || len(events) != 0 {
+			return events, nextCursor, err
+		}
+		cursor = nextCursor
+
+		select {
+		case <-signal:
+		case <-time.After(changeEventPollInterval):
+		case <-ctx.Done():
+			return events, cursor, nil
+		case <-deadline.C:
+			return events, cursor, nil
+		}
+	}
+}
+
+// getChangeEventRetentionDays returns the days the events are kept, they are kept forever when it is not positive
+func getChangeEventRetentionDays() int64 {
+	days, err := conf.GetConfigInt64("changeEventRetentionDays")
+	if err != nil {
+		return 7
+	}
+	return days
+}
+
+func deleteExpiredChangeEvents() (int64, error) {
+	days := getChangeEventRetentionDays()
+	if days <= 0 {
+		return 0, nil
+	}
+
+	// the latest event is kept for checkChangeEventCursor()
+	latestEvent := ChangeEvent{}
+	existed, err := ormer.Engine.Desc("id").Cols("id").Get(&latestEvent)
+	if err != nil || !existed {
+		return 0, err
+	}
+
+	cutoffTime := time.Now().AddDate(0, 0, -int(days)).Format(time.RFC3339)
+	return ormer.Engine.Where("created_time < ? and id < ?", cutoffTime, latestEvent.Id).Delete(&ChangeEvent{})
+}
+
+func RunChangeEventRetentionJob() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for ; true; <-ticker.C {
+		affected, err := deleteExpiredChangeEvents()
+		if err != nil {
+			logs.Error(fmt.Sprintf("RunChangeEventRetentionJob() error: %s", err.Error()))
+			continue
+		}
+
+		if affected != 0 {
+			logs.Info(fmt.Sprintf("RunChangeEventRetentionJob() removed %d change events", affected))
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChangeEventCursor(t *testing.T) {
+	for _, id := range []int64{0, 1, 4242} {
+		res, err := parseChangeEventCursor(GetChangeEventCursor(id))
+		if err != nil || res != id {
+			t.Fatalf("the cursor of %d is parsed as %d: %v", id, res, err)
+		}
+	}
+
+	for _, cursor := range []string{"42", "v1:42", GetChangeEventCursor(42) + "!", "djI6NDI"} {
+		_, err := parseChangeEventCursor(cursor)
+		if err == nil {
+			t.Fatalf("the cursor should be invalid: %s", cursor)
+		}
+	}
+}
+
+func TestNewChangeEvent(t *testing.T) {
+	user := &User{Owner: "org", Name: "alice", Password: "secret", AccessSecret: "key", TotpSecret: "totp"}
+	event, err := newChangeEvent(ChangeEventActionCreate, nil, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.ObjectType != ChangeEventObjectUser || event.ObjectId != "org/alice" || event.Organization != "org" || event.Before != nil {
+		t.Fatalf("unexpected event: %v", event)
+	}
+	if event.After["password"] != "***" || event.After["accessSecret"] != "***" || event.After["totpSecret"] != "" {
+		t.Fatalf("the secrets are not masked: %v", event.After)
+	}
+	if user.Password != "secret" || user.AccessSecret != "key" {
+		t.Fatalf("the user is modified: %v", user)
+	}
+
+	// an update only changing a masked secret is still reported
+	events, err := newChangeEvents(ChangeEventActionUpdate, user, &User{Owner: "org", Name: "alice", Password: "changed", AccessSecret: "key", TotpSecret: "totp"})
+	if err != nil || len(events) != 1 || events[0].Action != ChangeEventActionUpdate {
+		t.Fatalf("unexpected events: %v, %v", events, err)
+	}
+
+	events, err = newChangeEvents(ChangeEventActionUpdate, user, user)
+	if err != nil || len(events) != 0 {
+		t.Fatalf("an update not changing anything should have no event: %v, %v", events, err)
+	}
+
+	// a rename is also the delete of the old id
+	events, err = newChangeEvents(ChangeEventActionUpdate, user, &User{Owner: "org", Name: "bob"})
+	if err != nil || len(events) != 2 || events[0].Action != ChangeEventActionDelete || events[0].ObjectId != "org/alice" || events[1].ObjectId != "org/bob" {
+		t.Fatalf("unexpected events: %v, %v", events, err)
+	}
+
+	application := &Application{Owner: "admin", Name: "app", Organization: "org", ClientSecret: "secret"}
+	event, err = newChangeEvent(ChangeEventActionDelete, application, (*Application)(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.ObjectId != "admin/app" || event.Organization != "org" || event.After != nil || event.Before["clientSecret"] != "***" {
+		t.Fatalf("unexpected event: %v", event)
+	}
+}
+
+func TestWriteWithChangeEvents(t *testing.T) {
+	// the events can't be added without their table, and neither is the group
+	setupTestOrmer(t, new(Group))
+	_, err := insertWithChangeEvents(&Group{Owner: "org", Name: "group"})
+	if err == nil || !strings.Contains(err.Error(), "change_event") {
+		t.Fatal("the group should not be added without its event")
+	}
+
+	existed, err := ormer.Engine.Exist(&Group{Owner: "org", Name: "group"})
+	if err != nil || existed {
+		t.Fatalf("the group should have been rolled back: %v", err)
+	}
+
+	err = ormer.Engine.Sync2(new(ChangeEvent))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = insertWithChangeEvents(&Group{Owner: "org", Name: "group"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := ormer.Engine.Count(&ChangeEvent{})
+	if err != nil || count != 1 {
+		t.Fatalf("expected the event of the group, got %d: %v", count, err)
+	}
+}
+
+func TestChangeEventCursorExpired(t *testing.T) {
+	setupTestOrmer(t, new(ChangeEvent))
+
+	oldTime := time.Now().AddDate(0, 0, -30).Format(time.RFC3339)
+	for i := 0; i < 3; i++ {
+		_, err := ormer.Engine.Insert(&ChangeEvent{CreatedTime: oldTime, ObjectType: ChangeEventObjectUser, Action: ChangeEventActionCreate})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	affected, err := deleteExpiredChangeEvents()
+	if err != nil || affected != 2 {
+		t.Fatalf("expected all the events but the latest to be removed, got %d: %v", affected, err)
+	}
+
+	_, _, err = GetChangeEvents(GetChangeEventCursor(1), &ChangeEventFilter{}, 10)
+	if err != ErrChangeEventCursorExpired {
+		t.Fatalf("the cursor before the removed events should be expired, got %v", err)
+	}
+
+	for _, cursor := range []string{GetChangeEventCursor(2), "", "latest"} {
+		_, _, err = GetChangeEvents(cursor, &ChangeEventFilter{}, 10)
+		if err != nil {
+			t.Fatalf("the cursor: %q should still be valid: %v", cursor, err)
+		}
+	}
+}
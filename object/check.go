@@ -476,13 +476,7 @@ func CheckLoginPermission(userId string, application *Application) (bool, error)
 			continue
 		}
 
-		enforcer, err := getPermissionEnforcer(permission)
-		if err != nil {
-			return false, err
-		}
-
-		var isAllowed bool
-		isAllowed, err = enforcer.Enforce(userId, application.Name, "Read")
+		isAllowed, err := Enforce(permission, []string{userId, application.Name, "Read"})
 		if err != nil {
 			return false, err
 		}
@@ -0,0 +1,245 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/beego/beego/logs"
+	"github.com/casdoor/casdoor/conf"
+	"github.com/casdoor/casdoor/util"
+)
+
+// Sensitive columns are encrypted with AES-GCM by the functions writing them, and decrypted when they are
+// loaded and once they are written through the xorm processors below, so the rest of the code keeps seeing plaintext.
+// The stored value is "enc:v1:<key id>:<base64 of nonce and ciphertext>", the key id selects the key
+// in the "columnEncryptionKeys" config (a JSON map of key id to base64 256-bit key), and new values are
+// encrypted with the key of "columnEncryptionKeyId". Without a key id, values are stored as plaintext.
+const columnEncryptionPrefix = "enc:v1:"
+
+// the column encryption config is parsed once by initColumnEncryption at startup
+var (
+	columnEncryptionKeys  = map[string][]byte{}
+	columnEncryptionKeyId = ""
+)
+
+func parseColumnEncryptionKeys(keysJson string) (map[string][]byte, error) {
+	keys := map[string][]byte{}
+	if keysJson == "" {
+		return keys, nil
+	}
+
+	base64Keys := map[string]string{}
+	err := json.Unmarshal([]byte(keysJson), &base64Keys)
+	if err != nil {
+		return nil, fmt.Errorf("columnEncryptionKeys should be a JSON map of key id to base64 key: %s", err.Error())
+	}
+
+	for keyId, base64Key := range base64Keys {
+		if keyId == "" || strings.Contains(keyId, ":") {
+			return nil, fmt.Errorf("invalid column encryption key id: %q", keyId)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(base64Key)
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("the column encryption key: %s should be a base64 encoded 256-bit key", keyId)
+		}
+		keys[keyId] = key
+	}
+	return keys, nil
+}
+
+func isColumnEncrypted(value string) bool {
+	return strings.HasPrefix(value, columnEncryptionPrefix)
+}
+
+func getColumnKeyId(value string) string {
+	tokens := strings.SplitN(strings.TrimPrefix(value, columnEncryptionPrefix), ":", 2)
+	return tokens[0]
+}
+
+// initColumnEncryption parses the column encryption config, it fails when new values can't be encrypted,
+// so that the startup fails rather than the first write
+func initColumnEncryption() error {
+	keys, err := parseColumnEncryptionKeys(conf.GetConfigString("columnEncryptionKeys"))
+	if err != nil {
+		return err
+	}
+
+	keyId := conf.GetConfigString("columnEncryptionKeyId")
+	if _, ok := keys[keyId]; keyId != "" && !ok {
+		return fmt.Errorf("the column encryption key: %s is not in columnEncryptionKeys", keyId)
+	}
+
+	columnEncryptionKeys = keys
+	columnEncryptionKeyId = keyId
+	return nil
+}
+
+func encryptColumn(value string) (string, error) {
+	keyId := columnEncryptionKeyId
+	if keyId == "" || value == "" {
+		return value, nil
+	}
+
+	if isColumnEncrypted(value) {
+		if getColumnKeyId(value) == keyId {
+			return value, nil
+		}
+
+		// a value still encrypted with an old key is decrypted with it before being encrypted with the current one
+		plaintext, err := decryptColumn(value)
+		if err != nil {
+			return "", err
+		}
+		value = plaintext
+	}
+
+	key, ok := columnEncryptionKeys[keyId]
+	if !ok {
+		return "", fmt.Errorf("the column encryption key: %s is not in columnEncryptionKeys", keyId)
+	}
+
+	ciphertext, err := util.AesGcmEncrypt(key, []byte(value))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%s:%s", columnEncryptionPrefix, keyId, base64.StdEncoding.EncodeToString(ciphertext)), nil
+}
+
+func decryptColumn(value string) (string, error) {
+	if !isColumnEncrypted(value) {
+		return value, nil
+	}
+
+	tokens := strings.SplitN(strings.TrimPrefix(value, columnEncryptionPrefix), ":", 2)
+	if len(tokens) != 2 {
+		return "", fmt.Errorf("invalid encrypted column value")
+	}
+
+	key, ok := columnEncryptionKeys[tokens[0]]
+	if !ok {
+		return "", fmt.Errorf("the column encryption key: %s is not in columnEncryptionKeys", tokens[0])
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(tokens[1])
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := util.AesGcmDecrypt(key, ciphertext)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// decryptColumns is called after a row is loaded or written, a value that can't be decrypted is left encrypted
+func decryptColumns(values ...*string) {
+	for _, value := range values {
+		res, err := decryptColumn(*value)
+		if err != nil {
+			logs.Error(fmt.Sprintf("decryptColumns() error: %s", err.Error()))
+			continue
+		}
+		*value = res
+	}
+}
+
+type encryptedColumnsBean interface {
+	getEncryptedColumns() []*string
+}
+
+// encryptColumns is called before the beans are written, the values are only replaced once all of them are
+// encrypted, so that a write failing to encrypt a value doesn't store the plaintext of the others.
+// The After processors of the beans decrypt the values once they are written, restoreEncryptedColumns
+// does it when the write fails.
+func encryptColumns(beans ...encryptedColumnsBean) error {
+	values := []*string{}
+	for _, bean := range beans {
+		values = append(values, bean.getEncryptedColumns()...)
+	}
+
+	res := make([]string, len(values))
+	for i, value := range values {
+		encrypted, err := encryptColumn(*value)
+		if err != nil {
+			return err
+		}
+		res[i] = encrypted
+	}
+
+	for i, value := range values {
+		*value = res[i]
+	}
+	return nil
+}
+
+// restoreEncryptedColumns puts the plaintext back into a bean whose write has failed or has been rolled back,
+// xorm only runs the After processors decrypting the columns once the write is done
+func restoreEncryptedColumns(beans ...encryptedColumnsBean) {
+	for _, bean := range beans {
+		decryptColumns(bean.getEncryptedColumns()...)
+	}
+}
+
+func (provider *Provider) getEncryptedColumns() []*string {
+	return []*string{&provider.ClientSecret, &provider.ClientSecret2}
+}
+
+func (provider *Provider) AfterInsert() { decryptColumns(provider.getEncryptedColumns()...) }
+func (provider *Provider) AfterUpdate() { decryptColumns(provider.getEncryptedColumns()...) }
+func (provider *Provider) AfterLoad()   { decryptColumns(provider.getEncryptedColumns()...) }
+
+func (ldap *Ldap) getEncryptedColumns() []*string {
+	return []*string{&ldap.Password}
+}
+
+func (ldap *Ldap) AfterInsert() { decryptColumns(ldap.getEncryptedColumns()...) }
+func (ldap *Ldap) AfterUpdate() { decryptColumns(ldap.getEncryptedColumns()...) }
+func (ldap *Ldap) AfterLoad()   { decryptColumns(ldap.getEncryptedColumns()...) }
+
+func (syncer *Syncer) getEncryptedColumns() []*string {
+	return []*string{&syncer.Password, &syncer.SshPassword, &syncer.AuthHeader}
+}
+
+func (syncer *Syncer) AfterInsert() { decryptColumns(syncer.getEncryptedColumns()...) }
+func (syncer *Syncer) AfterUpdate() { decryptColumns(syncer.getEncryptedColumns()...) }
+func (syncer *Syncer) AfterLoad()   { decryptColumns(syncer.getEncryptedColumns()...) }
+
+func (user *User) getEncryptedColumns() []*string {
+	return []*string{&user.TotpSecret}
+}
+
+func (user *User) AfterInsert() { decryptColumns(user.getEncryptedColumns()...) }
+func (user *User) AfterUpdate() { decryptColumns(user.getEncryptedColumns()...) }
+func (user *User) AfterLoad()   { decryptColumns(user.getEncryptedColumns()...) }
+
+func (webhook *Webhook) getEncryptedColumns() []*string {
+	res := []*string{}
+	for _, header := range webhook.Headers {
+		if header != nil {
+			res = append(res, &header.Value)
+		}
+	}
+	return res
+}
+
+func (webhook *Webhook) AfterInsert() { decryptColumns(webhook.getEncryptedColumns()...) }
+func (webhook *Webhook) AfterUpdate() { decryptColumns(webhook.getEncryptedColumns()...) }
+func (webhook *Webhook) AfterLoad()   { decryptColumns(webhook.getEncryptedColumns()...) }
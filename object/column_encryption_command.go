@@ -0,0 +1,175 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"fmt"
+
+	"github.com/xorm-io/core"
+	"github.com/xorm-io/xorm"
+)
+
+const (
+	ColumnEncryptionMigrate = "migrate"
+	ColumnEncryptionRekey   = "rekey"
+)
+
+// reencryptRow decrypts the sensitive columns of a row with the keys they have been encrypted with, and writes them
+// back to be encrypted with the current key, a value that can't be decrypted or encrypted fails the row
+func reencryptRow(id string, bean encryptedColumnsBean, update func() error) error {
+	for _, value := range bean.getEncryptedColumns() {
+		plaintext, err := decryptColumn(*value)
+		if err != nil {
+			return fmt.Errorf("%s: %s", id, err.Error())
+		}
+		*value = plaintext
+	}
+
+	err := encryptColumns(bean)
+	if err != nil {
+		return fmt.Errorf("%s: %s", id, err.Error())
+	}
+
+	err = update()
+	if err != nil {
+		restoreEncryptedColumns(bean)
+		return fmt.Errorf("%s: %s", id, err.Error())
+	}
+	return nil
+}
+
+// reencryptColumns writes the sensitive columns of every row back encrypted with the current key,
+// so this both encrypts plaintext rows and re-keys encrypted ones, it stops at the first row failing
+func reencryptColumns() (int, error) {
+	count := 0
+
+	providers := []*Provider{}
+	err := ormer.Engine.Find(&providers)
+	if err != nil {
+		return count, err
+	}
+	for _, provider := range providers {
+		err = reencryptRow("provider: "+provider.GetId(), provider, func() error {
+			_, err := ormer.Engine.ID(core.PK{provider.Owner, provider.Name}).Cols("client_secret", "client_secret2").Update(provider)
+			return err
+		})
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	ldaps := []*Ldap{}
+	err = ormer.Engine.Find(&ldaps)
+	if err != nil {
+		return count, err
+	}
+	for _, ldap := range ldaps {
+		err = reencryptRow("LDAP: "+ldap.Id, ldap, func() error {
+			_, err := ormer.Engine.ID(ldap.Id).Cols("password").Update(ldap)
+			return err
+		})
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	syncers := []*Syncer{}
+	err = ormer.Engine.Find(&syncers)
+	if err != nil {
+		return count, err
+	}
+	for _, syncer := range syncers {
+		err = reencryptRow("syncer: "+syncer.GetId(), syncer, func() error {
+			_, err := ormer.Engine.ID(core.PK{syncer.Owner, syncer.Name}).Cols("password", "ssh_password", "auth_header").Update(syncer)
+			return err
+		})
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	webhooks := []*Webhook{}
+	err = ormer.Engine.Find(&webhooks)
+	if err != nil {
+		return count, err
+	}
+	for _, webhook := range webhooks {
+		err = reencryptRow("webhook: "+webhook.GetId(), webhook, func() error {
+			_, err := ormer.Engine.ID(core.PK{webhook.Owner, webhook.Name}).Cols("headers").Update(webhook)
+			return err
+		})
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	users := []*User{}
+	err = ormer.Engine.Where("totp_secret != ?", "").Find(&users)
+	if err != nil {
+		return count, err
+	}
+	for _, user := range users {
+		err = reencryptRow("user: "+user.GetId(), user, func() error {
+			// the rewrite keeps the values, its event tells the readers that the row has been written
+			_, err := writeWithChangeEvents(func(session *xorm.Session) (int64, error) {
+				return session.ID(core.PK{user.Owner, user.Name}).Cols("totp_secret").Update(user)
+			}, func(session *xorm.Session) ([]*ChangeEvent, error) {
+				event, err := newChangeEvent(ChangeEventActionUpdate, user, user)
+				return []*ChangeEvent{event}, err
+			})
+			return err
+		})
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// RunColumnEncryptionCommand runs the command given by the "-columnEncryption" flag and returns whether there was one.
+// Rotating the key is: add the new key to columnEncryptionKeys, point columnEncryptionKeyId at it,
+// run "-columnEncryption=rekey", then remove the old key. The column encryption config is parsed at every start.
+func RunColumnEncryptionCommand() bool {
+	err := initColumnEncryption()
+	if err != nil {
+		panic(err)
+	}
+
+	if columnEncryptionCommand == "" {
+		return false
+	}
+
+	if columnEncryptionCommand != ColumnEncryptionMigrate && columnEncryptionCommand != ColumnEncryptionRekey {
+		panic(fmt.Errorf("unknown column encryption command: %s", columnEncryptionCommand))
+	}
+
+	if columnEncryptionKeyId == "" {
+		panic(fmt.Errorf("columnEncryptionKeyId should be set to run the column encryption command: %s", columnEncryptionCommand))
+	}
+
+	count, err := reencryptColumns()
+	if err != nil {
+		panic(fmt.Errorf("the column encryption command: %s failed after %d rows: %s", columnEncryptionCommand, count, err.Error()))
+	}
+
+	fmt.Printf("The column encryption command: %s has updated %d rows with the key: %s\n", columnEncryptionCommand, count, columnEncryptionKeyId)
+	return true
+}
@@ -0,0 +1,193 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"strings"
+	"testing"
+)
+
+// setTestColumnEncryption parses the column encryption config of a test, the previous one is put back after it
+func setTestColumnEncryption(t *testing.T, keysJson string, keyId string) {
+	t.Setenv("columnEncryptionKeys", keysJson)
+	t.Setenv("columnEncryptionKeyId", keyId)
+
+	keys, oldKeyId := columnEncryptionKeys, columnEncryptionKeyId
+	t.Cleanup(func() {
+		columnEncryptionKeys, columnEncryptionKeyId = keys, oldKeyId
+	})
+
+	err := initColumnEncryption()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestColumnEncryption(t *testing.T) {
+	setTestColumnEncryption(t, `{"k1": "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=", "k2": "ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA="}`, "k1")
+
+	provider := &Provider{ClientSecret: "secret", ClientSecret2: ""}
+	err := encryptColumns(provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(provider.ClientSecret, "enc:v1:k1:") || provider.ClientSecret2 != "" {
+		t.Fatalf("unexpected encrypted columns: %q, %q", provider.ClientSecret, provider.ClientSecret2)
+	}
+	encrypted := provider.ClientSecret
+
+	// encrypting twice keeps the value
+	err = encryptColumns(provider)
+	if err != nil || provider.ClientSecret != encrypted {
+		t.Fatalf("the column should not be encrypted twice: %s", provider.ClientSecret)
+	}
+
+	// the values encrypted with the old key can still be read after switching to the new key
+	setTestColumnEncryption(t, `{"k1": "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=", "k2": "ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA="}`, "k2")
+	provider.AfterLoad()
+	if provider.ClientSecret != "secret" {
+		t.Fatalf("unexpected decrypted column: %s", provider.ClientSecret)
+	}
+
+	err = encryptColumns(provider)
+	if err != nil || !strings.HasPrefix(provider.ClientSecret, "enc:v1:k2:") {
+		t.Fatalf("the column should be encrypted with the new key: %s", provider.ClientSecret)
+	}
+
+	// plaintext values written before the encryption was enabled are read as they are
+	value, err := decryptColumn("plain")
+	if err != nil || value != "plain" {
+		t.Fatalf("unexpected plaintext column: %q, %v", value, err)
+	}
+
+	setTestColumnEncryption(t, `{"k2": "ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA="}`, "k2")
+	_, err = decryptColumn(encrypted)
+	if err == nil {
+		t.Fatal("the column should not be decrypted without its key")
+	}
+
+	webhook := &Webhook{Headers: []*Header{{Name: "Authorization", Value: "Bearer token"}}}
+	err = encryptColumns(webhook)
+	if err != nil || !isColumnEncrypted(webhook.Headers[0].Value) || webhook.Headers[0].Name != "Authorization" {
+		t.Fatalf("unexpected encrypted header: %v", webhook.Headers[0])
+	}
+	webhook.AfterInsert()
+	if webhook.Headers[0].Value != "Bearer token" {
+		t.Fatalf("unexpected decrypted header: %s", webhook.Headers[0].Value)
+	}
+}
+
+func TestColumnEncryptionFailClosed(t *testing.T) {
+	setTestColumnEncryption(t, `{"k1": "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="}`, "k1")
+
+	t.Setenv("columnEncryptionKeyId", "k2")
+	err := initColumnEncryption()
+	if err == nil {
+		t.Fatal("the config should be refused without the key of columnEncryptionKeyId")
+	}
+
+	// a value encrypted with a key which has been removed can't be encrypted again
+	provider := &Provider{ClientSecret: "enc:v1:k0:AAAA", ClientSecret2: "secret2"}
+	err = encryptColumns(provider)
+	if err == nil {
+		t.Fatal("the write should be refused when the columns can't be encrypted")
+	}
+	if provider.ClientSecret != "enc:v1:k0:AAAA" || provider.ClientSecret2 != "secret2" {
+		t.Fatalf("the columns should be left as they were: %q, %q", provider.ClientSecret, provider.ClientSecret2)
+	}
+}
+
+func TestReencryptColumns(t *testing.T) {
+	setupTestOrmer(t, new(Provider), new(Ldap), new(Syncer), new(Webhook), new(User))
+	setTestColumnEncryption(t, `{"k1": "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=", "k2": "ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA="}`, "k1")
+
+	provider := &Provider{Owner: "admin", Name: "provider-test", ClientSecret: "secret"}
+	_, err := AddProvider(provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if provider.ClientSecret != "secret" {
+		t.Fatalf("the plaintext should be restored after the write: %s", provider.ClientSecret)
+	}
+
+	var values []string
+	err = ormer.Engine.Table(new(Provider)).Cols("client_secret").Find(&values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 1 || !strings.HasPrefix(values[0], "enc:v1:k1:") {
+		t.Fatalf("the column should be encrypted by the write: %v", values)
+	}
+
+	// the rows are decrypted with the old key and encrypted with the new one
+	setTestColumnEncryption(t, `{"k1": "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=", "k2": "ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA="}`, "k2")
+	count, err := reencryptColumns()
+	if err != nil || count != 1 {
+		t.Fatalf("unexpected re-encryption: %d, %v", count, err)
+	}
+
+	values = []string{}
+	err = ormer.Engine.Table(new(Provider)).Cols("client_secret").Find(&values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 1 || !strings.HasPrefix(values[0], "enc:v1:k2:") {
+		t.Fatalf("the column should be encrypted with the new key: %v", values)
+	}
+
+	// a row whose key is gone fails the command instead of being skipped
+	setTestColumnEncryption(t, `{"k3": "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="}`, "k3")
+	_, err = reencryptColumns()
+	if err == nil {
+		t.Fatal("the re-encryption should fail without the key of a row")
+	}
+}
+
+func TestUpdateUserColumnEncryption(t *testing.T) {
+	setupTestOrmer(t, &User{}, &ChangeEvent{}, &Syncer{})
+	setTestColumnEncryption(t, `{"k1": "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="}`, "k1")
+
+	user := &User{Owner: "built-in", Name: "alice"}
+	_, err := ormer.Engine.Insert(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user.TotpSecret = "secret"
+	_, err = updateUser(user.GetId(), user, []string{"totp_secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.TotpSecret != "secret" {
+		t.Fatalf("the plaintext should be restored after the write: %s", user.TotpSecret)
+	}
+
+	var values []string
+	err = ormer.Engine.Table(new(User)).Cols("totp_secret").Find(&values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 1 || !strings.HasPrefix(values[0], "enc:v1:k1:") {
+		t.Fatalf("the column should be encrypted by the write: %v", values)
+	}
+
+	// a write failing to encrypt returns an error instead of writing the plaintext
+	setTestColumnEncryption(t, `{"k2": "ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA="}`, "k2")
+	user.TotpSecret = values[0]
+	_, err = updateUser(user.GetId(), user, []string{"totp_secret"})
+	if err == nil {
+		t.Fatal("the write should be refused when the columns can't be encrypted")
+	}
+}
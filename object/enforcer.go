@@ -168,6 +168,7 @@ func (enforcer *Enforcer) InitEnforcer() error {
 	if err != nil {
 		return err
 	}
+	enableAttributeRequests(casbinEnforcer)
 
 	enforcer.Enforcer = casbinEnforcer
 	return nil
@@ -212,11 +213,19 @@ func UpdatePolicy(id string, ptype string, oldPolicy []string, newPolicy []strin
 		return false, err
 	}
 
+	var affected bool
 	if ptype == "p" {
-		return enforcer.UpdatePolicy(oldPolicy, newPolicy)
+		affected, err = enforcer.UpdatePolicy(oldPolicy, newPolicy)
 	} else {
-		return enforcer.UpdateGroupingPolicy(oldPolicy, newPolicy)
+		affected, err = enforcer.UpdateGroupingPolicy(oldPolicy, newPolicy)
 	}
+	if err != nil {
+		return false, err
+	}
+
+	// the adapter of the enforcer may be the one of some permissions
+	refreshPermissionEnforcers(permissionEnforcerMessageAll)
+	return affected, nil
 }
 
 func AddPolicy(id string, ptype string, policy []string) (bool, error) {
@@ -225,11 +234,19 @@ func AddPolicy(id string, ptype string, policy []string) (bool, error) {
 		return false, err
 	}
 
+	var affected bool
 	if ptype == "p" {
-		return enforcer.AddPolicy(policy)
+		affected, err = enforcer.AddPolicy(policy)
 	} else {
-		return enforcer.AddGroupingPolicy(policy)
+		affected, err = enforcer.AddGroupingPolicy(policy)
+	}
+	if err != nil {
+		return false, err
 	}
+
+	// the adapter of the enforcer may be the one of some permissions
+	refreshPermissionEnforcers(permissionEnforcerMessageAll)
+	return affected, nil
 }
 
 func RemovePolicy(id string, ptype string, policy []string) (bool, error) {
@@ -238,11 +255,19 @@ func RemovePolicy(id string, ptype string, policy []string) (bool, error) {
 		return false, err
 	}
 
+	var affected bool
 	if ptype == "p" {
-		return enforcer.RemovePolicy(policy)
+		affected, err = enforcer.RemovePolicy(policy)
 	} else {
-		return enforcer.RemoveGroupingPolicy(policy)
+		affected, err = enforcer.RemoveGroupingPolicy(policy)
+	}
+	if err != nil {
+		return false, err
 	}
+
+	// the adapter of the enforcer may be the one of some permissions
+	refreshPermissionEnforcers(permissionEnforcerMessageAll)
+	return affected, nil
 }
 
 func (enforcer *Enforcer) LoadModelCfg() error {
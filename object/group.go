@@ -23,6 +23,7 @@ import (
 	"github.com/casdoor/casdoor/util"
 	"github.com/xorm-io/builder"
 	"github.com/xorm-io/core"
+	"github.com/xorm-io/xorm"
 )
 
 type Group struct {
@@ -148,7 +149,14 @@ func UpdateGroup(id string, group *Group) (bool, error) {
 		}
 	}
 
-	affected, err := ormer.Engine.ID(core.PK{owner, name}).AllCols().Update(group)
+	oldObject, err := getChangeEventObject(ormer.Engine, oldGroup)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := updateWithChangeEvents(oldObject, group, func(session *xorm.Session) (int64, error) {
+		return session.ID(core.PK{owner, name}).AllCols().Update(group)
+	})
 	if err != nil {
 		return false, err
 	}
@@ -162,7 +170,7 @@ func AddGroup(group *Group) (bool, error) {
 		return false, err
 	}
 
-	affected, err := ormer.Engine.Insert(group)
+	affected, err := insertWithChangeEvents(group)
 	if err != nil {
 		return false, err
 	}
@@ -174,7 +182,7 @@ func AddGroups(groups []*Group) (bool, error) {
 	if len(groups) == 0 {
 		return false, nil
 	}
-	affected, err := ormer.Engine.Insert(groups)
+	affected, err := insertWithChangeEvents(groups)
 	if err != nil {
 		return false, err
 	}
@@ -182,7 +190,12 @@ func AddGroups(groups []*Group) (bool, error) {
 }
 
 func deleteGroup(group *Group) (bool, error) {
-	affected, err := ormer.Engine.ID(core.PK{group.Owner, group.Name}).Delete(&Group{})
+	oldGroup, err := getChangeEventObject(ormer.Engine, group)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := deleteWithChangeEvents(oldGroup, group)
 	if err != nil {
 		return false, err
 	}
@@ -370,6 +383,8 @@ func GroupChangeTrigger(oldName, newName string) error {
 		return err
 	}
 
+	events := []*ChangeEvent{}
+
 	users := []*User{}
 	err = session.Where(builder.Like{"`groups`", oldName}).Find(&users)
 	if err != nil {
@@ -377,11 +392,18 @@ func GroupChangeTrigger(oldName, newName string) error {
 	}
 
 	for _, user := range users {
+		oldUser := *user
 		user.Groups = util.ReplaceVal(user.Groups, oldName, newName)
-		_, err := updateUser(user.GetId(), user, []string{"groups"})
+		_, err := session.ID(core.PK{user.Owner, user.Name}).Cols("groups").Update(user)
 		if err != nil {
 			return err
 		}
+
+		userEvents, err := getUpdateChangeEvents(session, &oldUser, user)
+		if err != nil {
+			return err
+		}
+		events = append(events, userEvents...)
 	}
 
 	groups := []*Group{}
@@ -390,16 +412,33 @@ func GroupChangeTrigger(oldName, newName string) error {
 		return err
 	}
 	for _, group := range groups {
+		oldGroup := *group
 		group.ParentId = newName
 		_, err := session.ID(core.PK{group.Owner, group.Name}).Cols("parent_id").Update(group)
 		if err != nil {
 			return err
 		}
+
+		groupEvents, err := newChangeEvents(ChangeEventActionUpdate, &oldGroup, group)
+		if err != nil {
+			return err
+		}
+		events = append(events, groupEvents...)
+	}
+
+	err = addChangeEvents(session, events)
+	if err != nil {
+		return err
 	}
 
 	err = session.Commit()
 	if err != nil {
 		return err
 	}
+
+	notifyChangeEvents()
+
+	// the group memberships of the users have changed
+	refreshPermissionEnforcers(permissionEnforcerMessageAll)
 	return nil
 }
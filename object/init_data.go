@@ -793,7 +793,7 @@ func initDefinedInvitation(invitation *Invitation) {
 func initDefinedRecord(record *casvisorsdk.Record) {
 	record.Id = 0
 	record.CreatedTime = util.GetCurrentTime()
-	_ = AddRecord(record)
+	_, _ = AddRecord(record)
 }
 
 func initDefinedSession(session *Session) {
@@ -0,0 +1,64 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"time"
+
+	"github.com/casdoor/casdoor/util"
+)
+
+// JobLease lets a single Casdoor instance run a background job when several share the database.
+// The holder renews the lease on each run, another instance takes it over once it expires.
+type JobLease struct {
+	Name       string `xorm:"varchar(100) notnull pk" json:"name"`
+	Holder     string `xorm:"varchar(100)" json:"holder"`
+	ExpireTime int64  `json:"expireTime"`
+}
+
+// the holder of the leases taken by this instance
+var jobLeaseHolder = util.GenerateId()
+
+// acquireJobLease takes or renews the lease of a job for the duration, and tells whether this instance holds it
+func acquireJobLease(name string, duration time.Duration) (bool, error) {
+	now := time.Now()
+	lease := &JobLease{Name: name, Holder: jobLeaseHolder, ExpireTime: now.Add(duration).Unix()}
+
+	// a single statement, so that only one instance takes an expired lease
+	affected, err := ormer.Engine.Where("name = ? and (holder = ? or expire_time < ?)", name, jobLeaseHolder, now.Unix()).
+		Cols("holder", "expire_time").Update(lease)
+	if err != nil {
+		return false, err
+	}
+	if affected != 0 {
+		return true, nil
+	}
+
+	existed, err := ormer.Engine.Exist(&JobLease{Name: name})
+	if err != nil || existed {
+		return false, err
+	}
+
+	_, err = ormer.Engine.Insert(lease)
+	if err != nil {
+		// another instance has inserted the lease first
+		existed, existErr := ormer.Engine.Exist(&JobLease{Name: name})
+		if existErr == nil && existed {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
@@ -29,7 +29,7 @@ type Ldap struct {
 	EnableSsl           bool     `xorm:"bool" json:"enableSsl"`
 	AllowSelfSignedCert bool     `xorm:"bool" json:"allowSelfSignedCert"`
 	Username            string   `xorm:"varchar(100)" json:"username"`
-	Password            string   `xorm:"varchar(100)" json:"password"`
+	Password            string   `xorm:"varchar(1000)" json:"password"`
 	BaseDn              string   `xorm:"varchar(100)" json:"baseDn"`
 	Filter              string   `xorm:"varchar(200)" json:"filter"`
 	FilterFields        []string `xorm:"varchar(100)" json:"filterFields"`
@@ -49,8 +49,14 @@ func AddLdap(ldap *Ldap) (bool, error) {
 		ldap.CreatedTime = util.GetCurrentTime()
 	}
 
+	err := encryptColumns(ldap)
+	if err != nil {
+		return false, err
+	}
+
 	affected, err := ormer.Engine.Insert(ldap)
 	if err != nil {
+		restoreEncryptedColumns(ldap)
 		return false, err
 	}
 
@@ -150,9 +156,15 @@ func UpdateLdap(ldap *Ldap) (bool, error) {
 		ldap.Password = l.Password
 	}
 
+	err = encryptColumns(ldap)
+	if err != nil {
+		return false, err
+	}
+
 	affected, err := ormer.Engine.ID(ldap.Id).Cols("owner", "server_name", "host",
 		"port", "enable_ssl", "username", "password", "base_dn", "filter", "filter_fields", "auto_sync", "default_group", "password_type", "allow_self_signed_cert").Update(ldap)
 	if err != nil {
+		restoreEncryptedColumns(ldap)
 		return false, nil
 	}
 
@@ -17,7 +17,10 @@ package object
 import (
 	"fmt"
 
+	"github.com/beego/beego/logs"
 	"github.com/casdoor/casdoor/util"
+	"github.com/xorm-io/core"
+	"github.com/xorm-io/xorm"
 )
 
 type MfaProps struct {
@@ -28,6 +31,8 @@ type MfaProps struct {
 	CountryCode   string   `json:"countryCode,omitempty"`
 	URL           string   `json:"url,omitempty"`
 	RecoveryCodes []string `json:"recoveryCodes,omitempty"`
+
+	RecoveryCodeCount int `json:"recoveryCodeCount,omitempty"`
 }
 
 type MfaInterface interface {
@@ -38,13 +43,17 @@ type MfaInterface interface {
 }
 
 const (
-	EmailType = "email"
-	SmsType   = "sms"
-	TotpType  = "app"
+	EmailType        = "email"
+	SmsType          = "sms"
+	TotpType         = "app"
+	RecoveryCodeType = "recovery"
+	WebAuthnType     = "webauthn"
+	PushType         = "push"
 )
 
 const (
 	MfaSessionUserId = "MfaSessionUserId"
+	MfaSessionUsed   = "MfaSessionUsed"
 	NextMfa          = "NextMfa"
 	RequiredMfa      = "RequiredMfa"
 )
@@ -57,33 +66,60 @@ func GetMfaUtil(mfaType string, config *MfaProps) MfaInterface {
 		return NewEmailMfaUtil(config)
 	case TotpType:
 		return NewTotpMfaUtil(config)
+	case RecoveryCodeType:
+		return NewRecoveryCodeMfaUtil(config)
+	case WebAuthnType:
+		return NewWebAuthnMfaUtil(config)
+	case PushType:
+		return NewPushMfaUtil(config)
 	}
 
 	return nil
 }
 
+// MfaRecover consumes one of the user's recovery codes, records the usage and notifies the user by email
 func MfaRecover(user *User, recoveryCode string) error {
-	hit := false
-
 	if len(user.RecoveryCodes) == 0 {
 		return fmt.Errorf("do not have recovery codes")
 	}
 
-	for _, code := range user.RecoveryCodes {
-		if code == recoveryCode {
-			hit = true
-			user.RecoveryCodes = util.DeleteVal(user.RecoveryCodes, code)
-			break
-		}
-	}
-	if !hit {
+	if matchRecoveryCode(user.RecoveryCodes, recoveryCode) == -1 {
 		return fmt.Errorf("recovery code not found")
 	}
 
-	_, err := UpdateUser(user.GetId(), user, []string{"recovery_codes"}, false)
+	// the codes are read again under a row lock, so that two concurrent logins can not both spend the same code
+	var oldUser User
+	affected, err := writeWithChangeEvents(func(session *xorm.Session) (int64, error) {
+		existed, err := session.ID(core.PK{user.Owner, user.Name}).ForUpdate().Get(&oldUser)
+		if err != nil || !existed {
+			return 0, err
+		}
+
+		i := matchRecoveryCode(oldUser.RecoveryCodes, recoveryCode)
+		if i == -1 {
+			return 0, nil
+		}
+
+		user.RecoveryCodes = append(oldUser.RecoveryCodes[:i:i], oldUser.RecoveryCodes[i+1:]...)
+		return session.ID(core.PK{user.Owner, user.Name}).And("recovery_codes = ?", util.StructToJson(oldUser.RecoveryCodes)).Cols("recovery_codes").Update(&User{RecoveryCodes: user.RecoveryCodes})
+	}, func(session *xorm.Session) ([]*ChangeEvent, error) {
+		return getUpdateChangeEvents(session, &oldUser, &User{Owner: user.Owner, Name: user.Name})
+	})
 	if err != nil {
 		return err
 	}
+	if affected == 0 {
+		return fmt.Errorf("recovery code not found")
+	}
+
+	addUserActionRecord(user.Owner, user.Name, "use-recovery-code", util.StructToJson(map[string]int{"remainingCount": len(user.RecoveryCodes)}))
+
+	util.SafeGoroutine(func() {
+		err := notifyRecoveryCodeUsed(user)
+		if err != nil {
+			logs.Error(fmt.Sprintf("MfaRecover() error: %s", err.Error()))
+		}
+	})
 
 	return nil
 }
@@ -91,12 +127,22 @@ func MfaRecover(user *User, recoveryCode string) error {
 func GetAllMfaProps(user *User, masked bool) []*MfaProps {
 	mfaProps := []*MfaProps{}
 
-	for _, mfaType := range []string{SmsType, EmailType, TotpType} {
+	for _, mfaType := range []string{SmsType, EmailType, TotpType, WebAuthnType, PushType, RecoveryCodeType} {
 		mfaProps = append(mfaProps, user.GetMfaProps(mfaType, masked))
 	}
 	return mfaProps
 }
 
+// getFallbackMfaType returns the first MFA type still enabled for the user, to be preferred when the preferred one is removed
+func getFallbackMfaType(user *User) string {
+	for _, mfaProps := range GetAllMfaProps(user, true) {
+		if mfaProps.Enabled {
+			return mfaProps.MfaType
+		}
+	}
+	return ""
+}
+
 func (user *User) GetMfaProps(mfaType string, masked bool) *MfaProps {
 	mfaProps := &MfaProps{}
 
@@ -152,6 +198,26 @@ func (user *User) GetMfaProps(mfaType string, masked bool) *MfaProps {
 		} else {
 			mfaProps.Secret = user.TotpSecret
 		}
+	} else if mfaType == WebAuthnType {
+		mfaProps = &MfaProps{
+			Enabled: user.MfaWebauthnEnabled,
+			MfaType: mfaType,
+		}
+	} else if mfaType == PushType {
+		mfaProps = &MfaProps{
+			Enabled: user.MfaPushEnabled,
+			MfaType: mfaType,
+			Secret:  user.MfaPushProvider,
+		}
+	} else if mfaType == RecoveryCodeType {
+		mfaProps = &MfaProps{
+			Enabled:           len(user.RecoveryCodes) != 0,
+			MfaType:           mfaType,
+			RecoveryCodeCount: len(user.RecoveryCodes),
+		}
+		if !masked {
+			mfaProps.RecoveryCodes = user.RecoveryCodes
+		}
 	}
 
 	if user.PreferredMfaType == mfaType {
@@ -165,9 +231,12 @@ func DisabledMultiFactorAuth(user *User) error {
 	user.RecoveryCodes = []string{}
 	user.MfaPhoneEnabled = false
 	user.MfaEmailEnabled = false
+	user.MfaWebauthnEnabled = false
+	user.MfaPushEnabled = false
+	user.MfaPushProvider = ""
 	user.TotpSecret = ""
 
-	_, err := updateUser(user.GetId(), user, []string{"preferred_mfa_type", "recovery_codes", "mfa_phone_enabled", "mfa_email_enabled", "totp_secret"})
+	_, err := updateUser(user.GetId(), user, []string{"preferred_mfa_type", "recovery_codes", "mfa_phone_enabled", "mfa_email_enabled", "mfa_webauthn_enabled", "mfa_push_enabled", "mfa_push_provider", "totp_secret"})
 	if err != nil {
 		return err
 	}
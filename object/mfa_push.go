@@ -0,0 +1,455 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/casdoor/casdoor/conf"
+	"github.com/casdoor/casdoor/util"
+)
+
+const (
+	MfaPushStatusPending  = "Pending"
+	MfaPushStatusApproved = "Approved"
+	MfaPushStatusDenied   = "Denied"
+	MfaPushStatusFraud    = "Fraud"
+	MfaPushStatusExpired  = "Expired"
+)
+
+const (
+	MfaPushActionApprove = "approve"
+	MfaPushActionDeny    = "deny"
+	MfaPushActionFraud   = "fraud"
+)
+
+// MfaPushChallenge is an approve/deny request sent to the user's device.
+// The number is shown on the sign-in screen and has to be typed on the
+// device, so that a blindly approved request is not enough.
+type MfaPushChallenge struct {
+	Id          string `json:"id"`
+	Owner       string `json:"owner"`
+	Name        string `json:"name"`
+	Number      string `json:"number,omitempty"`
+	Status      string `json:"status"`
+	ClientIp    string `json:"clientIp"`
+	UserAgent   string `json:"userAgent"`
+	CreatedTime string `json:"createdTime"`
+	ExpireTime  int64  `json:"expireTime"`
+
+	// Token authorizes the response, it is only sent to the user's device
+	Token string `json:"-"`
+}
+
+type PushMfa struct {
+	*MfaProps
+
+	UserId string
+}
+
+func getMfaPushTimeout() int64 {
+	timeout, err := conf.GetConfigInt64("mfaPushTimeout")
+	if err != nil || timeout <= 0 {
+		return 60
+	}
+	return timeout
+}
+
+// getMfaPushInterval is the minimum time in seconds between two pushes to a user
+func getMfaPushInterval() int64 {
+	interval, err := conf.GetConfigInt64("mfaPushInterval")
+	if err != nil || interval < 0 {
+		return 30
+	}
+	return interval
+}
+
+// getMfaPushLimit is the maximum number of pushes to a user in an hour
+func getMfaPushLimit() int {
+	limit, err := conf.GetConfigInt64("mfaPushLimit")
+	if err != nil || limit <= 0 {
+		return 10
+	}
+	return int(limit)
+}
+
+func getRandomMfaPushNumber() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(90))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d", n.Int64()+10), nil
+}
+
+// GetMasked hides the number, the device has to get it from the sign-in screen
+func (challenge *MfaPushChallenge) GetMasked() *MfaPushChallenge {
+	res := *challenge
+	res.Number = ""
+	return &res
+}
+
+func (challenge *MfaPushChallenge) isExpired() bool {
+	return time.Now().Unix() > challenge.ExpireTime
+}
+
+// The challenges are kept in the ticket store so that all the replicas see them. A challenge is answered once:
+// the responder takes its pending ticket, and the sign-in takes its approved ticket.
+func getMfaPushChallengeKey(id string) string {
+	return "mfa-push:" + id
+}
+
+func getMfaPushPendingKey(id string) string {
+	return "mfa-push-pending:" + id
+}
+
+func getMfaPushApprovedKey(id string) string {
+	return "mfa-push-approved:" + id
+}
+
+func getMfaPushTokenKey(token string) string {
+	return "mfa-push-token:" + token
+}
+
+func getMfaPushUserKey(owner string, name string) string {
+	return "mfa-push-user:" + util.GetId(owner, name)
+}
+
+func getMfaPushThrottleKey(owner string, name string) string {
+	return "mfa-push-throttle:" + util.GetId(owner, name)
+}
+
+// getMfaPushTicketTtl keeps the challenges a timeout longer than their expiration so that the sign-in screen sees them expire
+func getMfaPushTicketTtl() time.Duration {
+	return time.Duration(getMfaPushTimeout()*2) * time.Second
+}
+
+func storeMfaPushChallenge(challenge *MfaPushChallenge) error {
+	return storeTicket(getMfaPushChallengeKey(challenge.Id), challenge, getMfaPushTicketTtl())
+}
+
+// GetMfaPushChallenge returns the challenge with its status updated for the timeout
+func GetMfaPushChallenge(id string) (*MfaPushChallenge, error) {
+	if id == "" {
+		return nil, nil
+	}
+
+	challenge := &MfaPushChallenge{}
+	existed, err := loadTicket(getMfaPushChallengeKey(id), challenge)
+	if err != nil || !existed {
+		return nil, err
+	}
+
+	if challenge.Status == MfaPushStatusPending && challenge.isExpired() {
+		challenge.Status = MfaPushStatusExpired
+	}
+	return challenge, nil
+}
+
+func GetMfaPushChallengeByToken(token string) (*MfaPushChallenge, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	var id string
+	existed, err := loadTicket(getMfaPushTokenKey(token), &id)
+	if err != nil || !existed {
+		return nil, err
+	}
+	return GetMfaPushChallenge(id)
+}
+
+// GetPendingMfaPushChallenges is used by the companion app of the signed-in user to find the request to answer,
+// a new request replaces the previous one
+func GetPendingMfaPushChallenges(owner string, name string) ([]*MfaPushChallenge, error) {
+	res := []*MfaPushChallenge{}
+
+	var id string
+	existed, err := loadTicket(getMfaPushUserKey(owner, name), &id)
+	if err != nil || !existed {
+		return res, err
+	}
+
+	challenge, err := GetMfaPushChallenge(id)
+	if err != nil {
+		return nil, err
+	}
+	if challenge != nil && challenge.Owner == owner && challenge.Name == name && challenge.Status == MfaPushStatusPending {
+		res = append(res, challenge)
+	}
+	return res, nil
+}
+
+// getMfaPushProvider returns the notification provider of the user, which has to belong to the user's organization
+func getMfaPushProvider(user *User) (*Provider, error) {
+	if user.MfaPushProvider == "" {
+		return nil, nil
+	}
+
+	provider, err := GetProvider(user.MfaPushProvider)
+	if err != nil {
+		return nil, err
+	}
+	if provider == nil {
+		return nil, fmt.Errorf("the provider: %s does not exist", user.MfaPushProvider)
+	}
+	if provider.Category != "Notification" {
+		return nil, fmt.Errorf("the provider: %s is not a notification provider", user.MfaPushProvider)
+	}
+	// the global providers are those of the built-in organization
+	if provider.Owner != user.Owner && !(provider.Owner == "admin" && user.Owner == "built-in") {
+		return nil, fmt.Errorf("the provider: %s does not belong to the organization: %s", user.MfaPushProvider, user.Owner)
+	}
+	return provider, nil
+}
+
+// checkMfaPushThrottle counts a push to the user, and fails when it comes too soon after the previous one or
+// when the user has got too many of them in the last hour, so that the user can't be flooded with requests
+func checkMfaPushThrottle(user *User) error {
+	return updateTicket(getMfaPushThrottleKey(user.Owner, user.Name), time.Hour, func(value []byte) ([]byte, error) {
+		sendTimes := []int64{}
+		_, err := unmarshalTicket(value, &sendTimes)
+		if err != nil {
+			return nil, err
+		}
+
+		now := time.Now().Unix()
+		recentSendTimes := []int64{}
+		for _, sendTime := range sendTimes {
+			if sendTime > time.Now().Add(-time.Hour).Unix() {
+				recentSendTimes = append(recentSendTimes, sendTime)
+			}
+		}
+
+		if len(recentSendTimes) != 0 {
+			wait := recentSendTimes[len(recentSendTimes)-1] + getMfaPushInterval() - now
+			if wait > 0 {
+				return nil, fmt.Errorf("please wait %d seconds before sending another push request", wait)
+			}
+		}
+		if len(recentSendTimes) >= getMfaPushLimit() {
+			return nil, errors.New("too many push requests have been sent, please try again later or use another method")
+		}
+
+		return json.Marshal(append(recentSendTimes, now))
+	})
+}
+
+// SendMfaPushChallenge creates a challenge for the user and sends it through the user's notification provider,
+// users without a provider answer the challenge from the companion app
+func SendMfaPushChallenge(user *User, clientIp string, userAgent string, host string) (*MfaPushChallenge, error) {
+	err := checkMfaPushThrottle(user)
+	if err != nil {
+		return nil, err
+	}
+
+	number, err := getRandomMfaPushNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	challenge := &MfaPushChallenge{
+		Id:          util.GenerateId(),
+		Owner:       user.Owner,
+		Name:        user.Name,
+		Number:      number,
+		Status:      MfaPushStatusPending,
+		ClientIp:    clientIp,
+		UserAgent:   userAgent,
+		CreatedTime: util.GetCurrentTime(),
+		ExpireTime:  time.Now().Unix() + getMfaPushTimeout(),
+		Token:       util.GenerateClientSecret(),
+	}
+
+	provider, err := getMfaPushProvider(user)
+	if err != nil {
+		return nil, err
+	}
+
+	if provider != nil {
+		originFrontend, _ := getOriginFromHost(host)
+		content := fmt.Sprintf("Sign-in request for %s from %s (%s). If it was you, open %s/mfa/push/%s within %d seconds and type the number shown on the sign-in screen.",
+			user.GetId(), clientIp, userAgent, originFrontend, challenge.Token, getMfaPushTimeout())
+		err = SendNotification(provider, content)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ttl := getMfaPushTicketTtl()
+	err = storeMfaPushChallenge(challenge)
+	if err != nil {
+		return nil, err
+	}
+	err = storeTicket(getMfaPushPendingKey(challenge.Id), util.GetId(user.Owner, user.Name), time.Duration(getMfaPushTimeout())*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	err = storeTicket(getMfaPushTokenKey(challenge.Token), challenge.Id, ttl)
+	if err != nil {
+		return nil, err
+	}
+	err = storeTicket(getMfaPushUserKey(user.Owner, user.Name), challenge.Id, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	addUserActionRecord(user.Owner, user.Name, "send-mfa-push", util.StructToJson(map[string]string{"clientIp": clientIp, "userAgent": userAgent}))
+	return challenge, nil
+}
+
+// RespondMfaPushChallenge approves, denies or reports the challenge as fraudulent,
+// a fraud report locks the account until an administrator unlocks it
+func RespondMfaPushChallenge(challenge *MfaPushChallenge, action string, number string) error {
+	if action != MfaPushActionApprove && action != MfaPushActionDeny && action != MfaPushActionFraud {
+		return fmt.Errorf("unknown action: %s", action)
+	}
+
+	// only the first response is taken, even when several replicas get one at the same time
+	var userId string
+	existed, err := takeTicket(getMfaPushPendingKey(challenge.Id), &userId)
+	if err != nil {
+		return err
+	}
+	if !existed {
+		if challenge.Status == MfaPushStatusPending {
+			return fmt.Errorf("the request has already been %s", MfaPushStatusExpired)
+		}
+		return fmt.Errorf("the request has already been %s", challenge.Status)
+	}
+
+	switch action {
+	case MfaPushActionApprove:
+		if strings.TrimSpace(number) != challenge.Number {
+			// a wrong number means the user is not looking at the sign-in screen
+			challenge.Status = MfaPushStatusDenied
+			addUserActionRecord(challenge.Owner, challenge.Name, "deny-mfa-push", util.StructToJson(challenge.GetMasked()))
+			err = storeMfaPushChallenge(challenge)
+			if err != nil {
+				return err
+			}
+			return errors.New("the number does not match the sign-in screen, the request has been denied")
+		}
+		challenge.Status = MfaPushStatusApproved
+		addUserActionRecord(challenge.Owner, challenge.Name, "approve-mfa-push", util.StructToJson(challenge.GetMasked()))
+
+		err = storeTicket(getMfaPushApprovedKey(challenge.Id), userId, time.Duration(getMfaPushTimeout())*time.Second)
+		if err != nil {
+			return err
+		}
+	case MfaPushActionDeny:
+		challenge.Status = MfaPushStatusDenied
+		addUserActionRecord(challenge.Owner, challenge.Name, "deny-mfa-push", util.StructToJson(challenge.GetMasked()))
+	case MfaPushActionFraud:
+		challenge.Status = MfaPushStatusFraud
+		addUserActionRecord(challenge.Owner, challenge.Name, "report-mfa-push-fraud", util.StructToJson(challenge.GetMasked()))
+
+		user, err := getUser(challenge.Owner, challenge.Name)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return fmt.Errorf("the user: %s does not exist", util.GetId(challenge.Owner, challenge.Name))
+		}
+
+		user.IsForbidden = true
+		_, err = updateUser(user.GetId(), user, []string{"is_forbidden"})
+		if err != nil {
+			return err
+		}
+	}
+
+	return storeMfaPushChallenge(challenge)
+}
+
+func (mfa *PushMfa) Initiate(userId string) (*MfaProps, error) {
+	mfaProps := MfaProps{
+		MfaType: mfa.MfaType,
+	}
+	return &mfaProps, nil
+}
+
+func (mfa *PushMfa) SetupVerify(passcode string) error {
+	return mfa.Verify(passcode)
+}
+
+func (mfa *PushMfa) Enable(user *User) error {
+	columns := []string{"recovery_codes", "preferred_mfa_type", "mfa_push_enabled", "mfa_push_provider"}
+
+	recoveryCodes, err := hashRecoveryCodes(mfa.RecoveryCodes)
+	if err != nil {
+		return err
+	}
+
+	user.RecoveryCodes = recoveryCodes
+	user.MfaPushEnabled = true
+	user.MfaPushProvider = mfa.Secret
+	_, err = getMfaPushProvider(user)
+	if err != nil {
+		return err
+	}
+	if user.PreferredMfaType == "" {
+		user.PreferredMfaType = mfa.MfaType
+	}
+
+	_, err = updateUser(user.GetId(), user, columns)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Verify checks that the challenge whose id is the passcode has been approved by the user,
+// an approved challenge can only be used once
+func (mfa *PushMfa) Verify(passcode string) error {
+	challenge, err := GetMfaPushChallenge(passcode)
+	if err != nil {
+		return err
+	}
+	if challenge == nil || util.GetId(challenge.Owner, challenge.Name) != mfa.UserId {
+		return errors.New("the push request does not exist")
+	}
+	if challenge.Status != MfaPushStatusApproved {
+		return fmt.Errorf("the push request is %s", challenge.Status)
+	}
+
+	var userId string
+	existed, err := takeTicket(getMfaPushApprovedKey(challenge.Id), &userId)
+	if err != nil {
+		return err
+	}
+	if !existed || userId != mfa.UserId {
+		return errors.New("the push request has already been used")
+	}
+	return nil
+}
+
+func NewPushMfaUtil(config *MfaProps) *PushMfa {
+	if config == nil {
+		config = &MfaProps{
+			MfaType: PushType,
+		}
+	}
+
+	return &PushMfa{
+		MfaProps: config,
+	}
+}
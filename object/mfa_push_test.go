@@ -0,0 +1,109 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckMfaPushThrottle(t *testing.T) {
+	user := &User{Owner: "built-in", Name: "throttled"}
+	if err := checkMfaPushThrottle(user); err != nil {
+		t.Fatal(err)
+	}
+	if checkMfaPushThrottle(user) == nil {
+		t.Fatal("a push right after the previous one should be refused")
+	}
+
+	sendTimes := []int64{}
+	for i := 0; i < getMfaPushLimit(); i++ {
+		sendTimes = append(sendTimes, time.Now().Unix()-getMfaPushInterval()-int64(i+1)*60)
+	}
+	if err := storeTicket(getMfaPushThrottleKey(user.Owner, user.Name), sendTimes, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if checkMfaPushThrottle(user) == nil {
+		t.Fatal("a push over the hourly limit should be refused")
+	}
+
+	oldSendTimes := []int64{}
+	for _, sendTime := range sendTimes {
+		oldSendTimes = append(oldSendTimes, sendTime-3600)
+	}
+	if err := storeTicket(getMfaPushThrottleKey(user.Owner, user.Name), oldSendTimes, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkMfaPushThrottle(user); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPushMfaVerify(t *testing.T) {
+	challenge := &MfaPushChallenge{
+		Id:         "test-challenge",
+		Owner:      "built-in",
+		Name:       "alice",
+		Number:     "42",
+		Status:     MfaPushStatusPending,
+		ExpireTime: time.Now().Unix() + 60,
+	}
+	for key, value := range map[string]interface{}{
+		getMfaPushChallengeKey(challenge.Id): challenge,
+		getMfaPushPendingKey(challenge.Id):   "built-in/alice",
+	} {
+		if err := storeTicket(key, value, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mfa := NewPushMfaUtil(nil)
+	mfa.UserId = "built-in/alice"
+	if mfa.Verify(challenge.Id) == nil {
+		t.Fatal("a pending challenge should not pass")
+	}
+
+	if err := RespondMfaPushChallenge(challenge, MfaPushActionApprove, " 42 "); err != nil {
+		t.Fatal(err)
+	}
+	if RespondMfaPushChallenge(challenge, MfaPushActionDeny, "") == nil {
+		t.Fatal("a challenge should only be answered once")
+	}
+
+	mfa.UserId = "built-in/bob"
+	if mfa.Verify(challenge.Id) == nil {
+		t.Fatal("the challenge of another user should not pass")
+	}
+
+	mfa.UserId = "built-in/alice"
+	if err := mfa.Verify(challenge.Id); err != nil {
+		t.Fatal(err)
+	}
+	if mfa.Verify(challenge.Id) == nil {
+		t.Fatal("an approved challenge should only be used once")
+	}
+
+	expiredChallenge := &MfaPushChallenge{Id: "expired-challenge", Owner: "built-in", Name: "alice", Status: MfaPushStatusPending, ExpireTime: time.Now().Unix() - 1}
+	if err := storeMfaPushChallenge(expiredChallenge); err != nil {
+		t.Fatal(err)
+	}
+	res, err := GetMfaPushChallenge(expiredChallenge.Id)
+	if err != nil || res.Status != MfaPushStatusExpired {
+		t.Fatalf("the challenge should have timed out: %v, %v", res, err)
+	}
+	if RespondMfaPushChallenge(res, MfaPushActionApprove, "") == nil {
+		t.Fatal("an expired challenge should not be answered")
+	}
+}
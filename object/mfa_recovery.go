@@ -0,0 +1,232 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/casdoor/casdoor/conf"
+	"github.com/casdoor/casdoor/util"
+)
+
+const (
+	recoveryCodeCharset    = "abcdefghjkmnpqrstuvwxyz23456789"
+	recoveryCodeHashPrefix = "sha256$"
+)
+
+type RecoveryCodeMfa struct {
+	*MfaProps
+}
+
+func getRecoveryCodeCount() int {
+	count, err := conf.GetConfigInt64("recoveryCodeCount")
+	if err != nil || count <= 0 {
+		return 10
+	}
+	return int(count)
+}
+
+func generateRecoveryCode() (string, error) {
+	bytes := make([]byte, 10)
+	_, err := rand.Read(bytes)
+	if err != nil {
+		return "", err
+	}
+
+	code := make([]byte, len(bytes))
+	for i, b := range bytes {
+		code[i] = recoveryCodeCharset[int(b)%len(recoveryCodeCharset)]
+	}
+	return fmt.Sprintf("%s-%s", code[:5], code[5:]), nil
+}
+
+// GenerateRecoveryCodes returns a new set of plaintext recovery codes, they are only shown to the user once
+func GenerateRecoveryCodes() ([]string, error) {
+	codes := []string{}
+	for i := 0; i < getRecoveryCodeCount(); i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.TrimSpace(code))
+}
+
+func getRecoveryCodeHash(salt string, code string) string {
+	sum := sha256.Sum256([]byte(salt + normalizeRecoveryCode(code)))
+	return hex.EncodeToString(sum[:])
+}
+
+func hashRecoveryCode(code string) (string, error) {
+	saltBytes := make([]byte, 4)
+	_, err := rand.Read(saltBytes)
+	if err != nil {
+		return "", err
+	}
+
+	salt := hex.EncodeToString(saltBytes)
+	return fmt.Sprintf("%s%s$%s", recoveryCodeHashPrefix, salt, getRecoveryCodeHash(salt, code)), nil
+}
+
+func hashRecoveryCodes(codes []string) ([]string, error) {
+	res := []string{}
+	for _, code := range codes {
+		if code == "" {
+			continue
+		}
+
+		hashedCode, err := hashRecoveryCode(code)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, hashedCode)
+	}
+	return res, nil
+}
+
+// matchRecoveryCode returns the index of the stored code that matches the given code, or -1.
+// Codes stored before hashing was introduced are compared in plaintext.
+func matchRecoveryCode(storedCodes []string, code string) int {
+	for i, storedCode := range storedCodes {
+		if !strings.HasPrefix(storedCode, recoveryCodeHashPrefix) {
+			if storedCode != "" && subtle.ConstantTimeCompare([]byte(storedCode), []byte(strings.TrimSpace(code))) == 1 {
+				return i
+			}
+			continue
+		}
+
+		tokens := strings.SplitN(strings.TrimPrefix(storedCode, recoveryCodeHashPrefix), "$", 2)
+		if len(tokens) != 2 {
+			continue
+		}
+
+		if subtle.ConstantTimeCompare([]byte(tokens[1]), []byte(getRecoveryCodeHash(tokens[0], code))) == 1 {
+			return i
+		}
+	}
+	return -1
+}
+
+func (mfa *RecoveryCodeMfa) Initiate(userId string) (*MfaProps, error) {
+	codes, err := GenerateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	mfaProps := MfaProps{
+		MfaType:       mfa.MfaType,
+		RecoveryCodes: codes,
+	}
+	return &mfaProps, nil
+}
+
+func (mfa *RecoveryCodeMfa) SetupVerify(passcode string) error {
+	// the user proves the codes have been saved by typing one of them back
+	if !util.InSlice(mfa.RecoveryCodes, normalizeRecoveryCode(passcode)) {
+		return errors.New("recovery code not found")
+	}
+	return nil
+}
+
+func (mfa *RecoveryCodeMfa) Enable(user *User) error {
+	recoveryCodes, err := hashRecoveryCodes(mfa.RecoveryCodes)
+	if err != nil {
+		return err
+	}
+
+	user.RecoveryCodes = recoveryCodes
+	if user.PreferredMfaType == "" {
+		user.PreferredMfaType = mfa.MfaType
+	}
+
+	_, err = updateUser(user.GetId(), user, []string{"recovery_codes", "preferred_mfa_type"})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (mfa *RecoveryCodeMfa) Verify(passcode string) error {
+	if matchRecoveryCode(mfa.RecoveryCodes, passcode) == -1 {
+		return errors.New("recovery code not found")
+	}
+	return nil
+}
+
+func NewRecoveryCodeMfaUtil(config *MfaProps) *RecoveryCodeMfa {
+	if config == nil {
+		config = &MfaProps{
+			MfaType: RecoveryCodeType,
+		}
+	}
+	return &RecoveryCodeMfa{
+		config,
+	}
+}
+
+// RegenerateRecoveryCodes replaces all the user's recovery codes and returns the new plaintext codes
+func RegenerateRecoveryCodes(user *User) ([]string, error) {
+	codes, err := GenerateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	err = NewRecoveryCodeMfaUtil(&MfaProps{MfaType: RecoveryCodeType, RecoveryCodes: codes}).Enable(user)
+	if err != nil {
+		return nil, err
+	}
+
+	addUserActionRecord(user.Owner, user.Name, "regenerate-recovery-codes", "")
+	return codes, nil
+}
+
+func notifyRecoveryCodeUsed(user *User) error {
+	if user.Email == "" {
+		return nil
+	}
+
+	application, err := GetDefaultApplication(util.GetId("admin", user.Owner))
+	if err != nil {
+		return err
+	}
+	if application == nil {
+		return nil
+	}
+
+	provider, err := application.GetEmailProvider("All")
+	if err != nil {
+		return err
+	}
+	if provider == nil {
+		return nil
+	}
+
+	title := "A recovery code has been used to sign in"
+	content := fmt.Sprintf("Hi %s, a recovery code of your account %s has been used to sign in at %s. You have %d recovery codes left. If this was not you, please contact your administrator immediately.",
+		user.GetFriendlyName(), user.GetId(), util.GetCurrentTime(), len(user.RecoveryCodes))
+	return SendEmail(provider, title, content, user.Email, application.DisplayName)
+}
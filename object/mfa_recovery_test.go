@@ -0,0 +1,134 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMatchRecoveryCode(t *testing.T) {
+	codes, err := GenerateRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != getRecoveryCodeCount() {
+		t.Fatalf("expected %d codes, got %d", getRecoveryCodeCount(), len(codes))
+	}
+
+	hashedCodes, err := hashRecoveryCodes(codes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, hashedCode := range hashedCodes {
+		if strings.Contains(hashedCode, codes[i]) {
+			t.Errorf("the recovery code should not be stored in plaintext: %s", hashedCode)
+		}
+	}
+
+	if i := matchRecoveryCode(hashedCodes, " "+strings.ToUpper(codes[3])+" "); i != 3 {
+		t.Errorf("expected code 3 to match, got %d", i)
+	}
+	if i := matchRecoveryCode(hashedCodes, "aaaaa-aaaaa"); i != -1 {
+		t.Errorf("expected no match, got %d", i)
+	}
+
+	legacyCodes := []string{"0b7c6a0e-6c0b-4a55-9d2f-2c3d0c2a1f00"}
+	if i := matchRecoveryCode(legacyCodes, legacyCodes[0]); i != 0 {
+		t.Errorf("expected the legacy plaintext code to match, got %d", i)
+	}
+}
+
+func TestRecoveryCodeMfaEnable(t *testing.T) {
+	setupTestOrmer(t, &User{}, &ChangeEvent{}, &Syncer{})
+
+	user := &User{Owner: "built-in", Name: "alice"}
+	_, err := ormer.Engine.Insert(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	codes := []string{}
+	for i := 0; i < 30; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		codes = append(codes, code)
+	}
+
+	err = NewRecoveryCodeMfaUtil(&MfaProps{MfaType: RecoveryCodeType, RecoveryCodes: codes}).Enable(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, err = getUser("built-in", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(user.RecoveryCodes) != 30 || user.PreferredMfaType != RecoveryCodeType {
+		t.Fatalf("unexpected user: %d codes, preferred MFA type: %s", len(user.RecoveryCodes), user.PreferredMfaType)
+	}
+}
+
+func TestMfaRecoverOnce(t *testing.T) {
+	setupTestOrmer(t, &User{}, &ChangeEvent{}, &Syncer{})
+
+	codes := []string{}
+	for i := 0; i < 2; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		codes = append(codes, code)
+	}
+	hashedCodes, err := hashRecoveryCodes(codes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ormer.Engine.Insert(&User{Owner: "built-in", Name: "alice", RecoveryCodes: hashedCodes})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// both logins read the user before either of them spends the code
+	user, err := getUser("built-in", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	staleUser, err := getUser("built-in", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = MfaRecover(user, codes[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = MfaRecover(staleUser, codes[0])
+	if err == nil {
+		t.Fatal("expected the spent recovery code to be rejected")
+	}
+
+	user, err = getUser("built-in", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(user.RecoveryCodes) != 1 || matchRecoveryCode(user.RecoveryCodes, codes[1]) != 0 {
+		t.Fatalf("unexpected recovery codes: %v", user.RecoveryCodes)
+	}
+}
@@ -50,7 +50,12 @@ func (mfa *SmsMfa) SetupVerify(passCode string) error {
 func (mfa *SmsMfa) Enable(user *User) error {
 	columns := []string{"recovery_codes", "preferred_mfa_type"}
 
-	user.RecoveryCodes = append(user.RecoveryCodes, mfa.RecoveryCodes...)
+	recoveryCodes, err := hashRecoveryCodes(mfa.RecoveryCodes)
+	if err != nil {
+		return err
+	}
+
+	user.RecoveryCodes = recoveryCodes
 	if user.PreferredMfaType == "" {
 		user.PreferredMfaType = mfa.MfaType
 	}
@@ -64,7 +69,7 @@ func (mfa *SmsMfa) Enable(user *User) error {
 		columns = append(columns, "mfa_email_enabled", "email", "email_verified")
 	}
 
-	_, err := UpdateUser(user.GetId(), user, columns, false)
+	_, err = UpdateUser(user.GetId(), user, columns, false)
 	if err != nil {
 		return err
 	}
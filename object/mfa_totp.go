@@ -80,13 +80,18 @@ func (mfa *TotpMfa) SetupVerify(passcode string) error {
 func (mfa *TotpMfa) Enable(user *User) error {
 	columns := []string{"recovery_codes", "preferred_mfa_type", "totp_secret"}
 
-	user.RecoveryCodes = append(user.RecoveryCodes, mfa.RecoveryCodes...)
+	recoveryCodes, err := hashRecoveryCodes(mfa.RecoveryCodes)
+	if err != nil {
+		return err
+	}
+
+	user.RecoveryCodes = recoveryCodes
 	user.TotpSecret = mfa.Secret
 	if user.PreferredMfaType == "" {
 		user.PreferredMfaType = mfa.MfaType
 	}
 
-	_, err := updateUser(user.GetId(), user, columns)
+	_, err = updateUser(user.GetId(), user, columns)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,110 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// WebAuthnMfa uses one of the user's registered WebAuthn credentials as the second factor.
+// The passcode is the JSON encoded assertion returned by navigator.credentials.get(),
+// the WebAuthn object, the user and the session data of the assertion are set by the caller.
+type WebAuthnMfa struct {
+	*MfaProps
+
+	WebAuthn    *webauthn.WebAuthn
+	User        *User
+	SessionData *webauthn.SessionData
+}
+
+// BeginWebAuthnMfa creates the assertion options restricted to the user's registered credentials
+func BeginWebAuthnMfa(webAuthn *webauthn.WebAuthn, user *User) (*protocol.CredentialAssertion, *webauthn.SessionData, error) {
+	if len(user.WebauthnCredentials) == 0 {
+		return nil, nil, errors.New("the user has no WebAuthn credential")
+	}
+
+	return webAuthn.BeginLogin(user, webauthn.WithUserVerification(protocol.VerificationPreferred))
+}
+
+func (mfa *WebAuthnMfa) Initiate(userId string) (*MfaProps, error) {
+	mfaProps := MfaProps{
+		MfaType: mfa.MfaType,
+	}
+	return &mfaProps, nil
+}
+
+func (mfa *WebAuthnMfa) SetupVerify(passcode string) error {
+	return mfa.Verify(passcode)
+}
+
+func (mfa *WebAuthnMfa) Enable(user *User) error {
+	if len(user.WebauthnCredentials) == 0 {
+		return errors.New("please register a WebAuthn credential first")
+	}
+
+	columns := []string{"recovery_codes", "preferred_mfa_type", "mfa_webauthn_enabled"}
+
+	recoveryCodes, err := hashRecoveryCodes(mfa.RecoveryCodes)
+	if err != nil {
+		return err
+	}
+
+	user.RecoveryCodes = recoveryCodes
+	user.MfaWebauthnEnabled = true
+	if user.PreferredMfaType == "" {
+		user.PreferredMfaType = mfa.MfaType
+	}
+
+	_, err = updateUser(user.GetId(), user, columns)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (mfa *WebAuthnMfa) Verify(passcode string) error {
+	if mfa.WebAuthn == nil || mfa.User == nil || mfa.SessionData == nil {
+		return errors.New("please begin the WebAuthn verification first")
+	}
+
+	parsedResponse, err := protocol.ParseCredentialRequestResponseBody(strings.NewReader(passcode))
+	if err != nil {
+		return err
+	}
+
+	credential, err := mfa.WebAuthn.ValidateLogin(mfa.User, *mfa.SessionData, parsedResponse)
+	if err != nil {
+		return err
+	}
+
+	return mfa.User.UpdateCredentialsUsage(credential)
+}
+
+func NewWebAuthnMfaUtil(config *MfaProps) *WebAuthnMfa {
+	if config == nil {
+		config = &MfaProps{
+			MfaType: WebAuthnType,
+		}
+	}
+
+	return &WebAuthnMfa{
+		MfaProps: config,
+	}
+}
@@ -131,6 +131,7 @@ func UpdateModel(id string, modelObj *Model) (bool, error) {
 		return false, err
 	}
 
+	refreshPermissionEnforcers(permissionEnforcerMessageModel, util.GetId(owner, name))
 	return affected != 0, err
 }
 
@@ -149,6 +150,8 @@ func DeleteModel(model *Model) (bool, error) {
 		return false, err
 	}
 
+	refreshPermissionEnforcers(permissionEnforcerMessageModel, model.GetId())
+
 	return affected != 0, nil
 }
 
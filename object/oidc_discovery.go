@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"net"
 	"strings"
+	"time"
 
 	"github.com/casdoor/casdoor/conf"
 	"gopkg.in/square/go-jose.v2"
@@ -38,6 +39,10 @@ type OidcDiscovery struct {
 	GrantTypesSupported                    []string `json:"grant_types_supported"`
 	SubjectTypesSupported                  []string `json:"subject_types_supported"`
 	IdTokenSigningAlgValuesSupported       []string `json:"id_token_signing_alg_values_supported"`
+	IdTokenEncryptionAlgValuesSupported    []string `json:"id_token_encryption_alg_values_supported"`
+	IdTokenEncryptionEncValuesSupported    []string `json:"id_token_encryption_enc_values_supported"`
+	UserinfoEncryptionAlgValuesSupported   []string `json:"userinfo_encryption_alg_values_supported"`
+	UserinfoEncryptionEncValuesSupported   []string `json:"userinfo_encryption_enc_values_supported"`
 	ScopesSupported                        []string `json:"scopes_supported"`
 	ClaimsSupported                        []string `json:"claims_supported"`
 	RequestParameterSupported              bool     `json:"request_parameter_supported"`
@@ -127,7 +132,11 @@ func GetOidcDiscovery(host string) OidcDiscovery {
 		ResponseModesSupported:                 []string{"query", "fragment", "login", "code", "link"},
 		GrantTypesSupported:                    []string{"password", "authorization_code"},
 		SubjectTypesSupported:                  []string{"public"},
-		IdTokenSigningAlgValuesSupported:       []string{"RS256", "RS512", "ES256", "ES384", "ES512"},
+		IdTokenSigningAlgValuesSupported:       []string{"RS256", "RS512", "ES256", "ES384", "ES512", "EdDSA"},
+		IdTokenEncryptionAlgValuesSupported:    JweAlgValuesSupported,
+		IdTokenEncryptionEncValuesSupported:    JweEncValuesSupported,
+		UserinfoEncryptionAlgValuesSupported:   JweAlgValuesSupported,
+		UserinfoEncryptionEncValuesSupported:   JweEncValuesSupported,
 		ScopesSupported:                        []string{"openid", "email", "profile", "address", "phone", "offline_access"},
 		ClaimsSupported:                        []string{"iss", "ver", "sub", "aud", "iat", "exp", "id", "type", "displayName", "avatar", "permanentAvatar", "email", "phone", "location", "affiliation", "title", "homepage", "bio", "tag", "region", "language", "score", "ranking", "isOnline", "isAdmin", "isForbidden", "signupApplication", "ldap"},
 		RequestParameterSupported:              true,
@@ -138,6 +147,27 @@ func GetOidcDiscovery(host string) OidcDiscovery {
 	return oidcDiscovery
 }
 
+func getJsonWebKey(cert *Cert, keyId string, certificate string) (jose.JSONWebKey, error) {
+	var jwk jose.JSONWebKey
+	certPemBlock := []byte(certificate)
+	certDerBlock, _ := pem.Decode(certPemBlock)
+	if certDerBlock == nil {
+		return jwk, fmt.Errorf("failed to decode the certificate of the key: %s of the cert: %s", keyId, cert.GetId())
+	}
+
+	x509Cert, err := x509.ParseCertificate(certDerBlock.Bytes)
+	if err != nil {
+		return jwk, err
+	}
+
+	jwk.Key = x509Cert.PublicKey
+	jwk.Certificates = []*x509.Certificate{x509Cert}
+	jwk.KeyID = keyId
+	jwk.Algorithm = cert.CryptoAlgorithm
+	jwk.Use = "sig"
+	return jwk, nil
+}
+
 func GetJsonWebKeySet() (jose.JSONWebKeySet, error) {
 	jwks := jose.JSONWebKeySet{}
 	certs, err := GetCerts("")
@@ -157,20 +187,30 @@ func GetJsonWebKeySet() (jose.JSONWebKeySet, error) {
 			return jwks, fmt.Errorf("the certificate field should not be empty for the cert: %v", cert)
 		}
 
-		certPemBlock := []byte(cert.Certificate)
-		certDerBlock, _ := pem.Decode(certPemBlock)
-		x509Cert, err := x509.ParseCertificate(certDerBlock.Bytes)
+		jwk, err := getJsonWebKey(cert, cert.GetKeyId(), cert.Certificate)
 		if err != nil {
 			return jwks, err
 		}
-
-		var jwk jose.JSONWebKey
-		jwk.Key = x509Cert.PublicKey
-		jwk.Certificates = []*x509.Certificate{x509Cert}
-		jwk.KeyID = cert.Name
-		jwk.Algorithm = cert.CryptoAlgorithm
-		jwk.Use = "sig"
 		jwks.Keys = append(jwks.Keys, jwk)
+
+		// the next key is published before the cutover and the retired keys until their tokens expire,
+		// so that relying parties with a cached JWKS can verify tokens across a rotation
+		certKeys, err := GetCertKeys(cert)
+		if err != nil {
+			return jwks, err
+		}
+
+		for _, certKey := range certKeys {
+			if certKey.State == CertKeyStateExpired {
+				continue
+			}
+
+			jwk, err = getJsonWebKey(cert, certKey.Name, certKey.Certificate)
+			if err != nil {
+				return jwks, err
+			}
+			jwks.Keys = append(jwks.Keys, jwk)
+		}
 	}
 
 	return jwks, nil
@@ -223,6 +263,6 @@ func GetDeviceAuthResponse(deviceCode string, userCode string, host string) Devi
 		DeviceCode:      deviceCode,
 		UserCode:        userCode,
 		VerificationUri: fmt.Sprintf("%s/login/oauth/device/%s", originFrontend, userCode),
-		ExpiresIn:       120,
+		ExpiresIn:       int(deviceAuthTtl / time.Second),
 	}
 }
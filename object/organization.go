@@ -24,6 +24,7 @@ import (
 	"github.com/casdoor/casdoor/util"
 	"github.com/xorm-io/builder"
 	"github.com/xorm-io/core"
+	"github.com/xorm-io/xorm"
 )
 
 type AccountItem struct {
@@ -64,6 +65,8 @@ type Organization struct {
 	PasswordObfuscatorType string     `xorm:"varchar(100)" json:"passwordObfuscatorType"`
 	PasswordObfuscatorKey  string     `xorm:"varchar(100)" json:"passwordObfuscatorKey"`
 	PasswordExpireDays     int        `json:"passwordExpireDays"`
+	RecordRetentionDays    int        `json:"recordRetentionDays"`
+	WebauthnAllowedAaguids []string   `xorm:"mediumtext" json:"webauthnAllowedAaguids"`
 	CountryCodes           []string   `xorm:"mediumtext"  json:"countryCodes"`
 	DefaultAvatar          string     `xorm:"varchar(200)" json:"defaultAvatar"`
 	DefaultApplication     string     `xorm:"varchar(100)" json:"defaultApplication"`
@@ -232,19 +235,26 @@ func UpdateOrganization(id string, organization *Organization, isGlobalAdmin boo
 		organization.WidgetItems = org.WidgetItems
 	}
 
-	session := ormer.Engine.ID(core.PK{owner, name}).AllCols()
-
-	if organization.MasterPassword == "***" {
-		session.Omit("master_password")
-	}
-	if organization.DefaultPassword == "***" {
-		session.Omit("default_password")
-	}
-	if organization.MasterVerificationCode == "***" {
-		session.Omit("master_verification_code")
+	oldObject, err := getChangeEventObject(ormer.Engine, org)
+	if err != nil {
+		return false, err
 	}
 
-	affected, err := session.Update(organization)
+	affected, err := updateWithChangeEvents(oldObject, organization, func(session *xorm.Session) (int64, error) {
+		session = session.ID(core.PK{owner, name}).AllCols()
+
+		if organization.MasterPassword == "***" {
+			session.Omit("master_password")
+		}
+		if organization.DefaultPassword == "***" {
+			session.Omit("default_password")
+		}
+		if organization.MasterVerificationCode == "***" {
+			session.Omit("master_verification_code")
+		}
+
+		return session.Update(organization)
+	})
 	if err != nil {
 		return false, err
 	}
@@ -253,7 +263,7 @@ func UpdateOrganization(id string, organization *Organization, isGlobalAdmin boo
 }
 
 func AddOrganization(organization *Organization) (bool, error) {
-	affected, err := ormer.Engine.Insert(organization)
+	affected, err := insertWithChangeEvents(organization)
 	if err != nil {
 		return false, err
 	}
@@ -262,7 +272,12 @@ func AddOrganization(organization *Organization) (bool, error) {
 }
 
 func deleteOrganization(organization *Organization) (bool, error) {
-	affected, err := ormer.Engine.ID(core.PK{organization.Owner, organization.Name}).Delete(&Organization{})
+	oldOrganization, err := getChangeEventObject(ormer.Engine, organization)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := deleteWithChangeEvents(oldOrganization, organization)
 	if err != nil {
 		return false, err
 	}
@@ -392,23 +407,41 @@ func organizationChangeTrigger(oldName string, newName string) error {
 		return err
 	}
 
-	application := new(Application)
-	application.Organization = newName
-	_, err = session.Where("organization=?", oldName).Update(application)
+	// the objects moved to the new organization are renamed, they are paired by their names for their events
+	events, err := getCascadeChangeEvents(session, &[]*Application{}, &[]*Application{}, builder.Eq{"organization": oldName}, builder.Eq{"organization": newName}, getChangeEventId, func() error {
+		application := new(Application)
+		application.Organization = newName
+		_, err := session.Where("organization=?", oldName).Update(application)
+		return err
+	})
 	if err != nil {
 		return err
 	}
 
-	user := new(User)
-	user.Owner = newName
-	_, err = session.Where("owner=?", oldName).Update(user)
+	userEvents, err := getCascadeChangeEvents(session, &[]*User{}, &[]*User{}, builder.Eq{"owner": oldName}, builder.Eq{"owner": newName}, getChangeEventName, func() error {
+		user := new(User)
+		user.Owner = newName
+		_, err := session.Where("owner=?", oldName).Update(user)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	events = append(events, userEvents...)
+
+	groupEvents, err := getCascadeChangeEvents(session, &[]*Group{}, &[]*Group{}, builder.Eq{"owner": oldName}, builder.Eq{"owner": newName}, getChangeEventName, func() error {
+		group := new(Group)
+		group.Owner = newName
+		_, err := session.Where("owner=?", oldName).Update(group)
+		return err
+	})
 	if err != nil {
 		return err
 	}
+	events = append(events, groupEvents...)
 
-	group := new(Group)
-	group.Owner = newName
-	_, err = session.Where("owner=?", oldName).Update(group)
+	oldRoles := []*Role{}
+	err = session.Where("owner=?", oldName).Find(&oldRoles)
 	if err != nil {
 		return err
 	}
@@ -438,6 +471,24 @@ func organizationChangeTrigger(oldName string, newName string) error {
 		return err
 	}
 
+	roles := []*Role{}
+	err = session.Where("owner=?", newName).Find(&roles)
+	if err != nil {
+		return err
+	}
+
+	roleEvents, err := newCascadeChangeEvents(oldRoles, roles, getChangeEventName)
+	if err != nil {
+		return err
+	}
+	events = append(events, roleEvents...)
+
+	oldPermissions := []*Permission{}
+	err = session.Where("owner=?", oldName).Find(&oldPermissions)
+	if err != nil {
+		return err
+	}
+
 	permission := new(Permission)
 	_, err = ormer.Engine.Where("owner=?", oldName).Get(permission)
 	if err != nil {
@@ -463,6 +514,18 @@ func organizationChangeTrigger(oldName string, newName string) error {
 		return err
 	}
 
+	permissions := []*Permission{}
+	err = session.Where("owner=?", newName).Find(&permissions)
+	if err != nil {
+		return err
+	}
+
+	permissionEvents, err := newCascadeChangeEvents(oldPermissions, permissions, getChangeEventName)
+	if err != nil {
+		return err
+	}
+	events = append(events, permissionEvents...)
+
 	adapter := new(Adapter)
 	adapter.Owner = newName
 	_, err = session.Where("owner=?", oldName).Update(adapter)
@@ -529,7 +592,18 @@ func organizationChangeTrigger(oldName string, newName string) error {
 		return err
 	}
 
-	return session.Commit()
+	err = addChangeEvents(session, events)
+	if err != nil {
+		return err
+	}
+
+	err = session.Commit()
+	if err != nil {
+		return err
+	}
+
+	notifyChangeEvents()
+	return nil
 }
 
 func IsNeedPromptMfa(org *Organization, user *User) bool {
@@ -547,6 +621,12 @@ func IsNeedPromptMfa(org *Organization, user *User) bool {
 			if item.Name == TotpType && user.TotpSecret == "" {
 				return true
 			}
+			if item.Name == WebAuthnType && !user.MfaWebauthnEnabled {
+				return true
+			}
+			if item.Name == PushType && !user.MfaPushEnabled {
+				return true
+			}
 		}
 	}
 	return false
@@ -39,26 +39,34 @@ import (
 )
 
 var (
-	ormer          *Ormer = nil
-	createDatabase        = true
-	configPath            = "conf/app.conf"
+	ormer                   *Ormer = nil
+	createDatabase                 = true
+	configPath                     = "conf/app.conf"
+	columnEncryptionCommand        = ""
 )
 
 func InitFlag() {
-	createDatabase = getCreateDatabaseFlag()
-	configPath = getConfigFlag()
+	// all the flags have to be defined before parsing, otherwise the flags defined later are rejected
+	createDatabaseFlag := getCreateDatabaseFlag()
+	configFlag := getConfigFlag()
+	columnEncryptionFlag := getColumnEncryptionFlag()
+	flag.Parse()
+
+	createDatabase = *createDatabaseFlag
+	configPath = *configFlag
+	columnEncryptionCommand = *columnEncryptionFlag
 }
 
-func getCreateDatabaseFlag() bool {
-	res := flag.Bool("createDatabase", false, "true if you need to create database")
-	flag.Parse()
-	return *res
+func getCreateDatabaseFlag() *bool {
+	return flag.Bool("createDatabase", false, "true if you need to create database")
 }
 
-func getConfigFlag() string {
-	res := flag.String("config", "conf/app.conf", "set it to \"/your/path/app.conf\" if your config file is not in: \"/conf/app.conf\"")
-	flag.Parse()
-	return *res
+func getConfigFlag() *string {
+	return flag.String("config", "conf/app.conf", "set it to \"/your/path/app.conf\" if your config file is not in: \"/conf/app.conf\"")
+}
+
+func getColumnEncryptionFlag() *string {
+	return flag.String("columnEncryption", "", "\"migrate\" to encrypt the sensitive columns of the existing rows, \"rekey\" to re-encrypt them with the current columnEncryptionKeyId, then exit")
 }
 
 func InitConfig() {
@@ -279,6 +287,11 @@ func (a *Ormer) openFromDb(db *sql.DB) error {
 	return nil
 }
 
+// PingDatabase checks that the database of Casdoor is reachable
+func PingDatabase() error {
+	return ormer.Engine.Ping()
+}
+
 func (a *Ormer) close() {
 	_ = a.Engine.Close()
 	a.Engine = nil
@@ -328,6 +341,11 @@ func (a *Ormer) createTable() {
 		panic(err)
 	}
 
+	err = a.Engine.Sync2(new(CertKey))
+	if err != nil {
+		panic(err)
+	}
+
 	err = a.Engine.Sync2(new(Role))
 	if err != nil {
 		panic(err)
@@ -403,6 +421,21 @@ func (a *Ormer) createTable() {
 		panic(err)
 	}
 
+	err = a.Engine.Sync2(new(RecordChain))
+	if err != nil {
+		panic(err)
+	}
+
+	err = a.Engine.Sync2(new(RecordChainHead))
+	if err != nil {
+		panic(err)
+	}
+
+	err = a.Engine.Sync2(new(RecordCheckpoint))
+	if err != nil {
+		panic(err)
+	}
+
 	err = a.Engine.Sync2(new(Webhook))
 	if err != nil {
 		panic(err)
@@ -423,6 +456,11 @@ func (a *Ormer) createTable() {
 		panic(err)
 	}
 
+	err = a.Engine.Sync2(new(StoredTicket))
+	if err != nil {
+		panic(err)
+	}
+
 	err = a.Engine.Sync2(new(xormadapter.CasbinRule))
 	if err != nil {
 		panic(err)
@@ -433,4 +471,39 @@ func (a *Ormer) createTable() {
 	if err != nil {
 		panic(err)
 	}
+
+	err = a.Engine.Sync2(new(AccessWorkflow))
+	if err != nil {
+		panic(err)
+	}
+
+	err = a.Engine.Sync2(new(AccessRequest))
+	if err != nil {
+		panic(err)
+	}
+
+	err = a.Engine.Sync2(new(AccessReview))
+	if err != nil {
+		panic(err)
+	}
+
+	err = a.Engine.Sync2(new(AccessReviewItem))
+	if err != nil {
+		panic(err)
+	}
+
+	err = a.Engine.Sync2(new(SyncerRun))
+	if err != nil {
+		panic(err)
+	}
+
+	err = a.Engine.Sync2(new(ChangeEvent))
+	if err != nil {
+		panic(err)
+	}
+
+	err = a.Engine.Sync2(new(JobLease))
+	if err != nil {
+		panic(err)
+	}
 }
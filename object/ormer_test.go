@@ -0,0 +1,42 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/xorm-io/xorm"
+)
+
+// setupTestOrmer points the object layer to a temporary SQLite database with the tables of the given beans
+func setupTestOrmer(t *testing.T, beans ...interface{}) {
+	engine, err := xorm.NewEngine("sqlite", filepath.Join(t.TempDir(), "casdoor.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = engine.Sync2(beans...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previousOrmer := ormer
+	ormer = &Ormer{driverName: "sqlite", Engine: engine}
+	t.Cleanup(func() {
+		ormer = previousOrmer
+		engine.Close()
+	})
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/casdoor/casdoor/conf"
 	"github.com/casdoor/casdoor/util"
 	"github.com/xorm-io/core"
+	"github.com/xorm-io/xorm"
 )
 
 type Permission struct {
@@ -47,6 +48,11 @@ type Permission struct {
 	Approver    string `xorm:"varchar(100)" json:"approver"`
 	ApproveTime string `xorm:"varchar(100)" json:"approveTime"`
 	State       string `xorm:"varchar(100)" json:"state"`
+
+	// Grants are the time windows of the users and roles above, the others are granted with no end
+	Grants []*AccessGrant `xorm:"mediumtext" json:"grants"`
+	// NextGrantTime is when the policies of the grants change next, kept by the hooks of access_grant.go
+	NextGrantTime string `xorm:"varchar(100) index" json:"-"`
 }
 
 const builtInAvailableField = 5 // Casdoor built-in adapter, use V5 to filter permission, so has 5 available field
@@ -102,6 +108,11 @@ func GetPermission(id string) (*Permission, error) {
 
 // checkPermissionValid verifies if the permission is valid
 func checkPermissionValid(permission *Permission) error {
+	err := checkAccessGrants(permission.Grants, permission.Users, permission.Roles)
+	if err != nil {
+		return err
+	}
+
 	enforcer, err := getPermissionEnforcer(permission)
 	if err != nil {
 		return err
@@ -165,7 +176,14 @@ func UpdatePermission(id string, permission *Permission) (bool, error) {
 		}
 	}
 
-	affected, err := ormer.Engine.ID(core.PK{owner, name}).AllCols().Update(permission)
+	oldObject, err := getChangeEventObject(ormer.Engine, oldPermission)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := updateWithChangeEvents(oldObject, permission, func(session *xorm.Session) (int64, error) {
+		return session.ID(core.PK{owner, name}).AllCols().Update(permission)
+	})
 	if err != nil {
 		return false, err
 	}
@@ -206,7 +224,12 @@ func UpdatePermission(id string, permission *Permission) (bool, error) {
 }
 
 func AddPermission(permission *Permission) (bool, error) {
-	affected, err := ormer.Engine.Insert(permission)
+	err := checkAccessGrants(permission.Grants, permission.Users, permission.Roles)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := insertWithChangeEvents(permission)
 	if err != nil {
 		return false, err
 	}
@@ -231,7 +254,7 @@ func AddPermissions(permissions []*Permission) (bool, error) {
 		return false, nil
 	}
 
-	affected, err := ormer.Engine.Insert(permissions)
+	affected, err := insertWithChangeEvents(permissions)
 	if err != nil {
 		if !strings.Contains(err.Error(), "Duplicate entry") {
 			return false, err
@@ -287,7 +310,12 @@ func AddPermissionsInBatch(permissions []*Permission) (bool, error) {
 }
 
 func deletePermission(permission *Permission) (bool, error) {
-	affected, err := ormer.Engine.ID(core.PK{permission.Owner, permission.Name}).Delete(&Permission{})
+	oldPermission, err := getChangeEventObject(ormer.Engine, permission)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := deleteWithChangeEvents(oldPermission, permission)
 	if err != nil {
 		return false, err
 	}
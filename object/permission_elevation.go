@@ -0,0 +1,188 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/casdoor/casdoor/util"
+)
+
+const (
+	PermissionStateApproved = "Approved"
+	PermissionStatePending  = "Pending"
+	PermissionStateRejected = "Rejected"
+)
+
+const maxElevationDuration = 24 * time.Hour
+
+// ElevationRequest asks for the access of a permission for a while. The elevation is a pending copy of the
+// permission for the submitter, which gets the access for the requested duration once approved.
+type ElevationRequest struct {
+	Permission string `json:"permission"`
+	// Duration is in minutes
+	Duration int    `json:"duration"`
+	Reason   string `json:"reason"`
+}
+
+// getElevationGrant returns the grant of the submitter of a just-in-time elevation, nil for the other permissions
+func (p *Permission) getElevationGrant() *AccessGrant {
+	if p.Submitter == "" {
+		return nil
+	}
+	return getAccessGrant(p.Grants, util.GetId(p.Owner, p.Submitter))
+}
+
+func (p *Permission) IsPendingElevation() bool {
+	return p.State == PermissionStatePending && p.getElevationGrant() != nil
+}
+
+// newRequestedPermission copies the target permission for the user, as a pending permission without users named
+// after the target and the kind of request. The user is added once the permission is approved.
+func newRequestedPermission(target *Permission, user *User, reason string, kind string) (*Permission, error) {
+	if !target.IsEnabled || target.State != PermissionStateApproved {
+		return nil, fmt.Errorf("the permission: %s is not enabled and approved", target.GetId())
+	}
+
+	description := []rune(reason)
+	if len(description) > 100 {
+		description = description[:100]
+	}
+
+	name := target.Name
+	if len(name) > 89 {
+		name = name[:89]
+	}
+
+	return &Permission{
+		Owner:        target.Owner,
+		Name:         fmt.Sprintf("%s_%s_%s", name, kind, util.GetRandomName()),
+		CreatedTime:  util.GetCurrentTime(),
+		DisplayName:  target.DisplayName,
+		Description:  string(description),
+		Users:        []string{},
+		Groups:       []string{},
+		Roles:        []string{},
+		Domains:      target.Domains,
+		Model:        target.Model,
+		Adapter:      target.Adapter,
+		ResourceType: target.ResourceType,
+		Resources:    target.Resources,
+		Actions:      target.Actions,
+		Effect:       target.Effect,
+		IsEnabled:    true,
+		Submitter:    user.Name,
+		State:        PermissionStatePending,
+		Grants:       []*AccessGrant{},
+	}, nil
+}
+
+func RequestElevation(user *User, request *ElevationRequest) (*Permission, error) {
+	target, err := GetPermission(request.Permission)
+	if err != nil {
+		return nil, err
+	}
+	if target == nil || target.Owner != user.Owner {
+		return nil, fmt.Errorf("the permission: %s doesn't exist", request.Permission)
+	}
+
+	duration := time.Duration(request.Duration) * time.Minute
+	if duration <= 0 || duration > maxElevationDuration {
+		return nil, fmt.Errorf("the elevation duration should be between 1 and %d minutes", int(maxElevationDuration.Minutes()))
+	}
+
+	permission, err := newRequestedPermission(target, user, request.Reason, "jit")
+	if err != nil {
+		return nil, err
+	}
+
+	// the window is moved to start when the elevation is approved, it only keeps the requested duration meanwhile
+	now := time.Now()
+	permission.Grants = []*AccessGrant{{
+		Member:    user.GetId(),
+		StartTime: util.Time2String(now),
+		EndTime:   util.Time2String(now.Add(duration)),
+	}}
+
+	_, err = AddPermission(permission)
+	if err != nil {
+		return nil, err
+	}
+
+	addUserActionRecord(user.Owner, user.Name, "request-elevation", util.StructToJson(map[string]string{
+		"object":   permission.GetId(),
+		"target":   target.GetId(),
+		"duration": duration.String(),
+		"reason":   request.Reason,
+	}))
+	return permission, nil
+}
+
+func checkElevationApprover(permission *Permission, approver *User) error {
+	if !permission.IsPendingElevation() {
+		return fmt.Errorf("the permission: %s is not a pending elevation", permission.GetId())
+	}
+	if approver.Owner == permission.Owner && approver.Name == permission.Submitter {
+		return fmt.Errorf("the elevation: %s can't be reviewed by its submitter", permission.GetId())
+	}
+	return nil
+}
+
+// ApproveElevation grants the submitter the access of the elevation, for the requested duration from now on
+func ApproveElevation(permission *Permission, approver *User) (bool, error) {
+	err := checkElevationApprover(permission, approver)
+	if err != nil {
+		return false, err
+	}
+
+	grant := permission.getElevationGrant()
+	startTime, endTime := grant.getWindow()
+	now := time.Now()
+	grant.StartTime = util.Time2String(now)
+	grant.EndTime = util.Time2String(now.Add(endTime.Sub(startTime)))
+
+	permission.Users = []string{grant.Member}
+	permission.State = PermissionStateApproved
+	permission.Approver = approver.Name
+	permission.ApproveTime = util.GetCurrentTime()
+
+	affected, err := UpdatePermission(permission.GetId(), permission)
+	if err != nil {
+		return false, err
+	}
+
+	addAccessGrantRecord(permission.Owner, "approve-elevation", permission.GetId(), grant)
+	return affected, nil
+}
+
+func RejectElevation(permission *Permission, approver *User) (bool, error) {
+	err := checkElevationApprover(permission, approver)
+	if err != nil {
+		return false, err
+	}
+
+	permission.State = PermissionStateRejected
+	permission.Approver = approver.Name
+	permission.ApproveTime = util.GetCurrentTime()
+
+	affected, err := UpdatePermission(permission.GetId(), permission)
+	if err != nil {
+		return false, err
+	}
+
+	addAccessGrantRecord(permission.Owner, "reject-elevation", permission.GetId(), permission.getElevationGrant())
+	return affected, nil
+}
@@ -17,6 +17,7 @@ package object
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/casbin/casbin/v2"
 	"github.com/casbin/casbin/v2/config"
@@ -110,16 +111,31 @@ func (p *Permission) setEnforcerModel(enforcer *casbin.Enforcer) error {
 	if err != nil {
 		return err
 	}
+
+	enableAttributeRequests(enforcer)
 	return nil
 }
 
+// getPolicies returns the policies of the users and roles granted the permission at the moment
 func getPolicies(permission *Permission) [][]string {
+	return getPermissionPolicies(permission, true)
+}
+
+// getAllPolicies also returns the policies of the grants not started or already expired, to remove all the saved ones
+func getAllPolicies(permission *Permission) [][]string {
+	return getPermissionPolicies(permission, false)
+}
+
+func getPermissionPolicies(permission *Permission, activeOnly bool) [][]string {
 	var policies [][]string
 
 	permissionId := permission.GetId()
 	domainExist := len(permission.Domains) > 0
 
-	usersAndRoles := append(permission.Users, permission.Roles...)
+	usersAndRoles := append(append([]string{}, permission.Users...), permission.Roles...)
+	if activeOnly {
+		usersAndRoles = getActiveMembers(usersAndRoles, permission.Grants, time.Now())
+	}
 	for _, userOrRole := range usersAndRoles {
 		for _, resource := range permission.Resources {
 			for _, action := range permission.Actions {
@@ -138,6 +154,11 @@ func getPolicies(permission *Permission) [][]string {
 }
 
 func getRolesInRole(roleId string, visited map[string]struct{}) ([]*Role, error) {
+	return getRolesInRoleWithOverrides(roleId, visited, nil)
+}
+
+// getRolesInRoleWithOverrides reads the roles in overrides instead of the saved ones, to dry-run role changes
+func getRolesInRoleWithOverrides(roleId string, visited map[string]struct{}, overrides map[string]*Role) ([]*Role, error) {
 	roleOwner, roleName := util.GetOwnerAndNameFromId(roleId)
 	if roleName == "*" {
 		roles, err := GetRoles(roleOwner)
@@ -145,12 +166,28 @@ func getRolesInRole(roleId string, visited map[string]struct{}) ([]*Role, error)
 			return []*Role{}, err
 		}
 
+		found := map[string]bool{}
+		for i, role := range roles {
+			found[role.GetId()] = true
+			if override, ok := overrides[role.GetId()]; ok {
+				roles[i] = override
+			}
+		}
+		for id, override := range overrides {
+			if !found[id] && override.Owner == roleOwner {
+				roles = append(roles, override)
+			}
+		}
 		return roles, nil
 	}
 
-	role, err := GetRole(roleId)
-	if err != nil {
-		return []*Role{}, err
+	role, ok := overrides[roleId]
+	if !ok {
+		var err error
+		role, err = GetRole(roleId)
+		if err != nil {
+			return []*Role{}, err
+		}
 	}
 
 	if role == nil {
@@ -161,7 +198,7 @@ func getRolesInRole(roleId string, visited map[string]struct{}) ([]*Role, error)
 	roles := []*Role{role}
 	for _, subRole := range role.Roles {
 		if _, ok := visited[subRole]; !ok {
-			r, err := getRolesInRole(subRole, visited)
+			r, err := getRolesInRoleWithOverrides(subRole, visited, overrides)
 			if err != nil {
 				return []*Role{}, err
 			}
@@ -173,9 +210,20 @@ func getRolesInRole(roleId string, visited map[string]struct{}) ([]*Role, error)
 	return roles, nil
 }
 
+// getGroupingPolicies returns the role links of the permission at the moment
 func getGroupingPolicies(permission *Permission) ([][]string, error) {
+	return getGroupingPoliciesWithOverrides(permission, nil, true)
+}
+
+// getAllGroupingPolicies also returns the role links of the grants not started or already expired
+func getAllGroupingPolicies(permission *Permission) ([][]string, error) {
+	return getGroupingPoliciesWithOverrides(permission, nil, false)
+}
+
+func getGroupingPoliciesWithOverrides(permission *Permission, overrides map[string]*Role, activeOnly bool) ([][]string, error) {
 	var groupingPolicies [][]string
 
+	now := time.Now()
 	domainExist := len(permission.Domains) > 0
 	permissionId := permission.GetId()
 
@@ -186,14 +234,20 @@ func getGroupingPolicies(permission *Permission) ([][]string, error) {
 			roleId = util.GetId(permission.Owner, "*")
 		}
 
-		rolesInRole, err := getRolesInRole(roleId, visited)
+		rolesInRole, err := getRolesInRoleWithOverrides(roleId, visited, overrides)
 		if err != nil {
 			return nil, err
 		}
 
 		for _, role := range rolesInRole {
 			roleId = role.GetId()
-			for _, subUser := range role.Users {
+			users, roles := role.Users, role.Roles
+			if activeOnly {
+				users = getActiveMembers(users, role.Grants, now)
+				roles = getActiveMembers(roles, role.Grants, now)
+			}
+
+			for _, subUser := range users {
 				if domainExist {
 					for _, domain := range permission.Domains {
 						groupingPolicies = append(groupingPolicies, []string{subUser, roleId, domain, "", "", permissionId})
@@ -203,7 +257,7 @@ func getGroupingPolicies(permission *Permission) ([][]string, error) {
 				}
 			}
 
-			for _, subRole := range role.Roles {
+			for _, subRole := range roles {
 				if domainExist {
 					for _, domain := range permission.Domains {
 						groupingPolicies = append(groupingPolicies, []string{subRole, roleId, domain, "", "", permissionId})
@@ -225,9 +279,17 @@ func addPolicies(permission *Permission) error {
 	}
 
 	policies := getPolicies(permission)
+	if len(policies) == 0 {
+		return nil
+	}
+
+	saved, err := enforcer.AddPolicies(policies)
+	if err != nil {
+		return err
+	}
 
-	_, err = enforcer.AddPolicies(policies)
-	return err
+	addCachedPolicies(permission, saved, "p", policies)
+	return nil
 }
 
 func removePolicies(permission *Permission) error {
@@ -236,10 +298,18 @@ func removePolicies(permission *Permission) error {
 		return err
 	}
 
-	policies := getPolicies(permission)
+	policies := getAllPolicies(permission)
+	if len(policies) == 0 {
+		return nil
+	}
 
-	_, err = enforcer.RemovePolicies(policies)
-	return err
+	saved, err := enforcer.RemovePolicies(policies)
+	if err != nil {
+		return err
+	}
+
+	removeCachedPolicies(permission, saved, "p", policies)
+	return nil
 }
 
 func addGroupingPolicies(permission *Permission) error {
@@ -254,10 +324,12 @@ func addGroupingPolicies(permission *Permission) error {
 	}
 
 	if len(groupingPolicies) > 0 {
-		_, err = enforcer.AddGroupingPolicies(groupingPolicies)
+		saved, err := enforcer.AddGroupingPolicies(groupingPolicies)
 		if err != nil {
 			return err
 		}
+
+		addCachedPolicies(permission, saved, "g", groupingPolicies)
 	}
 
 	return nil
@@ -269,46 +341,65 @@ func removeGroupingPolicies(permission *Permission) error {
 		return err
 	}
 
-	groupingPolicies, err := getGroupingPolicies(permission)
+	groupingPolicies, err := getAllGroupingPolicies(permission)
 	if err != nil {
 		return err
 	}
 
 	if len(groupingPolicies) > 0 {
-		_, err = enforcer.RemoveGroupingPolicies(groupingPolicies)
+		saved, err := enforcer.RemoveGroupingPolicies(groupingPolicies)
 		if err != nil {
 			return err
 		}
+
+		removeCachedPolicies(permission, saved, "g", groupingPolicies)
 	}
 
 	return nil
 }
 
 func Enforce(permission *Permission, request []string, permissionIds ...string) (bool, error) {
-	enforcer, err := getPermissionEnforcer(permission, permissionIds...)
+	return EnforceWithEnvironment(permission, request, nil, permissionIds...)
+}
+
+// EnforceWithEnvironment passes the environment of the request to the attribute models, whose request ends with an env token
+func EnforceWithEnvironment(permission *Permission, request []string, env *EnforceEnvironment, permissionIds ...string) (bool, error) {
+	enforcer, err := getCachedPermissionEnforcer(permission, permissionIds...)
 	if err != nil {
 		return false, err
 	}
 
-	// type transformation
-	interfaceRequest := util.StringToInterfaceArray(request)
+	requests, err := HydrateEnforceRequests(enforcer.enforcer, [][]string{request}, env)
+	if err != nil {
+		return false, err
+	}
 
-	return enforcer.Enforce(interfaceRequest...)
+	enforcer.mu.RLock()
+	defer enforcer.mu.RUnlock()
+	return enforcer.enforcer.Enforce(requests[0]...)
 }
 
 func BatchEnforce(permission *Permission, requests [][]string, permissionIds ...string) ([]bool, error) {
-	enforcer, err := getPermissionEnforcer(permission, permissionIds...)
+	return BatchEnforceWithEnvironment(permission, requests, nil, permissionIds...)
+}
+
+func BatchEnforceWithEnvironment(permission *Permission, requests [][]string, env *EnforceEnvironment, permissionIds ...string) ([]bool, error) {
+	enforcer, err := getCachedPermissionEnforcer(permission, permissionIds...)
 	if err != nil {
 		return nil, err
 	}
 
-	// type transformation
-	interfaceRequests := util.StringToInterfaceArray2d(requests)
+	interfaceRequests, err := HydrateEnforceRequests(enforcer.enforcer, requests, env)
+	if err != nil {
+		return nil, err
+	}
 
-	return enforcer.BatchEnforce(interfaceRequests)
+	enforcer.mu.RLock()
+	defer enforcer.mu.RUnlock()
+	return enforcer.enforcer.BatchEnforce(interfaceRequests)
 }
 
-func getEnforcers(userId string) ([]*casbin.Enforcer, error) {
+func getEnforcers(userId string) ([]*cachedPermissionEnforcer, error) {
 	permissions, _, err := getPermissionsAndRolesByUser(userId)
 	if err != nil {
 		return nil, err
@@ -329,10 +420,10 @@ func getEnforcers(userId string) ([]*casbin.Enforcer, error) {
 		permissions = append(permissions, permissionsByRole...)
 	}
 
-	var enforcers []*casbin.Enforcer
+	var enforcers []*cachedPermissionEnforcer
 	for _, permission := range permissions {
-		var enforcer *casbin.Enforcer
-		enforcer, err = getPermissionEnforcer(permission)
+		var enforcer *cachedPermissionEnforcer
+		enforcer, err = getCachedPermissionEnforcer(permission)
 		if err != nil {
 			return nil, err
 		}
@@ -350,7 +441,9 @@ func GetAllObjects(userId string) ([]string, error) {
 
 	res := []string{}
 	for _, enforcer := range enforcers {
-		items := enforcer.GetAllObjects()
+		enforcer.mu.RLock()
+		items := enforcer.enforcer.GetAllObjects()
+		enforcer.mu.RUnlock()
 		res = append(res, items...)
 	}
 	return res, nil
@@ -364,7 +457,9 @@ func GetAllActions(userId string) ([]string, error) {
 
 	res := []string{}
 	for _, enforcer := range enforcers {
-		items := enforcer.GetAllActions()
+		enforcer.mu.RLock()
+		items := enforcer.enforcer.GetAllActions()
+		enforcer.mu.RUnlock()
 		res = append(res, items...)
 	}
 	return res, nil
@@ -0,0 +1,237 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casdoor/casdoor/util"
+)
+
+// the request attributes a matcher accesses, like r_sub.Properties.department once the assertion is escaped
+var requestAttributeRegex = regexp.MustCompile(`\br_([A-Za-z_0-9]+)\.[A-Za-z_0-9]`)
+
+// the values are quoted as they are by Casbin, a quote in them would end the string in the matcher
+var enforceValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// EnforceSubject is the user a request subject resolves to. Its fields keep the names of the user fields,
+// so the matchers access them like r.sub.Properties.department or r.sub.Tag. A subject that isn't a user,
+// like a role, only has its id. The groups are separated by commas. The subject is always read from the user store
+// by its id, a request can't pass it as JSON.
+type EnforceSubject struct {
+	Id          string
+	Owner       string
+	Name        string
+	DisplayName string
+	Type        string
+	Tag         string
+	Groups      string
+	Affiliation string
+	Region      string
+	Location    string
+	IsAdmin     bool
+	Properties  map[string]string
+}
+
+// EnforceEnvironment is the context of a request, the matchers access it like r.env.Ip or r.env.Hour
+// when the request definition ends with an env token, like "r = sub, obj, act, env".
+type EnforceEnvironment struct {
+	Ip        string
+	UserAgent string
+	MfaUsed   bool
+	Time      string
+	Hour      int
+	Weekday   string
+}
+
+func NewEnforceEnvironment(ip string, userAgent string, mfaUsed bool, now time.Time) *EnforceEnvironment {
+	return &EnforceEnvironment{
+		Ip:        ip,
+		UserAgent: userAgent,
+		MfaUsed:   mfaUsed,
+		Time:      now.Format(time.RFC3339),
+		Hour:      now.Hour(),
+		Weekday:   now.Weekday().String(),
+	}
+}
+
+func newEnforceSubject(id string, user *User) *EnforceSubject {
+	subject := &EnforceSubject{Id: id, Properties: map[string]string{}}
+	if user == nil {
+		return subject
+	}
+
+	subject.Owner = user.Owner
+	subject.Name = user.Name
+	subject.DisplayName = user.DisplayName
+	subject.Type = user.Type
+	subject.Tag = user.Tag
+	subject.Groups = strings.Join(user.Groups, ",")
+	subject.Affiliation = user.Affiliation
+	subject.Region = user.Region
+	subject.Location = user.Location
+	subject.IsAdmin = user.IsAdmin
+	for key, value := range user.Properties {
+		subject.Properties[key] = value
+	}
+	return subject
+}
+
+func (subject *EnforceSubject) toJson() string {
+	escaped := *subject
+	for _, value := range []*string{&escaped.Id, &escaped.Owner, &escaped.Name, &escaped.DisplayName, &escaped.Type, &escaped.Tag, &escaped.Groups, &escaped.Affiliation, &escaped.Region, &escaped.Location} {
+		*value = enforceValueReplacer.Replace(*value)
+	}
+	escaped.Properties = map[string]string{}
+	for key, value := range subject.Properties {
+		escaped.Properties[key] = enforceValueReplacer.Replace(value)
+	}
+
+	data, _ := json.Marshal(escaped)
+	return string(data)
+}
+
+// getRequestAttributeTokens returns the request tokens whose attributes the matcher accesses, like "sub" for r.sub.Tag
+func getRequestAttributeTokens(m model.Model) map[string]bool {
+	res := map[string]bool{}
+	assertion, ok := m["m"]["m"]
+	if !ok {
+		return res
+	}
+
+	for _, match := range requestAttributeRegex.FindAllStringSubmatch(assertion.Value, -1) {
+		res[match[1]] = true
+	}
+	return res
+}
+
+func isAttributeModel(m model.Model) bool {
+	return len(getRequestAttributeTokens(m)) != 0
+}
+
+// enableAttributeRequests lets the matchers of an attribute model access the JSON request values
+func enableAttributeRequests(enforcer *casbin.Enforcer) {
+	if isAttributeModel(enforcer.GetModel()) {
+		enforcer.EnableAcceptJsonRequest(true)
+	}
+}
+
+// enforceRequestHydrator resolves the subjects and adds the environment to the requests of an attribute model
+type enforceRequestHydrator struct {
+	tokens     []string
+	attributes map[string]bool
+	env        *EnforceEnvironment
+	getUser    func(id string) (*User, error)
+	subjects   map[string]string
+}
+
+func newEnforceRequestHydrator(m model.Model, env *EnforceEnvironment, getUser func(id string) (*User, error)) *enforceRequestHydrator {
+	hydrator := &enforceRequestHydrator{
+		attributes: getRequestAttributeTokens(m),
+		env:        env,
+		getUser:    getUser,
+		subjects:   map[string]string{},
+	}
+	if assertion, ok := m["r"]["r"]; ok {
+		for _, token := range assertion.Tokens {
+			hydrator.tokens = append(hydrator.tokens, strings.TrimPrefix(token, "r_"))
+		}
+	}
+	return hydrator
+}
+
+// isJsonRequestValue tells whether Casbin would read the attributes of a request value as JSON
+func isJsonRequestValue(value string) bool {
+	value = strings.TrimSpace(value)
+	return strings.HasPrefix(value, "{") || strings.HasPrefix(value, "[")
+}
+
+// hydrate returns the request values to enforce. The JSON values of an attribute model are kept as strings,
+// Casbin splices the attributes accessed by the matcher into it, their values are as many as the users and objects.
+// The environment changes with every request and is passed as a struct, whose fields the matcher reads without splicing,
+// as Casbin caches the compiled matchers by their text.
+func (hydrator *enforceRequestHydrator) hydrate(request []string) ([]interface{}, error) {
+	if len(hydrator.attributes) == 0 {
+		return util.StringToInterfaceArray(request), nil
+	}
+
+	res := []interface{}{}
+	for _, value := range request {
+		res = append(res, value)
+	}
+	for i, token := range hydrator.tokens {
+		switch {
+		case token == "env" && i == len(res) && i == len(hydrator.tokens)-1:
+			env := hydrator.env
+			if env == nil {
+				env = NewEnforceEnvironment("", "", false, time.Now())
+			}
+			res = append(res, env)
+		case token == "sub" && i < len(res) && hydrator.attributes[token]:
+			// the attributes of the subject always come from the user store, a caller passing them could claim any
+			if isJsonRequestValue(request[i]) {
+				return nil, fmt.Errorf("the subject of an enforce request should be an id, not JSON: %s", request[i])
+			}
+
+			subject, err := hydrator.getSubject(request[i])
+			if err != nil {
+				return nil, err
+			}
+			res[i] = subject
+		}
+	}
+	return res, nil
+}
+
+func (hydrator *enforceRequestHydrator) getSubject(id string) (string, error) {
+	if subject, ok := hydrator.subjects[id]; ok {
+		return subject, nil
+	}
+
+	var user *User
+	if strings.Count(id, "/") == 1 {
+		var err error
+		user, err = hydrator.getUser(id)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	subject := newEnforceSubject(id, user).toJson()
+	hydrator.subjects[id] = subject
+	return subject, nil
+}
+
+// HydrateEnforceRequests resolves the subjects to users and adds the environment to the requests,
+// when the model of the enforcer accesses the request attributes
+func HydrateEnforceRequests(enforcer *casbin.Enforcer, requests [][]string, env *EnforceEnvironment) ([][]interface{}, error) {
+	hydrator := newEnforceRequestHydrator(enforcer.GetModel(), env, GetUser)
+
+	res := [][]interface{}{}
+	for _, request := range requests {
+		hydrated, err := hydrator.hydrate(request)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, hydrated)
+	}
+	return res, nil
+}
@@ -0,0 +1,130 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+)
+
+func TestEnforceAttributes(t *testing.T) {
+	m, err := model.NewModelFromString(`[request_definition]
+r = sub, obj, act, env
+
+[policy_definition]
+p = sub, act
+
+[role_definition]
+g = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = g(r.sub.Id, p.sub) && r.act == p.act && r.sub.Properties.department == r.obj.owner && r.env.Hour >= 9 && r.env.Hour < 18`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enforcer, err := casbin.NewEnforcer(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enableAttributeRequests(enforcer)
+
+	_, err = enforcer.AddPolicy("role-staff", "read")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = enforcer.AddGroupingPolicies([][]string{{"built-in/alice", "role-staff"}, {"built-in/mallory", "role-staff"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users := map[string]*User{
+		"built-in/alice":   {Owner: "built-in", Name: "alice", Properties: map[string]string{"department": "eng"}},
+		"built-in/mallory": {Owner: "built-in", Name: "mallory", Properties: map[string]string{"department": `x" || "a" == "a`}},
+	}
+	getUser := func(id string) (*User, error) {
+		return users[id], nil
+	}
+
+	workTime := NewEnforceEnvironment("127.0.0.1", "", true, time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC))
+	night := NewEnforceEnvironment("127.0.0.1", "", true, time.Date(2024, 1, 15, 22, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		request []string
+		env     *EnforceEnvironment
+		allowed bool
+	}{
+		{[]string{"built-in/alice", `{"owner": "eng"}`, "read"}, workTime, true},
+		{[]string{"built-in/alice", `{"owner": "sales"}`, "read"}, workTime, false},
+		{[]string{"built-in/alice", `{"owner": "eng"}`, "write"}, workTime, false},
+		{[]string{"built-in/alice", `{"owner": "eng"}`, "read"}, night, false},
+		// a quote in an attribute doesn't change the matcher
+		{[]string{"built-in/mallory", `{"owner": "eng"}`, "read"}, workTime, false},
+		// a subject that isn't a user has no attributes
+		{[]string{"role-staff", `{"owner": "eng"}`, "read"}, workTime, false},
+	}
+	for _, test := range tests {
+		request, err := newEnforceRequestHydrator(enforcer.GetModel(), test.env, getUser).hydrate(test.request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(request) != 4 {
+			t.Fatalf("the environment should be added to the request: %v", request)
+		}
+		// the environment changes with every request, it isn't spliced into the matcher like the JSON values
+		if _, ok := request[3].(*EnforceEnvironment); !ok {
+			t.Fatalf("the environment should be passed as a struct: %v", request[3])
+		}
+
+		allowed, err := enforcer.Enforce(request[0], request[1], request[2], request[3])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if allowed != test.allowed {
+			t.Fatalf("%v at %s: got %v, expected %v", test.request, test.env.Time, allowed, test.allowed)
+		}
+	}
+
+	// the attributes of a subject passed as JSON would be taken as they are, like a department the user isn't in
+	spoofed := []string{`{"Id": "built-in/mallory", "Properties": {"department": "eng"}}`, `{"owner": "eng"}`, "read"}
+	_, err = newEnforceRequestHydrator(enforcer.GetModel(), workTime, getUser).hydrate(spoofed)
+	if err == nil {
+		t.Fatal("a JSON subject should be rejected")
+	}
+}
+
+func TestHydrateEnforceRequestWithoutAttributes(t *testing.T) {
+	m, err := GetBuiltInModel("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isAttributeModel(m) {
+		t.Fatal("the built-in model shouldn't access the request attributes")
+	}
+
+	request := []string{"built-in/alice", "data1", "read"}
+	res, err := newEnforceRequestHydrator(m, nil, nil).hydrate(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 3 || res[0] != "built-in/alice" {
+		t.Fatalf("the request should be kept: %v", res)
+	}
+}
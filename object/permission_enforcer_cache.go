@@ -0,0 +1,264 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/beego/beego/logs"
+	"github.com/casbin/casbin/v2"
+	"github.com/casdoor/casdoor/conf"
+	"github.com/casdoor/casdoor/util"
+)
+
+const (
+	permissionEnforcerCacheSize = 1000
+	// the default seconds a cached enforcer is used before it is loaded again, so that the replicas without a watcher,
+	// or missing one of its messages, still see the changes of the others
+	defaultPermissionEnforcerCacheTtl = 60
+)
+
+// cachedPermissionEnforcer is an enforcer kept in memory with the policies of its permissions.
+// It only changes in memory, the policies are saved to the adapter by the uncached enforcers.
+type cachedPermissionEnforcer struct {
+	mu            sync.RWMutex
+	enforcer      *casbin.Enforcer
+	model         string
+	adapter       string
+	permissionIds []string
+	loadedTime    time.Time
+	lastUsedTime  atomic.Int64
+}
+
+type permissionEnforcerCache struct {
+	mu        sync.Mutex
+	enforcers map[string]*cachedPermissionEnforcer
+	// generation changes with the policies, an enforcer loaded meanwhile may have missed the change
+	generation uint64
+}
+
+var permissionEnforcers = &permissionEnforcerCache{enforcers: map[string]*cachedPermissionEnforcer{}}
+
+// getPermissionEnforcerCacheTtl reads the "enforcerCacheTtl" config in seconds, 0 keeps the enforcers until they are invalidated
+func getPermissionEnforcerCacheTtl() time.Duration {
+	if conf.GetConfigString("enforcerCacheTtl") == "" {
+		return defaultPermissionEnforcerCacheTtl * time.Second
+	}
+
+	ttl, err := conf.GetConfigInt64("enforcerCacheTtl")
+	if err != nil || ttl < 0 {
+		logs.Warning(fmt.Sprintf("getPermissionEnforcerCacheTtl() error: invalid enforcerCacheTtl: %s", conf.GetConfigString("enforcerCacheTtl")))
+		return defaultPermissionEnforcerCacheTtl * time.Second
+	}
+	return time.Duration(ttl) * time.Second
+}
+
+func (entry *cachedPermissionEnforcer) isExpired(ttl time.Duration) bool {
+	return ttl > 0 && time.Since(entry.loadedTime) > ttl
+}
+
+func getPermissionEnforcerIds(p *Permission, permissionIds []string) []string {
+	if len(permissionIds) == 0 {
+		return []string{p.GetId()}
+	}
+
+	ids := append([]string{}, permissionIds...)
+	sort.Strings(ids)
+	return ids
+}
+
+func getPermissionEnforcerCacheKey(modelId string, adapterId string, ids []string) string {
+	return strings.Join([]string{modelId, adapterId, strings.Join(ids, ",")}, "|")
+}
+
+func getCachedPermissionEnforcer(p *Permission, permissionIds ...string) (*cachedPermissionEnforcer, error) {
+	ids := getPermissionEnforcerIds(p, permissionIds)
+	modelId := util.GetId(p.Owner, p.Model)
+	adapterId := util.GetId(p.Owner, p.Adapter)
+	key := getPermissionEnforcerCacheKey(modelId, adapterId, ids)
+
+	cache := permissionEnforcers
+	cache.mu.Lock()
+	entry, ok := cache.enforcers[key]
+	if ok && entry.isExpired(getPermissionEnforcerCacheTtl()) {
+		delete(cache.enforcers, key)
+		ok = false
+	}
+	generation := cache.generation
+	cache.mu.Unlock()
+
+	if ok {
+		EnforcerCacheHits.Inc()
+		entry.lastUsedTime.Store(time.Now().UnixNano())
+		return entry, nil
+	}
+	EnforcerCacheMisses.Inc()
+
+	enforcer, err := getPermissionEnforcer(p, permissionIds...)
+	if err != nil {
+		return nil, err
+	}
+	enforcer.EnableAutoSave(false)
+
+	entry = &cachedPermissionEnforcer{
+		enforcer:      enforcer,
+		model:         modelId,
+		adapter:       adapterId,
+		permissionIds: ids,
+		loadedTime:    time.Now(),
+	}
+	entry.lastUsedTime.Store(time.Now().UnixNano())
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if cache.generation != generation {
+		// the enforcer is still good for this call, but not for the next ones
+		return entry, nil
+	}
+	if existing, ok := cache.enforcers[key]; ok {
+		return existing, nil
+	}
+
+	if len(cache.enforcers) >= permissionEnforcerCacheSize {
+		cache.evictLeastRecentlyUsed()
+	}
+	cache.enforcers[key] = entry
+	EnforcerCacheSize.Set(float64(len(cache.enforcers)))
+	return entry, nil
+}
+
+func (cache *permissionEnforcerCache) evictLeastRecentlyUsed() {
+	oldestKey := ""
+	oldestTime := int64(0)
+	for key, entry := range cache.enforcers {
+		lastUsedTime := entry.lastUsedTime.Load()
+		if oldestKey == "" || lastUsedTime < oldestTime {
+			oldestKey, oldestTime = key, lastUsedTime
+		}
+	}
+	delete(cache.enforcers, oldestKey)
+}
+
+// updateCachedPermissionEnforcers applies a saved policy change of the permission to the cached enforcers
+// having its policies. An enforcer that can't apply it exactly is dropped, to be loaded again when used.
+func updateCachedPermissionEnforcers(permissionId string, update func(enforcer *casbin.Enforcer) (bool, error)) {
+	cache := permissionEnforcers
+	cache.mu.Lock()
+	cache.generation++
+	for key, entry := range cache.enforcers {
+		if !util.InSlice(entry.permissionIds, permissionId) {
+			continue
+		}
+
+		entry.mu.Lock()
+		ok, err := update(entry.enforcer)
+		entry.mu.Unlock()
+		if err != nil || !ok {
+			delete(cache.enforcers, key)
+		}
+	}
+	EnforcerCacheSize.Set(float64(len(cache.enforcers)))
+	cache.mu.Unlock()
+
+	err := notifyPermissionEnforcerWatcher(permissionEnforcerMessagePermission, permissionId)
+	if err != nil {
+		// the other replicas keep the outdated policies until their enforcers expire, this one reloads them too
+		invalidatePermissionEnforcers(permissionEnforcerMessagePermission, permissionId)
+	}
+}
+
+func invalidateCachedPermissionEnforcers(match func(entry *cachedPermissionEnforcer) bool) {
+	cache := permissionEnforcers
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cache.generation++
+	for key, entry := range cache.enforcers {
+		if match(entry) {
+			delete(cache.enforcers, key)
+		}
+	}
+	EnforcerCacheSize.Set(float64(len(cache.enforcers)))
+}
+
+// invalidatePermissionEnforcers drops the cached enforcers of this replica, matching the type and ids of the change
+func invalidatePermissionEnforcers(messageType string, ids ...string) {
+	invalidateCachedPermissionEnforcers(func(entry *cachedPermissionEnforcer) bool {
+		switch messageType {
+		case permissionEnforcerMessagePermission:
+			for _, id := range ids {
+				if util.InSlice(entry.permissionIds, id) {
+					return true
+				}
+			}
+			return false
+		case permissionEnforcerMessageModel:
+			return util.InSlice(ids, entry.model)
+		case permissionEnforcerMessageAdapter:
+			return util.InSlice(ids, entry.adapter)
+		default:
+			return true
+		}
+	})
+}
+
+// refreshPermissionEnforcers drops the cached enforcers on all the replicas, for the changes that can't be applied incrementally
+func refreshPermissionEnforcers(messageType string, ids ...string) {
+	invalidatePermissionEnforcers(messageType, ids...)
+	_ = notifyPermissionEnforcerWatcher(messageType, ids...)
+}
+
+func addCachedPolicies(permission *Permission, saved bool, sec string, rules [][]string) {
+	updateCachedPermissionEnforcers(permission.GetId(), func(enforcer *casbin.Enforcer) (bool, error) {
+		if len(rules) == 0 {
+			return true, nil
+		}
+		if !saved {
+			return false, nil
+		}
+		if sec == "g" && !HasRoleDefinition(enforcer.GetModel()) {
+			return true, nil
+		}
+		return enforcer.SelfAddPoliciesEx(sec, sec, rules)
+	})
+}
+
+func removeCachedPolicies(permission *Permission, saved bool, sec string, rules [][]string) {
+	updateCachedPermissionEnforcers(permission.GetId(), func(enforcer *casbin.Enforcer) (bool, error) {
+		if len(rules) == 0 {
+			return true, nil
+		}
+		if !saved {
+			return false, nil
+		}
+		if sec == "g" && !HasRoleDefinition(enforcer.GetModel()) {
+			return true, nil
+		}
+
+		ok, err := enforcer.SelfRemovePolicies(sec, sec, rules)
+		if err != nil || !ok || sec != "g" {
+			return ok, err
+		}
+
+		// another permission of the enforcer may have the same role link, which has just been deleted
+		return true, enforcer.BuildRoleLinks()
+	})
+}
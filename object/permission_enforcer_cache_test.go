@@ -0,0 +1,123 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casdoor/casdoor/util"
+)
+
+func cacheTestPermissionEnforcer(t *testing.T, permission *Permission) {
+	m, err := GetBuiltInModel("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	enforcer, err := casbin.NewEnforcer(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := []string{permission.GetId()}
+	modelId := util.GetId(permission.Owner, permission.Model)
+	adapterId := util.GetId(permission.Owner, permission.Adapter)
+	permissionEnforcers.enforcers[getPermissionEnforcerCacheKey(modelId, adapterId, ids)] = &cachedPermissionEnforcer{
+		enforcer:      enforcer,
+		model:         modelId,
+		adapter:       adapterId,
+		permissionIds: ids,
+		loadedTime:    time.Now(),
+	}
+}
+
+func TestPermissionEnforcerCache(t *testing.T) {
+	permission := &Permission{
+		Owner:     "built-in",
+		Name:      "permission-cache",
+		Model:     "model-cache",
+		Users:     []string{"built-in/alice"},
+		Resources: []string{"data1"},
+		Actions:   []string{"Read"},
+		Effect:    "Allow",
+	}
+	request := []string{"built-in/alice", "data1", "read"}
+	cacheTestPermissionEnforcer(t, permission)
+
+	allowed, err := Enforce(permission, request)
+	if err != nil || allowed {
+		t.Fatalf("unexpected result before the policies are added: %v, %v", allowed, err)
+	}
+
+	// the saved policies are applied to the cached enforcer, without loading it again
+	addCachedPolicies(permission, true, "p", getPolicies(permission))
+	allowed, err = Enforce(permission, request)
+	if err != nil || !allowed {
+		t.Fatalf("unexpected result after the policies are added: %v, %v", allowed, err)
+	}
+
+	removeCachedPolicies(permission, true, "p", getPolicies(permission))
+	allowed, err = Enforce(permission, request)
+	if err != nil || allowed {
+		t.Fatalf("unexpected result after the policies are removed: %v, %v", allowed, err)
+	}
+
+	// an enforcer that can't apply the change exactly is dropped
+	addCachedPolicies(permission, false, "p", getPolicies(permission))
+	if len(permissionEnforcers.enforcers) != 0 {
+		t.Fatalf("the outdated enforcer should have been dropped: %d", len(permissionEnforcers.enforcers))
+	}
+
+	// the messages of this replica are ignored, the ones of the others invalidate the cache
+	cacheTestPermissionEnforcer(t, permission)
+	message := &permissionEnforcerMessage{Replica: permissionEnforcerReplica, Type: permissionEnforcerMessageModel, Ids: []string{"built-in/model-cache"}}
+	data, err := json.Marshal(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handlePermissionEnforcerMessage(data)
+	if len(permissionEnforcers.enforcers) != 1 {
+		t.Fatal("a message of this replica should be ignored")
+	}
+
+	message.Replica = "another-replica"
+	data, err = json.Marshal(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handlePermissionEnforcerMessage(data)
+	if len(permissionEnforcers.enforcers) != 0 {
+		t.Fatal("a model change of another replica should invalidate the enforcers of the model")
+	}
+}
+
+func TestPermissionEnforcerCacheTtl(t *testing.T) {
+	entry := &cachedPermissionEnforcer{loadedTime: time.Now().Add(-2 * time.Minute)}
+	if !entry.isExpired(getPermissionEnforcerCacheTtl()) {
+		t.Fatal("the enforcer should expire after the default TTL, even without a watcher")
+	}
+
+	t.Setenv("enforcerCacheTtl", "300")
+	if entry.isExpired(getPermissionEnforcerCacheTtl()) {
+		t.Fatal("the enforcer should not expire before the configured TTL")
+	}
+
+	t.Setenv("enforcerCacheTtl", "0")
+	if entry.isExpired(getPermissionEnforcerCacheTtl()) {
+		t.Fatal("the enforcer should be kept until invalidated with a TTL of 0")
+	}
+}
@@ -0,0 +1,399 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"fmt"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/constant"
+	"github.com/casbin/casbin/v2/log"
+	"github.com/casdoor/casdoor/util"
+)
+
+const (
+	maxExplainedRequests = 100
+	maxExplainedPolicies = 200
+	maxExplainedPaths    = 100
+	maxRolePathLength    = 10
+)
+
+const (
+	EffectAllow      = "Allow"
+	EffectDeny       = "Deny"
+	EffectNotMatched = "Not matched"
+)
+
+// EnforceExplainRequest is the body of /api/enforce-explain. With a permission or roles, the requests are
+// evaluated against them as if they were saved, and compared with the decisions of the saved ones.
+type EnforceExplainRequest struct {
+	Requests   [][]string  `json:"requests"`
+	Permission *Permission `json:"permission"`
+	Roles      []*Role     `json:"roles"`
+}
+
+func (r *EnforceExplainRequest) IsDryRun() bool {
+	return r.Permission != nil || len(r.Roles) > 0
+}
+
+type PolicyEvaluation struct {
+	Policy  []string `json:"policy"`
+	Matched bool     `json:"matched"`
+}
+
+type PermissionExplanation struct {
+	Permission      string              `json:"permission"`
+	ModelAndAdapter string              `json:"modelAndAdapter"`
+	Matcher         string              `json:"matcher"`
+	PolicyEffect    string              `json:"policyEffect"`
+	Allowed         bool                `json:"allowed"`
+	Effect          string              `json:"effect"`
+	MatchedPolicies [][]string          `json:"matchedPolicies"`
+	Policies        []*PolicyEvaluation `json:"policies"`
+	PolicyCount     int                 `json:"policyCount"`
+	RolePaths       [][]string          `json:"rolePaths"`
+
+	// SavedAllowed is the decision of the saved permission and roles in a dry run, nil for a new permission
+	SavedAllowed *bool `json:"savedAllowed,omitempty"`
+	Changed      bool  `json:"changed"`
+}
+
+type EnforceExplanation struct {
+	Request     []string                 `json:"request"`
+	DryRun      bool                     `json:"dryRun"`
+	Permissions []*PermissionExplanation `json:"permissions"`
+}
+
+// evaluatePolicies tells for each policy whether the matcher holds with it, whatever its effect
+func evaluatePolicies(enforcer *casbin.Enforcer, request []interface{}) ([]*PolicyEvaluation, int, error) {
+	policies := enforcer.GetPolicy()
+
+	m := enforcer.GetModel().Copy()
+	m["p"]["p"].Policy = nil
+	m["p"]["p"].PolicyMap = map[string]int{}
+	m["e"]["e"].Value = constant.AllowOverrideEffect
+
+	eftIndex := -1
+	for i, token := range m["p"]["p"].Tokens {
+		if token == "p_eft" {
+			eftIndex = i
+		}
+	}
+
+	single, err := casbin.NewEnforcer(m)
+	if err != nil {
+		return nil, 0, err
+	}
+	enableAttributeRequests(single)
+	if HasRoleDefinition(m) {
+		err = single.BuildRoleLinks()
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	evaluations := []*PolicyEvaluation{}
+	for i, policy := range policies {
+		if i >= maxExplainedPolicies {
+			break
+		}
+
+		rule := append([]string{}, policy...)
+		if eftIndex >= 0 && eftIndex < len(rule) {
+			rule[eftIndex] = "allow"
+		}
+
+		_, err = single.SelfAddPolicy("p", "p", rule)
+		if err != nil {
+			return nil, 0, err
+		}
+		matched, err := single.Enforce(request...)
+		if err != nil {
+			return nil, 0, err
+		}
+		_, err = single.SelfRemovePolicy("p", "p", rule)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		evaluations = append(evaluations, &PolicyEvaluation{Policy: policy, Matched: matched})
+	}
+	return evaluations, len(policies), nil
+}
+
+// getRolePaths lists the role inheritance paths of the subject, from the grouping policies of the enforcer
+func getRolePaths(enforcer *casbin.Enforcer, subject string) [][]string {
+	res := [][]string{}
+	if !HasRoleDefinition(enforcer.GetModel()) {
+		return res
+	}
+
+	parents := map[string][]string{}
+	for _, rule := range enforcer.GetGroupingPolicy() {
+		if len(rule) < 2 || util.InSlice(parents[rule[0]], rule[1]) {
+			continue
+		}
+		parents[rule[0]] = append(parents[rule[0]], rule[1])
+	}
+
+	queue := [][]string{{subject}}
+	for len(queue) > 0 && len(res) < maxExplainedPaths {
+		path := queue[0]
+		queue = queue[1:]
+
+		for _, parent := range parents[path[len(path)-1]] {
+			if util.InSlice(path, parent) {
+				continue
+			}
+
+			newPath := append(append([]string{}, path...), parent)
+			res = append(res, newPath)
+			if len(newPath) < maxRolePathLength {
+				queue = append(queue, newPath)
+			}
+		}
+	}
+	return res
+}
+
+func explainEnforcer(enforcer *casbin.Enforcer, request []string) (*PermissionExplanation, error) {
+	hydrated, err := HydrateEnforceRequests(enforcer, [][]string{request}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	interfaceRequest := hydrated[0]
+	allowed, explain, err := enforcer.EnforceEx(interfaceRequest...)
+	if err != nil {
+		return nil, err
+	}
+
+	m := enforcer.GetModel()
+	res := &PermissionExplanation{
+		Matcher:         m["m"]["m"].Value,
+		PolicyEffect:    m["e"]["e"].Value,
+		Allowed:         allowed,
+		Effect:          EffectNotMatched,
+		MatchedPolicies: [][]string{},
+	}
+	if len(explain) > 0 {
+		res.MatchedPolicies = append(res.MatchedPolicies, explain)
+	}
+	if allowed {
+		res.Effect = EffectAllow
+	} else if len(explain) > 0 {
+		res.Effect = EffectDeny
+	}
+
+	res.Policies, res.PolicyCount, err = evaluatePolicies(enforcer, interfaceRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(request) > 0 {
+		res.RolePaths = getRolePaths(enforcer, request[0])
+	}
+	return res, nil
+}
+
+func explainPermission(permission *Permission, request []string) (*PermissionExplanation, error) {
+	enforcer, err := getCachedPermissionEnforcer(permission)
+	if err != nil {
+		return nil, err
+	}
+
+	enforcer.mu.RLock()
+	defer enforcer.mu.RUnlock()
+
+	res, err := explainEnforcer(enforcer.enforcer, request)
+	if err != nil {
+		return nil, err
+	}
+
+	res.Permission = permission.GetId()
+	res.ModelAndAdapter = permission.GetModelAndAdapter()
+	return res, nil
+}
+
+func checkExplainedRequests(requests [][]string) error {
+	if len(requests) == 0 {
+		return fmt.Errorf("the requests should not be empty")
+	}
+	if len(requests) > maxExplainedRequests {
+		return fmt.Errorf("at most %d requests can be explained at once", maxExplainedRequests)
+	}
+	return nil
+}
+
+// ExplainEnforce explains how each of the saved permissions decides each request
+func ExplainEnforce(permissions []*Permission, requests [][]string) ([]*EnforceExplanation, error) {
+	err := checkExplainedRequests(requests)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []*EnforceExplanation{}
+	for _, request := range requests {
+		explanation := &EnforceExplanation{Request: request, Permissions: []*PermissionExplanation{}}
+		for _, permission := range permissions {
+			permissionExplanation, err := explainPermission(permission, request)
+			if err != nil {
+				return nil, err
+			}
+			explanation.Permissions = append(explanation.Permissions, permissionExplanation)
+		}
+		res = append(res, explanation)
+	}
+	return res, nil
+}
+
+// getDryRunPermissionEnforcer builds an enforcer in memory with the policies of the permission,
+// reading the roles in overrides instead of the saved ones
+func getDryRunPermissionEnforcer(permission *Permission, overrides map[string]*Role) (*casbin.Enforcer, error) {
+	enforcer, err := casbin.NewEnforcer(&log.DefaultLogger{}, false)
+	if err != nil {
+		return nil, err
+	}
+
+	err = permission.setEnforcerModel(enforcer)
+	if err != nil {
+		return nil, err
+	}
+
+	policies := getPolicies(permission)
+	if len(policies) > 0 {
+		_, err = enforcer.AddPolicies(policies)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !HasRoleDefinition(enforcer.GetModel()) {
+		return enforcer, nil
+	}
+
+	groupingPolicies, err := getGroupingPoliciesWithOverrides(permission, overrides, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(groupingPolicies) > 0 {
+		_, err = enforcer.AddGroupingPolicies(groupingPolicies)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return enforcer, nil
+}
+
+// getDryRunPermissions returns the proposed permission, or the saved permissions depending on the proposed roles
+func getDryRunPermissions(r *EnforceExplainRequest) ([]*Permission, error) {
+	if r.Permission != nil {
+		return []*Permission{r.Permission}, nil
+	}
+
+	roleIds := []string{}
+	for _, role := range r.Roles {
+		roleIds = append(roleIds, role.GetId())
+	}
+
+	ancestorRoles, err := GetAncestorRoles(roleIds...)
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range ancestorRoles {
+		if !util.InSlice(roleIds, role.GetId()) {
+			roleIds = append(roleIds, role.GetId())
+		}
+	}
+
+	res := []*Permission{}
+	visited := map[string]bool{}
+	for _, roleId := range roleIds {
+		permissions, err := GetPermissionsByRole(roleId)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, permission := range permissions {
+			if !visited[permission.GetId()] {
+				visited[permission.GetId()] = true
+				res = append(res, permission)
+			}
+		}
+	}
+	return res, nil
+}
+
+// DryRunEnforce evaluates the requests against a proposed permission or role change before it is saved
+func DryRunEnforce(r *EnforceExplainRequest) ([]*EnforceExplanation, error) {
+	err := checkExplainedRequests(r.Requests)
+	if err != nil {
+		return nil, err
+	}
+
+	overrides := map[string]*Role{}
+	for _, role := range r.Roles {
+		overrides[role.GetId()] = role
+	}
+
+	permissions, err := getDryRunPermissions(r)
+	if err != nil {
+		return nil, err
+	}
+
+	enforcers := []*casbin.Enforcer{}
+	savedPermissions := []*Permission{}
+	for _, permission := range permissions {
+		enforcer, err := getDryRunPermissionEnforcer(permission, overrides)
+		if err != nil {
+			return nil, err
+		}
+		enforcers = append(enforcers, enforcer)
+
+		savedPermission, err := GetPermission(permission.GetId())
+		if err != nil {
+			return nil, err
+		}
+		savedPermissions = append(savedPermissions, savedPermission)
+	}
+
+	res := []*EnforceExplanation{}
+	for _, request := range r.Requests {
+		explanation := &EnforceExplanation{Request: request, DryRun: true, Permissions: []*PermissionExplanation{}}
+		for i, permission := range permissions {
+			permissionExplanation, err := explainEnforcer(enforcers[i], request)
+			if err != nil {
+				return nil, err
+			}
+			permissionExplanation.Permission = permission.GetId()
+			permissionExplanation.ModelAndAdapter = permission.GetModelAndAdapter()
+
+			if savedPermissions[i] != nil {
+				savedAllowed, err := Enforce(savedPermissions[i], request)
+				if err != nil {
+					return nil, err
+				}
+				permissionExplanation.SavedAllowed = &savedAllowed
+				permissionExplanation.Changed = savedAllowed != permissionExplanation.Allowed
+			} else {
+				permissionExplanation.Changed = permissionExplanation.Allowed
+			}
+
+			explanation.Permissions = append(explanation.Permissions, permissionExplanation)
+		}
+		res = append(res, explanation)
+	}
+	return res, nil
+}
@@ -0,0 +1,94 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+)
+
+func TestExplainEnforcer(t *testing.T) {
+	m, err := model.NewModelFromString(`[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act, eft
+
+[role_definition]
+g = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow)) && !some(where (p.eft == deny))
+
+[matchers]
+m = g(r.sub, p.sub) && r.obj == p.obj && r.act == p.act`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enforcer, err := casbin.NewEnforcer(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = enforcer.AddPolicies([][]string{
+		{"role-super", "data1", "read", "allow"},
+		{"alice", "data1", "read", "deny"},
+		{"bob", "data2", "write", "allow"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = enforcer.AddGroupingPolicies([][]string{{"alice", "role-admin"}, {"role-admin", "role-super"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	explanation, err := explainEnforcer(enforcer, []string{"alice", "data1", "read"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if explanation.Allowed || explanation.Effect != EffectDeny {
+		t.Fatalf("unexpected decision: %v, %s", explanation.Allowed, explanation.Effect)
+	}
+	if !reflect.DeepEqual(explanation.MatchedPolicies, [][]string{{"alice", "data1", "read", "deny"}}) {
+		t.Fatalf("unexpected matched policies: %v", explanation.MatchedPolicies)
+	}
+
+	// the matcher holds with the inherited allow policy and the deny one, whatever their effects
+	matched := []bool{}
+	for _, policy := range explanation.Policies {
+		matched = append(matched, policy.Matched)
+	}
+	if !reflect.DeepEqual(matched, []bool{true, true, false}) || explanation.PolicyCount != 3 {
+		t.Fatalf("unexpected policy evaluations: %v", matched)
+	}
+
+	rolePaths := [][]string{{"alice", "role-admin"}, {"alice", "role-admin", "role-super"}}
+	if !reflect.DeepEqual(explanation.RolePaths, rolePaths) {
+		t.Fatalf("unexpected role paths: %v", explanation.RolePaths)
+	}
+
+	explanation, err = explainEnforcer(enforcer, []string{"carol", "data1", "read"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if explanation.Allowed || explanation.Effect != EffectNotMatched || len(explanation.RolePaths) != 0 {
+		t.Fatalf("unexpected explanation for a subject without policies: %s, %v", explanation.Effect, explanation.RolePaths)
+	}
+}
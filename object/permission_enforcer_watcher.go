@@ -0,0 +1,157 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/beego/beego/logs"
+	"github.com/casdoor/casdoor/conf"
+	"github.com/casdoor/casdoor/util"
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	EnforcerWatcherNone  = "None"
+	EnforcerWatcherRedis = "Redis"
+
+	permissionEnforcerChannel = "casdoor_permission_enforcer"
+)
+
+const (
+	permissionEnforcerMessagePermission = "permission"
+	permissionEnforcerMessageModel      = "model"
+	permissionEnforcerMessageAdapter    = "adapter"
+	permissionEnforcerMessageAll        = "all"
+)
+
+// permissionEnforcerMessage tells the other replicas which of their cached enforcers are outdated
+type permissionEnforcerMessage struct {
+	Replica string   `json:"replica"`
+	Type    string   `json:"type"`
+	Ids     []string `json:"ids"`
+}
+
+var (
+	permissionEnforcerReplica     = util.GenerateId()
+	permissionEnforcerWatcherPool *redis.Pool
+	permissionEnforcerWatcherOnce sync.Once
+)
+
+// getEnforcerWatcherName reads the "enforcerWatcher" config, the replicas sharing a Redis for the sessions watch it by default
+func getEnforcerWatcherName() string {
+	name := conf.GetConfigString("enforcerWatcher")
+	if name != "" {
+		return name
+	}
+
+	if conf.GetConfigString("redisEndpoint") != "" {
+		return EnforcerWatcherRedis
+	}
+	return EnforcerWatcherNone
+}
+
+func getPermissionEnforcerWatcherPool() *redis.Pool {
+	permissionEnforcerWatcherOnce.Do(func() {
+		endpoint := conf.GetConfigString("redisEndpoint")
+		if getEnforcerWatcherName() == EnforcerWatcherRedis && endpoint != "" {
+			permissionEnforcerWatcherPool = newRedisPool(endpoint)
+		}
+	})
+	return permissionEnforcerWatcherPool
+}
+
+// notifyPermissionEnforcerWatcher tells the other replicas about a change, an error means they may have missed it
+func notifyPermissionEnforcerWatcher(messageType string, ids ...string) error {
+	pool := getPermissionEnforcerWatcherPool()
+	if pool == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(&permissionEnforcerMessage{
+		Replica: permissionEnforcerReplica,
+		Type:    messageType,
+		Ids:     ids,
+	})
+	if err != nil {
+		logs.Error(fmt.Sprintf("notifyPermissionEnforcerWatcher() error: %s", err.Error()))
+		return err
+	}
+
+	c := pool.Get()
+	defer c.Close()
+
+	// a replica missing the message serves outdated policies until its enforcers expire, but the change itself is saved
+	_, err = c.Do("PUBLISH", permissionEnforcerChannel, data)
+	if err != nil {
+		logs.Error(fmt.Sprintf("notifyPermissionEnforcerWatcher() error: %s", err.Error()))
+	}
+	return err
+}
+
+func handlePermissionEnforcerMessage(data []byte) {
+	var message permissionEnforcerMessage
+	err := json.Unmarshal(data, &message)
+	if err != nil {
+		logs.Warning(fmt.Sprintf("handlePermissionEnforcerMessage() error: %s", err.Error()))
+		return
+	}
+
+	if message.Replica == permissionEnforcerReplica {
+		return
+	}
+	invalidatePermissionEnforcers(message.Type, message.Ids...)
+}
+
+func watchPermissionEnforcers(pool *redis.Pool) error {
+	c := redis.PubSubConn{Conn: pool.Get()}
+	defer c.Close()
+
+	err := c.Subscribe(permissionEnforcerChannel)
+	if err != nil {
+		return err
+	}
+
+	for {
+		switch v := c.Receive().(type) {
+		case redis.Message:
+			handlePermissionEnforcerMessage(v.Data)
+		case redis.Subscription:
+			// the messages sent while not subscribed are lost
+			invalidatePermissionEnforcers(permissionEnforcerMessageAll)
+		case error:
+			return v
+		}
+	}
+}
+
+// RunPermissionEnforcerWatcher keeps the cached permission enforcers of this replica in sync with the changes made by the others
+func RunPermissionEnforcerWatcher() {
+	pool := getPermissionEnforcerWatcherPool()
+	if pool == nil {
+		return
+	}
+
+	for {
+		err := watchPermissionEnforcers(pool)
+		if err != nil {
+			logs.Error(fmt.Sprintf("RunPermissionEnforcerWatcher() error: %s", err.Error()))
+		}
+		time.Sleep(time.Second * 5)
+	}
+}
@@ -0,0 +1,552 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/casdoor/casdoor/util"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	PolicyChangeAdd    = "Add"
+	PolicyChangeUpdate = "Update"
+	PolicyChangeDelete = "Delete"
+)
+
+// PolicySpec is the definition of a model, adapter, enforcer, group, role or permission, with the keys of its JSON fields
+type PolicySpec map[string]interface{}
+
+// PolicyBundle is the declarative definition of the authorization of an organization, kept as YAML in Git.
+// Only the kinds listed in a bundle are reconciled, the objects of these kinds missing from the bundle are deleted.
+type PolicyBundle struct {
+	Organization string       `yaml:"organization,omitempty"`
+	Models       []PolicySpec `yaml:"models"`
+	Adapters     []PolicySpec `yaml:"adapters"`
+	Enforcers    []PolicySpec `yaml:"enforcers"`
+	Groups       []PolicySpec `yaml:"groups"`
+	Roles        []PolicySpec `yaml:"roles"`
+	Permissions  []PolicySpec `yaml:"permissions"`
+}
+
+type PolicyFieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+type PolicyChange struct {
+	Action string               `json:"action"`
+	Kind   string               `json:"kind"`
+	Name   string               `json:"name"`
+	Fields []*PolicyFieldChange `json:"fields"`
+
+	kind *policyKind
+	spec PolicySpec
+}
+
+// PolicyPlan lists the changes to reconcile an organization with a bundle, in the order they are applied
+type PolicyPlan struct {
+	Organization string          `json:"organization"`
+	Changes      []*PolicyChange `json:"changes"`
+	Applied      bool            `json:"applied"`
+}
+
+// policyKind reads and writes the objects of a kind as specs, the ignored fields are neither exported nor compared:
+// they are set by Casdoor, or are secrets like the adapter passwords, which are kept when an object is updated
+type policyKind struct {
+	name          string
+	ignoredFields []string
+	list          func(owner string) ([]PolicySpec, error)
+	normalize     func(spec PolicySpec) (PolicySpec, error)
+	add           func(spec PolicySpec) error
+	update        func(id string, spec PolicySpec) error
+	remove        func(spec PolicySpec) error
+	getSpecs      func(bundle *PolicyBundle) *[]PolicySpec
+}
+
+var policyIgnoredFields = []string{"owner", "createdTime", "updatedTime"}
+
+// policyKinds are in the order the objects are added, the objects are deleted in the reverse order
+var policyKinds = []*policyKind{
+	newPolicyKind("Model", nil, GetModels, AddModel, UpdateModel, DeleteModel,
+		func(bundle *PolicyBundle) *[]PolicySpec { return &bundle.Models }),
+	newPolicyKind("Adapter", []string{"password"}, GetAdapters, AddAdapter, UpdateAdapter, DeleteAdapter,
+		func(bundle *PolicyBundle) *[]PolicySpec { return &bundle.Adapters }),
+	newPolicyKind("Enforcer", []string{"modelCfg"}, GetEnforcers, AddEnforcer, UpdateEnforcer, DeleteEnforcer,
+		func(bundle *PolicyBundle) *[]PolicySpec { return &bundle.Enforcers }),
+	newPolicyKind("Group", []string{"parentName", "users", "title", "key", "haveChildren", "children"}, GetGroups, AddGroup, UpdateGroup, DeleteGroup,
+		func(bundle *PolicyBundle) *[]PolicySpec { return &bundle.Groups }),
+	newPolicyKind("Role", nil, GetRoles, AddRole, UpdateRole, DeleteRole,
+		func(bundle *PolicyBundle) *[]PolicySpec { return &bundle.Roles }),
+	newPolicyKind("Permission", []string{"submitter", "approver", "approveTime"}, GetPermissions, AddPermission, UpdatePermission, DeletePermission,
+		func(bundle *PolicyBundle) *[]PolicySpec { return &bundle.Permissions }),
+}
+
+func newPolicyKind[T any](name string, ignoredFields []string, list func(owner string) ([]*T, error), add func(obj *T) (bool, error), update func(id string, obj *T) (bool, error), remove func(obj *T) (bool, error), getSpecs func(bundle *PolicyBundle) *[]PolicySpec) *policyKind {
+	return &policyKind{
+		name:          name,
+		ignoredFields: append(append([]string{}, policyIgnoredFields...), ignoredFields...),
+		list: func(owner string) ([]PolicySpec, error) {
+			objs, err := list(owner)
+			if err != nil {
+				return nil, err
+			}
+
+			res := []PolicySpec{}
+			for _, obj := range objs {
+				spec, err := toPolicySpec(obj)
+				if err != nil {
+					return nil, err
+				}
+				res = append(res, spec)
+			}
+			return res, nil
+		},
+		normalize: func(spec PolicySpec) (PolicySpec, error) {
+			obj, err := fromPolicySpec[T](spec)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", name, spec.getName(), err)
+			}
+			return toPolicySpec(obj)
+		},
+		add: func(spec PolicySpec) error {
+			obj, err := fromPolicySpec[T](spec)
+			if err != nil {
+				return err
+			}
+			_, err = add(obj)
+			return err
+		},
+		update: func(id string, spec PolicySpec) error {
+			obj, err := fromPolicySpec[T](spec)
+			if err != nil {
+				return err
+			}
+			_, err = update(id, obj)
+			return err
+		},
+		remove: func(spec PolicySpec) error {
+			obj, err := fromPolicySpec[T](spec)
+			if err != nil {
+				return err
+			}
+			_, err = remove(obj)
+			return err
+		},
+		getSpecs: getSpecs,
+	}
+}
+
+func toPolicySpec(obj interface{}) (PolicySpec, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	spec := PolicySpec{}
+	err = json.Unmarshal(data, &spec)
+	if err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+func fromPolicySpec[T any](spec PolicySpec) (*T, error) {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var obj T
+	err = decoder.Decode(&obj)
+	if err != nil {
+		return nil, err
+	}
+	return &obj, nil
+}
+
+func (spec PolicySpec) getName() string {
+	name, _ := spec["name"].(string)
+	return name
+}
+
+func (spec PolicySpec) getString(field string) string {
+	value, _ := spec[field].(string)
+	return value
+}
+
+// MarshalYAML writes the name first, then the other fields in alphabetical order
+func (spec PolicySpec) MarshalYAML() (interface{}, error) {
+	keys := []string{}
+	for key := range spec {
+		if key != "name" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	if _, ok := spec["name"]; ok {
+		keys = append([]string{"name"}, keys...)
+	}
+
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, key := range keys {
+		value := &yaml.Node{}
+		err := value.Encode(spec[key])
+		if err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+	}
+	return node, nil
+}
+
+// getView returns the fields of the spec that are exported and compared
+func (kind *policyKind) getView(spec PolicySpec) PolicySpec {
+	res := PolicySpec{}
+	for key, value := range spec {
+		if !util.InSlice(kind.ignoredFields, key) {
+			res[key] = value
+		}
+	}
+	return res
+}
+
+func isBuiltInPolicyObject(owner string, name string) bool {
+	return owner == "built-in" && strings.HasSuffix(name, "-built-in")
+}
+
+// sortGroupSpecs puts the parent groups before their children
+func sortGroupSpecs(specs []PolicySpec) []PolicySpec {
+	res := []PolicySpec{}
+	added := map[string]bool{}
+	names := map[string]bool{}
+	for _, spec := range specs {
+		names[spec.getName()] = true
+	}
+
+	for len(res) < len(specs) {
+		progressed := false
+		for _, spec := range specs {
+			name := spec.getName()
+			parentId := spec.getString("parentId")
+			if added[name] || (names[parentId] && !added[parentId] && parentId != name) {
+				continue
+			}
+
+			res = append(res, spec)
+			added[name] = true
+			progressed = true
+		}
+
+		if !progressed {
+			// a cycle in the parents, the groups left are added as they are
+			for _, spec := range specs {
+				if !added[spec.getName()] {
+					res = append(res, spec)
+					added[spec.getName()] = true
+				}
+			}
+		}
+	}
+	return res
+}
+
+func ExportPolicyBundle(owner string) (*PolicyBundle, error) {
+	bundle := &PolicyBundle{Organization: owner}
+	for _, kind := range policyKinds {
+		specs, err := kind.list(owner)
+		if err != nil {
+			return nil, err
+		}
+
+		views := []PolicySpec{}
+		for _, spec := range specs {
+			views = append(views, kind.getView(spec))
+		}
+		sort.SliceStable(views, func(i, j int) bool {
+			return views[i].getName() < views[j].getName()
+		})
+		if kind.name == "Group" {
+			views = sortGroupSpecs(views)
+		}
+
+		*kind.getSpecs(bundle) = views
+	}
+	return bundle, nil
+}
+
+func (bundle *PolicyBundle) ToYaml() ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+
+	err := encoder.Encode(bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	err = encoder.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func ParsePolicyBundle(data []byte) (*PolicyBundle, error) {
+	bundle := &PolicyBundle{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	err := decoder.Decode(bundle)
+	if err != nil {
+		return nil, err
+	}
+	return bundle, nil
+}
+
+// merge adds the objects of another file of the same directory, a kind listed in any of the files is reconciled
+func (bundle *PolicyBundle) merge(other *PolicyBundle) error {
+	if other.Organization != "" {
+		if bundle.Organization != "" && bundle.Organization != other.Organization {
+			return fmt.Errorf("the organizations: %s and %s are both in the bundle", bundle.Organization, other.Organization)
+		}
+		bundle.Organization = other.Organization
+	}
+
+	for _, kind := range policyKinds {
+		otherSpecs := *kind.getSpecs(other)
+		if otherSpecs == nil {
+			continue
+		}
+
+		specs := kind.getSpecs(bundle)
+		if *specs == nil {
+			*specs = []PolicySpec{}
+		}
+		*specs = append(*specs, otherSpecs...)
+	}
+	return nil
+}
+
+// LoadPolicyDirectory reads the bundle made of the YAML files of a directory
+func LoadPolicyDirectory(directory string) (*PolicyBundle, error) {
+	if directory == "" {
+		return nil, fmt.Errorf("the policy directory should not be empty")
+	}
+
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := &PolicyBundle{}
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(directory, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		fileBundle, err := ParsePolicyBundle(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+
+		err = bundle.merge(fileBundle)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+	}
+	return bundle, nil
+}
+
+func getPolicyFieldChanges(oldView PolicySpec, newView PolicySpec) []*PolicyFieldChange {
+	keys := []string{}
+	for key := range oldView {
+		keys = append(keys, key)
+	}
+	for key := range newView {
+		if _, ok := oldView[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	res := []*PolicyFieldChange{}
+	for _, key := range keys {
+		if !reflect.DeepEqual(oldView[key], newView[key]) {
+			res = append(res, &PolicyFieldChange{Field: key, Old: oldView[key], New: newView[key]})
+		}
+	}
+	return res
+}
+
+// planPolicyKind compares the saved objects of a kind with the specs of the bundle. A field missing from a spec
+// keeps its saved value, so the adapter passwords and the fields added by later versions are not reset.
+func planPolicyKind(owner string, kind *policyKind, saved []PolicySpec, specs []PolicySpec) ([]*PolicyChange, []*PolicyChange, error) {
+	savedSpecs := map[string]PolicySpec{}
+	for _, spec := range saved {
+		savedSpecs[spec.getName()] = spec
+	}
+
+	if kind.name == "Group" {
+		specs = sortGroupSpecs(specs)
+	}
+
+	changes := []*PolicyChange{}
+	names := map[string]bool{}
+	for _, spec := range specs {
+		name := spec.getName()
+		if name == "" {
+			return nil, nil, fmt.Errorf("the name of a %s should not be empty", strings.ToLower(kind.name))
+		}
+		if names[name] {
+			return nil, nil, fmt.Errorf("the %s: %s is defined twice", strings.ToLower(kind.name), name)
+		}
+		names[name] = true
+
+		desired := PolicySpec{}
+		savedSpec, ok := savedSpecs[name]
+		for key, value := range savedSpec {
+			desired[key] = value
+		}
+		for key, value := range kind.getView(spec) {
+			desired[key] = value
+		}
+		desired["owner"] = owner
+		if !ok {
+			desired["createdTime"] = util.GetCurrentTime()
+		}
+
+		desired, err := kind.normalize(desired)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !ok {
+			changes = append(changes, &PolicyChange{Action: PolicyChangeAdd, Kind: kind.name, Name: name, Fields: getPolicyFieldChanges(PolicySpec{}, kind.getView(desired)), kind: kind, spec: desired})
+			continue
+		}
+
+		fields := getPolicyFieldChanges(kind.getView(savedSpec), kind.getView(desired))
+		if len(fields) != 0 {
+			changes = append(changes, &PolicyChange{Action: PolicyChangeUpdate, Kind: kind.name, Name: name, Fields: fields, kind: kind, spec: desired})
+		}
+	}
+
+	deletions := []*PolicyChange{}
+	for _, spec := range saved {
+		name := spec.getName()
+		if names[name] || isBuiltInPolicyObject(owner, name) {
+			continue
+		}
+		deletions = append(deletions, &PolicyChange{Action: PolicyChangeDelete, Kind: kind.name, Name: name, Fields: []*PolicyFieldChange{}, kind: kind, spec: spec})
+	}
+	if kind.name == "Group" {
+		// the children are deleted before their parents
+		sorted := sortGroupSpecs(saved)
+		order := map[string]int{}
+		for i, spec := range sorted {
+			order[spec.getName()] = i
+		}
+		sort.SliceStable(deletions, func(i, j int) bool {
+			return order[deletions[i].Name] > order[deletions[j].Name]
+		})
+	}
+	return changes, deletions, nil
+}
+
+// PlanPolicyBundle lists the adds, changes and deletes that reconcile the organization with the bundle
+func PlanPolicyBundle(owner string, bundle *PolicyBundle) (*PolicyPlan, error) {
+	if bundle.Organization != "" && bundle.Organization != owner {
+		return nil, fmt.Errorf("the bundle is for the organization: %s instead of: %s", bundle.Organization, owner)
+	}
+
+	plan := &PolicyPlan{Organization: owner, Changes: []*PolicyChange{}}
+	deletions := [][]*PolicyChange{}
+	for _, kind := range policyKinds {
+		specs := *kind.getSpecs(bundle)
+		if specs == nil {
+			continue
+		}
+
+		saved, err := kind.list(owner)
+		if err != nil {
+			return nil, err
+		}
+
+		changes, kindDeletions, err := planPolicyKind(owner, kind, saved, specs)
+		if err != nil {
+			return nil, err
+		}
+		plan.Changes = append(plan.Changes, changes...)
+		deletions = append(deletions, kindDeletions)
+	}
+
+	for i := len(deletions) - 1; i >= 0; i-- {
+		plan.Changes = append(plan.Changes, deletions[i]...)
+	}
+	return plan, nil
+}
+
+func (plan *PolicyPlan) apply() error {
+	for _, change := range plan.Changes {
+		var err error
+		switch change.Action {
+		case PolicyChangeAdd:
+			err = change.kind.add(change.spec)
+		case PolicyChangeUpdate:
+			err = change.kind.update(util.GetId(plan.Organization, change.Name), change.spec)
+		case PolicyChangeDelete:
+			err = change.kind.remove(change.spec)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to %s the %s: %s: %w", strings.ToLower(change.Action), strings.ToLower(change.Kind), change.Name, err)
+		}
+	}
+
+	plan.Applied = true
+	return nil
+}
+
+// ApplyPolicyBundle reconciles the organization with the bundle and returns the applied changes
+func ApplyPolicyBundle(owner string, bundle *PolicyBundle) (*PolicyPlan, error) {
+	plan, err := PlanPolicyBundle(owner, bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	err = plan.apply()
+	if err != nil {
+		return plan, err
+	}
+	return plan, nil
+}
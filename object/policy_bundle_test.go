@@ -0,0 +1,179 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func getPolicyKind(name string) *policyKind {
+	for _, kind := range policyKinds {
+		if kind.name == name {
+			return kind
+		}
+	}
+	return nil
+}
+
+func getPolicyChangeNames(changes []*PolicyChange) []string {
+	res := []string{}
+	for _, change := range changes {
+		fields := []string{}
+		for _, field := range change.Fields {
+			fields = append(fields, field.Field)
+		}
+		res = append(res, fmt.Sprintf("%s %s %s", change.Action, change.Name, strings.Join(fields, ",")))
+	}
+	return res
+}
+
+func TestPolicyBundleYaml(t *testing.T) {
+	adapter, err := toPolicySpec(&Adapter{Owner: "org", Name: "adapter-1", CreatedTime: "2024-01-01T00:00:00Z", Table: "casbin_rule", Password: "secret", Port: 3306})
+	if err != nil {
+		t.Fatal(err)
+	}
+	model, err := toPolicySpec(&Model{Owner: "org", Name: "model-1", ModelText: "[request_definition]\nr = sub, obj, act\n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bundle := &PolicyBundle{
+		Organization: "org",
+		Models:       []PolicySpec{getPolicyKind("Model").getView(model)},
+		Adapters:     []PolicySpec{getPolicyKind("Adapter").getView(adapter)},
+	}
+	data, err := bundle.ToYaml()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text := string(data)
+	if strings.Contains(text, "secret") || strings.Contains(text, "createdTime") {
+		t.Fatalf("the ignored fields should not be exported:\n%s", text)
+	}
+	if !strings.Contains(text, "  - name: adapter-1\n") || !strings.Contains(text, "modelText: |") {
+		t.Fatalf("unexpected YAML:\n%s", text)
+	}
+
+	parsed, err := ParsePolicyBundle(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Organization != "org" || len(parsed.Adapters) != 1 || parsed.Adapters[0]["port"] != 3306 {
+		t.Fatalf("unexpected bundle: %v", parsed)
+	}
+	// the kinds are all exported, so that the objects deleted since are deleted on import
+	if parsed.Roles == nil || len(parsed.Roles) != 0 {
+		t.Fatalf("the roles should be listed: %v", parsed.Roles)
+	}
+
+	_, err = ParsePolicyBundle([]byte("organization: org\nuserz: []\n"))
+	if err == nil {
+		t.Fatal("an unknown kind should be invalid")
+	}
+
+	// the kinds missing from a file are not reconciled
+	merged := &PolicyBundle{}
+	err = merged.merge(&PolicyBundle{Organization: "org", Roles: []PolicySpec{{"name": "role-1"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged.Roles == nil || merged.Permissions != nil {
+		t.Fatalf("unexpected merged bundle: %v", merged)
+	}
+	err = merged.merge(&PolicyBundle{Organization: "other"})
+	if err == nil {
+		t.Fatal("a bundle should be for a single organization")
+	}
+}
+
+func TestPlanPolicyKind(t *testing.T) {
+	kind := getPolicyKind("Adapter")
+	saved := []PolicySpec{}
+	for _, adapter := range []*Adapter{
+		{Owner: "built-in", Name: "adapter-kept", Table: "casbin_rule", Password: "secret"},
+		{Owner: "built-in", Name: "adapter-changed", Table: "casbin_rule", Password: "secret"},
+		{Owner: "built-in", Name: "adapter-removed", Table: "casbin_rule"},
+		{Owner: "built-in", Name: "api-adapter-built-in", Table: "casbin_api_rule"},
+	} {
+		spec, err := toPolicySpec(adapter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		saved = append(saved, spec)
+	}
+
+	bundle, err := ParsePolicyBundle([]byte(`adapters:
+  - name: adapter-kept
+    table: casbin_rule
+  - name: adapter-changed
+    table: casbin_user_rule
+    port: 5432
+  - name: adapter-added
+    table: casbin_rule
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes, deletions, err := planPolicyKind("built-in", kind, saved, bundle.Adapters)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := getPolicyChangeNames(append(changes, deletions...))
+	expected := []string{
+		"Update adapter-changed port,table",
+		"Add adapter-added database,databaseType,host,name,port,table,type,useSameDb,user",
+		"Delete adapter-removed ",
+	}
+	if !reflect.DeepEqual(res, expected) {
+		t.Fatalf("unexpected changes: %v", res)
+	}
+	// the password missing from the bundle is kept
+	if changes[0].spec["password"] != "secret" {
+		t.Fatalf("the password should be kept: %v", changes[0].spec)
+	}
+
+	bundle.Adapters = append(bundle.Adapters, PolicySpec{"name": "adapter-added"})
+	_, _, err = planPolicyKind("built-in", kind, saved, bundle.Adapters)
+	if err == nil {
+		t.Fatal("a name defined twice should be invalid")
+	}
+
+	_, _, err = planPolicyKind("built-in", kind, saved, []PolicySpec{{"name": "adapter-kept", "tabel": "casbin_rule"}})
+	if err == nil {
+		t.Fatal("an unknown field should be invalid")
+	}
+}
+
+func TestSortGroupSpecs(t *testing.T) {
+	specs := []PolicySpec{
+		{"name": "team-a", "parentId": "dept"},
+		{"name": "dept", "parentId": "org"},
+		{"name": "team-b", "parentId": "team-a"},
+	}
+
+	res := []string{}
+	for _, spec := range sortGroupSpecs(specs) {
+		res = append(res, spec.getName())
+	}
+	if !reflect.DeepEqual(res, []string{"dept", "team-a", "team-b"}) {
+		t.Fatalf("the parents should be first: %v", res)
+	}
+}
@@ -24,9 +24,11 @@ import (
 )
 
 type PrometheusInfo struct {
-	ApiThroughput   []GaugeVecInfo     `json:"apiThroughput"`
-	ApiLatency      []HistogramVecInfo `json:"apiLatency"`
-	TotalThroughput float64            `json:"totalThroughput"`
+	ApiThroughput       []GaugeVecInfo     `json:"apiThroughput"`
+	ApiLatency          []HistogramVecInfo `json:"apiLatency"`
+	TotalThroughput     float64            `json:"totalThroughput"`
+	EnforcerCacheHits   float64            `json:"enforcerCacheHits"`
+	EnforcerCacheMisses float64            `json:"enforcerCacheMisses"`
 }
 
 type GaugeVecInfo struct {
@@ -67,6 +69,21 @@ var (
 		Name: "casdoor_total_throughput",
 		Help: "The total throughput of casdoor",
 	})
+
+	EnforcerCacheHits = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "casdoor_enforcer_cache_hits",
+		Help: "The number of permission enforcers found in the cache",
+	})
+
+	EnforcerCacheMisses = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "casdoor_enforcer_cache_misses",
+		Help: "The number of permission enforcers loaded from the database",
+	})
+
+	EnforcerCacheSize = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "casdoor_enforcer_cache_size",
+		Help: "The number of cached permission enforcers",
+	})
 )
 
 func ClearThroughputPerSecond() {
@@ -92,6 +109,10 @@ func GetPrometheusInfo() (*PrometheusInfo, error) {
 			res.ApiLatency = getHistogramVecInfo(metricFamily)
 		case "casdoor_total_throughput":
 			res.TotalThroughput = metricFamily.GetMetric()[0].GetGauge().GetValue()
+		case "casdoor_enforcer_cache_hits":
+			res.EnforcerCacheHits = metricFamily.GetMetric()[0].GetCounter().GetValue()
+		case "casdoor_enforcer_cache_misses":
+			res.EnforcerCacheMisses = metricFamily.GetMetric()[0].GetCounter().GetValue()
 		}
 	}
 
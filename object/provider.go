@@ -38,16 +38,16 @@ type Provider struct {
 	SubType           string            `xorm:"varchar(100)" json:"subType"`
 	Method            string            `xorm:"varchar(100)" json:"method"`
 	ClientId          string            `xorm:"varchar(200)" json:"clientId"`
-	ClientSecret      string            `xorm:"varchar(3000)" json:"clientSecret"`
+	ClientSecret      string            `xorm:"mediumtext" json:"clientSecret"`
 	ClientId2         string            `xorm:"varchar(100)" json:"clientId2"`
-	ClientSecret2     string            `xorm:"varchar(500)" json:"clientSecret2"`
+	ClientSecret2     string            `xorm:"varchar(1000)" json:"clientSecret2"`
 	Cert              string            `xorm:"varchar(100)" json:"cert"`
 	CustomAuthUrl     string            `xorm:"varchar(200)" json:"customAuthUrl"`
 	CustomTokenUrl    string            `xorm:"varchar(200)" json:"customTokenUrl"`
 	CustomUserInfoUrl string            `xorm:"varchar(200)" json:"customUserInfoUrl"`
 	CustomLogo        string            `xorm:"varchar(200)" json:"customLogo"`
 	Scopes            string            `xorm:"varchar(100)" json:"scopes"`
-	UserMapping       map[string]string `xorm:"varchar(500)" json:"userMapping"`
+	UserMapping       map[string]string `xorm:"varchar(2000)" json:"userMapping"`
 	HttpHeaders       map[string]string `xorm:"varchar(500)" json:"httpHeaders"`
 
 	Host       string `xorm:"varchar(100)" json:"host"`
@@ -68,11 +68,16 @@ type Provider struct {
 	Bucket           string `xorm:"varchar(100)" json:"bucket"`
 	PathPrefix       string `xorm:"varchar(100)" json:"pathPrefix"`
 
-	Metadata               string `xorm:"mediumtext" json:"metadata"`
-	IdP                    string `xorm:"mediumtext" json:"idP"`
-	IssuerUrl              string `xorm:"varchar(100)" json:"issuerUrl"`
-	EnableSignAuthnRequest bool   `json:"enableSignAuthnRequest"`
-	EmailRegex             string `xorm:"varchar(200)" json:"emailRegex"`
+	Metadata                string `xorm:"mediumtext" json:"metadata"`
+	MetadataUrl             string `xorm:"varchar(500)" json:"metadataUrl"`
+	MetadataRefreshInterval int    `json:"metadataRefreshInterval"`
+	MetadataCertificate     string `xorm:"mediumtext" json:"metadataCertificate"`
+	MetadataTlsPin          string `xorm:"varchar(100)" json:"metadataTlsPin"`
+	IdP                     string `xorm:"mediumtext" json:"idP"`
+	IssuerUrl               string `xorm:"varchar(100)" json:"issuerUrl"`
+	EnableSignAuthnRequest  bool   `json:"enableSignAuthnRequest"`
+	EnableIdpDiscovery      bool   `json:"enableIdpDiscovery"`
+	EmailRegex              string `xorm:"varchar(200)" json:"emailRegex"`
 
 	ProviderUrl string `xorm:"varchar(200)" json:"providerUrl"`
 }
@@ -230,11 +235,20 @@ func UpdateProvider(id string, provider *Provider) (bool, error) {
 		provider.IntranetEndpoint = util.GetEndPoint(provider.IntranetEndpoint)
 	}
 
+	err := encryptColumns(provider)
+	if err != nil {
+		return false, err
+	}
+
 	affected, err := session.Update(provider)
 	if err != nil {
+		restoreEncryptedColumns(provider)
 		return false, err
 	}
 
+	// the IdPs of the metadata URL are fetched again with the new settings
+	samlIdpMetadataMap.Delete(id)
+
 	return affected != 0, nil
 }
 
@@ -251,8 +265,14 @@ func AddProvider(provider *Provider) (bool, error) {
 		}
 	}
 
+	err := encryptColumns(provider)
+	if err != nil {
+		return false, err
+	}
+
 	affected, err := ormer.Engine.Insert(provider)
 	if err != nil {
+		restoreEncryptedColumns(provider)
 		return false, err
 	}
 
@@ -265,6 +285,8 @@ func DeleteProvider(provider *Provider) (bool, error) {
 		return false, err
 	}
 
+	samlIdpMetadataMap.Delete(provider.GetId())
+
 	return affected != 0, nil
 }
 
@@ -21,9 +21,12 @@ import (
 	"strings"
 
 	"github.com/beego/beego/context"
+	"github.com/beego/beego/logs"
+	"github.com/casdoor/casdoor/audit"
 	"github.com/casdoor/casdoor/conf"
 	"github.com/casdoor/casdoor/util"
 	"github.com/casvisor/casvisor-go-sdk/casvisorsdk"
+	"github.com/xorm-io/xorm"
 )
 
 var (
@@ -97,20 +100,87 @@ func NewRecord(ctx *context.Context) (*casvisorsdk.Record, error) {
 	return &record, nil
 }
 
+// addUserActionRecord records an action that is not triggered by an API request of the user,
+// e.g. by a background job, so that it still shows up in the audit trail
+func addUserActionRecord(organization string, user string, action string, object string) {
+	record := &casvisorsdk.Record{
+		Name:         util.GenerateId(),
+		CreatedTime:  util.GetCurrentTime(),
+		Organization: organization,
+		User:         user,
+		Action:       action,
+		Object:       object,
+		StatusCode:   200,
+		Response:     "{status:\"ok\", msg:\"\"}",
+	}
+
+	util.SafeGoroutine(func() {
+		_, err := AddRecord(record)
+		if err != nil {
+			logs.Error(fmt.Sprintf("addUserActionRecord() error: %s", err.Error()))
+		}
+	})
+}
+
+// addRecord inserts the record and links it to the record chain in one transaction,
+// so that no record is stored without its chain entry
 func addRecord(record *casvisorsdk.Record) (int64, error) {
-	affected, err := ormer.Engine.Insert(record)
-	return affected, err
+	var affected int64
+	var recordChain *RecordChain
+	err := withRecordChainSession(func(session *xorm.Session) error {
+		// a failed attempt leaves the id of its rolled back insert
+		record.Id = 0
+
+		var err error
+		affected, err = session.Insert(record)
+		if err != nil {
+			return err
+		}
+
+		recordChain, err = appendRecordChain(session, record)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	streamRecord(record)
+	checkpointRecordChain(recordChain)
+	return affected, nil
 }
 
-func AddRecord(record *casvisorsdk.Record) bool {
+// chainRecord links a record stored in casvisor to the record chain
+func chainRecord(record *casvisorsdk.Record) error {
+	var recordChain *RecordChain
+	err := withRecordChainSession(func(session *xorm.Session) error {
+		var err error
+		recordChain, err = appendRecordChain(session, record)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	checkpointRecordChain(recordChain)
+	return nil
+}
+
+// streamRecord sends the record to the audit sinks configured by "auditSinks"
+func streamRecord(record *casvisorsdk.Record) {
+	record2 := *record
+	record2.Object = maskPassword(record2.Object)
+	audit.Emit(&record2)
+}
+
+func AddRecord(record *casvisorsdk.Record) (bool, error) {
 	if logPostOnly {
 		if record.Method == "GET" {
-			return false
+			return false, nil
 		}
 	}
 
 	if record.Organization == "app" {
-		return false
+		return false, nil
 	}
 
 	record.Owner = record.Organization
@@ -126,18 +196,27 @@ func AddRecord(record *casvisorsdk.Record) bool {
 	if casvisorsdk.GetClient() == nil {
 		affected, err := addRecord(record)
 		if err != nil {
-			panic(err)
+			return false, err
 		}
 
-		return affected != 0
+		return affected != 0, nil
 	}
 
 	affected, err := casvisorsdk.AddRecord(record)
 	if err != nil {
-		fmt.Printf("AddRecord() error: %s", err.Error())
+		return false, err
+	}
+
+	if affected {
+		streamRecord(record)
+
+		err = chainRecord(record)
+		if err != nil {
+			return affected, err
+		}
 	}
 
-	return affected
+	return affected, nil
 }
 
 func GetRecordCount(field, value string, filterRecord *casvisorsdk.Record) (int64, error) {
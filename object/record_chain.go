@@ -0,0 +1,640 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/beego/beego/logs"
+	"github.com/casdoor/casdoor/conf"
+	"github.com/casdoor/casdoor/util"
+	"github.com/casvisor/casvisor-go-sdk/casvisorsdk"
+	"github.com/xorm-io/builder"
+	"github.com/xorm-io/xorm"
+)
+
+// RecordChain is the append-only hash chain entry of a record. Every entry
+// links to the hash of the previous entry of the same organization, so that
+// editing, deleting or inserting records afterwards breaks the chain.
+type RecordChain struct {
+	Id          int    `xorm:"int notnull pk autoincr" json:"id"`
+	Owner       string `xorm:"varchar(100) notnull unique(owner_sequence)" json:"owner"`
+	Sequence    int64  `xorm:"notnull unique(owner_sequence)" json:"sequence"`
+	RecordId    int    `xorm:"index" json:"recordId"`
+	RecordName  string `xorm:"varchar(100) index" json:"recordName"`
+	CreatedTime string `xorm:"varchar(100) index" json:"createdTime"`
+
+	Digest   string `xorm:"varchar(100)" json:"digest"`
+	PrevHash string `xorm:"varchar(100)" json:"prevHash"`
+	Hash     string `xorm:"varchar(100)" json:"hash"`
+}
+
+// RecordChainHead is the last entry of an organization's record chain. Its row is locked while a record is
+// appended, so that the replicas append one record at a time, and it tells when the end of the chain is cut.
+type RecordChainHead struct {
+	Owner    string `xorm:"varchar(100) notnull pk" json:"owner"`
+	Sequence int64  `json:"sequence"`
+	Hash     string `xorm:"varchar(100)" json:"hash"`
+}
+
+// RecordCheckpoint is a periodic signature over the head of an organization's record chain.
+type RecordCheckpoint struct {
+	Id          int    `xorm:"int notnull pk autoincr" json:"id"`
+	Owner       string `xorm:"varchar(100) index" json:"owner"`
+	CreatedTime string `xorm:"varchar(100)" json:"createdTime"`
+
+	Sequence  int64  `json:"sequence"`
+	Hash      string `xorm:"varchar(100)" json:"hash"`
+	Cert      string `xorm:"varchar(200)" json:"cert"`
+	KeyId     string `xorm:"varchar(100)" json:"keyId"`
+	Signature string `xorm:"mediumtext" json:"signature"`
+}
+
+type RecordChainIssue struct {
+	Sequence   int64  `json:"sequence"`
+	RecordName string `json:"recordName"`
+	Type       string `json:"type"`
+	Message    string `json:"message"`
+}
+
+type RecordChainReport struct {
+	Organization  string              `json:"organization"`
+	Count         int                 `json:"count"`
+	RecordCount   int                 `json:"recordCount"`
+	FirstSequence int64               `json:"firstSequence"`
+	LastSequence  int64               `json:"lastSequence"`
+	Checkpoints   int                 `json:"checkpoints"`
+	IsValid       bool                `json:"isValid"`
+	Issues        []*RecordChainIssue `json:"issues"`
+}
+
+const (
+	RecordChainIssueGap        = "gap"
+	RecordChainIssueBrokenLink = "broken-link"
+	RecordChainIssueEdited     = "edited"
+	RecordChainIssueMissing    = "missing"
+	RecordChainIssueUnchained  = "unchained"
+	RecordChainIssueTruncated  = "truncated"
+	RecordChainIssueCount      = "count"
+	RecordChainIssueCheckpoint = "checkpoint"
+)
+
+// the attempts to append a record, the first records of an organization appended at once conflict on its new head
+const recordChainAttempts = 3
+
+func getRecordCheckpointInterval() int64 {
+	interval, err := conf.GetConfigInt64("recordCheckpointInterval")
+	if err != nil || interval <= 0 {
+		return 100
+	}
+	return interval
+}
+
+func getRecordCheckpointCert() (*Cert, error) {
+	certId := conf.GetConfigString("recordCheckpointCert")
+	if certId == "" {
+		return GetDefaultCert()
+	}
+	return GetCert(certId)
+}
+
+// getRecordDigest hashes the fields of a record that are covered by the chain.
+// The auto-increment id is left out as it is assigned by the database.
+func getRecordDigest(record *casvisorsdk.Record) string {
+	content := []string{
+		record.Owner, record.Name, record.CreatedTime, record.Organization, record.ClientIp, record.User,
+		record.Method, record.RequestUri, record.Action, record.Language, record.Object, record.Response,
+		strconv.Itoa(record.StatusCode), strconv.FormatBool(record.IsTriggered),
+	}
+
+	bytes, _ := json.Marshal(content)
+	sum := sha256.Sum256(bytes)
+	return hex.EncodeToString(sum[:])
+}
+
+func getRecordChainHash(prevHash string, sequence int64, digest string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d|%s", prevHash, sequence, digest)))
+	return hex.EncodeToString(sum[:])
+}
+
+func getLastRecordChain(owner string) (*RecordChain, error) {
+	recordChain := RecordChain{}
+	existed, err := ormer.Engine.Where("owner = ?", owner).Desc("sequence").Limit(1).Get(&recordChain)
+	if err != nil {
+		return nil, err
+	}
+
+	if existed {
+		return &recordChain, nil
+	} else {
+		return nil, nil
+	}
+}
+
+func getRecordChains(owner string, afterSequence int64, limit int) ([]*RecordChain, error) {
+	recordChains := []*RecordChain{}
+	err := ormer.Engine.Where("owner = ? and sequence > ?", owner, afterSequence).Asc("sequence").Limit(limit).Find(&recordChains)
+	if err != nil {
+		return recordChains, err
+	}
+
+	return recordChains, nil
+}
+
+func getRecordChainBySequence(owner string, sequence int64) (*RecordChain, error) {
+	recordChain := RecordChain{}
+	existed, err := ormer.Engine.Where("owner = ? and sequence = ?", owner, sequence).Get(&recordChain)
+	if err != nil {
+		return nil, err
+	}
+
+	if existed {
+		return &recordChain, nil
+	} else {
+		return nil, nil
+	}
+}
+
+func getRecordChainHead(owner string) (*RecordChainHead, error) {
+	head := RecordChainHead{}
+	existed, err := ormer.Engine.Where("owner = ?", owner).Get(&head)
+	if err != nil {
+		return nil, err
+	}
+
+	if existed {
+		return &head, nil
+	} else {
+		return nil, nil
+	}
+}
+
+func GetRecordCheckpoints(owner string) ([]*RecordCheckpoint, error) {
+	recordCheckpoints := []*RecordCheckpoint{}
+	err := ormer.Engine.Where("owner = ?", owner).Asc("sequence").Find(&recordCheckpoints)
+	if err != nil {
+		return recordCheckpoints, err
+	}
+
+	return recordCheckpoints, nil
+}
+
+// withRecordChainSession runs fn in a transaction, which is retried when it fails
+func withRecordChainSession(fn func(session *xorm.Session) error) error {
+	var err error
+	for i := 0; i < recordChainAttempts; i++ {
+		err = func() error {
+			session := ormer.Engine.NewSession()
+			defer session.Close()
+
+			err := session.Begin()
+			if err != nil {
+				return err
+			}
+
+			err = fn(session)
+			if err != nil {
+				session.Rollback()
+				return err
+			}
+			return session.Commit()
+		}()
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// lockRecordChainHead locks the head of the organization's chain until the end of the transaction,
+// the head of a chain started before the heads existed is its last entry
+func lockRecordChainHead(session *xorm.Session, owner string) (*RecordChainHead, error) {
+	head := &RecordChainHead{}
+	existed, err := session.Where("owner = ?", owner).ForUpdate().Get(head)
+	if err != nil {
+		return nil, err
+	}
+	if existed {
+		return head, nil
+	}
+
+	head = &RecordChainHead{Owner: owner}
+	lastRecordChain := RecordChain{}
+	existed, err = session.Where("owner = ?", owner).Desc("sequence").Limit(1).Get(&lastRecordChain)
+	if err != nil {
+		return nil, err
+	}
+	if existed {
+		head.Sequence = lastRecordChain.Sequence
+		head.Hash = lastRecordChain.Hash
+	}
+
+	// another replica adding the head meanwhile makes this insert fail, and the transaction is retried
+	_, err = session.Insert(head)
+	if err != nil {
+		return nil, err
+	}
+	return head, nil
+}
+
+// appendRecordChain links the record to the end of its organization's chain, in the transaction adding the record
+func appendRecordChain(session *xorm.Session, record *casvisorsdk.Record) (*RecordChain, error) {
+	head, err := lockRecordChainHead(session, record.Owner)
+	if err != nil {
+		return nil, err
+	}
+
+	sequence := head.Sequence + 1
+	digest := getRecordDigest(record)
+	recordChain := &RecordChain{
+		Owner:       record.Owner,
+		Sequence:    sequence,
+		RecordId:    record.Id,
+		RecordName:  record.Name,
+		CreatedTime: record.CreatedTime,
+		Digest:      digest,
+		PrevHash:    head.Hash,
+		Hash:        getRecordChainHash(head.Hash, sequence, digest),
+	}
+
+	_, err = session.Insert(recordChain)
+	if err != nil {
+		return nil, err
+	}
+
+	head.Sequence = recordChain.Sequence
+	head.Hash = recordChain.Hash
+	_, err = session.Where("owner = ?", head.Owner).Cols("sequence", "hash").Update(head)
+	if err != nil {
+		return nil, err
+	}
+	return recordChain, nil
+}
+
+// checkpointRecordChain signs the entry when a checkpoint is due, the record is kept when it fails
+// and the missing checkpoint shows in the verification
+func checkpointRecordChain(recordChain *RecordChain) {
+	if recordChain.Sequence%getRecordCheckpointInterval() != 0 {
+		return
+	}
+
+	err := addRecordCheckpoint(recordChain)
+	if err != nil {
+		logs.Error(fmt.Sprintf("checkpointRecordChain() error: %s", err.Error()))
+	}
+}
+
+func getRecordCheckpointContent(checkpoint *RecordCheckpoint) string {
+	return fmt.Sprintf("%s|%d|%s|%s", checkpoint.Owner, checkpoint.Sequence, checkpoint.Hash, checkpoint.CreatedTime)
+}
+
+func addRecordCheckpoint(recordChain *RecordChain) error {
+	cert, err := getRecordCheckpointCert()
+	if err != nil {
+		return err
+	}
+	if cert == nil {
+		return fmt.Errorf("the cert for record checkpoints is not found")
+	}
+
+	checkpoint := &RecordCheckpoint{
+		Owner:       recordChain.Owner,
+		CreatedTime: util.GetCurrentTime(),
+		Sequence:    recordChain.Sequence,
+		Hash:        recordChain.Hash,
+		Cert:        cert.GetId(),
+		KeyId:       cert.GetKeyId(),
+	}
+
+	checkpoint.Signature, err = signByCert(cert, getRecordCheckpointContent(checkpoint))
+	if err != nil {
+		return err
+	}
+
+	_, err = ormer.Engine.Insert(checkpoint)
+	return err
+}
+
+// AddRecordCheckpoint signs the current head of the organization's record chain.
+func AddRecordCheckpoint(owner string) (bool, error) {
+	lastRecordChain, err := getLastRecordChain(owner)
+	if err != nil {
+		return false, err
+	}
+	if lastRecordChain == nil {
+		return false, nil
+	}
+
+	err = addRecordCheckpoint(lastRecordChain)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// getChainedRecord returns the record of the chain entry, by its id and name, the entries appended
+// before the ids were kept only have the name
+func getChainedRecord(recordChain *RecordChain) (*casvisorsdk.Record, error) {
+	session := ormer.Engine.Where("owner = ? and name = ?", recordChain.Owner, recordChain.RecordName)
+	if recordChain.RecordId != 0 {
+		session = session.And("id = ?", recordChain.RecordId)
+	}
+
+	record := casvisorsdk.Record{}
+	existed, err := session.Get(&record)
+	if err != nil {
+		return nil, err
+	}
+
+	if existed {
+		return &record, nil
+	} else {
+		return nil, nil
+	}
+}
+
+// VerifyRecordChain walks the organization's record chain and reports gaps, broken links, edited, missing
+// or unchained records, entries cut from either end of the chain and invalid checkpoints.
+func VerifyRecordChain(owner string) (*RecordChainReport, error) {
+	report := &RecordChainReport{
+		Organization: owner,
+		Issues:       []*RecordChainIssue{},
+	}
+
+	// records stored in casvisor cannot be read back, only the chain itself is verified then
+	checkRecords := casvisorsdk.GetClient() == nil
+
+	var first *RecordChain
+	var prev *RecordChain
+	batchSize := conf.GetConfigBatchSize()
+	afterSequence := int64(0)
+	for {
+		recordChains, err := getRecordChains(owner, afterSequence, batchSize)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, recordChain := range recordChains {
+			issues, err := verifyRecordChainEntry(recordChain, prev, checkRecords)
+			if err != nil {
+				return nil, err
+			}
+			report.Issues = append(report.Issues, issues...)
+
+			if prev == nil {
+				first = recordChain
+				report.FirstSequence = recordChain.Sequence
+			}
+			report.LastSequence = recordChain.Sequence
+			report.Count++
+			prev = recordChain
+		}
+
+		if len(recordChains) < batchSize {
+			break
+		}
+		afterSequence = recordChains[len(recordChains)-1].Sequence
+	}
+
+	head, err := getRecordChainHead(owner)
+	if err != nil {
+		return nil, err
+	}
+
+	// the anchor is what precedes the first remaining entry: the start of the chain,
+	// or the signed checkpoint of the last entry removed by retention
+	anchorSequence := int64(0)
+	anchorHash := ""
+	if first != nil {
+		anchorSequence = first.Sequence - 1
+		anchorHash = first.PrevHash
+	} else if head != nil {
+		anchorSequence = head.Sequence
+		anchorHash = head.Hash
+	}
+
+	checkpoints, err := GetRecordCheckpoints(owner)
+	if err != nil {
+		return nil, err
+	}
+
+	isAnchored := anchorSequence == 0 && anchorHash == ""
+	for _, checkpoint := range checkpoints {
+		issue, err := verifyRecordCheckpoint(checkpoint, anchorSequence+1)
+		if err != nil {
+			return nil, err
+		}
+		if issue != nil {
+			report.Issues = append(report.Issues, issue)
+		} else if checkpoint.Sequence == anchorSequence && checkpoint.Hash == anchorHash {
+			isAnchored = true
+		}
+	}
+
+	if !isAnchored {
+		report.Issues = append(report.Issues, &RecordChainIssue{
+			Sequence: anchorSequence + 1,
+			Type:     RecordChainIssueTruncated,
+			Message:  fmt.Sprintf("the chain entries before sequence %d have been deleted", anchorSequence+1),
+		})
+	}
+
+	// entries appended since the heads exist always have one, chains older than the heads have none yet
+	if head != nil && prev != nil && (head.Sequence != prev.Sequence || head.Hash != prev.Hash) {
+		report.Issues = append(report.Issues, &RecordChainIssue{
+			Sequence: prev.Sequence,
+			Type:     RecordChainIssueTruncated,
+			Message:  fmt.Sprintf("the chain ends at sequence %d but its head is sequence %d", prev.Sequence, head.Sequence),
+		})
+	} else if head == nil && prev != nil && prev.RecordId != 0 {
+		report.Issues = append(report.Issues, &RecordChainIssue{
+			Sequence: prev.Sequence,
+			Type:     RecordChainIssueTruncated,
+			Message:  "the chain head has been deleted",
+		})
+	}
+
+	if checkRecords && (first != nil || head != nil) {
+		issues, err := verifyRecordsChained(owner, first, report)
+		if err != nil {
+			return nil, err
+		}
+		report.Issues = append(report.Issues, issues...)
+
+		if report.RecordCount != report.Count {
+			report.Issues = append(report.Issues, &RecordChainIssue{
+				Type:    RecordChainIssueCount,
+				Message: fmt.Sprintf("the chain has %d entries but there are %d records", report.Count, report.RecordCount),
+			})
+		}
+	}
+
+	report.Checkpoints = len(checkpoints)
+	report.IsValid = len(report.Issues) == 0
+	return report, nil
+}
+
+// verifyRecordsChained counts the records since the first chain entry and reports the ones without an entry,
+// records older than the chain were added before it existed
+func verifyRecordsChained(owner string, first *RecordChain, report *RecordChainReport) ([]*RecordChainIssue, error) {
+	issues := []*RecordChainIssue{}
+
+	cond := builder.NewCond()
+	if first != nil && first.RecordId != 0 {
+		cond = builder.Gte{"id": first.RecordId}
+	} else if first != nil {
+		cond = builder.Gte{"created_time": first.CreatedTime}
+	}
+
+	batchSize := conf.GetConfigBatchSize()
+	afterId := 0
+	for {
+		records := []*casvisorsdk.Record{}
+		err := ormer.Engine.Where("owner = ? and id > ?", owner, afterId).And(cond).Asc("id").Limit(batchSize).Find(&records)
+		if err != nil {
+			return nil, err
+		}
+		if len(records) == 0 {
+			break
+		}
+
+		names := []string{}
+		for _, record := range records {
+			names = append(names, record.Name)
+		}
+
+		recordChains := []*RecordChain{}
+		err = ormer.Engine.Where("owner = ?", owner).And(builder.In("record_name", names)).Find(&recordChains)
+		if err != nil {
+			return nil, err
+		}
+
+		chainedIds := map[int]bool{}
+		chainedNames := map[string]bool{}
+		for _, recordChain := range recordChains {
+			if recordChain.RecordId != 0 {
+				chainedIds[recordChain.RecordId] = true
+			} else {
+				chainedNames[recordChain.RecordName] = true
+			}
+		}
+
+		for _, record := range records {
+			report.RecordCount++
+			if !chainedIds[record.Id] && !chainedNames[record.Name] {
+				issues = append(issues, &RecordChainIssue{
+					RecordName: record.Name,
+					Type:       RecordChainIssueUnchained,
+					Message:    fmt.Sprintf("the record: %s has no chain entry", record.Name),
+				})
+			}
+		}
+
+		if len(records) < batchSize {
+			break
+		}
+		afterId = records[len(records)-1].Id
+	}
+
+	return issues, nil
+}
+
+func verifyRecordChainEntry(recordChain *RecordChain, prev *RecordChain, checkRecords bool) ([]*RecordChainIssue, error) {
+	issues := []*RecordChainIssue{}
+	newIssue := func(issueType string, format string, a ...interface{}) {
+		issues = append(issues, &RecordChainIssue{
+			Sequence:   recordChain.Sequence,
+			RecordName: recordChain.RecordName,
+			Type:       issueType,
+			Message:    fmt.Sprintf(format, a...),
+		})
+	}
+
+	// the first remaining entry is checked against its anchor, its predecessors may have been removed by retention
+	if prev != nil {
+		if recordChain.Sequence != prev.Sequence+1 {
+			newIssue(RecordChainIssueGap, "expected sequence %d but got %d", prev.Sequence+1, recordChain.Sequence)
+		}
+		if recordChain.PrevHash != prev.Hash {
+			newIssue(RecordChainIssueBrokenLink, "the previous hash does not match the hash of sequence %d", prev.Sequence)
+		}
+	}
+
+	if recordChain.Hash != getRecordChainHash(recordChain.PrevHash, recordChain.Sequence, recordChain.Digest) {
+		newIssue(RecordChainIssueEdited, "the chain entry has been modified")
+	}
+
+	if !checkRecords {
+		return issues, nil
+	}
+
+	record, err := getChainedRecord(recordChain)
+	if err != nil {
+		return nil, err
+	}
+
+	if record == nil {
+		newIssue(RecordChainIssueMissing, "the record: %s has been deleted", recordChain.RecordName)
+	} else if getRecordDigest(record) != recordChain.Digest {
+		newIssue(RecordChainIssueEdited, "the record: %s has been modified", recordChain.RecordName)
+	}
+
+	return issues, nil
+}
+
+func verifyRecordCheckpoint(checkpoint *RecordCheckpoint, firstSequence int64) (*RecordChainIssue, error) {
+	newIssue := func(format string, a ...interface{}) *RecordChainIssue {
+		return &RecordChainIssue{
+			Sequence: checkpoint.Sequence,
+			Type:     RecordChainIssueCheckpoint,
+			Message:  fmt.Sprintf(format, a...),
+		}
+	}
+
+	cert, err := GetCert(checkpoint.Cert)
+	if err != nil {
+		return nil, err
+	}
+	if cert == nil {
+		return newIssue("the cert: %s of the checkpoint is not found", checkpoint.Cert), nil
+	}
+
+	err = verifyByCert(cert, checkpoint.KeyId, getRecordCheckpointContent(checkpoint), checkpoint.Signature)
+	if err != nil {
+		return newIssue("the checkpoint signature is invalid: %s", err.Error()), nil
+	}
+
+	// checkpoints before the retention boundary refer to entries that no longer exist
+	if checkpoint.Sequence < firstSequence {
+		return nil, nil
+	}
+
+	recordChain, err := getRecordChainBySequence(checkpoint.Owner, checkpoint.Sequence)
+	if err != nil {
+		return nil, err
+	}
+
+	if recordChain == nil {
+		return newIssue("the chain entry of the checkpoint has been deleted"), nil
+	} else if recordChain.Hash != checkpoint.Hash {
+		return newIssue("the chain entry does not match the signed checkpoint"), nil
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,160 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/casdoor/casdoor/util"
+	"github.com/casvisor/casvisor-go-sdk/casvisorsdk"
+)
+
+func TestRecordChainEntry(t *testing.T) {
+	record := &casvisorsdk.Record{Owner: "built-in", Name: "record-1", Action: "login", Object: "{}"}
+	digest := getRecordDigest(record)
+
+	first := &RecordChain{Owner: "built-in", Sequence: 1, RecordName: "record-1", Digest: digest}
+	first.Hash = getRecordChainHash("", 1, digest)
+
+	second := &RecordChain{Owner: "built-in", Sequence: 3, RecordName: "record-2", Digest: digest, PrevHash: "edited"}
+	second.Hash = getRecordChainHash(second.PrevHash, 3, digest)
+
+	issues, err := verifyRecordChainEntry(first, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 0 {
+		t.Errorf("expected no issues for the first entry, got %d", len(issues))
+	}
+
+	issues, err = verifyRecordChainEntry(second, first, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 2 || issues[0].Type != RecordChainIssueGap || issues[1].Type != RecordChainIssueBrokenLink {
+		t.Errorf("expected a gap and a broken link, got %v", issues)
+	}
+
+	record.Object = "{\"edited\":true}"
+	if getRecordDigest(record) == digest {
+		t.Errorf("the digest should change when the record is edited")
+	}
+}
+
+func getRecordChainIssueTypes(report *RecordChainReport) []string {
+	issueTypes := []string{}
+	for _, issue := range report.Issues {
+		issueTypes = append(issueTypes, issue.Type)
+	}
+	return issueTypes
+}
+
+func TestVerifyRecordChain(t *testing.T) {
+	setupTestOrmer(t, new(casvisorsdk.Record), new(RecordChain), new(RecordChainHead), new(RecordCheckpoint))
+
+	records := []*casvisorsdk.Record{}
+	for i := 0; i < 3; i++ {
+		record := &casvisorsdk.Record{Owner: "built-in", Name: util.GenerateId(), CreatedTime: util.GetCurrentTime(), Action: "login"}
+		_, err := addRecord(record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+
+	verify := func() *RecordChainReport {
+		report, err := VerifyRecordChain("built-in")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return report
+	}
+
+	report := verify()
+	if !report.IsValid || report.Count != 3 || report.RecordCount != 3 {
+		t.Fatalf("expected a valid chain of 3 records, got %v", getRecordChainIssueTypes(report))
+	}
+
+	// a record written without its chain entry
+	unchained := &casvisorsdk.Record{Owner: "built-in", Name: util.GenerateId(), CreatedTime: util.GetCurrentTime()}
+	_, err := ormer.Engine.Insert(unchained)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issueTypes := getRecordChainIssueTypes(verify())
+	if strings.Join(issueTypes, ",") != "unchained,count" {
+		t.Fatalf("expected the record without a chain entry to be reported, got %v", issueTypes)
+	}
+
+	_, err = ormer.Engine.ID(unchained.Id).Delete(&casvisorsdk.Record{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the last record is removed together with its chain entry
+	_, err = ormer.Engine.ID(records[2].Id).Delete(&casvisorsdk.Record{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ormer.Engine.Where("owner = ? and sequence = ?", "built-in", 3).Delete(&RecordChain{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issueTypes = getRecordChainIssueTypes(verify())
+	if strings.Join(issueTypes, ",") != "truncated" {
+		t.Fatalf("expected the end of the chain to be reported as truncated, got %v", issueTypes)
+	}
+
+	// the first record is removed without a checkpoint anchoring the rest of the chain
+	_, err = ormer.Engine.ID(records[0].Id).Delete(&casvisorsdk.Record{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ormer.Engine.Where("owner = ? and sequence = ?", "built-in", 1).Delete(&RecordChain{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report = verify()
+	if report.FirstSequence != 2 || report.Issues[0].Type != RecordChainIssueTruncated || report.Issues[0].Sequence != 2 {
+		t.Fatalf("expected the start of the chain to be reported as truncated, got %v", getRecordChainIssueTypes(report))
+	}
+}
+
+func TestGetRecordCefLine(t *testing.T) {
+	item := &RecordExportItem{
+		Record: &casvisorsdk.Record{
+			Name:        "record-1",
+			CreatedTime: "2024-01-02T03:04:05Z",
+			User:        "alice",
+			Action:      "update|user",
+			RequestUri:  "/api/update-user?id=a=b",
+			Response:    "{status:\"error\", msg:\"\"}",
+		},
+		Sequence: 7,
+		Hash:     "abc",
+	}
+
+	line := getRecordCefLine(item)
+	if !strings.HasPrefix(line, "CEF:0|Casdoor|Casdoor|1.0|update\\|user|update\\|user|7|") {
+		t.Errorf("unexpected CEF header: %s", line)
+	}
+	if !strings.Contains(line, "rt=1704164645000") || !strings.Contains(line, "request=/api/update-user?id\\=a\\=b") || !strings.Contains(line, "outcome=failure") {
+		t.Errorf("unexpected CEF extension: %s", line)
+	}
+}
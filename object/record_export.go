@@ -0,0 +1,167 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/casdoor/casdoor/audit"
+	"github.com/casdoor/casdoor/conf"
+	"github.com/casvisor/casvisor-go-sdk/casvisorsdk"
+	"github.com/xorm-io/builder"
+)
+
+type RecordExportItem struct {
+	*casvisorsdk.Record
+	Sequence int64  `json:"sequence"`
+	PrevHash string `json:"prevHash"`
+	Hash     string `json:"hash"`
+}
+
+var (
+	cefHeaderReplacer    = strings.NewReplacer("\\", "\\\\", "|", "\\|", "\r", " ", "\n", " ")
+	cefExtensionReplacer = strings.NewReplacer("\\", "\\\\", "=", "\\=", "\r", "\\r", "\n", "\\n")
+)
+
+func getRecordCefLine(item *RecordExportItem) string {
+	record := item.Record
+
+	severity := 3
+	outcome := "success"
+	if audit.IsRecordFailed(record) {
+		severity = 7
+		outcome = "failure"
+	}
+
+	rt := record.CreatedTime
+	createdTime, err := time.Parse(time.RFC3339, record.CreatedTime)
+	if err == nil {
+		rt = fmt.Sprintf("%d", createdTime.UnixMilli())
+	}
+
+	extensions := [][]string{
+		{"rt", rt},
+		{"externalId", record.Name},
+		{"src", record.ClientIp},
+		{"suser", record.User},
+		{"cs1Label", "organization"},
+		{"cs1", record.Organization},
+		{"requestMethod", record.Method},
+		{"request", record.RequestUri},
+		{"outcome", outcome},
+		{"cn1Label", "sequence"},
+		{"cn1", fmt.Sprintf("%d", item.Sequence)},
+		{"cs2Label", "prevHash"},
+		{"cs2", item.PrevHash},
+		{"cs3Label", "hash"},
+		{"cs3", item.Hash},
+	}
+
+	extensionStrings := []string{}
+	for _, extension := range extensions {
+		if extension[1] == "" {
+			continue
+		}
+		extensionStrings = append(extensionStrings, fmt.Sprintf("%s=%s", extension[0], cefExtensionReplacer.Replace(extension[1])))
+	}
+
+	header := []string{"CEF:0", "Casdoor", "Casdoor", "1.0", record.Action, record.Action, fmt.Sprintf("%d", severity)}
+	for i := 1; i < len(header); i++ {
+		header[i] = cefHeaderReplacer.Replace(header[i])
+	}
+
+	return fmt.Sprintf("%s|%s", strings.Join(header, "|"), strings.Join(extensionStrings, " "))
+}
+
+func getRecordExportItems(records []*casvisorsdk.Record) ([]*RecordExportItem, error) {
+	names := []string{}
+	for _, record := range records {
+		names = append(names, record.Name)
+	}
+
+	recordChains := []*RecordChain{}
+	if len(names) != 0 {
+		err := ormer.Engine.Where(builder.In("record_name", names)).Find(&recordChains)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	recordChainMap := map[string]*RecordChain{}
+	for _, recordChain := range recordChains {
+		recordChainMap[recordChain.Owner+"/"+recordChain.RecordName] = recordChain
+	}
+
+	items := []*RecordExportItem{}
+	for _, record := range records {
+		item := &RecordExportItem{Record: record}
+		if recordChain, ok := recordChainMap[record.Owner+"/"+record.Name]; ok {
+			item.Sequence = recordChain.Sequence
+			item.PrevHash = recordChain.PrevHash
+			item.Hash = recordChain.Hash
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// ExportRecords exports the organization's records in "jsonl" or "cef" format,
+// together with their chain hashes so that the export can be verified offline.
+func ExportRecords(owner string, format string) ([]byte, error) {
+	if format != "jsonl" && format != "cef" {
+		return nil, fmt.Errorf("unsupported record export format: %s", format)
+	}
+
+	var buffer bytes.Buffer
+	batchSize := conf.GetConfigBatchSize()
+	lastId := 0
+	for {
+		records := []*casvisorsdk.Record{}
+		err := ormer.Engine.Where("owner = ? and id > ?", owner, lastId).Asc("id").Limit(batchSize).Find(&records)
+		if err != nil {
+			return nil, err
+		}
+
+		items, err := getRecordExportItems(records)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range items {
+			if format == "jsonl" {
+				line, err := json.Marshal(item)
+				if err != nil {
+					return nil, err
+				}
+				buffer.Write(line)
+			} else {
+				buffer.WriteString(getRecordCefLine(item))
+			}
+			buffer.WriteString("\n")
+		}
+
+		if len(records) < batchSize {
+			break
+		}
+		lastId = records[len(records)-1].Id
+	}
+
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,133 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/beego/beego/logs"
+	"github.com/casvisor/casvisor-go-sdk/casvisorsdk"
+)
+
+// applyRecordRetention removes the organization's records that are older than its retention period.
+// The last removed chain entry is signed first, so that the remaining part of the chain keeps a trusted anchor,
+// and the chain is cut at that entry, which takes along the newer records chained before it.
+func applyRecordRetention(organization *Organization) (int64, error) {
+	if organization.RecordRetentionDays <= 0 {
+		return 0, nil
+	}
+
+	cutoffTime := time.Now().AddDate(0, 0, -organization.RecordRetentionDays).Format(time.RFC3339)
+
+	boundary := RecordChain{}
+	existed, err := ormer.Engine.Where("owner = ? and created_time < ?", organization.Name, cutoffTime).Desc("sequence").Limit(1).Get(&boundary)
+	if err != nil {
+		return 0, err
+	}
+	if !existed {
+		return 0, nil
+	}
+
+	err = addRecordCheckpoint(&boundary)
+	if err != nil {
+		return 0, err
+	}
+
+	recordChains := []*RecordChain{}
+	err = ormer.Engine.Where("owner = ? and sequence <= ? and created_time >= ?", organization.Name, boundary.Sequence, cutoffTime).Find(&recordChains)
+	if err != nil {
+		return 0, err
+	}
+
+	session := ormer.Engine.NewSession()
+	defer session.Close()
+
+	err = session.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	affected, err := session.Where("owner = ? and created_time < ?", organization.Name, cutoffTime).Delete(&casvisorsdk.Record{})
+	if err != nil {
+		session.Rollback()
+		return 0, err
+	}
+
+	for _, recordChain := range recordChains {
+		recordSession := session.Where("owner = ? and name = ?", recordChain.Owner, recordChain.RecordName)
+		if recordChain.RecordId != 0 {
+			recordSession = recordSession.And("id = ?", recordChain.RecordId)
+		}
+
+		var recordAffected int64
+		recordAffected, err = recordSession.Delete(&casvisorsdk.Record{})
+		if err != nil {
+			session.Rollback()
+			return 0, err
+		}
+		affected += recordAffected
+	}
+
+	_, err = session.Where("owner = ? and sequence <= ?", organization.Name, boundary.Sequence).Delete(&RecordChain{})
+	if err != nil {
+		session.Rollback()
+		return 0, err
+	}
+
+	err = session.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return affected, nil
+}
+
+const recordRetentionJobLease = "record-retention"
+
+func RunRecordRetentionJob() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for ; true; <-ticker.C {
+		// a single instance deletes and checkpoints the records, the others would race on the chains
+		isHolder, err := acquireJobLease(recordRetentionJobLease, 2*time.Hour)
+		if err != nil {
+			logs.Error(fmt.Sprintf("RunRecordRetentionJob() error: %s", err.Error()))
+			continue
+		}
+		if !isHolder {
+			continue
+		}
+
+		organizations, err := GetOrganizations("admin")
+		if err != nil {
+			logs.Error(fmt.Sprintf("RunRecordRetentionJob() error: %s", err.Error()))
+			continue
+		}
+
+		for _, organization := range organizations {
+			affected, err := applyRecordRetention(organization)
+			if err != nil {
+				logs.Error(fmt.Sprintf("RunRecordRetentionJob() error for organization %s: %s", organization.Name, err.Error()))
+				continue
+			}
+
+			if affected != 0 {
+				logs.Info(fmt.Sprintf("RunRecordRetentionJob() removed %d records of organization %s", affected, organization.Name))
+			}
+		}
+	}
+}
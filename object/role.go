@@ -17,11 +17,13 @@ package object
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/casdoor/casdoor/conf"
 
 	"github.com/casdoor/casdoor/util"
 	"github.com/xorm-io/core"
+	"github.com/xorm-io/xorm"
 )
 
 type Role struct {
@@ -36,6 +38,11 @@ type Role struct {
 	Roles     []string `xorm:"mediumtext" json:"roles"`
 	Domains   []string `xorm:"mediumtext" json:"domains"`
 	IsEnabled bool     `json:"isEnabled"`
+
+	// Grants are the time windows of the users, groups and roles above, the others are members with no end
+	Grants []*AccessGrant `xorm:"mediumtext" json:"grants"`
+	// NextGrantTime is when the policies of the grants change next, kept by the hooks of access_grant.go
+	NextGrantTime string `xorm:"varchar(100) index" json:"-"`
 }
 
 func GetRoleCount(owner, field, value string) (int64, error) {
@@ -98,6 +105,11 @@ func UpdateRole(id string, role *Role) (bool, error) {
 		return false, nil
 	}
 
+	err = checkAccessGrants(role.Grants, role.Users, role.Groups, role.Roles)
+	if err != nil {
+		return false, err
+	}
+
 	visited := map[string]struct{}{}
 
 	permissions, err := GetPermissionsByRole(id)
@@ -138,7 +150,14 @@ func UpdateRole(id string, role *Role) (bool, error) {
 		}
 	}
 
-	affected, err := ormer.Engine.ID(core.PK{owner, name}).AllCols().Update(role)
+	oldObject, err := getChangeEventObject(ormer.Engine, oldRole)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := updateWithChangeEvents(oldObject, role, func(session *xorm.Session) (int64, error) {
+		return session.ID(core.PK{owner, name}).AllCols().Update(role)
+	})
 	if err != nil {
 		return false, err
 	}
@@ -192,7 +211,12 @@ func UpdateRole(id string, role *Role) (bool, error) {
 }
 
 func AddRole(role *Role) (bool, error) {
-	affected, err := ormer.Engine.Insert(role)
+	err := checkAccessGrants(role.Grants, role.Users, role.Groups, role.Roles)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := insertWithChangeEvents(role)
 	if err != nil {
 		return false, err
 	}
@@ -204,12 +228,24 @@ func AddRoles(roles []*Role) bool {
 	if len(roles) == 0 {
 		return false
 	}
-	affected, err := ormer.Engine.Insert(roles)
-	if err != nil {
-		if !strings.Contains(err.Error(), "Duplicate entry") {
-			panic(err)
+
+	var insertErr error
+	affected, err := writeWithChangeEvents(func(session *xorm.Session) (int64, error) {
+		var affected int64
+		affected, insertErr = session.Insert(roles)
+		return affected, insertErr
+	}, func(session *xorm.Session) ([]*ChangeEvent, error) {
+		return newCreateChangeEvents(roles)
+	})
+	if insertErr != nil {
+		if !strings.Contains(insertErr.Error(), "Duplicate entry") {
+			panic(insertErr)
 		}
+	} else if err != nil {
+		// the roles are not added without their events
+		fmt.Printf("AddRoles() error: %s\n", err.Error())
 	}
+
 	return affected != 0
 }
 
@@ -239,7 +275,12 @@ func AddRolesInBatch(roles []*Role) bool {
 }
 
 func deleteRole(role *Role) (bool, error) {
-	affected, err := ormer.Engine.ID(core.PK{role.Owner, role.Name}).Delete(&Role{})
+	oldRole, err := getChangeEventObject(ormer.Engine, role)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := deleteWithChangeEvents(oldRole, role)
 	if err != nil {
 		return false, err
 	}
@@ -289,9 +330,12 @@ func getRolesByUserInternal(userId string) ([]*Role, error) {
 		return nil, err
 	}
 
+	now := time.Now()
 	res := []*Role{}
 	for _, role := range roles {
-		if util.InSlice(role.Users, userId) || util.HaveIntersection(role.Groups, user.Groups) {
+		users := getActiveMembers(role.Users, role.Grants, now)
+		groups := getActiveMembers(role.Groups, role.Grants, now)
+		if util.InSlice(users, userId) || util.HaveIntersection(groups, user.Groups) {
 			res = append(res, role)
 		}
 	}
@@ -359,6 +403,7 @@ func roleChangeTrigger(oldName string, newName string) error {
 		return err
 	}
 
+	permissionIds := []string{}
 	for _, permission := range permissions {
 		for j, u := range permission.Roles {
 			// u = organization/username
@@ -369,6 +414,7 @@ func roleChangeTrigger(oldName string, newName string) error {
 			owner, name := util.GetOwnerAndNameFromId(u)
 			if name == oldName {
 				permission.Roles[j] = util.GetId(owner, newName)
+				permissionIds = append(permissionIds, permission.GetId())
 			}
 		}
 		_, err = session.Where("name=?", permission.Name).And("owner=?", permission.Owner).Update(permission)
@@ -377,7 +423,16 @@ func roleChangeTrigger(oldName string, newName string) error {
 		}
 	}
 
-	return session.Commit()
+	err = session.Commit()
+	if err != nil {
+		return err
+	}
+
+	// the renamed role is in the grouping policies of the permissions
+	if len(permissionIds) > 0 {
+		refreshPermissionEnforcers(permissionEnforcerMessagePermission, permissionIds...)
+	}
+	return nil
 }
 
 func GetMaskedRoles(roles []*Role) []*Role {
@@ -18,7 +18,6 @@ import (
 	"bytes"
 	"compress/flate"
 	"crypto"
-	"crypto/rsa"
 	"encoding/base64"
 	"encoding/json"
 	"encoding/pem"
@@ -30,7 +29,6 @@ import (
 	"time"
 
 	"github.com/beevik/etree"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	saml "github.com/russellhaering/gosaml2"
 	dsig "github.com/russellhaering/goxmldsig"
@@ -66,11 +64,7 @@ func NewSamlResponse(application *Application, user *User, host string, certific
 	assertion.CreateAttr("IssueInstant", now)
 	assertion.CreateElement("saml:Issuer").SetText(host)
 	subject := assertion.CreateElement("saml:Subject")
-	nameIDValue := user.Name
-	if application.UseEmailAsSamlNameId {
-		nameIDValue = user.Email
-	}
-	subject.CreateElement("saml:NameID").SetText(nameIDValue)
+	subject.CreateElement("saml:NameID").SetText(GetSamlNameId(application, user))
 	subjectConfirmation := subject.CreateElement("saml:SubjectConfirmation")
 	subjectConfirmation.CreateAttr("Method", "urn:oasis:names:tc:SAML:2.0:cm:bearer")
 	subjectConfirmationData := subjectConfirmation.CreateElement("saml:SubjectConfirmationData")
@@ -130,15 +124,64 @@ func NewSamlResponse(application *Application, user *User, host string, certific
 	return samlResponse, nil
 }
 
-type X509Key struct {
-	X509Certificate string
-	PrivateKey      string
+// getCertSigningContext returns an XML signing context with the cert's signer, so that
+// SAML and CAS responses can be signed whether the private key is in the database or in a PKCS#11 token
+func getCertSigningContext(cert *Cert) (*dsig.SigningContext, error) {
+	block, _ := pem.Decode([]byte(cert.Certificate))
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode the certificate of the cert: %s", cert.GetId())
+	}
+
+	signer, err := getCertSigner(cert)
+	if err != nil {
+		return nil, err
+	}
+
+	return dsig.NewSigningContext(signer, [][]byte{block.Bytes})
 }
 
-func (x X509Key) GetKeyPair() (privateKey *rsa.PrivateKey, cert []byte, err error) {
-	cert, _ = base64.StdEncoding.DecodeString(x.X509Certificate)
-	privateKey, err = jwt.ParseRSAPrivateKeyFromPEM([]byte(x.PrivateKey))
-	return privateKey, cert, err
+// getSamlSigningContext returns the signing context for the SAML messages of the application
+func getSamlSigningContext(application *Application, cert *Cert) (*dsig.SigningContext, error) {
+	ctx, err := getCertSigningContext(cert)
+	if err != nil {
+		return nil, err
+	}
+	ctx.Hash = crypto.SHA1
+
+	if application.EnableSamlC14n10 {
+		ctx.Canonicalizer = dsig.MakeC14N10ExclusiveCanonicalizerWithPrefixList("")
+	}
+	return ctx, nil
+}
+
+// decodeSamlMessage decodes a base64 SAML message, which is also deflated when it comes by the HTTP-Redirect binding
+func decodeSamlMessage(message string) ([]byte, error) {
+	message = strings.ReplaceAll(message, " ", "+")
+	// base64 decode
+	defated, err := base64.StdEncoding.DecodeString(message)
+	if err != nil {
+		return nil, fmt.Errorf("err: Failed to decode SAML request, %s", err.Error())
+	}
+
+	if strings.Contains(string(defated), "xmlns:") {
+		return defated, nil
+	}
+
+	// decompress
+	var buffer bytes.Buffer
+	rdr := flate.NewReader(bytes.NewReader(defated))
+
+	for {
+		_, err = io.CopyN(&buffer, rdr, 1024)
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+	}
+
+	return buffer.Bytes(), nil
 }
 
 // IdpEntityDescriptor
@@ -178,9 +221,11 @@ type IdpSSODescriptor struct {
 	XMLName                    xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:metadata IDPSSODescriptor"`
 	ProtocolSupportEnumeration string   `xml:"protocolSupportEnumeration,attr"`
 	SigningKeyDescriptor       KeyDescriptor
-	NameIDFormats              []NameIDFormat      `xml:"NameIDFormat"`
-	SingleSignOnService        SingleSignOnService `xml:"SingleSignOnService"`
-	Attribute                  []Attribute         `xml:"Attribute"`
+	ArtifactResolutionService  ArtifactResolutionService `xml:"ArtifactResolutionService"`
+	SingleLogoutServices       []SingleLogoutService     `xml:"SingleLogoutService"`
+	NameIDFormats              []NameIDFormat            `xml:"NameIDFormat"`
+	SingleSignOnService        SingleSignOnService       `xml:"SingleSignOnService"`
+	Attribute                  []Attribute               `xml:"Attribute"`
 }
 
 type NameIDFormat struct {
@@ -194,6 +239,17 @@ type SingleSignOnService struct {
 	Location string `xml:"Location,attr"`
 }
 
+type SingleLogoutService struct {
+	Binding  string `xml:"Binding,attr"`
+	Location string `xml:"Location,attr"`
+}
+
+type ArtifactResolutionService struct {
+	Binding  string `xml:"Binding,attr"`
+	Location string `xml:"Location,attr"`
+	Index    int    `xml:"index,attr"`
+}
+
 type Attribute struct {
 	// XMLName      xml.Name
 	Xmlns        string   `xml:"xmlns,attr"`
@@ -232,6 +288,8 @@ func GetSamlMeta(application *Application, host string, enablePostBinding bool)
 		idpBinding = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect"
 	}
 
+	sloLocation := fmt.Sprintf("%s/api/saml/slo/%s/%s", originBackend, application.Owner, application.Name)
+
 	d := IdpEntityDescriptor{
 		XMLName: xml.Name{
 			Local: "md:EntityDescriptor",
@@ -251,6 +309,15 @@ func GetSamlMeta(application *Application, host string, enablePostBinding bool)
 					},
 				},
 			},
+			ArtifactResolutionService: ArtifactResolutionService{
+				Binding:  "urn:oasis:names:tc:SAML:2.0:bindings:SOAP",
+				Location: fmt.Sprintf("%s/api/saml/artifact/%s/%s", originBackend, application.Owner, application.Name),
+				Index:    0,
+			},
+			SingleLogoutServices: []SingleLogoutService{
+				{Binding: SamlRedirectBinding, Location: sloLocation},
+				{Binding: SamlPostBinding, Location: sloLocation},
+			},
 			NameIDFormats: []NameIDFormat{
 				{Value: "urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress"},
 				{Value: "urn:oasis:names:tc:SAML:2.0:nameid-format:persistent"},
@@ -277,34 +344,9 @@ func GetSamlMeta(application *Application, host string, enablePostBinding bool)
 func GetSamlResponse(application *Application, user *User, samlRequest string, host string) (string, string, string, error) {
 	// request type
 	method := "GET"
-	samlRequest = strings.ReplaceAll(samlRequest, " ", "+")
-	// base64 decode
-	defated, err := base64.StdEncoding.DecodeString(samlRequest)
+	requestByte, err := decodeSamlMessage(samlRequest)
 	if err != nil {
-		return "", "", "", fmt.Errorf("err: Failed to decode SAML request, %s", err.Error())
-	}
-
-	var requestByte []byte
-
-	if strings.Contains(string(defated), "xmlns:") {
-		requestByte = defated
-	} else {
-		// decompress
-		var buffer bytes.Buffer
-		rdr := flate.NewReader(bytes.NewReader(defated))
-
-		for {
-
-			_, err = io.CopyN(&buffer, rdr, 1024)
-			if err != nil {
-				if err == io.EOF {
-					break
-				}
-				return "", "", "", err
-			}
-		}
-
-		requestByte = buffer.Bytes()
+		return "", "", "", err
 	}
 
 	var authnRequest saml.AuthNRequest
@@ -350,15 +392,16 @@ func GetSamlResponse(application *Application, user *User, samlRequest string, h
 		return "", "", "", fmt.Errorf("err: NewSamlResponse() error, %s", err.Error())
 	}
 
-	randomKeyStore := &X509Key{
-		PrivateKey:      cert.PrivateKey,
-		X509Certificate: certificate,
+	ctx, err := getSamlSigningContext(application, cert)
+	if err != nil {
+		return "", "", "", err
 	}
-	ctx := dsig.NewDefaultSigningContext(randomKeyStore)
-	ctx.Hash = crypto.SHA1
 
-	if application.EnableSamlC14n10 {
-		ctx.Canonicalizer = dsig.MakeC14N10ExclusiveCanonicalizerWithPrefixList("")
+	if application.SamlAssertionEncryption != "" {
+		err = encryptSamlAssertion(application, ctx, samlResponse)
+		if err != nil {
+			return "", "", "", err
+		}
 	}
 
 	// signedXML, err := ctx.SignEnvelopedLimix(samlResponse)
@@ -380,6 +423,15 @@ func GetSamlResponse(application *Application, user *User, samlRequest string, h
 		return "", "", "", fmt.Errorf("err: Failed to serializes the SAML request into bytes, %s", err.Error())
 	}
 
+	// with the artifact binding, the browser only carries a reference and the SP fetches the response from the resolution endpoint
+	if application.EnableSamlArtifactBinding || authnRequest.ProtocolBinding == SamlArtifactBinding {
+		artifact, err := storeSamlArtifact(application, authnRequest.Issuer, originBackend, xmlBytes)
+		if err != nil {
+			return "", "", "", err
+		}
+		return artifact, authnRequest.AssertionConsumerServiceURL, SamlMethodArtifact, nil
+	}
+
 	// compress
 	if application.EnableSamlCompress {
 		flated := bytes.NewBuffer(nil)
@@ -0,0 +1,176 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"time"
+
+	"github.com/beevik/etree"
+)
+
+const (
+	SamlPostBinding     = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST"
+	SamlRedirectBinding = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect"
+	SamlArtifactBinding = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Artifact"
+	SamlMethodArtifact  = "Artifact"
+
+	samlArtifactTimeout = time.Minute * 5
+)
+
+// SamlArtifact is a SAML response waiting to be resolved by the SP it was issued for, an artifact can only be resolved once
+type SamlArtifact struct {
+	Application string
+	Issuer      string
+	Response    []byte
+}
+
+func getSamlArtifactKey(artifact string) string {
+	return "saml_artifact:" + artifact
+}
+
+type samlArtifactResolve struct {
+	XMLName  xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:protocol ArtifactResolve"`
+	ID       string   `xml:"ID,attr"`
+	Issuer   string   `xml:"urn:oasis:names:tc:SAML:2.0:assertion Issuer"`
+	Artifact string   `xml:"urn:oasis:names:tc:SAML:2.0:protocol Artifact"`
+}
+
+// newSamlArtifact returns a type 0x0004 artifact: type code, endpoint index, SHA-1 of the IdP entity ID and a random message handle
+func newSamlArtifact(entityId string) (string, error) {
+	artifact := make([]byte, 44)
+	binary.BigEndian.PutUint16(artifact[0:2], 4)
+	binary.BigEndian.PutUint16(artifact[2:4], 0)
+
+	sourceId := sha1.Sum([]byte(entityId))
+	copy(artifact[4:24], sourceId[:])
+
+	_, err := rand.Read(artifact[24:44])
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(artifact), nil
+}
+
+func storeSamlArtifact(application *Application, issuer string, entityId string, response []byte) (string, error) {
+	artifact, err := newSamlArtifact(entityId)
+	if err != nil {
+		return "", err
+	}
+
+	err = storeTicket(getSamlArtifactKey(artifact), &SamlArtifact{
+		Application: application.GetId(),
+		Issuer:      issuer,
+		Response:    response,
+	}, samlArtifactTimeout)
+	if err != nil {
+		return "", err
+	}
+	return artifact, nil
+}
+
+// ResolveSamlArtifact answers the SOAP ArtifactResolve of an SP with the SOAP ArtifactResponse carrying the SAML response.
+// Per the spec, an unknown, expired or foreign artifact gets an ArtifactResponse without a message.
+func ResolveSamlArtifact(application *Application, body []byte, host string) ([]byte, error) {
+	doc := etree.NewDocument()
+	err := doc.ReadFromBytes(body)
+	if err != nil {
+		return nil, fmt.Errorf("err: Failed to unmarshal ArtifactResolve, %s", err.Error())
+	}
+
+	// the values are only read from the signed element, another ArtifactResolve could be wrapped around it
+	resolveElements := doc.FindElements("./Envelope/Body/ArtifactResolve")
+	if len(resolveElements) != 1 {
+		return nil, fmt.Errorf("the SOAP body should have one ArtifactResolve, got: %d", len(resolveElements))
+	}
+
+	validated, err := verifySamlElement(application, resolveElements[0])
+	if err != nil {
+		return nil, err
+	}
+
+	validatedDoc := etree.NewDocument()
+	validatedDoc.SetRoot(validated)
+	validatedBytes, err := validatedDoc.WriteToBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	var resolve samlArtifactResolve
+	err = xml.Unmarshal(validatedBytes, &resolve)
+	if err != nil {
+		return nil, fmt.Errorf("err: Failed to unmarshal ArtifactResolve, %s", err.Error())
+	}
+	if resolve.Artifact == "" {
+		return nil, fmt.Errorf("the ArtifactResolve has no artifact")
+	}
+
+	var response []byte
+	var samlArtifact SamlArtifact
+	ok, err := takeTicket(getSamlArtifactKey(resolve.Artifact), &samlArtifact)
+	if err != nil {
+		return nil, err
+	}
+	if ok && samlArtifact.Application == application.GetId() && samlArtifact.Issuer == resolve.Issuer {
+		response = samlArtifact.Response
+	}
+
+	_, originBackend := getOriginFromHost(host)
+	artifactResponse := newSamlMessage("ArtifactResponse", "", originBackend)
+	artifactResponse.CreateAttr("InResponseTo", resolve.ID)
+	artifactResponse.CreateElement("samlp:Status").CreateElement("samlp:StatusCode").CreateAttr("Value", samlStatusSuccess)
+
+	if response != nil {
+		samlResponseDoc := etree.NewDocument()
+		err = samlResponseDoc.ReadFromBytes(response)
+		if err != nil {
+			return nil, err
+		}
+		artifactResponse.AddChild(samlResponseDoc.Root())
+	}
+
+	cert, err := getCertByApplication(application)
+	if err != nil {
+		return nil, err
+	}
+	if cert == nil {
+		return nil, fmt.Errorf("please set a cert for the application first")
+	}
+
+	ctx, err := getSamlSigningContext(application, cert)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := ctx.ConstructSignature(artifactResponse, true)
+	if err != nil {
+		return nil, err
+	}
+	artifactResponse.InsertChildAt(1, sig)
+
+	soapEnvelope := etree.NewElement("soap:Envelope")
+	soapEnvelope.CreateAttr("xmlns:soap", "http://schemas.xmlsoap.org/soap/envelope/")
+	soapEnvelope.CreateElement("soap:Body").AddChild(artifactResponse)
+
+	responseDoc := etree.NewDocument()
+	responseDoc.SetRoot(soapEnvelope)
+	return responseDoc.WriteToBytes()
+}
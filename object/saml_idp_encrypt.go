@@ -0,0 +1,177 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"github.com/beevik/etree"
+	dsig "github.com/russellhaering/goxmldsig"
+)
+
+const (
+	SamlEncryptionAes256Gcm = "AES256-GCM"
+	SamlEncryptionAes256Cbc = "AES256-CBC"
+)
+
+const (
+	xmlEncNamespace       = "http://www.w3.org/2001/04/xmlenc#"
+	xmlEncElementType     = "http://www.w3.org/2001/04/xmlenc#Element"
+	xmlEncAes256Gcm       = "http://www.w3.org/2009/xmlenc11#aes256-gcm"
+	xmlEncAes256Cbc       = "http://www.w3.org/2001/04/xmlenc#aes256-cbc"
+	xmlEncRsaOaepMgf1p    = "http://www.w3.org/2001/04/xmlenc#rsa-oaep-mgf1p"
+	xmlDsigSha1DigestAlgo = "http://www.w3.org/2000/09/xmldsig#sha1"
+)
+
+// parseSamlSpCertificate parses the SP certificate of the application, either as PEM or as the base64 DER found in metadata
+func parseSamlSpCertificate(application *Application) (*x509.Certificate, error) {
+	if application.SamlSpCertificate == "" {
+		return nil, fmt.Errorf("the application: %s has no SAML SP certificate", application.GetId())
+	}
+
+	var der []byte
+	block, _ := pem.Decode([]byte(application.SamlSpCertificate))
+	if block != nil {
+		der = block.Bytes
+	} else {
+		var err error
+		der, err = base64.StdEncoding.DecodeString(strings.Join(strings.Fields(application.SamlSpCertificate), ""))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode the SAML SP certificate of the application: %s", application.GetId())
+		}
+	}
+
+	return x509.ParseCertificate(der)
+}
+
+func encryptSamlData(method string, key []byte, plaintext []byte) ([]byte, string, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch method {
+	case SamlEncryptionAes256Gcm:
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, "", err
+		}
+
+		nonce := make([]byte, gcm.NonceSize())
+		_, err = rand.Read(nonce)
+		if err != nil {
+			return nil, "", err
+		}
+		return gcm.Seal(nonce, nonce, plaintext, nil), xmlEncAes256Gcm, nil
+	case SamlEncryptionAes256Cbc:
+		// XML Encryption padding, the last byte is the padding length
+		padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+		plaintext = append(plaintext, bytes.Repeat([]byte{byte(padding)}, padding)...)
+
+		res := make([]byte, aes.BlockSize+len(plaintext))
+		iv := res[:aes.BlockSize]
+		_, err = rand.Read(iv)
+		if err != nil {
+			return nil, "", err
+		}
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(res[aes.BlockSize:], plaintext)
+		return res, xmlEncAes256Cbc, nil
+	default:
+		return nil, "", fmt.Errorf("unsupported SAML assertion encryption: %s", method)
+	}
+}
+
+// encryptSamlAssertion signs the assertion of the response on its own, then replaces it with
+// an EncryptedAssertion for the SP certificate, as the signature of the response can't be checked
+// against an assertion the SP has not decrypted yet
+func encryptSamlAssertion(application *Application, ctx *dsig.SigningContext, samlResponse *etree.Element) error {
+	certificate, err := parseSamlSpCertificate(application)
+	if err != nil {
+		return err
+	}
+
+	publicKey, ok := certificate.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("the SAML SP certificate of the application: %s should have an RSA key to encrypt assertions", application.GetId())
+	}
+
+	assertion := samlResponse.SelectElement("saml:Assertion")
+	if assertion == nil {
+		return fmt.Errorf("the SAML response has no assertion to encrypt")
+	}
+
+	// the assertion is decrypted without the response around it, so it is signed as a document of its own
+	signedAssertion := assertion.Copy()
+	signedAssertion.CreateAttr("xmlns:saml", "urn:oasis:names:tc:SAML:2.0:assertion")
+	sig, err := ctx.ConstructSignature(signedAssertion, true)
+	if err != nil {
+		return err
+	}
+	signedAssertion.InsertChildAt(1, sig)
+
+	doc := etree.NewDocument()
+	doc.SetRoot(signedAssertion)
+	plaintext, err := doc.WriteToBytes()
+	if err != nil {
+		return err
+	}
+
+	key := make([]byte, 32)
+	_, err = rand.Read(key)
+	if err != nil {
+		return err
+	}
+
+	ciphertext, algorithm, err := encryptSamlData(application.SamlAssertionEncryption, key, plaintext)
+	if err != nil {
+		return err
+	}
+
+	encryptedKey, err := rsa.EncryptOAEP(sha1.New(), rand.Reader, publicKey, key, nil)
+	if err != nil {
+		return err
+	}
+
+	encryptedAssertion := etree.NewElement("saml:EncryptedAssertion")
+	encryptedData := encryptedAssertion.CreateElement("xenc:EncryptedData")
+	encryptedData.CreateAttr("xmlns:xenc", xmlEncNamespace)
+	encryptedData.CreateAttr("Type", xmlEncElementType)
+	encryptedData.CreateElement("xenc:EncryptionMethod").CreateAttr("Algorithm", algorithm)
+
+	keyInfo := encryptedData.CreateElement("ds:KeyInfo")
+	keyInfo.CreateAttr("xmlns:ds", "http://www.w3.org/2000/09/xmldsig#")
+	encryptedKeyElement := keyInfo.CreateElement("xenc:EncryptedKey")
+	keyEncryptionMethod := encryptedKeyElement.CreateElement("xenc:EncryptionMethod")
+	keyEncryptionMethod.CreateAttr("Algorithm", xmlEncRsaOaepMgf1p)
+	keyEncryptionMethod.CreateElement("ds:DigestMethod").CreateAttr("Algorithm", xmlDsigSha1DigestAlgo)
+	encryptedKeyElement.CreateElement("ds:KeyInfo").CreateElement("ds:X509Data").CreateElement("ds:X509Certificate").SetText(base64.StdEncoding.EncodeToString(certificate.Raw))
+	encryptedKeyElement.CreateElement("xenc:CipherData").CreateElement("xenc:CipherValue").SetText(base64.StdEncoding.EncodeToString(encryptedKey))
+
+	encryptedData.CreateElement("xenc:CipherData").CreateElement("xenc:CipherValue").SetText(base64.StdEncoding.EncodeToString(ciphertext))
+
+	samlResponse.RemoveChild(assertion)
+	samlResponse.AddChild(encryptedAssertion)
+	return nil
+}
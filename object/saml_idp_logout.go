@@ -0,0 +1,379 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/beevik/etree"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	dsig "github.com/russellhaering/goxmldsig"
+)
+
+const samlStatusSuccess = "urn:oasis:names:tc:SAML:2.0:status:Success"
+
+// the signature algorithms of the HTTP-Redirect binding, the signature value has the same format as in JWS
+var samlSigAlgs = map[string]jwt.SigningMethod{
+	"http://www.w3.org/2001/04/xmldsig-more#rsa-sha256":   jwt.SigningMethodRS256,
+	"http://www.w3.org/2001/04/xmldsig-more#rsa-sha384":   jwt.SigningMethodRS384,
+	"http://www.w3.org/2001/04/xmldsig-more#rsa-sha512":   jwt.SigningMethodRS512,
+	"http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha256": jwt.SigningMethodES256,
+	"http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha384": jwt.SigningMethodES384,
+	"http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha512": jwt.SigningMethodES512,
+}
+
+type SamlLogoutRequest struct {
+	XMLName      xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:protocol LogoutRequest"`
+	ID           string   `xml:"ID,attr"`
+	Issuer       string   `xml:"urn:oasis:names:tc:SAML:2.0:assertion Issuer"`
+	NameID       string   `xml:"urn:oasis:names:tc:SAML:2.0:assertion NameID"`
+	SessionIndex []string `xml:"urn:oasis:names:tc:SAML:2.0:protocol SessionIndex"`
+}
+
+type SamlLogoutResponse struct {
+	XMLName      xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:protocol LogoutResponse"`
+	ID           string   `xml:"ID,attr"`
+	InResponseTo string   `xml:"InResponseTo,attr"`
+	Issuer       string   `xml:"urn:oasis:names:tc:SAML:2.0:assertion Issuer"`
+	Status       struct {
+		StatusCode struct {
+			Value string `xml:"Value,attr"`
+		} `xml:"StatusCode"`
+	} `xml:"Status"`
+}
+
+func GetSamlNameId(application *Application, user *User) string {
+	if application.UseEmailAsSamlNameId {
+		return user.Email
+	}
+	return user.Name
+}
+
+func getSamlSigAlg(signer crypto.Signer) (string, jwt.SigningMethod, error) {
+	switch publicKey := signer.Public().(type) {
+	case *rsa.PublicKey:
+		return "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256", jwt.SigningMethodRS256, nil
+	case *ecdsa.PublicKey:
+		switch publicKey.Curve.Params().BitSize {
+		case 256:
+			return "http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha256", jwt.SigningMethodES256, nil
+		case 384:
+			return "http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha384", jwt.SigningMethodES384, nil
+		case 521:
+			return "http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha512", jwt.SigningMethodES512, nil
+		}
+	}
+	return "", nil, fmt.Errorf("the key of the cert can't sign SAML messages by the HTTP-Redirect binding")
+}
+
+func deflateSamlMessage(message []byte) ([]byte, error) {
+	flated := bytes.NewBuffer(nil)
+	writer, err := flate.NewWriter(flated, flate.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = writer.Write(message)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+	return flated.Bytes(), nil
+}
+
+// getSamlRedirectUrl returns the URL carrying the message by the HTTP-Redirect binding,
+// the query is signed with the cert as the XML signature would not survive the deflation
+func getSamlRedirectUrl(cert *Cert, location string, param string, message []byte, relayState string) (string, error) {
+	flated, err := deflateSamlMessage(message)
+	if err != nil {
+		return "", err
+	}
+
+	query := param + "=" + url.QueryEscape(base64.StdEncoding.EncodeToString(flated))
+	if relayState != "" {
+		query += "&RelayState=" + url.QueryEscape(relayState)
+	}
+
+	signer, err := getCertSigner(cert)
+	if err != nil {
+		return "", err
+	}
+
+	sigAlg, method, err := getSamlSigAlg(signer)
+	if err != nil {
+		return "", err
+	}
+	query += "&SigAlg=" + url.QueryEscape(sigAlg)
+
+	signature, err := signBySigner(method, signer, []byte(query))
+	if err != nil {
+		return "", err
+	}
+	query += "&Signature=" + url.QueryEscape(base64.StdEncoding.EncodeToString(signature))
+
+	if strings.Contains(location, "?") {
+		return location + "&" + query, nil
+	}
+	return location + "?" + query, nil
+}
+
+// verifySamlRedirectSignature checks the query signature of the HTTP-Redirect binding,
+// which is computed over the parameters as they were encoded by the sender
+func verifySamlRedirectSignature(certificate *x509.Certificate, rawQuery string, param string) error {
+	values := map[string]string{}
+	for _, pair := range strings.Split(rawQuery, "&") {
+		key, value, _ := strings.Cut(pair, "=")
+		values[key] = value
+	}
+
+	if values["Signature"] == "" || values["SigAlg"] == "" {
+		return fmt.Errorf("the SAML message should be signed")
+	}
+
+	signed := param + "=" + values[param]
+	if relayState, ok := values["RelayState"]; ok {
+		signed += "&RelayState=" + relayState
+	}
+	signed += "&SigAlg=" + values["SigAlg"]
+
+	sigAlg, err := url.QueryUnescape(values["SigAlg"])
+	if err != nil {
+		return err
+	}
+	method, ok := samlSigAlgs[sigAlg]
+	if !ok {
+		return fmt.Errorf("unsupported SAML signature algorithm: %s", sigAlg)
+	}
+
+	signatureBase64, err := url.QueryUnescape(values["Signature"])
+	if err != nil {
+		return err
+	}
+	signature, err := base64.StdEncoding.DecodeString(signatureBase64)
+	if err != nil {
+		return err
+	}
+
+	err = method.Verify(signed, signature, certificate.PublicKey)
+	if err != nil {
+		return fmt.Errorf("invalid signature of the SAML message: %s", err.Error())
+	}
+	return nil
+}
+
+// verifySamlElement checks the XML signature of a message from the SP and returns the signed element,
+// the values of the message have to be read from it. The messages are refused without the SP certificate.
+func verifySamlElement(application *Application, el *etree.Element) (*etree.Element, error) {
+	if application.SamlSpCertificate == "" {
+		return nil, fmt.Errorf("the application: %s has no SAML SP certificate to verify the signature of the SAML message", application.GetId())
+	}
+
+	certificate, err := parseSamlSpCertificate(application)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := dsig.NewDefaultValidationContext(&dsig.MemoryX509CertificateStore{Roots: []*x509.Certificate{certificate}})
+	validated, err := ctx.Validate(el)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature of the SAML message: %s", err.Error())
+	}
+	return validated, nil
+}
+
+// verifySamlMessage checks the signature of a message from the SP, in the query for the HTTP-Redirect binding and
+// in the XML for the other bindings, and returns the signed message to read the values from
+func verifySamlMessage(application *Application, message []byte, rawQuery string, param string) ([]byte, error) {
+	if application.SamlSpCertificate == "" {
+		return nil, fmt.Errorf("the application: %s has no SAML SP certificate to verify the signature of the SAML message", application.GetId())
+	}
+
+	if strings.Contains("&"+rawQuery, "&"+param+"=") {
+		certificate, err := parseSamlSpCertificate(application)
+		if err != nil {
+			return nil, err
+		}
+
+		// the signature covers the encoded message of the query, which the message has been decoded from
+		err = verifySamlRedirectSignature(certificate, rawQuery, param)
+		if err != nil {
+			return nil, err
+		}
+		return message, nil
+	}
+
+	doc := etree.NewDocument()
+	err := doc.ReadFromBytes(message)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Root() == nil {
+		return nil, fmt.Errorf("the SAML message is empty")
+	}
+
+	validated, err := verifySamlElement(application, doc.Root())
+	if err != nil {
+		return nil, err
+	}
+
+	validatedDoc := etree.NewDocument()
+	validatedDoc.SetRoot(validated)
+	return validatedDoc.WriteToBytes()
+}
+
+func newSamlMessage(tag string, destination string, host string) *etree.Element {
+	message := &etree.Element{
+		Space: "samlp",
+		Tag:   tag,
+	}
+	message.CreateAttr("xmlns:samlp", "urn:oasis:names:tc:SAML:2.0:protocol")
+	message.CreateAttr("xmlns:saml", "urn:oasis:names:tc:SAML:2.0:assertion")
+	message.CreateAttr("ID", fmt.Sprintf("_%s", uuid.New()))
+	message.CreateAttr("Version", "2.0")
+	message.CreateAttr("IssueInstant", time.Now().UTC().Format(time.RFC3339))
+	if destination != "" {
+		message.CreateAttr("Destination", destination)
+	}
+	message.CreateElement("saml:Issuer").SetText(host)
+	return message
+}
+
+func getSamlLogoutUrl(application *Application, message *etree.Element, param string, relayState string) (string, error) {
+	if application.SamlSloUrl == "" {
+		return "", fmt.Errorf("the application: %s has no SAML single logout URL", application.GetId())
+	}
+
+	cert, err := getCertByApplication(application)
+	if err != nil {
+		return "", err
+	}
+	if cert == nil {
+		return "", fmt.Errorf("please set a cert for the application first")
+	}
+
+	doc := etree.NewDocument()
+	doc.SetRoot(message)
+	xmlBytes, err := doc.WriteToBytes()
+	if err != nil {
+		return "", err
+	}
+
+	return getSamlRedirectUrl(cert, application.SamlSloUrl, param, xmlBytes, relayState)
+}
+
+// ParseSamlLogoutRequest parses and checks a LogoutRequest sent by the SP of the application
+func ParseSamlLogoutRequest(application *Application, samlRequest string, rawQuery string) (*SamlLogoutRequest, error) {
+	requestByte, err := decodeSamlMessage(samlRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	requestByte, err = verifySamlMessage(application, requestByte, rawQuery, "SAMLRequest")
+	if err != nil {
+		return nil, err
+	}
+
+	var request SamlLogoutRequest
+	err = xml.Unmarshal(requestByte, &request)
+	if err != nil {
+		return nil, fmt.Errorf("err: Failed to unmarshal LogoutRequest, please check the SAML request, %s", err.Error())
+	}
+
+	if !application.IsRedirectUriValid(request.Issuer) {
+		return nil, fmt.Errorf("err: Issuer URI: %s doesn't exist in the allowed Redirect URI list", request.Issuer)
+	}
+
+	return &request, nil
+}
+
+// ParseSamlLogoutResponse parses and checks the LogoutResponse the SP sends back after an IdP-initiated logout
+func ParseSamlLogoutResponse(application *Application, samlResponse string, rawQuery string) (*SamlLogoutResponse, error) {
+	responseByte, err := decodeSamlMessage(samlResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	responseByte, err = verifySamlMessage(application, responseByte, rawQuery, "SAMLResponse")
+	if err != nil {
+		return nil, err
+	}
+
+	var response SamlLogoutResponse
+	err = xml.Unmarshal(responseByte, &response)
+	if err != nil {
+		return nil, fmt.Errorf("err: Failed to unmarshal LogoutResponse, %s", err.Error())
+	}
+
+	if !application.IsRedirectUriValid(response.Issuer) {
+		return nil, fmt.Errorf("err: Issuer URI: %s doesn't exist in the allowed Redirect URI list", response.Issuer)
+	}
+
+	if response.Status.StatusCode.Value != samlStatusSuccess {
+		return nil, fmt.Errorf("the SP failed to log out: %s", response.Status.StatusCode.Value)
+	}
+
+	return &response, nil
+}
+
+// GetSamlLogoutResponseUrl returns the URL sending the LogoutResponse of an SP-initiated logout back to the SP
+func GetSamlLogoutResponseUrl(application *Application, request *SamlLogoutRequest, relayState string, host string) (string, error) {
+	_, originBackend := getOriginFromHost(host)
+
+	response := newSamlMessage("LogoutResponse", application.SamlSloUrl, originBackend)
+	response.CreateAttr("InResponseTo", request.ID)
+	response.CreateElement("samlp:Status").CreateElement("samlp:StatusCode").CreateAttr("Value", samlStatusSuccess)
+
+	return getSamlLogoutUrl(application, response, "SAMLResponse", relayState)
+}
+
+// GetSamlLogoutRequestUrl returns the URL sending a LogoutRequest for the user to the SP, for an IdP-initiated logout
+func GetSamlLogoutRequestUrl(application *Application, user *User, relayState string, host string) (string, error) {
+	_, originBackend := getOriginFromHost(host)
+
+	request := newSamlMessage("LogoutRequest", application.SamlSloUrl, originBackend)
+	request.CreateAttr("NotOnOrAfter", time.Now().UTC().Add(time.Minute*5).Format(time.RFC3339))
+	request.CreateElement("saml:NameID").SetText(GetSamlNameId(application, user))
+
+	return getSamlLogoutUrl(application, request, "SAMLRequest", relayState)
+}
+
+// GetSamlLogoutRedirectUrl returns where to send the user once the logout is done, the relay state only when it's an allowed redirect URI
+func GetSamlLogoutRedirectUrl(application *Application, relayState string, host string) string {
+	if relayState != "" && application.IsRedirectUriValid(relayState) {
+		return relayState
+	}
+	if application.HomepageUrl != "" {
+		return application.HomepageUrl
+	}
+
+	originFrontend, _ := getOriginFromHost(host)
+	return originFrontend
+}
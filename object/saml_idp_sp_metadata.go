@@ -0,0 +1,119 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"encoding/base64"
+	"encoding/pem"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// SamlSpMetadata is what the SAML settings of an application need from the metadata of its SP
+type SamlSpMetadata struct {
+	EntityId              string `json:"entityId"`
+	ReplyUrl              string `json:"replyUrl"`
+	SloUrl                string `json:"sloUrl"`
+	Certificate           string `json:"certificate"`
+	EnableArtifactBinding bool   `json:"enableArtifactBinding"`
+}
+
+type samlSpEndpoint struct {
+	Binding   string `xml:"Binding,attr"`
+	Location  string `xml:"Location,attr"`
+	IsDefault bool   `xml:"isDefault,attr"`
+}
+
+type samlSpEntityDescriptor struct {
+	XMLName         xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:metadata EntityDescriptor"`
+	EntityId        string   `xml:"entityID,attr"`
+	SpSsoDescriptor struct {
+		KeyDescriptors []struct {
+			Use         string `xml:"use,attr"`
+			Certificate string `xml:"KeyInfo>X509Data>X509Certificate"`
+		} `xml:"KeyDescriptor"`
+		SingleLogoutServices      []samlSpEndpoint `xml:"SingleLogoutService"`
+		AssertionConsumerServices []samlSpEndpoint `xml:"AssertionConsumerService"`
+	} `xml:"SPSSODescriptor"`
+}
+
+func getSamlSpAcs(endpoints []samlSpEndpoint) *samlSpEndpoint {
+	var res *samlSpEndpoint
+	for i, endpoint := range endpoints {
+		if endpoint.Binding != SamlPostBinding && endpoint.Binding != SamlArtifactBinding {
+			continue
+		}
+		if endpoint.IsDefault {
+			return &endpoints[i]
+		}
+		// the POST binding is preferred over the artifact binding when there is no default
+		if res == nil || (res.Binding != SamlPostBinding && endpoint.Binding == SamlPostBinding) {
+			res = &endpoints[i]
+		}
+	}
+	return res
+}
+
+// ParseSamlSpMetadata reads the ACS URL, the single logout URL and the certificate from the metadata of a SAML SP
+func ParseSamlSpMetadata(metadata string) (*SamlSpMetadata, error) {
+	var descriptor samlSpEntityDescriptor
+	err := xml.Unmarshal([]byte(metadata), &descriptor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the SP metadata: %s", err.Error())
+	}
+
+	if descriptor.EntityId == "" {
+		return nil, fmt.Errorf("the SP metadata has no entityID")
+	}
+
+	res := &SamlSpMetadata{EntityId: descriptor.EntityId}
+
+	acs := getSamlSpAcs(descriptor.SpSsoDescriptor.AssertionConsumerServices)
+	if acs == nil {
+		return nil, fmt.Errorf("the SP metadata has no AssertionConsumerService with the HTTP-POST or HTTP-Artifact binding")
+	}
+	res.ReplyUrl = acs.Location
+	res.EnableArtifactBinding = acs.Binding == SamlArtifactBinding
+
+	// the logout messages are sent by the HTTP-Redirect binding
+	for _, slo := range descriptor.SpSsoDescriptor.SingleLogoutServices {
+		if slo.Binding == SamlRedirectBinding {
+			res.SloUrl = slo.Location
+			break
+		}
+	}
+
+	// the same certificate is used to encrypt the assertions and check the SP signatures, the encryption one is preferred
+	certificate := ""
+	for _, keyDescriptor := range descriptor.SpSsoDescriptor.KeyDescriptors {
+		if keyDescriptor.Certificate == "" {
+			continue
+		}
+		if certificate == "" || keyDescriptor.Use == "encryption" {
+			certificate = keyDescriptor.Certificate
+		}
+	}
+
+	if certificate != "" {
+		der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(certificate), ""))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode the certificate of the SP metadata: %s", err.Error())
+		}
+		res.Certificate = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	}
+
+	return res, nil
+}
@@ -0,0 +1,241 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/xml"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/beevik/etree"
+	"github.com/russellhaering/gosaml2/types"
+)
+
+func newTestSamlCert(t *testing.T, cryptoAlgorithm string) *Cert {
+	cert := &Cert{Owner: "admin", Name: "cert-test", Type: "x509", CryptoAlgorithm: cryptoAlgorithm, BitSize: 2048, ExpireInYears: 1}
+	err := cert.populateContent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestEncryptSamlAssertion(t *testing.T) {
+	idpCert := newTestSamlCert(t, "RS256")
+	spCert := newTestSamlCert(t, "RS256")
+	spKeyPair, err := tls.X509KeyPair([]byte(spCert.Certificate), []byte(spCert.PrivateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, method := range []string{SamlEncryptionAes256Gcm, SamlEncryptionAes256Cbc} {
+		application := &Application{Owner: "admin", Name: "app-test", SamlSpCertificate: spCert.Certificate, SamlAssertionEncryption: method}
+
+		samlResponse := &etree.Element{Space: "samlp", Tag: "Response"}
+		samlResponse.CreateAttr("xmlns:samlp", "urn:oasis:names:tc:SAML:2.0:protocol")
+		samlResponse.CreateAttr("xmlns:saml", "urn:oasis:names:tc:SAML:2.0:assertion")
+		samlResponse.CreateElement("saml:Issuer").SetText("https://idp.example.com")
+		assertion := samlResponse.CreateElement("saml:Assertion")
+		assertion.CreateAttr("ID", "_assertion")
+		assertion.CreateElement("saml:Issuer").SetText("https://idp.example.com")
+		assertion.CreateElement("saml:Subject").CreateElement("saml:NameID").SetText("alice")
+
+		ctx, err := getSamlSigningContext(application, idpCert)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = encryptSamlAssertion(application, ctx, samlResponse)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if samlResponse.SelectElement("Assertion") != nil {
+			t.Fatal("the assertion should have been replaced")
+		}
+
+		doc := etree.NewDocument()
+		doc.SetRoot(samlResponse)
+		responseBytes, err := doc.WriteToBytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var response types.Response
+		err = xml.Unmarshal(responseBytes, &response)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(response.EncryptedAssertions) != 1 {
+			t.Fatalf("%s: the response should have an encrypted assertion", method)
+		}
+
+		decrypted, err := response.EncryptedAssertions[0].Decrypt(&spKeyPair)
+		if err != nil {
+			t.Fatalf("%s: %v", method, err)
+		}
+		if decrypted.Subject.NameID.Value != "alice" || decrypted.Signature == nil {
+			t.Fatalf("%s: unexpected decrypted assertion: %+v", method, decrypted)
+		}
+
+		// the assertion is signed on its own, so its signature holds once it is decrypted
+		plaintext, err := response.EncryptedAssertions[0].DecryptBytes(&spKeyPair)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = verifySamlMessage(&Application{SamlSpCertificate: idpCert.Certificate}, plaintext, "", "")
+		if err != nil {
+			t.Fatalf("%s: %v", method, err)
+		}
+	}
+}
+
+func TestSamlRedirectSignature(t *testing.T) {
+	for _, cryptoAlgorithm := range []string{"RS256", "ES256"} {
+		cert := newTestSamlCert(t, cryptoAlgorithm)
+		application := &Application{Owner: "admin", Name: "app-test", SamlSpCertificate: cert.Certificate}
+
+		redirectUrl, err := getSamlRedirectUrl(cert, "https://sp.example.com/slo?tenant=1", "SAMLRequest", []byte(`<samlp:LogoutRequest xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol"/>`), "https://sp.example.com/?a=b")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		u, err := url.Parse(redirectUrl)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		message, err := decodeSamlMessage(u.Query().Get("SAMLRequest"))
+		if err != nil || !strings.Contains(string(message), "LogoutRequest") {
+			t.Fatalf("unexpected message: %s, %v", message, err)
+		}
+
+		_, err = verifySamlMessage(application, message, u.RawQuery, "SAMLRequest")
+		if err != nil {
+			t.Fatalf("%s: %v", cryptoAlgorithm, err)
+		}
+
+		tampered := strings.Replace(u.RawQuery, "RelayState=", "RelayState=x", 1)
+		_, err = verifySamlMessage(application, message, tampered, "SAMLRequest")
+		if err == nil {
+			t.Fatalf("%s: the tampered query should not be verified", cryptoAlgorithm)
+		}
+
+		// without the SP certificate, the messages can't be trusted
+		_, err = verifySamlMessage(&Application{Owner: "admin", Name: "app-test"}, message, u.RawQuery, "SAMLRequest")
+		if err == nil {
+			t.Fatalf("%s: the message should be refused without the SP certificate", cryptoAlgorithm)
+		}
+	}
+}
+
+func TestVerifySamlElement(t *testing.T) {
+	cert := newTestSamlCert(t, "RS256")
+	application := &Application{Owner: "admin", Name: "app-test", SamlSpCertificate: cert.Certificate}
+
+	resolve := &etree.Element{Space: "samlp", Tag: "ArtifactResolve"}
+	resolve.CreateAttr("xmlns:samlp", "urn:oasis:names:tc:SAML:2.0:protocol")
+	resolve.CreateAttr("ID", "_resolve")
+	resolve.CreateElement("samlp:Artifact").SetText("signed")
+
+	ctx, err := getSamlSigningContext(application, cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signedResolve, err := ctx.SignEnveloped(resolve)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the messages are verified as read from the request
+	doc := etree.NewDocument()
+	doc.SetRoot(signedResolve)
+	signedBytes, err := doc.WriteToBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc = etree.NewDocument()
+	err = doc.ReadFromBytes(signedBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := doc.Root()
+
+	validated, err := verifySamlElement(application, signed.Copy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if validated.SelectElement("Artifact").Text() != "signed" {
+		t.Fatalf("unexpected validated element: %v", validated)
+	}
+
+	tampered := signed.Copy()
+	tampered.SelectElement("Artifact").SetText("forged")
+	_, err = verifySamlElement(application, tampered)
+	if err == nil {
+		t.Fatal("the tampered element should not be verified")
+	}
+
+	_, err = verifySamlElement(&Application{Owner: "admin", Name: "app-test"}, signed.Copy())
+	if err == nil {
+		t.Fatal("the element should be refused without the SP certificate")
+	}
+}
+
+func TestParseSamlSpMetadata(t *testing.T) {
+	cert := newTestSamlCert(t, "RS256")
+	certificate := strings.Join(strings.Split(strings.TrimSpace(cert.Certificate), "\n")[1:], "")
+	certificate = strings.TrimSuffix(certificate, "-----END CERTIFICATE-----")
+
+	metadata := `<md:EntityDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata" xmlns:ds="http://www.w3.org/2000/09/xmldsig#" entityID="https://sp.example.com/metadata">
+  <md:SPSSODescriptor protocolSupportEnumeration="urn:oasis:names:tc:SAML:2.0:protocol">
+    <md:KeyDescriptor use="encryption"><ds:KeyInfo><ds:X509Data><ds:X509Certificate>` + certificate + `</ds:X509Certificate></ds:X509Data></ds:KeyInfo></md:KeyDescriptor>
+    <md:SingleLogoutService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST" Location="https://sp.example.com/slo/post"/>
+    <md:SingleLogoutService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect" Location="https://sp.example.com/slo"/>
+    <md:AssertionConsumerService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Artifact" Location="https://sp.example.com/acs/artifact" index="0"/>
+    <md:AssertionConsumerService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST" Location="https://sp.example.com/acs" index="1"/>
+  </md:SPSSODescriptor>
+</md:EntityDescriptor>`
+
+	res, err := ParseSamlSpMetadata(metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.EntityId != "https://sp.example.com/metadata" || res.ReplyUrl != "https://sp.example.com/acs" || res.EnableArtifactBinding || res.SloUrl != "https://sp.example.com/slo" {
+		t.Fatalf("unexpected SP metadata: %+v", res)
+	}
+
+	_, err = parseSamlSpCertificate(&Application{SamlSpCertificate: res.Certificate})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewSamlArtifact(t *testing.T) {
+	artifact, err := newSamlArtifact("https://idp.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(artifact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 44 || data[0] != 0 || data[1] != 4 {
+		t.Fatalf("unexpected artifact: %x", data)
+	}
+}
@@ -15,7 +15,6 @@
 package object
 
 import (
-	"crypto/tls"
 	"crypto/x509"
 	"encoding/base64"
 	"fmt"
@@ -23,17 +22,96 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/beevik/etree"
 	"github.com/casdoor/casdoor/idp"
-	"github.com/mitchellh/mapstructure"
+	"github.com/casdoor/casdoor/util"
 
 	"github.com/casdoor/casdoor/i18n"
 	saml2 "github.com/russellhaering/gosaml2"
 	dsig "github.com/russellhaering/goxmldsig"
 )
 
+// samlUserInfoFields are the user mapping keys filling the user info, the other keys of the mapping go to its extra
+var samlUserInfoFields = map[string]bool{
+	"id":          true,
+	"username":    true,
+	"displayName": true,
+	"email":       true,
+	"phone":       true,
+	"countryCode": true,
+	"avatarUrl":   true,
+}
+
+// getSamlAttributeValue finds an attribute by its name or friendly name, a multi-valued attribute is joined by commas
+func getSamlAttributeValue(values saml2.Values, name string) (string, bool) {
+	for _, attr := range values {
+		if attr.Name != name && attr.FriendlyName != name {
+			continue
+		}
+
+		res := []string{}
+		for _, value := range attr.Values {
+			res = append(res, value.Value)
+		}
+		return strings.Join(res, ","), true
+	}
+	return "", false
+}
+
+// getSamlResponseIssuer reads the issuer of the response, or of its assertion, to tell which IdP of the provider sent it
+func getSamlResponseIssuer(samlResponse string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(samlResponse)
+	if err != nil {
+		return "", err
+	}
+
+	doc := etree.NewDocument()
+	err = doc.ReadFromBytes(data)
+	if err != nil {
+		return "", err
+	}
+	if doc.Root() == nil {
+		return "", fmt.Errorf("the SAML response is empty")
+	}
+
+	issuer := doc.Root().FindElement("./Issuer")
+	if issuer == nil {
+		issuer = doc.Root().FindElement("./Assertion/Issuer")
+	}
+	if issuer == nil {
+		return "", nil
+	}
+	return strings.TrimSpace(issuer.Text()), nil
+}
+
+// getSamlUserId namespaces the ID by the IdP when the provider trusts several of them, as in a federation,
+// so that an IdP can't assert the ID of a user of another IdP to sign in as them
+func getSamlUserId(idpCount int, entityId string, id string) string {
+	if idpCount <= 1 || id == "" {
+		return id
+	}
+	return entityId + "!" + id
+}
+
 func ParseSamlResponse(samlResponse string, provider *Provider, host string) (*idp.UserInfo, error) {
 	samlResponse, _ = url.QueryUnescape(samlResponse)
-	sp, err := buildSp(provider, samlResponse, host)
+
+	issuer, err := getSamlResponseIssuer(samlResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	idps, err := getSamlIdps(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	samlIdp, err := getSamlIdp(provider, issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	sp, err := buildSp(provider, samlIdp, samlResponse, host)
 	if err != nil {
 		return nil, err
 	}
@@ -43,54 +121,81 @@ func ParseSamlResponse(samlResponse string, provider *Provider, host string) (*i
 		return nil, err
 	}
 
-	userInfoMap := make(map[string]string)
+	userInfo := &idp.UserInfo{Extra: map[string]string{}}
+	userInfoMap := map[string]string{}
 	for spAttr, idpAttr := range provider.UserMapping {
-		for _, attr := range assertionInfo.Values {
-			if attr.Name == idpAttr {
-				userInfoMap[spAttr] = attr.Values[0].Value
-			}
+		value, ok := getSamlAttributeValue(assertionInfo.Values, idpAttr)
+		if !ok {
+			continue
+		}
+
+		if samlUserInfoFields[spAttr] {
+			userInfoMap[spAttr] = value
+		} else {
+			userInfo.Extra[spAttr] = value
 		}
 	}
-	userInfoMap["id"] = assertionInfo.NameID
 
-	customUserInfo := &idp.CustomUserInfo{}
-	err = mapstructure.Decode(userInfoMap, customUserInfo)
-	if err != nil {
-		return nil, err
+	// the name ID is the ID unless the provider maps a stable attribute to it, as IdPs of federations often send transient name IDs
+	if userInfoMap["id"] == "" {
+		userInfoMap["id"] = assertionInfo.NameID
 	}
-	userInfo := &idp.UserInfo{
-		Id:          customUserInfo.Id,
-		Username:    customUserInfo.Username,
-		DisplayName: customUserInfo.DisplayName,
-		Email:       customUserInfo.Email,
-		AvatarUrl:   customUserInfo.AvatarUrl,
+
+	userInfo.Id = getSamlUserId(len(idps), samlIdp.EntityId, userInfoMap["id"])
+	userInfo.Username = userInfoMap["username"]
+	userInfo.DisplayName = userInfoMap["displayName"]
+	userInfo.Email = userInfoMap["email"]
+	userInfo.Phone = userInfoMap["phone"]
+	userInfo.CountryCode = userInfoMap["countryCode"]
+	userInfo.AvatarUrl = userInfoMap["avatarUrl"]
+	if samlIdp.EntityId != "" {
+		userInfo.Extra["samlIssuer"] = samlIdp.EntityId
 	}
-	return userInfo, err
+	return userInfo, nil
 }
 
-func GenerateSamlRequest(id, relayState, host, lang string) (auth string, method string, err error) {
+// GenerateSamlRequest builds the AuthnRequest to the IdP with the entity ID, which is chosen by the IdP discovery when the provider has several IdPs
+func GenerateSamlRequest(id, entityId, relayState, host, lang string) (auth string, method string, err error) {
 	provider, err := GetProvider(id)
 	if err != nil {
 		return "", "", err
 	}
+	if provider == nil {
+		return "", "", fmt.Errorf(i18n.Translate(lang, "auth:The provider: %s does not exist"), id)
+	}
 	if provider.Category != "SAML" {
 		return "", "", fmt.Errorf(i18n.Translate(lang, "saml_sp:provider %s's category is not SAML"), provider.Name)
 	}
 
-	sp, err := buildSp(provider, "", host)
+	samlIdp, err := getSamlIdp(provider, entityId)
+	if err != nil {
+		return "", "", err
+	}
+
+	sp, err := buildSp(provider, samlIdp, "", host)
+	if err != nil {
+		return "", "", err
+	}
+
+	doc, err := sp.BuildAuthRequestDocumentNoSig()
 	if err != nil {
 		return "", "", err
 	}
 
 	if provider.EnableSignAuthnRequest {
-		post, err := sp.BuildAuthBodyPost(relayState)
+		err = signSamlAuthnRequest(provider, doc)
+		if err != nil {
+			return "", "", err
+		}
+
+		post, err := sp.BuildAuthBodyPostFromDocument(relayState, doc)
 		if err != nil {
 			return "", "", err
 		}
 		auth = string(post[:])
 		method = "POST"
 	} else {
-		auth, err = sp.BuildAuthURL(relayState)
+		auth, err = sp.BuildAuthURLFromDocument(relayState, doc)
 		if err != nil {
 			return "", "", err
 		}
@@ -99,10 +204,55 @@ func GenerateSamlRequest(id, relayState, host, lang string) (auth string, method
 	return auth, method, nil
 }
 
-func buildSp(provider *Provider, samlResponse string, host string) (*saml2.SAMLServiceProvider, error) {
+// getSamlSpCert returns the cert signing the AuthnRequests of the provider, the built-in cert is used when none is chosen
+func getSamlSpCert(provider *Provider) (*Cert, error) {
+	if provider.Cert == "" {
+		cert, err := GetDefaultCert()
+		if err != nil {
+			return nil, err
+		}
+		if cert == nil {
+			return nil, fmt.Errorf("please set a cert for the provider: %s to sign the SAML requests", provider.GetId())
+		}
+		return cert, nil
+	}
+
+	cert, err := GetCert(util.GetId(provider.Owner, provider.Cert))
+	if err != nil {
+		return nil, err
+	}
+	if cert == nil {
+		return nil, fmt.Errorf("the cert: %s does not exist", provider.Cert)
+	}
+	return cert, nil
+}
+
+// signSamlAuthnRequest signs the AuthnRequest with the cert's signer, so that the key can also be in a PKCS#11 token.
+// The signature goes right after the issuer, as the schema wants.
+func signSamlAuthnRequest(provider *Provider, doc *etree.Document) error {
+	cert, err := getSamlSpCert(provider)
+	if err != nil {
+		return err
+	}
+
+	ctx, err := getCertSigningContext(cert)
+	if err != nil {
+		return err
+	}
+
+	authnRequest := doc.Root()
+	sig, err := ctx.ConstructSignature(authnRequest, true)
+	if err != nil {
+		return err
+	}
+	authnRequest.InsertChildAt(1, sig)
+	return nil
+}
+
+func buildSp(provider *Provider, samlIdp *SamlIdp, samlResponse string, host string) (*saml2.SAMLServiceProvider, error) {
 	_, origin := getOriginFromHost(host)
 
-	certStore, err := buildSpCertificateStore(provider, samlResponse)
+	certStore, err := buildSpCertificateStore(provider, samlIdp, samlResponse)
 	if err != nil {
 		return nil, err
 	}
@@ -115,54 +265,41 @@ func buildSp(provider *Provider, samlResponse string, host string) (*saml2.SAMLS
 		SPKeyStore:                  dsig.RandomKeyStoreForTest(),
 	}
 
-	if provider.Endpoint != "" {
-		sp.IdentityProviderSSOURL = provider.Endpoint
-		sp.IdentityProviderIssuer = provider.IssuerUrl
-	}
-	if provider.EnableSignAuthnRequest {
-		sp.SignAuthnRequests = true
-		sp.SPKeyStore, err = buildSpKeyStore()
-		if err != nil {
-			return nil, err
-		}
+	if samlIdp.SsoUrl != "" {
+		sp.IdentityProviderSSOURL = samlIdp.SsoUrl
+		sp.IdentityProviderIssuer = samlIdp.EntityId
 	}
 
 	return sp, nil
 }
 
-func buildSpKeyStore() (dsig.X509KeyStore, error) {
-	keyPair, err := tls.LoadX509KeyPair("object/token_jwt_key.pem", "object/token_jwt_key.key")
+func buildSpCertificateStore(provider *Provider, samlIdp *SamlIdp, samlResponse string) (certStore dsig.MemoryX509CertificateStore, err error) {
+	// every certificate of the IdP is trusted during a key rollover
+	roots, err := getSamlIdpCertificates(samlIdp)
 	if err != nil {
-		return nil, err
+		return dsig.MemoryX509CertificateStore{}, err
 	}
-	return &dsig.TLSCertKeyStore{
-		PrivateKey:  keyPair.PrivateKey,
-		Certificate: keyPair.Certificate,
-	}, nil
-}
 
-func buildSpCertificateStore(provider *Provider, samlResponse string) (certStore dsig.MemoryX509CertificateStore, err error) {
-	certEncodedData := ""
-	if samlResponse != "" {
-		certEncodedData, err = getCertificateFromSamlResponse(samlResponse, provider.Type)
+	if len(roots) == 0 && samlResponse != "" {
+		// without any configured certificate, the one embedded in the response is used like before
+		certEncodedData, err := getCertificateFromSamlResponse(samlResponse, provider.Type)
 		if err != nil {
-			return
+			return dsig.MemoryX509CertificateStore{}, err
 		}
-	} else if provider.IdP != "" {
-		certEncodedData = provider.IdP
-	}
 
-	certData, err := base64.StdEncoding.DecodeString(certEncodedData)
-	if err != nil {
-		return dsig.MemoryX509CertificateStore{}, err
-	}
-	idpCert, err := x509.ParseCertificate(certData)
-	if err != nil {
-		return dsig.MemoryX509CertificateStore{}, err
+		certData, err := base64.StdEncoding.DecodeString(certEncodedData)
+		if err != nil {
+			return dsig.MemoryX509CertificateStore{}, err
+		}
+		idpCert, err := x509.ParseCertificate(certData)
+		if err != nil {
+			return dsig.MemoryX509CertificateStore{}, err
+		}
+		roots = append(roots, idpCert)
 	}
 
 	certStore = dsig.MemoryX509CertificateStore{
-		Roots: []*x509.Certificate{idpCert},
+		Roots: roots,
 	}
 	return certStore, nil
 }
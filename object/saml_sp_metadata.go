@@ -0,0 +1,512 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/beego/beego/logs"
+	"github.com/beevik/etree"
+	"github.com/casdoor/casdoor/proxy"
+	dsig "github.com/russellhaering/goxmldsig"
+	"github.com/xorm-io/core"
+)
+
+const (
+	defaultSamlMetadataRefreshInterval = 24
+	samlMetadataFetchTimeout           = time.Minute
+	maxSamlIdpDiscoveryResults         = 50
+	// the aggregates of the biggest federations are about 100 MB
+	maxSamlMetadataSize = 200 << 20
+	// how long the last metadata without a validUntil is kept when its URL can't be fetched
+	maxSamlMetadataStaleDuration = 7 * 24 * time.Hour
+)
+
+// SamlIdp is an IdP found in the metadata of a SAML provider, an aggregate metadata of a federation can have thousands of them
+type SamlIdp struct {
+	EntityId     string   `json:"entityId"`
+	DisplayName  string   `json:"displayName"`
+	Logo         string   `json:"logo"`
+	SsoUrl       string   `json:"-"`
+	Certificates []string `json:"-"`
+}
+
+type samlIdpMetadataCache struct {
+	Idps        []*SamlIdp
+	UpdatedTime time.Time
+	// ValidUntil is when the metadata expires, the last fetch plus maxSamlMetadataStaleDuration when it has no validUntil
+	ValidUntil time.Time
+}
+
+// samlIdpMetadataMap keeps the IdPs fetched from the metadata URL of each provider, the aggregates are too big for the provider table
+var samlIdpMetadataMap = sync.Map{}
+
+type samlIdpMetadataNode struct {
+	XMLName          xml.Name
+	EntityId         string `xml:"entityID,attr"`
+	ValidUntil       string `xml:"validUntil,attr"`
+	IdpSsoDescriptor *struct {
+		KeyDescriptors []struct {
+			Use          string   `xml:"use,attr"`
+			Certificates []string `xml:"KeyInfo>X509Data>X509Certificate"`
+		} `xml:"KeyDescriptor"`
+		SingleSignOnServices []samlSpEndpoint `xml:"SingleSignOnService"`
+		DisplayNames         []struct {
+			Lang  string `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"Extensions>UIInfo>DisplayName"`
+		Logos []string `xml:"Extensions>UIInfo>Logo"`
+	} `xml:"IDPSSODescriptor"`
+	EntityDescriptors   []samlIdpMetadataNode `xml:"EntityDescriptor"`
+	EntitiesDescriptors []samlIdpMetadataNode `xml:"EntitiesDescriptor"`
+}
+
+func isSamlMetadataExpired(validUntil string, now time.Time) bool {
+	if validUntil == "" {
+		return false
+	}
+
+	t, err := time.Parse(time.RFC3339, validUntil)
+	return err == nil && t.Before(now)
+}
+
+func getSamlIdpFromNode(node *samlIdpMetadataNode) *SamlIdp {
+	descriptor := node.IdpSsoDescriptor
+	res := &SamlIdp{EntityId: node.EntityId, DisplayName: node.EntityId}
+
+	// the HTTP-Redirect binding is preferred, as the AuthnRequest is only sent by POST when it is signed
+	for _, sso := range descriptor.SingleSignOnServices {
+		if sso.Binding == SamlRedirectBinding || (res.SsoUrl == "" && sso.Binding == SamlPostBinding) {
+			res.SsoUrl = sso.Location
+		}
+	}
+
+	// all the signing certificates are trusted, so that the IdP can roll its key over by publishing the next one in advance
+	for _, keyDescriptor := range descriptor.KeyDescriptors {
+		if keyDescriptor.Use != "" && keyDescriptor.Use != "signing" {
+			continue
+		}
+		for _, certificate := range keyDescriptor.Certificates {
+			certificate = strings.Join(strings.Fields(certificate), "")
+			if certificate != "" {
+				res.Certificates = append(res.Certificates, certificate)
+			}
+		}
+	}
+
+	for i, displayName := range descriptor.DisplayNames {
+		if i == 0 || displayName.Lang == "en" {
+			res.DisplayName = strings.TrimSpace(displayName.Value)
+		}
+	}
+	if len(descriptor.Logos) != 0 {
+		res.Logo = strings.TrimSpace(descriptor.Logos[0])
+	}
+
+	return res
+}
+
+func collectSamlIdps(node *samlIdpMetadataNode, now time.Time, idps []*SamlIdp) []*SamlIdp {
+	if isSamlMetadataExpired(node.ValidUntil, now) {
+		return idps
+	}
+
+	if node.IdpSsoDescriptor != nil && node.EntityId != "" {
+		idps = append(idps, getSamlIdpFromNode(node))
+	}
+
+	for i := range node.EntityDescriptors {
+		idps = collectSamlIdps(&node.EntityDescriptors[i], now, idps)
+	}
+	for i := range node.EntitiesDescriptors {
+		idps = collectSamlIdps(&node.EntitiesDescriptors[i], now, idps)
+	}
+	return idps
+}
+
+// verifySamlMetadata checks the signature of the metadata against the certificate of its publisher, e.g. a federation operator
+func verifySamlMetadata(metadata []byte, certificate string) ([]byte, error) {
+	block, _ := pem.Decode([]byte(certificate))
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode the metadata signing certificate")
+	}
+	x509Cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := etree.NewDocument()
+	err = doc.ReadFromBytes(metadata)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Root() == nil {
+		return nil, fmt.Errorf("the SAML metadata is empty")
+	}
+
+	ctx := dsig.NewDefaultValidationContext(&dsig.MemoryX509CertificateStore{Roots: []*x509.Certificate{x509Cert}})
+	validated, err := ctx.Validate(doc.Root())
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify the signature of the SAML metadata: %s", err.Error())
+	}
+
+	validatedDoc := etree.NewDocument()
+	validatedDoc.SetRoot(validated)
+	return validatedDoc.WriteToBytes()
+}
+
+// parseSamlIdpMetadata returns the IdPs of an EntityDescriptor or of an aggregate EntitiesDescriptor
+func parseSamlIdpMetadata(metadata []byte, certificate string) ([]*SamlIdp, error) {
+	idps, _, err := parseSamlIdpMetadataWithValidUntil(metadata, certificate)
+	return idps, err
+}
+
+// parseSamlIdpMetadataWithValidUntil is parseSamlIdpMetadata also returning the validUntil of the metadata, zero when it has none
+func parseSamlIdpMetadataWithValidUntil(metadata []byte, certificate string) ([]*SamlIdp, time.Time, error) {
+	if certificate != "" {
+		var err error
+		metadata, err = verifySamlMetadata(metadata, certificate)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+	}
+
+	var node samlIdpMetadataNode
+	err := xml.Unmarshal(metadata, &node)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to parse the SAML metadata: %s", err.Error())
+	}
+
+	if node.XMLName.Local != "EntityDescriptor" && node.XMLName.Local != "EntitiesDescriptor" {
+		return nil, time.Time{}, fmt.Errorf("the SAML metadata should be an EntityDescriptor or an EntitiesDescriptor, got: %s", node.XMLName.Local)
+	}
+
+	now := time.Now()
+	if isSamlMetadataExpired(node.ValidUntil, now) {
+		return nil, time.Time{}, fmt.Errorf("the SAML metadata has expired at: %s", node.ValidUntil)
+	}
+
+	var validUntil time.Time
+	if node.ValidUntil != "" {
+		validUntil, _ = time.Parse(time.RFC3339, node.ValidUntil)
+	}
+
+	idps := collectSamlIdps(&node, now, nil)
+	if len(idps) == 0 {
+		return nil, time.Time{}, fmt.Errorf("the SAML metadata has no IdP")
+	}
+
+	sort.SliceStable(idps, func(i, j int) bool {
+		return strings.ToLower(idps[i].DisplayName) < strings.ToLower(idps[j].DisplayName)
+	})
+	return idps, validUntil, nil
+}
+
+// getTlsPin returns the pin of a certificate: the base64 SHA-256 of its public key, as in HPKP
+func getTlsPin(certificate *x509.Certificate) string {
+	hash := sha256.Sum256(certificate.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// checkTlsPin checks that a certificate of the TLS chain of the response has the pin, so that the CA can also be pinned
+func checkTlsPin(resp *http.Response, tlsPin string) error {
+	if resp.TLS == nil {
+		return fmt.Errorf("the SAML metadata URL should use HTTPS to be checked against the TLS pin")
+	}
+
+	for _, certificate := range resp.TLS.PeerCertificates {
+		if getTlsPin(certificate) == tlsPin {
+			return nil
+		}
+	}
+	return fmt.Errorf("the TLS certificate of the SAML metadata URL does not match the TLS pin")
+}
+
+// fetchSamlMetadata fetches the metadata, checking the TLS pin when there is one
+func fetchSamlMetadata(metadataUrl string, tlsPin string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), samlMetadataFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadataUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := proxy.DefaultHttpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch the SAML metadata from: %s, status: %s", metadataUrl, resp.Status)
+	}
+
+	if tlsPin != "" {
+		err = checkTlsPin(resp, tlsPin)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	metadata, err := io.ReadAll(io.LimitReader(resp.Body, maxSamlMetadataSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(metadata) > maxSamlMetadataSize {
+		return nil, fmt.Errorf("the SAML metadata from: %s exceeds %d bytes", metadataUrl, maxSamlMetadataSize)
+	}
+	return metadata, nil
+}
+
+func getSamlMetadataRefreshInterval(provider *Provider) time.Duration {
+	if provider.MetadataRefreshInterval <= 0 {
+		return time.Hour * defaultSamlMetadataRefreshInterval
+	}
+	return time.Hour * time.Duration(provider.MetadataRefreshInterval)
+}
+
+// FetchSamlIdpMetadata fetches the metadata URL of the provider. When it describes a single IdP,
+// the metadata, SSO URL, issuer and certificates of the provider are filled from it.
+func FetchSamlIdpMetadata(provider *Provider) ([]*SamlIdp, error) {
+	idps, _, err := fetchSamlIdpMetadata(provider)
+	return idps, err
+}
+
+// fetchSamlIdpMetadata is FetchSamlIdpMetadata also returning the validUntil of the metadata. The metadata replaces
+// the trusted certificates of the IdPs, so it has to be authenticated: by its signature or by the pinned TLS key of its URL.
+func fetchSamlIdpMetadata(provider *Provider) ([]*SamlIdp, time.Time, error) {
+	if provider.MetadataUrl == "" {
+		return nil, time.Time{}, fmt.Errorf("the provider: %s has no metadata URL", provider.GetId())
+	}
+	if strings.TrimSpace(provider.MetadataCertificate) == "" && strings.TrimSpace(provider.MetadataTlsPin) == "" {
+		return nil, time.Time{}, fmt.Errorf("the metadata URL of the provider: %s needs a metadata certificate or a TLS pin to be trusted", provider.GetId())
+	}
+
+	// with a signature, the pin is an additional check when set
+	metadata, err := fetchSamlMetadata(provider.MetadataUrl, strings.TrimSpace(provider.MetadataTlsPin))
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	idps, validUntil, err := parseSamlIdpMetadataWithValidUntil(metadata, provider.MetadataCertificate)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	if len(idps) == 1 {
+		provider.Metadata = string(metadata)
+		provider.Endpoint = idps[0].SsoUrl
+		provider.IssuerUrl = idps[0].EntityId
+		provider.IdP = strings.Join(idps[0].Certificates, "\n")
+	}
+	return idps, validUntil, nil
+}
+
+// refreshSamlIdpMetadata fetches the metadata of a saved provider and keeps its IdPs, which are also saved for a single IdP
+func refreshSamlIdpMetadata(provider *Provider) ([]*SamlIdp, error) {
+	idps, validUntil, err := fetchSamlIdpMetadata(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if validUntil.IsZero() {
+		validUntil = now.Add(maxSamlMetadataStaleDuration)
+	}
+	samlIdpMetadataMap.Store(provider.GetId(), &samlIdpMetadataCache{Idps: idps, UpdatedTime: now, ValidUntil: validUntil})
+
+	if len(idps) == 1 {
+		_, err = ormer.Engine.ID(core.PK{provider.Owner, provider.Name}).Cols("metadata", "endpoint", "issuer_url", "id_p").Update(provider)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return idps, nil
+}
+
+// getSamlIdps returns the IdPs a SAML provider trusts, from its metadata URL, its metadata or its IdP fields in this order
+func getSamlIdps(provider *Provider) ([]*SamlIdp, error) {
+	if provider.MetadataUrl != "" {
+		value, ok := samlIdpMetadataMap.Load(provider.GetId())
+		var cache *samlIdpMetadataCache
+		if ok && time.Now().Before(value.(*samlIdpMetadataCache).ValidUntil) {
+			cache = value.(*samlIdpMetadataCache)
+		}
+		if cache != nil && time.Since(cache.UpdatedTime) < getSamlMetadataRefreshInterval(provider) {
+			return cache.Idps, nil
+		}
+
+		idps, err := refreshSamlIdpMetadata(provider)
+		if err != nil {
+			// a federation being down for a while should not break the login, the last metadata is kept until it expires
+			if cache != nil {
+				logs.Warning(fmt.Sprintf("getSamlIdps() failed to refresh the metadata of provider %s: %s", provider.GetId(), err.Error()))
+				return cache.Idps, nil
+			}
+			return nil, err
+		}
+		return idps, nil
+	}
+
+	if strings.TrimSpace(provider.Metadata) != "" {
+		idps, err := parseSamlIdpMetadata([]byte(provider.Metadata), provider.MetadataCertificate)
+		if err == nil {
+			// the fields of the provider can be edited after the metadata has been parsed, they take precedence
+			if len(idps) == 1 && provider.Endpoint != "" {
+				idps[0].SsoUrl = provider.Endpoint
+			}
+			return idps, nil
+		}
+		if provider.Endpoint == "" {
+			return nil, err
+		}
+	}
+
+	res := &SamlIdp{EntityId: provider.IssuerUrl, DisplayName: provider.DisplayName, SsoUrl: provider.Endpoint, Certificates: splitSamlIdpCertificates(provider.IdP)}
+	return []*SamlIdp{res}, nil
+}
+
+// splitSamlIdpCertificates reads the IdP certificates of a provider, one per line, while
+// still accepting a single certificate that has been pasted with line breaks
+func splitSamlIdpCertificates(idP string) []string {
+	certificates := strings.Fields(idP)
+	for _, certificate := range certificates {
+		der, err := base64.StdEncoding.DecodeString(certificate)
+		if err != nil {
+			return []string{strings.Join(certificates, "")}
+		}
+		_, err = x509.ParseCertificate(der)
+		if err != nil {
+			return []string{strings.Join(certificates, "")}
+		}
+	}
+	return certificates
+}
+
+// getSamlIdp returns the IdP of the provider with the entity ID, which can be empty when the provider only has one IdP
+func getSamlIdp(provider *Provider, entityId string) (*SamlIdp, error) {
+	idps, err := getSamlIdps(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	if entityId == "" {
+		if len(idps) == 1 {
+			return idps[0], nil
+		}
+		return nil, fmt.Errorf("the provider: %s has %d IdPs, please choose one of them", provider.GetId(), len(idps))
+	}
+
+	for _, idp := range idps {
+		if idp.EntityId == entityId {
+			return idp, nil
+		}
+	}
+
+	// a single IdP may have been configured by hand without its entity ID
+	if len(idps) == 1 && idps[0].EntityId == "" {
+		return idps[0], nil
+	}
+	return nil, fmt.Errorf("the IdP: %s is not trusted by the provider: %s", entityId, provider.GetId())
+}
+
+func getSamlIdpCertificates(idp *SamlIdp) ([]*x509.Certificate, error) {
+	res := []*x509.Certificate{}
+	for _, certificate := range idp.Certificates {
+		der, err := base64.StdEncoding.DecodeString(certificate)
+		if err != nil {
+			return nil, err
+		}
+
+		x509Cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, x509Cert)
+	}
+	return res, nil
+}
+
+// GetSamlIdps lists the IdPs of a SAML provider whose entity ID or display name contains the query, for the IdP discovery of the login page
+func GetSamlIdps(providerId string, query string) ([]*SamlIdp, error) {
+	provider, err := GetProvider(providerId)
+	if err != nil {
+		return nil, err
+	}
+	if provider == nil || provider.Category != "SAML" {
+		return nil, fmt.Errorf("the SAML provider: %s does not exist", providerId)
+	}
+
+	idps, err := getSamlIdps(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	query = strings.ToLower(strings.TrimSpace(query))
+	res := []*SamlIdp{}
+	for _, idp := range idps {
+		if query != "" && !strings.Contains(strings.ToLower(idp.DisplayName), query) && !strings.Contains(strings.ToLower(idp.EntityId), query) {
+			continue
+		}
+
+		res = append(res, idp)
+		if len(res) == maxSamlIdpDiscoveryResults {
+			break
+		}
+	}
+	return res, nil
+}
+
+func RunSamlMetadataRefreshJob() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for ; true; <-ticker.C {
+		providers, err := GetGlobalProviders()
+		if err != nil {
+			logs.Error(fmt.Sprintf("RunSamlMetadataRefreshJob() error: %s", err.Error()))
+			continue
+		}
+
+		for _, provider := range providers {
+			if provider.Category != "SAML" || provider.MetadataUrl == "" {
+				continue
+			}
+
+			value, ok := samlIdpMetadataMap.Load(provider.GetId())
+			if ok && time.Since(value.(*samlIdpMetadataCache).UpdatedTime) < getSamlMetadataRefreshInterval(provider) {
+				continue
+			}
+
+			_, err = refreshSamlIdpMetadata(provider)
+			if err != nil {
+				logs.Error(fmt.Sprintf("RunSamlMetadataRefreshJob() error for provider %s: %s", provider.GetId(), err.Error()))
+			}
+		}
+	}
+}
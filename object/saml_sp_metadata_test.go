@@ -0,0 +1,154 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/beevik/etree"
+)
+
+func getTestSamlCertificate(t *testing.T) string {
+	cert := newTestSamlCert(t, "RS256")
+	lines := strings.Split(strings.TrimSpace(cert.Certificate), "\n")
+	return strings.Join(lines[1:len(lines)-1], "")
+}
+
+func getTestSamlIdpDescriptor(entityId string, displayName string, validUntil string, certificates ...string) string {
+	keyDescriptors := ""
+	for _, certificate := range certificates {
+		keyDescriptors += `<md:KeyDescriptor use="signing"><ds:KeyInfo><ds:X509Data><ds:X509Certificate>` + certificate + `</ds:X509Certificate></ds:X509Data></ds:KeyInfo></md:KeyDescriptor>`
+	}
+
+	return `<md:EntityDescriptor entityID="` + entityId + `" validUntil="` + validUntil + `">
+    <md:IDPSSODescriptor protocolSupportEnumeration="urn:oasis:names:tc:SAML:2.0:protocol">
+      <md:Extensions><mdui:UIInfo><mdui:DisplayName xml:lang="de">` + displayName + ` (de)</mdui:DisplayName><mdui:DisplayName xml:lang="en">` + displayName + `</mdui:DisplayName></mdui:UIInfo></md:Extensions>
+      ` + keyDescriptors + `
+      <md:KeyDescriptor use="encryption"><ds:KeyInfo><ds:X509Data><ds:X509Certificate>ignored</ds:X509Certificate></ds:X509Data></ds:KeyInfo></md:KeyDescriptor>
+      <md:SingleSignOnService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST" Location="` + entityId + `/sso/post"/>
+      <md:SingleSignOnService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect" Location="` + entityId + `/sso"/>
+    </md:IDPSSODescriptor>
+  </md:EntityDescriptor>`
+}
+
+func TestParseSamlIdpAggregateMetadata(t *testing.T) {
+	oldCertificate := getTestSamlCertificate(t)
+	newCertificate := getTestSamlCertificate(t)
+
+	metadata := `<md:EntitiesDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata" xmlns:ds="http://www.w3.org/2000/09/xmldsig#" xmlns:mdui="urn:oasis:names:tc:SAML:metadata:ui" Name="federation">
+  ` + getTestSamlIdpDescriptor("https://idp.b.example.org", "University B", "", oldCertificate, newCertificate) + `
+  ` + getTestSamlIdpDescriptor("https://idp.expired.example.org", "Expired", "2000-01-01T00:00:00Z", oldCertificate) + `
+  <md:EntitiesDescriptor Name="nested">
+    ` + getTestSamlIdpDescriptor("https://idp.a.example.org", "University A", "2999-01-01T00:00:00Z", newCertificate) + `
+  </md:EntitiesDescriptor>
+  <md:EntityDescriptor entityID="https://sp.example.org"><md:SPSSODescriptor protocolSupportEnumeration="urn:oasis:names:tc:SAML:2.0:protocol"/></md:EntityDescriptor>
+</md:EntitiesDescriptor>`
+
+	idps, err := parseSamlIdpMetadata([]byte(metadata), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(idps) != 2 || idps[0].DisplayName != "University A" || idps[1].DisplayName != "University B" {
+		t.Fatalf("unexpected IdPs: %+v", idps)
+	}
+	if idps[1].SsoUrl != "https://idp.b.example.org/sso" || len(idps[1].Certificates) != 2 {
+		t.Fatalf("unexpected IdP: %+v", idps[1])
+	}
+
+	// both certificates of an IdP rolling its key over are trusted
+	certStore, err := buildSpCertificateStore(&Provider{}, idps[1], "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(certStore.Roots) != 2 {
+		t.Fatalf("unexpected roots: %d", len(certStore.Roots))
+	}
+
+	provider := &Provider{Owner: "admin", Name: "provider-test", Category: "SAML", Metadata: metadata}
+	_, err = getSamlIdp(provider, "")
+	if err == nil {
+		t.Fatal("an IdP should be chosen when the metadata has several of them")
+	}
+	_, err = getSamlIdp(provider, "https://idp.expired.example.org")
+	if err == nil {
+		t.Fatal("an expired IdP should not be trusted")
+	}
+	idp, err := getSamlIdp(provider, "https://idp.a.example.org")
+	if err != nil || idp.EntityId != "https://idp.a.example.org" {
+		t.Fatalf("unexpected IdP: %+v, %v", idp, err)
+	}
+}
+
+func TestVerifySamlMetadata(t *testing.T) {
+	cert := newTestSamlCert(t, "RS256")
+	metadata := `<md:EntitiesDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata" xmlns:ds="http://www.w3.org/2000/09/xmldsig#" xmlns:mdui="urn:oasis:names:tc:SAML:metadata:ui" ID="_federation">
+  ` + getTestSamlIdpDescriptor("https://idp.example.org", "University", "", getTestSamlCertificate(t)) + `
+</md:EntitiesDescriptor>`
+
+	doc := etree.NewDocument()
+	err := doc.ReadFromString(metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, err := getCertSigningContext(cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := ctx.SignEnveloped(doc.Root())
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc.SetRoot(signed)
+	signedMetadata, err := doc.WriteToString()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idps, err := parseSamlIdpMetadata([]byte(signedMetadata), cert.Certificate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(idps) != 1 || idps[0].EntityId != "https://idp.example.org" {
+		t.Fatalf("unexpected IdPs: %+v", idps)
+	}
+
+	tampered := strings.Replace(signedMetadata, "https://idp.example.org/sso\"", "https://evil.example.org/sso\"", 1)
+	_, err = parseSamlIdpMetadata([]byte(tampered), cert.Certificate)
+	if err == nil {
+		t.Fatal("the tampered metadata should not be verified")
+	}
+}
+
+func TestFetchSamlIdpMetadataTrust(t *testing.T) {
+	provider := &Provider{Owner: "admin", Name: "provider_saml", MetadataUrl: "https://federation.example.com/metadata.xml"}
+	_, err := FetchSamlIdpMetadata(provider)
+	if err == nil || !strings.Contains(err.Error(), "TLS pin") {
+		t.Fatalf("unauthenticated metadata should be refused: %v", err)
+	}
+}
+
+func TestGetSamlUserId(t *testing.T) {
+	if id := getSamlUserId(1, "https://idp.example.com", "alice"); id != "alice" {
+		t.Fatalf("the ID of a single IdP should not change: %s", id)
+	}
+	if id := getSamlUserId(2, "https://idp.example.com", "alice"); id != "https://idp.example.com!alice" {
+		t.Fatalf("the ID should be namespaced by the IdP: %s", id)
+	}
+	if getSamlUserId(2, "https://idp1.example.com", "alice") == getSamlUserId(2, "https://idp2.example.com", "alice") {
+		t.Fatal("the IDs of two IdPs should differ")
+	}
+}
@@ -28,6 +28,8 @@ type TableColumn struct {
 	IsKey       bool     `json:"isKey"`
 	IsHashed    bool     `json:"isHashed"`
 	Values      []string `json:"values"`
+	// Owner is the side whose value is kept with the field ownership conflict policy, the source when empty
+	Owner string `json:"owner"`
 }
 
 type Syncer struct {
@@ -44,19 +46,26 @@ type Syncer struct {
 	Host             string         `xorm:"varchar(100)" json:"host"`
 	Port             int            `json:"port"`
 	User             string         `xorm:"varchar(100)" json:"user"`
-	Password         string         `xorm:"varchar(150)" json:"password"`
+	Password         string         `xorm:"varchar(1000)" json:"password"`
 	SshHost          string         `xorm:"varchar(100)" json:"sshHost"`
 	SshPort          int            `json:"sshPort"`
 	SshUser          string         `xorm:"varchar(100)" json:"sshUser"`
-	SshPassword      string         `xorm:"varchar(150)" json:"sshPassword"`
+	SshPassword      string         `xorm:"varchar(1000)" json:"sshPassword"`
 	Cert             string         `xorm:"varchar(100)" json:"cert"`
 	Database         string         `xorm:"varchar(100)" json:"database"`
+	Directory        string         `xorm:"varchar(500)" json:"directory"`
+	AuthHeader       string         `xorm:"varchar(1000)" json:"authHeader"`
+	DataPath         string         `xorm:"varchar(200)" json:"dataPath"`
+	PageParam        string         `xorm:"varchar(100)" json:"pageParam"`
+	NextPath         string         `xorm:"varchar(200)" json:"nextPath"`
 	Table            string         `xorm:"varchar(100)" json:"table"`
 	TableColumns     []*TableColumn `xorm:"mediumtext" json:"tableColumns"`
 	AffiliationTable string         `xorm:"varchar(100)" json:"affiliationTable"`
 	AvatarBaseUrl    string         `xorm:"varchar(100)" json:"avatarBaseUrl"`
 	ErrorText        string         `xorm:"mediumtext" json:"errorText"`
 	SyncInterval     int            `json:"syncInterval"`
+	ConflictPolicy   string         `xorm:"varchar(100)" json:"conflictPolicy"`
+	DeletionPolicy   string         `xorm:"varchar(100)" json:"deletionPolicy"`
 	IsReadOnly       bool           `json:"isReadOnly"`
 	IsEnabled        bool           `json:"isEnabled"`
 
@@ -134,6 +143,9 @@ func GetMaskedSyncer(syncer *Syncer, errs ...error) (*Syncer, error) {
 	if syncer.Password != "" {
 		syncer.Password = "***"
 	}
+	if syncer.AuthHeader != "" {
+		syncer.AuthHeader = "***"
+	}
 	return syncer, nil
 }
 
@@ -166,8 +178,17 @@ func UpdateSyncer(id string, syncer *Syncer) (bool, error) {
 	if syncer.Password == "***" {
 		syncer.Password = s.Password
 	}
+	if syncer.AuthHeader == "***" {
+		syncer.AuthHeader = s.AuthHeader
+	}
+	err = encryptColumns(syncer)
+	if err != nil {
+		return false, err
+	}
+
 	affected, err := session.Update(syncer)
 	if err != nil {
+		restoreEncryptedColumns(syncer)
 		return false, err
 	}
 
@@ -202,8 +223,14 @@ func updateSyncerErrorText(syncer *Syncer, line string) (bool, error) {
 }
 
 func AddSyncer(syncer *Syncer) (bool, error) {
+	err := encryptColumns(syncer)
+	if err != nil {
+		return false, err
+	}
+
 	affected, err := ormer.Engine.Insert(syncer)
 	if err != nil {
+		restoreEncryptedColumns(syncer)
 		return false, err
 	}
 
@@ -225,6 +252,11 @@ func DeleteSyncer(syncer *Syncer) (bool, error) {
 
 	if affected == 1 {
 		deleteSyncerJob(syncer)
+
+		_, err = deleteSyncerRuns(syncer)
+		if err != nil {
+			return false, err
+		}
 	}
 
 	return affected != 0, nil
@@ -234,22 +266,6 @@ func (syncer *Syncer) GetId() string {
 	return fmt.Sprintf("%s/%s", syncer.Owner, syncer.Name)
 }
 
-func (syncer *Syncer) getTableColumnsTypeMap() map[string]string {
-	m := map[string]string{}
-	for _, tableColumn := range syncer.TableColumns {
-		m[tableColumn.Name] = tableColumn.Type
-	}
-	return m
-}
-
-func (syncer *Syncer) getTable() string {
-	if syncer.DatabaseType == "mssql" {
-		return fmt.Sprintf("[%s]", syncer.Table)
-	} else {
-		return syncer.Table
-	}
-}
-
 func (syncer *Syncer) getKeyColumn() *TableColumn {
 	var column *TableColumn
 	for _, tableColumn := range syncer.TableColumns {
@@ -278,13 +294,22 @@ func (syncer *Syncer) getKey() string {
 	return util.CamelToSnakeCase(column.CasdoorName)
 }
 
-func RunSyncer(syncer *Syncer) error {
-	err := syncer.initAdapter()
-	if err != nil {
-		return err
+func RunSyncer(syncer *Syncer) (*SyncerRun, error) {
+	if syncer.Type == "Policy" {
+		_, err := syncer.syncPolicies()
+		return nil, err
+	}
+
+	return syncer.runSyncUsers(false)
+}
+
+// DryRunSyncer reports the users the syncer would add, update, disable or delete, without changing any of them
+func DryRunSyncer(syncer *Syncer) (*SyncerRun, error) {
+	if syncer.Type == "Policy" {
+		return nil, fmt.Errorf("the policies of a Policy syncer are planned by the policy import instead")
 	}
 
-	return syncer.syncUsers()
+	return syncer.runSyncUsers(true)
 }
 
 func TestSyncerDb(syncer Syncer) error {
@@ -296,13 +321,28 @@ func TestSyncerDb(syncer Syncer) error {
 	if syncer.Password == "***" {
 		syncer.Password = oldSyncer.Password
 	}
+	if syncer.AuthHeader == "***" {
+		syncer.AuthHeader = oldSyncer.AuthHeader
+	}
 
-	err = syncer.initAdapter()
+	if syncer.Type == "Policy" {
+		_, err = LoadPolicyDirectory(syncer.Directory)
+		return err
+	}
+
+	provider, err := syncer.getInitProvider()
 	if err != nil {
 		return err
 	}
 
-	err = syncer.Ormer.Engine.Ping()
+	switch p := provider.(type) {
+	case *databaseSyncerProvider:
+		err = syncer.Ormer.Engine.Ping()
+	case *csvSyncerProvider:
+		_, _, err = p.getFiles()
+	default:
+		_, err = provider.getOriginalUsers()
+	}
 	if err != nil {
 		return err
 	}
@@ -50,19 +50,27 @@ func addSyncerJob(syncer *Syncer) error {
 		return nil
 	}
 
-	err := syncer.initAdapter()
-	if err != nil {
-		return err
-	}
+	job := syncer.syncUsersNoError
+	if syncer.Type == "Policy" {
+		_, err := syncer.syncPolicies()
+		if err != nil {
+			return err
+		}
 
-	err = syncer.syncUsers()
-	if err != nil {
-		return err
+		job = syncer.syncPoliciesNoError
+	} else {
+		// the files of a directory are read again when the syncer is changed
+		csvSyncerFingerprints.Delete(syncer.GetId())
+
+		err := syncer.syncUsers()
+		if err != nil {
+			return err
+		}
 	}
 
 	schedule := fmt.Sprintf("@every %ds", syncer.SyncInterval)
 	cron := getCronMap(syncer.Name)
-	_, err = cron.AddFunc(schedule, syncer.syncUsersNoError)
+	_, err := cron.AddFunc(schedule, job)
 	if err != nil {
 		return err
 	}
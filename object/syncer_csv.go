@@ -0,0 +1,167 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/casdoor/casdoor/xlsx"
+)
+
+// the fingerprints of the directories read by the CSV syncers, by syncer id
+var csvSyncerFingerprints sync.Map
+
+// csvSyncerProvider reads the users from the CSV and xlsx files dropped in a directory, like a nightly export.
+// The first row of a file is the header, the table columns are the names in the header. The files are read
+// again only when one of them is added, removed or changed.
+type csvSyncerProvider struct {
+	syncer      *Syncer
+	fingerprint string
+	isForced    bool
+}
+
+func (p *csvSyncerProvider) init() error {
+	if p.syncer.Directory == "" {
+		return fmt.Errorf("the directory of the CSV syncer should not be empty")
+	}
+	return nil
+}
+
+func (p *csvSyncerProvider) canPull() bool {
+	return true
+}
+
+func (p *csvSyncerProvider) canPush() bool {
+	return false
+}
+
+func (p *csvSyncerProvider) addUser(user *OriginalUser) (bool, error) {
+	return false, fmt.Errorf("the CSV syncer is read-only")
+}
+
+func (p *csvSyncerProvider) updateUser(user *OriginalUser) (bool, error) {
+	return false, fmt.Errorf("the CSV syncer is read-only")
+}
+
+func (p *csvSyncerProvider) getFiles() ([]string, string, error) {
+	entries, err := os.ReadDir(p.syncer.Directory)
+	if err != nil {
+		return nil, "", err
+	}
+
+	files := []string{}
+	tokens := []string{}
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".csv" && ext != ".xlsx") {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, "", err
+		}
+
+		files = append(files, filepath.Join(p.syncer.Directory, entry.Name()))
+		tokens = append(tokens, fmt.Sprintf("%s:%d:%d", entry.Name(), info.Size(), info.ModTime().UnixNano()))
+	}
+	sort.Strings(files)
+	sort.Strings(tokens)
+	return files, strings.Join(tokens, "|"), nil
+}
+
+func readCsvRows(path string) ([][]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	return reader.ReadAll()
+}
+
+func (syncer *Syncer) getOriginalUsersFromRows(rows [][]string) []*OriginalUser {
+	users := []*OriginalUser{}
+	if len(rows) == 0 {
+		return users
+	}
+
+	header := map[string]int{}
+	for i, name := range rows[0] {
+		header[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+	}
+
+	for _, row := range rows[1:] {
+		if len(strings.Join(row, "")) == 0 {
+			continue
+		}
+
+		users = append(users, syncer.getOriginalUserFromValues(func(name string) string {
+			i, ok := header[name]
+			if !ok || i >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[i])
+		}))
+	}
+	return users
+}
+
+func (p *csvSyncerProvider) getOriginalUsers() ([]*OriginalUser, error) {
+	files, fingerprint, err := p.getFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	if last, ok := csvSyncerFingerprints.Load(p.syncer.GetId()); ok && last == fingerprint && !p.isForced {
+		return nil, errSyncerSourceUnchanged
+	}
+
+	users := []*OriginalUser{}
+	for _, file := range files {
+		var rows [][]string
+		if strings.ToLower(filepath.Ext(file)) == ".xlsx" {
+			rows, err = xlsx.ReadXlsxRows(file)
+		} else {
+			rows, err = readCsvRows(file)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(file), err)
+		}
+
+		users = append(users, p.syncer.getOriginalUsersFromRows(rows)...)
+	}
+
+	p.fingerprint = fingerprint
+	return users, nil
+}
+
+func (p *csvSyncerProvider) commit() {
+	if p.fingerprint != "" {
+		csvSyncerFingerprints.Store(p.syncer.GetId(), p.fingerprint)
+	}
+}
+
+func (p *csvSyncerProvider) force() {
+	p.isForced = true
+}
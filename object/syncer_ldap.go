@@ -0,0 +1,175 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+
+	goldap "github.com/go-ldap/ldap/v3"
+)
+
+var ldapSyncerObjectClasses = []string{"top", "person", "organizationalPerson", "inetOrgPerson"}
+
+// ldapSyncerProvider pushes the users of the organization to a directory server. The table is the base DN
+// of the entries, the table columns are the LDAP attributes and the key column is the RDN attribute, like uid.
+// The entries are inetOrgPerson ones, so the columns should include the cn and sn attributes.
+type ldapSyncerProvider struct {
+	syncer *Syncer
+}
+
+func (p *ldapSyncerProvider) init() error {
+	if p.syncer.Host == "" || p.syncer.Table == "" {
+		return fmt.Errorf("the host and the base DN of the LDAP syncer should not be empty")
+	}
+	if len(p.syncer.TableColumns) == 0 {
+		return fmt.Errorf("The syncer table columns should not be empty")
+	}
+	return nil
+}
+
+func (p *ldapSyncerProvider) canPull() bool {
+	return false
+}
+
+func (p *ldapSyncerProvider) canPush() bool {
+	return true
+}
+
+func (p *ldapSyncerProvider) getConn() (*goldap.Conn, error) {
+	var conn *goldap.Conn
+	var err error
+	address := fmt.Sprintf("%s:%d", p.syncer.Host, p.syncer.Port)
+	if p.syncer.SslMode != "" && p.syncer.SslMode != "disable" {
+		conn, err = goldap.DialTLS("tcp", address, &tls.Config{ServerName: p.syncer.Host})
+	} else {
+		conn, err = goldap.Dial("tcp", address)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = conn.Bind(p.syncer.User, p.syncer.Password)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (p *ldapSyncerProvider) getRdnAttribute() string {
+	return p.syncer.getKeyColumn().Name
+}
+
+func (p *ldapSyncerProvider) getDn(m map[string]string) (string, error) {
+	rdnAttribute := p.getRdnAttribute()
+	value := m[rdnAttribute]
+	if value == "" {
+		return "", fmt.Errorf("the LDAP attribute: %s of the user should not be empty", rdnAttribute)
+	}
+	return fmt.Sprintf("%s=%s,%s", rdnAttribute, goldap.EscapeDN(value), p.syncer.Table), nil
+}
+
+func (p *ldapSyncerProvider) getOriginalUsers() ([]*OriginalUser, error) {
+	conn, err := p.getConn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	attributes := []string{}
+	for _, tableColumn := range p.syncer.TableColumns {
+		attributes = append(attributes, tableColumn.Name)
+	}
+
+	request := goldap.NewSearchRequest(p.syncer.Table, goldap.ScopeSingleLevel, goldap.NeverDerefAliases, 0, 0, false,
+		"(objectClass=inetOrgPerson)", attributes, nil)
+	result, err := conn.SearchWithPaging(request, 500)
+	if err != nil {
+		return nil, err
+	}
+
+	users := []*OriginalUser{}
+	for _, entry := range result.Entries {
+		users = append(users, p.syncer.getOriginalUserFromValues(func(name string) string {
+			return entry.GetAttributeValue(name)
+		}))
+	}
+	return users, nil
+}
+
+func (p *ldapSyncerProvider) addUser(user *OriginalUser) (bool, error) {
+	m := p.syncer.getMapFromOriginalUser(user)
+	dn, err := p.getDn(m)
+	if err != nil {
+		return false, err
+	}
+
+	conn, err := p.getConn()
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	request := goldap.NewAddRequest(dn, nil)
+	request.Attribute("objectClass", ldapSyncerObjectClasses)
+	for _, tableColumn := range p.syncer.TableColumns {
+		if value := m[tableColumn.Name]; value != "" && !strings.Contains(tableColumn.Name, "+") {
+			request.Attribute(tableColumn.Name, []string{value})
+		}
+	}
+
+	err = conn.Add(request)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (p *ldapSyncerProvider) updateUser(user *OriginalUser) (bool, error) {
+	m := p.syncer.getMapFromOriginalUser(user)
+	dn, err := p.getDn(m)
+	if err != nil {
+		return false, err
+	}
+
+	conn, err := p.getConn()
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	request := goldap.NewModifyRequest(dn, nil)
+	rdnAttribute := p.getRdnAttribute()
+	for _, tableColumn := range p.syncer.TableColumns {
+		if tableColumn.Name == rdnAttribute || strings.Contains(tableColumn.Name, "+") {
+			continue
+		}
+
+		// an empty value removes the attribute
+		values := []string{}
+		if value := m[tableColumn.Name]; value != "" {
+			values = append(values, value)
+		}
+		request.Replace(tableColumn.Name, values)
+	}
+
+	err = conn.Modify(request)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,51 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"fmt"
+
+	"github.com/casdoor/casdoor/util"
+)
+
+// syncPolicies reconciles the models, adapters, enforcers, groups, roles and permissions of the organization
+// with the YAML files of the directory of a "Policy" syncer, like a checkout of a Git repository
+func (syncer *Syncer) syncPolicies() (*PolicyPlan, error) {
+	fmt.Printf("Running syncPolicies()..\n")
+
+	bundle, err := LoadPolicyDirectory(syncer.Directory)
+	if err == nil {
+		var plan *PolicyPlan
+		plan, err = ApplyPolicyBundle(syncer.Organization, bundle)
+		if err == nil {
+			fmt.Printf("Policy changes: %d\n", len(plan.Changes))
+			return plan, nil
+		}
+	}
+
+	line := fmt.Sprintf("[%s] %s\n", util.GetCurrentTime(), err.Error())
+	_, err2 := updateSyncerErrorText(syncer, line)
+	if err2 != nil {
+		return nil, err2
+	}
+	return nil, err
+}
+
+func (syncer *Syncer) syncPoliciesNoError() {
+	_, err := syncer.syncPolicies()
+	if err != nil {
+		fmt.Printf("syncPoliciesNoError() error: %s\n", err.Error())
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// errSyncerSourceUnchanged is returned by the sources that know nothing changed since the last run, like a directory
+var errSyncerSourceUnchanged = errors.New("the syncer source is unchanged")
+
+// syncerProvider reads and writes the original users of a syncer type, the users of the organization are
+// reconciled with them by syncUsers, with the table columns and the hashes of the users
+type syncerProvider interface {
+	init() error
+	getOriginalUsers() ([]*OriginalUser, error)
+	addUser(user *OriginalUser) (bool, error)
+	updateUser(user *OriginalUser) (bool, error)
+	// canPull tells whether the original users are added and updated in Casdoor
+	canPull() bool
+	// canPush tells whether the users of Casdoor are added and updated in the original source
+	canPush() bool
+}
+
+// syncerSourceTracker is implemented by the providers returning errSyncerSourceUnchanged
+type syncerSourceTracker interface {
+	// commit remembers the source once its users are synced, so that it is read again if the sync failed
+	commit()
+	// force reads the source even if it is unchanged, like for a dry run
+	force()
+}
+
+func (syncer *Syncer) getProvider() (syncerProvider, error) {
+	var provider syncerProvider
+	switch syncer.Type {
+	case "", "Database", "Keycloak":
+		provider = &databaseSyncerProvider{syncer: syncer}
+	case "REST":
+		provider = &restSyncerProvider{syncer: syncer}
+	case "CSV":
+		provider = &csvSyncerProvider{syncer: syncer}
+	case "LDAP":
+		provider = &ldapSyncerProvider{syncer: syncer}
+	default:
+		return nil, fmt.Errorf("unsupported syncer type: %s", syncer.Type)
+	}
+	return provider, nil
+}
+
+// getInitProvider returns the provider of the syncer, ready to read and write the original users
+func (syncer *Syncer) getInitProvider() (syncerProvider, error) {
+	provider, err := syncer.getProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	err = provider.init()
+	if err != nil {
+		return nil, err
+	}
+	return provider, nil
+}
+
+// getOriginalUserFromValues maps the values of a record of the source to a user with the table columns,
+// the values of a column like "LAST_NAME+FIRST_NAME" are joined with a space
+func (syncer *Syncer) getOriginalUserFromValues(getValue func(name string) string) *OriginalUser {
+	originalUser := &OriginalUser{
+		Address:    []string{},
+		Properties: map[string]string{},
+		Groups:     []string{},
+	}
+
+	for _, tableColumn := range syncer.TableColumns {
+		value := ""
+		if strings.Contains(tableColumn.Name, "+") {
+			names := strings.Split(tableColumn.Name, "+")
+			var values []string
+			for _, name := range names {
+				values = append(values, getValue(strings.Trim(name, " ")))
+			}
+			value = strings.Join(values, " ")
+		} else {
+			value = getValue(tableColumn.Name)
+		}
+		syncer.setUserByKeyValue(originalUser, tableColumn.CasdoorName, value)
+	}
+	return originalUser
+}
+
+// databaseSyncerProvider syncs with a table of a database, both ways unless the syncer is read-only
+type databaseSyncerProvider struct {
+	syncer *Syncer
+}
+
+func (p *databaseSyncerProvider) init() error {
+	return p.syncer.initAdapter()
+}
+
+func (p *databaseSyncerProvider) getOriginalUsers() ([]*OriginalUser, error) {
+	return p.syncer.getOriginalUsers()
+}
+
+func (p *databaseSyncerProvider) addUser(user *OriginalUser) (bool, error) {
+	return p.syncer.addUser(user)
+}
+
+func (p *databaseSyncerProvider) updateUser(user *OriginalUser) (bool, error) {
+	return p.syncer.updateUser(user)
+}
+
+func (p *databaseSyncerProvider) canPull() bool {
+	return true
+}
+
+func (p *databaseSyncerProvider) canPush() bool {
+	return !p.syncer.IsReadOnly
+}
@@ -0,0 +1,204 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/casdoor/casdoor/proxy"
+)
+
+func getOriginalUserNames(users []*OriginalUser) []string {
+	res := []string{}
+	for _, user := range users {
+		res = append(res, fmt.Sprintf("%s <%s> %s", user.Name, user.Email, user.DisplayName))
+	}
+	return res
+}
+
+func TestGetJsonPathValues(t *testing.T) {
+	var data interface{}
+	err := json.Unmarshal([]byte(`{"data": [{"id": 1, "profile": {"email": "a@example.com"}, "active": true}, {"id": 2, "profile": {"email": "b@example.com"}}], "links": {"next": null}}`), &data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path     string
+		expected []string
+	}{
+		{"$.data[0].id", []string{"1"}},
+		{"data[1].profile.email", []string{"b@example.com"}},
+		{"$.data[*].profile['email']", []string{"a@example.com", "b@example.com"}},
+		{"$.data[0].active", []string{"true"}},
+		{"$.data[5].id", []string{}},
+		{"$.links.next", []string{""}},
+	}
+	for _, test := range tests {
+		values, err := getJsonPathValues(data, test.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res := []string{}
+		for _, value := range values {
+			res = append(res, getJsonPathString(value))
+		}
+		if !reflect.DeepEqual(res, test.expected) {
+			t.Fatalf("%s: got %v, expected %v", test.path, res, test.expected)
+		}
+	}
+
+	_, err = getJsonPathValues(data, "$.data[x]")
+	if err == nil {
+		t.Fatal("an invalid index should be an error")
+	}
+}
+
+func TestRestSyncerProvider(t *testing.T) {
+	if proxy.DefaultHttpClient == nil {
+		proxy.DefaultHttpClient = http.DefaultClient
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Query().Get("cursor") {
+		case "":
+			fmt.Fprint(w, `{"items": [{"login": "alice", "contact": {"mail": "alice@example.com"}, "first": "Alice", "last": "Smith"}], "next": "page-2"}`)
+		case "page-2":
+			fmt.Fprint(w, `{"items": [{"login": "bob", "contact": {"mail": "bob@example.com"}, "first": "Bob", "last": "Jones"}], "next": ""}`)
+		}
+	}))
+	defer server.Close()
+
+	syncer := &Syncer{
+		Type:       "REST",
+		Host:       server.URL + "/users",
+		AuthHeader: "X-Api-Key: secret",
+		DataPath:   "$.items",
+		NextPath:   "$.next",
+		TableColumns: []*TableColumn{
+			{Name: "$.login", CasdoorName: "Name"},
+			{Name: "$.contact.mail", CasdoorName: "Email"},
+			{Name: "first+last", CasdoorName: "DisplayName"},
+		},
+	}
+	provider, err := syncer.getInitProvider()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !provider.canPull() || provider.canPush() {
+		t.Fatal("the REST syncer should be read-only")
+	}
+
+	users, err := provider.getOriginalUsers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"alice <alice@example.com> Alice Smith", "bob <bob@example.com> Bob Jones"}
+	if res := getOriginalUserNames(users); !reflect.DeepEqual(res, expected) {
+		t.Fatalf("unexpected users: %v", res)
+	}
+
+	syncer.AuthHeader = ""
+	_, err = provider.getOriginalUsers()
+	if err == nil {
+		t.Fatal("an unauthorized page should be an error")
+	}
+
+	// the next links are only followed on the origin of the syncer
+	for next, valid := range map[string]bool{"/users?cursor=page-2": true, server.URL + "/users?cursor=page-2": true, "https://evil.example.com/users": false, "//evil.example.com/users": false} {
+		data := map[string]interface{}{"next": next}
+		_, err = provider.(*restSyncerProvider).getNextPageUrl(syncer.Host, data, 1, 1)
+		if (err == nil) != valid {
+			t.Fatalf("unexpected next page: %s, %v", next, err)
+		}
+	}
+}
+
+func TestCsvSyncerProvider(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "users.csv"), []byte("\ufeffuid,mail,given,family\nalice,alice@example.com,Alice,Smith\n\nbob,bob@example.com,Bob\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a user file"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	syncer := &Syncer{
+		Owner:     "admin",
+		Name:      "syncer-csv-test",
+		Type:      "CSV",
+		Directory: dir,
+		TableColumns: []*TableColumn{
+			{Name: "uid", CasdoorName: "Name"},
+			{Name: "mail", CasdoorName: "Email"},
+			{Name: "given+family", CasdoorName: "DisplayName"},
+		},
+	}
+	defer csvSyncerFingerprints.Delete(syncer.GetId())
+
+	provider, err := syncer.getInitProvider()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users, err := provider.getOriginalUsers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"alice <alice@example.com> Alice Smith", "bob <bob@example.com> Bob "}
+	if res := getOriginalUserNames(users); !reflect.DeepEqual(res, expected) {
+		t.Fatalf("unexpected users: %v", res)
+	}
+
+	// the files are read again until the users are synced
+	_, err = provider.getOriginalUsers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider.(*csvSyncerProvider).commit()
+
+	_, err = provider.getOriginalUsers()
+	if !errors.Is(err, errSyncerSourceUnchanged) {
+		t.Fatalf("the unchanged directory should be skipped: %v", err)
+	}
+
+	err = os.WriteFile(filepath.Join(dir, "more.csv"), []byte("uid,mail\ncarol,carol@example.com\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	users, err = provider.getOriginalUsers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 3 {
+		t.Fatalf("the new file should be read: %v", getOriginalUserNames(users))
+	}
+}
@@ -23,34 +23,44 @@ func getDbSyncerForUser(user *User) (*Syncer, error) {
 	}
 
 	for _, syncer := range syncers {
-		if syncer.Organization == user.Owner && syncer.IsEnabled && syncer.Type == "Database" {
+		if syncer.Organization == user.Owner && syncer.IsEnabled && syncer.Type != "Policy" {
 			return syncer, nil
 		}
 	}
 	return nil, nil
 }
 
-func getEnabledSyncerForOrganization(organization string) (*Syncer, error) {
+func getEnabledSyncerForOrganization(organization string) (*Syncer, syncerProvider, error) {
 	syncers, err := GetSyncers("admin")
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	for _, syncer := range syncers {
-		if syncer.Organization == organization && syncer.IsEnabled {
-			err = syncer.initAdapter()
-			if err != nil {
-				return nil, err
-			}
+		if syncer.Organization != organization || !syncer.IsEnabled || syncer.Type == "Policy" {
+			continue
+		}
 
-			return syncer, nil
+		provider, err := syncer.getProvider()
+		if err != nil {
+			return nil, nil, err
+		}
+		if !provider.canPush() {
+			continue
 		}
+
+		err = provider.init()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return syncer, provider, nil
 	}
-	return nil, nil
+	return nil, nil, nil
 }
 
 func AddUserToOriginalDatabase(user *User) error {
-	syncer, err := getEnabledSyncerForOrganization(user.Owner)
+	syncer, provider, err := getEnabledSyncerForOrganization(user.Owner)
 	if err != nil {
 		return err
 	}
@@ -59,12 +69,8 @@ func AddUserToOriginalDatabase(user *User) error {
 		return nil
 	}
 
-	if syncer.IsReadOnly {
-		return nil
-	}
-
 	updatedOUser := syncer.createOriginalUserFromUser(user)
-	_, err = syncer.addUser(updatedOUser)
+	_, err = provider.addUser(updatedOUser)
 	if err != nil {
 		return err
 	}
@@ -74,7 +80,7 @@ func AddUserToOriginalDatabase(user *User) error {
 }
 
 func UpdateUserToOriginalDatabase(user *User) error {
-	syncer, err := getEnabledSyncerForOrganization(user.Owner)
+	syncer, provider, err := getEnabledSyncerForOrganization(user.Owner)
 	if err != nil {
 		return err
 	}
@@ -82,17 +88,13 @@ func UpdateUserToOriginalDatabase(user *User) error {
 		return nil
 	}
 
-	if syncer.IsReadOnly {
-		return nil
-	}
-
 	newUser, err := GetUser(user.GetId())
 	if err != nil {
 		return err
 	}
 
 	updatedOUser := syncer.createOriginalUserFromUser(newUser)
-	_, err = syncer.updateUser(updatedOUser)
+	_, err = provider.updateUser(updatedOUser)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,306 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/casdoor/casdoor/proxy"
+)
+
+const restSyncerMaxPages = 1000
+
+// restSyncerProvider reads the users from a paginated REST API, like the one of an HR system. The items of a page
+// are found at the data path, and the table columns are JSONPath expressions in an item, like "$.profile.email".
+// The next page is the URL or cursor at the next path, or else the page parameter is increased until a page is empty.
+type restSyncerProvider struct {
+	syncer *Syncer
+}
+
+func (p *restSyncerProvider) init() error {
+	if p.syncer.Host == "" {
+		return fmt.Errorf("the URL of the REST syncer should not be empty")
+	}
+	return nil
+}
+
+func (p *restSyncerProvider) canPull() bool {
+	return true
+}
+
+func (p *restSyncerProvider) canPush() bool {
+	return false
+}
+
+func (p *restSyncerProvider) addUser(user *OriginalUser) (bool, error) {
+	return false, fmt.Errorf("the REST syncer is read-only")
+}
+
+func (p *restSyncerProvider) updateUser(user *OriginalUser) (bool, error) {
+	return false, fmt.Errorf("the REST syncer is read-only")
+}
+
+func (p *restSyncerProvider) getPage(pageUrl string) (interface{}, error) {
+	req, err := http.NewRequest(http.MethodGet, pageUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if p.syncer.AuthHeader != "" {
+		name, value, ok := strings.Cut(p.syncer.AuthHeader, ":")
+		if !ok {
+			name, value = "Authorization", p.syncer.AuthHeader
+		}
+		req.Header.Set(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	resp, err := proxy.DefaultHttpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("the REST syncer got the status: %d from: %s", resp.StatusCode, pageUrl)
+	}
+
+	var data interface{}
+	err = json.Unmarshal(body, &data)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// getNextPageUrl returns the URL of the next page, or "" when the page is the last one
+func (p *restSyncerProvider) getNextPageUrl(pageUrl string, data interface{}, itemCount int, page int) (string, error) {
+	if p.syncer.NextPath != "" {
+		values, err := getJsonPathValues(data, p.syncer.NextPath)
+		if err != nil || len(values) == 0 || values[0] == nil {
+			return "", err
+		}
+
+		next := getJsonPathString(values[0])
+		if next == "" {
+			return "", nil
+		}
+		if strings.HasPrefix(next, "http://") || strings.HasPrefix(next, "https://") || strings.HasPrefix(next, "/") {
+			base, err := url.Parse(pageUrl)
+			if err != nil {
+				return "", err
+			}
+			nextUrl, err := base.Parse(next)
+			if err != nil {
+				return "", err
+			}
+
+			// the auth header is sent to the next page, so it is only followed on the origin of the syncer
+			host, err := url.Parse(p.syncer.Host)
+			if err != nil {
+				return "", err
+			}
+			if nextUrl.Scheme != host.Scheme || nextUrl.Host != host.Host {
+				return "", fmt.Errorf("the REST syncer refuses the next page: %s outside of the origin: %s://%s", nextUrl.String(), host.Scheme, host.Host)
+			}
+			return nextUrl.String(), nil
+		}
+		// a cursor, passed with the page parameter
+		return setUrlQuery(p.syncer.Host, p.getPageParam(), next)
+	}
+
+	if p.syncer.PageParam == "" || itemCount == 0 {
+		return "", nil
+	}
+	return setUrlQuery(p.syncer.Host, p.syncer.PageParam, strconv.Itoa(page+1))
+}
+
+func (p *restSyncerProvider) getPageParam() string {
+	if p.syncer.PageParam == "" {
+		return "cursor"
+	}
+	return p.syncer.PageParam
+}
+
+func setUrlQuery(rawUrl string, key string, value string) (string, error) {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return "", err
+	}
+
+	query := u.Query()
+	query.Set(key, value)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+func (p *restSyncerProvider) getOriginalUsers() ([]*OriginalUser, error) {
+	pageUrl := p.syncer.Host
+	page := 1
+	if p.syncer.PageParam != "" && p.syncer.NextPath == "" {
+		var err error
+		pageUrl, err = setUrlQuery(p.syncer.Host, p.syncer.PageParam, strconv.Itoa(page))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	users := []*OriginalUser{}
+	for ; pageUrl != "" && page <= restSyncerMaxPages; page++ {
+		data, err := p.getPage(pageUrl)
+		if err != nil {
+			return nil, err
+		}
+
+		items, err := getJsonPathItems(data, p.syncer.DataPath)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			users = append(users, p.syncer.getOriginalUserFromJson(item))
+		}
+
+		pageUrl, err = p.getNextPageUrl(pageUrl, data, len(items), page)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return users, nil
+}
+
+func (syncer *Syncer) getOriginalUserFromJson(item interface{}) *OriginalUser {
+	return syncer.getOriginalUserFromValues(func(name string) string {
+		values, err := getJsonPathValues(item, name)
+		if err != nil || len(values) == 0 {
+			return ""
+		}
+		return getJsonPathString(values[0])
+	})
+}
+
+// getJsonPathItems returns the items of a page, the page itself is the list of items when the data path is empty
+func getJsonPathItems(data interface{}, path string) ([]interface{}, error) {
+	if path == "" || path == "$" {
+		path = "$[*]"
+	}
+
+	values, err := getJsonPathValues(data, path)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 1 {
+		if items, ok := values[0].([]interface{}); ok {
+			return items, nil
+		}
+	}
+	return values, nil
+}
+
+func getJsonPathString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+// getJsonPathValues evaluates the JSONPath subset used by the syncers: the root "$", the children ".name"
+// and "['name']", the indexes "[0]" and the wildcards "[*]" and ".*". The leading "$." can be omitted.
+func getJsonPathValues(data interface{}, path string) ([]interface{}, error) {
+	path = strings.TrimSpace(path)
+	path = strings.TrimPrefix(path, "$")
+
+	values := []interface{}{data}
+	for path != "" {
+		var selector string
+		var wildcard bool
+		var index = -1
+
+		switch {
+		case strings.HasPrefix(path, "["):
+			end := strings.Index(path, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid JSONPath: %s", path)
+			}
+			token := strings.TrimSpace(path[1:end])
+			path = path[end+1:]
+
+			if token == "*" {
+				wildcard = true
+			} else if strings.HasPrefix(token, "'") || strings.HasPrefix(token, "\"") {
+				selector = strings.Trim(token, "'\"")
+			} else {
+				i, err := strconv.Atoi(token)
+				if err != nil {
+					return nil, fmt.Errorf("invalid JSONPath index: %s", token)
+				}
+				index = i
+			}
+		default:
+			path = strings.TrimPrefix(path, ".")
+			end := strings.IndexAny(path, ".[")
+			if end < 0 {
+				end = len(path)
+			}
+			selector = path[:end]
+			path = path[end:]
+
+			if selector == "*" {
+				wildcard = true
+			} else if selector == "" {
+				return nil, fmt.Errorf("invalid JSONPath: %s", path)
+			}
+		}
+
+		next := []interface{}{}
+		for _, value := range values {
+			switch v := value.(type) {
+			case map[string]interface{}:
+				if wildcard {
+					for _, child := range v {
+						next = append(next, child)
+					}
+				} else if child, ok := v[selector]; ok && index < 0 {
+					next = append(next, child)
+				}
+			case []interface{}:
+				if wildcard {
+					next = append(next, v...)
+				} else if index >= 0 && index < len(v) {
+					next = append(next, v[index])
+				}
+			}
+		}
+		values = next
+	}
+	return values, nil
+}
@@ -0,0 +1,212 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"time"
+
+	"github.com/casdoor/casdoor/util"
+)
+
+const (
+	SyncerRunStateSucceeded = "Succeeded"
+	SyncerRunStateFailed    = "Failed"
+	// SyncerRunStateSkipped is the state of the runs whose source is unchanged since the last one
+	SyncerRunStateSkipped = "Skipped"
+)
+
+const (
+	SyncerActionAdd     = "Add"
+	SyncerActionUpdate  = "Update"
+	SyncerActionDelete  = "Delete"
+	SyncerActionDisable = "Disable"
+)
+
+const (
+	SyncerTargetCasdoor = "Casdoor"
+	SyncerTargetSource  = "Source"
+)
+
+// the changes kept in a run, the counts of a run are always complete
+const syncerRunMaxChanges = 1000
+
+// SyncerUserChange is a user added, updated, disabled or deleted by a run, in Casdoor or in the source
+type SyncerUserChange struct {
+	User   string `json:"user"`
+	Action string `json:"action"`
+	Target string `json:"target"`
+	// Fields are the Casdoor names of the updated columns
+	Fields     []string `json:"fields"`
+	IsConflict bool     `json:"isConflict"`
+}
+
+// SyncerUserError is a user that failed to sync, the other users of the run are synced anyway
+type SyncerUserError struct {
+	User  string `json:"user"`
+	Error string `json:"error"`
+}
+
+// SyncerRun is the report of a run of a syncer. The runs of the scheduled syncs are kept only when they change
+// or fail something, the dry runs are always kept.
+type SyncerRun struct {
+	Owner       string `xorm:"varchar(100) notnull pk" json:"owner"`
+	Name        string `xorm:"varchar(100) notnull pk" json:"name"`
+	CreatedTime string `xorm:"varchar(100)" json:"createdTime"`
+
+	Syncer       string `xorm:"varchar(100) index" json:"syncer"`
+	Organization string `xorm:"varchar(100)" json:"organization"`
+	IsDryRun     bool   `json:"isDryRun"`
+	State        string `xorm:"varchar(100)" json:"state"`
+	Message      string `xorm:"varchar(1000)" json:"message"`
+	// Duration is in milliseconds
+	Duration int64 `json:"duration"`
+
+	SourceCount   int `json:"sourceCount"`
+	UserCount     int `json:"userCount"`
+	Added         int `json:"added"`
+	Updated       int `json:"updated"`
+	Disabled      int `json:"disabled"`
+	Deleted       int `json:"deleted"`
+	SourceAdded   int `json:"sourceAdded"`
+	SourceUpdated int `json:"sourceUpdated"`
+	Conflicts     int `json:"conflicts"`
+	Failed        int `json:"failed"`
+
+	Changes []*SyncerUserChange `xorm:"mediumtext" json:"changes"`
+	Errors  []*SyncerUserError  `xorm:"mediumtext" json:"errors"`
+}
+
+func newSyncerRun(syncer *Syncer, isDryRun bool) *SyncerRun {
+	return &SyncerRun{
+		Owner:        syncer.Owner,
+		Name:         util.GenerateId(),
+		CreatedTime:  util.GetCurrentTime(),
+		Syncer:       syncer.Name,
+		Organization: syncer.Organization,
+		IsDryRun:     isDryRun,
+		Changes:      []*SyncerUserChange{},
+		Errors:       []*SyncerUserError{},
+	}
+}
+
+func (run *SyncerRun) addChange(user string, action string, target string, fields []string, isConflict bool) {
+	switch {
+	case action == SyncerActionAdd && target == SyncerTargetCasdoor:
+		run.Added++
+	case action == SyncerActionUpdate && target == SyncerTargetCasdoor:
+		run.Updated++
+	case action == SyncerActionAdd && target == SyncerTargetSource:
+		run.SourceAdded++
+	case action == SyncerActionUpdate && target == SyncerTargetSource:
+		run.SourceUpdated++
+	case action == SyncerActionDisable:
+		run.Disabled++
+	case action == SyncerActionDelete:
+		run.Deleted++
+	}
+
+	if len(run.Changes) < syncerRunMaxChanges {
+		run.Changes = append(run.Changes, &SyncerUserChange{User: user, Action: action, Target: target, Fields: fields, IsConflict: isConflict})
+	}
+}
+
+func (run *SyncerRun) addError(user string, err error) {
+	run.Failed++
+	if len(run.Errors) < syncerRunMaxChanges {
+		run.Errors = append(run.Errors, &SyncerUserError{User: user, Error: err.Error()})
+	}
+}
+
+func (run *SyncerRun) finish(startTime time.Time, err error) {
+	run.Duration = time.Since(startTime).Milliseconds()
+	if err != nil {
+		run.State = SyncerRunStateFailed
+		run.Message = err.Error()
+	} else if run.Failed != 0 {
+		run.State = SyncerRunStateFailed
+	} else {
+		run.State = SyncerRunStateSucceeded
+	}
+}
+
+func (run *SyncerRun) isWorthKeeping() bool {
+	if run.State == SyncerRunStateSkipped {
+		return false
+	}
+	return run.IsDryRun || run.State == SyncerRunStateFailed || run.Added+run.Updated+run.Disabled+run.Deleted+run.SourceAdded+run.SourceUpdated != 0
+}
+
+func GetSyncerRunCount(owner, syncer, field, value string) (int64, error) {
+	session := GetSession(owner, -1, -1, field, value, "", "")
+	return session.Count(&SyncerRun{Syncer: syncer})
+}
+
+func GetSyncerRuns(owner, syncer string) ([]*SyncerRun, error) {
+	runs := []*SyncerRun{}
+	err := ormer.Engine.Desc("created_time").Find(&runs, &SyncerRun{Owner: owner, Syncer: syncer})
+	if err != nil {
+		return runs, err
+	}
+
+	return runs, nil
+}
+
+func GetPaginationSyncerRuns(owner, syncer string, offset, limit int, field, value, sortField, sortOrder string) ([]*SyncerRun, error) {
+	runs := []*SyncerRun{}
+	session := GetSession(owner, offset, limit, field, value, sortField, sortOrder)
+	err := session.Find(&runs, &SyncerRun{Syncer: syncer})
+	if err != nil {
+		return runs, err
+	}
+
+	return runs, nil
+}
+
+func GetSyncerRun(id string) (*SyncerRun, error) {
+	owner, name := util.GetOwnerAndNameFromIdNoCheck(id)
+	if owner == "" || name == "" {
+		return nil, nil
+	}
+
+	run := SyncerRun{Owner: owner, Name: name}
+	existed, err := ormer.Engine.Get(&run)
+	if err != nil {
+		return &run, err
+	}
+
+	if existed {
+		return &run, nil
+	} else {
+		return nil, nil
+	}
+}
+
+func addSyncerRun(run *SyncerRun) (bool, error) {
+	affected, err := ormer.Engine.Insert(run)
+	if err != nil {
+		return false, err
+	}
+
+	return affected != 0, nil
+}
+
+func deleteSyncerRuns(syncer *Syncer) (bool, error) {
+	affected, err := ormer.Engine.Delete(&SyncerRun{Owner: syncer.Owner, Syncer: syncer.Name})
+	if err != nil {
+		return false, err
+	}
+
+	return affected != 0, nil
+}
@@ -0,0 +1,231 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// the names which can be written without quotes, PostgreSQL folds them to lower case
+var syncerSqlNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*$`)
+
+// syncerSqlBuilder builds the statements of the database syncer for its database type. The table and column
+// names are quoted for the database and the values are always passed as parameters, never in the statements.
+type syncerSqlBuilder struct {
+	databaseType string
+	// isOrdinal tells whether the placeholders are numbered, like "$1" or "@p1"
+	isOrdinal bool
+	prefix    string
+	args      []interface{}
+}
+
+func (syncer *Syncer) newSqlBuilder() *syncerSqlBuilder {
+	b := &syncerSqlBuilder{databaseType: syncer.DatabaseType}
+	switch syncer.DatabaseType {
+	case "postgres":
+		b.isOrdinal, b.prefix = true, "$"
+	case "mssql":
+		// the "mssql" driver rewrites the "?" placeholders, but not the connector used through SSH
+		if syncer.Ormer != nil && syncer.Ormer.Db != nil {
+			b.isOrdinal, b.prefix = true, "@p"
+		}
+	}
+	return b
+}
+
+// foldSqlName returns a table or column name as the database stores it when it is written without quotes.
+// The statements of the syncers used to be written without quotes, so for PostgreSQL a name like "UserName"
+// of an existing syncer means the column username, and it keeps meaning it now that the names are quoted.
+// A name which cannot be written without quotes, like "e-mail", is kept as is.
+func foldSqlName(databaseType string, name string) string {
+	if databaseType == "postgres" && syncerSqlNameRegex.MatchString(name) {
+		return strings.ToLower(name)
+	}
+	return name
+}
+
+// quote quotes a table or column name, a name like "schema.table" is quoted part by part
+func (b *syncerSqlBuilder) quote(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		part = foldSqlName(b.databaseType, part)
+		switch b.databaseType {
+		case "mysql":
+			parts[i] = "`" + strings.ReplaceAll(part, "`", "``") + "`"
+		case "mssql":
+			parts[i] = "[" + strings.ReplaceAll(part, "]", "]]") + "]"
+		default:
+			parts[i] = `"` + strings.ReplaceAll(part, `"`, `""`) + `"`
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// bind adds a parameter and returns its placeholder
+func (b *syncerSqlBuilder) bind(value interface{}) string {
+	b.args = append(b.args, value)
+	if b.isOrdinal {
+		return fmt.Sprintf("%s%d", b.prefix, len(b.args))
+	}
+	return "?"
+}
+
+// getSqlValue converts the value of a column to its type, so that the databases comparing strictly,
+// like PostgreSQL, accept it for the integer and boolean columns
+func getSqlValue(tableColumn *TableColumn, value string) interface{} {
+	switch tableColumn.Type {
+	case "integer":
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return i
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
+
+func (syncer *Syncer) getSqlDb() (*sql.DB, error) {
+	if syncer.Ormer == nil || syncer.Ormer.Engine == nil {
+		return nil, fmt.Errorf("the database of the syncer: %s is not connected", syncer.Name)
+	}
+	return syncer.Ormer.Engine.DB().DB, nil
+}
+
+// querySyncerRows runs a query of the syncer and returns its rows, keyed by the column names of the database
+func (syncer *Syncer) querySyncerRows(query string, args ...interface{}) ([]map[string]sql.NullString, error) {
+	db, err := syncer.getSqlDb()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	results := []map[string]sql.NullString{}
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+
+		err = rows.Scan(pointers...)
+		if err != nil {
+			return nil, err
+		}
+
+		result := map[string]sql.NullString{}
+		for i, column := range columns {
+			result[column] = values[i]
+		}
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+func (syncer *Syncer) execSyncerStatement(query string, args ...interface{}) (int64, error) {
+	db, err := syncer.getSqlDb()
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := db.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// getWritableColumns returns the columns written to the table, the columns joining several ones like
+// "LAST_NAME+FIRST_NAME" are only read
+func (syncer *Syncer) getWritableColumns() []*TableColumn {
+	res := []*TableColumn{}
+	for _, tableColumn := range syncer.TableColumns {
+		if !strings.Contains(tableColumn.Name, "+") {
+			res = append(res, tableColumn)
+		}
+	}
+	return res
+}
+
+func (syncer *Syncer) getSelectStatement() string {
+	b := syncer.newSqlBuilder()
+	return fmt.Sprintf("SELECT * FROM %s", b.quote(syncer.Table))
+}
+
+func (syncer *Syncer) getInsertStatement(m map[string]string) (string, []interface{}) {
+	b := syncer.newSqlBuilder()
+	columns := []string{}
+	values := []string{}
+	for _, tableColumn := range syncer.getWritableColumns() {
+		columns = append(columns, b.quote(tableColumn.Name))
+		values = append(values, b.bind(getSqlValue(tableColumn, m[tableColumn.Name])))
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", b.quote(syncer.Table), strings.Join(columns, ", "), strings.Join(values, ", "))
+	return query, b.args
+}
+
+func (syncer *Syncer) getUpdateStatement(m map[string]string) (string, []interface{}) {
+	b := syncer.newSqlBuilder()
+	keyColumn := syncer.getKeyColumn()
+
+	tokens := []string{}
+	for _, tableColumn := range syncer.getWritableColumns() {
+		if tableColumn.Name == keyColumn.Name {
+			continue
+		}
+		tokens = append(tokens, fmt.Sprintf("%s = %s", b.quote(tableColumn.Name), b.bind(getSqlValue(tableColumn, m[tableColumn.Name]))))
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s = %s", b.quote(syncer.Table), strings.Join(tokens, ", "),
+		b.quote(keyColumn.Name), b.bind(getSqlValue(keyColumn, m[keyColumn.Name])))
+	return query, b.args
+}
+
+// getKeycloakCredentialStatement returns the query of the password of a Keycloak user
+func (syncer *Syncer) getKeycloakCredentialStatement(userId string) (string, []interface{}) {
+	b := syncer.newSqlBuilder()
+	query := fmt.Sprintf("SELECT * FROM %s WHERE %s = %s AND %s = %s", b.quote("credential"),
+		b.quote("type"), b.bind("password"), b.quote("user_id"), b.bind(userId))
+	return query, b.args
+}
+
+// getKeycloakGroupStatement returns the query of the group name of a Keycloak user
+func (syncer *Syncer) getKeycloakGroupStatement(userId string) (string, []interface{}) {
+	b := syncer.newSqlBuilder()
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s IN (SELECT %s FROM %s WHERE %s = %s)", b.quote("name"), b.quote("keycloak_group"),
+		b.quote("id"), b.quote("group_id"), b.quote("user_group_membership"), b.quote("user_id"), b.bind(userId))
+	return query, b.args
+}
+
+// getUserKeyCondition returns the condition of a user of Casdoor by the key of the syncer
+func (syncer *Syncer) getUserKeyCondition(key string) string {
+	return fmt.Sprintf("%s = ? and owner = ?", ormer.Engine.Quote(key))
+}
@@ -0,0 +1,259 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/xorm-io/xorm"
+)
+
+var hostileSqlValues = []string{
+	"O'Brien",
+	"x'); DROP TABLE \"user's table\"; --",
+	`"double" \ back\slash`,
+	"? $1 @p1 :name",
+	"' OR '1'='1",
+	"`tick` [bracket]] ;",
+	"ünïcødé 名前",
+}
+
+func newTestSqliteSyncer(t *testing.T) *Syncer {
+	engine, err := xorm.NewEngine("sqlite", filepath.Join(t.TempDir(), "syncer.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		engine.Close()
+	})
+
+	_, err = engine.Exec("CREATE TABLE \"user's table\" (\"id\" TEXT PRIMARY KEY, \"display \"\"name\"\"\" TEXT, \"e-mail\" TEXT, \"score\" INTEGER, \"is_admin\" BOOLEAN)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Syncer{
+		Name:         "syncer-sql-test",
+		DatabaseType: "sqlite",
+		Table:        "user's table",
+		TableColumns: []*TableColumn{
+			{Name: "id", Type: "string", CasdoorName: "Name", IsKey: true},
+			{Name: "display \"name\"", Type: "string", CasdoorName: "DisplayName"},
+			{Name: "e-mail", Type: "string", CasdoorName: "Email"},
+			{Name: "score", Type: "integer", CasdoorName: "Score"},
+			{Name: "is_admin", Type: "boolean", CasdoorName: "IsAdmin"},
+		},
+		Ormer: &Ormer{Engine: engine},
+	}
+}
+
+func TestSyncerSqlHostileValues(t *testing.T) {
+	syncer := newTestSqliteSyncer(t)
+
+	expected := map[string]*OriginalUser{}
+	for i, value := range hostileSqlValues {
+		user := &OriginalUser{Name: value, DisplayName: value + " display", Email: value + "@example.com", Score: i, IsAdmin: i%2 == 0}
+		expected[value] = user
+
+		affected, err := syncer.addUser(user)
+		if err != nil {
+			t.Fatalf("%s: %s", value, err.Error())
+		}
+		if !affected {
+			t.Fatalf("%s: the user should be added", value)
+		}
+	}
+
+	users, err := syncer.getOriginalUsers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != len(hostileSqlValues) {
+		t.Fatalf("got %d users, expected %d", len(users), len(hostileSqlValues))
+	}
+	for _, user := range users {
+		e := expected[user.Name]
+		if e == nil || user.DisplayName != e.DisplayName || user.Email != e.Email || user.Score != e.Score || user.IsAdmin != e.IsAdmin {
+			t.Fatalf("unexpected user: %s, %s, %s, %d, %v", user.Name, user.DisplayName, user.Email, user.Score, user.IsAdmin)
+		}
+	}
+
+	// only the user with the hostile key is updated
+	updated := &OriginalUser{Name: "' OR '1'='1", DisplayName: "'; DELETE FROM \"user's table\"; --", Email: "updated@example.com", Score: 42}
+	affected, err := syncer.updateUser(updated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !affected {
+		t.Fatal("the user should be updated")
+	}
+
+	users, err = syncer.getOriginalUsers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != len(hostileSqlValues) {
+		t.Fatalf("got %d users after the update, expected %d", len(users), len(hostileSqlValues))
+	}
+	for _, user := range users {
+		if user.Name == updated.Name {
+			if user.DisplayName != updated.DisplayName || user.Email != updated.Email || user.Score != 42 || user.IsAdmin {
+				t.Fatalf("unexpected updated user: %v", user)
+			}
+		} else if user.Email == updated.Email {
+			t.Fatalf("the user: %s should not be updated", user.Name)
+		}
+	}
+}
+
+func TestSyncerSqlStatements(t *testing.T) {
+	m := map[string]string{"uid": "alice", "mail": "alice@example.com", "score": "7", "first+last": "Alice Smith"}
+	tableColumns := []*TableColumn{
+		{Name: "uid", Type: "string", CasdoorName: "Name", IsKey: true},
+		{Name: "mail", Type: "string", CasdoorName: "Email"},
+		{Name: "score", Type: "integer", CasdoorName: "Score"},
+		{Name: "first+last", Type: "string", CasdoorName: "DisplayName"},
+	}
+
+	tests := []struct {
+		databaseType string
+		isSsh        bool
+		insert       string
+		update       string
+	}{
+		{
+			databaseType: "mysql",
+			insert:       "INSERT INTO `app`.`users` (`uid`, `mail`, `score`) VALUES (?, ?, ?)",
+			update:       "UPDATE `app`.`users` SET `mail` = ?, `score` = ? WHERE `uid` = ?",
+		},
+		{
+			databaseType: "postgres",
+			insert:       `INSERT INTO "app"."users" ("uid", "mail", "score") VALUES ($1, $2, $3)`,
+			update:       `UPDATE "app"."users" SET "mail" = $1, "score" = $2 WHERE "uid" = $3`,
+		},
+		{
+			databaseType: "mssql",
+			insert:       "INSERT INTO [app].[users] ([uid], [mail], [score]) VALUES (?, ?, ?)",
+			update:       "UPDATE [app].[users] SET [mail] = ?, [score] = ? WHERE [uid] = ?",
+		},
+		{
+			databaseType: "mssql",
+			isSsh:        true,
+			insert:       "INSERT INTO [app].[users] ([uid], [mail], [score]) VALUES (@p1, @p2, @p3)",
+			update:       "UPDATE [app].[users] SET [mail] = @p1, [score] = @p2 WHERE [uid] = @p3",
+		},
+		{
+			databaseType: "sqlite",
+			insert:       `INSERT INTO "app"."users" ("uid", "mail", "score") VALUES (?, ?, ?)`,
+			update:       `UPDATE "app"."users" SET "mail" = ?, "score" = ? WHERE "uid" = ?`,
+		},
+	}
+
+	for _, test := range tests {
+		syncer := &Syncer{DatabaseType: test.databaseType, Table: "app.users", TableColumns: tableColumns}
+		if test.isSsh {
+			// the connections through SSH are opened from a connector
+			syncer.Ormer = &Ormer{Db: &sql.DB{}}
+		}
+
+		query, args := syncer.getInsertStatement(m)
+		if query != test.insert {
+			t.Fatalf("%s: unexpected insert: %s", test.databaseType, query)
+		}
+		if !reflect.DeepEqual(args, []interface{}{"alice", "alice@example.com", int64(7)}) {
+			t.Fatalf("%s: unexpected insert args: %v", test.databaseType, args)
+		}
+
+		query, args = syncer.getUpdateStatement(m)
+		if query != test.update {
+			t.Fatalf("%s: unexpected update: %s", test.databaseType, query)
+		}
+		if !reflect.DeepEqual(args, []interface{}{"alice@example.com", int64(7), "alice"}) {
+			t.Fatalf("%s: unexpected update args: %v", test.databaseType, args)
+		}
+	}
+
+	// the names of PostgreSQL are folded to lower case like when they were not quoted, unless they need the quotes
+	b := (&Syncer{DatabaseType: "postgres"}).newSqlBuilder()
+	if res := b.quote("App.UserName"); res != `"app"."username"` {
+		t.Fatalf("unexpected quoted name: %s", res)
+	}
+	if res := b.quote("E-Mail"); res != `"E-Mail"` {
+		t.Fatalf("unexpected quoted name: %s", res)
+	}
+
+	b = (&Syncer{DatabaseType: "mssql"}).newSqlBuilder()
+	if res := b.quote("a]b"); res != "[a]]b]" {
+		t.Fatalf("unexpected quoted name: %s", res)
+	}
+	b = (&Syncer{DatabaseType: "mysql"}).newSqlBuilder()
+	if res := b.quote("a`b"); res != "`a``b`" {
+		t.Fatalf("unexpected quoted name: %s", res)
+	}
+}
+
+func TestSyncerSqlKeycloakStatements(t *testing.T) {
+	syncer := newTestSqliteSyncer(t)
+	for _, statement := range []string{
+		"CREATE TABLE credential (type TEXT, user_id TEXT, SECRET_DATA TEXT)",
+		"CREATE TABLE keycloak_group (id TEXT, name TEXT)",
+		"CREATE TABLE user_group_membership (group_id TEXT, user_id TEXT)",
+	} {
+		_, err := syncer.execSyncerStatement(statement)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i, userId := range hostileSqlValues {
+		secretData := fmt.Sprintf(`{"value": "hash-%d", "salt": "salt-%d"}`, i, i)
+		_, err := syncer.execSyncerStatement("INSERT INTO credential (type, user_id, SECRET_DATA) VALUES (?, ?, ?)", "password", userId, secretData)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = syncer.execSyncerStatement("INSERT INTO keycloak_group (id, name) VALUES (?, ?)", fmt.Sprintf("group-%d", i), fmt.Sprintf("app-%d", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = syncer.execSyncerStatement("INSERT INTO user_group_membership (group_id, user_id) VALUES (?, ?)", fmt.Sprintf("group-%d", i), userId)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i, userId := range hostileSqlValues {
+		query, args := syncer.getKeycloakCredentialStatement(userId)
+		rows, err := syncer.querySyncerRows(query, args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 1 || rows[0]["SECRET_DATA"].String != fmt.Sprintf(`{"value": "hash-%d", "salt": "salt-%d"}`, i, i) {
+			t.Fatalf("%s: unexpected credentials: %v", userId, rows)
+		}
+
+		query, args = syncer.getKeycloakGroupStatement(userId)
+		rows, err = syncer.querySyncerRows(query, args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 1 || rows[0]["name"].String != fmt.Sprintf("app-%d", i) {
+			t.Fatalf("%s: unexpected groups: %v", userId, rows)
+		}
+	}
+}
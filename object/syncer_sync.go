@@ -15,52 +15,107 @@
 package object
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/casdoor/casdoor/util"
 )
 
+// the conflict policies decide which side is kept when a user changed both in Casdoor and in the source
+const (
+	SyncerConflictSourceWins     = "SourceWins"
+	SyncerConflictCasdoorWins    = "CasdoorWins"
+	SyncerConflictNewestWins     = "NewestWins"
+	SyncerConflictFieldOwnership = "FieldOwnership"
+)
+
+// the deletion policies decide what happens to the users of the syncer removed from the source, instead of
+// being pushed back to a source that is also pushed
+const (
+	SyncerDeletionIgnore  = "Ignore"
+	SyncerDeletionDisable = "Disable"
+	SyncerDeletionDelete  = "Delete"
+)
+
+// the property of the users created by a syncer or found in its source, only those are disabled or deleted with the source
+const syncerUserProperty = "syncer"
+
+var syncerTimeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04:05"}
+
 func (syncer *Syncer) syncUsers() error {
-	if len(syncer.TableColumns) == 0 {
-		return fmt.Errorf("The syncer table columns should not be empty")
+	_, err := syncer.runSyncUsers(false)
+	return err
+}
+
+// runSyncUsers syncs the users and keeps the report of the run, a dry run only reports what would be changed
+func (syncer *Syncer) runSyncUsers(isDryRun bool) (*SyncerRun, error) {
+	startTime := time.Now()
+	run := newSyncerRun(syncer, isDryRun)
+
+	err := syncer.syncUsersForRun(run)
+	if errors.Is(err, errSyncerSourceUnchanged) {
+		run.State = SyncerRunStateSkipped
+		return run, nil
 	}
+	run.finish(startTime, err)
 
-	fmt.Printf("Running syncUsers()..\n")
+	if !isDryRun && run.State == SyncerRunStateFailed {
+		line := fmt.Sprintf("[%s] %d users failed to sync, see the syncer run: %s\n", util.GetCurrentTime(), run.Failed, run.Name)
+		if err != nil {
+			line = fmt.Sprintf("[%s] %s\n", util.GetCurrentTime(), err.Error())
+		}
 
-	users, err := GetUsers(syncer.Organization)
-	if err != nil {
-		line := fmt.Sprintf("[%s] %s\n", util.GetCurrentTime(), err.Error())
 		_, err2 := updateSyncerErrorText(syncer, line)
 		if err2 != nil {
 			panic(err2)
 		}
+	}
+
+	if run.isWorthKeeping() {
+		_, err2 := addSyncerRun(run)
+		if err2 != nil && err == nil {
+			err = err2
+		}
+	}
+	return run, err
+}
 
+func (syncer *Syncer) syncUsersForRun(run *SyncerRun) error {
+	if len(syncer.TableColumns) == 0 {
+		return fmt.Errorf("The syncer table columns should not be empty")
+	}
+
+	fmt.Printf("Running syncUsers()..\n")
+
+	users, err := GetUsers(syncer.Organization)
+	if err != nil {
 		return err
 	}
 
-	oUsers, err := syncer.getOriginalUsers()
+	provider, err := syncer.getInitProvider()
 	if err != nil {
-		line := fmt.Sprintf("[%s] %s\n", util.GetCurrentTime(), err.Error())
-		_, err2 := updateSyncerErrorText(syncer, line)
-		if err2 != nil {
-			panic(err2)
-		}
+		return err
+	}
+
+	tracker, isTracker := provider.(syncerSourceTracker)
+	if isTracker && run.IsDryRun {
+		tracker.force()
+	}
 
+	oUsers, err := provider.getOriginalUsers()
+	if err != nil {
 		return err
 	}
 
 	fmt.Printf("Users: %d, oUsers: %d\n", len(users), len(oUsers))
+	run.UserCount = len(users)
+	run.SourceCount = len(oUsers)
 
 	var affiliationMap map[int]string
-	if syncer.AffiliationTable != "" {
+	if _, ok := provider.(*databaseSyncerProvider); ok && syncer.AffiliationTable != "" {
 		_, affiliationMap, err = syncer.getAffiliationMap()
 		if err != nil {
-			line := fmt.Sprintf("[%s] %s\n", util.GetCurrentTime(), err.Error())
-			_, err2 := updateSyncerErrorText(syncer, line)
-			if err2 != nil {
-				panic(err2)
-			}
-
 			return err
 		}
 	}
@@ -78,95 +133,289 @@ func (syncer *Syncer) syncUsers() error {
 	}
 
 	newUsers := []*User{}
-	for _, oUser := range oUsers {
-		primary := syncer.getUserValue(oUser, key)
+	if provider.canPull() {
+		for _, oUser := range oUsers {
+			primary := syncer.getUserValue(oUser, key)
 
-		if _, ok := myUsers[primary]; !ok {
-			newUser := syncer.createUserFromOriginalUser(oUser, affiliationMap)
-			fmt.Printf("New user: %v\n", newUser)
-			newUsers = append(newUsers, newUser)
-		} else {
-			user := myUsers[primary]
-			oHash := syncer.calculateHash(oUser)
-			if user.Hash == user.PreHash {
-				if user.Hash != oHash {
-					updatedUser := syncer.createUserFromOriginalUser(oUser, affiliationMap)
-					updatedUser.Hash = oHash
-					updatedUser.PreHash = oHash
-
-					fmt.Printf("Update from oUser to user: %v\n", updatedUser)
-					_, err = syncer.updateUserForOriginalFields(updatedUser, key)
-					if err != nil {
-						return err
-					}
-				}
-			} else {
-				if user.PreHash == oHash {
-					if !syncer.IsReadOnly {
-						updatedOUser := syncer.createOriginalUserFromUser(user)
-
-						fmt.Printf("Update from user to oUser: %v\n", updatedOUser)
-						_, err = syncer.updateUser(updatedOUser)
-						if err != nil {
-							return err
-						}
-					}
-
-					// update preHash
-					user.PreHash = user.Hash
-					_, err = SetUserField(user, "pre_hash", user.PreHash)
-					if err != nil {
-						return err
-					}
-				} else {
-					if user.Hash == oHash {
-						// update preHash
-						user.PreHash = user.Hash
-						_, err = SetUserField(user, "pre_hash", user.PreHash)
-						if err != nil {
-							return err
-						}
-					} else {
-						updatedUser := syncer.createUserFromOriginalUser(oUser, affiliationMap)
-						updatedUser.Hash = oHash
-						updatedUser.PreHash = oHash
-
-						fmt.Printf("Update from oUser to user (2nd condition): %v\n", updatedUser)
-						_, err = syncer.updateUserForOriginalFields(updatedUser, key)
-						if err != nil {
-							return err
-						}
-					}
-				}
+			user, ok := myUsers[primary]
+			if !ok {
+				newUser := syncer.createUserFromOriginalUser(oUser, affiliationMap)
+				newUser.Properties[syncerUserProperty] = syncer.Name
+				fmt.Printf("New user: %v\n", newUser)
+				run.addChange(primary, SyncerActionAdd, SyncerTargetCasdoor, nil, false)
+				newUsers = append(newUsers, newUser)
+				continue
+			}
+
+			err = syncer.reconcileUser(run, provider, user, oUser, affiliationMap, key)
+			if err == nil {
+				err = syncer.claimUser(run, user)
+			}
+			if err != nil {
+				run.addError(primary, err)
 			}
 		}
 	}
 
-	if len(newUsers) != 0 {
+	if len(newUsers) != 0 && !run.IsDryRun {
 		_, err = AddUsersInBatch(newUsers)
 		if err != nil {
 			return err
 		}
 	}
 
-	if !syncer.IsReadOnly {
-		for _, user := range users {
-			primary := syncer.getUserValue(user, key)
-			if _, ok := myOUsers[primary]; !ok {
-				newOUser := syncer.createOriginalUserFromUser(user)
+	isRemoving := syncer.DeletionPolicy == SyncerDeletionDisable || syncer.DeletionPolicy == SyncerDeletionDelete
+	if isRemoving && len(oUsers) == 0 {
+		return fmt.Errorf("the source of the syncer has no users, the users of the organization are not removed")
+	}
+
+	for _, user := range users {
+		primary := syncer.getUserValue(user, key)
+		oUser := myOUsers[primary]
 
-				fmt.Printf("New oUser: %v\n", newOUser)
-				_, err = syncer.addUser(newOUser)
-				if err != nil {
-					return err
-				}
-			}
+		// a user of the syncer missing from the source has been removed from it, the other ones are pushed to it
+		if oUser == nil && isRemoving && user.Properties[syncerUserProperty] == syncer.Name {
+			err = syncer.removeUser(run, user, primary)
+		} else if provider.canPush() {
+			err = syncer.pushUser(run, provider, user, oUser, primary)
+		} else {
+			continue
+		}
+		if err != nil {
+			run.addError(primary, err)
 		}
 	}
 
+	if isTracker && !run.IsDryRun && run.Failed == 0 {
+		tracker.commit()
+	}
 	return nil
 }
 
+// reconcileUser syncs a user found both in Casdoor and in the source. The hash is the one of the user when it
+// was changed in Casdoor, the pre-hash is the one of the last sync, so each side tells whether it changed since.
+func (syncer *Syncer) reconcileUser(run *SyncerRun, provider syncerProvider, user *User, oUser *OriginalUser, affiliationMap map[int]string, key string) error {
+	primary := syncer.getUserValue(user, key)
+	if syncer.ConflictPolicy == SyncerConflictFieldOwnership {
+		return syncer.reconcileOwnedFields(run, provider, user, oUser, affiliationMap, key)
+	}
+
+	oHash := syncer.calculateHash(oUser)
+	if user.Hash == user.PreHash {
+		if user.Hash != oHash {
+			return syncer.updateCasdoorUser(run, user, oUser, oHash, affiliationMap, key, false)
+		}
+		return nil
+	}
+
+	if user.PreHash == oHash {
+		if provider.canPush() {
+			err := syncer.updateSourceUser(run, provider, syncer.createOriginalUserFromUser(user), oUser, primary, false)
+			if err != nil {
+				return err
+			}
+		}
+		return syncer.setUserPreHash(run, user, user.Hash)
+	}
+
+	if user.Hash == oHash {
+		return syncer.setUserPreHash(run, user, user.Hash)
+	}
+
+	// the user changed both in Casdoor and in the source since the last sync
+	run.Conflicts++
+	if !syncer.isCasdoorWinning(user, oUser) {
+		return syncer.updateCasdoorUser(run, user, oUser, oHash, affiliationMap, key, true)
+	}
+	if !provider.canPush() {
+		// the user is kept as is in Casdoor until it changes in the source again
+		return nil
+	}
+
+	err := syncer.updateSourceUser(run, provider, syncer.createOriginalUserFromUser(user), oUser, primary, true)
+	if err != nil {
+		return err
+	}
+	return syncer.setUserPreHash(run, user, user.Hash)
+}
+
+// reconcileOwnedFields keeps each field from the side owning it, whatever changed since the last sync
+func (syncer *Syncer) reconcileOwnedFields(run *SyncerRun, provider syncerProvider, user *User, oUser *OriginalUser, affiliationMap map[int]string, key string) error {
+	casdoorUser := syncer.createOriginalUserFromUser(user)
+	merged := syncer.createOriginalUserFromUser(user)
+	m := syncer.getMapFromOriginalUser(oUser)
+	for _, tableColumn := range syncer.TableColumns {
+		if tableColumn.Owner != SyncerTargetCasdoor {
+			syncer.setUserByKeyValue(merged, tableColumn.CasdoorName, m[tableColumn.Name])
+		}
+	}
+
+	if len(syncer.getChangedFields(casdoorUser, merged)) != 0 {
+		err := syncer.updateCasdoorUser(run, user, merged, syncer.calculateHash(merged), affiliationMap, key, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !provider.canPush() {
+		return nil
+	}
+	return syncer.updateSourceUser(run, provider, merged, oUser, syncer.getUserValue(user, key), false)
+}
+
+func (syncer *Syncer) isCasdoorWinning(user *User, oUser *OriginalUser) bool {
+	switch syncer.ConflictPolicy {
+	case SyncerConflictCasdoorWins:
+		return true
+	case SyncerConflictNewestWins:
+		// the source wins when the updated times are unknown, like when the column is not synced
+		casdoorTime, ok := parseSyncerTime(user.UpdatedTime)
+		if !ok {
+			return false
+		}
+		sourceTime, ok := parseSyncerTime(oUser.UpdatedTime)
+		if !ok {
+			return false
+		}
+		return casdoorTime.After(sourceTime)
+	default:
+		return false
+	}
+}
+
+func parseSyncerTime(s string) (time.Time, bool) {
+	for _, layout := range syncerTimeLayouts {
+		t, err := time.Parse(layout, s)
+		if err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// getChangedFields returns the Casdoor names of the columns whose values differ between the users
+func (syncer *Syncer) getChangedFields(user *OriginalUser, updatedUser *OriginalUser) []string {
+	m := syncer.getMapFromOriginalUser(user)
+	updatedM := syncer.getMapFromOriginalUser(updatedUser)
+
+	fields := []string{}
+	for _, tableColumn := range syncer.TableColumns {
+		if m[tableColumn.Name] != updatedM[tableColumn.Name] {
+			fields = append(fields, tableColumn.CasdoorName)
+		}
+	}
+	return fields
+}
+
+func (syncer *Syncer) updateCasdoorUser(run *SyncerRun, user *User, oUser *OriginalUser, oHash string, affiliationMap map[int]string, key string, isConflict bool) error {
+	fields := syncer.getChangedFields(syncer.createOriginalUserFromUser(user), oUser)
+	if len(fields) != 0 {
+		run.addChange(syncer.getUserValue(user, key), SyncerActionUpdate, SyncerTargetCasdoor, fields, isConflict)
+	}
+	if run.IsDryRun {
+		return nil
+	}
+
+	updatedUser := syncer.createUserFromOriginalUser(oUser, affiliationMap)
+	updatedUser.Hash = oHash
+	updatedUser.PreHash = oHash
+
+	fmt.Printf("Update from oUser to user: %v\n", updatedUser)
+	_, err := syncer.updateUserForOriginalFields(updatedUser, key)
+	return err
+}
+
+func (syncer *Syncer) updateSourceUser(run *SyncerRun, provider syncerProvider, updatedOUser *OriginalUser, oUser *OriginalUser, primary string, isConflict bool) error {
+	fields := syncer.getChangedFields(oUser, updatedOUser)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	run.addChange(primary, SyncerActionUpdate, SyncerTargetSource, fields, isConflict)
+	if run.IsDryRun {
+		return nil
+	}
+
+	fmt.Printf("Update from user to oUser: %v\n", updatedOUser)
+	_, err := provider.updateUser(updatedOUser)
+	return err
+}
+
+// pushUser adds a user of Casdoor missing from the source, a push target having no hashes of its own,
+// the user is also pushed as soon as it differs
+func (syncer *Syncer) pushUser(run *SyncerRun, provider syncerProvider, user *User, oUser *OriginalUser, primary string) error {
+	if oUser == nil {
+		newOUser := syncer.createOriginalUserFromUser(user)
+		run.addChange(primary, SyncerActionAdd, SyncerTargetSource, nil, false)
+		if run.IsDryRun {
+			return nil
+		}
+
+		fmt.Printf("New oUser: %v\n", newOUser)
+		_, err := provider.addUser(newOUser)
+		return err
+	}
+
+	if provider.canPull() {
+		return nil
+	}
+	return syncer.updateSourceUser(run, provider, syncer.createOriginalUserFromUser(user), oUser, primary, false)
+}
+
+// claimUser marks a user found in the source as a user of the syncer, so that the users synced before
+// the property existed, or pushed to the source, are also removed with the source
+func (syncer *Syncer) claimUser(run *SyncerRun, user *User) error {
+	if run.IsDryRun || user.Properties[syncerUserProperty] != "" {
+		return nil
+	}
+
+	if user.Properties == nil {
+		user.Properties = map[string]string{}
+	}
+	user.Properties[syncerUserProperty] = syncer.Name
+	_, err := UpdateUser(user.GetId(), user, []string{"properties"}, false)
+	return err
+}
+
+func (syncer *Syncer) setUserPreHash(run *SyncerRun, user *User, preHash string) error {
+	if run.IsDryRun || user.PreHash == preHash {
+		return nil
+	}
+
+	user.PreHash = preHash
+	_, err := SetUserField(user, "pre_hash", user.PreHash)
+	return err
+}
+
+// removeUser disables or deletes a user created by the syncer and removed from the source since
+func (syncer *Syncer) removeUser(run *SyncerRun, user *User, primary string) error {
+	switch syncer.DeletionPolicy {
+	case SyncerDeletionDisable:
+		if user.IsForbidden {
+			return nil
+		}
+
+		run.addChange(primary, SyncerActionDisable, SyncerTargetCasdoor, nil, false)
+		if run.IsDryRun {
+			return nil
+		}
+
+		user.IsForbidden = true
+		_, err := UpdateUser(user.GetId(), user, []string{"is_forbidden"}, false)
+		return err
+	case SyncerDeletionDelete:
+		run.addChange(primary, SyncerActionDelete, SyncerTargetCasdoor, nil, false)
+		if run.IsDryRun {
+			return nil
+		}
+
+		_, err := DeleteUser(user)
+		return err
+	default:
+		return nil
+	}
+}
+
 func (syncer *Syncer) syncUsersNoError() {
 	err := syncer.syncUsers()
 	if err != nil {
@@ -0,0 +1,237 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testSyncerProvider struct {
+	isPushed bool
+}
+
+func (p *testSyncerProvider) init() error {
+	return nil
+}
+
+func (p *testSyncerProvider) getOriginalUsers() ([]*OriginalUser, error) {
+	return nil, nil
+}
+
+func (p *testSyncerProvider) addUser(user *OriginalUser) (bool, error) {
+	return false, fmt.Errorf("a dry run should not add users")
+}
+
+func (p *testSyncerProvider) updateUser(user *OriginalUser) (bool, error) {
+	return false, fmt.Errorf("a dry run should not update users")
+}
+
+func (p *testSyncerProvider) canPull() bool {
+	return true
+}
+
+func (p *testSyncerProvider) canPush() bool {
+	return p.isPushed
+}
+
+func getSyncerRunChanges(run *SyncerRun) []string {
+	res := []string{}
+	for _, change := range run.Changes {
+		res = append(res, fmt.Sprintf("%s %s %s %s %v", change.Action, change.Target, change.User, strings.Join(change.Fields, ","), change.IsConflict))
+	}
+	return res
+}
+
+func TestReconcileUser(t *testing.T) {
+	syncer := &Syncer{
+		Organization: "org",
+		TableColumns: []*TableColumn{
+			{Name: "uid", CasdoorName: "Name", IsKey: true, IsHashed: true},
+			{Name: "mail", CasdoorName: "Email", IsHashed: true},
+			{Name: "phone", CasdoorName: "Phone", IsHashed: true, Owner: SyncerTargetCasdoor},
+			{Name: "modified", CasdoorName: "UpdatedTime"},
+		},
+	}
+
+	synced := &User{Owner: "org", Name: "alice", Email: "alice@example.com", Phone: "123"}
+	syncedHash := syncer.calculateHash(synced)
+	changedInCasdoor := &User{Owner: "org", Name: "alice", Email: "alice@casdoor.com", Phone: "456", UpdatedTime: "2024-05-02T00:00:00Z"}
+
+	tests := []struct {
+		name           string
+		conflictPolicy string
+		isPushed       bool
+		user           *User
+		oUser          *User
+		expected       []string
+		conflicts      int
+	}{
+		{
+			name:     "unchanged",
+			user:     synced,
+			oUser:    synced,
+			expected: []string{},
+		},
+		{
+			name:     "changed in the source",
+			user:     synced,
+			oUser:    &User{Name: "alice", Email: "alice@source.com", Phone: "123"},
+			expected: []string{"Update Casdoor alice Email false"},
+		},
+		{
+			name:     "changed in Casdoor",
+			isPushed: true,
+			user:     changedInCasdoor,
+			oUser:    synced,
+			expected: []string{"Update Source alice Email,Phone,UpdatedTime false"},
+		},
+		{
+			name:      "conflict won by the source",
+			isPushed:  true,
+			user:      changedInCasdoor,
+			oUser:     &User{Name: "alice", Email: "alice@source.com", Phone: "123", UpdatedTime: "2024-05-01T00:00:00Z"},
+			expected:  []string{"Update Casdoor alice Email,Phone,UpdatedTime true"},
+			conflicts: 1,
+		},
+		{
+			name:           "conflict won by Casdoor",
+			conflictPolicy: SyncerConflictCasdoorWins,
+			isPushed:       true,
+			user:           changedInCasdoor,
+			oUser:          &User{Name: "alice", Email: "alice@source.com", Phone: "123"},
+			expected:       []string{"Update Source alice Email,Phone,UpdatedTime true"},
+			conflicts:      1,
+		},
+		{
+			name:           "conflict won by Casdoor without push",
+			conflictPolicy: SyncerConflictCasdoorWins,
+			user:           changedInCasdoor,
+			oUser:          &User{Name: "alice", Email: "alice@source.com", Phone: "123"},
+			expected:       []string{},
+			conflicts:      1,
+		},
+		{
+			name:           "conflict won by the newest Casdoor user",
+			conflictPolicy: SyncerConflictNewestWins,
+			isPushed:       true,
+			user:           changedInCasdoor,
+			oUser:          &User{Name: "alice", Email: "alice@source.com", Phone: "123", UpdatedTime: "2024-05-01 00:00:00"},
+			expected:       []string{"Update Source alice Email,Phone,UpdatedTime true"},
+			conflicts:      1,
+		},
+		{
+			name:           "conflict won by the newest source user",
+			conflictPolicy: SyncerConflictNewestWins,
+			isPushed:       true,
+			user:           changedInCasdoor,
+			oUser:          &User{Name: "alice", Email: "alice@source.com", Phone: "123", UpdatedTime: "2024-05-03T00:00:00Z"},
+			expected:       []string{"Update Casdoor alice Email,Phone,UpdatedTime true"},
+			conflicts:      1,
+		},
+		{
+			name:           "owned fields",
+			conflictPolicy: SyncerConflictFieldOwnership,
+			isPushed:       true,
+			user:           changedInCasdoor,
+			oUser:          &User{Name: "alice", Email: "alice@source.com", Phone: "123", UpdatedTime: "2024-05-01T00:00:00Z"},
+			expected: []string{
+				"Update Casdoor alice Email,UpdatedTime false",
+				"Update Source alice Phone false",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		user := *test.user
+		user.Hash = syncer.calculateHash(&user)
+		user.PreHash = syncedHash
+
+		syncer.ConflictPolicy = test.conflictPolicy
+		run := newSyncerRun(syncer, true)
+		err := syncer.reconcileUser(run, &testSyncerProvider{isPushed: test.isPushed}, &user, test.oUser, nil, "name")
+		if err != nil {
+			t.Fatalf("%s: %s", test.name, err.Error())
+		}
+
+		if res := getSyncerRunChanges(run); !reflect.DeepEqual(res, test.expected) {
+			t.Fatalf("%s: got %v, expected %v", test.name, res, test.expected)
+		}
+		if run.Conflicts != test.conflicts {
+			t.Fatalf("%s: got %d conflicts, expected %d", test.name, run.Conflicts, test.conflicts)
+		}
+		if user.PreHash != syncedHash {
+			t.Fatalf("%s: a dry run should not change the user", test.name)
+		}
+	}
+}
+
+func TestRemoveUser(t *testing.T) {
+	syncer := &Syncer{Name: "syncer-1", DeletionPolicy: SyncerDeletionDisable}
+	run := newSyncerRun(syncer, true)
+
+	err := syncer.removeUser(run, &User{Owner: "org", Name: "alice"}, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = syncer.removeUser(run, &User{Owner: "org", Name: "bob", IsForbidden: true}, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	syncer.DeletionPolicy = SyncerDeletionDelete
+	err = syncer.removeUser(run, &User{Owner: "org", Name: "carol"}, "carol")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	syncer.DeletionPolicy = SyncerDeletionIgnore
+	err = syncer.removeUser(run, &User{Owner: "org", Name: "dave"}, "dave")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"Disable Casdoor alice  false", "Delete Casdoor carol  false"}
+	if res := getSyncerRunChanges(run); !reflect.DeepEqual(res, expected) {
+		t.Fatalf("unexpected changes: %v", res)
+	}
+	if run.Disabled != 1 || run.Deleted != 1 || !run.isWorthKeeping() {
+		t.Fatalf("unexpected counts: %d disabled, %d deleted", run.Disabled, run.Deleted)
+	}
+}
+
+func TestClaimUser(t *testing.T) {
+	syncer := &Syncer{Name: "syncer-1"}
+
+	user := &User{Owner: "org", Name: "alice"}
+	err := syncer.claimUser(newSyncerRun(syncer, true), user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Properties[syncerUserProperty] != "" {
+		t.Fatalf("a dry run should not claim users: %v", user.Properties)
+	}
+
+	user = &User{Owner: "org", Name: "bob", Properties: map[string]string{syncerUserProperty: "syncer-2"}}
+	err = syncer.claimUser(newSyncerRun(syncer, false), user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Properties[syncerUserProperty] != "syncer-2" {
+		t.Fatalf("the user of another syncer should not be claimed: %v", user.Properties)
+	}
+}
@@ -26,6 +26,7 @@ import (
 
 	"github.com/casdoor/casdoor/util"
 	"github.com/go-sql-driver/mysql"
+	"github.com/xorm-io/xorm"
 )
 
 type OriginalUser = User
@@ -36,8 +37,7 @@ type Credential struct {
 }
 
 func (syncer *Syncer) getOriginalUsers() ([]*OriginalUser, error) {
-	var results []map[string]sql.NullString
-	err := syncer.Ormer.Engine.Table(syncer.getTable()).Find(&results)
+	results, err := syncer.querySyncerRows(syncer.getSelectStatement())
 	if err != nil {
 		return nil, err
 	}
@@ -56,7 +56,8 @@ func (syncer *Syncer) getOriginalUsers() ([]*OriginalUser, error) {
 
 func (syncer *Syncer) addUser(user *OriginalUser) (bool, error) {
 	m := syncer.getMapFromOriginalUser(user)
-	affected, err := syncer.Ormer.Engine.Table(syncer.getTable()).Insert(m)
+	query, args := syncer.getInsertStatement(m)
+	affected, err := syncer.execSyncerStatement(query, args...)
 	if err != nil {
 		return false, err
 	}
@@ -75,12 +76,9 @@ func (syncer *Syncer) getCasdoorColumns() []string {
 }
 
 func (syncer *Syncer) updateUser(user *OriginalUser) (bool, error) {
-	key := syncer.getKey()
 	m := syncer.getMapFromOriginalUser(user)
-	pkValue := m[key]
-	delete(m, key)
-
-	affected, err := syncer.Ormer.Engine.Table(syncer.getTable()).Where(fmt.Sprintf("%s = ?", key), pkValue).Update(&m)
+	query, args := syncer.getUpdateStatement(m)
+	affected, err := syncer.execSyncerStatement(query, args...)
 	if err != nil {
 		return false, err
 	}
@@ -91,7 +89,7 @@ func (syncer *Syncer) updateUserForOriginalFields(user *User, key string) (bool,
 	var err error
 	oldUser := User{}
 
-	existed, err := ormer.Engine.Where(key+" = ? and owner = ?", syncer.getUserValue(user, key), user.Owner).Get(&oldUser)
+	existed, err := ormer.Engine.Where(syncer.getUserKeyCondition(key), syncer.getUserValue(user, key), user.Owner).Get(&oldUser)
 	if err != nil {
 		return false, err
 	}
@@ -108,8 +106,22 @@ func (syncer *Syncer) updateUserForOriginalFields(user *User, key string) (bool,
 
 	columns := syncer.getCasdoorColumns()
 	columns = append(columns, "affiliation", "hash", "pre_hash")
-	affected, err := ormer.Engine.Where(key+" = ? and owner = ?", syncer.getUserValue(&oldUser, key), oldUser.Owner).Cols(columns...).Update(user)
+	// the user is matched by the key, its name is only changed when the name is a synced column
+	newUser := &oldUser
+	if util.ContainsString(columns, "name") {
+		newUser = user
+	}
+
+	err = encryptColumns(user)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := updateWithChangeEvents(&oldUser, newUser, func(session *xorm.Session) (int64, error) {
+		return session.Where(syncer.getUserKeyCondition(key), syncer.getUserValue(&oldUser, key), oldUser.Owner).Cols(columns...).Update(user)
+	})
 	if err != nil {
+		restoreEncryptedColumns(user)
 		return false, err
 	}
 
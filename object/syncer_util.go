@@ -208,48 +208,36 @@ func (syncer *Syncer) getUserValue(user *User, key string) string {
 func (syncer *Syncer) getOriginalUsersFromMap(results []map[string]sql.NullString) []*OriginalUser {
 	users := []*OriginalUser{}
 	for _, result := range results {
-		originalUser := &OriginalUser{
-			Address:    []string{},
-			Properties: map[string]string{},
-			Groups:     []string{},
-		}
-
-		for _, tableColumn := range syncer.TableColumns {
-			tableColumnName := tableColumn.Name
+		originalUser := syncer.getOriginalUserFromValues(func(name string) string {
 			if syncer.Type == "Keycloak" && syncer.DatabaseType == "postgres" {
-				tableColumnName = strings.ToLower(tableColumnName)
+				name = strings.ToLower(name)
 			}
-
-			value := ""
-			if strings.Contains(tableColumnName, "+") {
-				names := strings.Split(tableColumnName, "+")
-				var values []string
-				for _, name := range names {
-					values = append(values, result[strings.Trim(name, " ")].String)
-				}
-				value = strings.Join(values, " ")
-			} else {
-				value = result[tableColumnName].String
+			if value, ok := result[name]; ok {
+				return value.String
 			}
-			syncer.setUserByKeyValue(originalUser, tableColumn.CasdoorName, value)
-		}
+			return result[foldSqlName(syncer.DatabaseType, name)].String
+		})
 
 		if syncer.Type == "Keycloak" {
 			// query and set password and password salt from credential table
-			sql := fmt.Sprintf("select * from credential where type = 'password' and user_id = '%s'", originalUser.Id)
-			credentialResult, _ := syncer.Ormer.Engine.QueryString(sql)
+			query, args := syncer.getKeycloakCredentialStatement(originalUser.Id)
+			credentialResult, _ := syncer.querySyncerRows(query, args...)
 			if len(credentialResult) > 0 {
+				secretData, ok := credentialResult[0]["SECRET_DATA"]
+				if !ok {
+					secretData = credentialResult[0]["secret_data"]
+				}
+
 				credential := Credential{}
-				_ = json.Unmarshal([]byte(credentialResult[0]["SECRET_DATA"]), &credential)
+				_ = json.Unmarshal([]byte(secretData.String), &credential)
 				originalUser.Password = credential.Value
 				originalUser.PasswordSalt = credential.Salt
 			}
 			// query and set signup application from user group table
-			sql = fmt.Sprintf("select name from keycloak_group where id = "+
-				"(select group_id as gid from user_group_membership where user_id = '%s')", originalUser.Id)
-			groupResult, _ := syncer.Ormer.Engine.QueryString(sql)
+			query, args = syncer.getKeycloakGroupStatement(originalUser.Id)
+			groupResult, _ := syncer.querySyncerRows(query, args...)
 			if len(groupResult) > 0 {
-				originalUser.SignupApplication = groupResult[0]["name"]
+				originalUser.SignupApplication = groupResult[0]["name"].String
 			}
 			// create time
 			i, _ := strconv.ParseInt(originalUser.CreatedTime, 10, 64)
@@ -320,18 +308,3 @@ func (syncer *Syncer) getMapFromOriginalUser(user *OriginalUser) map[string]stri
 
 	return m2
 }
-
-func (syncer *Syncer) getSqlSetStringFromMap(m map[string]string) string {
-	typeMap := syncer.getTableColumnsTypeMap()
-
-	tokens := []string{}
-	for k, v := range m {
-		token := fmt.Sprintf("%s = %s", k, v)
-		if typeMap[k] == "string" {
-			token = fmt.Sprintf("%s = '%s'", k, v)
-		}
-
-		tokens = append(tokens, token)
-	}
-	return strings.Join(tokens, ", ")
-}
@@ -0,0 +1,245 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/beego/beego/logs"
+	"github.com/casdoor/casdoor/conf"
+)
+
+const (
+	TicketStoreMemory   = "Memory"
+	TicketStoreDatabase = "Database"
+	TicketStoreRedis    = "Redis"
+)
+
+// TicketStore keeps the short-lived tickets of the login flows: CAS tickets, device codes and SAML artifacts.
+// The memory store only works with a single replica, the database and Redis stores are shared by all the replicas.
+type TicketStore interface {
+	// Store keeps the value until the TTL has passed
+	Store(key string, value []byte, ttl time.Duration) error
+	// Load returns nil for a missing or expired ticket
+	Load(key string) ([]byte, error)
+	// LoadAndDelete returns the ticket to a single caller, even when several replicas ask for it at the same time
+	LoadAndDelete(key string) ([]byte, error)
+	// Update stores the value returned by update for the current one, nil for a missing or expired ticket.
+	// The concurrent updates of a ticket are all kept, update may be called again when another one comes first.
+	Update(key string, ttl time.Duration, update func(value []byte) ([]byte, error)) error
+	Delete(key string) error
+	// Purge drops the expired tickets the store doesn't expire by itself
+	Purge() error
+}
+
+type memoryTicket struct {
+	value      []byte
+	expireTime time.Time
+}
+
+type memoryTicketStore struct {
+	tickets sync.Map
+}
+
+func (ts *memoryTicketStore) Store(key string, value []byte, ttl time.Duration) error {
+	ts.tickets.Store(key, &memoryTicket{value: value, expireTime: time.Now().Add(ttl)})
+	return nil
+}
+
+func (ts *memoryTicketStore) Load(key string) ([]byte, error) {
+	value, ok := ts.tickets.Load(key)
+	if !ok || value.(*memoryTicket).expireTime.Before(time.Now()) {
+		return nil, nil
+	}
+	return value.(*memoryTicket).value, nil
+}
+
+func (ts *memoryTicketStore) LoadAndDelete(key string) ([]byte, error) {
+	value, ok := ts.tickets.LoadAndDelete(key)
+	if !ok || value.(*memoryTicket).expireTime.Before(time.Now()) {
+		return nil, nil
+	}
+	return value.(*memoryTicket).value, nil
+}
+
+func (ts *memoryTicketStore) Update(key string, ttl time.Duration, update func(value []byte) ([]byte, error)) error {
+	for {
+		old, ok := ts.tickets.Load(key)
+
+		var value []byte
+		if ok && !old.(*memoryTicket).expireTime.Before(time.Now()) {
+			value = old.(*memoryTicket).value
+		}
+		value, err := update(value)
+		if err != nil {
+			return err
+		}
+
+		ticket := &memoryTicket{value: value, expireTime: time.Now().Add(ttl)}
+		if ok {
+			if ts.tickets.CompareAndSwap(key, old, ticket) {
+				return nil
+			}
+		} else if _, loaded := ts.tickets.LoadOrStore(key, ticket); !loaded {
+			return nil
+		}
+	}
+}
+
+func (ts *memoryTicketStore) Delete(key string) error {
+	ts.tickets.Delete(key)
+	return nil
+}
+
+func (ts *memoryTicketStore) Purge() error {
+	now := time.Now()
+	ts.tickets.Range(func(key, value interface{}) bool {
+		if value.(*memoryTicket).expireTime.Before(now) {
+			ts.tickets.Delete(key)
+		}
+		return true
+	})
+	return nil
+}
+
+var (
+	ticketStore     TicketStore
+	ticketStoreErr  error
+	ticketStoreOnce sync.Once
+)
+
+// getTicketStoreName reads the "ticketStore" config, the tickets go to Redis by default when the sessions do
+func getTicketStoreName() string {
+	name := conf.GetConfigString("ticketStore")
+	if name != "" {
+		return name
+	}
+
+	if conf.GetConfigString("redisEndpoint") != "" {
+		return TicketStoreRedis
+	}
+	return TicketStoreMemory
+}
+
+func newTicketStore(name string) (TicketStore, error) {
+	switch name {
+	case TicketStoreMemory:
+		return &memoryTicketStore{}, nil
+	case TicketStoreDatabase:
+		return &databaseTicketStore{}, nil
+	case TicketStoreRedis:
+		return newRedisTicketStore(conf.GetConfigString("redisEndpoint"))
+	default:
+		return nil, fmt.Errorf("unsupported ticket store: %s", name)
+	}
+}
+
+func getTicketStore() (TicketStore, error) {
+	ticketStoreOnce.Do(func() {
+		ticketStore, ticketStoreErr = newTicketStore(getTicketStoreName())
+	})
+	return ticketStore, ticketStoreErr
+}
+
+func storeTicket(key string, value interface{}, ttl time.Duration) error {
+	ts, err := getTicketStore()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return ts.Store(key, data, ttl)
+}
+
+func unmarshalTicket(data []byte, value interface{}) (bool, error) {
+	if data == nil {
+		return false, nil
+	}
+
+	err := json.Unmarshal(data, value)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// loadTicket reads the ticket into value and tells whether it has been found
+func loadTicket(key string, value interface{}) (bool, error) {
+	ts, err := getTicketStore()
+	if err != nil {
+		return false, err
+	}
+
+	data, err := ts.Load(key)
+	if err != nil {
+		return false, err
+	}
+	return unmarshalTicket(data, value)
+}
+
+// takeTicket is loadTicket for a single-use ticket, which can't be found again afterwards
+func takeTicket(key string, value interface{}) (bool, error) {
+	ts, err := getTicketStore()
+	if err != nil {
+		return false, err
+	}
+
+	data, err := ts.LoadAndDelete(key)
+	if err != nil {
+		return false, err
+	}
+	return unmarshalTicket(data, value)
+}
+
+// updateTicket changes a ticket with update, see TicketStore.Update
+func updateTicket(key string, ttl time.Duration, update func(value []byte) ([]byte, error)) error {
+	ts, err := getTicketStore()
+	if err != nil {
+		return err
+	}
+	return ts.Update(key, ttl, update)
+}
+
+func deleteTicket(key string) error {
+	ts, err := getTicketStore()
+	if err != nil {
+		return err
+	}
+	return ts.Delete(key)
+}
+
+func RunTicketPurgeJob() {
+	ticker := time.NewTicker(time.Minute * 10)
+	defer ticker.Stop()
+
+	for ; true; <-ticker.C {
+		ts, err := getTicketStore()
+		if err != nil {
+			logs.Error(fmt.Sprintf("RunTicketPurgeJob() error: %s", err.Error()))
+			continue
+		}
+
+		err = ts.Purge()
+		if err != nil {
+			logs.Error(fmt.Sprintf("RunTicketPurgeJob() error: %s", err.Error()))
+		}
+	}
+}
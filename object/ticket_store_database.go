@@ -0,0 +1,138 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"time"
+
+	"github.com/xorm-io/xorm"
+)
+
+// the attempts of an update, another replica inserting the same ticket first makes it fail
+const ticketUpdateAttempts = 3
+
+// StoredTicket is a ticket of the database ticket store
+type StoredTicket struct {
+	Id         string `xorm:"varchar(200) notnull pk" json:"id"`
+	Value      string `xorm:"mediumtext" json:"value"`
+	ExpireTime int64  `xorm:"index" json:"expireTime"`
+}
+
+type databaseTicketStore struct{}
+
+func (ts *databaseTicketStore) Store(key string, value []byte, ttl time.Duration) error {
+	ticket := &StoredTicket{Id: key, Value: string(value), ExpireTime: time.Now().Add(ttl).UnixNano()}
+
+	// a ticket key is random, so it is only replaced when it has been stored again on purpose
+	_, err := ormer.Engine.ID(key).Delete(&StoredTicket{})
+	if err != nil {
+		return err
+	}
+
+	_, err = ormer.Engine.Insert(ticket)
+	return err
+}
+
+func (ts *databaseTicketStore) Load(key string) ([]byte, error) {
+	var ticket StoredTicket
+	existed, err := ormer.Engine.ID(key).Get(&ticket)
+	if err != nil {
+		return nil, err
+	}
+
+	if !existed || ticket.ExpireTime < time.Now().UnixNano() {
+		return nil, nil
+	}
+	return []byte(ticket.Value), nil
+}
+
+func (ts *databaseTicketStore) LoadAndDelete(key string) ([]byte, error) {
+	value, err := ts.Load(key)
+	if err != nil || value == nil {
+		return nil, err
+	}
+
+	// of the replicas reading the ticket at the same time, only the one that deletes it gets it
+	affected, err := ormer.Engine.ID(key).Delete(&StoredTicket{})
+	if err != nil {
+		return nil, err
+	}
+	if affected == 0 {
+		return nil, nil
+	}
+	return value, nil
+}
+
+func (ts *databaseTicketStore) Update(key string, ttl time.Duration, update func(value []byte) ([]byte, error)) error {
+	var err error
+	for i := 0; i < ticketUpdateAttempts; i++ {
+		err = func() error {
+			session := ormer.Engine.NewSession()
+			defer session.Close()
+
+			err := session.Begin()
+			if err != nil {
+				return err
+			}
+
+			err = ts.update(session, key, ttl, update)
+			if err != nil {
+				session.Rollback()
+				return err
+			}
+			return session.Commit()
+		}()
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// update locks the row of the ticket until the end of the transaction
+func (ts *databaseTicketStore) update(session *xorm.Session, key string, ttl time.Duration, update func(value []byte) ([]byte, error)) error {
+	var ticket StoredTicket
+	existed, err := session.ID(key).ForUpdate().Get(&ticket)
+	if err != nil {
+		return err
+	}
+
+	var value []byte
+	if existed && ticket.ExpireTime >= time.Now().UnixNano() {
+		value = []byte(ticket.Value)
+	}
+	value, err = update(value)
+	if err != nil {
+		return err
+	}
+
+	ticket = StoredTicket{Id: key, Value: string(value), ExpireTime: time.Now().Add(ttl).UnixNano()}
+	if existed {
+		_, err = session.ID(key).AllCols().Update(&ticket)
+	} else {
+		_, err = session.Insert(&ticket)
+	}
+	return err
+}
+
+func (ts *databaseTicketStore) Delete(key string) error {
+	_, err := ormer.Engine.ID(key).Delete(&StoredTicket{})
+	return err
+}
+
+func (ts *databaseTicketStore) Purge() error {
+	_, err := ormer.Engine.Where("expire_time < ?", time.Now().UnixNano()).Delete(&StoredTicket{})
+	return err
+}
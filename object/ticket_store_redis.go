@@ -0,0 +1,179 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const redisTicketKeyPrefix = "casdoor_ticket:"
+
+// redisGetDelScript is GETDEL for the Redis servers older than 6.2
+var redisGetDelScript = redis.NewScript(1, `local value = redis.call("GET", KEYS[1])
+if value then
+  redis.call("DEL", KEYS[1])
+end
+return value`)
+
+type redisTicketStore struct {
+	pool *redis.Pool
+}
+
+// newRedisPool connects to the "redisEndpoint" config, which has the same
+// "address,pool size,password,db number,idle timeout" format as for the sessions
+func newRedisPool(endpoint string) *redis.Pool {
+	configs := strings.Split(endpoint, ",")
+	address := configs[0]
+	poolSize := 100
+	password := ""
+	dbNum := 0
+	idleTimeout := time.Duration(0)
+	if len(configs) > 1 {
+		if size, err := strconv.Atoi(configs[1]); err == nil && size > 0 {
+			poolSize = size
+		}
+	}
+	if len(configs) > 2 {
+		password = configs[2]
+	}
+	if len(configs) > 3 {
+		if num, err := strconv.Atoi(configs[3]); err == nil && num > 0 {
+			dbNum = num
+		}
+	}
+	if len(configs) > 4 {
+		if timeout, err := strconv.Atoi(configs[4]); err == nil && timeout > 0 {
+			idleTimeout = time.Duration(timeout) * time.Second
+		}
+	}
+
+	return &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			c, err := redis.Dial("tcp", address)
+			if err != nil {
+				return nil, err
+			}
+			if password != "" {
+				if _, err = c.Do("AUTH", password); err != nil {
+					c.Close()
+					return nil, err
+				}
+			}
+			if dbNum > 0 {
+				if _, err = c.Do("SELECT", dbNum); err != nil {
+					c.Close()
+					return nil, err
+				}
+			}
+			return c, nil
+		},
+		MaxIdle:     poolSize,
+		IdleTimeout: idleTimeout,
+	}
+}
+
+func newRedisTicketStore(endpoint string) (*redisTicketStore, error) {
+	if endpoint == "" {
+		return nil, fmt.Errorf("redisEndpoint should be set to use the %s ticket store", TicketStoreRedis)
+	}
+
+	return &redisTicketStore{pool: newRedisPool(endpoint)}, nil
+}
+
+func (ts *redisTicketStore) Store(key string, value []byte, ttl time.Duration) error {
+	c := ts.pool.Get()
+	defer c.Close()
+
+	_, err := c.Do("SET", redisTicketKeyPrefix+key, value, "PX", ttl.Milliseconds())
+	return err
+}
+
+func getRedisTicketValue(reply interface{}, err error) ([]byte, error) {
+	value, err := redis.Bytes(reply, err)
+	if err == redis.ErrNil {
+		return nil, nil
+	}
+	return value, err
+}
+
+func (ts *redisTicketStore) Load(key string) ([]byte, error) {
+	c := ts.pool.Get()
+	defer c.Close()
+
+	return getRedisTicketValue(c.Do("GET", redisTicketKeyPrefix+key))
+}
+
+func (ts *redisTicketStore) LoadAndDelete(key string) ([]byte, error) {
+	c := ts.pool.Get()
+	defer c.Close()
+
+	return getRedisTicketValue(redisGetDelScript.Do(c, redisTicketKeyPrefix+key))
+}
+
+// Update watches the ticket, the transaction setting it fails when another caller changes it first
+func (ts *redisTicketStore) Update(key string, ttl time.Duration, update func(value []byte) ([]byte, error)) error {
+	c := ts.pool.Get()
+	defer c.Close()
+
+	for {
+		_, err := c.Do("WATCH", redisTicketKeyPrefix+key)
+		if err != nil {
+			return err
+		}
+
+		value, err := getRedisTicketValue(c.Do("GET", redisTicketKeyPrefix+key))
+		if err == nil {
+			value, err = update(value)
+		}
+		if err != nil {
+			// the pool unwatches the connection when it is closed
+			return err
+		}
+
+		err = c.Send("MULTI")
+		if err == nil {
+			err = c.Send("SET", redisTicketKeyPrefix+key, value, "PX", ttl.Milliseconds())
+		}
+		if err != nil {
+			return err
+		}
+
+		reply, err := c.Do("EXEC")
+		if err != nil {
+			return err
+		}
+		if reply != nil {
+			return nil
+		}
+	}
+}
+
+func (ts *redisTicketStore) Delete(key string) error {
+	c := ts.pool.Get()
+	defer c.Close()
+
+	_, err := c.Do("DEL", redisTicketKeyPrefix+key)
+	return err
+}
+
+// Purge does nothing as Redis expires the tickets by itself
+func (ts *redisTicketStore) Purge() error {
+	return nil
+}
@@ -0,0 +1,165 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMemoryTicketStore(t *testing.T) {
+	ts := &memoryTicketStore{}
+
+	err := ts.Store("expired", []byte("value"), -time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := ts.Load("expired")
+	if err != nil || value != nil {
+		t.Fatalf("an expired ticket should not be loaded: %s, %v", value, err)
+	}
+
+	err = ts.Purge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ts.tickets.Load("expired"); ok {
+		t.Fatal("the expired ticket should have been purged")
+	}
+
+	err = ts.Store("ticket", []byte("value"), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only one of the concurrent callers gets a single-use ticket
+	var count int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := ts.LoadAndDelete("ticket")
+			if err == nil && string(value) == "value" {
+				atomic.AddInt32(&count, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if count != 1 {
+		t.Fatalf("the ticket has been taken %d times", count)
+	}
+
+	// none of the concurrent updates is lost
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := ts.Update("list", time.Minute, func(value []byte) ([]byte, error) {
+				return append(append([]byte{}, value...), 'x'), nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	value, err = ts.Load("list")
+	if err != nil || string(value) != "xxxxxxxxxx" {
+		t.Fatalf("unexpected updated ticket: %s, %v", value, err)
+	}
+}
+
+func TestDatabaseTicketStoreUpdate(t *testing.T) {
+	setupTestOrmer(t, &StoredTicket{})
+	ts := &databaseTicketStore{}
+
+	err := ts.Store("expired", []byte("value"), -time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"list", "list", "expired"} {
+		err = ts.Update(key, time.Minute, func(value []byte) ([]byte, error) {
+			return append(value, 'x'), nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	value, err := ts.Load("list")
+	if err != nil || string(value) != "xx" {
+		t.Fatalf("unexpected updated ticket: %s, %v", value, err)
+	}
+	value, err = ts.Load("expired")
+	if err != nil || string(value) != "x" {
+		t.Fatalf("an expired ticket should be updated from nothing: %s, %v", value, err)
+	}
+}
+
+func TestCasTickets(t *testing.T) {
+	ticketStore, ticketStoreErr = &memoryTicketStore{}, nil
+	ticketStoreOnce.Do(func() {})
+
+	application := &Application{Owner: "admin", Name: "app-cas"}
+	token := &CasAuthenticationSuccess{User: "alice"}
+	pgt, err := StoreCasTokenForPgt(application, token, "https://app.example.com", "built-in/alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a proxy granting ticket issues several proxy tickets, which are validated once
+	for i := 0; i < 2; i++ {
+		wrapper, err := GetCasTokenByPgt(application, pgt)
+		if err != nil || wrapper == nil || wrapper.AuthenticationSuccess.User != "alice" || wrapper.Service != "https://app.example.com" || wrapper.UserId != "built-in/alice" {
+			t.Fatalf("unexpected PGT: %+v, %v", wrapper, err)
+		}
+
+		pt, err := StoreCasTokenForProxyTicket(application, wrapper.AuthenticationSuccess, "https://backend.example.com", wrapper.UserId)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		wrapper, err = GetCasTokenByTicket(application, pt)
+		if err != nil || wrapper == nil || wrapper.AuthenticationSuccess.User != "alice" || wrapper.Service != "https://backend.example.com" {
+			t.Fatalf("unexpected PT: %+v, %v", wrapper, err)
+		}
+
+		wrapper, err = GetCasTokenByTicket(application, pt)
+		if err != nil || wrapper != nil {
+			t.Fatalf("a proxy ticket should only be validated once: %+v, %v", wrapper, err)
+		}
+	}
+
+	// a PGT is not a service ticket
+	wrapper, err := GetCasTokenByTicket(application, pgt)
+	if err != nil || wrapper != nil {
+		t.Fatalf("a PGT should not be validated as a ticket: %+v, %v", wrapper, err)
+	}
+
+	// a PGT is only valid for the application that issued it
+	wrapper, err = GetCasTokenByPgt(&Application{Owner: "admin", Name: "app-other"}, pgt)
+	if err != nil || wrapper != nil {
+		t.Fatalf("a PGT should not be used by another application: %+v, %v", wrapper, err)
+	}
+
+	err = StoreDeviceAuthCache("device-code", &DeviceAuthCache{RequestAt: time.Now().Add(-deviceAuthTtl)})
+	if err == nil {
+		t.Fatal("an expired device authorization should not be stored")
+	}
+}
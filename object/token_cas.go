@@ -16,20 +16,15 @@ package object
 
 import (
 	"crypto"
-	"encoding/base64"
 	"encoding/json"
-	"encoding/pem"
 	"encoding/xml"
 	"fmt"
-	"math/rand"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/beevik/etree"
 	"github.com/casdoor/casdoor/i18n"
 	"github.com/casdoor/casdoor/util"
-	dsig "github.com/russellhaering/goxmldsig"
 )
 
 type CasServiceResponse struct {
@@ -92,6 +87,7 @@ type CasAuthenticationSuccessWrapper struct {
 	AuthenticationSuccess *CasAuthenticationSuccess // the token we issued
 	Service               string                    // to which service this token is issued
 	UserId                string
+	Application           string // the application issuing this token
 }
 
 type CasProxySuccess struct {
@@ -118,71 +114,89 @@ type Saml11AssertionArtifact struct {
 	InnerXML string   `xml:",innerxml"`
 }
 
-// st is short for service ticket
-var stToServiceResponse sync.Map
+// the default lifetimes of the CAS tickets, an application can set its own ones
+const (
+	// casTicketTtl is how long a service or proxy ticket can be validated, once
+	casTicketTtl = time.Minute * 5
+	// casPgtTtl is how long a proxy granting ticket can issue proxy tickets
+	casPgtTtl = time.Hour * 2
+)
 
-// pgt is short for proxy granting ticket
-var pgtToServiceResponse sync.Map
+// getCasTicketKey is the key of a CAS ticket in the ticket store. ST is short for service ticket,
+// PT for proxy ticket and PGT for proxy granting ticket.
+func getCasTicketKey(ticket string) string {
+	return "cas:" + ticket
+}
 
 func CheckCasLogin(application *Application, lang string, service string) error {
-	if len(application.RedirectUris) > 0 && !application.IsRedirectUriValid(service) {
-		return fmt.Errorf(i18n.Translate(lang, "token:Redirect URI: %s doesn't exist in the allowed Redirect URI list"), service)
+	if application.IsCasServiceAllowed(service) {
+		return nil
+	}
+
+	if len(application.CasServices) > 0 {
+		return fmt.Errorf(i18n.Translate(lang, "cas:Service %s is not registered in the application: %s"), service, application.Name)
 	}
-	return nil
+	return fmt.Errorf(i18n.Translate(lang, "token:Redirect URI: %s doesn't exist in the allowed Redirect URI list"), service)
 }
 
-func StoreCasTokenForPgt(token *CasAuthenticationSuccess, service, userId string) string {
+func StoreCasTokenForPgt(application *Application, token *CasAuthenticationSuccess, service, userId string) (string, error) {
 	pgt := fmt.Sprintf("PGT-%s", util.GenerateId())
-	pgtToServiceResponse.Store(pgt, &CasAuthenticationSuccessWrapper{
+	err := storeTicket(getCasTicketKey(pgt), &CasAuthenticationSuccessWrapper{
 		AuthenticationSuccess: token,
 		Service:               service,
 		UserId:                userId,
-	})
-	return pgt
+		Application:           application.GetId(),
+	}, application.getCasPgtTtl())
+	if err != nil {
+		return "", err
+	}
+	return pgt, nil
 }
 
 func GenerateId() {
 	panic("unimplemented")
 }
 
-// GetCasTokenByPgt
-/**
-@ret1: whether a token is found
-@ret2: token, nil if not found
-@ret3: the service URL who requested to issue this token
-@ret4: userIf of user who requested to issue this token
-*/
-func GetCasTokenByPgt(pgt string) (bool, *CasAuthenticationSuccess, string, string) {
-	if responseWrapperType, ok := pgtToServiceResponse.LoadAndDelete(pgt); ok {
-		responseWrapperTypeCast := responseWrapperType.(*CasAuthenticationSuccessWrapper)
-		return true, responseWrapperTypeCast.AuthenticationSuccess, responseWrapperTypeCast.Service, responseWrapperTypeCast.UserId
+// GetCasTokenByPgt returns the token of a proxy granting ticket issued by the application, nil if not found
+func GetCasTokenByPgt(application *Application, pgt string) (*CasAuthenticationSuccessWrapper, error) {
+	// a proxy granting ticket issues proxy tickets until it expires
+	var wrapper CasAuthenticationSuccessWrapper
+	ok, err := loadTicket(getCasTicketKey(pgt), &wrapper)
+	if err != nil || !ok || !strings.HasPrefix(pgt, "PGT-") || wrapper.Application != application.GetId() {
+		return nil, err
 	}
-	return false, nil, "", ""
+	return &wrapper, nil
 }
 
-// GetCasTokenByTicket
-/**
-@ret1: whether a token is found
-@ret2: token, nil if not found
-@ret3: the service URL who requested to issue this token
-@ret4: userIf of user who requested to issue this token
-*/
-func GetCasTokenByTicket(ticket string) (bool, *CasAuthenticationSuccess, string, string) {
-	if responseWrapperType, ok := stToServiceResponse.LoadAndDelete(ticket); ok {
-		responseWrapperTypeCast := responseWrapperType.(*CasAuthenticationSuccessWrapper)
-		return true, responseWrapperTypeCast.AuthenticationSuccess, responseWrapperTypeCast.Service, responseWrapperTypeCast.UserId
+// GetCasTokenByTicket returns the token of a service or proxy ticket issued by the application, nil if not found
+func GetCasTokenByTicket(application *Application, ticket string) (*CasAuthenticationSuccessWrapper, error) {
+	// service and proxy tickets can only be validated once
+	if !strings.HasPrefix(ticket, "ST-") && !strings.HasPrefix(ticket, "PT-") {
+		return nil, nil
+	}
+
+	var wrapper CasAuthenticationSuccessWrapper
+	ok, err := takeTicket(getCasTicketKey(ticket), &wrapper)
+	if err != nil || !ok || wrapper.Application != application.GetId() {
+		return nil, err
 	}
-	return false, nil, "", ""
+	return &wrapper, nil
 }
 
-func StoreCasTokenForProxyTicket(token *CasAuthenticationSuccess, targetService, userId string) string {
+func StoreCasTokenForProxyTicket(application *Application, token *CasAuthenticationSuccess, targetService, userId string) (string, error) {
+	filterCasAttributes(token, application.GetCasService(targetService))
+
 	proxyTicket := fmt.Sprintf("PT-%s", util.GenerateId())
-	stToServiceResponse.Store(proxyTicket, &CasAuthenticationSuccessWrapper{
+	err := storeTicket(getCasTicketKey(proxyTicket), &CasAuthenticationSuccessWrapper{
 		AuthenticationSuccess: token,
 		Service:               targetService,
 		UserId:                userId,
-	})
-	return proxyTicket
+		Application:           application.GetId(),
+	}, application.getCasProxyTicketTtl())
+	if err != nil {
+		return "", err
+	}
+	return proxyTicket, nil
 }
 
 func escapeXMLText(input string) (string, error) {
@@ -194,7 +208,7 @@ func escapeXMLText(input string) (string, error) {
 	return sb.String(), nil
 }
 
-func GenerateCasToken(userId string, service string) (string, error) {
+func GenerateCasToken(application *Application, userId string, service string) (string, error) {
 	user, err := GetUser(userId)
 	if err != nil {
 		return "", err
@@ -247,12 +261,18 @@ func GenerateCasToken(userId string, service string) (string, error) {
 		}
 	}
 
-	st := fmt.Sprintf("ST-%d", rand.Int())
-	stToServiceResponse.Store(st, &CasAuthenticationSuccessWrapper{
+	filterCasAttributes(&authenticationSuccess, application.GetCasService(service))
+
+	st := fmt.Sprintf("ST-%s", util.GenerateId())
+	err = storeTicket(getCasTicketKey(st), &CasAuthenticationSuccessWrapper{
 		AuthenticationSuccess: &authenticationSuccess,
 		Service:               service,
 		UserId:                userId,
-	})
+		Application:           application.GetId(),
+	}, application.getCasTicketTtl())
+	if err != nil {
+		return "", err
+	}
 	return st, nil
 }
 
@@ -262,7 +282,7 @@ func GenerateCasToken(userId string, service string) (string, error) {
 @ret2: the service URL who requested to issue this token
 @ret3: error
 */
-func GetValidationBySaml(samlRequest string, host string) (string, string, error) {
+func GetValidationBySaml(application *Application, samlRequest string, host string) (string, string, error) {
 	var request Saml11Request
 	err := xml.Unmarshal([]byte(samlRequest), &request)
 	if err != nil {
@@ -274,11 +294,15 @@ func GetValidationBySaml(samlRequest string, host string) (string, string, error
 		return "", "", fmt.Errorf("request.AssertionArtifact.InnerXML error, AssertionArtifact field not found")
 	}
 
-	ok, _, service, userId := GetCasTokenByTicket(ticket)
-	if !ok {
+	wrapper, err := GetCasTokenByTicket(application, ticket)
+	if err != nil {
+		return "", "", err
+	}
+	if wrapper == nil {
 		return "", "", fmt.Errorf("the CAS token for ticket %s is not found", ticket)
 	}
 
+	service, userId := wrapper.Service, wrapper.UserId
 	user, err := GetUser(userId)
 	if err != nil {
 		return "", "", err
@@ -288,14 +312,6 @@ func GetValidationBySaml(samlRequest string, host string) (string, string, error
 		return "", "", fmt.Errorf("the user %s is not found", userId)
 	}
 
-	application, err := GetApplicationByUser(user)
-	if err != nil {
-		return "", "", err
-	}
-	if application == nil {
-		return "", "", fmt.Errorf("the application for user %s is not found", userId)
-	}
-
 	samlResponse, err := NewSamlResponse11(application, user, request.RequestID, host)
 	if err != nil {
 		return "", "", err
@@ -310,14 +326,10 @@ func GetValidationBySaml(samlRequest string, host string) (string, string, error
 		return "", "", fmt.Errorf("the certificate field should not be empty for the cert: %v", cert)
 	}
 
-	block, _ := pem.Decode([]byte(cert.Certificate))
-	certificate := base64.StdEncoding.EncodeToString(block.Bytes)
-	randomKeyStore := &X509Key{
-		PrivateKey:      cert.PrivateKey,
-		X509Certificate: certificate,
+	ctx, err := getCertSigningContext(cert)
+	if err != nil {
+		return "", "", err
 	}
-
-	ctx := dsig.NewDefaultSigningContext(randomKeyStore)
 	ctx.Hash = crypto.SHA1
 	signedXML, err := ctx.SignEnveloped(samlResponse)
 	if err != nil {
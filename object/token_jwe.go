@@ -0,0 +1,117 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+
+	"github.com/casdoor/casdoor/util"
+	"gopkg.in/square/go-jose.v2"
+)
+
+var (
+	JweAlgValuesSupported = []string{string(jose.RSA_OAEP_256), string(jose.ECDH_ES)}
+	JweEncValuesSupported = []string{string(jose.A256GCM)}
+)
+
+// getClientEncryptionKey returns the key of the client's registered JWKS to encrypt for with the given algorithm
+func getClientEncryptionKey(application *Application, alg string) (*jose.JSONWebKey, error) {
+	if application.ClientJwks == "" {
+		return nil, fmt.Errorf("the application: %s has no registered JWKS to encrypt for", application.GetId())
+	}
+
+	jwks := jose.JSONWebKeySet{}
+	err := json.Unmarshal([]byte(application.ClientJwks), &jwks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the JWKS of the application: %s, error: %s", application.GetId(), err.Error())
+	}
+
+	for _, key := range jwks.Keys {
+		if key.Use != "" && key.Use != "enc" {
+			continue
+		}
+		if key.Algorithm != "" && key.Algorithm != alg {
+			continue
+		}
+
+		switch key.Key.(type) {
+		case *rsa.PublicKey:
+			if alg == string(jose.RSA_OAEP_256) {
+				return &key, nil
+			}
+		case *ecdsa.PublicKey:
+			if alg == string(jose.ECDH_ES) {
+				return &key, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("the JWKS of the application: %s has no encryption key for the algorithm: %s", application.GetId(), alg)
+}
+
+func encryptForApplication(application *Application, alg string, payload []byte, contentType string) (string, error) {
+	if !util.InSlice(JweAlgValuesSupported, alg) {
+		return "", fmt.Errorf("unsupported JWE algorithm: %s", alg)
+	}
+
+	key, err := getClientEncryptionKey(application, alg)
+	if err != nil {
+		return "", err
+	}
+
+	recipient := jose.Recipient{Algorithm: jose.KeyAlgorithm(alg), Key: key.Key, KeyID: key.KeyID}
+	options := (&jose.EncrypterOptions{}).WithType("JWT")
+	if contentType != "" {
+		options = options.WithContentType(jose.ContentType(contentType))
+	}
+
+	encrypter, err := jose.NewEncrypter(jose.A256GCM, recipient, options)
+	if err != nil {
+		return "", err
+	}
+
+	object, err := encrypter.Encrypt(payload)
+	if err != nil {
+		return "", err
+	}
+
+	return object.CompactSerialize()
+}
+
+// EncryptIdToken nests the signed ID token in a JWE when the application has chosen an encryption algorithm
+func EncryptIdToken(application *Application, idToken string) (string, error) {
+	if application.IdTokenEncryptionAlg == "" {
+		return idToken, nil
+	}
+
+	return encryptForApplication(application, application.IdTokenEncryptionAlg, []byte(idToken), "JWT")
+}
+
+// EncryptUserinfo returns the userinfo claims as a JWE, or "" when the application has not chosen an encryption algorithm
+func EncryptUserinfo(application *Application, userinfo interface{}) (string, error) {
+	if application == nil || application.UserinfoEncryptionAlg == "" {
+		return "", nil
+	}
+
+	payload, err := json.Marshal(userinfo)
+	if err != nil {
+		return "", err
+	}
+
+	return encryptForApplication(application, application.UserinfoEncryptionAlg, payload, "")
+}
@@ -0,0 +1,167 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gopkg.in/square/go-jose.v2"
+)
+
+func TestEdDsaCert(t *testing.T) {
+	cert := &Cert{Owner: "admin", Name: "cert-ed", CryptoAlgorithm: "EdDSA", ExpireInYears: 1}
+	err := cert.populateContent()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := getCertSigner(cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, Claims{TokenType: "access-token"})
+	token.Header["kid"] = cert.GetKeyId()
+	tokenString, err := signJwtToken(token, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := ParseJwtToken(tokenString, cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.TokenType != "access-token" {
+		t.Fatalf("unexpected claims: %v", claims)
+	}
+}
+
+func TestEncryptIdToken(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwks := jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: &rsaKey.PublicKey, KeyID: "rsa", Use: "enc"},
+		{Key: &ecKey.PublicKey, KeyID: "ec", Use: "enc"},
+	}}
+	jwksBytes, err := json.Marshal(jwks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scenarios := []struct {
+		alg string
+		key interface{}
+	}{
+		{"RSA-OAEP-256", rsaKey},
+		{"ECDH-ES", ecKey},
+	}
+
+	for _, scenario := range scenarios {
+		application := &Application{Owner: "admin", Name: "app-test", IdTokenEncryptionAlg: scenario.alg, ClientJwks: string(jwksBytes)}
+		idToken, err := EncryptIdToken(application, "header.payload.signature")
+		if err != nil {
+			t.Fatalf("%s: %v", scenario.alg, err)
+		}
+
+		object, err := jose.ParseEncrypted(idToken)
+		if err != nil {
+			t.Fatalf("%s: %v", scenario.alg, err)
+		}
+		if object.Header.ExtraHeaders["cty"] != "JWT" {
+			t.Fatalf("%s: unexpected header: %v", scenario.alg, object.Header)
+		}
+
+		plaintext, err := object.Decrypt(scenario.key)
+		if err != nil {
+			t.Fatalf("%s: %v", scenario.alg, err)
+		}
+		if string(plaintext) != "header.payload.signature" {
+			t.Fatalf("%s: unexpected plaintext: %s", scenario.alg, plaintext)
+		}
+	}
+
+	application := &Application{Owner: "admin", Name: "app-test"}
+	idToken, err := EncryptIdToken(application, "header.payload.signature")
+	if err != nil || idToken != "header.payload.signature" {
+		t.Fatalf("the ID token should not be encrypted without an algorithm, got %s, %v", idToken, err)
+	}
+}
+
+func TestGenerateEncryptedIdToken(t *testing.T) {
+	setupTestOrmer(t, &Cert{}, &UserIdentityBinding{})
+
+	cert := &Cert{Owner: "admin", Name: "cert-built-in", CryptoAlgorithm: "RS256", BitSize: 2048, ExpireInYears: 1}
+	err := cert.populateContent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ormer.Engine.Insert(cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwksBytes, err := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: &rsaKey.PublicKey, KeyID: "rsa", Use: "enc"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	application := &Application{Owner: "admin", Name: "app-test", ClientId: "client-test", ExpireInHours: 1, IdTokenEncryptionAlg: "RSA-OAEP-256", ClientJwks: string(jwksBytes)}
+	user := &User{Owner: "built-in", Name: "alice", DisplayName: "Alice"}
+	accessToken, _, idToken, _, err := generateJwtToken(application, user, "", "", "openid", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := ParseJwtToken(accessToken, cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.User.DisplayName != "" {
+		t.Fatalf("the access token should not have the claims of the user: %v", claims.User)
+	}
+
+	object, err := jose.ParseEncrypted(idToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := object.Decrypt(rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err = ParseJwtToken(string(plaintext), cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.User.DisplayName != "Alice" {
+		t.Fatalf("the ID token should have the claims of the user: %v", claims.User)
+	}
+}
@@ -17,7 +17,6 @@ package object
 import (
 	"fmt"
 	"reflect"
-	"strings"
 	"time"
 
 	"github.com/casdoor/casdoor/util"
@@ -385,7 +384,10 @@ func refineUser(user *User) *User {
 	return user
 }
 
-func generateJwtToken(application *Application, user *User, provider string, nonce string, scope string, host string) (string, string, string, error) {
+// generateJwtToken returns the access token, the refresh token, the ID token and the name of the token.
+// The ID token is the access token, unless the application encrypts it: the access and refresh tokens are sent
+// in the clear, so they only identify the user and the profile of the user is only in the encrypted ID token.
+func generateJwtToken(application *Application, user *User, provider string, nonce string, scope string, host string) (string, string, string, string, error) {
 	if provider == "" && user.Phone != "" {
 		user.DisplayName = "ph_" + user.Phone
 	}
@@ -406,7 +408,7 @@ func generateJwtToken(application *Application, user *User, provider string, non
 	// Get user's authentication information (phone number and GitHub account)
 	phoneNumber, githubAccount, err := getUserAuthInfo(user.UniversalId)
 	if err != nil {
-		return "", "", "", err
+		return "", "", "", "", err
 	}
 
 	claims := Claims{
@@ -455,6 +457,8 @@ func generateJwtToken(application *Application, user *User, provider string, non
 		jwtMethod = jwt.SigningMethodES512
 	} else if application.TokenSigningMethod == "ES384" {
 		jwtMethod = jwt.SigningMethodES384
+	} else if application.TokenSigningMethod == "EdDSA" {
+		jwtMethod = jwt.SigningMethodEdDSA
 	} else {
 		jwtMethod = jwt.SigningMethodRS256
 	}
@@ -490,50 +494,72 @@ func generateJwtToken(application *Application, user *User, provider string, non
 		claimsStandard.TokenType = "refresh-token"
 		refreshToken = jwt.NewWithClaims(jwtMethod, claimsStandard)
 	} else {
-		return "", "", "", fmt.Errorf("unknown application TokenFormat: %s", application.TokenFormat)
+		return "", "", "", "", fmt.Errorf("unknown application TokenFormat: %s", application.TokenFormat)
+	}
+
+	var idToken *jwt.Token
+	if application.IdTokenEncryptionAlg != "" {
+		idToken = token
+
+		// the user is only identified, its profile is only in the encrypted ID token
+		claimsShort := getShortClaims(claims)
+		claimsShort.UserShort = &UserShort{Owner: user.Owner, Name: user.Name, Id: user.Id}
+		token = jwt.NewWithClaims(jwtMethod, claimsShort)
+		claimsShort.ExpiresAt = jwt.NewNumericDate(refreshExpireTime)
+		claimsShort.TokenType = "refresh-token"
+		refreshToken = jwt.NewWithClaims(jwtMethod, claimsShort)
 	}
 
 	cert, err := getCertByApplication(application)
 	if err != nil {
-		return "", "", "", err
+		return "", "", "", "", err
 	}
 
 	if cert == nil {
 		if application.Cert == "" {
-			return "", "", "", fmt.Errorf("The cert field of the application \"%s\" should not be empty", application.GetId())
+			return "", "", "", "", fmt.Errorf("The cert field of the application \"%s\" should not be empty", application.GetId())
 		} else {
-			return "", "", "", fmt.Errorf("The cert \"%s\" does not exist", application.Cert)
+			return "", "", "", "", fmt.Errorf("The cert \"%s\" does not exist", application.Cert)
 		}
 	}
 
 	var (
 		tokenString        string
 		refreshTokenString string
-		key                interface{}
 	)
 
-	if strings.Contains(application.TokenSigningMethod, "RS") || application.TokenSigningMethod == "" {
-		// RSA private key
-		key, err = jwt.ParseRSAPrivateKeyFromPEM([]byte(cert.PrivateKey))
-	} else if strings.Contains(application.TokenSigningMethod, "ES") {
-		// ES private key
-		key, err = jwt.ParseECPrivateKeyFromPEM([]byte(cert.PrivateKey))
-	} else if strings.Contains(application.TokenSigningMethod, "Ed") {
-		// Ed private key
-		key, err = jwt.ParseEdPrivateKeyFromPEM([]byte(cert.PrivateKey))
+	// the private key may be encrypted or kept in a PKCS#11 token, so the tokens are signed by the cert's signer
+	signer, err := getCertSigner(cert)
+	if err != nil {
+		return "", "", "", "", err
+	}
+
+	token.Header["kid"] = cert.GetKeyId()
+	tokenString, err = signJwtToken(token, signer)
+	if err != nil {
+		return "", "", "", "", err
 	}
+	refreshToken.Header["kid"] = cert.GetKeyId()
+	refreshTokenString, err = signJwtToken(refreshToken, signer)
 	if err != nil {
-		return "", "", "", err
+		return "", "", "", "", err
 	}
 
-	token.Header["kid"] = cert.Name
-	tokenString, err = token.SignedString(key)
+	if idToken == nil {
+		return tokenString, refreshTokenString, tokenString, name, nil
+	}
+
+	idToken.Header["kid"] = cert.GetKeyId()
+	idTokenString, err := signJwtToken(idToken, signer)
+	if err != nil {
+		return "", "", "", "", err
+	}
+	idTokenString, err = EncryptIdToken(application, idTokenString)
 	if err != nil {
-		return "", "", "", err
+		return "", "", "", "", err
 	}
-	refreshTokenString, err = refreshToken.SignedString(key)
 
-	return tokenString, refreshTokenString, name, err
+	return tokenString, refreshTokenString, idTokenString, name, nil
 }
 
 func ParseJwtToken(token string, cert *Cert) (*Claims, error) {
@@ -547,12 +573,22 @@ func ParseJwtToken(token string, cert *Cert) (*Claims, error) {
 			return nil, fmt.Errorf("the certificate field should not be empty for the cert: %v", cert)
 		}
 
+		// tokens signed before a rotation are verified with the retired key they name
+		keyId, _ := token.Header["kid"].(string)
+		pemCertificate, err := getCertificateByKeyId(cert, keyId)
+		if err != nil {
+			return nil, err
+		}
+
 		if _, ok := token.Method.(*jwt.SigningMethodRSA); ok {
 			// RSA certificate
-			certificate, err = jwt.ParseRSAPublicKeyFromPEM([]byte(cert.Certificate))
+			certificate, err = jwt.ParseRSAPublicKeyFromPEM([]byte(pemCertificate))
 		} else if _, ok := token.Method.(*jwt.SigningMethodECDSA); ok {
 			// ES certificate
-			certificate, err = jwt.ParseECPublicKeyFromPEM([]byte(cert.Certificate))
+			certificate, err = jwt.ParseECPublicKeyFromPEM([]byte(pemCertificate))
+		} else if _, ok := token.Method.(*jwt.SigningMethodEd25519); ok {
+			// Ed certificate
+			certificate, err = parseEdPublicKeyFromPem(pemCertificate)
 		} else {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
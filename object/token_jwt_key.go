@@ -15,7 +15,9 @@
 package object
 
 import (
+	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
@@ -197,3 +199,108 @@ func generateRsaPssKeys(bitSize int, shaSize int, expireInYears int, commonName
 
 	return string(certPem), string(privateKeyPem), nil
 }
+
+func generateEdKeys(expireInYears int, commonName string, organization string) (string, string, error) {
+	// Generate Ed25519 key pair.
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Encode private key to PKCS#8 ASN.1 PEM, which is what jwt.ParseEdPrivateKeyFromPEM() expects.
+	privateKeyBytes, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return "", "", err
+	}
+	privateKeyPem := pem.EncodeToMemory(&pem.Block{
+		Type:  "PRIVATE KEY",
+		Bytes: privateKeyBytes,
+	})
+
+	// Generate certificate template.
+	template := x509.Certificate{
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().AddDate(expireInYears, 0, 0),
+		SerialNumber: big.NewInt(time.Now().Unix()),
+		Subject: pkix.Name{
+			CommonName:   commonName,
+			Organization: []string{organization},
+		},
+		BasicConstraintsValid: true,
+	}
+
+	// Generate certificate, Ed25519 has no separate hash so the signature algorithm is implied by the key.
+	certBytes, err := x509.CreateCertificate(rand.Reader, &template, &template, publicKey, privateKey)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Encode certificate to PEM format.
+	certPem := pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: certBytes,
+	})
+
+	return string(certPem), string(privateKeyPem), nil
+}
+
+func generatePkcs11Keys(sigAlgorithm string, bitSize int, shaSize int, expireInYears int, commonName string, organization string) (string, string, error) {
+	var signatureAlgorithms map[int]x509.SignatureAlgorithm
+	switch sigAlgorithm {
+	case "RS":
+		signatureAlgorithms = map[int]x509.SignatureAlgorithm{256: x509.SHA256WithRSA, 384: x509.SHA384WithRSA, 512: x509.SHA512WithRSA}
+	case "PS":
+		signatureAlgorithms = map[int]x509.SignatureAlgorithm{256: x509.SHA256WithRSAPSS, 384: x509.SHA384WithRSAPSS, 512: x509.SHA512WithRSAPSS}
+	case "ES":
+		signatureAlgorithms = map[int]x509.SignatureAlgorithm{256: x509.ECDSAWithSHA256, 384: x509.ECDSAWithSHA384, 512: x509.ECDSAWithSHA512}
+	default:
+		return "", "", fmt.Errorf("generatePkcs11Keys() error, unsupported signature algorithm: %s", sigAlgorithm)
+	}
+
+	signatureAlgorithm, ok := signatureAlgorithms[shaSize]
+	if !ok {
+		return "", "", fmt.Errorf("generatePkcs11Keys() error, unsupported SHA size: %d", shaSize)
+	}
+
+	// Generate the key pair inside the token, only its id is returned as the private key.
+	signer, privateKey, err := generatePkcs11Key(sigAlgorithm, bitSize, shaSize, fmt.Sprintf("%s/%s", organization, commonName))
+	if err != nil {
+		return "", "", err
+	}
+
+	certPem, err := generateCertificate(signer, signatureAlgorithm, expireInYears, commonName, organization)
+	if err != nil {
+		return "", "", err
+	}
+
+	return certPem, privateKey, nil
+}
+
+// generateCertificate self-signs a certificate with a signer whose private key may not be available, like a PKCS#11 key
+func generateCertificate(signer crypto.Signer, signatureAlgorithm x509.SignatureAlgorithm, expireInYears int, commonName string, organization string) (string, error) {
+	// Generate certificate template.
+	template := x509.Certificate{
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().AddDate(expireInYears, 0, 0),
+		SerialNumber: big.NewInt(time.Now().Unix()),
+		Subject: pkix.Name{
+			CommonName:   commonName,
+			Organization: []string{organization},
+		},
+		BasicConstraintsValid: true,
+		SignatureAlgorithm:    signatureAlgorithm,
+	}
+
+	certBytes, err := x509.CreateCertificate(rand.Reader, &template, &template, signer.Public(), signer)
+	if err != nil {
+		return "", err
+	}
+
+	// Encode certificate to PEM format.
+	certPem := pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: certBytes,
+	})
+
+	return string(certPem), nil
+}
@@ -18,7 +18,6 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"fmt"
-	"sync"
 	"time"
 
 	"github.com/casdoor/casdoor/i18n"
@@ -38,8 +37,6 @@ const (
 	EndpointError        = "endpoint_error"
 )
 
-var DeviceAuthMap = sync.Map{}
-
 type Code struct {
 	Message string `xorm:"varchar(100)" json:"message"`
 	Code    string `xorm:"varchar(100)" json:"code"`
@@ -82,6 +79,42 @@ type DeviceAuthCache struct {
 	RequestAt     time.Time
 }
 
+// deviceAuthTtl is the expires_in of the device authorization response
+const deviceAuthTtl = time.Second * 120
+
+func getDeviceAuthKey(code string) string {
+	return "device:" + code
+}
+
+// StoreDeviceAuthCache keeps the cache of a device code or a user code until the device authorization expires
+func StoreDeviceAuthCache(code string, cache *DeviceAuthCache) error {
+	ttl := time.Until(cache.RequestAt.Add(deviceAuthTtl))
+	if ttl <= 0 {
+		return fmt.Errorf("the device authorization has expired")
+	}
+	return storeTicket(getDeviceAuthKey(code), cache, ttl)
+}
+
+// GetDeviceAuthCache returns nil for an unknown or expired code
+func GetDeviceAuthCache(code string) (*DeviceAuthCache, error) {
+	var cache DeviceAuthCache
+	ok, err := loadTicket(getDeviceAuthKey(code), &cache)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &cache, nil
+}
+
+// TakeDeviceAuthCache is GetDeviceAuthCache for a code that can only be used once
+func TakeDeviceAuthCache(code string) (*DeviceAuthCache, error) {
+	var cache DeviceAuthCache
+	ok, err := takeTicket(getDeviceAuthKey(code), &cache)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &cache, nil
+}
+
 type DeviceAuthResponse struct {
 	DeviceCode      string `json:"device_code"`
 	UserCode        string `json:"user_code"`
@@ -171,7 +204,7 @@ func GetOAuthCode(userId string, clientId string, provider string, responseType
 	if err != nil {
 		return nil, err
 	}
-	accessToken, refreshToken, tokenName, err := generateJwtToken(application, user, provider, nonce, scope, host)
+	accessToken, refreshToken, idToken, tokenName, err := generateJwtToken(application, user, provider, nonce, scope, host)
 	if err != nil {
 		return nil, err
 	}
@@ -190,6 +223,7 @@ func GetOAuthCode(userId string, clientId string, provider string, responseType
 		Code:          util.GenerateClientId(),
 		AccessToken:   accessToken,
 		RefreshToken:  refreshToken,
+		IdToken:       idToken,
 		ExpiresIn:     application.ExpireInHours * hourSeconds,
 		Scope:         scope,
 		TokenType:     "Bearer",
@@ -274,9 +308,18 @@ func GetOAuthToken(grantType string, clientId string, clientSecret string, code
 		return nil, err
 	}
 
+	idToken := token.IdToken
+	if idToken == "" {
+		// the tokens issued before the ID tokens were kept
+		idToken, err = EncryptIdToken(application, token.AccessToken)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	tokenWrapper := &TokenWrapper{
 		AccessToken:  token.AccessToken,
-		IdToken:      token.AccessToken,
+		IdToken:      idToken,
 		RefreshToken: token.RefreshToken,
 		TokenType:    token.TokenType,
 		ExpiresIn:    token.ExpiresIn,
@@ -379,7 +422,7 @@ func RefreshToken(grantType string, refreshToken string, scope string, clientId
 		return nil, err
 	}
 
-	newAccessToken, newRefreshToken, tokenName, err := generateJwtToken(application, user, "", "", scope, host)
+	newAccessToken, newRefreshToken, newIdToken, tokenName, err := generateJwtToken(application, user, "", "", scope, host)
 	if err != nil {
 		return &TokenError{
 			Error:            EndpointError,
@@ -397,6 +440,7 @@ func RefreshToken(grantType string, refreshToken string, scope string, clientId
 		Code:         util.GenerateClientId(),
 		AccessToken:  newAccessToken,
 		RefreshToken: newRefreshToken,
+		IdToken:      newIdToken,
 		ExpiresIn:    application.ExpireInHours * hourSeconds,
 		Scope:        scope,
 		TokenType:    "Bearer",
@@ -413,7 +457,7 @@ func RefreshToken(grantType string, refreshToken string, scope string, clientId
 
 	tokenWrapper := &TokenWrapper{
 		AccessToken:  newToken.AccessToken,
-		IdToken:      newToken.AccessToken,
+		IdToken:      newToken.IdToken,
 		RefreshToken: newToken.RefreshToken,
 		TokenType:    newToken.TokenType,
 		ExpiresIn:    newToken.ExpiresIn,
@@ -558,7 +602,7 @@ func GetPasswordToken(application *Application, username string, password string
 		return nil, nil, err
 	}
 
-	accessToken, refreshToken, tokenName, err := generateJwtToken(application, user, "", "", scope, host)
+	accessToken, refreshToken, idToken, tokenName, err := generateJwtToken(application, user, "", "", scope, host)
 	if err != nil {
 		return nil, &TokenError{
 			Error:            EndpointError,
@@ -575,6 +619,7 @@ func GetPasswordToken(application *Application, username string, password string
 		Code:         util.GenerateClientId(),
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
+		IdToken:      idToken,
 		ExpiresIn:    application.ExpireInHours * hourSeconds,
 		Scope:        scope,
 		TokenType:    "Bearer",
@@ -604,7 +649,7 @@ func GetClientCredentialsToken(application *Application, clientSecret string, sc
 		Type:  "application",
 	}
 
-	accessToken, _, tokenName, err := generateJwtToken(application, nullUser, "", "", scope, host)
+	accessToken, _, idToken, tokenName, err := generateJwtToken(application, nullUser, "", "", scope, host)
 	if err != nil {
 		return nil, &TokenError{
 			Error:            EndpointError,
@@ -620,6 +665,7 @@ func GetClientCredentialsToken(application *Application, clientSecret string, sc
 		User:         nullUser.Name,
 		Code:         util.GenerateClientId(),
 		AccessToken:  accessToken,
+		IdToken:      idToken,
 		ExpiresIn:    application.ExpireInHours * hourSeconds,
 		Scope:        scope,
 		TokenType:    "Bearer",
@@ -668,7 +714,7 @@ func GetTokenByUser(application *Application, user *User, scope string, nonce st
 		return nil, err
 	}
 
-	accessToken, refreshToken, tokenName, err := generateJwtToken(application, user, "", nonce, scope, host)
+	accessToken, refreshToken, idToken, tokenName, err := generateJwtToken(application, user, "", nonce, scope, host)
 	if err != nil {
 		return nil, err
 	}
@@ -683,6 +729,7 @@ func GetTokenByUser(application *Application, user *User, scope string, nonce st
 		Code:         util.GenerateClientId(),
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
+		IdToken:      idToken,
 		ExpiresIn:    application.ExpireInHours * hourSeconds,
 		Scope:        scope,
 		TokenType:    "Bearer",
@@ -775,7 +822,7 @@ func GetWechatMiniProgramToken(application *Application, code string, host strin
 		return nil, nil, err
 	}
 
-	accessToken, refreshToken, tokenName, err := generateJwtToken(application, user, "", "", "", host)
+	accessToken, refreshToken, idToken, tokenName, err := generateJwtToken(application, user, "", "", "", host)
 	if err != nil {
 		return nil, &TokenError{
 			Error:            EndpointError,
@@ -793,6 +840,7 @@ func GetWechatMiniProgramToken(application *Application, code string, host strin
 		Code:         session.SessionKey, // a trick, because miniprogram does not use the code, so use the code field to save the session_key
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
+		IdToken:      idToken,
 		ExpiresIn:    application.ExpireInHours * hourSeconds,
 		Scope:        "",
 		TokenType:    "Bearer",
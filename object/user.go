@@ -28,9 +28,9 @@ import (
 	"github.com/casdoor/casdoor/i18n"
 	"github.com/casdoor/casdoor/proxy"
 	"github.com/casdoor/casdoor/util"
-	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/xorm-io/builder"
 	"github.com/xorm-io/core"
+	"github.com/xorm-io/xorm"
 )
 
 const (
@@ -189,16 +189,19 @@ type User struct {
 	Web3Onboard     string `xorm:"web3onboard varchar(100)" json:"web3onboard"`
 	Custom          string `xorm:"custom varchar(100)" json:"custom"`
 
-	WebauthnCredentials []webauthn.Credential `xorm:"webauthnCredentials blob" json:"webauthnCredentials"`
-	PreferredMfaType    string                `xorm:"varchar(100)" json:"preferredMfaType"`
-	RecoveryCodes       []string              `xorm:"varchar(1000)" json:"recoveryCodes"`
-	TotpSecret          string                `xorm:"varchar(100)" json:"totpSecret"`
-	MfaPhoneEnabled     bool                  `json:"mfaPhoneEnabled"`
-	MfaEmailEnabled     bool                  `json:"mfaEmailEnabled"`
-	MultiFactorAuths    []*MfaProps           `xorm:"-" json:"multiFactorAuths,omitempty"`
-	Invitation          string                `xorm:"varchar(100) index" json:"invitation"`
-	InvitationCode      string                `xorm:"varchar(100) index" json:"invitationCode"`
-	FaceIds             []*FaceId             `json:"faceIds"`
+	WebauthnCredentials []WebauthnCredential `xorm:"webauthnCredentials blob" json:"webauthnCredentials"`
+	PreferredMfaType    string               `xorm:"varchar(100)" json:"preferredMfaType"`
+	RecoveryCodes       []string             `xorm:"text" json:"recoveryCodes"`
+	TotpSecret          string               `xorm:"varchar(500)" json:"totpSecret"`
+	MfaPhoneEnabled     bool                 `json:"mfaPhoneEnabled"`
+	MfaEmailEnabled     bool                 `json:"mfaEmailEnabled"`
+	MfaWebauthnEnabled  bool                 `json:"mfaWebauthnEnabled"`
+	MfaPushEnabled      bool                 `json:"mfaPushEnabled"`
+	MfaPushProvider     string               `xorm:"varchar(100)" json:"mfaPushProvider"`
+	MultiFactorAuths    []*MfaProps          `xorm:"-" json:"multiFactorAuths,omitempty"`
+	Invitation          string               `xorm:"varchar(100) index" json:"invitation"`
+	InvitationCode      string               `xorm:"varchar(100) index" json:"invitationCode"`
+	FaceIds             []*FaceId            `json:"faceIds"`
 
 	Ldap       string            `xorm:"ldap varchar(100)" json:"ldap"`
 	Properties map[string]string `json:"properties"`
@@ -729,7 +732,7 @@ func UpdateUser(id string, user *User, columns []string, isAdmin bool) (bool, er
 			"owner", "display_name", "avatar", "first_name", "last_name",
 			"location", "address", "country_code", "region", "language", "affiliation", "title", "id_card_type", "id_card", "homepage", "bio", "tag", "language", "gender", "birthday", "education", "score", "karma", "ranking", "signup_application",
 			"is_admin", "is_forbidden", "is_deleted", "hash", "is_default_avatar", "properties", "webauthnCredentials", "managedAccounts", "face_ids", "mfaAccounts",
-			"signin_wrong_times", "last_change_password_time", "last_signin_wrong_time", "groups", "access_key", "access_secret", "mfa_phone_enabled", "mfa_email_enabled",
+			"signin_wrong_times", "last_change_password_time", "last_signin_wrong_time", "groups", "access_key", "access_secret", "mfa_phone_enabled", "mfa_email_enabled", "mfa_webauthn_enabled", "mfa_push_enabled", "mfa_push_provider",
 			"github", "google", "qq", "wechat", "facebook", "dingtalk", "weibo", "gitee", "linkedin", "wecom", "lark", "gitlab", "adfs",
 			"baidu", "alipay", "casdoor", "infoflow", "apple", "azuread", "azureadb2c", "slack", "steam", "bilibili", "okta", "douyin", "kwai", "line", "amazon",
 			"auth0", "battlenet", "bitbucket", "box", "cloudfoundry", "dailymotion", "deezer", "digitalocean", "discord", "dropbox",
@@ -772,10 +775,23 @@ func updateUser(id string, user *User, columns []string) (int64, error) {
 		return 0, err
 	}
 
-	affected, err := ormer.Engine.ID(core.PK{owner, name}).Cols(columns...).Update(user)
+	oldUser, err := getChangeEventObject(ormer.Engine, &User{Owner: owner, Name: name})
 	if err != nil {
 		return 0, err
 	}
+
+	err = encryptColumns(user)
+	if err != nil {
+		return 0, err
+	}
+
+	affected, err := updateWithChangeEvents(oldUser, user, func(session *xorm.Session) (int64, error) {
+		return session.ID(core.PK{owner, name}).Cols(columns...).Update(user)
+	})
+	if err != nil {
+		restoreEncryptedColumns(user)
+		return 0, err
+	}
 	return affected, nil
 }
 
@@ -812,8 +828,16 @@ func UpdateUserForAllFields(id string, user *User) (bool, error) {
 
 	user.UpdatedTime = util.GetCurrentTime()
 
-	affected, err := ormer.Engine.ID(core.PK{owner, name}).AllCols().Update(user)
+	err = encryptColumns(user)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := updateWithChangeEvents(oldUser, user, func(session *xorm.Session) (int64, error) {
+		return session.ID(core.PK{owner, name}).AllCols().Update(user)
+	})
 	if err != nil {
+		restoreEncryptedColumns(user)
 		return false, err
 	}
 
@@ -932,6 +956,11 @@ func AddUser(user *User, lang string, primaryProvider string) (bool, error) {
 		user.Name = strings.ToLower(user.Name)
 	}
 
+	err = encryptColumns(user)
+	if err != nil {
+		return false, err
+	}
+
 	// Start transaction processing
 	session := ormer.Engine.NewSession()
 	defer session.Close()
@@ -944,6 +973,7 @@ func AddUser(user *User, lang string, primaryProvider string) (bool, error) {
 	affected, err := session.Insert(user)
 	if err != nil {
 		session.Rollback()
+		restoreEncryptedColumns(user)
 		return false, err
 	}
 
@@ -951,14 +981,32 @@ func AddUser(user *User, lang string, primaryProvider string) (bool, error) {
 	err = createIdentityBindings(session, user, user.UniversalId, primaryProvider)
 	if err != nil {
 		session.Rollback()
+		restoreEncryptedColumns(user)
 		return false, err
 	}
 
+	if affected != 0 {
+		events, err := newChangeEvents(ChangeEventActionCreate, nil, user)
+		if err == nil {
+			err = addChangeEvents(session, events)
+		}
+		if err != nil {
+			session.Rollback()
+			restoreEncryptedColumns(user)
+			return false, err
+		}
+	}
+
 	// Commit transaction
 	if err := session.Commit(); err != nil {
+		restoreEncryptedColumns(user)
 		return false, err
 	}
 
+	if affected != 0 {
+		notifyChangeEvents()
+	}
+
 	return affected != 0, nil
 }
 
@@ -999,8 +1047,22 @@ func AddUsers(users []*User) (bool, error) {
 		}
 	}
 
-	affected, err := ormer.Engine.Insert(users)
+	for _, user := range users {
+		err := encryptColumns(user)
+		if err != nil {
+			for _, encryptedUser := range users {
+				restoreEncryptedColumns(encryptedUser)
+			}
+			return false, err
+		}
+	}
+
+	// the batch is inserted by a single statement, a duplicate entry inserts none of it
+	affected, err := insertWithChangeEvents(users)
 	if err != nil {
+		for _, user := range users {
+			restoreEncryptedColumns(user)
+		}
 		if !strings.Contains(err.Error(), "Duplicate entry") {
 			return false, err
 		}
@@ -1037,7 +1099,12 @@ func AddUsersInBatch(users []*User) (bool, error) {
 }
 
 func deleteUser(user *User) (bool, error) {
-	affected, err := ormer.Engine.ID(core.PK{user.Owner, user.Name}).Delete(&User{})
+	oldUser, err := getChangeEventObject(ormer.Engine, user)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := deleteWithChangeEvents(oldUser, user)
 	if err != nil {
 		return false, err
 	}
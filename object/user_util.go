@@ -29,6 +29,7 @@ import (
 	"github.com/go-webauthn/webauthn/webauthn"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/xorm-io/core"
+	"github.com/xorm-io/xorm"
 )
 
 func GetUserByField(organizationName string, field string, value string) (*User, error) {
@@ -115,26 +116,36 @@ func SetUserField(user *User, field string, value string) (bool, error) {
 		bean[strings.ToLower(field)] = value
 	}
 
-	affected, err := ormer.Engine.Table(user).ID(core.PK{user.Owner, user.Name}).Update(bean)
+	oldUser, err := getChangeEventObject(ormer.Engine, user)
 	if err != nil {
 		return false, err
 	}
 
-	user, err = getUser(user.Owner, user.Name)
-	if err != nil {
-		return false, err
-	}
+	affected, err := updateWithChangeEvents(oldUser, user, func(session *xorm.Session) (int64, error) {
+		affected, err := session.Table(user).ID(core.PK{user.Owner, user.Name}).Update(bean)
+		if err != nil {
+			return 0, err
+		}
 
-	err = user.UpdateUserHash()
-	if err != nil {
-		return false, err
-	}
+		newUser := User{}
+		_, err = session.ID(core.PK{user.Owner, user.Name}).Get(&newUser)
+		if err != nil {
+			return 0, err
+		}
 
-	if user != nil {
-		user.UpdatedTime = util.GetCurrentTime()
-	}
+		err = newUser.UpdateUserHash()
+		if err != nil {
+			return 0, err
+		}
 
-	_, err = ormer.Engine.ID(core.PK{user.Owner, user.Name}).Cols("hash").Update(user)
+		newUser.UpdatedTime = util.GetCurrentTime()
+
+		_, err = session.ID(core.PK{newUser.Owner, newUser.Name}).Cols("hash").Update(&newUser)
+		if err != nil {
+			return 0, err
+		}
+		return affected, nil
+	})
 	if err != nil {
 		return false, err
 	}
@@ -255,8 +266,16 @@ func ClearUserOAuthProperties(user *User, providerType string) (bool, error) {
 		}
 	}
 
-	affected, err := ormer.Engine.ID(core.PK{user.Owner, user.Name}).Cols("properties").Update(user)
+	oldUser, err := getChangeEventObject(ormer.Engine, user)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := updateWithChangeEvents(oldUser, user, func(session *xorm.Session) (int64, error) {
+		return session.ID(core.PK{user.Owner, user.Name}).Cols("properties").Update(user)
+	})
 	if err != nil {
+		restoreEncryptedColumns(user)
 		return false, err
 	}
 
@@ -21,10 +21,34 @@ import (
 	"strings"
 
 	"github.com/casdoor/casdoor/conf"
+	"github.com/casdoor/casdoor/util"
 	"github.com/go-webauthn/webauthn/protocol"
 	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
 )
 
+// WebauthnCredential is a stored WebAuthn credential with the metadata used for credential management.
+// The embedded credential keeps the JSON layout of the credentials stored before the metadata existed.
+type WebauthnCredential struct {
+	webauthn.Credential
+
+	DisplayName  string `json:"displayName"`
+	CreatedTime  string `json:"createdTime"`
+	LastUsedTime string `json:"lastUsedTime"`
+}
+
+func (credential *WebauthnCredential) GetId() string {
+	return base64.StdEncoding.EncodeToString(credential.ID)
+}
+
+func (credential *WebauthnCredential) GetAaguid() string {
+	id, err := uuid.FromBytes(credential.Authenticator.AAGUID)
+	if err != nil {
+		return ""
+	}
+	return id.String()
+}
+
 func GetWebAuthnObject(host string) (*webauthn.WebAuthn, error) {
 	var err error
 
@@ -63,7 +87,11 @@ func (user *User) WebAuthnDisplayName() string {
 }
 
 func (user *User) WebAuthnCredentials() []webauthn.Credential {
-	return user.WebauthnCredentials
+	credentials := []webauthn.Credential{}
+	for _, credential := range user.WebauthnCredentials {
+		credentials = append(credentials, credential.Credential)
+	}
+	return credentials
 }
 
 func (user *User) WebAuthnIcon() string {
@@ -85,16 +113,98 @@ func (user *User) CredentialExcludeList() []protocol.CredentialDescriptor {
 	return credentialExcludeList
 }
 
-func (user *User) AddCredentials(credential webauthn.Credential, isGlobalAdmin bool) (bool, error) {
-	user.WebauthnCredentials = append(user.WebauthnCredentials, credential)
+// CheckWebauthnAaguid checks the authenticator model of a new credential against the organization's attestation policy,
+// an empty allow list accepts any authenticator
+func CheckWebauthnAaguid(organization *Organization, credential *webauthn.Credential) error {
+	if organization == nil || len(organization.WebauthnAllowedAaguids) == 0 {
+		return nil
+	}
+
+	aaguid := (&WebauthnCredential{Credential: *credential}).GetAaguid()
+	for _, allowedAaguid := range organization.WebauthnAllowedAaguids {
+		if strings.EqualFold(strings.TrimSpace(allowedAaguid), aaguid) {
+			return nil
+		}
+	}
+	return fmt.Errorf("the authenticator (AAGUID: %s) is not allowed by organization: %s", aaguid, organization.Name)
+}
+
+func (user *User) GetWebauthnCredential(credentialIdBase64 string) *WebauthnCredential {
+	for i := range user.WebauthnCredentials {
+		if user.WebauthnCredentials[i].GetId() == credentialIdBase64 {
+			return &user.WebauthnCredentials[i]
+		}
+	}
+	return nil
+}
+
+func (user *User) AddCredentials(credential webauthn.Credential, displayName string, isGlobalAdmin bool) (bool, error) {
+	if displayName == "" {
+		displayName = fmt.Sprintf("Credential %d", len(user.WebauthnCredentials)+1)
+	}
+
+	user.WebauthnCredentials = append(user.WebauthnCredentials, WebauthnCredential{
+		Credential:  credential,
+		DisplayName: displayName,
+		CreatedTime: util.GetCurrentTime(),
+	})
 	return UpdateUser(user.GetId(), user, []string{"webauthnCredentials"}, isGlobalAdmin)
 }
 
+func (user *User) RenameCredentials(credentialIdBase64 string, displayName string) (bool, error) {
+	credential := user.GetWebauthnCredential(credentialIdBase64)
+	if credential == nil {
+		return false, nil
+	}
+
+	credential.DisplayName = displayName
+	return UpdateUser(user.GetId(), user, []string{"webauthnCredentials"}, false)
+}
+
+// UpdateCredentialsUsage stores the sign count reported by the authenticator after a successful assertion,
+// the assertion is rejected when the sign count has not increased, as the authenticator may have been cloned
+func (user *User) UpdateCredentialsUsage(credential *webauthn.Credential) error {
+	storedCredential := user.GetWebauthnCredential(base64.StdEncoding.EncodeToString(credential.ID))
+	if storedCredential == nil {
+		return nil
+	}
+
+	if credential.Authenticator.CloneWarning {
+		storedCredential.Authenticator.CloneWarning = true
+		_, err := updateUser(user.GetId(), user, []string{"webauthnCredentials"})
+		if err != nil {
+			return err
+		}
+
+		addUserActionRecord(user.Owner, user.Name, "webauthn-clone-warning", util.StructToJson(map[string]interface{}{
+			"credential": storedCredential.DisplayName,
+			"signCount":  storedCredential.Authenticator.SignCount,
+		}))
+		return fmt.Errorf("the sign count of the WebAuthn credential: %s has not increased, the authenticator may have been cloned", storedCredential.DisplayName)
+	}
+
+	storedCredential.Authenticator.SignCount = credential.Authenticator.SignCount
+	storedCredential.LastUsedTime = util.GetCurrentTime()
+	_, err := updateUser(user.GetId(), user, []string{"webauthnCredentials"})
+	return err
+}
+
 func (user *User) DeleteCredentials(credentialIdBase64 string) (bool, error) {
 	for i, credential := range user.WebauthnCredentials {
-		if base64.StdEncoding.EncodeToString(credential.ID) == credentialIdBase64 {
+		if credential.GetId() == credentialIdBase64 {
 			user.WebauthnCredentials = append(user.WebauthnCredentials[0:i], user.WebauthnCredentials[i+1:]...)
-			return UpdateUserForAllFields(user.GetId(), user)
+
+			columns := []string{"webauthnCredentials"}
+			if len(user.WebauthnCredentials) == 0 && user.MfaWebauthnEnabled {
+				// WebAuthn can no longer be used as a second factor without any credential
+				user.MfaWebauthnEnabled = false
+				columns = append(columns, "mfa_webauthn_enabled")
+				if user.PreferredMfaType == WebAuthnType {
+					user.PreferredMfaType = getFallbackMfaType(user)
+					columns = append(columns, "preferred_mfa_type")
+				}
+			}
+			return UpdateUser(user.GetId(), user, columns, false)
 		}
 	}
 	return false, nil
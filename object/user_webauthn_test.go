@@ -0,0 +1,129 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+func TestWebauthnCredentialJson(t *testing.T) {
+	// credentials stored before the metadata was added
+	storedCredentials := `[{"id":"AQID","publicKey":"BAUG","attestationType":"none","transport":null,"flags":{},"authenticator":{"AAGUID":"+/AAAAAAAAAAAAAAAAAAAA==","signCount":3,"cloneWarning":false,"attachment":""}}]`
+
+	credentials := []WebauthnCredential{}
+	err := json.Unmarshal([]byte(storedCredentials), &credentials)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(credentials) != 1 || credentials[0].GetId() != "AQID" || credentials[0].Authenticator.SignCount != 3 {
+		t.Fatalf("unexpected credentials: %v", credentials)
+	}
+	if credentials[0].GetAaguid() != "fbf00000-0000-0000-0000-000000000000" {
+		t.Fatalf("unexpected AAGUID: %s", credentials[0].GetAaguid())
+	}
+}
+
+func TestCheckWebauthnAaguid(t *testing.T) {
+	credential := &webauthn.Credential{
+		Authenticator: webauthn.Authenticator{AAGUID: []byte{0xfb, 0xf0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+	}
+
+	scenarios := []struct {
+		description string
+		aaguids     []string
+		allowed     bool
+	}{
+		{"No policy", nil, true},
+		{"Allowed", []string{"FBF00000-0000-0000-0000-000000000000"}, true},
+		{"Not allowed", []string{"ee882879-721c-4913-9775-3dfcce97072a"}, false},
+	}
+
+	for _, scenario := range scenarios {
+		organization := &Organization{Name: "built-in", WebauthnAllowedAaguids: scenario.aaguids}
+		err := CheckWebauthnAaguid(organization, credential)
+		if (err == nil) != scenario.allowed {
+			t.Errorf("%s: got error %v", scenario.description, err)
+		}
+	}
+}
+
+func TestGetFallbackMfaType(t *testing.T) {
+	// the last credential is deleted while WebAuthn is preferred
+	user := &User{MfaWebauthnEnabled: false, TotpSecret: "secret", RecoveryCodes: []string{"code"}, PreferredMfaType: WebAuthnType}
+	if mfaType := getFallbackMfaType(user); mfaType != TotpType {
+		t.Errorf("expected TOTP to be preferred instead, got %q", mfaType)
+	}
+
+	user.TotpSecret = ""
+	if mfaType := getFallbackMfaType(user); mfaType != RecoveryCodeType {
+		t.Errorf("expected the recovery codes to be preferred instead, got %q", mfaType)
+	}
+
+	user.RecoveryCodes = []string{}
+	if mfaType := getFallbackMfaType(user); mfaType != "" {
+		t.Errorf("expected no MFA type to be left, got %q", mfaType)
+	}
+}
+
+func TestUpdateCredentialsUsage(t *testing.T) {
+	setupTestOrmer(t, &User{}, &ChangeEvent{}, &Syncer{})
+
+	storedCredential := WebauthnCredential{
+		Credential:  webauthn.Credential{ID: []byte{1, 2, 3}, Authenticator: webauthn.Authenticator{SignCount: 5}},
+		DisplayName: "Security key",
+	}
+	user := &User{Owner: "built-in", Name: "alice", WebauthnCredentials: []WebauthnCredential{storedCredential}}
+	_, err := ormer.Engine.Insert(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the library keeps the stored sign count and sets the clone warning when the count has not increased
+	clonedCredential := storedCredential.Credential
+	clonedCredential.Authenticator.CloneWarning = true
+	err = user.UpdateCredentialsUsage(&clonedCredential)
+	if err == nil {
+		t.Fatal("expected the assertion with a regressed sign count to be rejected")
+	}
+
+	user, err = getUser("built-in", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	credential := user.WebauthnCredentials[0]
+	if !credential.Authenticator.CloneWarning || credential.LastUsedTime != "" {
+		t.Fatalf("unexpected credential: %v", credential)
+	}
+
+	usedCredential := storedCredential.Credential
+	usedCredential.Authenticator.SignCount = 6
+	err = user.UpdateCredentialsUsage(&usedCredential)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, err = getUser("built-in", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	credential = user.WebauthnCredentials[0]
+	if credential.Authenticator.SignCount != 6 || credential.LastUsedTime == "" {
+		t.Fatalf("unexpected credential: %v", credential)
+	}
+}
@@ -88,7 +88,12 @@ func DialWithCert(SshUser string, CertId string, SshHost string, SshPort int) (*
 		return nil, err
 	}
 
-	signer, err := ssh.ParsePrivateKey([]byte(cert.PrivateKey))
+	privateKey, err := getCertPrivateKeyPem(cert)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := ssh.ParsePrivateKey([]byte(privateKey))
 	if err != nil {
 		return nil, err
 	}
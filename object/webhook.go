@@ -112,8 +112,14 @@ func UpdateWebhook(id string, webhook *Webhook) (bool, error) {
 		return false, nil
 	}
 
+	err := encryptColumns(webhook)
+	if err != nil {
+		return false, err
+	}
+
 	affected, err := ormer.Engine.ID(core.PK{owner, name}).AllCols().Update(webhook)
 	if err != nil {
+		restoreEncryptedColumns(webhook)
 		return false, err
 	}
 
@@ -121,8 +127,14 @@ func UpdateWebhook(id string, webhook *Webhook) (bool, error) {
 }
 
 func AddWebhook(webhook *Webhook) (bool, error) {
+	err := encryptColumns(webhook)
+	if err != nil {
+		return false, err
+	}
+
 	affected, err := ormer.Engine.Insert(webhook)
 	if err != nil {
+		restoreEncryptedColumns(webhook)
 		return false, err
 	}
 
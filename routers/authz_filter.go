@@ -23,6 +23,7 @@ import (
 	"github.com/casdoor/casdoor/object"
 
 	"github.com/beego/beego/context"
+	"github.com/beego/beego/logs"
 	"github.com/casdoor/casdoor/authz"
 	"github.com/casdoor/casdoor/util"
 )
@@ -169,10 +170,22 @@ func getUrlPath(urlPath string) string {
 		return "/api/webauthn"
 	}
 
+	if strings.HasPrefix(urlPath, "/api/mfa/push") {
+		return "/api/mfa/push"
+	}
+
 	if strings.HasPrefix(urlPath, "/api/saml/redirect") {
 		return "/api/saml/redirect"
 	}
 
+	if strings.HasPrefix(urlPath, "/api/saml/slo") {
+		return "/api/saml/slo"
+	}
+
+	if strings.HasPrefix(urlPath, "/api/saml/artifact") {
+		return "/api/saml/artifact"
+	}
+
 	return urlPath
 }
 
@@ -221,7 +234,10 @@ func ApiFilter(ctx *context.Context) {
 		record.Response = fmt.Sprintf("{status:\"error\", msg:\"%s\"}", T(ctx, "auth:Unauthorized operation"))
 
 		util.SafeGoroutine(func() {
-			object.AddRecord(record)
+			_, err := object.AddRecord(record)
+			if err != nil {
+				logs.Error(fmt.Sprintf("ApiFilter() error: %s", err.Error()))
+			}
 		})
 	}
 }
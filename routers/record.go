@@ -18,6 +18,7 @@ import (
 	"fmt"
 
 	"github.com/beego/beego/context"
+	"github.com/beego/beego/logs"
 	"github.com/casdoor/casdoor/object"
 	"github.com/casdoor/casdoor/util"
 	"github.com/casvisor/casvisor-go-sdk/casvisorsdk"
@@ -71,7 +72,7 @@ func RecordMessage(ctx *context.Context) {
 func AfterRecordMessage(ctx *context.Context) {
 	record, err := object.NewRecord(ctx)
 	if err != nil {
-		fmt.Printf("AfterRecordMessage() error: %s\n", err.Error())
+		logs.Error(fmt.Sprintf("AfterRecordMessage() error: %s", err.Error()))
 		return
 	}
 
@@ -89,12 +90,12 @@ func AfterRecordMessage(ctx *context.Context) {
 		var user *object.User
 		user, err = object.GetUser(userId)
 		if err != nil {
-			fmt.Printf("AfterRecordMessage() error: %s\n", err.Error())
+			logs.Error(fmt.Sprintf("AfterRecordMessage() error: %s", err.Error()))
 			return
 		}
 		if user == nil {
 			err = fmt.Errorf("the user: %s is not found", userId)
-			fmt.Printf("AfterRecordMessage() error: %s\n", err.Error())
+			logs.Error(fmt.Sprintf("AfterRecordMessage() error: %s", err.Error()))
 			return
 		}
 
@@ -102,10 +103,16 @@ func AfterRecordMessage(ctx *context.Context) {
 	}
 
 	util.SafeGoroutine(func() {
-		object.AddRecord(record)
+		_, err := object.AddRecord(record)
+		if err != nil {
+			logs.Error(fmt.Sprintf("AfterRecordMessage() error: %s", err.Error()))
+		}
 
 		if record2 != nil {
-			object.AddRecord(record2)
+			_, err = object.AddRecord(record2)
+			if err != nil {
+				logs.Error(fmt.Sprintf("AfterRecordMessage() error: %s", err.Error()))
+			}
 		}
 	})
 }
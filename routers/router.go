@@ -61,6 +61,11 @@ func initAPI() {
 	beego.Router("/api/acs", &controllers.ApiController{}, "POST:HandleSamlLogin")
 	beego.Router("/api/saml/metadata", &controllers.ApiController{}, "GET:GetSamlMeta")
 	beego.Router("/api/saml/redirect/:owner/:application", &controllers.ApiController{}, "*:HandleSamlRedirect")
+	beego.Router("/api/saml/slo/:owner/:application", &controllers.ApiController{}, "GET,POST:HandleSamlLogout")
+	beego.Router("/api/saml/artifact/:owner/:application", &controllers.ApiController{}, "POST:ResolveSamlArtifact")
+	beego.Router("/api/parse-saml-sp-metadata", &controllers.ApiController{}, "POST:ParseSamlSpMetadata")
+	beego.Router("/api/get-saml-idps", &controllers.ApiController{}, "GET:GetSamlIdps")
+	beego.Router("/api/fetch-saml-idp-metadata", &controllers.ApiController{}, "POST:FetchSamlIdpMetadata")
 	beego.Router("/api/webhook", &controllers.ApiController{}, "*:HandleOfficialAccountEvent")
 	beego.Router("/api/get-qrcode", &controllers.ApiController{}, "GET:GetQRCode")
 	beego.Router("/api/get-webhook-event", &controllers.ApiController{}, "GET:GetWebhookEventType")
@@ -130,6 +135,7 @@ func initAPI() {
 	beego.Router("/api/update-cert", &controllers.ApiController{}, "POST:UpdateCert")
 	beego.Router("/api/add-cert", &controllers.ApiController{}, "POST:AddCert")
 	beego.Router("/api/delete-cert", &controllers.ApiController{}, "POST:DeleteCert")
+	beego.Router("/api/rotate-cert", &controllers.ApiController{}, "POST:RotateCert")
 
 	beego.Router("/api/get-roles", &controllers.ApiController{}, "GET:GetRoles")
 	beego.Router("/api/get-role", &controllers.ApiController{}, "GET:GetRole")
@@ -146,6 +152,39 @@ func initAPI() {
 	beego.Router("/api/add-permission", &controllers.ApiController{}, "POST:AddPermission")
 	beego.Router("/api/delete-permission", &controllers.ApiController{}, "POST:DeletePermission")
 	beego.Router("/api/upload-permissions", &controllers.ApiController{}, "POST:UploadPermissions")
+	beego.Router("/api/export-policies", &controllers.ApiController{}, "GET:ExportPolicies")
+	beego.Router("/api/plan-policies", &controllers.ApiController{}, "POST:PlanPolicies")
+	beego.Router("/api/import-policies", &controllers.ApiController{}, "POST:ImportPolicies")
+	beego.Router("/api/request-elevation", &controllers.ApiController{}, "POST:RequestElevation")
+	beego.Router("/api/approve-elevation", &controllers.ApiController{}, "POST:ApproveElevation")
+	beego.Router("/api/reject-elevation", &controllers.ApiController{}, "POST:RejectElevation")
+
+	beego.Router("/api/get-access-workflows", &controllers.ApiController{}, "GET:GetAccessWorkflows")
+	beego.Router("/api/get-access-workflow", &controllers.ApiController{}, "GET:GetAccessWorkflow")
+	beego.Router("/api/update-access-workflow", &controllers.ApiController{}, "POST:UpdateAccessWorkflow")
+	beego.Router("/api/add-access-workflow", &controllers.ApiController{}, "POST:AddAccessWorkflow")
+	beego.Router("/api/delete-access-workflow", &controllers.ApiController{}, "POST:DeleteAccessWorkflow")
+
+	beego.Router("/api/get-access-requests", &controllers.ApiController{}, "GET:GetAccessRequests")
+	beego.Router("/api/get-access-request", &controllers.ApiController{}, "GET:GetAccessRequest")
+	beego.Router("/api/get-my-access-requests", &controllers.ApiController{}, "GET:GetMyAccessRequests")
+	beego.Router("/api/get-pending-access-requests", &controllers.ApiController{}, "GET:GetPendingAccessRequests")
+	beego.Router("/api/submit-access-request", &controllers.ApiController{}, "POST:SubmitAccessRequest")
+	beego.Router("/api/review-access-request", &controllers.ApiController{}, "POST:ReviewAccessRequest")
+	beego.Router("/api/cancel-access-request", &controllers.ApiController{}, "POST:CancelAccessRequest")
+	beego.Router("/api/delete-access-request", &controllers.ApiController{}, "POST:DeleteAccessRequest")
+
+	beego.Router("/api/get-access-reviews", &controllers.ApiController{}, "GET:GetAccessReviews")
+	beego.Router("/api/get-access-review", &controllers.ApiController{}, "GET:GetAccessReview")
+	beego.Router("/api/update-access-review", &controllers.ApiController{}, "POST:UpdateAccessReview")
+	beego.Router("/api/add-access-review", &controllers.ApiController{}, "POST:AddAccessReview")
+	beego.Router("/api/delete-access-review", &controllers.ApiController{}, "POST:DeleteAccessReview")
+	beego.Router("/api/start-access-review", &controllers.ApiController{}, "POST:StartAccessReview")
+	beego.Router("/api/complete-access-review", &controllers.ApiController{}, "POST:CompleteAccessReview")
+	beego.Router("/api/export-access-review", &controllers.ApiController{}, "GET:ExportAccessReview")
+	beego.Router("/api/get-access-review-items", &controllers.ApiController{}, "GET:GetAccessReviewItems")
+	beego.Router("/api/get-my-access-review-items", &controllers.ApiController{}, "GET:GetMyAccessReviewItems")
+	beego.Router("/api/decide-access-review-item", &controllers.ApiController{}, "POST:DecideAccessReviewItem")
 
 	beego.Router("/api/get-models", &controllers.ApiController{}, "GET:GetModels")
 	beego.Router("/api/get-model", &controllers.ApiController{}, "GET:GetModel")
@@ -171,6 +210,7 @@ func initAPI() {
 
 	beego.Router("/api/enforce", &controllers.ApiController{}, "POST:Enforce")
 	beego.Router("/api/batch-enforce", &controllers.ApiController{}, "POST:BatchEnforce")
+	beego.Router("/api/enforce-explain", &controllers.ApiController{}, "POST:EnforceExplain")
 	beego.Router("/api/get-all-objects", &controllers.ApiController{}, "GET:GetAllObjects")
 	beego.Router("/api/get-all-actions", &controllers.ApiController{}, "GET:GetAllActions")
 	beego.Router("/api/get-all-roles", &controllers.ApiController{}, "GET:GetAllRoles")
@@ -234,6 +274,10 @@ func initAPI() {
 	beego.Router("/api/get-system-info", &controllers.ApiController{}, "GET:GetSystemInfo")
 	beego.Router("/api/get-version-info", &controllers.ApiController{}, "GET:GetVersionInfo")
 	beego.Router("/api/health", &controllers.ApiController{}, "GET:Health")
+	beego.Router("/api/get-replication-status", &controllers.ApiController{}, "GET:GetReplicationStatus")
+	beego.Router("/api/start-replication", &controllers.ApiController{}, "POST:StartReplication")
+	beego.Router("/api/stop-replication", &controllers.ApiController{}, "POST:StopReplication")
+	beego.Router("/api/promote-replica", &controllers.ApiController{}, "POST:PromoteReplica")
 	beego.Router("/api/get-prometheus-info", &controllers.ApiController{}, "GET:GetPrometheusInfo")
 	beego.Handler("/api/metrics", promhttp.Handler())
 
@@ -243,6 +287,9 @@ func initAPI() {
 	beego.Router("/api/add-syncer", &controllers.ApiController{}, "POST:AddSyncer")
 	beego.Router("/api/delete-syncer", &controllers.ApiController{}, "POST:DeleteSyncer")
 	beego.Router("/api/run-syncer", &controllers.ApiController{}, "GET:RunSyncer")
+	beego.Router("/api/dry-run-syncer", &controllers.ApiController{}, "POST:DryRunSyncer")
+	beego.Router("/api/get-syncer-runs", &controllers.ApiController{}, "GET:GetSyncerRuns")
+	beego.Router("/api/get-syncer-run", &controllers.ApiController{}, "GET:GetSyncerRun")
 	beego.Router("/api/test-syncer-db", &controllers.ApiController{}, "POST:TestSyncerDb")
 
 	beego.Router("/api/get-webhooks", &controllers.ApiController{}, "GET:GetWebhooks")
@@ -280,6 +327,13 @@ func initAPI() {
 	beego.Router("/api/get-records", &controllers.ApiController{}, "GET:GetRecords")
 	beego.Router("/api/get-records-filter", &controllers.ApiController{}, "POST:GetRecordsByFilter")
 	beego.Router("/api/add-record", &controllers.ApiController{}, "POST:AddRecord")
+	beego.Router("/api/verify-records", &controllers.ApiController{}, "GET:VerifyRecords")
+	beego.Router("/api/export-records", &controllers.ApiController{}, "GET:ExportRecords")
+	beego.Router("/api/add-record-checkpoint", &controllers.ApiController{}, "POST:AddRecordCheckpoint")
+	beego.Router("/api/get-audit-sinks", &controllers.ApiController{}, "GET:GetAuditSinks")
+
+	beego.Router("/api/get-change-events", &controllers.ApiController{}, "GET:GetChangeEvents")
+	beego.Router("/api/stream-change-events", &controllers.ApiController{}, "GET:StreamChangeEvents")
 
 	beego.Router("/api/send-email", &controllers.ApiController{}, "POST:SendEmail")
 	beego.Router("/api/send-sms", &controllers.ApiController{}, "POST:SendSms")
@@ -289,12 +343,23 @@ func initAPI() {
 	beego.Router("/api/webauthn/signup/finish", &controllers.ApiController{}, "POST:WebAuthnSignupFinish")
 	beego.Router("/api/webauthn/signin/begin", &controllers.ApiController{}, "GET:WebAuthnSigninBegin")
 	beego.Router("/api/webauthn/signin/finish", &controllers.ApiController{}, "POST:WebAuthnSigninFinish")
+	beego.Router("/api/webauthn/mfa/begin", &controllers.ApiController{}, "GET:WebAuthnMfaBegin")
+	beego.Router("/api/webauthn/get-credentials", &controllers.ApiController{}, "GET:GetWebAuthnCredentials")
+	beego.Router("/api/webauthn/rename-credential", &controllers.ApiController{}, "POST:RenameWebAuthnCredential")
+	beego.Router("/api/webauthn/delete-credential", &controllers.ApiController{}, "POST:DeleteWebAuthnCredential")
 
 	beego.Router("/api/mfa/setup/initiate", &controllers.ApiController{}, "POST:MfaSetupInitiate")
 	beego.Router("/api/mfa/setup/verify", &controllers.ApiController{}, "POST:MfaSetupVerify")
 	beego.Router("/api/mfa/setup/enable", &controllers.ApiController{}, "POST:MfaSetupEnable")
+	beego.Router("/api/mfa/push/send", &controllers.ApiController{}, "POST:SendMfaPush")
+	beego.Router("/api/mfa/push/get-status", &controllers.ApiController{}, "GET:GetMfaPushStatus")
+	beego.Router("/api/mfa/push/get-challenge", &controllers.ApiController{}, "GET:GetMfaPushChallenge")
+	beego.Router("/api/mfa/push/get-pending-challenges", &controllers.ApiController{}, "GET:GetPendingMfaPushChallenges")
+	beego.Router("/api/mfa/push/respond", &controllers.ApiController{}, "POST:RespondMfaPush")
 	beego.Router("/api/delete-mfa", &controllers.ApiController{}, "POST:DeleteMfa")
 	beego.Router("/api/set-preferred-mfa", &controllers.ApiController{}, "POST:SetPreferredMfa")
+	beego.Router("/api/get-recovery-code-count", &controllers.ApiController{}, "GET:GetRecoveryCodeCount")
+	beego.Router("/api/regenerate-recovery-codes", &controllers.ApiController{}, "POST:RegenerateRecoveryCodes")
 
 	beego.Router("/.well-known/openid-configuration", &controllers.RootController{}, "GET:GetOidcDiscovery")
 	beego.Router("/.well-known/jwks", &controllers.RootController{}, "*:GetJwks")
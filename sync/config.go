@@ -0,0 +1,49 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sync
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/casdoor/casdoor/sync_v2"
+)
+
+// Config is the "syncConfig" config, the two MySQL databases whose changes are copied to each other
+type Config struct {
+	Databases []*sync_v2.NodeConfig `json:"databases"`
+}
+
+func parseConfig(configText string) (*Config, error) {
+	config := &Config{}
+	err := json.Unmarshal([]byte(configText), config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid syncConfig config: %s", err.Error())
+	}
+
+	if len(config.Databases) != 2 {
+		return nil, fmt.Errorf("the sync job needs two databases, got: %d", len(config.Databases))
+	}
+	for _, node := range config.Databases {
+		if node == nil || node.Host == "" || node.Database == "" {
+			return nil, fmt.Errorf("the sync job databases need a host and a database")
+		}
+		if node.Port == 0 {
+			node.Port = 3306
+		}
+	}
+
+	return config, nil
+}
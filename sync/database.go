@@ -17,16 +17,14 @@ package sync
 import (
 	"fmt"
 
+	"github.com/casdoor/casdoor/sync_v2"
 	"github.com/go-mysql-org/go-mysql/canal"
+	"github.com/go-sql-driver/mysql"
 	"github.com/xorm-io/xorm"
 )
 
 type Database struct {
-	host     string
-	port     int
-	database string
-	username string
-	password string
+	config *sync_v2.NodeConfig
 
 	engine     *xorm.Engine
 	serverId   uint32
@@ -35,44 +33,47 @@ type Database struct {
 	canal.DummyEventHandler
 }
 
-func newDatabase(host string, port int, database string, username string, password string) *Database {
-	db := &Database{
-		host:     host,
-		port:     port,
-		database: database,
-		username: username,
-		password: password,
-	}
+func newDatabase(config *sync_v2.NodeConfig) (*Database, error) {
+	db := &Database{config: config}
 
-	dataSourceName := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s", username, password, host, port, database)
-	engine, err := createEngine(dataSourceName)
+	mysqlConfig := mysql.NewConfig()
+	mysqlConfig.User = config.Username
+	mysqlConfig.Passwd = config.Password
+	mysqlConfig.Net = "tcp"
+	mysqlConfig.Addr = db.getAddress()
+	mysqlConfig.DBName = config.Database
+	engine, err := createEngine(mysqlConfig.FormatDSN())
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	db.engine = engine
 
 	db.serverId, err = getServerId(engine)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	db.serverUuid, err = getServerUuid(engine)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	return db
+	return db, nil
+}
+
+func (db *Database) getAddress() string {
+	return fmt.Sprintf("%s:%d", db.config.Host, db.config.Port)
 }
 
 func (db *Database) getCanalConfig() *canal.Config {
 	// config canal
 	cfg := canal.NewDefaultConfig()
-	cfg.Addr = fmt.Sprintf("%s:%d", db.host, db.port)
-	cfg.Password = db.password
-	cfg.User = db.username
+	cfg.Addr = db.getAddress()
+	cfg.Password = db.config.Password
+	cfg.User = db.config.Username
 	// We only care table in database1
-	cfg.Dump.TableDB = db.database
+	cfg.Dump.TableDB = db.config.Database
 	return cfg
 }
 
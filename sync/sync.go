@@ -14,29 +14,72 @@
 
 package sync
 
-import "sync"
+import (
+	"fmt"
 
+	"github.com/beego/beego/logs"
+	"github.com/casdoor/casdoor/sync_v2"
+)
+
+var currentConfig *Config
+
+// InitSyncJob sets up the sync job from the "syncConfig" config, which is a JSON Config. The sync job is
+// disabled when the config is empty, and cannot be used with the active/passive replication of sync_v2.
+func InitSyncJob(configText string) error {
+	if configText == "" {
+		return nil
+	}
+
+	if sync_v2.IsReplicationEnabled() {
+		return fmt.Errorf("the syncConfig and replicationConfig configs cannot be used together")
+	}
+
+	config, err := parseConfig(configText)
+	if err != nil {
+		return err
+	}
+
+	currentConfig = config
+	return nil
+}
+
+// RunSyncJob copies the changes between the databases of the "syncConfig" config until a copy fails
+func RunSyncJob() {
+	config := currentConfig
+	if config == nil {
+		return
+	}
+
+	db1, err := newDatabase(config.Databases[0])
+	if err != nil {
+		logs.Error("sync job: %s", err.Error())
+		return
+	}
+	db2, err := newDatabase(config.Databases[1])
+	if err != nil {
+		logs.Error("sync job: %s", err.Error())
+		return
+	}
+
+	err = startSyncJob(db1, db2)
+	if err != nil {
+		logs.Error("sync job: %s", err.Error())
+	}
+}
+
+// startSyncJob copies the changes of each database to the other one until one of the copies fails
 func startSyncJob(db1 *Database, db2 *Database) error {
-	var wg sync.WaitGroup
+	errChan := make(chan error, 2)
 
 	// start canal1 replication
-	go func(db1 *Database, db2 *Database) {
-		err := db1.startCanal(db2)
-		if err != nil {
-			panic(err)
-		}
-	}(db1, db2)
-	wg.Add(1)
+	go func() {
+		errChan <- db1.startCanal(db2)
+	}()
 
 	// start canal2 replication
-	go func(db1 *Database, db2 *Database) {
-		err := db2.startCanal(db1)
-		if err != nil {
-			panic(err)
-		}
-	}(db1, db2)
-	wg.Add(1)
-
-	wg.Wait()
-	return nil
+	go func() {
+		errChan <- db2.startCanal(db1)
+	}()
+
+	return <-errChan
 }
@@ -24,10 +24,25 @@ import (
 )
 
 func TestStartSyncJob(t *testing.T) {
-	db1 := newDatabase("localhost", 3306, "casdoor", "root", "123456")
-	db2 := newDatabase("localhost", 3306, "casdoor2", "root", "123456")
-	err := startSyncJob(db1, db2)
+	config, err := parseConfig(`{"databases": [
+		{"host": "localhost", "username": "root", "password": "123456", "database": "casdoor"},
+		{"host": "localhost", "username": "root", "password": "123456", "database": "casdoor2"}
+	]}`)
 	if err != nil {
-		panic(err)
+		t.Fatal(err)
+	}
+
+	db1, err := newDatabase(config.Databases[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	db2, err := newDatabase(config.Databases[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = startSyncJob(db1, db2)
+	if err != nil {
+		t.Fatal(err)
 	}
 }
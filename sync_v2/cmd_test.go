@@ -18,99 +18,70 @@
 package sync_v2
 
 import (
+	"encoding/json"
 	"testing"
 
 	_ "github.com/go-sql-driver/mysql"
 )
 
-/*
-	The following config should be added to my.cnf:
-
-	gtid_mode=on
-	enforce_gtid_consistency=on
-	binlog-format=ROW
-	server-id = 1 # this should be different for each mysql instance (1,2)
-	auto_increment_offset = 1 # this is same as server-id
-	auto_increment_increment = 2 # this is same as the number of mysql instances (2)
-	log-bin = mysql-bin
-	replicate-do-db = casdoor # this is the database name
-	binlog-do-db = casdoor # this is the database name
-*/
-
-var Configs = []Database{
-	{
-		host:     "test-db.v2tl.com",
-		port:     3306,
-		username: "root",
-		password: "password",
-		database: "casdoor",
-		// the following two fields are used to create replication user, you don't need to change them
-		slaveUser:     "repl_user",
-		slavePassword: "repl_user",
-	},
-	{
-		host:     "localhost",
-		port:     3306,
-		username: "root",
-		password: "password",
-		database: "casdoor",
-		// the following two fields are used to create replication user, you don't need to change them
-		slaveUser:     "repl_user",
-		slavePassword: "repl_user",
-	},
-}
-
-func TestStartMasterSlaveSync(t *testing.T) {
+// testConfig is a MySQL pair, see master.go for the config of my.cnf
+var testConfig = &Config{
+	Driver: DriverMysql,
+	Role:   RolePrimary,
 	// for example, this is aliyun rds
-	db0 := newDatabase(&Configs[0])
+	Primary: &NodeConfig{
+		Host:     "test-db.v2tl.com",
+		Port:     3306,
+		Username: "root",
+		Password: "password",
+		Database: "casdoor",
+	},
 	// for example, this is local mysql instance
-	db1 := newDatabase(&Configs[1])
-
-	createSlaveUser(db0)
-	// db0 is master, db1 is slave
-	startSlave(db0, db1)
+	Replica: &NodeConfig{
+		Host:     "localhost",
+		Port:     3306,
+		Username: "root",
+		Password: "password",
+		Database: "casdoor",
+	},
+	ReplicationUser:     "repl_user",
+	ReplicationPassword: "repl_user",
 }
 
-func TestStopMasterSlaveSync(t *testing.T) {
-	// for example, this is aliyun rds
-	db0 := newDatabase(&Configs[0])
-	// for example, this is local mysql instance
-	db1 := newDatabase(&Configs[1])
-
-	stopSlave(db1)
-	deleteSlaveUser(db0)
-}
+func initTestReplication(t *testing.T) {
+	configText, err := json.Marshal(testConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
 
-func TestStartMasterMasterSync(t *testing.T) {
-	db0 := newDatabase(&Configs[0])
-	db1 := newDatabase(&Configs[1])
-	createSlaveUser(db0)
-	createSlaveUser(db1)
-	// db0 is master, db1 is slave
-	startSlave(db0, db1)
-	// db1 is master, db0 is slave
-	startSlave(db1, db0)
+	err = InitReplication(string(configText))
+	if err != nil {
+		t.Fatal(err)
+	}
 }
 
-func TestStopMasterMasterSync(t *testing.T) {
-	db0 := newDatabase(&Configs[0])
-	db1 := newDatabase(&Configs[1])
-	stopSlave(db0)
-	stopSlave(db1)
-	deleteSlaveUser(db0)
-	deleteSlaveUser(db1)
+func TestStartReplication(t *testing.T) {
+	initTestReplication(t)
+	status, err := StartReplication()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("%s: %s", status.State, status.Message)
 }
 
-func TestShowSlaveStatus(t *testing.T) {
-	db0 := newDatabase(&Configs[0])
-	db1 := newDatabase(&Configs[1])
-	slaveStatus(db0)
-	slaveStatus(db1)
+func TestStopReplication(t *testing.T) {
+	initTestReplication(t)
+	_, err := StopReplication()
+	if err != nil {
+		t.Fatal(err)
+	}
 }
 
-func TestShowMasterStatus(t *testing.T) {
-	db0 := newDatabase(&Configs[0])
-	db1 := newDatabase(&Configs[1])
-	masterStatus(db0)
-	masterStatus(db1)
+func TestShowReplicationStatus(t *testing.T) {
+	initTestReplication(t)
+	status, err := GetReplicationStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("%s: %s, lag: %d, primary: %v, replica: %v", status.State, status.Message, status.LagSeconds, status.Primary, status.Replica)
 }
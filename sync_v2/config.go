@@ -0,0 +1,125 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sync_v2
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+const (
+	DriverMysql    = "mysql"
+	DriverPostgres = "postgres"
+
+	RolePrimary = "primary"
+	RoleReplica = "replica"
+)
+
+var reIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+type NodeConfig struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Database string `json:"database"`
+	// SslMode is only used by PostgreSQL, "disable" by default
+	SslMode string `json:"sslMode"`
+}
+
+// Config is the "replicationConfig" config, an active/passive pair of databases. Role is the role of the
+// database used by this Casdoor instance, the other instance of the pair uses the same config with the other role.
+type Config struct {
+	Driver  string      `json:"driver"`
+	Role    string      `json:"role"`
+	Primary *NodeConfig `json:"primary"`
+	Replica *NodeConfig `json:"replica"`
+
+	// ReplicationUser is created in the primary and used by the replica to connect to it
+	ReplicationUser     string `json:"replicationUser"`
+	ReplicationPassword string `json:"replicationPassword"`
+	// Publication and Subscription are the names of the PostgreSQL logical replication
+	Publication  string `json:"publication"`
+	Subscription string `json:"subscription"`
+
+	// MaxLagSeconds is the lag above which a replica is reported unhealthy and not promoted without force
+	MaxLagSeconds int64 `json:"maxLagSeconds"`
+	// PromoteTimeoutSeconds is how long a promotion waits for the replica to catch up with the primary
+	PromoteTimeoutSeconds int64 `json:"promoteTimeoutSeconds"`
+	// CheckIntervalSeconds is the interval of the status checks reported by /api/health
+	CheckIntervalSeconds int64 `json:"checkIntervalSeconds"`
+}
+
+func parseConfig(configText string) (*Config, error) {
+	config := &Config{}
+	err := json.Unmarshal([]byte(configText), config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid replicationConfig config: %s", err.Error())
+	}
+
+	if config.Driver != DriverMysql && config.Driver != DriverPostgres {
+		return nil, fmt.Errorf("unsupported replication driver: %s", config.Driver)
+	}
+	if config.Role != RolePrimary && config.Role != RoleReplica {
+		return nil, fmt.Errorf("invalid replication role: %s", config.Role)
+	}
+	if config.Primary == nil || config.Replica == nil {
+		return nil, fmt.Errorf("the replication needs both the primary and the replica databases")
+	}
+
+	for _, node := range []*NodeConfig{config.Primary, config.Replica} {
+		if node.Host == "" || node.Database == "" {
+			return nil, fmt.Errorf("the replication databases need a host and a database")
+		}
+		if node.Port == 0 {
+			if config.Driver == DriverMysql {
+				node.Port = 3306
+			} else {
+				node.Port = 5432
+			}
+		}
+		if node.SslMode == "" {
+			node.SslMode = "disable"
+		}
+	}
+
+	if config.ReplicationUser == "" {
+		config.ReplicationUser = "casdoor_replication"
+	}
+	if config.Publication == "" {
+		config.Publication = "casdoor_publication"
+	}
+	if config.Subscription == "" {
+		config.Subscription = "casdoor_subscription"
+	}
+	for _, name := range []string{config.ReplicationUser, config.Publication, config.Subscription} {
+		if !reIdentifier.MatchString(name) {
+			return nil, fmt.Errorf("invalid replication name: %s, only letters, digits and underscores are allowed", name)
+		}
+	}
+
+	if config.MaxLagSeconds <= 0 {
+		config.MaxLagSeconds = 30
+	}
+	if config.PromoteTimeoutSeconds <= 0 {
+		config.PromoteTimeoutSeconds = 30
+	}
+	if config.CheckIntervalSeconds <= 0 {
+		config.CheckIntervalSeconds = 10
+	}
+
+	return config, nil
+}
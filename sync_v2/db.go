@@ -16,55 +16,99 @@ package sync_v2
 
 import (
 	"fmt"
-	"log"
+	"strings"
+	"time"
 
+	"github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
 	"github.com/xorm-io/xorm"
 )
 
 type Database struct {
-	host          string
-	port          int
-	database      string
-	username      string
-	password      string
-	slaveUser     string
-	slavePassword string
-	engine        *xorm.Engine
+	driver string
+	config *NodeConfig
+	engine *xorm.Engine
 }
 
-func (db *Database) exec(format string, args ...interface{}) []map[string]string {
-	sql := fmt.Sprintf(format, args...)
-	res, err := db.engine.QueryString(sql)
+// newDatabase creates the engine of a database without connecting to it, so that a database down
+// is reported by the operations using it
+func newDatabase(driver string, config *NodeConfig) (*Database, error) {
+	db := &Database{driver: driver, config: config}
+	engine, err := xorm.NewEngine(driver, db.getDataSourceName())
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+
+	// the operations are serialized, a single connection also keeps it when the other sessions are terminated
+	engine.SetMaxOpenConns(1)
+	db.engine = engine
+	return db, nil
+}
+
+func (db *Database) getDataSourceName() string {
+	if db.driver == DriverMysql {
+		mysqlConfig := mysql.NewConfig()
+		mysqlConfig.User = db.config.Username
+		mysqlConfig.Passwd = db.config.Password
+		mysqlConfig.Net = "tcp"
+		mysqlConfig.Addr = db.getAddress()
+		mysqlConfig.DBName = db.config.Database
+		mysqlConfig.Timeout = 5 * time.Second
+		return mysqlConfig.FormatDSN()
 	}
-	return res
+
+	return db.getConnInfo(db.config.Username, db.config.Password)
+}
+
+// getConnInfo returns the PostgreSQL connection string of the database for a user
+func (db *Database) getConnInfo(username string, password string) string {
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s connect_timeout=5",
+		quoteConnInfoValue(db.config.Host), db.config.Port, quoteConnInfoValue(username), quoteConnInfoValue(password),
+		quoteConnInfoValue(db.config.Database), quoteConnInfoValue(db.config.SslMode))
+}
+
+func (db *Database) getAddress() string {
+	return fmt.Sprintf("%s:%d", db.config.Host, db.config.Port)
 }
 
-func createEngine(dataSourceName string) (*xorm.Engine, error) {
-	engine, err := xorm.NewEngine("mysql", dataSourceName)
+func (db *Database) query(query string, args ...interface{}) ([]map[string]string, error) {
+	res, err := db.engine.QueryString(append([]interface{}{query}, args...)...)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%s: %s", db.getAddress(), err.Error())
 	}
+	return res, nil
+}
 
-	// ping mysql
-	err = engine.Ping()
+func (db *Database) exec(query string, args ...interface{}) error {
+	_, err := db.engine.Exec(append([]interface{}{query}, args...)...)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("%s: %s", db.getAddress(), err.Error())
 	}
+	return nil
+}
 
-	engine.ShowSQL(true)
-	log.Println("mysql connection success")
-	return engine, nil
+func (db *Database) close() {
+	_ = db.engine.Close()
 }
 
-func newDatabase(db *Database) *Database {
-	dataSourceName := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s", db.username, db.password, db.host, db.port, db.database)
-	engine, err := createEngine(dataSourceName)
-	if err != nil {
-		panic(err)
+// quoteConnInfoValue quotes a value of a PostgreSQL connection string
+func quoteConnInfoValue(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
+
+// quoteString quotes a string literal for the statements not accepting parameters, like CREATE USER
+func quoteString(driver string, value string) string {
+	if driver == DriverMysql {
+		value = strings.ReplaceAll(value, `\`, `\\`)
 	}
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
 
-	db.engine = engine
-	return db
+func quoteIdentifier(driver string, name string) string {
+	if driver == DriverMysql {
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
 }
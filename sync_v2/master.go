@@ -16,74 +16,199 @@ package sync_v2
 
 import (
 	"fmt"
-	"log"
+	"regexp"
+	"strconv"
+	"strings"
 )
 
-func deleteSlaveUser(masterdb *Database) {
-	defer func() {
-		if err := recover(); err != nil {
-			log.Fatalln(err)
-		}
-	}()
-	masterdb.exec("delete from mysql.user where user = '%v'", masterdb.slaveUser)
-	masterdb.exec("flush privileges")
+/*
+	The MySQL replication needs the following config in my.cnf of both databases:
+
+	gtid_mode = on # optional, the binlog file and position are used without it
+	enforce_gtid_consistency = on
+	binlog-format = ROW
+	server-id = 1 # this should be different for each mysql instance (1,2)
+	log-bin = mysql-bin
+	replicate-do-db = casdoor # this is the database name
+	binlog-do-db = casdoor # this is the database name
+*/
+
+var reMysqlVersion = regexp.MustCompile(`^(\d+)\.(\d+)\.(\d+)`)
+
+type mysqlReplicator struct {
+	config *Config
+}
+
+type mysqlVersion struct {
+	isMariaDb bool
+	major     int
+	minor     int
+	patch     int
+}
+
+func parseMysqlVersion(version string) *mysqlVersion {
+	res := &mysqlVersion{isMariaDb: strings.Contains(strings.ToLower(version), "mariadb")}
+	match := reMysqlVersion.FindStringSubmatch(version)
+	if match != nil {
+		res.major, _ = strconv.Atoi(match[1])
+		res.minor, _ = strconv.Atoi(match[2])
+		res.patch, _ = strconv.Atoi(match[3])
+	}
+	return res
+}
+
+func (v *mysqlVersion) isAtLeast(major int, minor int, patch int) bool {
+	if v.isMariaDb {
+		return false
+	}
+	if v.major != major {
+		return v.major > major
+	}
+	if v.minor != minor {
+		return v.minor > minor
+	}
+	return v.patch >= patch
+}
+
+// isSourceSyntax tells whether the "SOURCE" and "REPLICA" statements replacing the "MASTER" and "SLAVE" ones are supported
+func (v *mysqlVersion) isSourceSyntax() bool {
+	return v.isAtLeast(8, 0, 23)
 }
 
-func createSlaveUser(masterdb *Database) {
-	res := make([]map[string]string, 0)
-	defer func() {
-		if err := recover(); err != nil {
-			log.Fatalln(err)
+func getMysqlVersion(db *Database) (*mysqlVersion, error) {
+	res, err := db.query("SELECT VERSION() AS version")
+	if err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("%s: the version is unknown", db.getAddress())
+	}
+	return parseMysqlVersion(res[0]["version"]), nil
+}
+
+// getMysqlColumn returns the first column found, the columns of the status are renamed in the recent versions
+func getMysqlColumn(row map[string]string, names ...string) string {
+	for _, name := range names {
+		if value, ok := row[name]; ok {
+			return value
 		}
-	}()
-	res = masterdb.exec("show databases")
-	dbNames := make([]string, 0, len(res))
-	for _, dbInfo := range res {
-		dbName := dbInfo["Database"]
-		dbNames = append(dbNames, dbName)
-	}
-	log.Println("dbs in mysql: ", dbNames)
-	res = masterdb.exec("show tables")
-	tableNames := make([]string, 0, len(res))
-	for _, table := range res {
-		tableName := table[fmt.Sprintf("Tables_in_%v", masterdb.database)]
-		tableNames = append(tableNames, tableName)
-	}
-	log.Printf("tables in %v: %v", masterdb.database, tableNames)
-
-	// delete user to prevent user already exists
-	res = masterdb.exec("delete from mysql.user where user = '%v'", masterdb.slaveUser)
-	res = masterdb.exec("flush privileges")
-
-	// create replication user
-	res = masterdb.exec("create user '%s'@'%s' identified by '%s'", masterdb.slaveUser, "%", masterdb.slavePassword)
-	res = masterdb.exec("select host, user from mysql.user where user = '%v'", masterdb.slaveUser)
-	log.Println("user: ", res[0])
-	res = masterdb.exec("grant replication slave on *.* to '%s'@'%s'", masterdb.slaveUser, "%")
-	res = masterdb.exec("flush privileges")
-	res = masterdb.exec("show grants for '%s'@'%s'", masterdb.slaveUser, "%")
-	log.Println("grants: ", res[0])
-
-	// check env
-	res = masterdb.exec("show variables like 'server_id'")
-	log.Println("server_id: ", res[0]["Value"])
-	res = masterdb.exec("show variables like 'log_bin'")
-	log.Println("log_bin: ", res[0]["Value"])
-	res = masterdb.exec("show variables like 'binlog_format'")
-	log.Println("binlog_format: ", res[0]["Value"])
-	res = masterdb.exec("show variables like 'binlog_row_image'")
+	}
+	return ""
 }
 
-func masterStatus(masterdb *Database) {
-	res := masterdb.exec("show master status")
+func getMysqlVariables(db *Database) (map[string]string, error) {
+	rows, err := db.query("SHOW GLOBAL VARIABLES WHERE Variable_name IN ('log_bin', 'binlog_format', 'server_id', 'gtid_mode', 'read_only')")
+	if err != nil {
+		return nil, err
+	}
+
+	res := map[string]string{}
+	for _, row := range rows {
+		res[row["Variable_name"]] = row["Value"]
+	}
+	return res, nil
+}
+
+func isMysqlGtidEnabled(db *Database) (bool, error) {
+	variables, err := getMysqlVariables(db)
+	if err != nil {
+		return false, err
+	}
+	return variables["gtid_mode"] == "ON", nil
+}
+
+// getBinlogStatus returns the current binlog file, position and executed GTID set of a database
+func getBinlogStatus(db *Database) (string, string, string, error) {
+	res, err := db.query("SHOW MASTER STATUS")
+	if err != nil {
+		// the statement is renamed since MySQL 8.2
+		res, err = db.query("SHOW BINARY LOG STATUS")
+		if err != nil {
+			return "", "", "", err
+		}
+	}
 	if len(res) == 0 {
-		log.Printf("no master status for master [%v:%v]\n", masterdb.host, masterdb.port)
-		return
-	}
-	pos := res[0]["Position"]
-	file := res[0]["File"]
-	log.Println("*****check master status*****")
-	log.Println("master:", masterdb.host, ":", masterdb.port)
-	log.Println("file:", file, ", position:", pos, ", master status:", res)
-	log.Println("*****************************")
+		return "", "", "", fmt.Errorf("%s: the binary log is disabled", db.getAddress())
+	}
+
+	gtid := strings.ReplaceAll(res[0]["Executed_Gtid_Set"], "\n", "")
+	return res[0]["File"], res[0]["Position"], gtid, nil
+}
+
+func (r *mysqlReplicator) getUser() string {
+	return quoteString(DriverMysql, r.config.ReplicationUser) + "@'%'"
+}
+
+func (r *mysqlReplicator) setupPrimary(primary *Database) error {
+	variables, err := getMysqlVariables(primary)
+	if err != nil {
+		return err
+	}
+	if variables["log_bin"] != "ON" {
+		return fmt.Errorf("%s: the binary log is disabled, log-bin should be set in my.cnf", primary.getAddress())
+	}
+	if variables["binlog_format"] != "ROW" {
+		return fmt.Errorf("%s: the binlog format is %s, it should be ROW", primary.getAddress(), variables["binlog_format"])
+	}
+
+	// the user is recreated so that its password is always the configured one
+	err = primary.exec("DROP USER IF EXISTS " + r.getUser())
+	if err != nil {
+		return err
+	}
+	err = primary.exec(fmt.Sprintf("CREATE USER %s IDENTIFIED BY %s", r.getUser(), quoteString(DriverMysql, r.config.ReplicationPassword)))
+	if err != nil {
+		return err
+	}
+	return primary.exec("GRANT REPLICATION SLAVE ON *.* TO " + r.getUser())
+}
+
+func (r *mysqlReplicator) getPrimaryStatus(primary *Database) (*NodeStatus, error) {
+	status := &NodeStatus{Address: primary.getAddress(), Role: RolePrimary, LagSeconds: -1}
+	file, position, gtid, err := getBinlogStatus(primary)
+	if err != nil {
+		return status, err
+	}
+
+	status.IsReachable = true
+	status.Position = fmt.Sprintf("%s:%s", file, position)
+	status.Gtid = gtid
+
+	variables, err := getMysqlVariables(primary)
+	if err != nil {
+		return status, err
+	}
+	status.IsReadOnly = variables["read_only"] == "ON"
+	return status, nil
+}
+
+// fence makes the database read-only, also for the users with SUPER like root when supported
+func (r *mysqlReplicator) fence(primary *Database) error {
+	err := primary.exec("SET GLOBAL read_only = ON")
+	if err != nil {
+		return err
+	}
+
+	version, err := getMysqlVersion(primary)
+	if err != nil {
+		return err
+	}
+	if version.isAtLeast(5, 7, 8) {
+		return primary.exec("SET GLOBAL super_read_only = ON")
+	}
+	return nil
+}
+
+func (r *mysqlReplicator) unfence(primary *Database) error {
+	version, err := getMysqlVersion(primary)
+	if err != nil {
+		return err
+	}
+	if version.isAtLeast(5, 7, 8) {
+		err = primary.exec("SET GLOBAL super_read_only = OFF")
+		if err != nil {
+			return err
+		}
+	}
+	return primary.exec("SET GLOBAL read_only = OFF")
 }
@@ -0,0 +1,300 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sync_v2
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+/*
+	The PostgreSQL logical replication needs the following config in postgresql.conf of the primary:
+
+	wal_level = logical
+
+	The replica subscribes to a publication of all the tables of the primary, so the tables must already exist
+	in the replica, which is the case once the Casdoor instance of the replica has started. The sequences are
+	not replicated, they are moved past the replicated rows when the replica is promoted.
+*/
+
+type postgresReplicator struct {
+	config *Config
+}
+
+func (r *postgresReplicator) getUser() string {
+	return quoteIdentifier(DriverPostgres, r.config.ReplicationUser)
+}
+
+func (r *postgresReplicator) getPublication() string {
+	return quoteIdentifier(DriverPostgres, r.config.Publication)
+}
+
+func (r *postgresReplicator) getSubscription() string {
+	return quoteIdentifier(DriverPostgres, r.config.Subscription)
+}
+
+func isPostgresRowFound(db *Database, query string, args ...interface{}) (bool, error) {
+	res, err := db.query(query, args...)
+	if err != nil {
+		return false, err
+	}
+	return len(res) != 0, nil
+}
+
+func (r *postgresReplicator) setupPrimary(primary *Database) error {
+	res, err := primary.query("SHOW wal_level")
+	if err != nil {
+		return err
+	}
+	if len(res) == 0 || res[0]["wal_level"] != "logical" {
+		return fmt.Errorf("%s: the wal_level should be logical in postgresql.conf", primary.getAddress())
+	}
+
+	isRoleFound, err := isPostgresRowFound(primary, "SELECT 1 FROM pg_roles WHERE rolname = $1", r.config.ReplicationUser)
+	if err != nil {
+		return err
+	}
+	password := quoteString(DriverPostgres, r.config.ReplicationPassword)
+	if isRoleFound {
+		err = primary.exec(fmt.Sprintf("ALTER ROLE %s WITH REPLICATION LOGIN PASSWORD %s", r.getUser(), password))
+	} else {
+		err = primary.exec(fmt.Sprintf("CREATE ROLE %s WITH REPLICATION LOGIN PASSWORD %s", r.getUser(), password))
+	}
+	if err != nil {
+		return err
+	}
+
+	// the initial copy of the tables reads them as the replication user
+	err = primary.exec(fmt.Sprintf("GRANT SELECT ON ALL TABLES IN SCHEMA public TO %s", r.getUser()))
+	if err != nil {
+		return err
+	}
+
+	isPublicationFound, err := isPostgresRowFound(primary, "SELECT 1 FROM pg_publication WHERE pubname = $1", r.config.Publication)
+	if err != nil {
+		return err
+	}
+	if isPublicationFound {
+		return nil
+	}
+	return primary.exec(fmt.Sprintf("CREATE PUBLICATION %s FOR ALL TABLES", r.getPublication()))
+}
+
+// isReadOnly tells whether the database is fenced, the setting of the database is read as the sessions
+// opened before keep the setting they started with
+func (r *postgresReplicator) isReadOnly(db *Database) (bool, error) {
+	return isPostgresRowFound(db, `SELECT 1 FROM pg_db_role_setting s JOIN pg_database d ON d.oid = s.setdatabase
+		WHERE d.datname = current_database() AND s.setrole = 0 AND 'default_transaction_read_only=on' = ANY(s.setconfig)`)
+}
+
+func (r *postgresReplicator) getPrimaryStatus(primary *Database) (*NodeStatus, error) {
+	status := &NodeStatus{Address: primary.getAddress(), Role: RolePrimary, LagSeconds: -1}
+	res, err := primary.query("SELECT pg_current_wal_lsn()::text AS lsn")
+	if err != nil {
+		return status, err
+	}
+
+	status.IsReachable = true
+	status.Position = res[0]["lsn"]
+	status.IsReadOnly, err = r.isReadOnly(primary)
+	if err != nil {
+		return status, err
+	}
+
+	// the WAL sender of the subscription reports how late the replica replays, the lag is NULL when idle
+	res, err = primary.query("SELECT COALESCE(EXTRACT(EPOCH FROM replay_lag), 0)::bigint AS lag FROM pg_stat_replication WHERE application_name = $1", r.config.Subscription)
+	if err != nil {
+		return status, err
+	}
+	if len(res) != 0 {
+		status.LagSeconds, _ = strconv.ParseInt(res[0]["lag"], 10, 64)
+	}
+	return status, nil
+}
+
+func (r *postgresReplicator) getReplicaStatus(replica *Database) (*NodeStatus, error) {
+	status := &NodeStatus{Address: replica.getAddress(), Role: RoleReplica, LagSeconds: -1}
+	res, err := replica.query("SELECT subenabled::text AS enabled FROM pg_subscription WHERE subname = $1", r.config.Subscription)
+	if err != nil {
+		return status, err
+	}
+
+	status.IsReachable = true
+	status.IsReadOnly, err = r.isReadOnly(replica)
+	if err != nil {
+		return status, err
+	}
+
+	if len(res) == 0 {
+		status.LastError = notConfiguredError
+		return status, nil
+	}
+	if res[0]["enabled"] != "true" {
+		status.LastError = "the subscription is disabled"
+		return status, nil
+	}
+
+	res, err = replica.query(`SELECT COALESCE(pid::text, '') AS pid, COALESCE(received_lsn::text, '') AS received_lsn,
+		COALESCE(latest_end_lsn::text, '') AS latest_end_lsn, COALESCE(EXTRACT(EPOCH FROM (now() - latest_end_time))::bigint, -1) AS lag
+		FROM pg_stat_subscription WHERE subname = $1 AND relid IS NULL`, r.config.Subscription)
+	if err != nil {
+		return status, err
+	}
+	parsePostgresReplicaStatus(status, res)
+	return status, nil
+}
+
+// parsePostgresReplicaStatus fills the status from the row of the apply worker in pg_stat_subscription
+func parsePostgresReplicaStatus(status *NodeStatus, rows []map[string]string) {
+	if len(rows) == 0 || rows[0]["pid"] == "" {
+		status.LastError = "the apply worker of the subscription is not running"
+		return
+	}
+
+	row := rows[0]
+	status.IsReplicating = true
+	status.ReceivedPosition = row["received_lsn"]
+	status.Position = row["latest_end_lsn"]
+	status.LagSeconds = -1
+	lag, err := strconv.ParseInt(row["lag"], 10, 64)
+	if err == nil {
+		status.LagSeconds = lag
+	}
+}
+
+func (r *postgresReplicator) startReplica(primary *Database, replica *Database) error {
+	// the database may have been fenced as a former primary, the changes are applied with the default setting
+	err := r.unfence(replica)
+	if err != nil {
+		return err
+	}
+
+	isSubscriptionFound, err := isPostgresRowFound(replica, "SELECT 1 FROM pg_subscription WHERE subname = $1", r.config.Subscription)
+	if err != nil {
+		return err
+	}
+	if isSubscriptionFound {
+		return replica.exec(fmt.Sprintf("ALTER SUBSCRIPTION %s ENABLE", r.getSubscription()))
+	}
+
+	connInfo := primary.getConnInfo(r.config.ReplicationUser, r.config.ReplicationPassword)
+	return replica.exec(fmt.Sprintf("CREATE SUBSCRIPTION %s CONNECTION %s PUBLICATION %s", r.getSubscription(),
+		quoteString(DriverPostgres, connInfo), r.getPublication()))
+}
+
+func (r *postgresReplicator) stopReplica(replica *Database) error {
+	return replica.exec(fmt.Sprintf("ALTER SUBSCRIPTION %s DISABLE", r.getSubscription()))
+}
+
+// fence makes the database read-only for the new sessions and closes the sessions of the clients, like Casdoor,
+// so that they reconnect read-only
+func (r *postgresReplicator) fence(primary *Database) error {
+	err := primary.exec(fmt.Sprintf("ALTER DATABASE %s SET default_transaction_read_only = on", quoteIdentifier(DriverPostgres, primary.config.Database)))
+	if err != nil {
+		return err
+	}
+
+	_, err = primary.query("SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE datname = current_database() AND pid <> pg_backend_pid() AND backend_type = 'client backend'")
+	return err
+}
+
+func (r *postgresReplicator) unfence(primary *Database) error {
+	return primary.exec(fmt.Sprintf("ALTER DATABASE %s RESET default_transaction_read_only", quoteIdentifier(DriverPostgres, primary.config.Database)))
+}
+
+func (r *postgresReplicator) waitForReplica(primary *Database, replica *Database, timeout time.Duration) error {
+	// without the primary, there is nothing more the replica can apply
+	if primary == nil {
+		return nil
+	}
+
+	res, err := primary.query("SELECT pg_current_wal_lsn()::text AS lsn")
+	if err != nil {
+		return err
+	}
+	lsn := res[0]["lsn"]
+
+	deadline := time.Now().Add(timeout)
+	for {
+		res, err = replica.query("SELECT 1 FROM pg_stat_subscription WHERE subname = $1 AND relid IS NULL AND latest_end_lsn >= $2::pg_lsn", r.config.Subscription, lsn)
+		if err != nil {
+			return err
+		}
+		if len(res) != 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s: the replica has not applied the LSN: %s after %d seconds", replica.getAddress(), lsn, int64(timeout.Seconds()))
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+func (r *postgresReplicator) promote(primary *Database, replica *Database) error {
+	err := replica.exec(fmt.Sprintf("ALTER SUBSCRIPTION %s DISABLE", r.getSubscription()))
+	if err != nil {
+		return err
+	}
+
+	// the replication slot is in the primary, which may be down, so it is detached before the subscription is dropped
+	err = replica.exec(fmt.Sprintf("ALTER SUBSCRIPTION %s SET (slot_name = NONE)", r.getSubscription()))
+	if err != nil {
+		return err
+	}
+	err = replica.exec(fmt.Sprintf("DROP SUBSCRIPTION %s", r.getSubscription()))
+	if err != nil {
+		return err
+	}
+	if primary != nil {
+		err = primary.exec("SELECT pg_drop_replication_slot(slot_name) FROM pg_replication_slots WHERE slot_name = $1", r.config.Subscription)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = r.resetSequences(replica)
+	if err != nil {
+		return err
+	}
+	return r.unfence(replica)
+}
+
+// resetSequences moves the sequences of the columns past their replicated values, the logical replication
+// does not replicate the sequences
+func (r *postgresReplicator) resetSequences(db *Database) error {
+	rows, err := db.query(`SELECT sn.nspname AS sequence_schema, s.relname AS sequence_name, tn.nspname AS table_schema, t.relname AS table_name, a.attname AS column_name
+		FROM pg_class s
+		JOIN pg_namespace sn ON sn.oid = s.relnamespace
+		JOIN pg_depend d ON d.objid = s.oid AND d.classid = 'pg_class'::regclass AND d.refclassid = 'pg_class'::regclass AND d.deptype IN ('a', 'i')
+		JOIN pg_class t ON t.oid = d.refobjid
+		JOIN pg_namespace tn ON tn.oid = t.relnamespace
+		JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = d.refobjsubid
+		WHERE s.relkind = 'S'`)
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		sequence := quoteIdentifier(DriverPostgres, row["sequence_schema"]) + "." + quoteIdentifier(DriverPostgres, row["sequence_name"])
+		table := quoteIdentifier(DriverPostgres, row["table_schema"]) + "." + quoteIdentifier(DriverPostgres, row["table_name"])
+		column := quoteIdentifier(DriverPostgres, row["column_name"])
+		_, err = db.query(fmt.Sprintf("SELECT setval($1::regclass, COALESCE(MAX(%s), 0) + 1, false) FROM %s", column, table), sequence)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,376 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sync_v2
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/beego/beego/logs"
+	"github.com/casdoor/casdoor/util"
+)
+
+const (
+	StateHealthy = "Healthy"
+	// StateDegraded is the state of a primary whose replica is late or down, the primary still serves
+	StateDegraded  = "Degraded"
+	StateUnhealthy = "Unhealthy"
+	// StateUnknown is the state before the first check of RunReplicationMonitor
+	StateUnknown = "Unknown"
+)
+
+// Status is the status of the replication seen from this Casdoor instance
+type Status struct {
+	Driver string `json:"driver"`
+	// Role is the current role of the database of this instance
+	Role        string      `json:"role"`
+	State       string      `json:"state"`
+	Message     string      `json:"message"`
+	LagSeconds  int64       `json:"lagSeconds"`
+	Primary     *NodeStatus `json:"primary"`
+	Replica     *NodeStatus `json:"replica"`
+	CheckedTime string      `json:"checkedTime"`
+}
+
+// Health is the part of the status reported by /api/health, without the addresses and errors of the databases
+type Health struct {
+	Role        string `json:"role"`
+	State       string `json:"state"`
+	LagSeconds  int64  `json:"lagSeconds"`
+	CheckedTime string `json:"checkedTime"`
+}
+
+type replication struct {
+	config     *Config
+	replicator replicator
+	// local is the database of this instance, primary and replica are swapped by the promotions
+	local   *Database
+	primary *Database
+	replica *Database
+
+	// mutex serializes the operations, the status is read without waiting for them
+	mutex       sync.Mutex
+	statusMutex sync.RWMutex
+	status      *Status
+}
+
+var currentReplication *replication
+
+// InitReplication sets up the replication from the "replicationConfig" config, which is a JSON Config.
+// The replication is disabled when the config is empty.
+func InitReplication(configText string) error {
+	if configText == "" {
+		return nil
+	}
+
+	config, err := parseConfig(configText)
+	if err != nil {
+		return err
+	}
+
+	primary, err := newDatabase(config.Driver, config.Primary)
+	if err != nil {
+		return err
+	}
+	replica, err := newDatabase(config.Driver, config.Replica)
+	if err != nil {
+		return err
+	}
+
+	r := &replication{
+		config:     config,
+		replicator: newReplicator(config),
+		primary:    primary,
+		replica:    replica,
+		local:      primary,
+	}
+	if config.Role == RoleReplica {
+		r.local = replica
+	}
+
+	currentReplication = r
+	return nil
+}
+
+func IsReplicationEnabled() bool {
+	return currentReplication != nil
+}
+
+func getReplication() (*replication, error) {
+	if currentReplication == nil {
+		return nil, fmt.Errorf("the replication is disabled, it is enabled by the replicationConfig config")
+	}
+	return currentReplication, nil
+}
+
+func (r *replication) getRole() string {
+	if r.local == r.primary {
+		return RolePrimary
+	}
+	return RoleReplica
+}
+
+// checkStatus gets the status of both databases, it is called with the mutex held
+func (r *replication) checkStatus() *Status {
+	primaryStatus, err := r.replicator.getPrimaryStatus(r.primary)
+	if err != nil {
+		primaryStatus.LastError = err.Error()
+	}
+	replicaStatus, err := r.replicator.getReplicaStatus(r.replica)
+	if err != nil {
+		replicaStatus.LastError = err.Error()
+	}
+
+	// the other instance of the pair may have promoted the replica: it is writable without replicating anything
+	// while the former primary is fenced
+	if primaryStatus.IsReachable && primaryStatus.IsReadOnly && replicaStatus.IsReachable && !replicaStatus.IsReadOnly && replicaStatus.LastError == notConfiguredError {
+		logs.Info("the replica: %s has been promoted, it is now the primary", r.replica.getAddress())
+		r.primary, r.replica = r.replica, r.primary
+		return r.checkStatus()
+	}
+
+	status := &Status{
+		Driver:      r.config.Driver,
+		Role:        r.getRole(),
+		Primary:     primaryStatus,
+		Replica:     replicaStatus,
+		CheckedTime: util.GetCurrentTime(),
+	}
+	evaluateStatus(status, r.config.MaxLagSeconds)
+	return status
+}
+
+// evaluateStatus sets the state of a status. The replica instance is unhealthy when it does not replicate
+// or is too late, while the primary instance only needs its database to be writable.
+func evaluateStatus(status *Status, maxLagSeconds int64) {
+	status.LagSeconds = status.Replica.LagSeconds
+	if status.Primary.LagSeconds >= 0 {
+		// the lag reported by the primary is more accurate when available, like in PostgreSQL
+		status.LagSeconds = status.Primary.LagSeconds
+	}
+
+	local := status.Primary
+	if status.Role == RoleReplica {
+		local = status.Replica
+	}
+	if !local.IsReachable {
+		status.State = StateUnhealthy
+		status.Message = fmt.Sprintf("the database of this instance is unreachable: %s", local.LastError)
+		return
+	}
+
+	if status.Role == RolePrimary {
+		switch {
+		case status.Primary.IsReadOnly:
+			status.State = StateUnhealthy
+			status.Message = "the primary is read-only"
+		case !status.Replica.IsReachable || !status.Replica.IsReplicating:
+			status.State = StateDegraded
+			status.Message = fmt.Sprintf("the replica does not replicate: %s", status.Replica.LastError)
+		case status.LagSeconds < 0 || status.LagSeconds > maxLagSeconds:
+			status.State = StateDegraded
+			status.Message = fmt.Sprintf("the replica lag: %d seconds exceeds %d seconds", status.LagSeconds, maxLagSeconds)
+		default:
+			status.State = StateHealthy
+		}
+		return
+	}
+
+	switch {
+	case !status.Replica.IsReplicating:
+		status.State = StateUnhealthy
+		status.Message = fmt.Sprintf("the replica does not replicate: %s", status.Replica.LastError)
+	case status.LagSeconds < 0 || status.LagSeconds > maxLagSeconds:
+		status.State = StateUnhealthy
+		status.Message = fmt.Sprintf("the replica lag: %d seconds exceeds %d seconds", status.LagSeconds, maxLagSeconds)
+	default:
+		status.State = StateHealthy
+	}
+}
+
+func (r *replication) refreshStatus() *Status {
+	r.mutex.Lock()
+	status := r.checkStatus()
+	r.mutex.Unlock()
+
+	r.statusMutex.Lock()
+	r.status = status
+	r.statusMutex.Unlock()
+	return status
+}
+
+func GetReplicationStatus() (*Status, error) {
+	r, err := getReplication()
+	if err != nil {
+		return nil, err
+	}
+
+	return r.refreshStatus(), nil
+}
+
+// GetReplicationHealth returns the last status checked by RunReplicationMonitor, nil when the replication is disabled.
+// It never checks the databases itself, so that /api/health answers at once even while a promotion is running.
+func GetReplicationHealth() *Health {
+	r := currentReplication
+	if r == nil {
+		return nil
+	}
+
+	r.statusMutex.RLock()
+	status := r.status
+	r.statusMutex.RUnlock()
+	if status == nil {
+		return &Health{Role: r.config.Role, State: StateUnknown, LagSeconds: -1}
+	}
+
+	return &Health{Role: status.Role, State: status.State, LagSeconds: status.LagSeconds, CheckedTime: status.CheckedTime}
+}
+
+// StartReplication creates the replication user in the primary and makes the replica replicate it
+func StartReplication() (*Status, error) {
+	r, err := getReplication()
+	if err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	err = r.replicator.setupPrimary(r.primary)
+	if err == nil {
+		err = r.replicator.startReplica(r.primary, r.replica)
+	}
+	r.mutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return r.refreshStatus(), nil
+}
+
+// StopReplication pauses the replication, the replica stays read-only until it is started again or promoted
+func StopReplication() (*Status, error) {
+	r, err := getReplication()
+	if err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	err = r.replicator.stopReplica(r.replica)
+	r.mutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return r.refreshStatus(), nil
+}
+
+// PromoteReplica makes the replica the primary. The primary is fenced first and the replica applies all the
+// changes before being made writable. Without force, the promotion is refused when the replica is too late or
+// does not catch up in time, with force it happens anyway and the latest changes may be lost.
+// The promotion is always refused when the primary is reachable but cannot be fenced. An unreachable primary
+// cannot be fenced either, so isPrimaryStopped confirms that it has been stopped or isolated by other means,
+// otherwise both databases could accept writes when it comes back.
+func PromoteReplica(force bool, isPrimaryStopped bool) (*Status, error) {
+	r, err := getReplication()
+	if err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	err = r.promote(force, isPrimaryStopped)
+	r.mutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return r.refreshStatus(), nil
+}
+
+func (r *replication) promote(force bool, isPrimaryStopped bool) error {
+	replicaStatus, err := r.replicator.getReplicaStatus(r.replica)
+	if err != nil {
+		return fmt.Errorf("the replica cannot be promoted: %s", err.Error())
+	}
+	if replicaStatus.LastError == notConfiguredError {
+		return fmt.Errorf("the replica cannot be promoted: %s", notConfiguredError)
+	}
+
+	primary := r.primary
+	primaryStatus, err := r.replicator.getPrimaryStatus(r.primary)
+	if err != nil || !primaryStatus.IsReachable {
+		if !isPrimaryStopped {
+			return fmt.Errorf("the replica cannot be promoted, the primary is unreachable and cannot be made read-only, stop or isolate it and confirm it")
+		}
+		primary = nil
+	}
+
+	if !force {
+		if primary != nil && !replicaStatus.IsReplicating {
+			return fmt.Errorf("the replica cannot be promoted, it does not replicate: %s", replicaStatus.LastError)
+		}
+		if replicaStatus.IsReplicating && replicaStatus.LagSeconds > r.config.MaxLagSeconds {
+			return fmt.Errorf("the replica cannot be promoted, its lag: %d seconds exceeds %d seconds", replicaStatus.LagSeconds, r.config.MaxLagSeconds)
+		}
+	}
+
+	// the primary stops accepting writes so that the replica can catch up with it and no write is lost after
+	// the promotion, even with force
+	if primary != nil {
+		err = r.replicator.fence(primary)
+		if err != nil {
+			return fmt.Errorf("the replica cannot be promoted, the primary cannot be made read-only: %s", err.Error())
+		}
+	}
+
+	err = r.replicator.waitForReplica(primary, r.replica, time.Duration(r.config.PromoteTimeoutSeconds)*time.Second)
+	if err != nil {
+		if !force {
+			if primary != nil {
+				unfenceErr := r.replicator.unfence(primary)
+				if unfenceErr != nil {
+					logs.Error("the primary cannot be made writable again: %s", unfenceErr.Error())
+				}
+			}
+			return fmt.Errorf("the replica cannot be promoted: %s", err.Error())
+		}
+		logs.Warning("the replica is promoted without catching up: %s", err.Error())
+	}
+
+	err = r.replicator.promote(primary, r.replica)
+	if err != nil {
+		return err
+	}
+
+	logs.Info("the replica: %s has been promoted, it is now the primary", r.replica.getAddress())
+	r.primary, r.replica = r.replica, r.primary
+	return nil
+}
+
+// RunReplicationMonitor checks the replication regularly for GetReplicationHealth
+func RunReplicationMonitor() {
+	r := currentReplication
+	if r == nil {
+		return
+	}
+
+	for {
+		status := r.refreshStatus()
+		if status.State != StateHealthy {
+			logs.Warning("replication %s: %s", status.State, status.Message)
+		}
+
+		time.Sleep(time.Duration(r.config.CheckIntervalSeconds) * time.Second)
+	}
+}
@@ -0,0 +1,272 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sync_v2
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+type testReplicator struct {
+	isPrimaryReachable bool
+	fenceError         error
+	isPromoted         bool
+}
+
+func (r *testReplicator) setupPrimary(primary *Database) error {
+	return nil
+}
+
+func (r *testReplicator) startReplica(primary *Database, replica *Database) error {
+	return nil
+}
+
+func (r *testReplicator) stopReplica(replica *Database) error {
+	return nil
+}
+
+func (r *testReplicator) getPrimaryStatus(primary *Database) (*NodeStatus, error) {
+	if !r.isPrimaryReachable {
+		return &NodeStatus{LagSeconds: -1}, fmt.Errorf("connection refused")
+	}
+	return &NodeStatus{IsReachable: true, LagSeconds: -1}, nil
+}
+
+func (r *testReplicator) getReplicaStatus(replica *Database) (*NodeStatus, error) {
+	return &NodeStatus{IsReachable: true, IsReadOnly: true, IsReplicating: r.isPrimaryReachable}, nil
+}
+
+func (r *testReplicator) fence(primary *Database) error {
+	return r.fenceError
+}
+
+func (r *testReplicator) unfence(primary *Database) error {
+	return nil
+}
+
+func (r *testReplicator) waitForReplica(primary *Database, replica *Database, timeout time.Duration) error {
+	return nil
+}
+
+func (r *testReplicator) promote(primary *Database, replica *Database) error {
+	r.isPromoted = true
+	return nil
+}
+
+func TestParseConfig(t *testing.T) {
+	config, err := parseConfig(`{"driver": "postgres", "role": "replica", "primary": {"host": "db1", "database": "casdoor"}, "replica": {"host": "db2", "database": "casdoor"}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Primary.Port != 5432 || config.Replica.SslMode != "disable" || config.ReplicationUser != "casdoor_replication" ||
+		config.Subscription != "casdoor_subscription" || config.MaxLagSeconds != 30 || config.CheckIntervalSeconds != 10 {
+		t.Fatalf("unexpected defaults: %v", config)
+	}
+
+	for _, configText := range []string{
+		`{"driver": "mssql", "role": "primary", "primary": {"host": "db1", "database": "casdoor"}, "replica": {"host": "db2", "database": "casdoor"}}`,
+		`{"driver": "mysql", "role": "master", "primary": {"host": "db1", "database": "casdoor"}, "replica": {"host": "db2", "database": "casdoor"}}`,
+		`{"driver": "mysql", "role": "primary", "primary": {"host": "db1", "database": "casdoor"}}`,
+		`{"driver": "mysql", "role": "primary", "primary": {"host": "db1", "database": "casdoor"}, "replica": {"host": "db2", "database": "casdoor"}, "replicationUser": "repl'@'%"}`,
+		`{"driver": "mysql"`,
+	} {
+		_, err = parseConfig(configText)
+		if err == nil {
+			t.Fatalf("the config should be invalid: %s", configText)
+		}
+	}
+}
+
+func TestMysqlStatements(t *testing.T) {
+	tests := []struct {
+		version string
+		status  string
+		posWait string
+	}{
+		{"5.7.44-log", "SHOW SLAVE STATUS", "MASTER_POS_WAIT"},
+		{"8.0.22", "SHOW SLAVE STATUS", "MASTER_POS_WAIT"},
+		{"8.0.23", "SHOW REPLICA STATUS", "MASTER_POS_WAIT"},
+		{"8.4.0", "SHOW REPLICA STATUS", "SOURCE_POS_WAIT"},
+		{"10.11.6-MariaDB-log", "SHOW SLAVE STATUS", "MASTER_POS_WAIT"},
+	}
+
+	for _, test := range tests {
+		statements := getMysqlStatements(parseMysqlVersion(test.version))
+		if statements.status != test.status || statements.posWait != test.posWait {
+			t.Fatalf("%s: unexpected statements: %s, %s", test.version, statements.status, statements.posWait)
+		}
+	}
+}
+
+func TestParseMysqlReplicaStatus(t *testing.T) {
+	status := &NodeStatus{}
+	parseMysqlReplicaStatus(status, map[string]string{
+		"Replica_IO_Running":    "Yes",
+		"Replica_SQL_Running":   "Yes",
+		"Source_Log_File":       "mysql-bin.000003",
+		"Read_Source_Log_Pos":   "1200",
+		"Relay_Source_Log_File": "mysql-bin.000003",
+		"Exec_Source_Log_Pos":   "1100",
+		"Seconds_Behind_Source": "2",
+		"Executed_Gtid_Set":     "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5,\n4e11fa47-71ca-11e1-9e33-c80aa9429562:1",
+	})
+	if !status.IsReplicating || status.LagSeconds != 2 || status.Position != "mysql-bin.000003:1100" || status.ReceivedPosition != "mysql-bin.000003:1200" ||
+		status.Gtid != "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5,4e11fa47-71ca-11e1-9e33-c80aa9429562:1" || status.LastError != "" {
+		t.Fatalf("unexpected status: %v", status)
+	}
+
+	// the lag is NULL, an empty string, when the replication is stopped
+	status = &NodeStatus{}
+	parseMysqlReplicaStatus(status, map[string]string{
+		"Slave_IO_Running":      "Connecting",
+		"Slave_SQL_Running":     "Yes",
+		"Seconds_Behind_Master": "",
+		"Last_IO_Error":         "error connecting to master",
+	})
+	if status.IsReplicating || status.LagSeconds != -1 || status.LastError != "error connecting to master" {
+		t.Fatalf("unexpected status: %v", status)
+	}
+}
+
+func TestParsePostgresReplicaStatus(t *testing.T) {
+	status := &NodeStatus{}
+	parsePostgresReplicaStatus(status, []map[string]string{{"pid": "4242", "received_lsn": "0/3000148", "latest_end_lsn": "0/3000110", "lag": "1"}})
+	if !status.IsReplicating || status.LagSeconds != 1 || status.Position != "0/3000110" || status.ReceivedPosition != "0/3000148" {
+		t.Fatalf("unexpected status: %v", status)
+	}
+
+	status = &NodeStatus{LagSeconds: -1}
+	parsePostgresReplicaStatus(status, []map[string]string{{"pid": "", "lag": "-1"}})
+	if status.IsReplicating || status.LagSeconds != -1 || status.LastError == "" {
+		t.Fatalf("unexpected status: %v", status)
+	}
+}
+
+func TestEvaluateStatus(t *testing.T) {
+	replicating := &NodeStatus{IsReachable: true, IsReadOnly: true, IsReplicating: true, LagSeconds: 3}
+	late := &NodeStatus{IsReachable: true, IsReadOnly: true, IsReplicating: true, LagSeconds: 120}
+	stopped := &NodeStatus{IsReachable: true, IsReadOnly: true, LagSeconds: -1, LastError: "the replication is stopped"}
+	writable := &NodeStatus{IsReachable: true, LagSeconds: -1}
+	fenced := &NodeStatus{IsReachable: true, IsReadOnly: true, LagSeconds: -1}
+	down := &NodeStatus{LagSeconds: -1, LastError: "connection refused"}
+
+	tests := []struct {
+		name    string
+		role    string
+		primary *NodeStatus
+		replica *NodeStatus
+		state   string
+		lag     int64
+	}{
+		{"healthy primary", RolePrimary, writable, replicating, StateHealthy, 3},
+		{"primary with a late replica", RolePrimary, writable, late, StateDegraded, 120},
+		{"primary with a replica down", RolePrimary, writable, down, StateDegraded, -1},
+		{"fenced primary", RolePrimary, fenced, replicating, StateUnhealthy, 3},
+		{"primary down", RolePrimary, down, replicating, StateUnhealthy, 3},
+		{"healthy replica", RoleReplica, writable, replicating, StateHealthy, 3},
+		{"healthy replica without its primary", RoleReplica, down, replicating, StateHealthy, 3},
+		{"late replica", RoleReplica, writable, late, StateUnhealthy, 120},
+		{"stopped replica", RoleReplica, writable, stopped, StateUnhealthy, -1},
+		{"replica down", RoleReplica, writable, down, StateUnhealthy, -1},
+		{"lag reported by the primary", RoleReplica, &NodeStatus{IsReachable: true, LagSeconds: 0}, late, StateHealthy, 0},
+	}
+
+	for _, test := range tests {
+		status := &Status{Role: test.role, Primary: test.primary, Replica: test.replica}
+		evaluateStatus(status, 30)
+		if status.State != test.state || status.LagSeconds != test.lag {
+			t.Fatalf("%s: got %s with a lag of %d, expected %s with a lag of %d: %s", test.name, status.State, status.LagSeconds, test.state, test.lag, status.Message)
+		}
+	}
+}
+
+func TestQuote(t *testing.T) {
+	if res := quoteString(DriverMysql, `it's \'`); res != `'it''s \\'''` {
+		t.Fatalf("unexpected MySQL string: %s", res)
+	}
+	if res := quoteString(DriverPostgres, `it's \'`); res != `'it''s \'''` {
+		t.Fatalf("unexpected PostgreSQL string: %s", res)
+	}
+	if res := quoteIdentifier(DriverPostgres, `my "db"`); res != `"my ""db"""` {
+		t.Fatalf("unexpected PostgreSQL identifier: %s", res)
+	}
+
+	db := &Database{driver: DriverPostgres, config: &NodeConfig{Host: "db1", Port: 5432, Database: "casdoor", SslMode: "disable"}}
+	expected := `host='db1' port=5432 user='repl' password='p a\'s\\s' dbname='casdoor' sslmode='disable' connect_timeout=5`
+	if res := db.getConnInfo("repl", `p a's\s`); res != expected {
+		t.Fatalf("unexpected connection string: %s", res)
+	}
+}
+
+func TestPromote(t *testing.T) {
+	config, err := parseConfig(`{"driver": "mysql", "role": "replica", "primary": {"host": "db1", "database": "casdoor"}, "replica": {"host": "db2", "database": "casdoor"}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		replicator       *testReplicator
+		isPrimaryStopped bool
+		isPromoted       bool
+	}{
+		// an unreachable primary cannot be fenced, it must be confirmed stopped even with force
+		{&testReplicator{}, false, false},
+		{&testReplicator{}, true, true},
+		// a reachable primary which cannot be fenced is never left writable
+		{&testReplicator{isPrimaryReachable: true, fenceError: fmt.Errorf("access denied")}, true, false},
+		{&testReplicator{isPrimaryReachable: true}, false, true},
+	}
+
+	for i, test := range tests {
+		primary, replica := &Database{config: config.Primary}, &Database{config: config.Replica}
+		r := &replication{config: config, replicator: test.replicator, primary: primary, replica: replica, local: replica}
+
+		err = r.promote(true, test.isPrimaryStopped)
+		if test.replicator.isPromoted != test.isPromoted || (err == nil) != test.isPromoted {
+			t.Fatalf("%d: unexpected promotion: %v, %v", i, test.replicator.isPromoted, err)
+		}
+		if test.isPromoted && (r.primary != replica || r.getRole() != RolePrimary) {
+			t.Fatalf("%d: the replica should be the primary", i)
+		}
+	}
+}
+
+func TestGetReplicationHealth(t *testing.T) {
+	config, err := parseConfig(`{"driver": "mysql", "role": "replica", "primary": {"host": "db1", "database": "casdoor"}, "replica": {"host": "db2", "database": "casdoor"}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	currentReplication = &replication{config: config}
+	defer func() {
+		currentReplication = nil
+	}()
+
+	// the health does not wait for the operations holding the mutex
+	currentReplication.mutex.Lock()
+	defer currentReplication.mutex.Unlock()
+
+	health := GetReplicationHealth()
+	if health.Role != RoleReplica || health.State != StateUnknown {
+		t.Fatalf("unexpected health: %v", health)
+	}
+
+	currentReplication.status = &Status{Role: RoleReplica, State: StateHealthy, LagSeconds: 1, CheckedTime: "now"}
+	health = GetReplicationHealth()
+	if health.State != StateHealthy || health.LagSeconds != 1 || health.CheckedTime != "now" {
+		t.Fatalf("unexpected health: %v", health)
+	}
+}
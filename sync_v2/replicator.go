@@ -0,0 +1,70 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sync_v2
+
+import "time"
+
+// notConfiguredError is the error of a replica status when the database does not replicate anything,
+// like a fresh database or a promoted replica
+const notConfiguredError = "the replication is not configured"
+
+// NodeStatus is the replication status of a database of the pair
+type NodeStatus struct {
+	Address     string `json:"address"`
+	Role        string `json:"role"`
+	IsReachable bool   `json:"isReachable"`
+	IsReadOnly  bool   `json:"isReadOnly"`
+	// Position is the binlog "file:position" in MySQL and the WAL LSN in PostgreSQL. For the primary it is the
+	// current position, for the replica the position of the primary applied by the replica.
+	Position string `json:"position"`
+	// ReceivedPosition is the position of the primary received by the replica
+	ReceivedPosition string `json:"receivedPosition"`
+	// Gtid is the executed GTID set, only for MySQL with GTID enabled
+	Gtid string `json:"gtid"`
+
+	// IsReplicating tells whether the replica receives and applies the changes of the primary
+	IsReplicating bool `json:"isReplicating"`
+	// LagSeconds is -1 when unknown
+	LagSeconds int64  `json:"lagSeconds"`
+	LastError  string `json:"lastError"`
+}
+
+// replicator runs the replication of a database type, the primary and the replica are always passed
+// in their current roles
+type replicator interface {
+	// setupPrimary creates the replication user and what the replica subscribes to in the primary
+	setupPrimary(primary *Database) error
+	startReplica(primary *Database, replica *Database) error
+	stopReplica(replica *Database) error
+
+	getPrimaryStatus(primary *Database) (*NodeStatus, error)
+	getReplicaStatus(replica *Database) (*NodeStatus, error)
+
+	// fence makes the primary read-only before a promotion, unfence reverts it when the promotion fails
+	fence(primary *Database) error
+	unfence(primary *Database) error
+	// waitForReplica waits until the replica applied all the changes of the primary, or of its relay logs
+	// when the primary is nil
+	waitForReplica(primary *Database, replica *Database, timeout time.Duration) error
+	// promote stops the replication and makes the replica writable
+	promote(primary *Database, replica *Database) error
+}
+
+func newReplicator(config *Config) replicator {
+	if config.Driver == DriverPostgres {
+		return &postgresReplicator{config: config}
+	}
+	return &mysqlReplicator{config: config}
+}
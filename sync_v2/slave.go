@@ -14,71 +14,265 @@
 
 package sync_v2
 
-import "log"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
 
-// slaveStatus shows slave status
-func slaveStatus(slavedb *Database) {
-	res := slavedb.exec("show slave status")
+type mysqlStatements struct {
+	change string
+	// option is the prefix of the options of change, like "MASTER_" in "MASTER_HOST"
+	option  string
+	start   string
+	stop    string
+	reset   string
+	status  string
+	posWait string
+}
+
+func getMysqlStatements(version *mysqlVersion) *mysqlStatements {
+	res := &mysqlStatements{
+		change:  "CHANGE MASTER TO",
+		option:  "MASTER_",
+		start:   "START SLAVE",
+		stop:    "STOP SLAVE",
+		reset:   "RESET SLAVE ALL",
+		status:  "SHOW SLAVE STATUS",
+		posWait: "MASTER_POS_WAIT",
+	}
+	if version.isSourceSyntax() {
+		res.change = "CHANGE REPLICATION SOURCE TO"
+		res.option = "SOURCE_"
+		res.start = "START REPLICA"
+		res.stop = "STOP REPLICA"
+		res.reset = "RESET REPLICA ALL"
+		res.status = "SHOW REPLICA STATUS"
+	}
+	if version.isAtLeast(8, 0, 26) {
+		res.posWait = "SOURCE_POS_WAIT"
+	}
+	return res
+}
+
+func getReplicaStatements(replica *Database) (*mysqlStatements, error) {
+	version, err := getMysqlVersion(replica)
+	if err != nil {
+		return nil, err
+	}
+	return getMysqlStatements(version), nil
+}
+
+// getReplicaRow returns the replica status of a database, nil when the replication is not configured
+func getReplicaRow(replica *Database, statements *mysqlStatements) (map[string]string, error) {
+	res, err := replica.query(statements.status)
+	if err != nil {
+		return nil, err
+	}
 	if len(res) == 0 {
-		log.Printf("no slave status for slave [%v:%v]\n", slavedb.host, slavedb.port)
-		return
-	}
-	log.Println("*****check slave status*****")
-	log.Println("slave:", slavedb.host, ":", slavedb.port)
-	masterServerId := res[0]["Master_Server_Id"]
-	log.Println("master server id:", masterServerId)
-	lastError := res[0]["Last_Error"]
-	log.Println("last error:", lastError) // this should be empty
-	lastIoError := res[0]["Last_IO_Error"]
-	log.Println("last io error:", lastIoError) // this should be empty
-	slaveIoState := res[0]["Slave_IO_State"]
-	log.Println("slave io state:", slaveIoState)
-	slaveIoRunning := res[0]["Slave_IO_Running"]
-	log.Println("slave io running:", slaveIoRunning) // this should be Yes
-	slaveSqlRunning := res[0]["Slave_SQL_Running"]
-	log.Println("slave sql running:", slaveSqlRunning) // this should be Yes
-	slaveSqlRunningState := res[0]["Slave_SQL_Running_State"]
-	log.Println("slave sql running state:", slaveSqlRunningState)
-	slaveSecondsBehindMaster := res[0]["Seconds_Behind_Master"]
-	log.Println("seconds behind master:", slaveSecondsBehindMaster) // this should be 0, if not, it means the slave is behind the master
-	log.Println("slave status:", res)
-	log.Println("****************************")
+		return nil, nil
+	}
+	return res[0], nil
 }
 
-// stopSlave stops slave
-func stopSlave(slavedb *Database) {
-	defer func() {
-		if err := recover(); err != nil {
-			log.Fatalln(err)
+// parseMysqlReplicaStatus fills the status from a row of "SHOW REPLICA STATUS", or of "SHOW SLAVE STATUS"
+// in the versions before MySQL 8.0.22
+func parseMysqlReplicaStatus(status *NodeStatus, row map[string]string) {
+	ioRunning := getMysqlColumn(row, "Replica_IO_Running", "Slave_IO_Running")
+	sqlRunning := getMysqlColumn(row, "Replica_SQL_Running", "Slave_SQL_Running")
+	status.IsReplicating = ioRunning == "Yes" && sqlRunning == "Yes"
+
+	status.Position = fmt.Sprintf("%s:%s", getMysqlColumn(row, "Relay_Source_Log_File", "Relay_Master_Log_File"),
+		getMysqlColumn(row, "Exec_Source_Log_Pos", "Exec_Master_Log_Pos"))
+	status.ReceivedPosition = fmt.Sprintf("%s:%s", getMysqlColumn(row, "Source_Log_File", "Master_Log_File"),
+		getMysqlColumn(row, "Read_Source_Log_Pos", "Read_Master_Log_Pos"))
+	status.Gtid = strings.ReplaceAll(row["Executed_Gtid_Set"], "\n", "")
+
+	// the lag is NULL when the replication is stopped
+	status.LagSeconds = -1
+	lag, err := strconv.ParseInt(getMysqlColumn(row, "Seconds_Behind_Source", "Seconds_Behind_Master"), 10, 64)
+	if err == nil && status.IsReplicating {
+		status.LagSeconds = lag
+	}
+
+	for _, lastError := range []string{row["Last_IO_Error"], row["Last_SQL_Error"], row["Last_Error"]} {
+		if lastError != "" {
+			status.LastError = lastError
+			break
+		}
+	}
+	if status.LastError == "" && !status.IsReplicating {
+		status.LastError = fmt.Sprintf("the replication is stopped, IO thread: %s, SQL thread: %s", ioRunning, sqlRunning)
+	}
+}
+
+func (r *mysqlReplicator) getReplicaStatus(replica *Database) (*NodeStatus, error) {
+	status := &NodeStatus{Address: replica.getAddress(), Role: RoleReplica, LagSeconds: -1}
+	statements, err := getReplicaStatements(replica)
+	if err != nil {
+		return status, err
+	}
+
+	row, err := getReplicaRow(replica, statements)
+	if err != nil {
+		return status, err
+	}
+
+	status.IsReachable = true
+	variables, err := getMysqlVariables(replica)
+	if err != nil {
+		return status, err
+	}
+	status.IsReadOnly = variables["read_only"] == "ON"
+
+	if row == nil {
+		status.LastError = notConfiguredError
+		return status, nil
+	}
+
+	parseMysqlReplicaStatus(status, row)
+	return status, nil
+}
+
+func (r *mysqlReplicator) startReplica(primary *Database, replica *Database) error {
+	primaryVariables, err := getMysqlVariables(primary)
+	if err != nil {
+		return err
+	}
+	replicaVariables, err := getMysqlVariables(replica)
+	if err != nil {
+		return err
+	}
+	if primaryVariables["server_id"] == replicaVariables["server_id"] {
+		return fmt.Errorf("the primary and the replica have the same server_id: %s, it should be different in my.cnf", primaryVariables["server_id"])
+	}
+
+	version, err := getMysqlVersion(replica)
+	if err != nil {
+		return err
+	}
+	statements := getMysqlStatements(version)
+
+	err = replica.exec(statements.stop)
+	if err != nil {
+		return err
+	}
+
+	o := statements.option
+	options := []string{
+		fmt.Sprintf("%sHOST = %s", o, quoteString(DriverMysql, primary.config.Host)),
+		fmt.Sprintf("%sPORT = %d", o, primary.config.Port),
+		fmt.Sprintf("%sUSER = %s", o, quoteString(DriverMysql, r.config.ReplicationUser)),
+		fmt.Sprintf("%sPASSWORD = %s", o, quoteString(DriverMysql, r.config.ReplicationPassword)),
+	}
+	if primaryVariables["gtid_mode"] == "ON" && replicaVariables["gtid_mode"] == "ON" {
+		options = append(options, fmt.Sprintf("%sAUTO_POSITION = 1", o))
+	} else {
+		file, position, _, err := getBinlogStatus(primary)
+		if err != nil {
+			return err
 		}
-	}()
-	slavedb.exec("stop slave")
-	slaveStatus(slavedb)
+		options = append(options, fmt.Sprintf("%sLOG_FILE = %s", o, quoteString(DriverMysql, file)), fmt.Sprintf("%sLOG_POS = %s", o, position))
+	}
+	if version.isAtLeast(8, 0, 0) {
+		// the replication user has the caching_sha2_password plugin by default, it needs the RSA key without TLS
+		options = append(options, fmt.Sprintf("GET_%sPUBLIC_KEY = 1", o))
+	}
+
+	err = replica.exec(fmt.Sprintf("%s %s", statements.change, strings.Join(options, ", ")))
+	if err != nil {
+		return err
+	}
+	err = replica.exec(statements.start)
+	if err != nil {
+		return err
+	}
+
+	// the passive Casdoor instance must not write to the replica
+	return r.fence(replica)
+}
+
+func (r *mysqlReplicator) stopReplica(replica *Database) error {
+	statements, err := getReplicaStatements(replica)
+	if err != nil {
+		return err
+	}
+	return replica.exec(statements.stop)
 }
 
-// startSlave starts slave
-func startSlave(masterdb *Database, slavedb *Database) {
-	res := make([]map[string]string, 0)
-	defer func() {
-		if err := recover(); err != nil {
-			log.Fatalln(err)
+func (r *mysqlReplicator) waitForReplica(primary *Database, replica *Database, timeout time.Duration) error {
+	statements, err := getReplicaStatements(replica)
+	if err != nil {
+		return err
+	}
+
+	// without the primary, the replica applies what it already received
+	var file, position, gtid string
+	if primary != nil {
+		file, position, gtid, err = getBinlogStatus(primary)
+		if err != nil {
+			return err
 		}
-	}()
-	stopSlave(slavedb)
-	// get the info about master
-	res = masterdb.exec("show master status")
-	if len(res) == 0 {
-		log.Println("no master status")
-		return
-	}
-	pos := res[0]["Position"]
-	file := res[0]["File"]
-	log.Println("file:", file, ", position:", pos, ", master status:", res)
-	res = slavedb.exec("stop slave")
-	res = slavedb.exec(
-		"change master to master_host='%v', master_port=%v, master_user='%v', master_password='%v', master_log_file='%v', master_log_pos=%v;",
-		masterdb.host, masterdb.port, masterdb.slaveUser, masterdb.slavePassword, file, pos,
-	)
-	res = slavedb.exec("start slave")
-	slaveStatus(slavedb)
+	} else {
+		row, err := getReplicaRow(replica, statements)
+		if err != nil {
+			return err
+		}
+		if row == nil {
+			return fmt.Errorf("%s: %s", replica.getAddress(), notConfiguredError)
+		}
+		file = getMysqlColumn(row, "Source_Log_File", "Master_Log_File")
+		position = getMysqlColumn(row, "Read_Source_Log_Pos", "Read_Master_Log_Pos")
+		gtid = strings.ReplaceAll(row["Retrieved_Gtid_Set"], "\n", "")
+	}
+
+	seconds := int64(timeout.Seconds())
+	isGtidEnabled, err := isMysqlGtidEnabled(replica)
+	if err != nil {
+		return err
+	}
+	if isGtidEnabled && gtid != "" {
+		res, err := replica.query("SELECT WAIT_FOR_EXECUTED_GTID_SET(?, ?) AS res", gtid, seconds)
+		if err != nil {
+			return err
+		}
+		if res[0]["res"] != "0" {
+			return fmt.Errorf("%s: the replica has not applied the GTID set: %s after %d seconds", replica.getAddress(), gtid, seconds)
+		}
+		return nil
+	}
+
+	pos, err := strconv.ParseInt(position, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%s: invalid binlog position: %s", replica.getAddress(), position)
+	}
+	res, err := replica.query(fmt.Sprintf("SELECT %s(?, ?, ?) AS res", statements.posWait), file, pos, seconds)
+	if err != nil {
+		return err
+	}
+	switch res[0]["res"] {
+	case "":
+		return fmt.Errorf("%s: the replication is stopped", replica.getAddress())
+	case "-1":
+		return fmt.Errorf("%s: the replica has not applied the position: %s:%s after %d seconds", replica.getAddress(), file, position, seconds)
+	}
+	return nil
+}
+
+func (r *mysqlReplicator) promote(primary *Database, replica *Database) error {
+	statements, err := getReplicaStatements(replica)
+	if err != nil {
+		return err
+	}
+
+	err = replica.exec(statements.stop)
+	if err != nil {
+		return err
+	}
+	err = replica.exec(statements.reset)
+	if err != nil {
+		return err
+	}
+	return r.unfence(replica)
 }
@@ -18,7 +18,6 @@
 package sync_v2
 
 import (
-	"log"
 	"math/rand"
 	"testing"
 
@@ -34,33 +33,42 @@ type TestUser struct {
 }
 
 func TestCreateUserTable(t *testing.T) {
-	db := newDatabase(&Configs[0])
-	err := db.engine.Sync2(new(TestUser))
+	db, err := newDatabase(DriverMysql, testConfig.Primary)
 	if err != nil {
-		log.Fatalln(err)
+		t.Fatal(err)
+	}
+	err = db.engine.Sync2(new(TestUser))
+	if err != nil {
+		t.Fatal(err)
 	}
 }
 
 func TestInsertUser(t *testing.T) {
-	db := newDatabase(&Configs[0])
+	db, err := newDatabase(DriverMysql, testConfig.Primary)
+	if err != nil {
+		t.Fatal(err)
+	}
 	// random generate user
 	user := &TestUser{
 		Username: util.GetRandomName(),
 		Age:      rand.Intn(100) + 10,
 	}
-	_, err := db.engine.Insert(user)
+	_, err = db.engine.Insert(user)
 	if err != nil {
-		log.Fatalln(err)
+		t.Fatal(err)
 	}
 }
 
 func TestDeleteUser(t *testing.T) {
-	db := newDatabase(&Configs[0])
+	db, err := newDatabase(DriverMysql, testConfig.Primary)
+	if err != nil {
+		t.Fatal(err)
+	}
 	user := &TestUser{
 		Id: 10,
 	}
-	_, err := db.engine.Delete(user)
+	_, err = db.engine.Delete(user)
 	if err != nil {
-		log.Fatalln(err)
+		t.Fatal(err)
 	}
 }
@@ -0,0 +1,482 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+import React from "react";
+import {Button, Col, Input, InputNumber, Modal, Row, Select, Table, Tag} from "antd";
+import * as Setting from "./Setting";
+import * as AccessRequestBackend from "./backend/AccessRequestBackend";
+import * as GroupBackend from "./backend/GroupBackend";
+import * as RoleBackend from "./backend/RoleBackend";
+import * as PermissionBackend from "./backend/PermissionBackend";
+import i18next from "i18next";
+import BaseListPage from "./BaseListPage";
+import PopconfirmModal from "./common/modal/PopconfirmModal";
+
+class AccessRequestListPage extends BaseListPage {
+  constructor(props) {
+    super(props);
+    this.state = {
+      ...this.state,
+      submitVisible: false,
+      request: this.newAccessRequest(),
+      targets: [],
+    };
+  }
+
+  newAccessRequest() {
+    return {
+      targetType: "Role",
+      target: "",
+      reason: "",
+      duration: 0,
+    };
+  }
+
+  getAccountId() {
+    return `${this.props.account.owner}/${this.props.account.name}`;
+  }
+
+  getTargets(targetType) {
+    const owner = this.props.account.owner;
+    const getTargets = {
+      "Role": () => RoleBackend.getRoles(owner),
+      "Group": () => GroupBackend.getGroups(owner),
+      "Permission": () => PermissionBackend.getPermissions(owner),
+    }[targetType];
+
+    getTargets()
+      .then((res) => {
+        if (res.status === "ok") {
+          this.setState({targets: res.data});
+        } else {
+          this.setState({targets: []});
+        }
+      });
+  }
+
+  updateAccessRequestField(key, value) {
+    const request = this.state.request;
+    request[key] = value;
+    this.setState({
+      request: request,
+    });
+  }
+
+  openSubmitModal() {
+    this.setState({
+      submitVisible: true,
+      request: this.newAccessRequest(),
+    });
+    this.getTargets("Role");
+  }
+
+  submitAccessRequest() {
+    AccessRequestBackend.submitAccessRequest(this.state.request)
+      .then((res) => {
+        if (res.status === "ok") {
+          Setting.showMessage("success", i18next.t("accessRequest:Successfully submitted"));
+          this.setState({submitVisible: false});
+          this.fetch({pagination: this.state.pagination});
+        } else {
+          Setting.showMessage("error", `${i18next.t("accessRequest:Failed to submit")}: ${res.msg}`);
+        }
+      })
+      .catch(error => {
+        Setting.showMessage("error", `${i18next.t("general:Failed to connect to server")}: ${error}`);
+      });
+  }
+
+  reviewAccessRequest(record, action) {
+    AccessRequestBackend.reviewAccessRequest(record.owner, record.name, action)
+      .then((res) => {
+        if (res.status === "ok") {
+          Setting.showMessage("success", i18next.t("general:Successfully saved"));
+          this.fetch({pagination: this.state.pagination});
+        } else {
+          Setting.showMessage("error", `${i18next.t("general:Failed to save")}: ${res.msg}`);
+        }
+      })
+      .catch(error => {
+        Setting.showMessage("error", `${i18next.t("general:Failed to connect to server")}: ${error}`);
+      });
+  }
+
+  cancelAccessRequest(record) {
+    AccessRequestBackend.cancelAccessRequest(record.owner, record.name)
+      .then((res) => {
+        if (res.status === "ok") {
+          Setting.showMessage("success", i18next.t("general:Successfully saved"));
+          this.fetch({pagination: this.state.pagination});
+        } else {
+          Setting.showMessage("error", `${i18next.t("general:Failed to save")}: ${res.msg}`);
+        }
+      })
+      .catch(error => {
+        Setting.showMessage("error", `${i18next.t("general:Failed to connect to server")}: ${error}`);
+      });
+  }
+
+  deleteAccessRequest(i) {
+    AccessRequestBackend.deleteAccessRequest(this.state.data[i])
+      .then((res) => {
+        if (res.status === "ok") {
+          Setting.showMessage("success", i18next.t("general:Successfully deleted"));
+          this.fetch({
+            pagination: {
+              ...this.state.pagination,
+              current: this.state.pagination.current > 1 && this.state.data.length === 1 ? this.state.pagination.current - 1 : this.state.pagination.current,
+            },
+          });
+        } else {
+          Setting.showMessage("error", `${i18next.t("general:Failed to delete")}: ${res.msg}`);
+        }
+      })
+      .catch(error => {
+        Setting.showMessage("error", `${i18next.t("general:Failed to connect to server")}: ${error}`);
+      });
+  }
+
+  getStateTag(state) {
+    const color = {
+      "Pending": "processing",
+      "Approved": "success",
+      "Denied": "error",
+      "Canceled": "default",
+    }[state];
+    return <Tag color={color}>{i18next.t(`accessRequest:${state}`)}</Tag>;
+  }
+
+  renderHistory(record) {
+    const columns = [
+      {
+        title: i18next.t("general:Time"),
+        dataIndex: "time",
+        key: "time",
+        width: "160px",
+        render: (text, record, index) => {
+          return Setting.getFormattedDate(text);
+        },
+      },
+      {
+        title: i18next.t("accessRequest:Step"),
+        dataIndex: "step",
+        key: "step",
+        width: "80px",
+        render: (text, event, index) => {
+          return `${text + 1} - ${record.steps?.[text]?.name ?? ""}`;
+        },
+      },
+      {
+        title: i18next.t("general:User"),
+        dataIndex: "user",
+        key: "user",
+        width: "200px",
+      },
+      {
+        title: i18next.t("general:Action"),
+        dataIndex: "action",
+        key: "action",
+        width: "120px",
+      },
+      {
+        title: i18next.t("accessRequest:Comment"),
+        dataIndex: "comment",
+        key: "comment",
+      },
+    ];
+
+    return (
+      <Table columns={columns} dataSource={record.history ?? []} rowKey={(event) => `${event.time}/${event.action}/${event.user}`} size="small" bordered pagination={false} />
+    );
+  }
+
+  renderSubmitModal() {
+    return (
+      <Modal
+        title={i18next.t("accessRequest:Request access")}
+        open={this.state.submitVisible}
+        okText={i18next.t("accessRequest:Submit")}
+        cancelText={i18next.t("general:Cancel")}
+        onOk={() => this.submitAccessRequest()}
+        onCancel={() => this.setState({submitVisible: false})}
+      >
+        <Row style={{marginTop: "10px"}} >
+          <Col span={6}>
+            {i18next.t("accessWorkflow:Target type")} :
+          </Col>
+          <Col span={18} >
+            <Select virtual={false} style={{width: "100%"}} value={this.state.request.targetType} onChange={(value => {
+              this.updateAccessRequestField("targetType", value);
+              this.updateAccessRequestField("target", "");
+              this.updateAccessRequestField("duration", 0);
+              this.getTargets(value);
+            })}
+            options={[
+              {value: "Role", label: i18next.t("general:Roles")},
+              {value: "Group", label: i18next.t("general:Groups")},
+              {value: "Permission", label: i18next.t("general:Permissions")},
+            ]} />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col span={6}>
+            {i18next.t("accessRequest:Target")} :
+          </Col>
+          <Col span={18} >
+            <Select virtual={false} showSearch style={{width: "100%"}} value={this.state.request.target} onChange={(value => {
+              this.updateAccessRequestField("target", value);
+            })}
+            options={this.state.targets.map((target) => Setting.getOption(`${target.owner}/${target.name}`, `${target.owner}/${target.name}`))}
+            />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col span={6}>
+            {i18next.t("accessRequest:Reason")} :
+          </Col>
+          <Col span={18} >
+            <Input.TextArea rows={3} maxLength={500} value={this.state.request.reason} onChange={e => {
+              this.updateAccessRequestField("reason", e.target.value);
+            }} />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col span={6}>
+            {Setting.getLabel(i18next.t("accessRequest:Duration (minutes)"), i18next.t("accessRequest:Duration (minutes) - Tooltip"))} :
+          </Col>
+          <Col span={18} >
+            <InputNumber min={0} disabled={this.state.request.targetType === "Group"} value={this.state.request.duration} onChange={value => {
+              this.updateAccessRequestField("duration", value ?? 0);
+            }} />
+          </Col>
+        </Row>
+      </Modal>
+    );
+  }
+
+  renderTable(requests) {
+    const columns = [
+      {
+        title: i18next.t("general:Name"),
+        dataIndex: "name",
+        key: "name",
+        width: "150px",
+        fixed: "left",
+        sorter: true,
+        ...this.getColumnSearchProps("name"),
+      },
+      {
+        title: i18next.t("general:Created time"),
+        dataIndex: "createdTime",
+        key: "createdTime",
+        width: "160px",
+        sorter: true,
+        render: (text, record, index) => {
+          return Setting.getFormattedDate(text);
+        },
+      },
+      {
+        title: i18next.t("general:User"),
+        dataIndex: "user",
+        key: "user",
+        width: "150px",
+        sorter: true,
+        ...this.getColumnSearchProps("user"),
+      },
+      {
+        title: i18next.t("accessWorkflow:Target type"),
+        dataIndex: "targetType",
+        key: "targetType",
+        width: "120px",
+        sorter: true,
+        ...this.getColumnSearchProps("targetType"),
+      },
+      {
+        title: i18next.t("accessRequest:Target"),
+        dataIndex: "target",
+        key: "target",
+        width: "200px",
+        sorter: true,
+        ...this.getColumnSearchProps("target"),
+      },
+      {
+        title: i18next.t("accessRequest:Reason"),
+        dataIndex: "reason",
+        key: "reason",
+        width: "200px",
+      },
+      {
+        title: i18next.t("accessRequest:Step"),
+        dataIndex: "currentStep",
+        key: "currentStep",
+        width: "150px",
+        render: (text, record, index) => {
+          return `${text + 1} / ${record.steps?.length ?? 0} - ${record.steps?.[text]?.name ?? ""}`;
+        },
+      },
+      {
+        title: i18next.t("accessWorkflow:Approvers"),
+        dataIndex: "approvers",
+        key: "approvers",
+        render: (text, record, index) => {
+          return Setting.getTags(text ?? []);
+        },
+      },
+      {
+        title: i18next.t("accessRequest:Deadline"),
+        dataIndex: "deadline",
+        key: "deadline",
+        width: "160px",
+        render: (text, record, index) => {
+          return text ? Setting.getFormattedDate(text) : null;
+        },
+      },
+      {
+        title: i18next.t("general:State"),
+        dataIndex: "state",
+        key: "state",
+        width: "100px",
+        sorter: true,
+        ...this.getColumnSearchProps("state"),
+        render: (text, record, index) => {
+          return this.getStateTag(text);
+        },
+      },
+      {
+        title: i18next.t("general:Action"),
+        dataIndex: "",
+        key: "op",
+        width: "250px",
+        fixed: (Setting.isMobile()) ? "false" : "right",
+        render: (text, record, index) => {
+          const isPending = record.state === "Pending";
+          const accountId = this.getAccountId();
+          return (
+            <div>
+              {
+                isPending && (record.approvers ?? []).includes(accountId) ? (
+                  <React.Fragment>
+                    <Button style={{marginTop: "10px", marginBottom: "10px", marginRight: "10px"}} type="primary" onClick={() => this.reviewAccessRequest(record, "approve")}>{i18next.t("accessRequest:Approve")}</Button>
+                    <Button style={{marginTop: "10px", marginBottom: "10px", marginRight: "10px"}} danger onClick={() => this.reviewAccessRequest(record, "deny")}>{i18next.t("accessRequest:Deny")}</Button>
+                  </React.Fragment>
+                ) : null
+              }
+              {
+                isPending && record.user === accountId ? (
+                  <Button style={{marginTop: "10px", marginBottom: "10px", marginRight: "10px"}} onClick={() => this.cancelAccessRequest(record)}>{i18next.t("general:Cancel")}</Button>
+                ) : null
+              }
+              {
+                Setting.isLocalAdminUser(this.props.account) ? (
+                  <PopconfirmModal
+                    title={i18next.t("general:Sure to delete") + `: ${record.name} ?`}
+                    onConfirm={() => this.deleteAccessRequest(index)}
+                  >
+                  </PopconfirmModal>
+                ) : null
+              }
+            </div>
+          );
+        },
+      },
+    ];
+
+    const paginationProps = {
+      total: this.state.pagination.total,
+      showQuickJumper: true,
+      showSizeChanger: true,
+      showTotal: () => i18next.t("general:{total} in total").replace("{total}", this.state.pagination.total),
+    };
+
+    return (
+      <div>
+        <Table scroll={{x: "max-content"}} columns={columns} dataSource={requests} rowKey={(record) => `${record.owner}/${record.name}`} size="middle" bordered pagination={paginationProps}
+          expandable={{expandedRowRender: (record) => this.renderHistory(record)}}
+          title={() => (
+            <div>
+              {i18next.t("general:Access Requests")}&nbsp;&nbsp;&nbsp;&nbsp;
+              <Button type="primary" size="small" onClick={() => this.openSubmitModal()}>{i18next.t("accessRequest:Request access")}</Button>
+            </div>
+          )}
+          loading={this.state.loading}
+          onChange={this.handleTableChange}
+        />
+        {
+          this.renderSubmitModal()
+        }
+      </div>
+    );
+  }
+
+  // fetchOwnAccessRequests lists the requests of a user without admin rights: the submitted ones and the ones to review
+  fetchOwnAccessRequests = (params) => {
+    Promise.all([AccessRequestBackend.getMyAccessRequests(), AccessRequestBackend.getPendingAccessRequests()])
+      .then(([myRes, pendingRes]) => {
+        this.setState({
+          loading: false,
+        });
+        if (myRes.status !== "ok" || pendingRes.status !== "ok") {
+          Setting.showMessage("error", myRes.status !== "ok" ? myRes.msg : pendingRes.msg);
+          return;
+        }
+
+        const data = [...pendingRes.data, ...myRes.data.filter(request => !pendingRes.data.some(pending => pending.owner === request.owner && pending.name === request.name))];
+        this.setState({
+          data: data,
+          pagination: {
+            ...params.pagination,
+            total: data.length,
+          },
+        });
+      });
+  };
+
+  fetch = (params = {}) => {
+    const field = params.searchedColumn, value = params.searchText;
+    const sortField = params.sortField, sortOrder = params.sortOrder;
+    this.setState({loading: true});
+    if (!Setting.isLocalAdminUser(this.props.account)) {
+      this.fetchOwnAccessRequests(params);
+      return;
+    }
+
+    AccessRequestBackend.getAccessRequests(Setting.isDefaultOrganizationSelected(this.props.account) ? "" : Setting.getRequestOrganization(this.props.account), params.pagination.current, params.pagination.pageSize, field, value, sortField, sortOrder)
+      .then((res) => {
+        this.setState({
+          loading: false,
+        });
+        if (res.status === "ok") {
+          this.setState({
+            data: res.data,
+            pagination: {
+              ...params.pagination,
+              total: res.data2,
+            },
+            searchText: params.searchText,
+            searchedColumn: params.searchedColumn,
+          });
+        } else {
+          if (Setting.isResponseDenied(res)) {
+            this.setState({
+              isAuthorized: false,
+            });
+          } else {
+            Setting.showMessage("error", res.msg);
+          }
+        }
+      });
+  };
+}
+
+export default AccessRequestListPage;
@@ -0,0 +1,429 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+import React from "react";
+import {Button, Card, Col, DatePicker, Input, InputNumber, Row, Select, Table} from "antd";
+import * as AccessReviewBackend from "./backend/AccessReviewBackend";
+import * as UserBackend from "./backend/UserBackend";
+import * as ProviderBackend from "./backend/ProviderBackend";
+import * as Setting from "./Setting";
+import i18next from "i18next";
+import dayjs from "dayjs";
+
+class AccessReviewEditPage extends React.Component {
+  constructor(props) {
+    super(props);
+    this.state = {
+      classes: props,
+      organizationName: props.organizationName !== undefined ? props.organizationName : props.match.params.organizationName,
+      reviewName: decodeURIComponent(props.match.params.reviewName),
+      review: null,
+      items: [],
+      users: [],
+      providers: [],
+      mode: props.location.mode !== undefined ? props.location.mode : "edit",
+    };
+  }
+
+  UNSAFE_componentWillMount() {
+    this.getAccessReview();
+    this.getAccessReviewItems();
+    this.getUsers();
+    this.getProviders();
+  }
+
+  getAccessReview() {
+    AccessReviewBackend.getAccessReview(this.state.organizationName, this.state.reviewName)
+      .then((res) => {
+        if (res.data === null) {
+          this.props.history.push("/404");
+          return;
+        }
+        if (res.status === "error") {
+          Setting.showMessage("error", res.msg);
+          return;
+        }
+
+        this.setState({
+          review: res.data,
+        });
+      });
+  }
+
+  getAccessReviewItems() {
+    AccessReviewBackend.getAccessReviewItems(this.state.organizationName, this.state.reviewName)
+      .then((res) => {
+        if (res.status === "ok") {
+          this.setState({
+            items: res.data,
+          });
+        }
+      });
+  }
+
+  getUsers() {
+    UserBackend.getUsers(this.state.organizationName)
+      .then((res) => {
+        if (res.status === "ok") {
+          this.setState({
+            users: res.data,
+          });
+        }
+      });
+  }
+
+  getProviders() {
+    ProviderBackend.getProviders("admin")
+      .then((res) => {
+        if (res.status === "ok") {
+          this.setState({
+            providers: res.data.filter(provider => provider.category === "Notification"),
+          });
+        }
+      });
+  }
+
+  parseAccessReviewField(key, value) {
+    if (["recurrenceMonths"].includes(key)) {
+      value = Setting.myParseInt(value);
+    }
+    return value;
+  }
+
+  updateAccessReviewField(key, value) {
+    value = this.parseAccessReviewField(key, value);
+
+    const review = this.state.review;
+    review[key] = value;
+    this.setState({
+      review: review,
+    });
+  }
+
+  runAccessReviewAction(action) {
+    action(this.state.review.owner, this.state.review.name)
+      .then((res) => {
+        if (res.status === "ok") {
+          Setting.showMessage("success", i18next.t("general:Successfully saved"));
+          this.getAccessReview();
+          this.getAccessReviewItems();
+        } else {
+          Setting.showMessage("error", `${i18next.t("general:Failed to save")}: ${res.msg}`);
+        }
+      })
+      .catch(error => {
+        Setting.showMessage("error", `${i18next.t("general:Failed to connect to server")}: ${error}`);
+      });
+  }
+
+  decideAccessReviewItem(item, decision) {
+    AccessReviewBackend.decideAccessReviewItem(item.owner, item.name, decision)
+      .then((res) => {
+        if (res.status === "ok") {
+          Setting.showMessage("success", i18next.t("general:Successfully saved"));
+          this.getAccessReviewItems();
+        } else {
+          Setting.showMessage("error", `${i18next.t("general:Failed to save")}: ${res.msg}`);
+        }
+      })
+      .catch(error => {
+        Setting.showMessage("error", `${i18next.t("general:Failed to connect to server")}: ${error}`);
+      });
+  }
+
+  renderTime(key) {
+    const value = this.state.review[key];
+    return (
+      <DatePicker showTime style={{width: "100%"}} value={value ? dayjs(value) : null} onChange={value => {
+        this.updateAccessReviewField(key, value ? value.format() : "");
+      }} />
+    );
+  }
+
+  renderAccessReview() {
+    const isScheduled = this.state.review.state === "Scheduled";
+    const isActive = this.state.review.state === "Active";
+    return (
+      <Card size="small" title={
+        <div>
+          {this.state.mode === "add" ? i18next.t("accessReview:New Access Review") : i18next.t("accessReview:Edit Access Review")}&nbsp;&nbsp;&nbsp;&nbsp;
+          <Button onClick={() => this.submitAccessReviewEdit(false)}>{i18next.t("general:Save")}</Button>
+          <Button style={{marginLeft: "20px"}} type="primary" onClick={() => this.submitAccessReviewEdit(true)}>{i18next.t("general:Save & Exit")}</Button>
+          {this.state.mode === "add" ? <Button style={{marginLeft: "20px"}} onClick={() => this.deleteAccessReview()}>{i18next.t("general:Cancel")}</Button> : null}
+          {isScheduled ? <Button style={{marginLeft: "20px"}} onClick={() => this.runAccessReviewAction(AccessReviewBackend.startAccessReview)}>{i18next.t("accessReview:Start")}</Button> : null}
+          {isActive ? <Button style={{marginLeft: "20px"}} onClick={() => this.runAccessReviewAction(AccessReviewBackend.completeAccessReview)}>{i18next.t("accessReview:Complete")}</Button> : null}
+          {!isScheduled ? <Button style={{marginLeft: "20px"}} onClick={() => Setting.openLink(AccessReviewBackend.getAccessReviewExportUrl(this.state.review.owner, this.state.review.name, "xlsx"))}>{i18next.t("accessReview:Export evidence (.xlsx)")}</Button> : null}
+          {!isScheduled ? <Button style={{marginLeft: "20px"}} onClick={() => Setting.openLink(AccessReviewBackend.getAccessReviewExportUrl(this.state.review.owner, this.state.review.name, "csv"))}>{i18next.t("accessReview:Export evidence (.csv)")}</Button> : null}
+        </div>
+      } style={(Setting.isMobile()) ? {margin: "5px"} : {}} type="inner">
+        <Row style={{marginTop: "10px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("general:Organization"), i18next.t("general:Organization - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Input disabled={true} value={this.state.review.owner} />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("general:Name"), i18next.t("general:Name - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Input disabled={!isScheduled} value={this.state.review.name} onChange={e => {
+              this.updateAccessReviewField("name", e.target.value);
+            }} />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("general:Display name"), i18next.t("general:Display name - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Input value={this.state.review.displayName} onChange={e => {
+              this.updateAccessReviewField("displayName", e.target.value);
+            }} />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("accessReview:Target types"), i18next.t("accessReview:Target types - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Select virtual={false} mode="multiple" style={{width: "100%"}} disabled={!isScheduled} value={this.state.review.targetTypes} onChange={(value => {
+              this.updateAccessReviewField("targetTypes", value);
+            })}
+            options={[
+              {value: "Role", label: i18next.t("general:Roles")},
+              {value: "Group", label: i18next.t("general:Groups")},
+              {value: "Permission", label: i18next.t("general:Permissions")},
+            ]} />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("accessReview:Default reviewer"), i18next.t("accessReview:Default reviewer - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Select virtual={false} allowClear showSearch style={{width: "100%"}} disabled={!isScheduled} value={this.state.review.defaultReviewer} onChange={(value => {
+              this.updateAccessReviewField("defaultReviewer", value ?? "");
+            })}
+            options={this.state.users.map((user) => Setting.getOption(`${user.owner}/${user.name}`, `${user.owner}/${user.name}`))}
+            />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("accessReview:Start time"), i18next.t("accessReview:Start time - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            {this.renderTime("startTime")}
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("accessRequest:Deadline"), i18next.t("accessReview:Deadline - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            {this.renderTime("deadline")}
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("accessReview:Undecided action"), i18next.t("accessReview:Undecided action - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Select virtual={false} style={{width: "100%"}} value={this.state.review.undecidedAction} onChange={(value => {
+              this.updateAccessReviewField("undecidedAction", value);
+            })}
+            options={[
+              {value: "Keep", label: i18next.t("accessReview:Keep")},
+              {value: "Revoke", label: i18next.t("accessReview:Revoke")},
+            ]} />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("accessReview:Recurrence (months)"), i18next.t("accessReview:Recurrence (months) - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <InputNumber min={0} value={this.state.review.recurrenceMonths} onChange={value => {
+              this.updateAccessReviewField("recurrenceMonths", value);
+            }} />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("accessWorkflow:Notification provider"), i18next.t("accessWorkflow:Notification provider - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Select virtual={false} allowClear style={{width: "100%"}} value={this.state.review.notificationProvider} onChange={(value => {
+              this.updateAccessReviewField("notificationProvider", value ?? "");
+            })}
+            options={this.state.providers.map((provider) => Setting.getOption(provider.name, provider.name))}
+            />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("general:State"), i18next.t("general:State - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Input disabled={true} value={i18next.t(`accessReview:${this.state.review.state}`)} />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("accessReview:Items"), i18next.t("accessReview:Items - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            {this.renderItems(isActive)}
+          </Col>
+        </Row>
+      </Card>
+    );
+  }
+
+  renderItems(isActive) {
+    const columns = [
+      {
+        title: i18next.t("general:User"),
+        dataIndex: "user",
+        key: "user",
+        width: "150px",
+      },
+      {
+        title: i18next.t("accessWorkflow:Target type"),
+        dataIndex: "targetType",
+        key: "targetType",
+        width: "100px",
+      },
+      {
+        title: i18next.t("accessRequest:Target"),
+        dataIndex: "target",
+        key: "target",
+        width: "200px",
+      },
+      {
+        title: i18next.t("accessReview:Reviewer"),
+        dataIndex: "reviewer",
+        key: "reviewer",
+        width: "150px",
+        render: (text, record, index) => {
+          return text !== "" ? text : i18next.t("accessWorkflow:Organization admins");
+        },
+      },
+      {
+        title: i18next.t("general:State"),
+        dataIndex: "state",
+        key: "state",
+        width: "100px",
+        render: (text, record, index) => {
+          return i18next.t(`accessReview:${text}`);
+        },
+      },
+      {
+        title: i18next.t("accessReview:Decided by"),
+        dataIndex: "decidedBy",
+        key: "decidedBy",
+        width: "150px",
+      },
+      {
+        title: i18next.t("accessRequest:Comment"),
+        dataIndex: "comment",
+        key: "comment",
+        render: (text, record, index) => {
+          return record.message !== "" ? `${text} ${record.message}` : text;
+        },
+      },
+      {
+        title: i18next.t("general:Action"),
+        dataIndex: "",
+        key: "op",
+        width: "180px",
+        render: (text, record, index) => {
+          if (!isActive || record.state !== "Pending") {
+            return null;
+          }
+
+          return (
+            <div>
+              <Button style={{marginRight: "10px"}} type="primary" size="small" onClick={() => this.decideAccessReviewItem(record, "Keep")}>{i18next.t("accessReview:Keep")}</Button>
+              <Button danger size="small" onClick={() => this.decideAccessReviewItem(record, "Revoke")}>{i18next.t("accessReview:Revoke")}</Button>
+            </div>
+          );
+        },
+      },
+    ];
+
+    return (
+      <Table columns={columns} dataSource={this.state.items} rowKey={(record) => `${record.owner}/${record.name}`} size="middle" bordered pagination={{pageSize: 20}} />
+    );
+  }
+
+  submitAccessReviewEdit(exitAfterSave) {
+    const review = Setting.deepCopy(this.state.review);
+    AccessReviewBackend.updateAccessReview(this.state.organizationName, this.state.reviewName, review)
+      .then((res) => {
+        if (res.status === "ok") {
+          Setting.showMessage("success", i18next.t("general:Successfully saved"));
+          this.setState({
+            reviewName: this.state.review.name,
+          });
+
+          if (exitAfterSave) {
+            this.props.history.push("/access-reviews");
+          } else {
+            this.props.history.push(`/access-reviews/${this.state.review.owner}/${encodeURIComponent(this.state.review.name)}`);
+          }
+        } else {
+          Setting.showMessage("error", `${i18next.t("general:Failed to save")}: ${res.msg}`);
+          this.updateAccessReviewField("name", this.state.reviewName);
+        }
+      })
+      .catch(error => {
+        Setting.showMessage("error", `${i18next.t("general:Failed to connect to server")}: ${error}`);
+      });
+  }
+
+  deleteAccessReview() {
+    AccessReviewBackend.deleteAccessReview(this.state.review)
+      .then((res) => {
+        if (res.status === "ok") {
+          this.props.history.push("/access-reviews");
+        } else {
+          Setting.showMessage("error", `${i18next.t("general:Failed to delete")}: ${res.msg}`);
+        }
+      })
+      .catch(error => {
+        Setting.showMessage("error", `${i18next.t("general:Failed to connect to server")}: ${error}`);
+      });
+  }
+
+  render() {
+    return (
+      <div>
+        {
+          this.state.review !== null ? this.renderAccessReview() : null
+        }
+        <div style={{marginTop: "20px", marginLeft: "40px"}}>
+          <Button size="large" onClick={() => this.submitAccessReviewEdit(false)}>{i18next.t("general:Save")}</Button>
+          <Button style={{marginLeft: "20px"}} type="primary" size="large" onClick={() => this.submitAccessReviewEdit(true)}>{i18next.t("general:Save & Exit")}</Button>
+          {this.state.mode === "add" ? <Button style={{marginLeft: "20px"}} size="large" onClick={() => this.deleteAccessReview()}>{i18next.t("general:Cancel")}</Button> : null}
+        </div>
+      </div>
+    );
+  }
+}
+
+export default AccessReviewEditPage;
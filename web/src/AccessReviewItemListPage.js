@@ -0,0 +1,132 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+import React from "react";
+import {Button, Input, Table} from "antd";
+import * as AccessReviewBackend from "./backend/AccessReviewBackend";
+import * as Setting from "./Setting";
+import i18next from "i18next";
+
+// AccessReviewItemListPage lists the access the signed-in user has to keep or revoke in the active campaigns
+class AccessReviewItemListPage extends React.Component {
+  constructor(props) {
+    super(props);
+    this.state = {
+      classes: props,
+      items: [],
+      comments: {},
+      loading: false,
+    };
+  }
+
+  UNSAFE_componentWillMount() {
+    this.getMyAccessReviewItems();
+  }
+
+  getMyAccessReviewItems() {
+    this.setState({loading: true});
+    AccessReviewBackend.getMyAccessReviewItems()
+      .then((res) => {
+        this.setState({loading: false});
+        if (res.status === "ok") {
+          this.setState({
+            items: res.data,
+          });
+        } else {
+          Setting.showMessage("error", res.msg);
+        }
+      });
+  }
+
+  decideAccessReviewItem(item, decision) {
+    AccessReviewBackend.decideAccessReviewItem(item.owner, item.name, decision, this.state.comments[item.name] ?? "")
+      .then((res) => {
+        if (res.status === "ok") {
+          Setting.showMessage("success", i18next.t("general:Successfully saved"));
+          this.getMyAccessReviewItems();
+        } else {
+          Setting.showMessage("error", `${i18next.t("general:Failed to save")}: ${res.msg}`);
+        }
+      })
+      .catch(error => {
+        Setting.showMessage("error", `${i18next.t("general:Failed to connect to server")}: ${error}`);
+      });
+  }
+
+  render() {
+    const columns = [
+      {
+        title: i18next.t("accessReview:Access review"),
+        dataIndex: "review",
+        key: "review",
+        width: "200px",
+      },
+      {
+        title: i18next.t("general:User"),
+        dataIndex: "user",
+        key: "user",
+        width: "150px",
+      },
+      {
+        title: i18next.t("accessWorkflow:Target type"),
+        dataIndex: "targetType",
+        key: "targetType",
+        width: "100px",
+      },
+      {
+        title: i18next.t("accessRequest:Target"),
+        dataIndex: "target",
+        key: "target",
+        width: "200px",
+      },
+      {
+        title: i18next.t("accessRequest:Comment"),
+        dataIndex: "comment",
+        key: "comment",
+        render: (text, record, index) => {
+          return (
+            <Input value={this.state.comments[record.name]} onChange={e => {
+              this.setState({comments: {...this.state.comments, [record.name]: e.target.value}});
+            }} />
+          );
+        },
+      },
+      {
+        title: i18next.t("general:Action"),
+        dataIndex: "",
+        key: "op",
+        width: "180px",
+        render: (text, record, index) => {
+          return (
+            <div>
+              <Button style={{marginRight: "10px"}} type="primary" size="small" onClick={() => this.decideAccessReviewItem(record, "Keep")}>{i18next.t("accessReview:Keep")}</Button>
+              <Button danger size="small" onClick={() => this.decideAccessReviewItem(record, "Revoke")}>{i18next.t("accessReview:Revoke")}</Button>
+            </div>
+          );
+        },
+      },
+    ];
+
+    return (
+      <div>
+        <Table scroll={{x: "max-content"}} columns={columns} dataSource={this.state.items} rowKey={(record) => `${record.owner}/${record.name}`} size="middle" bordered pagination={{pageSize: 20}}
+          title={() => i18next.t("accessReview:My Access Reviews")}
+          loading={this.state.loading}
+        />
+      </div>
+    );
+  }
+}
+
+export default AccessReviewItemListPage;
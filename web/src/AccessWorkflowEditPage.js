@@ -0,0 +1,302 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+import React from "react";
+import {Button, Card, Col, Input, InputNumber, Row, Select, Switch} from "antd";
+import * as AccessWorkflowBackend from "./backend/AccessWorkflowBackend";
+import * as UserBackend from "./backend/UserBackend";
+import * as GroupBackend from "./backend/GroupBackend";
+import * as RoleBackend from "./backend/RoleBackend";
+import * as PermissionBackend from "./backend/PermissionBackend";
+import * as ProviderBackend from "./backend/ProviderBackend";
+import * as Setting from "./Setting";
+import i18next from "i18next";
+import AccessWorkflowStepTable from "./table/AccessWorkflowStepTable";
+
+class AccessWorkflowEditPage extends React.Component {
+  constructor(props) {
+    super(props);
+    this.state = {
+      classes: props,
+      organizationName: props.organizationName !== undefined ? props.organizationName : props.match.params.organizationName,
+      workflowName: decodeURIComponent(props.match.params.workflowName),
+      workflow: null,
+      users: [],
+      groups: [],
+      roles: [],
+      permissions: [],
+      providers: [],
+      mode: props.location.mode !== undefined ? props.location.mode : "edit",
+    };
+  }
+
+  UNSAFE_componentWillMount() {
+    this.getAccessWorkflow();
+    this.getProviders();
+  }
+
+  getAccessWorkflow() {
+    AccessWorkflowBackend.getAccessWorkflow(this.state.organizationName, this.state.workflowName)
+      .then((res) => {
+        if (res.data === null) {
+          this.props.history.push("/404");
+          return;
+        }
+        if (res.status === "error") {
+          Setting.showMessage("error", res.msg);
+          return;
+        }
+
+        this.setState({
+          workflow: res.data,
+        });
+
+        this.getTargets(this.state.organizationName);
+      });
+  }
+
+  getTargets(organizationName) {
+    UserBackend.getUsers(organizationName)
+      .then((res) => {
+        if (res.status === "ok") {
+          this.setState({users: res.data});
+        }
+      });
+    GroupBackend.getGroups(organizationName)
+      .then((res) => {
+        if (res.status === "ok") {
+          this.setState({groups: res.data});
+        }
+      });
+    RoleBackend.getRoles(organizationName)
+      .then((res) => {
+        if (res.status === "ok") {
+          this.setState({roles: res.data});
+        }
+      });
+    PermissionBackend.getPermissions(organizationName)
+      .then((res) => {
+        if (res.status === "ok") {
+          this.setState({permissions: res.data});
+        }
+      });
+  }
+
+  getProviders() {
+    ProviderBackend.getProviders("admin")
+      .then((res) => {
+        if (res.status === "ok") {
+          this.setState({
+            providers: res.data.filter(provider => provider.category === "Notification"),
+          });
+        }
+      });
+  }
+
+  getTargetOptions() {
+    const targets = {
+      "Role": this.state.roles,
+      "Group": this.state.groups,
+      "Permission": this.state.permissions,
+    }[this.state.workflow.targetType] ?? [];
+    return targets.map((target) => Setting.getOption(`${target.owner}/${target.name}`, `${target.owner}/${target.name}`));
+  }
+
+  parseAccessWorkflowField(key, value) {
+    if (["timeout"].includes(key)) {
+      value = Setting.myParseInt(value);
+    }
+    return value;
+  }
+
+  updateAccessWorkflowField(key, value) {
+    value = this.parseAccessWorkflowField(key, value);
+
+    const workflow = this.state.workflow;
+    workflow[key] = value;
+    this.setState({
+      workflow: workflow,
+    });
+  }
+
+  renderAccessWorkflow() {
+    return (
+      <Card size="small" title={
+        <div>
+          {this.state.mode === "add" ? i18next.t("accessWorkflow:New Access Workflow") : i18next.t("accessWorkflow:Edit Access Workflow")}&nbsp;&nbsp;&nbsp;&nbsp;
+          <Button onClick={() => this.submitAccessWorkflowEdit(false)}>{i18next.t("general:Save")}</Button>
+          <Button style={{marginLeft: "20px"}} type="primary" onClick={() => this.submitAccessWorkflowEdit(true)}>{i18next.t("general:Save & Exit")}</Button>
+          {this.state.mode === "add" ? <Button style={{marginLeft: "20px"}} onClick={() => this.deleteAccessWorkflow()}>{i18next.t("general:Cancel")}</Button> : null}
+        </div>
+      } style={(Setting.isMobile()) ? {margin: "5px"} : {}} type="inner">
+        <Row style={{marginTop: "10px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("general:Organization"), i18next.t("general:Organization - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Input disabled={true} value={this.state.workflow.owner} />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("general:Name"), i18next.t("general:Name - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Input value={this.state.workflow.name} onChange={e => {
+              this.updateAccessWorkflowField("name", e.target.value);
+            }} />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("general:Display name"), i18next.t("general:Display name - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Input value={this.state.workflow.displayName} onChange={e => {
+              this.updateAccessWorkflowField("displayName", e.target.value);
+            }} />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("accessWorkflow:Target type"), i18next.t("accessWorkflow:Target type - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Select virtual={false} style={{width: "100%"}} value={this.state.workflow.targetType} onChange={(value => {
+              this.updateAccessWorkflowField("targetType", value);
+              this.updateAccessWorkflowField("targets", []);
+            })}
+            options={[
+              {value: "Role", label: i18next.t("general:Roles")},
+              {value: "Group", label: i18next.t("general:Groups")},
+              {value: "Permission", label: i18next.t("general:Permissions")},
+            ]} />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("accessWorkflow:Targets"), i18next.t("accessWorkflow:Targets - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Select virtual={false} mode="multiple" style={{width: "100%"}} value={this.state.workflow.targets ?? []}
+              onChange={(value => {this.updateAccessWorkflowField("targets", value);})}
+              options={this.getTargetOptions()}
+            />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("accessWorkflow:Steps"), i18next.t("accessWorkflow:Steps - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <AccessWorkflowStepTable
+              title={i18next.t("accessWorkflow:Steps")}
+              table={this.state.workflow.steps}
+              users={this.state.users}
+              onUpdateTable={(value) => {this.updateAccessWorkflowField("steps", value);}}
+            />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("accessWorkflow:Timeout (hours)"), i18next.t("accessWorkflow:Timeout (hours) - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <InputNumber min={0} value={this.state.workflow.timeout} onChange={value => {
+              this.updateAccessWorkflowField("timeout", value);
+            }} />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("accessWorkflow:Notification provider"), i18next.t("accessWorkflow:Notification provider - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Select virtual={false} allowClear style={{width: "100%"}} value={this.state.workflow.notificationProvider} onChange={(value => {
+              this.updateAccessWorkflowField("notificationProvider", value ?? "");
+            })}
+            options={this.state.providers.map((provider) => Setting.getOption(provider.name, provider.name))}
+            />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 19 : 2}>
+            {Setting.getLabel(i18next.t("general:Is enabled"), i18next.t("general:Is enabled - Tooltip"))} :
+          </Col>
+          <Col span={1} >
+            <Switch checked={this.state.workflow.isEnabled} onChange={checked => {
+              this.updateAccessWorkflowField("isEnabled", checked);
+            }} />
+          </Col>
+        </Row>
+      </Card>
+    );
+  }
+
+  submitAccessWorkflowEdit(exitAfterSave) {
+    const workflow = Setting.deepCopy(this.state.workflow);
+    AccessWorkflowBackend.updateAccessWorkflow(this.state.organizationName, this.state.workflowName, workflow)
+      .then((res) => {
+        if (res.status === "ok") {
+          Setting.showMessage("success", i18next.t("general:Successfully saved"));
+          this.setState({
+            workflowName: this.state.workflow.name,
+          });
+
+          if (exitAfterSave) {
+            this.props.history.push("/access-workflows");
+          } else {
+            this.props.history.push(`/access-workflows/${this.state.workflow.owner}/${encodeURIComponent(this.state.workflow.name)}`);
+          }
+        } else {
+          Setting.showMessage("error", `${i18next.t("general:Failed to save")}: ${res.msg}`);
+          this.updateAccessWorkflowField("name", this.state.workflowName);
+        }
+      })
+      .catch(error => {
+        Setting.showMessage("error", `${i18next.t("general:Failed to connect to server")}: ${error}`);
+      });
+  }
+
+  deleteAccessWorkflow() {
+    AccessWorkflowBackend.deleteAccessWorkflow(this.state.workflow)
+      .then((res) => {
+        if (res.status === "ok") {
+          this.props.history.push("/access-workflows");
+        } else {
+          Setting.showMessage("error", `${i18next.t("general:Failed to delete")}: ${res.msg}`);
+        }
+      })
+      .catch(error => {
+        Setting.showMessage("error", `${i18next.t("general:Failed to connect to server")}: ${error}`);
+      });
+  }
+
+  render() {
+    return (
+      <div>
+        {
+          this.state.workflow !== null ? this.renderAccessWorkflow() : null
+        }
+        <div style={{marginTop: "20px", marginLeft: "40px"}}>
+          <Button size="large" onClick={() => this.submitAccessWorkflowEdit(false)}>{i18next.t("general:Save")}</Button>
+          <Button style={{marginLeft: "20px"}} type="primary" size="large" onClick={() => this.submitAccessWorkflowEdit(true)}>{i18next.t("general:Save & Exit")}</Button>
+          {this.state.mode === "add" ? <Button style={{marginLeft: "20px"}} size="large" onClick={() => this.deleteAccessWorkflow()}>{i18next.t("general:Cancel")}</Button> : null}
+        </div>
+      </div>
+    );
+  }
+}
+
+export default AccessWorkflowEditPage;
@@ -113,7 +113,7 @@ class App extends Component {
       this.setState({selectedMenuKey: "/orgs"});
     } else if (uri.includes("/applications") || uri.includes("/providers") || uri.includes("/resources") || uri.includes("/certs")) {
       this.setState({selectedMenuKey: "/identity"});
-    } else if (uri.includes("/roles") || uri.includes("/permissions") || uri.includes("/models") || uri.includes("/adapters") || uri.includes("/enforcers")) {
+    } else if (uri.includes("/roles") || uri.includes("/permissions") || uri.includes("/models") || uri.includes("/adapters") || uri.includes("/enforcers") || uri.includes("/access-workflows") || uri.includes("/access-requests") || uri.includes("/access-review")) {
       this.setState({selectedMenuKey: "/auth"});
     } else if (uri.includes("/records") || uri.includes("/tokens") || uri.includes("/sessions")) {
       this.setState({selectedMenuKey: "/logs"});
@@ -385,7 +385,8 @@ class App extends Component {
         window.location.pathname.startsWith("/select-plan") ||
         window.location.pathname.startsWith("/buy-plan") ||
         window.location.pathname.startsWith("/qrcode") ||
-        window.location.pathname.startsWith("/captcha");
+        window.location.pathname.startsWith("/captcha") ||
+        window.location.pathname.startsWith("/mfa/push");
   }
 
   onClick = ({key}) => {
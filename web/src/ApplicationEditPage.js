@@ -30,6 +30,7 @@ import ProviderTable from "./table/ProviderTable";
 import SigninMethodTable from "./table/SigninMethodTable";
 import SignupTable from "./table/SignupTable";
 import SamlAttributeTable from "./table/SamlAttributeTable";
+import CasServiceTable from "./table/CasServiceTable";
 import PromptPage from "./auth/PromptPage";
 import copy from "copy-to-clipboard";
 import ThemeEditor from "./common/theme/ThemeEditor";
@@ -118,6 +119,7 @@ class ApplicationEditPage extends React.Component {
       mode: props.location.mode !== undefined ? props.location.mode : "edit",
       samlAttributes: [],
       samlMetadata: null,
+      samlSpMetadata: "",
       isAuthorized: true,
     };
   }
@@ -227,6 +229,30 @@ class ApplicationEditPage extends React.Component {
       });
   }
 
+  importSamlSpMetadata() {
+    ApplicationBackend.parseSamlSpMetadata(this.state.samlSpMetadata)
+      .then((res) => {
+        if (res.status === "ok") {
+          const application = this.state.application;
+          application.samlReplyUrl = res.data.replyUrl;
+          application.samlSloUrl = res.data.sloUrl;
+          application.samlSpCertificate = res.data.certificate;
+          application.enableSamlArtifactBinding = res.data.enableArtifactBinding;
+          // the SP entity ID is the issuer of its requests, which has to be an allowed redirect URI
+          if (!(application.redirectUris ?? []).includes(res.data.entityId)) {
+            application.redirectUris = [...(application.redirectUris ?? []), res.data.entityId];
+          }
+          this.setState({
+            application: application,
+            samlSpMetadata: "",
+          });
+          Setting.showMessage("success", i18next.t("application:SP metadata imported, please save the application"));
+        } else {
+          Setting.showMessage("error", res.msg);
+        }
+      });
+  }
+
   parseApplicationField(key, value) {
     if (["offset"].includes(key)) {
       value = Setting.myParseInt(value);
@@ -444,10 +470,44 @@ class ApplicationEditPage extends React.Component {
           </Col>
           <Col span={22} >
             <Select virtual={false} style={{width: "100%"}} value={this.state.application.tokenSigningMethod === "" ? "RS256" : this.state.application.tokenSigningMethod} onChange={(value => {this.updateApplicationField("tokenSigningMethod", value);})}
-              options={["RS256", "RS512", "ES256", "ES512", "ES384"].map((item) => Setting.getOption(item, item))}
+              options={["RS256", "RS512", "ES256", "ES512", "ES384", "EdDSA"].map((item) => Setting.getOption(item, item))}
+            />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:ID token encryption"), i18next.t("application:ID token encryption - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Select virtual={false} style={{width: "100%"}} value={this.state.application.idTokenEncryptionAlg} onChange={(value => {this.updateApplicationField("idTokenEncryptionAlg", value);})}
+              options={[{value: "", label: i18next.t("general:None")}, ...["RSA-OAEP-256", "ECDH-ES"].map((item) => Setting.getOption(`${item} + A256GCM`, item))]}
+            />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:Userinfo encryption"), i18next.t("application:Userinfo encryption - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Select virtual={false} style={{width: "100%"}} value={this.state.application.userinfoEncryptionAlg} onChange={(value => {this.updateApplicationField("userinfoEncryptionAlg", value);})}
+              options={[{value: "", label: i18next.t("general:None")}, ...["RSA-OAEP-256", "ECDH-ES"].map((item) => Setting.getOption(`${item} + A256GCM`, item))]}
             />
           </Col>
         </Row>
+        {
+          (!this.state.application.idTokenEncryptionAlg && !this.state.application.userinfoEncryptionAlg) ? null : (
+            <Row style={{marginTop: "20px"}} >
+              <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+                {Setting.getLabel(i18next.t("application:Client JWKS"), i18next.t("application:Client JWKS - Tooltip"))} :
+              </Col>
+              <Col span={22} >
+                <Input.TextArea rows={6} value={this.state.application.clientJwks} onChange={e => {
+                  this.updateApplicationField("clientJwks", e.target.value);
+                }} />
+              </Col>
+            </Row>
+          )
+        }
         <Row style={{marginTop: "20px"}} >
           <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
             {Setting.getLabel(i18next.t("application:Token fields"), i18next.t("application:Token fields - Tooltip"))} :
@@ -743,6 +803,49 @@ class ApplicationEditPage extends React.Component {
             }} />
           </Col>
         </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:SAML SLO URL"), i18next.t("application:SAML SLO URL - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Input prefix={<LinkOutlined />} value={this.state.application.samlSloUrl} onChange={e => {
+              this.updateApplicationField("samlSloUrl", e.target.value);
+            }} />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:SP certificate"), i18next.t("application:SP certificate - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Input.TextArea rows={4} value={this.state.application.samlSpCertificate} onChange={e => {
+              this.updateApplicationField("samlSpCertificate", e.target.value);
+            }} />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:SAML assertion encryption"), i18next.t("application:SAML assertion encryption - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Select virtual={false} style={{width: "100%"}} value={this.state.application.samlAssertionEncryption ?? ""} onChange={(value => {this.updateApplicationField("samlAssertionEncryption", value);})}
+              options={[{value: "", label: i18next.t("general:None")}, ...["AES256-GCM", "AES256-CBC"].map((item) => Setting.getOption(item, item))]}
+            />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:SP metadata"), i18next.t("application:SP metadata - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Input.TextArea rows={4} value={this.state.samlSpMetadata} onChange={e => {
+              this.setState({samlSpMetadata: e.target.value});
+            }} />
+            <Button style={{marginTop: "10px"}} type="primary" disabled={this.state.samlSpMetadata === ""} onClick={() => this.importSamlSpMetadata()}>
+              {i18next.t("application:Import SP metadata")}
+            </Button>
+          </Col>
+        </Row>
         <Row style={{marginTop: "20px"}} >
           <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 19 : 2}>
             {Setting.getLabel(i18next.t("application:Enable SAML compression"), i18next.t("application:Enable SAML compression - Tooltip"))} :
@@ -784,6 +887,16 @@ class ApplicationEditPage extends React.Component {
             }} />
           </Col>
         </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 19 : 2}>
+            {Setting.getLabel(i18next.t("application:Enable SAML artifact binding"), i18next.t("application:Enable SAML artifact binding - Tooltip"))} :
+          </Col>
+          <Col span={1} >
+            <Switch checked={this.state.application.enableSamlArtifactBinding} onChange={checked => {
+              this.updateApplicationField("enableSamlArtifactBinding", checked);
+            }} />
+          </Col>
+        </Row>
         <Row style={{marginTop: "20px"}} >
           <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
             {Setting.getLabel(i18next.t("general:SAML attributes"), i18next.t("general:SAML attributes - Tooltip"))} :
@@ -813,6 +926,49 @@ class ApplicationEditPage extends React.Component {
             </Button>
           </Col>
         </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:CAS services"), i18next.t("application:CAS services - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <CasServiceTable
+              title={i18next.t("application:CAS services")}
+              table={this.state.application.casServices}
+              application={this.state.application}
+              onUpdateTable={(value) => {this.updateApplicationField("casServices", value);}}
+            />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:CAS ticket TTL"), i18next.t("application:CAS ticket TTL - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <InputNumber style={{width: "150px"}} value={this.state.application.casTicketTtl} min={0} step={1} precision={0} addonAfter="Seconds" onChange={value => {
+              this.updateApplicationField("casTicketTtl", value);
+            }} />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:CAS proxy ticket TTL"), i18next.t("application:CAS proxy ticket TTL - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <InputNumber style={{width: "150px"}} value={this.state.application.casProxyTicketTtl} min={0} step={1} precision={0} addonAfter="Seconds" onChange={value => {
+              this.updateApplicationField("casProxyTicketTtl", value);
+            }} />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:CAS PGT TTL"), i18next.t("application:CAS PGT TTL - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <InputNumber style={{width: "150px"}} value={this.state.application.casPgtTtl} min={0} step={1} precision={0} addonAfter="Seconds" onChange={value => {
+              this.updateApplicationField("casPgtTtl", value);
+            }} />
+          </Col>
+        </Row>
         <Row style={{marginTop: "20px"}} >
           <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
             {Setting.getLabel(i18next.t("general:Providers"), i18next.t("general:Providers - Tooltip"))} :
@@ -70,6 +70,23 @@ class CertEditPage extends React.Component {
       });
   }
 
+  rotateCert() {
+    CertBackend.rotateCert(this.state.cert.owner, this.state.certName)
+      .then((res) => {
+        if (res.status === "ok") {
+          Setting.showMessage("success", i18next.t("general:Successfully saved"));
+          this.setState({
+            cert: res.data,
+          });
+        } else {
+          Setting.showMessage("error", `${i18next.t("general:Failed to save")}: ${res.msg}`);
+        }
+      })
+      .catch(error => {
+        Setting.showMessage("error", `${i18next.t("general:Failed to connect to server")}: ${error}`);
+      });
+  }
+
   parseCertField(key, value) {
     if (["port"].includes(key)) {
       value = Setting.myParseInt(value);
@@ -172,7 +189,7 @@ class CertEditPage extends React.Component {
             <Select virtual={false} style={{width: "100%"}} value={this.state.cert.cryptoAlgorithm} onChange={(value => {
               this.updateCertField("cryptoAlgorithm", value);
 
-              if (value.startsWith("ES")) {
+              if (value.startsWith("ES") || value === "EdDSA") {
                 this.updateCertField("bitSize", 0);
               } else {
                 if (this.state.cert.bitSize !== 1024 && this.state.cert.bitSize !== 2048 && this.state.cert.bitSize !== 4096) {
@@ -194,13 +211,14 @@ class CertEditPage extends React.Component {
                   {id: "PS256", name: "PS256 (RSASSA-PSS using SHA256 and MGF1 with SHA256)"},
                   {id: "PS384", name: "PS384 (RSASSA-PSS using SHA384 and MGF1 with SHA384)"},
                   {id: "PS512", name: "PS512 (RSASSA-PSS using SHA512 and MGF1 with SHA512)"},
+                  {id: "EdDSA", name: "EdDSA (Ed25519)"},
                 ].map((item, index) => <Option key={index} value={item.id}>{item.name}</Option>)
               }
             </Select>
           </Col>
         </Row>
         {
-          this.state.cert.cryptoAlgorithm.startsWith("ES") ? null : (
+          (this.state.cert.cryptoAlgorithm.startsWith("ES") || this.state.cert.cryptoAlgorithm === "EdDSA") ? null : (
             <Row style={{marginTop: "20px"}} >
               <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
                 {Setting.getLabel(i18next.t("cert:Bit size"), i18next.t("cert:Bit size - Tooltip"))} :
@@ -229,6 +247,69 @@ class CertEditPage extends React.Component {
             }} />
           </Col>
         </Row>
+        {
+          this.state.cert.type !== "x509" ? null : (
+            <React.Fragment>
+              <Row style={{marginTop: "20px"}} >
+                <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+                  {Setting.getLabel(i18next.t("cert:Key store"), i18next.t("cert:Key store - Tooltip"))} :
+                </Col>
+                <Col span={22} >
+                  <Select virtual={false} style={{width: "100%"}} value={this.state.cert.keyStore} onChange={(value => {
+                    this.updateCertField("keyStore", value);
+                    if (value === "PKCS#11") {
+                      // existing keys can't be moved into the token, a new key is generated in it
+                      this.updateCertField("certificate", "");
+                      this.updateCertField("privateKey", "");
+                    }
+                  })}>
+                    {
+                      [
+                        {id: "", name: i18next.t("general:Default")},
+                        {id: "Database", name: "Database"},
+                        {id: "Encrypted", name: "Encrypted"},
+                        {id: "PKCS#11", name: "PKCS#11"},
+                      ].map((item, index) => <Option key={index} value={item.id}>{item.name}</Option>)
+                    }
+                  </Select>
+                </Col>
+              </Row>
+              <Row style={{marginTop: "20px"}} >
+                <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+                  {Setting.getLabel(i18next.t("cert:Key ID"), i18next.t("cert:Key ID - Tooltip"))} :
+                </Col>
+                <Col span={22} >
+                  <Input disabled={true} value={this.state.cert.keyId || this.state.cert.name} />
+                </Col>
+              </Row>
+              <Row style={{marginTop: "20px"}} >
+                <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+                  {Setting.getLabel(i18next.t("cert:Rotation interval days"), i18next.t("cert:Rotation interval days - Tooltip"))} :
+                </Col>
+                <Col span={22} >
+                  <InputNumber min={0} value={this.state.cert.rotationIntervalDays} onChange={value => {
+                    this.updateCertField("rotationIntervalDays", value);
+                  }} />
+                  {
+                    this.state.mode === "add" ? null : (
+                      <Button style={{marginLeft: "20px"}} onClick={() => this.rotateCert()}>{i18next.t("cert:Rotate now")}</Button>
+                    )
+                  }
+                </Col>
+              </Row>
+              <Row style={{marginTop: "20px"}} >
+                <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+                  {Setting.getLabel(i18next.t("cert:Rotation prepublish hours"), i18next.t("cert:Rotation prepublish hours - Tooltip"))} :
+                </Col>
+                <Col span={22} >
+                  <InputNumber min={0} value={this.state.cert.rotationPrepublishHours} onChange={value => {
+                    this.updateCertField("rotationPrepublishHours", value);
+                  }} />
+                </Col>
+              </Row>
+            </React.Fragment>
+          )
+        }
         <Row style={{marginTop: "20px"}} >
           <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
             {Setting.getLabel(i18next.t("cert:Certificate"), i18next.t("cert:Certificate - Tooltip"))} :
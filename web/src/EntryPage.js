@@ -33,6 +33,7 @@ import ProductBuyPage from "./ProductBuyPage";
 import PaymentResultPage from "./PaymentResultPage";
 import QrCodePage from "./QrCodePage";
 import CaptchaPage from "./CaptchaPage";
+import MfaPushPage from "./auth/MfaPushPage";
 import CustomHead from "./basic/CustomHead";
 import * as Util from "./auth/Util";
 import zgsmLogo from "./static/zgsm-logo.png";
@@ -140,6 +141,7 @@ class EntryPage extends React.Component {
             <Route exact path="/buy-plan/:owner/:pricingName/result" render={(props) => <PaymentResultPage {...this.props} pricing={this.state.pricing} onUpdatePricing={onUpdatePricing} {...props} />} />
             <Route exact path="/qrcode/:owner/:paymentName" render={(props) => <QrCodePage {...this.props} onUpdateApplication={onUpdateApplication} {...props} />} />
             <Route exact path="/captcha" render={(props) => <CaptchaPage {...props} />} />
+            <Route exact path="/mfa/push/:token" render={(props) => <MfaPushPage {...props} />} />
           </Switch>
         </div>
 
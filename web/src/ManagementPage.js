@@ -77,6 +77,12 @@ import SyncerListPage from "./SyncerListPage";
 import SyncerEditPage from "./SyncerEditPage";
 import WebhookListPage from "./WebhookListPage";
 import WebhookEditPage from "./WebhookEditPage";
+import AccessWorkflowListPage from "./AccessWorkflowListPage";
+import AccessWorkflowEditPage from "./AccessWorkflowEditPage";
+import AccessRequestListPage from "./AccessRequestListPage";
+import AccessReviewListPage from "./AccessReviewListPage";
+import AccessReviewEditPage from "./AccessReviewEditPage";
+import AccessReviewItemListPage from "./AccessReviewItemListPage";
 import LdapEditPage from "./LdapEditPage";
 import LdapSyncPage from "./LdapSyncPage";
 import MfaSetupPage from "./auth/MfaSetupPage";
@@ -302,6 +308,9 @@ function ManagementPage(props) {
         Setting.getItem(<Link to="/models">{i18next.t("general:Models")}</Link>, "/models"),
         Setting.getItem(<Link to="/adapters">{i18next.t("general:Adapters")}</Link>, "/adapters"),
         Setting.getItem(<Link to="/enforcers">{i18next.t("general:Enforcers")}</Link>, "/enforcers"),
+        Setting.getItem(<Link to="/access-workflows">{i18next.t("general:Access Workflows")}</Link>, "/access-workflows"),
+        Setting.getItem(<Link to="/access-requests">{i18next.t("general:Access Requests")}</Link>, "/access-requests"),
+        Setting.getItem(<Link to="/access-reviews">{i18next.t("general:Access Reviews")}</Link>, "/access-reviews"),
       ].filter(item => {
         if (!Setting.isLocalAdminUser(props.account) && ["/models", "/adapters", "/enforcers"].includes(item.key)) {
           return false;
@@ -418,6 +427,12 @@ function ManagementPage(props) {
         <Route exact path="/adapters/:organizationName/:adapterName" render={(props) => renderLoginIfNotLoggedIn(<AdapterEditPage account={account} {...props} />)} />
         <Route exact path="/enforcers" render={(props) => renderLoginIfNotLoggedIn(<EnforcerListPage account={account} {...props} />)} />
         <Route exact path="/enforcers/:organizationName/:enforcerName" render={(props) => renderLoginIfNotLoggedIn(<EnforcerEditPage account={account} {...props} />)} />
+        <Route exact path="/access-workflows" render={(props) => renderLoginIfNotLoggedIn(<AccessWorkflowListPage account={account} {...props} />)} />
+        <Route exact path="/access-workflows/:organizationName/:workflowName" render={(props) => renderLoginIfNotLoggedIn(<AccessWorkflowEditPage account={account} {...props} />)} />
+        <Route exact path="/access-requests" render={(props) => renderLoginIfNotLoggedIn(<AccessRequestListPage account={account} {...props} />)} />
+        <Route exact path="/access-reviews" render={(props) => renderLoginIfNotLoggedIn(<AccessReviewListPage account={account} {...props} />)} />
+        <Route exact path="/access-reviews/:organizationName/:reviewName" render={(props) => renderLoginIfNotLoggedIn(<AccessReviewEditPage account={account} {...props} />)} />
+        <Route exact path="/access-review-items" render={(props) => renderLoginIfNotLoggedIn(<AccessReviewItemListPage account={account} {...props} />)} />
         <Route exact path="/sessions" render={(props) => renderLoginIfNotLoggedIn(<SessionListPage account={account} {...props} />)} />
         <Route exact path="/tokens" render={(props) => renderLoginIfNotLoggedIn(<TokenListPage account={account} {...props} />)} />
         <Route exact path="/tokens/:tokenName" render={(props) => renderLoginIfNotLoggedIn(<TokenEditPage account={account} {...props} />)} />
@@ -24,6 +24,7 @@ import * as RoleBackend from "./backend/RoleBackend";
 import * as ModelBackend from "./backend/ModelBackend";
 import * as ApplicationBackend from "./backend/ApplicationBackend";
 import moment from "moment/moment";
+import AccessGrantTable from "./table/AccessGrantTable";
 
 class PermissionEditPage extends React.Component {
   constructor(props) {
@@ -312,6 +313,19 @@ class PermissionEditPage extends React.Component {
             />
           </Col>
         </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("role:Grants"), i18next.t("permission:Grants - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <AccessGrantTable
+              title={i18next.t("role:Grants")}
+              table={this.state.permission.grants}
+              members={[...(this.state.permission.users ?? []), ...(this.state.permission.roles ?? [])]}
+              onUpdateTable={(value) => {this.updatePermissionField("grants", value);}}
+            />
+          </Col>
+        </Row>
         <Row style={{marginTop: "20px"}} >
           <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
             {Setting.getLabel(i18next.t("role:Sub domains"), i18next.t("role:Sub domains - Tooltip"))} :
@@ -451,14 +465,49 @@ class PermissionEditPage extends React.Component {
             options={[
               {value: "Approved", name: i18next.t("permission:Approved")},
               {value: "Pending", name: i18next.t("permission:Pending")},
+              {value: "Rejected", name: i18next.t("permission:Rejected")},
             ].map((item) => Setting.getOption(item.name, item.value))}
             />
           </Col>
         </Row>
+        {
+          this.isPendingElevation() && Setting.isLocalAdminUser(this.props.account) ? (
+            <Row style={{marginTop: "20px"}} >
+              <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+                {Setting.getLabel(i18next.t("permission:Elevation"), i18next.t("permission:Elevation - Tooltip"))} :
+              </Col>
+              <Col span={22} >
+                <Button type="primary" onClick={() => this.reviewElevation(true)}>{i18next.t("permission:Approve elevation")}</Button>
+                <Button style={{marginLeft: "20px"}} danger onClick={() => this.reviewElevation(false)}>{i18next.t("permission:Reject elevation")}</Button>
+              </Col>
+            </Row>
+          ) : null
+        }
       </Card>
     );
   }
 
+  isPendingElevation() {
+    const permission = this.state.permission;
+    return permission.state === "Pending" && permission.submitter !== "" && (permission.grants ?? []).some(grant => grant.member === `${permission.owner}/${permission.submitter}`);
+  }
+
+  reviewElevation(approved) {
+    const review = approved ? PermissionBackend.approveElevation : PermissionBackend.rejectElevation;
+    review(this.state.permission.owner, this.state.permission.name)
+      .then((res) => {
+        if (res.status === "ok") {
+          Setting.showMessage("success", i18next.t("general:Successfully saved"));
+          this.getPermission();
+        } else {
+          Setting.showMessage("error", `${i18next.t("general:Failed to save")}: ${res.msg}`);
+        }
+      })
+      .catch(error => {
+        Setting.showMessage("error", `${i18next.t("general:Failed to connect to server")}: ${error}`);
+      });
+  }
+
   submitPermissionEdit(exitAfterSave) {
     if (this.state.permission.users.length === 0 && this.state.permission.roles.length === 0) {
       Setting.showMessage("error", "The users and roles cannot be empty at the same time");
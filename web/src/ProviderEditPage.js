@@ -42,6 +42,8 @@ const defaultUserMapping = {
   avatarUrl: "avatarUrl",
 };
 
+const samlUserMappingFields = ["id", "username", "displayName", "email", "phone", "countryCode", "avatarUrl"];
+
 const defaultEmailMapping = {
   fromName: "fromName",
   toAddress: "toAddress",
@@ -208,6 +210,62 @@ class ProviderEditPage extends React.Component {
     );
   }
 
+  updateSamlUserMappingField(key, value) {
+    const provider = this.state.provider;
+    if (value === "") {
+      delete provider.userMapping[key];
+    } else {
+      provider.userMapping[key] = value;
+    }
+
+    this.setState({
+      provider: provider,
+    });
+  }
+
+  updateSamlExtraAttributes(values) {
+    const provider = this.state.provider;
+    Object.keys(provider.userMapping).filter(key => !samlUserMappingFields.includes(key)).forEach(key => delete provider.userMapping[key]);
+    values.forEach(value => {
+      provider.userMapping[value] = value;
+    });
+
+    this.setState({
+      provider: provider,
+    });
+  }
+
+  renderSamlUserMappingInput() {
+    const labels = {
+      id: i18next.t("general:ID"),
+      username: i18next.t("signup:Username"),
+      displayName: i18next.t("general:Display name"),
+      email: i18next.t("general:Email"),
+      phone: i18next.t("general:Phone"),
+      countryCode: i18next.t("user:Country code"),
+      avatarUrl: i18next.t("general:Avatar"),
+    };
+
+    return (
+      <React.Fragment>
+        {
+          samlUserMappingFields.map(key => (
+            <React.Fragment key={key}>
+              {Setting.getLabel(labels[key], i18next.t("provider:SAML attribute - Tooltip"))} :
+              <Input value={this.state.provider.userMapping[key]} onChange={e => {
+                this.updateSamlUserMappingField(key, e.target.value);
+              }} />
+            </React.Fragment>
+          ))
+        }
+        {Setting.getLabel(i18next.t("provider:Extra attributes"), i18next.t("provider:Extra attributes - Tooltip"))} :
+        <Select virtual={false} mode="tags" style={{width: "100%"}} value={Object.keys(this.state.provider.userMapping).filter(key => !samlUserMappingFields.includes(key))} onChange={values => {
+          this.updateSamlExtraAttributes(values);
+        }} />
+      </React.Fragment>
+    );
+  }
+
   renderEmailMappingInput() {
     return (
       <React.Fragment>
@@ -510,24 +568,24 @@ class ProviderEditPage extends React.Component {
     this.setState({
       metadataLoading: true,
     });
-    fetch(this.state.requestUrl, {
-      method: "GET",
-    }).then(res => {
-      if (!res.ok) {
-        return Promise.reject("error");
-      }
-      return res.text();
-    }).then(text => {
-      this.updateProviderField("metadata", text);
-      this.parseSamlMetadata();
-      Setting.showMessage("success", i18next.t("general:Successfully added"));
-    }).catch(err => {
-      Setting.showMessage("error", err.message);
-    }).finally(() => {
-      this.setState({
-        metadataLoading: false,
+    ProviderBackend.fetchSamlIdpMetadata(this.state.provider)
+      .then((res) => {
+        if (res.status === "ok") {
+          const provider = res.data;
+          provider.userMapping = this.state.provider.userMapping;
+          this.setState({
+            provider: provider,
+          });
+          Setting.showMessage("success", i18next.t("provider:Found IdPs in metadata").replace("%s", res.data2));
+        } else {
+          Setting.showMessage("error", res.msg);
+        }
+      })
+      .finally(() => {
+        this.setState({
+          metadataLoading: false,
+        });
       });
-    });
   }
 
   parseSamlMetadata() {
@@ -1390,21 +1448,72 @@ class ProviderEditPage extends React.Component {
                   }} />
                 </Col>
               </Row>
+              {
+                !this.state.provider.enableSignAuthnRequest ? null : (
+                  <Row style={{marginTop: "20px"}} >
+                    <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+                      {Setting.getLabel(i18next.t("general:Cert"), i18next.t("provider:Signing cert - Tooltip"))} :
+                    </Col>
+                    <Col span={22} >
+                      <Select virtual={false} style={{width: "100%"}} value={this.state.provider.cert} onChange={(value => {this.updateProviderField("cert", value);})}
+                        options={[{value: "", label: i18next.t("general:Default")}, ...this.state.certs.map((cert) => Setting.getOption(cert.name, cert.name))]} />
+                    </Col>
+                  </Row>
+                )
+              }
               <Row style={{marginTop: "20px"}} >
                 <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
                   {Setting.getLabel(i18next.t("provider:Metadata url"), i18next.t("provider:Metadata url - Tooltip"))} :
                 </Col>
                 <Col span={6} >
-                  <Input value={this.state.requestUrl} onChange={e => {
-                    this.setState({
-                      requestUrl: e.target.value,
-                    });
+                  <Input value={this.state.provider.metadataUrl} onChange={e => {
+                    this.updateProviderField("metadataUrl", e.target.value);
                   }} />
                 </Col>
                 <Col span={16} >
                   <Button style={{marginLeft: "10px"}} type="primary" loading={this.state.metadataLoading} onClick={() => {this.fetchSamlMetadata();}}>{i18next.t("general:Request")}</Button>
                 </Col>
               </Row>
+              <Row style={{marginTop: "20px"}} >
+                <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+                  {Setting.getLabel(i18next.t("provider:Metadata refresh interval"), i18next.t("provider:Metadata refresh interval - Tooltip"))} :
+                </Col>
+                <Col span={22} >
+                  <InputNumber min={0} value={this.state.provider.metadataRefreshInterval} addonAfter={i18next.t("provider:Hours")} onChange={value => {
+                    this.updateProviderField("metadataRefreshInterval", value);
+                  }} />
+                </Col>
+              </Row>
+              <Row style={{marginTop: "20px"}} >
+                <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+                  {Setting.getLabel(i18next.t("provider:Metadata certificate"), i18next.t("provider:Metadata certificate - Tooltip"))} :
+                </Col>
+                <Col span={22}>
+                  <TextArea rows={4} value={this.state.provider.metadataCertificate} onChange={e => {
+                    this.updateProviderField("metadataCertificate", e.target.value);
+                  }} />
+                </Col>
+              </Row>
+              <Row style={{marginTop: "20px"}} >
+                <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+                  {Setting.getLabel(i18next.t("provider:Metadata TLS pin"), i18next.t("provider:Metadata TLS pin - Tooltip"))} :
+                </Col>
+                <Col span={22} >
+                  <Input value={this.state.provider.metadataTlsPin} onChange={e => {
+                    this.updateProviderField("metadataTlsPin", e.target.value);
+                  }} />
+                </Col>
+              </Row>
+              <Row style={{marginTop: "20px"}} >
+                <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+                  {Setting.getLabel(i18next.t("provider:Enable IdP discovery"), i18next.t("provider:Enable IdP discovery - Tooltip"))} :
+                </Col>
+                <Col span={22} >
+                  <Switch checked={this.state.provider.enableIdpDiscovery} onChange={checked => {
+                    this.updateProviderField("enableIdpDiscovery", checked);
+                  }} />
+                </Col>
+              </Row>
               <Row style={{marginTop: "20px"}} >
                 <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
                   {Setting.getLabel(i18next.t("provider:Metadata"), i18next.t("provider:Metadata - Tooltip"))} :
@@ -1438,7 +1547,7 @@ class ProviderEditPage extends React.Component {
                   {Setting.getLabel(i18next.t("provider:IdP"), i18next.t("provider:IdP certificate"))} :
                 </Col>
                 <Col span={22} >
-                  <Input value={this.state.provider.idP} onChange={e => {
+                  <TextArea autoSize={{minRows: 1, maxRows: 10}} value={this.state.provider.idP} onChange={e => {
                     this.updateProviderField("idP", e.target.value);
                   }} />
                 </Col>
@@ -1453,6 +1562,14 @@ class ProviderEditPage extends React.Component {
                   }} />
                 </Col>
               </Row>
+              <Row style={{marginTop: "20px"}} >
+                <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+                  {Setting.getLabel(i18next.t("provider:User mapping"), i18next.t("provider:User mapping - Tooltip"))} :
+                </Col>
+                <Col span={22} >
+                  {this.renderSamlUserMappingInput()}
+                </Col>
+              </Row>
               <Row style={{marginTop: "20px"}} >
                 <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
                   {Setting.getLabel(i18next.t("provider:SP ACS URL"), i18next.t("provider:SP ACS URL - Tooltip"))} :
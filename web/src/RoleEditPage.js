@@ -20,6 +20,7 @@ import * as GroupBackend from "./backend/GroupBackend";
 import * as RoleBackend from "./backend/RoleBackend";
 import * as Setting from "./Setting";
 import i18next from "i18next";
+import AccessGrantTable from "./table/AccessGrantTable";
 
 class RoleEditPage extends React.Component {
   constructor(props) {
@@ -214,6 +215,19 @@ class RoleEditPage extends React.Component {
               } />
           </Col>
         </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("role:Grants"), i18next.t("role:Grants - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <AccessGrantTable
+              title={i18next.t("role:Grants")}
+              table={this.state.role.grants}
+              members={[...(this.state.role.users ?? []), ...(this.state.role.groups ?? []), ...(this.state.role.roles ?? [])]}
+              onUpdateTable={(value) => {this.updateRoleField("grants", value);}}
+            />
+          </Col>
+        </Row>
         <Row style={{marginTop: "20px"}} >
           <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
             {Setting.getLabel(i18next.t("role:Sub domains"), i18next.t("role:Sub domains - Tooltip"))} :
@@ -26,7 +26,7 @@ import * as Conf from "./Conf";
 import * as phoneNumber from "libphonenumber-js";
 import moment from "moment";
 import {MfaAuthVerifyForm, NextMfa, RequiredMfa} from "./auth/mfa/MfaAuthVerifyForm";
-import {EmailMfaType, SmsMfaType, TotpMfaType} from "./auth/MfaSetupPage";
+import {EmailMfaType, PushMfaType, SmsMfaType, TotpMfaType, WebAuthnMfaType} from "./auth/MfaSetupPage";
 import GithubImg from "./static/social-github.png";
 
 const {Option} = Select;
@@ -1542,7 +1542,7 @@ export function getUserCommonFields() {
   return ["Owner", "Name", "CreatedTime", "UpdatedTime", "DeletedTime", "Id", "Type", "Password", "PasswordSalt", "DisplayName", "FirstName", "LastName", "Avatar", "PermanentAvatar",
     "Email", "EmailVerified", "Phone", "Location", "Address", "Affiliation", "Title", "IdCardType", "IdCard", "Homepage", "Bio", "Tag", "Region",
     "Language", "Gender", "Birthday", "Education", "Score", "Ranking", "IsDefaultAvatar", "IsOnline", "IsAdmin", "IsForbidden", "IsDeleted", "CreatedIp",
-    "PreferredMfaType", "TotpSecret", "SignupApplication", "RecoveryCodes", "MfaPhoneEnabled", "MfaEmailEnabled"];
+    "PreferredMfaType", "TotpSecret", "SignupApplication", "RecoveryCodes", "MfaPhoneEnabled", "MfaEmailEnabled", "MfaWebauthnEnabled", "MfaPushEnabled", "MfaPushProvider"];
 }
 
 export function getDefaultFooterContent() {
@@ -1706,6 +1706,8 @@ function renderMfaAuthVerifyForm(values, authParams, onSuccess, componentThis) {
             case SmsMfaType: mfaI18n = i18next.t("mfa:Use SMS"); break;
             case TotpMfaType: mfaI18n = i18next.t("mfa:Use Authenticator App"); break ;
             case EmailMfaType: mfaI18n = i18next.t("mfa:Use Email") ;break;
+            case WebAuthnMfaType: mfaI18n = i18next.t("mfa:Use WebAuthn"); break;
+            case PushMfaType: mfaI18n = i18next.t("mfa:Use push notification"); break;
             }
             return <div key={mfa.mfaType}><Button type={"link"} onClick={() => {
               componentThis.setState({
@@ -240,12 +240,72 @@ class SyncerEditPage extends React.Component {
               });
             })}>
               {
-                ["Database", "Keycloak"]
+                ["Database", "Keycloak", "REST", "CSV", "LDAP", "Policy"]
                   .map((item, index) => <Option key={index} value={item}>{item}</Option>)
               }
             </Select>
           </Col>
         </Row>
+        {
+          (this.state.syncer.type !== "Policy" && this.state.syncer.type !== "CSV") ? null : (
+            <Row style={{marginTop: "20px"}} >
+              <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+                {Setting.getLabel(i18next.t("syncer:Directory"), i18next.t("syncer:Directory - Tooltip"))} :
+              </Col>
+              <Col span={22} >
+                <Input value={this.state.syncer.directory} onChange={e => {
+                  this.updateSyncerField("directory", e.target.value);
+                }} />
+              </Col>
+            </Row>
+          )
+        }
+        {
+          this.state.syncer.type !== "REST" ? null : (
+            <React.Fragment>
+              <Row style={{marginTop: "20px"}} >
+                <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+                  {Setting.getLabel(i18next.t("syncer:Auth header"), i18next.t("syncer:Auth header - Tooltip"))} :
+                </Col>
+                <Col span={22} >
+                  <Input value={this.state.syncer.authHeader} onChange={e => {
+                    this.updateSyncerField("authHeader", e.target.value);
+                  }} />
+                </Col>
+              </Row>
+              <Row style={{marginTop: "20px"}} >
+                <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+                  {Setting.getLabel(i18next.t("syncer:Data path"), i18next.t("syncer:Data path - Tooltip"))} :
+                </Col>
+                <Col span={22} >
+                  <Input value={this.state.syncer.dataPath} onChange={e => {
+                    this.updateSyncerField("dataPath", e.target.value);
+                  }} />
+                </Col>
+              </Row>
+              <Row style={{marginTop: "20px"}} >
+                <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+                  {Setting.getLabel(i18next.t("syncer:Page param"), i18next.t("syncer:Page param - Tooltip"))} :
+                </Col>
+                <Col span={22} >
+                  <Input value={this.state.syncer.pageParam} onChange={e => {
+                    this.updateSyncerField("pageParam", e.target.value);
+                  }} />
+                </Col>
+              </Row>
+              <Row style={{marginTop: "20px"}} >
+                <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+                  {Setting.getLabel(i18next.t("syncer:Next path"), i18next.t("syncer:Next path - Tooltip"))} :
+                </Col>
+                <Col span={22} >
+                  <Input value={this.state.syncer.nextPath} onChange={e => {
+                    this.updateSyncerField("nextPath", e.target.value);
+                  }} />
+                </Col>
+              </Row>
+            </React.Fragment>
+          )
+        }
         <Row style={{marginTop: "20px"}} >
           <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
             {Setting.getLabel(i18next.t("syncer:Database type"), i18next.t("syncer:Database type - Tooltip"))} :
@@ -522,6 +582,39 @@ class SyncerEditPage extends React.Component {
             </div>
           </Col>
         </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("syncer:Conflict policy"), i18next.t("syncer:Conflict policy - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Select virtual={false} style={{width: "100%"}} value={this.state.syncer.conflictPolicy || "SourceWins"} onChange={(value => {this.updateSyncerField("conflictPolicy", value);})}>
+              {
+                [
+                  {id: "SourceWins", name: i18next.t("syncer:Source wins")},
+                  {id: "CasdoorWins", name: i18next.t("syncer:Casdoor wins")},
+                  {id: "NewestWins", name: i18next.t("syncer:Newest wins")},
+                  {id: "FieldOwnership", name: i18next.t("syncer:Field ownership")},
+                ].map((item, index) => <Option key={index} value={item.id}>{item.name}</Option>)
+              }
+            </Select>
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("syncer:Deletion policy"), i18next.t("syncer:Deletion policy - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Select virtual={false} style={{width: "100%"}} value={this.state.syncer.deletionPolicy || "Ignore"} onChange={(value => {this.updateSyncerField("deletionPolicy", value);})}>
+              {
+                [
+                  {id: "Ignore", name: i18next.t("syncer:Ignore")},
+                  {id: "Disable", name: i18next.t("syncer:Disable")},
+                  {id: "Delete", name: i18next.t("general:Delete")},
+                ].map((item, index) => <Option key={index} value={item.id}>{item.name}</Option>)
+              }
+            </Select>
+          </Col>
+        </Row>
         <Row style={{marginTop: "20px"}} >
           <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 19 : 2}>
             {Setting.getLabel(i18next.t("syncer:Is read-only"), i18next.t("syncer:Is read-only - Tooltip"))} :
@@ -103,6 +103,24 @@ class SyncerListPage extends BaseListPage {
       });
   }
 
+  dryRunSyncer(i) {
+    this.setState({loading: true});
+    SyncerBackend.dryRunSyncer("admin", this.state.data[i].name)
+      .then((res) => {
+        this.setState({loading: false});
+        if (res.status === "ok") {
+          const run = res.data;
+          Setting.showMessage("success", `${i18next.t("syncer:Dry run")}: ${i18next.t("syncer:Added")} ${run.added + run.sourceAdded}, ${i18next.t("syncer:Updated")} ${run.updated + run.sourceUpdated}, ${i18next.t("syncer:Removed")} ${run.disabled + run.deleted}, ${i18next.t("syncer:Conflicts")} ${run.conflicts}`);
+        } else {
+          Setting.showMessage("error", `${i18next.t("general:Failed to sync")}: ${res.msg}`);
+        }
+      })
+      .catch(error => {
+        this.setState({loading: false});
+        Setting.showMessage("error", `${i18next.t("general:Failed to connect to server")}: ${error}`);
+      });
+  }
+
   renderTable(syncers) {
     const columns = [
       {
@@ -235,12 +253,13 @@ class SyncerListPage extends BaseListPage {
         title: i18next.t("general:Action"),
         dataIndex: "",
         key: "op",
-        width: "240px",
+        width: "320px",
         fixed: (Setting.isMobile()) ? "false" : "right",
         render: (text, record, index) => {
           return (
             <div>
               <Button style={{marginTop: "10px", marginBottom: "10px", marginRight: "10px"}} type="primary" onClick={() => this.runSyncer(index)}>{i18next.t("general:Sync")}</Button>
+              <Button style={{marginTop: "10px", marginBottom: "10px", marginRight: "10px"}} onClick={() => this.dryRunSyncer(index)}>{i18next.t("syncer:Dry run")}</Button>
               <Button style={{marginTop: "10px", marginBottom: "10px", marginRight: "10px"}} onClick={() => this.props.history.push(`/syncers/${record.name}`)}>{i18next.t("general:Edit")}</Button>
               <PopconfirmModal
                 title={i18next.t("general:Sure to delete") + `: ${record.name} ?`}
@@ -121,8 +121,18 @@ export function unlink(values) {
   }).then(res => res.json());
 }
 
-export function getSamlLogin(providerId, relayState) {
-  return fetch(`${authConfig.serverUrl}/api/get-saml-login?id=${providerId}&relayState=${relayState}`, {
+export function getSamlLogin(providerId, relayState, entityId = "") {
+  return fetch(`${authConfig.serverUrl}/api/get-saml-login?id=${providerId}&relayState=${relayState}&entityId=${encodeURIComponent(entityId)}`, {
+    method: "GET",
+    credentials: "include",
+    headers: {
+      "Accept-Language": Setting.getAcceptLanguage(),
+    },
+  }).then(res => res.json());
+}
+
+export function getSamlIdps(providerId, query) {
+  return fetch(`${authConfig.serverUrl}/api/get-saml-idps?id=${providerId}&query=${encodeURIComponent(query)}`, {
     method: "GET",
     credentials: "include",
     headers: {
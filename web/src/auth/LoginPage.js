@@ -534,6 +534,9 @@ class LoginPage extends React.Component {
                   redirectUrl: res.data2.redirectUrl,
                   relayState: oAuthParams.relayState,
                 });
+              } else if (res.data2.method === "Artifact") {
+                const redirectUri = res.data2.redirectUrl;
+                Setting.goToLink(`${redirectUri}${redirectUri.includes("?") ? "&" : "?"}SAMLart=${encodeURIComponent(res.data)}&RelayState=${oAuthParams.relayState}`);
               } else {
                 const SAMLResponse = res.data;
                 const redirectUri = res.data2.redirectUrl;
@@ -0,0 +1,91 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+import React from "react";
+import {Button, Card, Descriptions, Input, Result, Space} from "antd";
+import i18next from "i18next";
+import * as MfaBackend from "../backend/MfaBackend";
+import * as Setting from "../Setting";
+
+class MfaPushPage extends React.Component {
+  constructor(props) {
+    super(props);
+    this.state = {
+      token: props.match.params.token,
+      challenge: null,
+      number: "",
+      msg: "",
+    };
+  }
+
+  componentDidMount() {
+    this.getChallenge();
+  }
+
+  getChallenge() {
+    MfaBackend.getMfaPushChallenge(this.state.token).then((res) => {
+      if (res.status === "ok") {
+        this.setState({challenge: res.data});
+      } else {
+        this.setState({msg: res.msg});
+      }
+    });
+  }
+
+  respond(action, number = "") {
+    MfaBackend.respondMfaPush(this.state.token, action, number).then((res) => {
+      if (res.status !== "ok") {
+        Setting.showMessage("error", res.msg);
+      }
+      this.getChallenge();
+    });
+  }
+
+  render() {
+    if (this.state.challenge === null) {
+      return this.state.msg === "" ? null : (
+        <Result status="warning" title={this.state.msg} />
+      );
+    }
+
+    const challenge = this.state.challenge;
+    if (challenge.status !== "Pending") {
+      return (
+        <Result status={challenge.status === "Approved" ? "success" : "warning"} title={`${i18next.t("mfa:Push request")}: ${challenge.status}`} />
+      );
+    }
+
+    return (
+      <Card title={i18next.t("mfa:Push request")} style={{maxWidth: 480, margin: "40px auto"}}>
+        <Descriptions column={1} bordered size="small">
+          <Descriptions.Item label={i18next.t("general:User")}>{`${challenge.owner}/${challenge.name}`}</Descriptions.Item>
+          <Descriptions.Item label={i18next.t("general:Client IP")}>{challenge.clientIp}</Descriptions.Item>
+          <Descriptions.Item label={i18next.t("mfa:Device")}>{challenge.userAgent}</Descriptions.Item>
+          <Descriptions.Item label={i18next.t("general:Created time")}>{Setting.getFormattedDate(challenge.createdTime)}</Descriptions.Item>
+        </Descriptions>
+        <p style={{marginTop: 24}}>{i18next.t("mfa:Type the number shown on the sign-in screen to approve")}</p>
+        <Space style={{marginBottom: 24}}>
+          <Input size="large" style={{width: 120}} maxLength={2} value={this.state.number} onChange={e => this.setState({number: e.target.value})} onPressEnter={() => this.respond("approve", this.state.number)} />
+          <Button size="large" type="primary" disabled={this.state.number === ""} onClick={() => this.respond("approve", this.state.number)}>{i18next.t("mfa:Approve")}</Button>
+        </Space>
+        <Space>
+          <Button onClick={() => this.respond("deny")}>{i18next.t("mfa:Deny")}</Button>
+          <Button danger onClick={() => this.respond("fraud")}>{i18next.t("mfa:This was not me, report fraud")}</Button>
+        </Space>
+      </Card>
+    );
+  }
+}
+
+export default MfaPushPage;
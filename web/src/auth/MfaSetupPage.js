@@ -28,6 +28,8 @@ export const EmailMfaType = "email";
 export const SmsMfaType = "sms";
 export const TotpMfaType = "app";
 export const RecoveryMfaType = "recovery";
+export const WebAuthnMfaType = "webauthn";
+export const PushMfaType = "push";
 
 class MfaSetupPage extends React.Component {
   constructor(props) {
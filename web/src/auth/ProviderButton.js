@@ -15,6 +15,7 @@
 import React from "react";
 import i18next from "i18next";
 import * as Provider from "./Provider";
+import {chooseSamlIdp} from "./SamlIdpDiscovery";
 import {getProviderLogoURL} from "../Setting";
 import {GithubLoginButton, GoogleLoginButton} from "react-social-login-buttons";
 import QqLoginButton from "./QqLoginButton";
@@ -119,17 +120,25 @@ function goToSamlUrl(provider, location) {
   const providerName = provider.name;
 
   const relayState = `${clientId}&${state}&${providerName}&${realRedirectUri}&${redirectUri}`;
-  AuthBackend.getSamlLogin(`${provider.owner}/${providerName}`, btoa(relayState)).then((res) => {
-    if (res.status === "ok") {
-      if (res.data2 === "POST") {
-        document.write(res.data);
+  const login = (entityId) => {
+    AuthBackend.getSamlLogin(`${provider.owner}/${providerName}`, btoa(relayState), entityId).then((res) => {
+      if (res.status === "ok") {
+        if (res.data2 === "POST") {
+          document.write(res.data);
+        } else {
+          window.location.href = res.data;
+        }
       } else {
-        window.location.href = res.data;
+        Setting.showMessage("error", res.msg);
       }
-    } else {
-      Setting.showMessage("error", res.msg);
-    }
-  });
+    });
+  };
+
+  if (provider.enableIdpDiscovery) {
+    chooseSamlIdp(provider, login);
+  } else {
+    login("");
+  }
 }
 
 export function goToWeb3Url(application, provider, method) {
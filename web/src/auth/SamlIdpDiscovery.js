@@ -0,0 +1,90 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+import React from "react";
+import {Modal, Select} from "antd";
+import i18next from "i18next";
+import * as AuthBackend from "./AuthBackend";
+import * as Setting from "../Setting";
+
+class SamlIdpSelect extends React.Component {
+  constructor(props) {
+    super(props);
+    this.state = {
+      idps: [],
+      loading: false,
+    };
+  }
+
+  componentDidMount() {
+    this.searchIdps("");
+  }
+
+  searchIdps(query) {
+    this.setState({
+      loading: true,
+    });
+    AuthBackend.getSamlIdps(this.props.providerId, query)
+      .then((res) => {
+        if (res.status === "ok") {
+          this.setState({
+            idps: res.data,
+          });
+        } else {
+          Setting.showMessage("error", res.msg);
+        }
+      })
+      .finally(() => {
+        this.setState({
+          loading: false,
+        });
+      });
+  }
+
+  render() {
+    return (
+      <Select virtual={false} showSearch style={{width: "100%"}} filterOption={false} loading={this.state.loading}
+        placeholder={i18next.t("login:Search for your organization")}
+        onSearch={value => this.searchIdps(value)} onChange={value => this.props.onChange(value)}
+        options={this.state.idps.map((idp) => ({
+          value: idp.entityId,
+          label: (
+            <span>
+              {idp.logo ? <img width={20} height={20} src={idp.logo} alt={idp.displayName} style={{marginRight: "8px"}} /> : null}
+              {idp.displayName}
+            </span>
+          ),
+        }))} />
+    );
+  }
+}
+
+// chooseSamlIdp asks the user which IdP of a federation to sign in with, the entity ID of the IdP is passed to the callback
+export function chooseSamlIdp(provider, callback) {
+  let entityId = "";
+  Modal.confirm({
+    title: i18next.t("login:Choose your organization"),
+    icon: null,
+    content: <SamlIdpSelect providerId={`${provider.owner}/${provider.name}`} onChange={value => {entityId = value;}} />,
+    okText: i18next.t("login:Continue"),
+    cancelText: i18next.t("general:Cancel"),
+    onOk: () => {
+      if (entityId === "") {
+        Setting.showMessage("error", i18next.t("login:Choose your organization"));
+        return Promise.reject();
+      }
+      callback(entityId);
+    },
+  });
+}
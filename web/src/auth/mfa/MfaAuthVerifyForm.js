@@ -16,10 +16,12 @@ import React, {Fragment, useState} from "react";
 import i18next from "i18next";
 import {Button, Input} from "antd";
 import * as AuthBackend from "../AuthBackend";
-import {EmailMfaType, RecoveryMfaType, SmsMfaType} from "../MfaSetupPage";
+import * as UserWebauthnBackend from "../../backend/UserWebauthnBackend";
+import {EmailMfaType, PushMfaType, RecoveryMfaType, SmsMfaType, WebAuthnMfaType} from "../MfaSetupPage";
 import {mfaAuth} from "./MfaVerifyForm";
 import MfaVerifySmsForm from "./MfaVerifySmsForm";
 import MfaVerifyTotpForm from "./MfaVerifyTotpForm";
+import MfaVerifyPushForm from "./MfaVerifyPushForm";
 
 export const NextMfa = "NextMfa";
 export const RequiredMfa = "RequiredMfa";
@@ -84,6 +86,19 @@ export function MfaAuthVerifyForm({formValues, authParams, mfaProps, application
               application={application}
             />
           </Fragment>
+        ) : mfaProps.mfaType === PushMfaType ? (
+          <MfaVerifyPushForm
+            onFinish={verify}
+            onFail={onFail}
+          />
+        ) : mfaProps.mfaType === WebAuthnMfaType ? (
+          <Button style={{width: "100%", marginBottom: 20}} size={"large"} loading={loading}
+            type={"primary"} onClick={() => {
+              UserWebauthnBackend.getWebauthnMfaPasscode()
+                .then(passcode => verify({passcode}))
+                .catch(error => onFail(error.message));
+            }}>{i18next.t("forget:Verify")}
+          </Button>
         ) : (
           <Fragment>
             <div style={{marginBottom: 24}}>
@@ -13,7 +13,7 @@ export function MfaEnableForm({user, mfaType, secret, recoveryCodes, dest, count
       countryCode,
       ...user,
     };
-    data["recoveryCodes"] = recoveryCodes[0];
+    data["recoveryCodes"] = recoveryCodes.join(",");
     setLoading(true);
     MfaBackend.MfaSetupEnable(data).then(res => {
       if (res.status === "ok") {
@@ -31,7 +31,11 @@ export function MfaEnableForm({user, mfaType, secret, recoveryCodes, dest, count
     <div style={{width: "400px"}}>
       <p>{i18next.t("mfa:Please save this recovery code. Once your device cannot provide an authentication code, you can reset mfa authentication by this recovery code")}</p>
       <br />
-      <code style={{fontStyle: "solid"}}>{recoveryCodes[0]}</code>
+      {recoveryCodes.map(recoveryCode => (
+        <div key={recoveryCode}>
+          <code style={{fontStyle: "solid"}}>{recoveryCode}</code>
+        </div>
+      ))}
       <Button style={{marginTop: 24}} loading={loading} onClick={() => {
         requestEnableMfa();
       }} block type="primary">
@@ -12,14 +12,16 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-import {Form} from "antd";
+import {Button, Form} from "antd";
 import i18next from "i18next";
 import * as MfaBackend from "../../backend/MfaBackend";
+import * as UserWebauthnBackend from "../../backend/UserWebauthnBackend";
 import * as Setting from "../../Setting";
 import React from "react";
-import {EmailMfaType, SmsMfaType, TotpMfaType} from "../MfaSetupPage";
+import {EmailMfaType, PushMfaType, SmsMfaType, TotpMfaType, WebAuthnMfaType} from "../MfaSetupPage";
 import MfaVerifySmsForm from "./MfaVerifySmsForm";
 import MfaVerifyTotpForm from "./MfaVerifyTotpForm";
+import MfaVerifyPushForm from "./MfaVerifyPushForm";
 
 export const mfaAuth = "mfaAuth";
 export const mfaSetup = "mfaSetup";
@@ -54,6 +56,26 @@ export function MfaVerifyForm({mfaProps, application, user, onSuccess, onFail})
     return <MfaVerifySmsForm mfaProps={mfaProps} onFinish={onFinish} application={application} method={mfaSetup} user={user} />;
   } else if (mfaProps.mfaType === TotpMfaType) {
     return <MfaVerifyTotpForm mfaProps={mfaProps} onFinish={onFinish} />;
+  } else if (mfaProps.mfaType === PushMfaType) {
+    // the push goes through the first notification provider of the application, or the companion app if there is none
+    const provider = application.providers?.find(providerItem => providerItem.provider?.category === "Notification")?.provider;
+    const dest = provider ? `${provider.owner}/${provider.name}` : "";
+    return <MfaVerifyPushForm dest={dest} onFinish={({passcode}) => onFinish({passcode, dest})} onFail={msg => onFail({msg: msg})} />;
+  } else if (mfaProps.mfaType === WebAuthnMfaType) {
+    return (
+      <Button style={{width: "300px", marginTop: 24}} type="primary" onClick={() => {
+        const register = user.webauthnCredentials?.length > 0 ? Promise.resolve({status: "ok"}) : UserWebauthnBackend.registerWebauthnCredential();
+        register.then((res) => {
+          if (res.status !== "ok") {
+            throw new Error(res.msg);
+          }
+          return UserWebauthnBackend.getWebauthnMfaPasscode();
+        }).then(passcode => onFinish({passcode}))
+          .catch(error => onFail({msg: error.message}));
+      }}>
+        {i18next.t("forget:Verify")}
+      </Button>
+    );
   } else {
     return <div></div>;
   }
@@ -0,0 +1,70 @@
+import {Button} from "antd";
+import i18next from "i18next";
+import React, {useEffect, useRef, useState} from "react";
+import * as MfaBackend from "../../backend/MfaBackend";
+
+export const MfaVerifyPushForm = ({dest, onFinish, onFail}) => {
+  const [loading, setLoading] = useState(false);
+  const [number, setNumber] = useState("");
+  const timer = useRef(null);
+
+  useEffect(() => {
+    return () => clearInterval(timer.current);
+  }, []);
+
+  const pollStatus = (id) => {
+    clearInterval(timer.current);
+    timer.current = setInterval(() => {
+      MfaBackend.getMfaPushStatus(id).then((res) => {
+        if (res.status !== "ok") {
+          clearInterval(timer.current);
+          setLoading(false);
+          onFail(res.msg);
+        } else if (res.data === "Approved") {
+          clearInterval(timer.current);
+          onFinish({passcode: id});
+        } else if (res.data !== "Pending") {
+          clearInterval(timer.current);
+          setLoading(false);
+          setNumber("");
+          onFail(`${i18next.t("mfa:Push request")}: ${res.data}`);
+        }
+      });
+    }, 2000);
+  };
+
+  const sendPush = () => {
+    setLoading(true);
+    MfaBackend.sendMfaPush(dest).then((res) => {
+      if (res.status === "ok") {
+        setNumber(res.data2);
+        pollStatus(res.data);
+      } else {
+        setLoading(false);
+        onFail(res.msg);
+      }
+    });
+  };
+
+  return (
+    <div style={{width: "300px"}}>
+      {number !== "" ? (
+        <div style={{marginBottom: 24, textAlign: "center"}}>
+          <p>{i18next.t("mfa:Type this number on your device to approve the sign-in")}</p>
+          <div style={{fontSize: "48px", fontWeight: "bold"}}>{number}</div>
+        </div>
+      ) : null}
+      <Button
+        style={{marginTop: 24}}
+        loading={loading}
+        block
+        type="primary"
+        onClick={sendPush}
+      >
+        {i18next.t("mfa:Send push notification")}
+      </Button>
+    </div>
+  );
+};
+
+export default MfaVerifyPushForm;
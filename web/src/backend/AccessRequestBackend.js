@@ -0,0 +1,99 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+import * as Setting from "../Setting";
+
+export function getAccessRequests(owner, page = "", pageSize = "", field = "", value = "", sortField = "", sortOrder = "") {
+  return fetch(`${Setting.ServerUrl}/api/get-access-requests?owner=${owner}&p=${page}&pageSize=${pageSize}&field=${field}&value=${value}&sortField=${sortField}&sortOrder=${sortOrder}`, {
+    method: "GET",
+    credentials: "include",
+    headers: {
+      "Accept-Language": Setting.getAcceptLanguage(),
+    },
+  }).then(res => res.json());
+}
+
+export function getAccessRequest(owner, name) {
+  return fetch(`${Setting.ServerUrl}/api/get-access-request?id=${owner}/${encodeURIComponent(name)}`, {
+    method: "GET",
+    credentials: "include",
+    headers: {
+      "Accept-Language": Setting.getAcceptLanguage(),
+    },
+  }).then(res => res.json());
+}
+
+export function getMyAccessRequests() {
+  return fetch(`${Setting.ServerUrl}/api/get-my-access-requests`, {
+    method: "GET",
+    credentials: "include",
+    headers: {
+      "Accept-Language": Setting.getAcceptLanguage(),
+    },
+  }).then(res => res.json());
+}
+
+export function getPendingAccessRequests() {
+  return fetch(`${Setting.ServerUrl}/api/get-pending-access-requests`, {
+    method: "GET",
+    credentials: "include",
+    headers: {
+      "Accept-Language": Setting.getAcceptLanguage(),
+    },
+  }).then(res => res.json());
+}
+
+export function submitAccessRequest(request) {
+  const newRequest = Setting.deepCopy(request);
+  return fetch(`${Setting.ServerUrl}/api/submit-access-request`, {
+    method: "POST",
+    credentials: "include",
+    body: JSON.stringify(newRequest),
+    headers: {
+      "Accept-Language": Setting.getAcceptLanguage(),
+    },
+  }).then(res => res.json());
+}
+
+export function reviewAccessRequest(owner, name, action, comment = "") {
+  return fetch(`${Setting.ServerUrl}/api/review-access-request?id=${owner}/${encodeURIComponent(name)}&action=${action}&comment=${encodeURIComponent(comment)}`, {
+    method: "POST",
+    credentials: "include",
+    headers: {
+      "Accept-Language": Setting.getAcceptLanguage(),
+    },
+  }).then(res => res.json());
+}
+
+export function cancelAccessRequest(owner, name) {
+  return fetch(`${Setting.ServerUrl}/api/cancel-access-request?id=${owner}/${encodeURIComponent(name)}`, {
+    method: "POST",
+    credentials: "include",
+    headers: {
+      "Accept-Language": Setting.getAcceptLanguage(),
+    },
+  }).then(res => res.json());
+}
+
+export function deleteAccessRequest(request) {
+  const newRequest = Setting.deepCopy(request);
+  return fetch(`${Setting.ServerUrl}/api/delete-access-request`, {
+    method: "POST",
+    credentials: "include",
+    body: JSON.stringify(newRequest),
+    headers: {
+      "Accept-Language": Setting.getAcceptLanguage(),
+    },
+  }).then(res => res.json());
+}
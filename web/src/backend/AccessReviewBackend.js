@@ -0,0 +1,125 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+import * as Setting from "../Setting";
+
+export function getAccessReviews(owner, page = "", pageSize = "", field = "", value = "", sortField = "", sortOrder = "") {
+  return fetch(`${Setting.ServerUrl}/api/get-access-reviews?owner=${owner}&p=${page}&pageSize=${pageSize}&field=${field}&value=${value}&sortField=${sortField}&sortOrder=${sortOrder}`, {
+    method: "GET",
+    credentials: "include",
+    headers: {
+      "Accept-Language": Setting.getAcceptLanguage(),
+    },
+  }).then(res => res.json());
+}
+
+export function getAccessReview(owner, name) {
+  return fetch(`${Setting.ServerUrl}/api/get-access-review?id=${owner}/${encodeURIComponent(name)}`, {
+    method: "GET",
+    credentials: "include",
+    headers: {
+      "Accept-Language": Setting.getAcceptLanguage(),
+    },
+  }).then(res => res.json());
+}
+
+export function updateAccessReview(owner, name, review) {
+  const newReview = Setting.deepCopy(review);
+  return fetch(`${Setting.ServerUrl}/api/update-access-review?id=${owner}/${encodeURIComponent(name)}`, {
+    method: "POST",
+    credentials: "include",
+    body: JSON.stringify(newReview),
+    headers: {
+      "Accept-Language": Setting.getAcceptLanguage(),
+    },
+  }).then(res => res.json());
+}
+
+export function addAccessReview(review) {
+  const newReview = Setting.deepCopy(review);
+  return fetch(`${Setting.ServerUrl}/api/add-access-review`, {
+    method: "POST",
+    credentials: "include",
+    body: JSON.stringify(newReview),
+    headers: {
+      "Accept-Language": Setting.getAcceptLanguage(),
+    },
+  }).then(res => res.json());
+}
+
+export function deleteAccessReview(review) {
+  const newReview = Setting.deepCopy(review);
+  return fetch(`${Setting.ServerUrl}/api/delete-access-review`, {
+    method: "POST",
+    credentials: "include",
+    body: JSON.stringify(newReview),
+    headers: {
+      "Accept-Language": Setting.getAcceptLanguage(),
+    },
+  }).then(res => res.json());
+}
+
+export function startAccessReview(owner, name) {
+  return fetch(`${Setting.ServerUrl}/api/start-access-review?id=${owner}/${encodeURIComponent(name)}`, {
+    method: "POST",
+    credentials: "include",
+    headers: {
+      "Accept-Language": Setting.getAcceptLanguage(),
+    },
+  }).then(res => res.json());
+}
+
+export function completeAccessReview(owner, name) {
+  return fetch(`${Setting.ServerUrl}/api/complete-access-review?id=${owner}/${encodeURIComponent(name)}`, {
+    method: "POST",
+    credentials: "include",
+    headers: {
+      "Accept-Language": Setting.getAcceptLanguage(),
+    },
+  }).then(res => res.json());
+}
+
+export function getAccessReviewItems(owner, name) {
+  return fetch(`${Setting.ServerUrl}/api/get-access-review-items?id=${owner}/${encodeURIComponent(name)}`, {
+    method: "GET",
+    credentials: "include",
+    headers: {
+      "Accept-Language": Setting.getAcceptLanguage(),
+    },
+  }).then(res => res.json());
+}
+
+export function getMyAccessReviewItems() {
+  return fetch(`${Setting.ServerUrl}/api/get-my-access-review-items`, {
+    method: "GET",
+    credentials: "include",
+    headers: {
+      "Accept-Language": Setting.getAcceptLanguage(),
+    },
+  }).then(res => res.json());
+}
+
+export function decideAccessReviewItem(owner, name, decision, comment = "") {
+  return fetch(`${Setting.ServerUrl}/api/decide-access-review-item?id=${owner}/${encodeURIComponent(name)}&decision=${decision}&comment=${encodeURIComponent(comment)}`, {
+    method: "POST",
+    credentials: "include",
+    headers: {
+      "Accept-Language": Setting.getAcceptLanguage(),
+    },
+  }).then(res => res.json());
+}
+
+export function getAccessReviewExportUrl(owner, name, format) {
+  return `${Setting.ServerUrl}/api/export-access-review?id=${owner}/${encodeURIComponent(name)}&format=${format}`;
+}
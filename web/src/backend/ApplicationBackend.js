@@ -98,3 +98,14 @@ export function getSamlMetadata(owner, name, enablePostBinding) {
     },
   }).then(res => res.text());
 }
+
+export function parseSamlSpMetadata(metadata) {
+  return fetch(`${Setting.ServerUrl}/api/parse-saml-sp-metadata`, {
+    method: "POST",
+    credentials: "include",
+    body: metadata,
+    headers: {
+      "Accept-Language": Setting.getAcceptLanguage(),
+    },
+  }).then(res => res.json());
+}
@@ -80,3 +80,39 @@ export function SetPreferredMfa(values) {
     body: formData,
   }).then((res) => res.json());
 }
+
+export function sendMfaPush(dest = "") {
+  const formData = new FormData();
+  formData.append("dest", dest);
+  return fetch(`${Setting.ServerUrl}/api/mfa/push/send`, {
+    method: "POST",
+    credentials: "include",
+    body: formData,
+  }).then(res => res.json());
+}
+
+export function getMfaPushStatus(id) {
+  return fetch(`${Setting.ServerUrl}/api/mfa/push/get-status?id=${encodeURIComponent(id)}`, {
+    method: "GET",
+    credentials: "include",
+  }).then(res => res.json());
+}
+
+export function getMfaPushChallenge(token) {
+  return fetch(`${Setting.ServerUrl}/api/mfa/push/get-challenge?token=${encodeURIComponent(token)}`, {
+    method: "GET",
+    credentials: "include",
+  }).then(res => res.json());
+}
+
+export function respondMfaPush(token, action, number = "") {
+  const formData = new FormData();
+  formData.append("token", token);
+  formData.append("action", action);
+  formData.append("number", number);
+  return fetch(`${Setting.ServerUrl}/api/mfa/push/respond`, {
+    method: "POST",
+    credentials: "include",
+    body: formData,
+  }).then(res => res.json());
+}
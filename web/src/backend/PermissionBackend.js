@@ -79,3 +79,34 @@ export function deletePermission(permission) {
     },
   }).then(res => res.json());
 }
+
+export function requestElevation(permission, duration, reason) {
+  return fetch(`${Setting.ServerUrl}/api/request-elevation`, {
+    method: "POST",
+    credentials: "include",
+    body: JSON.stringify({permission: permission, duration: duration, reason: reason}),
+    headers: {
+      "Accept-Language": Setting.getAcceptLanguage(),
+    },
+  }).then(res => res.json());
+}
+
+export function approveElevation(owner, name) {
+  return fetch(`${Setting.ServerUrl}/api/approve-elevation?id=${owner}/${encodeURIComponent(name)}`, {
+    method: "POST",
+    credentials: "include",
+    headers: {
+      "Accept-Language": Setting.getAcceptLanguage(),
+    },
+  }).then(res => res.json());
+}
+
+export function rejectElevation(owner, name) {
+  return fetch(`${Setting.ServerUrl}/api/reject-elevation?id=${owner}/${encodeURIComponent(name)}`, {
+    method: "POST",
+    credentials: "include",
+    headers: {
+      "Accept-Language": Setting.getAcceptLanguage(),
+    },
+  }).then(res => res.json());
+}
@@ -79,3 +79,15 @@ export function deleteProvider(provider) {
     },
   }).then(res => res.json());
 }
+
+export function fetchSamlIdpMetadata(provider) {
+  const newProvider = Setting.deepCopy(provider);
+  return fetch(`${Setting.ServerUrl}/api/fetch-saml-idp-metadata`, {
+    method: "POST",
+    credentials: "include",
+    body: JSON.stringify(newProvider),
+    headers: {
+      "Accept-Language": Setting.getAcceptLanguage(),
+    },
+  }).then(res => res.json());
+}
@@ -91,3 +91,23 @@ export function runSyncer(owner, name) {
     },
   }).then(res => res.json());
 }
+
+export function dryRunSyncer(owner, name) {
+  return fetch(`${Setting.ServerUrl}/api/dry-run-syncer?id=${owner}/${encodeURIComponent(name)}`, {
+    method: "POST",
+    credentials: "include",
+    headers: {
+      "Accept-Language": Setting.getAcceptLanguage(),
+    },
+  }).then(res => res.json());
+}
+
+export function getSyncerRuns(owner, name, page = "", pageSize = "") {
+  return fetch(`${Setting.ServerUrl}/api/get-syncer-runs?id=${owner}/${encodeURIComponent(name)}&p=${page}&pageSize=${pageSize}`, {
+    method: "GET",
+    credentials: "include",
+    headers: {
+      "Accept-Language": Setting.getAcceptLanguage(),
+    },
+  }).then(res => res.json());
+}
@@ -56,8 +56,76 @@ export function registerWebauthnCredential() {
     });
 }
 
-export function deleteUserWebAuthnCredential(credentialID) {
+// Returns the JSON encoded assertion of a registered credential, used as the passcode of the WebAuthn MFA
+export function getWebauthnMfaPasscode() {
+  return fetch(`${Setting.ServerUrl}/api/webauthn/mfa/begin`, {
+    method: "GET",
+    credentials: "include",
+    headers: {
+      "Accept-Language": Setting.getAcceptLanguage(),
+    },
+  })
+    .then(res => res.json())
+    .then((credentialRequestOptions) => {
+      if (credentialRequestOptions.status === "error") {
+        throw new Error(credentialRequestOptions.msg);
+      }
+
+      credentialRequestOptions.publicKey.challenge = webAuthnBufferDecode(credentialRequestOptions.publicKey.challenge);
+      if (credentialRequestOptions.publicKey.allowCredentials) {
+        for (let i = 0; i < credentialRequestOptions.publicKey.allowCredentials.length; i++) {
+          credentialRequestOptions.publicKey.allowCredentials[i].id = webAuthnBufferDecode(credentialRequestOptions.publicKey.allowCredentials[i].id);
+        }
+      }
+      return navigator.credentials.get({
+        publicKey: credentialRequestOptions.publicKey,
+      });
+    })
+    .then((assertion) => {
+      return JSON.stringify({
+        id: assertion.id,
+        rawId: webAuthnBufferEncode(assertion.rawId),
+        type: assertion.type,
+        response: {
+          authenticatorData: webAuthnBufferEncode(assertion.response.authenticatorData),
+          clientDataJSON: webAuthnBufferEncode(assertion.response.clientDataJSON),
+          signature: webAuthnBufferEncode(assertion.response.signature),
+          userHandle: assertion.response.userHandle ? webAuthnBufferEncode(assertion.response.userHandle) : "",
+        },
+      });
+    });
+}
+
+export function getUserWebAuthnCredentials(id) {
+  return fetch(`${Setting.ServerUrl}/api/webauthn/get-credentials?id=${encodeURIComponent(id)}`, {
+    method: "GET",
+    credentials: "include",
+    headers: {
+      "Accept-Language": Setting.getAcceptLanguage(),
+    },
+  }).then(res => res.json());
+}
+
+export function renameUserWebAuthnCredential(id, credentialID, displayName) {
+  const form = new FormData();
+  form.append("id", id);
+  form.append("credentialID", credentialID);
+  form.append("displayName", displayName);
+
+  return fetch(`${Setting.ServerUrl}/api/webauthn/rename-credential`, {
+    method: "POST",
+    credentials: "include",
+    body: form,
+    dataType: "text",
+    headers: {
+      "Accept-Language": Setting.getAcceptLanguage(),
+    },
+  }).then(res => res.json());
+}
+
+export function deleteUserWebAuthnCredential(credentialID, id = "") {
   const form = new FormData();
+  form.append("id", id);
   form.append("credentialID", credentialID);
 
   return fetch(`${Setting.ServerUrl}/api/webauthn/delete-credential`, {
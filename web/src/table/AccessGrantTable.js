@@ -0,0 +1,136 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+import React from "react";
+import {DeleteOutlined} from "@ant-design/icons";
+import {Button, Col, DatePicker, Row, Select, Table, Tooltip} from "antd";
+import * as Setting from "../Setting";
+import i18next from "i18next";
+import dayjs from "dayjs";
+
+class AccessGrantTable extends React.Component {
+  constructor(props) {
+    super(props);
+    this.state = {
+      classes: props,
+    };
+  }
+
+  updateTable(table) {
+    this.props.onUpdateTable(table);
+  }
+
+  updateField(table, index, key, value) {
+    table[index][key] = value;
+    this.updateTable(table);
+  }
+
+  addRow(table) {
+    const row = {member: "", startTime: "", endTime: ""};
+    if (table === undefined || table === null) {
+      table = [];
+    }
+    table = Setting.addRow(table, row);
+    this.updateTable(table);
+  }
+
+  deleteRow(table, i) {
+    table = Setting.deleteRow(table, i);
+    this.updateTable(table);
+  }
+
+  renderTime(table, index, key, text) {
+    return (
+      <DatePicker showTime style={{width: "100%"}} value={text ? dayjs(text) : null} onChange={value => {
+        this.updateField(table, index, key, value ? value.format() : "");
+      }} />
+    );
+  }
+
+  renderTable(table) {
+    const columns = [
+      {
+        title: i18next.t("role:Member"),
+        dataIndex: "member",
+        key: "member",
+        render: (text, record, index) => {
+          return (
+            <Select virtual={false} style={{width: "100%"}} value={text} onChange={value => {
+              this.updateField(table, index, "member", value);
+            }}
+            options={(this.props.members ?? []).map((member) => Setting.getOption(member, member))}
+            />
+          );
+        },
+      },
+      {
+        title: i18next.t("role:Start time"),
+        dataIndex: "startTime",
+        key: "startTime",
+        width: "250px",
+        render: (text, record, index) => {
+          return this.renderTime(table, index, "startTime", text);
+        },
+      },
+      {
+        title: i18next.t("role:End time"),
+        dataIndex: "endTime",
+        key: "endTime",
+        width: "250px",
+        render: (text, record, index) => {
+          return this.renderTime(table, index, "endTime", text);
+        },
+      },
+      {
+        title: i18next.t("general:Action"),
+        dataIndex: "action",
+        key: "action",
+        width: "20px",
+        render: (text, record, index) => {
+          return (
+            <Tooltip placement="topLeft" title={i18next.t("general:Delete")}>
+              <Button icon={<DeleteOutlined />} size="small" onClick={() => this.deleteRow(table, index)} />
+            </Tooltip>
+          );
+        },
+      },
+    ];
+
+    return (
+      <Table title={() => (
+        <div>
+          <Button style={{marginRight: "5px"}} type="primary" size="small" onClick={() => this.addRow(table)}>{i18next.t("general:Add")}</Button>
+        </div>
+      )}
+      columns={columns} dataSource={table} rowKey="key" size="middle" bordered pagination={false}
+      />
+    );
+  }
+
+  render() {
+    return (
+      <div>
+        <Row style={{marginTop: "20px"}} >
+          <Col span={24}>
+            {
+              this.renderTable(this.props.table)
+            }
+          </Col>
+        </Row>
+      </div>
+    );
+  }
+}
+
+export default AccessGrantTable;
@@ -0,0 +1,204 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+import React from "react";
+import {DeleteOutlined, DownOutlined, UpOutlined} from "@ant-design/icons";
+import {Button, Col, Input, Row, Select, Switch, Table, Tooltip} from "antd";
+import * as Setting from "../Setting";
+import i18next from "i18next";
+
+class CasServiceTable extends React.Component {
+  constructor(props) {
+    super(props);
+    this.state = {
+      classes: props,
+    };
+  }
+
+  updateTable(table) {
+    this.props.onUpdateTable(table);
+  }
+
+  updateField(table, index, key, value) {
+    table[index][key] = value;
+    this.updateTable(table);
+  }
+
+  addRow(table) {
+    const row = {name: "", servicePattern: "", allowedAttributes: [], allowProxy: false, proxyCallbackPattern: "", enableSingleLogout: false, logoutUrl: ""};
+    if (table === undefined || table === null) {
+      table = [];
+    }
+    table = Setting.addRow(table, row);
+    this.updateTable(table);
+  }
+
+  deleteRow(table, i) {
+    table = Setting.deleteRow(table, i);
+    this.updateTable(table);
+  }
+
+  upRow(table, i) {
+    table = Setting.swapRow(table, i - 1, i);
+    this.updateTable(table);
+  }
+
+  downRow(table, i) {
+    table = Setting.swapRow(table, i, i + 1);
+    this.updateTable(table);
+  }
+
+  renderTable(table) {
+    const columns = [
+      {
+        title: i18next.t("general:Name"),
+        dataIndex: "name",
+        key: "name",
+        width: "200px",
+        render: (text, record, index) => {
+          return (
+            <Input value={text} onChange={e => {
+              this.updateField(table, index, "name", e.target.value);
+            }} />
+          );
+        },
+      },
+      {
+        title: i18next.t("application:Service pattern"),
+        dataIndex: "servicePattern",
+        key: "servicePattern",
+        width: "250px",
+        render: (text, record, index) => {
+          return (
+            <Input value={text} placeholder="https://app\.example\.com/.*" onChange={e => {
+              this.updateField(table, index, "servicePattern", e.target.value);
+            }} />
+          );
+        },
+      },
+      {
+        title: i18next.t("application:Allowed attributes"),
+        dataIndex: "allowedAttributes",
+        key: "allowedAttributes",
+        width: "200px",
+        render: (text, record, index) => {
+          return (
+            <Select virtual={false} mode="tags" style={{width: "100%"}} value={text ?? []} onChange={value => {
+              this.updateField(table, index, "allowedAttributes", value);
+            }} />
+          );
+        },
+      },
+      {
+        title: i18next.t("application:Allow proxy"),
+        dataIndex: "allowProxy",
+        key: "allowProxy",
+        width: "100px",
+        render: (text, record, index) => {
+          return (
+            <Switch checked={text} onChange={checked => {
+              this.updateField(table, index, "allowProxy", checked);
+            }} />
+          );
+        },
+      },
+      {
+        title: i18next.t("application:Proxy callback pattern"),
+        dataIndex: "proxyCallbackPattern",
+        key: "proxyCallbackPattern",
+        width: "200px",
+        render: (text, record, index) => {
+          return (
+            <Input value={text} disabled={!record.allowProxy} onChange={e => {
+              this.updateField(table, index, "proxyCallbackPattern", e.target.value);
+            }} />
+          );
+        },
+      },
+      {
+        title: i18next.t("application:Single logout"),
+        dataIndex: "enableSingleLogout",
+        key: "enableSingleLogout",
+        width: "100px",
+        render: (text, record, index) => {
+          return (
+            <Switch checked={text} onChange={checked => {
+              this.updateField(table, index, "enableSingleLogout", checked);
+            }} />
+          );
+        },
+      },
+      {
+        title: i18next.t("application:Logout URL"),
+        dataIndex: "logoutUrl",
+        key: "logoutUrl",
+        width: "200px",
+        render: (text, record, index) => {
+          return (
+            <Input value={text} disabled={!record.enableSingleLogout} onChange={e => {
+              this.updateField(table, index, "logoutUrl", e.target.value);
+            }} />
+          );
+        },
+      },
+      {
+        title: i18next.t("general:Action"),
+        dataIndex: "action",
+        key: "action",
+        width: "20px",
+        render: (text, record, index) => {
+          return (
+            <div>
+              <Tooltip placement="bottomLeft" title={i18next.t("general:Up")}>
+                <Button style={{marginRight: "5px"}} disabled={index === 0} icon={<UpOutlined />} size="small" onClick={() => this.upRow(table, index)} />
+              </Tooltip>
+              <Tooltip placement="topLeft" title={i18next.t("general:Down")}>
+                <Button style={{marginRight: "5px"}} disabled={index === table.length - 1} icon={<DownOutlined />} size="small" onClick={() => this.downRow(table, index)} />
+              </Tooltip>
+              <Tooltip placement="topLeft" title={i18next.t("general:Delete")}>
+                <Button icon={<DeleteOutlined />} size="small" onClick={() => this.deleteRow(table, index)} />
+              </Tooltip>
+            </div>
+          );
+        },
+      },
+    ];
+
+    return (
+      <Table title={() => (
+        <div>
+          <Button style={{marginRight: "5px"}} type="primary" size="small" onClick={() => this.addRow(table)}>{i18next.t("general:Add")}</Button>
+        </div>
+      )}
+      columns={columns} dataSource={table} rowKey="key" size="middle" bordered
+      />
+    );
+  }
+
+  render() {
+    return (
+      <div>
+        <Row style={{marginTop: "20px"}} >
+          <Col span={24}>
+            {
+              this.renderTable(this.props.table)
+            }
+          </Col>
+        </Row>
+      </div>
+    );
+  }
+}
+
+export default CasServiceTable;
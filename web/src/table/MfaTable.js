@@ -15,7 +15,7 @@
 import React from "react";
 import {DeleteOutlined, DownOutlined, UpOutlined} from "@ant-design/icons";
 import {Button, Col, Row, Select, Table, Tooltip} from "antd";
-import {EmailMfaType, SmsMfaType, TotpMfaType} from "../auth/MfaSetupPage";
+import {EmailMfaType, PushMfaType, SmsMfaType, TotpMfaType, WebAuthnMfaType} from "../auth/MfaSetupPage";
 import {MfaRuleOptional, MfaRulePrompted, MfaRuleRequired} from "../Setting";
 import * as Setting from "../Setting";
 import i18next from "i18next";
@@ -26,6 +26,8 @@ const MfaItems = [
   {name: "Phone", value: SmsMfaType},
   {name: "Email", value: EmailMfaType},
   {name: "App", value: TotpMfaType},
+  {name: "WebAuthn", value: WebAuthnMfaType},
+  {name: "Push", value: PushMfaType},
 ];
 
 const RuleItems = [
@@ -136,6 +136,24 @@ class SyncerTableColumnTable extends React.Component {
           );
         },
       },
+      {
+        title: i18next.t("syncer:Owner"),
+        dataIndex: "owner",
+        key: "owner",
+        width: "120px",
+        render: (text, record, index) => {
+          return (
+            <Select virtual={false} style={{width: "100%"}} value={text || "Source"} onChange={(value => {this.updateField(table, index, "owner", value === "Source" ? "" : value);})}>
+              {
+                [
+                  {id: "Source", name: i18next.t("syncer:Source")},
+                  {id: "Casdoor", name: "Casdoor"},
+                ].map((item, index) => <Option key={index} value={item.id}>{item.name}</Option>)
+              }
+            </Select>
+          );
+        },
+      },
       {
         title: i18next.t("general:Action"),
         key: "action",
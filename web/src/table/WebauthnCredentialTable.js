@@ -42,8 +42,19 @@ class WebAuthnCredentialTable extends React.Component {
     const columns = [
       {
         title: i18next.t("general:Name"),
-        dataIndex: "ID",
-        key: "ID",
+        dataIndex: "displayName",
+        key: "displayName",
+        render: (text, record, index) => {
+          return text !== undefined && text !== "" ? text : record.id;
+        },
+      },
+      {
+        title: i18next.t("general:Created time"),
+        dataIndex: "createdTime",
+        key: "createdTime",
+        render: (text, record, index) => {
+          return text ? Setting.getFormattedDate(text) : "";
+        },
       },
       {
         title: i18next.t("general:Action"),
@@ -60,7 +71,7 @@ class WebAuthnCredentialTable extends React.Component {
     ];
 
     return (
-      <Table rowKey={"ID"} columns={columns} dataSource={this.props.table} size="middle" bordered pagination={false}
+      <Table rowKey={"id"} columns={columns} dataSource={this.props.table} size="middle" bordered pagination={false}
         title={() => (
           <div>
             {i18next.t("user:WebAuthn credentials")}&nbsp;&nbsp;&nbsp;&nbsp;
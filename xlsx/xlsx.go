@@ -14,14 +14,28 @@
 
 package xlsx
 
-import "github.com/tealeg/xlsx"
+import (
+	"bytes"
+
+	"github.com/tealeg/xlsx"
+)
 
 func ReadXlsxFile(path string) [][]string {
-	file, err := xlsx.OpenFile(path)
+	res, err := ReadXlsxRows(path)
 	if err != nil {
 		panic(err)
 	}
 
+	return res
+}
+
+// ReadXlsxRows returns the rows of the first sheet of an xlsx file
+func ReadXlsxRows(path string) ([][]string, error) {
+	file, err := xlsx.OpenFile(path)
+	if err != nil {
+		return nil, err
+	}
+
 	res := [][]string{}
 	for _, sheet := range file.Sheets {
 		for _, row := range sheet.Rows {
@@ -35,5 +49,29 @@ func ReadXlsxFile(path string) [][]string {
 		break
 	}
 
-	return res
+	return res, nil
+}
+
+// WriteXlsxFile returns an xlsx file with a sheet of the rows
+func WriteXlsxFile(sheetName string, rows [][]string) ([]byte, error) {
+	file := xlsx.NewFile()
+	sheet, err := file.AddSheet(sheetName)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range rows {
+		row := sheet.AddRow()
+		for _, text := range line {
+			row.AddCell().SetString(text)
+		}
+	}
+
+	var buffer bytes.Buffer
+	err = file.Write(&buffer)
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
 }